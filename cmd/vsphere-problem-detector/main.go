@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"k8s.io/component-base/cli"
@@ -45,8 +49,56 @@ func NewOperatorCommand() *cobra.Command {
 		},
 	}
 
+	alertRulesCmd := &cobra.Command{
+		Use:   "alert-rules",
+		Short: "Print PrometheusRule with alerts for failing checks of vSphere Problem Detector",
+		Run: func(cmd *cobra.Command, args []string) {
+			data, err := operator.GenerateAlertRules()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to generate alert rules: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(string(data))
+		},
+	}
+
 	cmd.AddCommand(ctrlCmd)
 	cmd.AddCommand(versionCmd)
+	cmd.AddCommand(alertRulesCmd)
+	cmd.AddCommand(NewCheckCommand())
 
 	return cmd
 }
+
+// NewCheckCommand returns command that runs all checks once from outside of the cluster.
+// It exits with 1 when a check fails and with 2 when the checks could not run at all.
+func NewCheckCommand() *cobra.Command {
+	opts := &operator.StandaloneOptions{}
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Run all checks of vSphere Problem Detector once and print their results",
+		Long: "Run all checks of vSphere Problem Detector once against the cluster in the kubeconfig and print their results. " +
+			"vSphere cloud provider config and vCenter credentials are read from the cluster, unless they are given as flags. " +
+			"Exits with 1 when at least one check fails or times out and with 2 when the checks could not run.",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+			failed, err := operator.RunStandaloneChecks(ctx, opts, os.Stdout)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to run checks: %s\n", err)
+				os.Exit(2)
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&opts.Kubeconfig, "kubeconfig", "", "Path to kubeconfig of the cluster. KUBECONFIG environment variable or ~/.kube/config is used when empty.")
+	cmd.Flags().StringVar(&opts.CloudConfigFile, "cloud-config", "", "Path to vSphere cloud provider config. The config of the cluster is used when empty.")
+	cmd.Flags().StringVar(&opts.Username, "username", "", "vCenter user name, used for all vCenters. Credentials of the cluster are used when empty.")
+	cmd.Flags().StringVar(&opts.Password, "password", os.Getenv("VSPHERE_PASSWORD"), "vCenter password, used for all vCenters. Defaults to VSPHERE_PASSWORD environment variable.")
+	// Options of the checks, e.g. enable-checks or check-timeout.
+	cmd.Flags().AddGoFlagSet(flag.CommandLine)
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", operator.OutputTable, "Output format of the results, "+operator.OutputTable+" or "+operator.OutputJSON+".")
+	return cmd
+}
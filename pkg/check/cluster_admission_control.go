@@ -0,0 +1,142 @@
+package check
+
+import (
+	"fmt"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	failoverCapacityMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_cluster_failover_capacity_percent",
+			Help:           "Percentage of memory of vSphere compute clusters with node VMs reserved for failover by HA admission control.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, clusterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(failoverCapacityMetric)
+}
+
+// CheckClusterAdmissionControlPolicyType tests that HA admission control of compute clusters that run
+// node VMs reserves enough failover capacity to restart all node VMs of the ESXi host with the largest
+// node VM memory footprint. With less reserved capacity, HA may not restart all node VMs after a host failure.
+func CheckClusterAdmissionControlPolicyType(ctx *CheckContext) error {
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return err
+	}
+	vms, err := getNodeVMs(ctx, []string{"name", "runtime.host", "config.hardware.memoryMB"})
+	if err != nil {
+		return err
+	}
+
+	// Reset series of the vCenter to drop clusters that do not run nodes any longer.
+	resetVCenterMetric(failoverCapacityMetric, ctx.VMConfig.Workspace.VCenterIP)
+	var errs []error
+	for _, clusterRef := range clusterRefs {
+		cluster, err := getClusterConfigurationEx(ctx, clusterRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		_, hosts, err := getComputeClusterHosts(ctx, clusterRef, []string{"name", "summary.hardware.memorySize"})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var das *vim.ClusterDasConfigInfo
+		if config, ok := cluster.ConfigurationEx.(*vim.ClusterConfigInfoEx); ok {
+			das = &config.DasConfig
+		}
+		policy, reserved := getFailoverCapacityPercent(das, len(hosts))
+		failoverCapacityMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, cluster.Name).Set(reserved)
+
+		hostName, required := getRequiredFailoverCapacityPercent(hosts, vms)
+		if reserved < required {
+			errs = append(errs, fmt.Errorf("compute cluster %s has HA admission control policy %s that reserves %.0f%% of cluster memory for failover, node VMs on ESXi host %s need %.0f%%", cluster.Name, policy, reserved, hostName, required))
+			continue
+		}
+		klog.V(4).Infof("Compute cluster %s has HA admission control policy %s that reserves %.0f%% of cluster memory for failover, %.0f%% is needed", cluster.Name, policy, reserved, required)
+	}
+	return JoinErrors(errs)
+}
+
+// getFailoverCapacityPercent returns description of the HA admission control policy and percentage
+// of cluster resources it reserves for failover. Policies based on host count assume the hosts in
+// the cluster have the same size.
+func getFailoverCapacityPercent(das *vim.ClusterDasConfigInfo, hostCount int) (string, float64) {
+	if das == nil || das.Enabled == nil || !*das.Enabled {
+		return "none (vSphere HA is disabled)", 0
+	}
+	if das.AdmissionControlEnabled == nil || !*das.AdmissionControlEnabled {
+		return "none (admission control is disabled)", 0
+	}
+	hostPercent := func(hosts int) float64 {
+		if hostCount == 0 {
+			return 0
+		}
+		return float64(hosts) * 100 / float64(hostCount)
+	}
+
+	switch policy := das.AdmissionControlPolicy.(type) {
+	case *vim.ClusterFailoverResourcesAdmissionControlPolicy:
+		percent := policy.CpuFailoverResourcesPercent
+		if policy.MemoryFailoverResourcesPercent < percent {
+			percent = policy.MemoryFailoverResourcesPercent
+		}
+		return fmt.Sprintf("cluster resource percentage (CPU %d%%, memory %d%%)", policy.CpuFailoverResourcesPercent, policy.MemoryFailoverResourcesPercent), float64(percent)
+	case *vim.ClusterFailoverLevelAdmissionControlPolicy:
+		return fmt.Sprintf("host failures cluster tolerates (%d)", policy.FailoverLevel), hostPercent(int(policy.FailoverLevel))
+	case *vim.ClusterFailoverHostAdmissionControlPolicy:
+		return fmt.Sprintf("dedicated failover hosts (%d)", len(policy.FailoverHosts)), hostPercent(len(policy.FailoverHosts))
+	default:
+		return "unknown", 0
+	}
+}
+
+// getRequiredFailoverCapacityPercent returns name of the ESXi host with the largest memory of node VMs
+// and percentage of the cluster memory needed to restart these node VMs on other hosts.
+func getRequiredFailoverCapacityPercent(hosts []mo.HostSystem, vms []mo.VirtualMachine) (string, float64) {
+	var clusterMemory int64
+	hostNames := make(map[vim.ManagedObjectReference]string)
+	for _, host := range hosts {
+		hostNames[host.Self] = host.Name
+		if host.Summary.Hardware != nil {
+			clusterMemory += host.Summary.Hardware.MemorySize
+		}
+	}
+	if clusterMemory == 0 {
+		return "", 0
+	}
+
+	// host -> memory of node VMs on the host in bytes
+	vmMemory := make(map[vim.ManagedObjectReference]int64)
+	for _, vm := range vms {
+		if vm.Runtime.Host == nil || vm.Config == nil {
+			continue
+		}
+		if _, found := hostNames[*vm.Runtime.Host]; !found {
+			continue
+		}
+		vmMemory[*vm.Runtime.Host] += int64(vm.Config.Hardware.MemoryMB) * 1024 * 1024
+	}
+
+	var largestHost string
+	var largestMemory int64
+	for _, host := range hosts {
+		if memory := vmMemory[host.Self]; memory > largestMemory {
+			largestHost = host.Name
+			largestMemory = memory
+		}
+	}
+	return largestHost, float64(largestMemory) * 100 / float64(clusterMemory)
+}
@@ -0,0 +1,133 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckClusterAdmissionControlPolicyType(t *testing.T) {
+	tests := []struct {
+		name             string
+		dasConfig        types.ClusterDasConfigInfo
+		expectedError    string
+		expectedCapacity float64
+	}{
+		{
+			name:             "HA disabled",
+			dasConfig:        types.ClusterDasConfigInfo{},
+			expectedError:    "compute cluster DC0_C0 has HA admission control policy none (vSphere HA is disabled) that reserves 0% of cluster memory for failover, node VMs on ESXi host DC0_C0_H0 need 31%",
+			expectedCapacity: 0,
+		},
+		{
+			name: "admission control disabled",
+			dasConfig: types.ClusterDasConfigInfo{
+				Enabled:                 types.NewBool(true),
+				AdmissionControlEnabled: types.NewBool(false),
+			},
+			expectedError:    "compute cluster DC0_C0 has HA admission control policy none (admission control is disabled) that reserves 0% of cluster memory for failover, node VMs on ESXi host DC0_C0_H0 need 31%",
+			expectedCapacity: 0,
+		},
+		{
+			name: "sufficient cluster resource percentage",
+			dasConfig: types.ClusterDasConfigInfo{
+				Enabled:                 types.NewBool(true),
+				AdmissionControlEnabled: types.NewBool(true),
+				AdmissionControlPolicy: &types.ClusterFailoverResourcesAdmissionControlPolicy{
+					CpuFailoverResourcesPercent:    50,
+					MemoryFailoverResourcesPercent: 40,
+				},
+			},
+			expectedCapacity: 40,
+		},
+		{
+			name: "insufficient cluster resource percentage",
+			dasConfig: types.ClusterDasConfigInfo{
+				Enabled:                 types.NewBool(true),
+				AdmissionControlEnabled: types.NewBool(true),
+				AdmissionControlPolicy: &types.ClusterFailoverResourcesAdmissionControlPolicy{
+					CpuFailoverResourcesPercent:    50,
+					MemoryFailoverResourcesPercent: 25,
+				},
+			},
+			expectedError:    "compute cluster DC0_C0 has HA admission control policy cluster resource percentage (CPU 50%, memory 25%) that reserves 25% of cluster memory for failover, node VMs on ESXi host DC0_C0_H0 need 31%",
+			expectedCapacity: 25,
+		},
+		{
+			name: "host failures cluster tolerates",
+			dasConfig: types.ClusterDasConfigInfo{
+				Enabled:                 types.NewBool(true),
+				AdmissionControlEnabled: types.NewBool(true),
+				AdmissionControlPolicy:  &types.ClusterFailoverLevelAdmissionControlPolicy{FailoverLevel: 1},
+			},
+			expectedCapacity: 100.0 / 3,
+		},
+		{
+			name: "dedicated failover hosts",
+			dasConfig: types.ClusterDasConfigInfo{
+				Enabled:                 types.NewBool(true),
+				AdmissionControlEnabled: types.NewBool(true),
+				AdmissionControlPolicy: &types.ClusterFailoverHostAdmissionControlPolicy{
+					FailoverHosts: []types.ManagedObjectReference{{Type: "HostSystem", Value: "host-53"}},
+				},
+			},
+			expectedCapacity: 100.0 / 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			// DC0_C0_RP0_VM1 and DC0_C0_APP0_VM0 run on DC0_C0_H0, give them ~31% of the cluster memory
+			for _, node := range kubeClient.nodes[1:3] {
+				if err := customizeVM(ctx, node, &types.VirtualMachineConfigSpec{MemoryMB: 1900}); err != nil {
+					t.Fatalf("Failed to customize node: %s", err)
+				}
+			}
+			obj := simulator.Map.Get(types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "clustercomputeresource-30"})
+			cluster := obj.(*simulator.ClusterComputeResource)
+			cluster.ConfigurationEx.(*types.ClusterConfigInfoEx).DasConfig = test.dasConfig
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckClusterAdmissionControlPolicyType(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_cluster_failover_capacity_percent [ALPHA] Percentage of memory of vSphere compute clusters with node VMs reserved for failover by HA admission control.
+# TYPE vsphere_cluster_failover_capacity_percent gauge
+vsphere_cluster_failover_capacity_percent{cluster="DC0_C0",vcenter="dc0"} %v
+`, test.expectedCapacity)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_cluster_failover_capacity_percent"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package check
+
+import (
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	dpmBehaviorLabel = "behavior"
+)
+
+var (
+	dpmEnabledMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_cluster_dpm_enabled",
+			Help:           "Distributed Power Management status of vSphere compute clusters with node VMs, 1 when enabled, 0 otherwise.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, clusterLabel, dpmBehaviorLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(dpmEnabledMetric)
+}
+
+// CheckClusterDPMEnabled checks Distributed Power Management (DPM) of compute clusters that run node VMs.
+// DPM in automated mode powers off hosts with low utilization, it evacuates node VMs from them and
+// reduces capacity available for failover. The check is advisory, it only logs a warning and reports
+// the metric when DPM is automated.
+func CheckClusterDPMEnabled(ctx *CheckContext) error {
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Reset series of the vCenter to drop clusters that do not run nodes any longer.
+	resetVCenterMetric(dpmEnabledMetric, ctx.VMConfig.Workspace.VCenterIP)
+	var errs []error
+	for _, clusterRef := range clusterRefs {
+		cluster, err := getClusterConfigurationEx(ctx, clusterRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var dpmConfig *vim.ClusterDpmConfigInfo
+		if config, ok := cluster.ConfigurationEx.(*vim.ClusterConfigInfoEx); ok {
+			dpmConfig = config.DpmConfigInfo
+		}
+		if dpmConfig == nil || dpmConfig.Enabled == nil || !*dpmConfig.Enabled {
+			dpmEnabledMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, cluster.Name, "").Set(0)
+			klog.V(4).Infof("Compute cluster %s has DPM disabled", cluster.Name)
+			continue
+		}
+
+		behavior := dpmConfig.DefaultDpmBehavior
+		dpmEnabledMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, cluster.Name, string(behavior)).Set(1)
+		if behavior == vim.DpmBehaviorAutomated {
+			klog.Warningf("Compute cluster %s has DPM enabled with behavior %s and threshold %d, ESXi hosts may be powered off and node VMs evacuated from them", cluster.Name, behavior, dpmConfig.HostPowerActionRate)
+		} else {
+			klog.V(2).Infof("Compute cluster %s has DPM enabled with behavior %s", cluster.Name, behavior)
+		}
+	}
+	return JoinErrors(errs)
+}
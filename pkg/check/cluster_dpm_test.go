@@ -0,0 +1,88 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckClusterDPMEnabled(t *testing.T) {
+	tests := []struct {
+		name            string
+		dpmConfig       *types.ClusterDpmConfigInfo
+		expectedMetrics string
+	}{
+		{
+			name:      "DPM not configured",
+			dpmConfig: nil,
+			expectedMetrics: `
+# HELP vsphere_cluster_dpm_enabled [ALPHA] Distributed Power Management status of vSphere compute clusters with node VMs, 1 when enabled, 0 otherwise.
+# TYPE vsphere_cluster_dpm_enabled gauge
+vsphere_cluster_dpm_enabled{behavior="",cluster="DC0_C0",vcenter="dc0"} 0
+`,
+		},
+		{
+			name:      "DPM disabled",
+			dpmConfig: &types.ClusterDpmConfigInfo{Enabled: types.NewBool(false), DefaultDpmBehavior: types.DpmBehaviorAutomated},
+			expectedMetrics: `
+# HELP vsphere_cluster_dpm_enabled [ALPHA] Distributed Power Management status of vSphere compute clusters with node VMs, 1 when enabled, 0 otherwise.
+# TYPE vsphere_cluster_dpm_enabled gauge
+vsphere_cluster_dpm_enabled{behavior="",cluster="DC0_C0",vcenter="dc0"} 0
+`,
+		},
+		{
+			name:      "DPM manual",
+			dpmConfig: &types.ClusterDpmConfigInfo{Enabled: types.NewBool(true), DefaultDpmBehavior: types.DpmBehaviorManual},
+			expectedMetrics: `
+# HELP vsphere_cluster_dpm_enabled [ALPHA] Distributed Power Management status of vSphere compute clusters with node VMs, 1 when enabled, 0 otherwise.
+# TYPE vsphere_cluster_dpm_enabled gauge
+vsphere_cluster_dpm_enabled{behavior="manual",cluster="DC0_C0",vcenter="dc0"} 1
+`,
+		},
+		{
+			name:      "DPM automated",
+			dpmConfig: &types.ClusterDpmConfigInfo{Enabled: types.NewBool(true), DefaultDpmBehavior: types.DpmBehaviorAutomated, HostPowerActionRate: 3},
+			expectedMetrics: `
+# HELP vsphere_cluster_dpm_enabled [ALPHA] Distributed Power Management status of vSphere compute clusters with node VMs, 1 when enabled, 0 otherwise.
+# TYPE vsphere_cluster_dpm_enabled gauge
+vsphere_cluster_dpm_enabled{behavior="automated",cluster="DC0_C0",vcenter="dc0"} 1
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			obj := simulator.Map.Get(types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "clustercomputeresource-30"})
+			cluster := obj.(*simulator.ClusterComputeResource)
+			cluster.ConfigurationEx.(*types.ClusterConfigInfoEx).DpmConfigInfo = test.dpmConfig
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckClusterDPMEnabled(ctx)
+
+			// Assert
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_cluster_dpm_enabled"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package check
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Minimal number of heartbeat datastores: vSphere HA selects two heartbeat datastores per host.
+	minHeartbeatDatastores = 2
+)
+
+var (
+	heartbeatDatastoresMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_cluster_ha_heartbeat_datastores",
+			Help:           "Number of datastores eligible for vSphere HA datastore heartbeating in compute clusters with node VMs.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, clusterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(heartbeatDatastoresMetric)
+}
+
+// CheckClusterOverlappingDatastoreHeartbeatSelection tests that HA enabled compute clusters that run node VMs
+// have at least two datastores eligible for HA datastore heartbeating and that the heartbeat datastore
+// selection does not exclude shared datastores used by node VMs. Without datastore heartbeats, HA cannot
+// tell a network partitioned host from a failed one. Only datastores mounted on at least two hosts
+// of the cluster are eligible.
+func CheckClusterOverlappingDatastoreHeartbeatSelection(ctx *CheckContext) error {
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return err
+	}
+	vms, err := getNodeVMs(ctx, []string{"runtime.host", "datastore"})
+	if err != nil {
+		return err
+	}
+
+	// Reset series of the vCenter to drop clusters that do not run nodes any longer.
+	resetVCenterMetric(heartbeatDatastoresMetric, ctx.VMConfig.Workspace.VCenterIP)
+	var errs []error
+	for _, clusterRef := range clusterRefs {
+		cluster, err := getClusterConfigurationEx(ctx, clusterRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		config, ok := cluster.ConfigurationEx.(*vim.ClusterConfigInfoEx)
+		if !ok || config.DasConfig.Enabled == nil || !*config.DasConfig.Enabled {
+			klog.V(4).Infof("Compute cluster %s has vSphere HA disabled, skipping heartbeat datastores", cluster.Name)
+			continue
+		}
+		_, hosts, err := getComputeClusterHosts(ctx, clusterRef, []string{"datastore"})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// Heartbeat datastores must be shared by hosts of the cluster.
+		hostCount := make(map[vim.ManagedObjectReference]int)
+		clusterHosts := make(map[vim.ManagedObjectReference]bool)
+		for _, host := range hosts {
+			clusterHosts[host.Self] = true
+			for _, ref := range host.Datastore {
+				hostCount[ref]++
+			}
+		}
+		feasible := make(map[vim.ManagedObjectReference]bool)
+		for ref, count := range hostCount {
+			if count > 1 {
+				feasible[ref] = true
+			}
+		}
+
+		policy := config.DasConfig.HBDatastoreCandidatePolicy
+		if policy == "" {
+			policy = string(vim.ClusterDasConfigInfoHBDatastoreCandidateAllFeasibleDsWithUserPreference)
+		}
+		eligible := feasible
+		if policy == string(vim.ClusterDasConfigInfoHBDatastoreCandidateUserSelectedDs) {
+			eligible = make(map[vim.ManagedObjectReference]bool)
+			for _, ref := range config.DasConfig.HeartbeatDatastore {
+				if feasible[ref] {
+					eligible[ref] = true
+				}
+			}
+		}
+		heartbeatDatastoresMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, cluster.Name).Set(float64(len(eligible)))
+
+		// Shared datastores of node VMs in this cluster that are not eligible for heartbeating.
+		var excludedRefs []vim.ManagedObjectReference
+		excludedFound := make(map[vim.ManagedObjectReference]bool)
+		for _, vm := range vms {
+			if vm.Runtime.Host == nil || !clusterHosts[*vm.Runtime.Host] {
+				continue
+			}
+			for _, ref := range vm.Datastore {
+				if feasible[ref] && !eligible[ref] && !excludedFound[ref] {
+					excludedFound[ref] = true
+					excludedRefs = append(excludedRefs, ref)
+				}
+			}
+		}
+
+		if len(eligible) < minHeartbeatDatastores {
+			errs = append(errs, fmt.Errorf("compute cluster %s has %d datastores eligible for HA heartbeating with heartbeat datastore policy %s, at least %d are needed", cluster.Name, len(eligible), policy, minHeartbeatDatastores))
+		}
+		if len(excludedRefs) > 0 {
+			names, err := getDatastoreNames(ctx, excludedRefs)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			var excluded []string
+			for _, ref := range excludedRefs {
+				excluded = append(excluded, names[ref])
+			}
+			sort.Strings(excluded)
+			errs = append(errs, fmt.Errorf("compute cluster %s with %d HA heartbeat datastores excludes datastores of node VMs from heartbeating with heartbeat datastore policy %s: %s", cluster.Name, len(eligible), policy, strings.Join(excluded, ", ")))
+		}
+		klog.V(4).Infof("Compute cluster %s has %d datastores eligible for HA heartbeating with heartbeat datastore policy %s", cluster.Name, len(eligible), policy)
+	}
+	return JoinErrors(errs)
+}
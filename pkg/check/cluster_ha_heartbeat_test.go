@@ -0,0 +1,140 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckClusterOverlappingDatastoreHeartbeatSelection(t *testing.T) {
+	tests := []struct {
+		name                string
+		haDisabled          bool
+		policy              string
+		heartbeatDatastores []string
+		// Datastores mounted to the cluster hosts. All LocalDS_0 - LocalDS_3 are mounted when empty.
+		hostDatastores  []string
+		expectedError   string
+		expectedMetrics string
+	}{
+		{
+			name:       "HA disabled",
+			haDisabled: true,
+		},
+		{
+			name: "default policy",
+			expectedMetrics: `
+vsphere_cluster_ha_heartbeat_datastores{cluster="DC0_C0",vcenter="dc0"} 4
+`,
+		},
+		{
+			name:           "default policy with a single shared datastore",
+			hostDatastores: []string{"LocalDS_0"},
+			expectedError:  "compute cluster DC0_C0 has 1 datastores eligible for HA heartbeating with heartbeat datastore policy allFeasibleDsWithUserPreference, at least 2 are needed",
+			expectedMetrics: `
+vsphere_cluster_ha_heartbeat_datastores{cluster="DC0_C0",vcenter="dc0"} 1
+`,
+		},
+		{
+			name:                "user selected datastores",
+			policy:              "userSelectedDs",
+			heartbeatDatastores: []string{"LocalDS_0", "LocalDS_1"},
+			expectedMetrics: `
+vsphere_cluster_ha_heartbeat_datastores{cluster="DC0_C0",vcenter="dc0"} 2
+`,
+		},
+		{
+			name:                "user selected datastores exclude node datastore",
+			policy:              "userSelectedDs",
+			heartbeatDatastores: []string{"LocalDS_1", "LocalDS_3"},
+			expectedError:       "compute cluster DC0_C0 with 2 HA heartbeat datastores excludes datastores of node VMs from heartbeating with heartbeat datastore policy userSelectedDs: LocalDS_0",
+			expectedMetrics: `
+vsphere_cluster_ha_heartbeat_datastores{cluster="DC0_C0",vcenter="dc0"} 2
+`,
+		},
+		{
+			name:                "single user selected datastore",
+			policy:              "userSelectedDs",
+			heartbeatDatastores: []string{"LocalDS_1"},
+			expectedError: "compute cluster DC0_C0 has 1 datastores eligible for HA heartbeating with heartbeat datastore policy userSelectedDs, at least 2 are needed;\n" +
+				"compute cluster DC0_C0 with 1 HA heartbeat datastores excludes datastores of node VMs from heartbeating with heartbeat datastore policy userSelectedDs: LocalDS_0",
+			expectedMetrics: `
+vsphere_cluster_ha_heartbeat_datastores{cluster="DC0_C0",vcenter="dc0"} 1
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			obj := simulator.Map.Get(types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "clustercomputeresource-30"})
+			cluster := obj.(*simulator.ClusterComputeResource)
+			// Datastore names are not unique in the simulator, find the ones mounted to the cluster hosts.
+			firstHost := simulator.Map.Get(cluster.Host[0]).(*simulator.HostSystem)
+			clusterDatastores := make(map[string]types.ManagedObjectReference)
+			for _, ref := range firstHost.Datastore {
+				clusterDatastores[simulator.Map.Get(ref).(*simulator.Datastore).Name] = ref
+			}
+			getDatastoreRefs := func(names []string) []types.ManagedObjectReference {
+				var refs []types.ManagedObjectReference
+				for _, name := range names {
+					refs = append(refs, clusterDatastores[name])
+				}
+				return refs
+			}
+			cluster.ConfigurationEx.(*types.ClusterConfigInfoEx).DasConfig = types.ClusterDasConfigInfo{
+				Enabled:                    types.NewBool(!test.haDisabled),
+				HBDatastoreCandidatePolicy: test.policy,
+				HeartbeatDatastore:         getDatastoreRefs(test.heartbeatDatastores),
+			}
+			if len(test.hostDatastores) > 0 {
+				for _, hostRef := range cluster.Host {
+					host := simulator.Map.Get(hostRef).(*simulator.HostSystem)
+					host.Datastore = getDatastoreRefs(test.hostDatastores)
+				}
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckClusterOverlappingDatastoreHeartbeatSelection(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := ""
+			if test.expectedMetrics != "" {
+				expectedMetrics = `
+# HELP vsphere_cluster_ha_heartbeat_datastores [ALPHA] Number of datastores eligible for vSphere HA datastore heartbeating in compute clusters with node VMs.
+# TYPE vsphere_cluster_ha_heartbeat_datastores gauge
+` + test.expectedMetrics
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_cluster_ha_heartbeat_datastores"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	dvsLabel = "dvs"
+)
+
+var (
+	niocEnabledMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_dvs_nioc_enabled",
+			Help:           "Network I/O Control status of distributed virtual switches with node VM traffic, 1 when enabled, 0 otherwise.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, dvsLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(niocEnabledMetric)
+}
+
+// CheckClusterNetworkIOControlEnabled checks that Network I/O Control (NIOC) is enabled on distributed
+// virtual switches that carry traffic of node VMs. Without NIOC, a single node VM can saturate the uplinks
+// and starve the rest of the cluster. The check is advisory, it only logs a warning and reports the metric
+// when NIOC is disabled.
+func CheckClusterNetworkIOControlEnabled(ctx *CheckContext) error {
+	switches, err := getNodeDistributedVirtualSwitches(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Reset series of the vCenter to drop switches that do not carry node traffic any longer.
+	resetVCenterMetric(niocEnabledMetric, ctx.VMConfig.Workspace.VCenterIP)
+	for _, dvs := range switches {
+		enabled := false
+		if dvs.Config != nil {
+			if config := dvs.Config.GetDVSConfigInfo(); config.NetworkResourceManagementEnabled != nil {
+				enabled = *config.NetworkResourceManagementEnabled
+			}
+		}
+		if enabled {
+			niocEnabledMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, dvs.Name).Set(1)
+			klog.V(4).Infof("Distributed virtual switch %s has Network I/O Control enabled", dvs.Name)
+		} else {
+			niocEnabledMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, dvs.Name).Set(0)
+			klog.Warningf("Distributed virtual switch %s has Network I/O Control disabled, a single node VM can saturate its uplinks", dvs.Name)
+		}
+	}
+	return nil
+}
+
+// getNodeDistributedVirtualSwitches returns distributed virtual switches with port groups used by node VMs,
+// sorted by name. Node VMs on standard switches are skipped.
+func getNodeDistributedVirtualSwitches(ctx *CheckContext) ([]mo.DistributedVirtualSwitch, error) {
+	vms, err := getNodeVMs(ctx, []string{"network"})
+	if err != nil {
+		return nil, err
+	}
+	portgroups := make(map[vim.ManagedObjectReference]bool)
+	for _, vm := range vms {
+		for _, ref := range vm.Network {
+			if ref.Type == "DistributedVirtualPortgroup" {
+				portgroups[ref] = true
+			}
+		}
+	}
+	if len(portgroups) == 0 {
+		return nil, nil
+	}
+
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+
+	var pgRefs []vim.ManagedObjectReference
+	for ref := range portgroups {
+		pgRefs = append(pgRefs, ref)
+	}
+	var pgs []mo.DistributedVirtualPortgroup
+	if err := pc.Retrieve(tctx, pgRefs, []string{"config.distributedVirtualSwitch"}, &pgs); err != nil {
+		return nil, fmt.Errorf("failed to get distributed virtual port groups: %s", err)
+	}
+	switchRefs := make(map[vim.ManagedObjectReference]bool)
+	for _, pg := range pgs {
+		if pg.Config.DistributedVirtualSwitch != nil {
+			switchRefs[*pg.Config.DistributedVirtualSwitch] = true
+		}
+	}
+	if len(switchRefs) == 0 {
+		return nil, nil
+	}
+
+	var dvsRefs []vim.ManagedObjectReference
+	for ref := range switchRefs {
+		dvsRefs = append(dvsRefs, ref)
+	}
+	var switches []mo.DistributedVirtualSwitch
+	if err := pc.Retrieve(tctx, dvsRefs, []string{"name", "config"}, &switches); err != nil {
+		return nil, fmt.Errorf("failed to get distributed virtual switches: %s", err)
+	}
+	sort.Slice(switches, func(i, j int) bool { return switches[i].Name < switches[j].Name })
+	return switches, nil
+}
@@ -0,0 +1,79 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckClusterNetworkIOControlEnabled(t *testing.T) {
+	tests := []struct {
+		name            string
+		niocEnabled     *bool
+		expectedMetrics string
+	}{
+		{
+			name:        "NIOC not set",
+			niocEnabled: nil,
+			expectedMetrics: `
+# HELP vsphere_dvs_nioc_enabled [ALPHA] Network I/O Control status of distributed virtual switches with node VM traffic, 1 when enabled, 0 otherwise.
+# TYPE vsphere_dvs_nioc_enabled gauge
+vsphere_dvs_nioc_enabled{dvs="DVS0",vcenter="dc0"} 0
+`,
+		},
+		{
+			name:        "NIOC disabled",
+			niocEnabled: types.NewBool(false),
+			expectedMetrics: `
+# HELP vsphere_dvs_nioc_enabled [ALPHA] Network I/O Control status of distributed virtual switches with node VM traffic, 1 when enabled, 0 otherwise.
+# TYPE vsphere_dvs_nioc_enabled gauge
+vsphere_dvs_nioc_enabled{dvs="DVS0",vcenter="dc0"} 0
+`,
+		},
+		{
+			name:        "NIOC enabled",
+			niocEnabled: types.NewBool(true),
+			expectedMetrics: `
+# HELP vsphere_dvs_nioc_enabled [ALPHA] Network I/O Control status of distributed virtual switches with node VM traffic, 1 when enabled, 0 otherwise.
+# TYPE vsphere_dvs_nioc_enabled gauge
+vsphere_dvs_nioc_enabled{dvs="DVS0",vcenter="dc0"} 1
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			obj := simulator.Map.Get(types.ManagedObjectReference{Type: "DistributedVirtualSwitch", Value: "dvs-10"})
+			dvs := obj.(*simulator.DistributedVirtualSwitch)
+			dvs.Config.GetDVSConfigInfo().NetworkResourceManagementEnabled = test.niocEnabled
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckClusterNetworkIOControlEnabled(ctx)
+
+			// Assert
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_dvs_nioc_enabled"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
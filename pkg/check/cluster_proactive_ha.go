@@ -0,0 +1,79 @@
+package check
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	clusterLabel = "cluster"
+)
+
+var (
+	proactiveHAEnabledMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_cluster_proactive_ha_enabled",
+			Help:           "Proactive HA status of vSphere compute clusters with node VMs, 1 when enabled, 0 otherwise.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, clusterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(proactiveHAEnabledMetric)
+}
+
+// CheckClusterProactiveHAEnabled checks that Proactive HA is enabled in compute clusters that run
+// node VMs. Proactive HA evacuates VMs from hosts with degraded hardware before they fail.
+// The check is advisory, it only logs a warning and reports the metric when Proactive HA is disabled.
+func CheckClusterProactiveHAEnabled(ctx *CheckContext) error {
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Reset series of the vCenter to drop clusters that do not run nodes any longer.
+	resetVCenterMetric(proactiveHAEnabledMetric, ctx.VMConfig.Workspace.VCenterIP)
+	var errs []error
+	for _, clusterRef := range clusterRefs {
+		cluster, err := getClusterConfigurationEx(ctx, clusterRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		enabled := false
+		if config, ok := cluster.ConfigurationEx.(*vim.ClusterConfigInfoEx); ok && config.InfraUpdateHaConfig != nil && config.InfraUpdateHaConfig.Enabled != nil {
+			enabled = *config.InfraUpdateHaConfig.Enabled
+		}
+		if enabled {
+			proactiveHAEnabledMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, cluster.Name).Set(1)
+			klog.V(4).Infof("Compute cluster %s has Proactive HA enabled", cluster.Name)
+		} else {
+			proactiveHAEnabledMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, cluster.Name).Set(0)
+			klog.Warningf("Compute cluster %s has Proactive HA disabled, node VMs are not evacuated from hosts with degraded hardware", cluster.Name)
+		}
+	}
+	return JoinErrors(errs)
+}
+
+// getClusterConfigurationEx returns compute cluster with its name and configurationEx.
+func getClusterConfigurationEx(ctx *CheckContext, clusterRef vim.ManagedObjectReference) (*mo.ClusterComputeResource, error) {
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+
+	var cluster mo.ClusterComputeResource
+	if err := pc.RetrieveOne(tctx, clusterRef, []string{"name", "configurationEx"}, &cluster); err != nil {
+		return nil, fmt.Errorf("failed to get configuration of compute cluster %s: %s", clusterRef.Value, err)
+	}
+	return &cluster, nil
+}
@@ -0,0 +1,79 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckClusterProactiveHAEnabled(t *testing.T) {
+	tests := []struct {
+		name            string
+		haConfig        *types.ClusterInfraUpdateHaConfigInfo
+		expectedMetrics string
+	}{
+		{
+			name:     "Proactive HA not configured",
+			haConfig: nil,
+			expectedMetrics: `
+# HELP vsphere_cluster_proactive_ha_enabled [ALPHA] Proactive HA status of vSphere compute clusters with node VMs, 1 when enabled, 0 otherwise.
+# TYPE vsphere_cluster_proactive_ha_enabled gauge
+vsphere_cluster_proactive_ha_enabled{cluster="DC0_C0",vcenter="dc0"} 0
+`,
+		},
+		{
+			name:     "Proactive HA disabled",
+			haConfig: &types.ClusterInfraUpdateHaConfigInfo{Enabled: types.NewBool(false)},
+			expectedMetrics: `
+# HELP vsphere_cluster_proactive_ha_enabled [ALPHA] Proactive HA status of vSphere compute clusters with node VMs, 1 when enabled, 0 otherwise.
+# TYPE vsphere_cluster_proactive_ha_enabled gauge
+vsphere_cluster_proactive_ha_enabled{cluster="DC0_C0",vcenter="dc0"} 0
+`,
+		},
+		{
+			name:     "Proactive HA enabled",
+			haConfig: &types.ClusterInfraUpdateHaConfigInfo{Enabled: types.NewBool(true), Behavior: "Automated"},
+			expectedMetrics: `
+# HELP vsphere_cluster_proactive_ha_enabled [ALPHA] Proactive HA status of vSphere compute clusters with node VMs, 1 when enabled, 0 otherwise.
+# TYPE vsphere_cluster_proactive_ha_enabled gauge
+vsphere_cluster_proactive_ha_enabled{cluster="DC0_C0",vcenter="dc0"} 1
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			obj := simulator.Map.Get(types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "clustercomputeresource-30"})
+			cluster := obj.(*simulator.ClusterComputeResource)
+			cluster.ConfigurationEx.(*types.ClusterConfigInfoEx).InfraUpdateHaConfig = test.haConfig
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckClusterProactiveHAEnabled(ctx)
+
+			// Assert
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_cluster_proactive_ha_enabled"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
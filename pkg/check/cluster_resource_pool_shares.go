@@ -0,0 +1,166 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	resourcePoolLabel = "resource_pool"
+	resourceLabel     = "resource"
+
+	resourceCPU    = "cpu"
+	resourceMemory = "memory"
+)
+
+var (
+	resourcePoolSharesRatioMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_resource_pool_shares_ratio",
+			Help:           "Ratio of shares of a resource pool with node VMs to the highest shares of its sibling resource pools. Values below 1 mean node VMs lose resource contention.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, resourcePoolLabel, resourceLabel},
+	)
+
+	// Number of shares of resource pools for predefined share levels, as documented by vSphere.
+	// Custom levels carry the number of shares explicitly.
+	resourcePoolSharesByLevel = map[string]map[vim.SharesLevel]int32{
+		resourceCPU: {
+			vim.SharesLevelLow:    2000,
+			vim.SharesLevelNormal: 4000,
+			vim.SharesLevelHigh:   8000,
+		},
+		resourceMemory: {
+			vim.SharesLevelLow:    81920,
+			vim.SharesLevelNormal: 163840,
+			vim.SharesLevelHigh:   327680,
+		},
+	}
+)
+
+func init() {
+	legacyregistry.MustRegister(resourcePoolSharesRatioMetric)
+}
+
+// CheckClusterResourcePoolSharesFairness compares CPU and memory shares of resource pools with node VMs
+// against their sibling resource pools. Under contention, a pool with fewer shares than its siblings
+// gets a smaller portion of the parent's resources and its node VMs get starved. Node VMs in the root
+// resource pool of a cluster are not checked, the root pool has no siblings.
+func CheckClusterResourcePoolSharesFairness(ctx *CheckContext) error {
+	vms, err := getNodeVMs(ctx, []string{"resourcePool"})
+	if err != nil {
+		return err
+	}
+	nodePools := make(map[vim.ManagedObjectReference]bool)
+	for _, vm := range vms {
+		if vm.ResourcePool != nil {
+			nodePools[*vm.ResourcePool] = true
+		}
+	}
+
+	// Reset series of the vCenter to drop pools that do not have node VMs any longer.
+	resetVCenterMetric(resourcePoolSharesRatioMetric, ctx.VMConfig.Workspace.VCenterIP)
+	var errs []error
+	for poolRef := range nodePools {
+		pool, siblings, err := getResourcePoolSiblings(ctx, poolRef, nodePools)
+		if err != nil {
+			return err
+		}
+		if len(siblings) == 0 {
+			klog.V(4).Infof("Resource pool %s has no sibling resource pools", pool.Name)
+			continue
+		}
+		for _, resource := range []string{resourceCPU, resourceMemory} {
+			shares := getResourcePoolShares(pool, resource)
+			var maxSibling *mo.ResourcePool
+			var maxSiblingShares int32
+			for i := range siblings {
+				siblingShares := getResourcePoolShares(&siblings[i], resource)
+				if maxSibling == nil || siblingShares > maxSiblingShares {
+					maxSibling = &siblings[i]
+					maxSiblingShares = siblingShares
+				}
+			}
+			if maxSiblingShares <= 0 {
+				continue
+			}
+			ratio := float64(shares) / float64(maxSiblingShares)
+			resourcePoolSharesRatioMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, pool.Name, resource).Set(ratio)
+			if shares < maxSiblingShares {
+				errs = append(errs, fmt.Errorf("resource pool %s with node VMs has %s shares %d, lower than %d shares of sibling resource pool %s: node VMs would lose %s contention", pool.Name, resource, shares, maxSiblingShares, maxSibling.Name, resource))
+				continue
+			}
+			klog.V(4).Infof("Resource pool %s has %s shares %d, sibling resource pool %s has %d", pool.Name, resource, shares, maxSibling.Name, maxSiblingShares)
+		}
+	}
+
+	// Make the error message stable
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	klog.V(2).Infof("CheckClusterResourcePoolSharesFairness checked %d resource pools, %d problems found", len(nodePools), len(errs))
+	return JoinErrors(errs)
+}
+
+// getResourcePoolSiblings returns the given resource pool and all other child pools (incl. vApps) of its
+// parent pool. Pools present in the skip map are not returned as siblings. Root resource pools of clusters
+// and hosts have no siblings.
+func getResourcePoolSiblings(ctx *CheckContext, poolRef vim.ManagedObjectReference, skip map[vim.ManagedObjectReference]bool) (*mo.ResourcePool, []mo.ResourcePool, error) {
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+
+	var pool mo.ResourcePool
+	if err := pc.RetrieveOne(tctx, poolRef, []string{"name", "parent", "config"}, &pool); err != nil {
+		return nil, nil, fmt.Errorf("failed to get resource pool %s: %s", poolRef.Value, err)
+	}
+	if pool.Parent == nil || (pool.Parent.Type != "ResourcePool" && pool.Parent.Type != "VirtualApp") {
+		return &pool, nil, nil
+	}
+
+	var parent mo.ResourcePool
+	if err := pc.RetrieveOne(tctx, *pool.Parent, []string{"resourcePool"}, &parent); err != nil {
+		return nil, nil, fmt.Errorf("failed to get parent of resource pool %s: %s", pool.Name, err)
+	}
+	var siblings []mo.ResourcePool
+	for _, ref := range parent.ResourcePool {
+		if ref == poolRef || skip[ref] {
+			continue
+		}
+		var sibling mo.ResourcePool
+		if err := pc.RetrieveOne(tctx, ref, []string{"name", "config"}, &sibling); err != nil {
+			return nil, nil, fmt.Errorf("failed to get sibling %s of resource pool %s: %s", ref.Value, pool.Name, err)
+		}
+		siblings = append(siblings, sibling)
+	}
+	sort.Slice(siblings, func(i, j int) bool { return siblings[i].Name < siblings[j].Name })
+	return &pool, siblings, nil
+}
+
+// getResourcePoolShares returns number of CPU or memory shares of a resource pool.
+func getResourcePoolShares(pool *mo.ResourcePool, resource string) int32 {
+	var allocation *vim.ResourceAllocationInfo
+	switch resource {
+	case resourceCPU:
+		allocation = &pool.Config.CpuAllocation
+	case resourceMemory:
+		allocation = &pool.Config.MemoryAllocation
+	}
+	if allocation == nil || allocation.Shares == nil {
+		return resourcePoolSharesByLevel[resource][vim.SharesLevelNormal]
+	}
+	if allocation.Shares.Level == vim.SharesLevelCustom {
+		return allocation.Shares.Shares
+	}
+	if shares, found := resourcePoolSharesByLevel[resource][allocation.Shares.Level]; found {
+		return shares
+	}
+	return allocation.Shares.Shares
+}
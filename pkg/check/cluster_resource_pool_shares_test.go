@@ -0,0 +1,106 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckClusterResourcePoolSharesFairness(t *testing.T) {
+	tests := []struct {
+		name            string
+		siblingCPU      types.SharesInfo
+		siblingMemory   types.SharesInfo
+		expectedError   string
+		expectedMetrics string
+	}{
+		{
+			name:          "same shares",
+			siblingCPU:    types.SharesInfo{Level: types.SharesLevelNormal},
+			siblingMemory: types.SharesInfo{Level: types.SharesLevelNormal},
+			expectedMetrics: `
+vsphere_resource_pool_shares_ratio{resource="cpu",resource_pool="DC0_C0_APP0",vcenter="dc0"} 1
+vsphere_resource_pool_shares_ratio{resource="memory",resource_pool="DC0_C0_APP0",vcenter="dc0"} 1
+`,
+		},
+		{
+			name:          "sibling with lower shares",
+			siblingCPU:    types.SharesInfo{Level: types.SharesLevelLow},
+			siblingMemory: types.SharesInfo{Level: types.SharesLevelCustom, Shares: 1000},
+			expectedMetrics: `
+vsphere_resource_pool_shares_ratio{resource="cpu",resource_pool="DC0_C0_APP0",vcenter="dc0"} 1
+vsphere_resource_pool_shares_ratio{resource="memory",resource_pool="DC0_C0_APP0",vcenter="dc0"} 1
+`,
+		},
+		{
+			name:          "sibling with high CPU shares",
+			siblingCPU:    types.SharesInfo{Level: types.SharesLevelHigh},
+			siblingMemory: types.SharesInfo{Level: types.SharesLevelNormal},
+			expectedError: "resource pool DC0_C0_APP0 with node VMs has cpu shares 4000, lower than 8000 shares of sibling resource pool DC0_C0_RP1: node VMs would lose cpu contention",
+			expectedMetrics: `
+vsphere_resource_pool_shares_ratio{resource="cpu",resource_pool="DC0_C0_APP0",vcenter="dc0"} 0.5
+vsphere_resource_pool_shares_ratio{resource="memory",resource_pool="DC0_C0_APP0",vcenter="dc0"} 1
+`,
+		},
+		{
+			name:          "sibling with custom memory shares",
+			siblingCPU:    types.SharesInfo{Level: types.SharesLevelNormal},
+			siblingMemory: types.SharesInfo{Level: types.SharesLevelCustom, Shares: 655360},
+			expectedError: "resource pool DC0_C0_APP0 with node VMs has memory shares 163840, lower than 655360 shares of sibling resource pool DC0_C0_RP1: node VMs would lose memory contention",
+			expectedMetrics: `
+vsphere_resource_pool_shares_ratio{resource="cpu",resource_pool="DC0_C0_APP0",vcenter="dc0"} 1
+vsphere_resource_pool_shares_ratio{resource="memory",resource_pool="DC0_C0_APP0",vcenter="dc0"} 0.25
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			// DC0_C0_APP0 vApp with node VMs has siblings DC0_C0_RP1 and DC0_C0_RP2 under the cluster root pool.
+			obj := simulator.Map.Get(types.ManagedObjectReference{Type: "ResourcePool", Value: "resgroup-55"})
+			sibling := obj.(*simulator.ResourcePool)
+			sibling.Config.CpuAllocation.Shares = &test.siblingCPU
+			sibling.Config.MemoryAllocation.Shares = &test.siblingMemory
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckClusterResourcePoolSharesFairness(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := `
+# HELP vsphere_resource_pool_shares_ratio [ALPHA] Ratio of shares of a resource pool with node VMs to the highest shares of its sibling resource pools. Values below 1 mean node VMs lose resource contention.
+# TYPE vsphere_resource_pool_shares_ratio gauge
+` + test.expectedMetrics
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_resource_pool_shares_ratio"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	vmcpEnabledMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_cluster_vmcp_enabled",
+			Help:           "VM Component Protection status of vSphere compute clusters with node VMs, 1 when VMs are restarted on APD and PDL, 0 otherwise.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, clusterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(vmcpEnabledMetric)
+}
+
+// CheckClusterVMComponentProtectionEnabled checks that VM Component Protection (VMCP) restarts VMs
+// on storage failures (APD and PDL) in compute clusters that run node VMs. Without it, node VMs hang
+// indefinitely when they lose their storage.
+// The check is advisory, it only logs a warning and reports the metric when VMCP is disabled.
+func CheckClusterVMComponentProtectionEnabled(ctx *CheckContext) error {
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Reset series of the vCenter to drop clusters that do not run nodes any longer.
+	resetVCenterMetric(vmcpEnabledMetric, ctx.VMConfig.Workspace.VCenterIP)
+	var errs []error
+	for _, clusterRef := range clusterRefs {
+		cluster, err := getClusterConfigurationEx(ctx, clusterRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var problems []string
+		if config, ok := cluster.ConfigurationEx.(*vim.ClusterConfigInfoEx); ok {
+			problems = getVMCPProblems(&config.DasConfig)
+		} else {
+			problems = []string{"cluster configuration is not available"}
+		}
+		if len(problems) == 0 {
+			vmcpEnabledMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, cluster.Name).Set(1)
+			klog.V(4).Infof("Compute cluster %s has VM Component Protection enabled", cluster.Name)
+		} else {
+			vmcpEnabledMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, cluster.Name).Set(0)
+			klog.Warningf("Compute cluster %s does not restart node VMs on storage failures: %s", cluster.Name, strings.Join(problems, ", "))
+		}
+	}
+	return JoinErrors(errs)
+}
+
+// getVMCPProblems returns list of VMCP settings that prevent restart of VMs on APD and PDL.
+func getVMCPProblems(das *vim.ClusterDasConfigInfo) []string {
+	if das.Enabled == nil || !*das.Enabled {
+		return []string{"vSphere HA is disabled"}
+	}
+	if das.VmComponentProtecting != string(vim.ClusterDasConfigInfoServiceStateEnabled) {
+		return []string{fmt.Sprintf("vmComponentProtecting is %q", das.VmComponentProtecting)}
+	}
+
+	var settings vim.ClusterVmComponentProtectionSettings
+	if das.DefaultVmSettings != nil && das.DefaultVmSettings.VmComponentProtectionSettings != nil {
+		settings = *das.DefaultVmSettings.VmComponentProtectionSettings
+	}
+	var problems []string
+	if !isVMCPRestart(settings.VmStorageProtectionForAPD) {
+		problems = append(problems, fmt.Sprintf("vmStorageProtectionForAPD is %q", settings.VmStorageProtectionForAPD))
+	}
+	if !isVMCPRestart(settings.VmStorageProtectionForPDL) {
+		problems = append(problems, fmt.Sprintf("vmStorageProtectionForPDL is %q", settings.VmStorageProtectionForPDL))
+	}
+	return problems
+}
+
+// isVMCPRestart returns true if the VMCP reaction restarts affected VMs.
+func isVMCPRestart(reaction string) bool {
+	switch vim.ClusterVmComponentProtectionSettingsStorageVmReaction(reaction) {
+	case vim.ClusterVmComponentProtectionSettingsStorageVmReactionRestartConservative,
+		vim.ClusterVmComponentProtectionSettingsStorageVmReactionRestartAggressive:
+		return true
+	}
+	return false
+}
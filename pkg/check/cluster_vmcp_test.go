@@ -0,0 +1,100 @@
+package check
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func vmcpDasConfig(haEnabled bool, vmcp string, apd, pdl string) types.ClusterDasConfigInfo {
+	return types.ClusterDasConfigInfo{
+		Enabled:               types.NewBool(haEnabled),
+		VmComponentProtecting: vmcp,
+		DefaultVmSettings: &types.ClusterDasVmSettings{
+			VmComponentProtectionSettings: &types.ClusterVmComponentProtectionSettings{
+				VmStorageProtectionForAPD: apd,
+				VmStorageProtectionForPDL: pdl,
+			},
+		},
+	}
+}
+
+func TestCheckClusterVMComponentProtectionEnabled(t *testing.T) {
+	tests := []struct {
+		name             string
+		dasConfig        types.ClusterDasConfigInfo
+		expectedProblems []string
+	}{
+		{
+			name:             "HA disabled",
+			dasConfig:        vmcpDasConfig(false, "enabled", "restartConservative", "restartAggressive"),
+			expectedProblems: []string{"vSphere HA is disabled"},
+		},
+		{
+			name:             "VMCP disabled",
+			dasConfig:        vmcpDasConfig(true, "disabled", "restartConservative", "restartAggressive"),
+			expectedProblems: []string{`vmComponentProtecting is "disabled"`},
+		},
+		{
+			name:             "VMCP only logs",
+			dasConfig:        vmcpDasConfig(true, "enabled", "warning", "disabled"),
+			expectedProblems: []string{`vmStorageProtectionForAPD is "warning"`, `vmStorageProtectionForPDL is "disabled"`},
+		},
+		{
+			name:             "VMCP restarts VMs",
+			dasConfig:        vmcpDasConfig(true, "enabled", "restartConservative", "restartAggressive"),
+			expectedProblems: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			obj := simulator.Map.Get(types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "clustercomputeresource-30"})
+			cluster := obj.(*simulator.ClusterComputeResource)
+			cluster.ConfigurationEx.(*types.ClusterConfigInfoEx).DasConfig = test.dasConfig
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			problems := getVMCPProblems(&test.dasConfig)
+			err = CheckClusterVMComponentProtectionEnabled(ctx)
+
+			// Assert
+			if !reflect.DeepEqual(problems, test.expectedProblems) {
+				t.Errorf("Expected problems %q, got %q", test.expectedProblems, problems)
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			enabled := 0
+			if len(test.expectedProblems) == 0 {
+				enabled = 1
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_cluster_vmcp_enabled [ALPHA] VM Component Protection status of vSphere compute clusters with node VMs, 1 when VMs are restarted on APD and PDL, 0 otherwise.
+# TYPE vsphere_cluster_vmcp_enabled gauge
+vsphere_cluster_vmcp_enabled{cluster="DC0_C0",vcenter="dc0"} %d
+`, enabled)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_cluster_vmcp_enabled"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	vsanDiskGroupHealthy   = "healthy"
+	vsanDiskGroupDegraded  = "degraded"
+	vsanDiskGroupAbsent    = "absent"
+	vsanDiskGroupUnmounted = "unmounted"
+)
+
+var (
+	vsanUnhealthyDiskGroupsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vsan_unhealthy_disk_groups_total",
+			Help:           "Number of unhealthy vSAN disk groups in compute clusters with node VMs.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, clusterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(vsanUnhealthyDiskGroupsMetric)
+}
+
+// CheckClusterVsanDiskGroupHealth tests that vSAN disk groups of compute clusters that run node VMs are healthy.
+// A degraded or absent disk group reduces redundancy of vSAN objects of node VMs and volumes, or makes them
+// inaccessible. The vSAN health service is not part of the vSphere API available to the check, it evaluates
+// disk groups from the vSAN status of their disks reported by each ESXi host.
+// Compute clusters without vSAN are skipped.
+func CheckClusterVsanDiskGroupHealth(ctx *CheckContext) error {
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Reset series of the vCenter to drop clusters that do not run nodes any longer.
+	resetVCenterMetric(vsanUnhealthyDiskGroupsMetric, ctx.VMConfig.Workspace.VCenterIP)
+	var errs []error
+	for _, clusterRef := range clusterRefs {
+		cluster, err := getClusterConfigurationEx(ctx, clusterRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !isClusterVsanEnabled(cluster) {
+			klog.V(4).Infof("Compute cluster %s does not have vSAN enabled, skipping disk groups", cluster.Name)
+			continue
+		}
+		_, hosts, err := getComputeClusterHosts(ctx, clusterRef, []string{"name", "configManager.vsanSystem", "config.vsanHostConfig"})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		unhealthy := 0
+		for _, host := range hosts {
+			if host.Config == nil || host.Config.VsanHostConfig == nil || host.Config.VsanHostConfig.StorageInfo == nil {
+				continue
+			}
+			diskGroups := host.Config.VsanHostConfig.StorageInfo.DiskMapInfo
+			if len(diskGroups) == 0 {
+				continue
+			}
+			disks, err := queryVsanDisks(ctx, &host, diskGroups)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, diskGroup := range diskGroups {
+				health, problemDisks := getVsanDiskGroupHealth(diskGroup, disks)
+				if health == vsanDiskGroupHealthy {
+					klog.V(4).Infof("vSAN disk group %s on ESXi host %s in compute cluster %s is healthy", diskGroup.Mapping.Ssd.CanonicalName, host.Name, cluster.Name)
+					continue
+				}
+				unhealthy++
+				msg := fmt.Sprintf("compute cluster %s: vSAN disk group with cache disk %s on ESXi host %s is %s", cluster.Name, diskGroup.Mapping.Ssd.CanonicalName, host.Name, health)
+				if len(problemDisks) > 0 {
+					msg += ": " + strings.Join(problemDisks, ", ")
+				}
+				errs = append(errs, fmt.Errorf("%s", msg))
+			}
+		}
+		vsanUnhealthyDiskGroupsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, cluster.Name).Set(float64(unhealthy))
+	}
+	return JoinErrors(errs)
+}
+
+// isClusterVsanEnabled returns true if vSAN is enabled in the compute cluster. The cluster must have configurationEx filled.
+func isClusterVsanEnabled(cluster *mo.ClusterComputeResource) bool {
+	config, ok := cluster.ConfigurationEx.(*vim.ClusterConfigInfoEx)
+	if !ok || config.VsanConfigInfo == nil || config.VsanConfigInfo.Enabled == nil {
+		return false
+	}
+	return *config.VsanConfigInfo.Enabled
+}
+
+// queryVsanDisks returns vSAN status of all disks of given disk groups on the host, disk canonical name -> status.
+func queryVsanDisks(ctx *CheckContext, host *mo.HostSystem, diskGroups []vim.VsanHostDiskMapInfo) (map[string]vim.VsanHostDiskResult, error) {
+	if host.ConfigManager.VsanSystem == nil {
+		return nil, fmt.Errorf("ESXi host %s has vSAN disk groups, but no vSAN system", host.Name)
+	}
+	var names []string
+	for _, diskGroup := range diskGroups {
+		names = append(names, diskGroup.Mapping.Ssd.CanonicalName)
+		for _, disk := range diskGroup.Mapping.NonSsd {
+			names = append(names, disk.CanonicalName)
+		}
+	}
+
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	req := vim.QueryDisksForVsan{
+		This:          *host.ConfigManager.VsanSystem,
+		CanonicalName: names,
+	}
+	res, err := methods.QueryDisksForVsan(tctx, ctx.VMClient, &req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vSAN disks of ESXi host %s: %s", host.Name, err)
+	}
+	disks := make(map[string]vim.VsanHostDiskResult)
+	for _, disk := range res.Returnval {
+		disks[disk.Disk.CanonicalName] = disk
+	}
+	return disks, nil
+}
+
+// getVsanDiskGroupHealth returns health of a vSAN disk group and descriptions of its disks that are not healthy.
+// A disk group is absent when any of its disks is not reported or not used by vSAN any longer,
+// and degraded when any of its disks is degraded or reports an error.
+func getVsanDiskGroupHealth(diskGroup vim.VsanHostDiskMapInfo, disks map[string]vim.VsanHostDiskResult) (string, []string) {
+	if !diskGroup.Mounted {
+		return vsanDiskGroupUnmounted, nil
+	}
+
+	health := vsanDiskGroupHealthy
+	var problemDisks []string
+	groupDisks := append([]vim.HostScsiDisk{diskGroup.Mapping.Ssd}, diskGroup.Mapping.NonSsd...)
+	for _, groupDisk := range groupDisks {
+		name := groupDisk.CanonicalName
+		disk, found := disks[name]
+		switch {
+		case !found:
+			health = vsanDiskGroupAbsent
+			problemDisks = append(problemDisks, fmt.Sprintf("disk %s not found", name))
+		case disk.State != string(vim.VsanHostDiskResultStateInUse):
+			health = vsanDiskGroupAbsent
+			problemDisks = append(problemDisks, fmt.Sprintf("disk %s is %s", name, disk.State))
+		case disk.Error != nil:
+			if health == vsanDiskGroupHealthy {
+				health = vsanDiskGroupDegraded
+			}
+			problemDisks = append(problemDisks, fmt.Sprintf("disk %s has error %s", name, disk.Error.LocalizedMessage))
+		case disk.Degraded != nil && *disk.Degraded:
+			if health == vsanDiskGroupHealthy {
+				health = vsanDiskGroupDegraded
+			}
+			problemDisks = append(problemDisks, fmt.Sprintf("disk %s is degraded", name))
+		}
+	}
+	sort.Strings(problemDisks)
+	return health, problemDisks
+}
@@ -0,0 +1,138 @@
+package check
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckClusterVsanDiskGroupHealthNoVsan(t *testing.T) {
+	// Stage
+	kubeClient := &fakeKubeClient{
+		infrastructure: infrastructure(),
+		nodes:          defaultNodes(),
+	}
+	ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+
+	// Act
+	err = CheckClusterVsanDiskGroupHealth(ctx)
+
+	// Assert
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	// Clusters without vSAN are not reported
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(""), "vsphere_vsan_unhealthy_disk_groups_total"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+}
+
+func TestGetVsanDiskGroupHealth(t *testing.T) {
+	trueVal := true
+	falseVal := false
+	scsiDisk := func(name string) vim.HostScsiDisk {
+		disk := vim.HostScsiDisk{}
+		disk.CanonicalName = name
+		return disk
+	}
+	diskResult := func(name string, state vim.VsanHostDiskResultState, degraded *bool) vim.VsanHostDiskResult {
+		return vim.VsanHostDiskResult{
+			Disk:     scsiDisk(name),
+			State:    string(state),
+			Degraded: degraded,
+		}
+	}
+	diskGroup := vim.VsanHostDiskMapInfo{
+		Mapping: vim.VsanHostDiskMapping{
+			Ssd:    scsiDisk("naa.cache"),
+			NonSsd: []vim.HostScsiDisk{scsiDisk("naa.capacity0"), scsiDisk("naa.capacity1")},
+		},
+		Mounted: true,
+	}
+
+	tests := []struct {
+		name                 string
+		unmounted            bool
+		disks                []vim.VsanHostDiskResult
+		expectedHealth       string
+		expectedProblemDisks []string
+	}{
+		{
+			name: "healthy disk group",
+			disks: []vim.VsanHostDiskResult{
+				diskResult("naa.cache", vim.VsanHostDiskResultStateInUse, &falseVal),
+				diskResult("naa.capacity0", vim.VsanHostDiskResultStateInUse, &falseVal),
+				diskResult("naa.capacity1", vim.VsanHostDiskResultStateInUse, nil),
+			},
+			expectedHealth: vsanDiskGroupHealthy,
+		},
+		{
+			name:           "unmounted disk group",
+			unmounted:      true,
+			expectedHealth: vsanDiskGroupUnmounted,
+		},
+		{
+			name: "degraded capacity disk",
+			disks: []vim.VsanHostDiskResult{
+				diskResult("naa.cache", vim.VsanHostDiskResultStateInUse, &falseVal),
+				diskResult("naa.capacity0", vim.VsanHostDiskResultStateInUse, &trueVal),
+				diskResult("naa.capacity1", vim.VsanHostDiskResultStateInUse, &falseVal),
+			},
+			expectedHealth:       vsanDiskGroupDegraded,
+			expectedProblemDisks: []string{"disk naa.capacity0 is degraded"},
+		},
+		{
+			name: "disk with error",
+			disks: []vim.VsanHostDiskResult{
+				diskResult("naa.cache", vim.VsanHostDiskResultStateInUse, &falseVal),
+				{
+					Disk:  scsiDisk("naa.capacity0"),
+					State: string(vim.VsanHostDiskResultStateInUse),
+					Error: &vim.LocalizedMethodFault{LocalizedMessage: "I/O error"},
+				},
+				diskResult("naa.capacity1", vim.VsanHostDiskResultStateInUse, &falseVal),
+			},
+			expectedHealth:       vsanDiskGroupDegraded,
+			expectedProblemDisks: []string{"disk naa.capacity0 has error I/O error"},
+		},
+		{
+			name: "missing cache disk and degraded capacity disk",
+			disks: []vim.VsanHostDiskResult{
+				diskResult("naa.capacity0", vim.VsanHostDiskResultStateInUse, &trueVal),
+				diskResult("naa.capacity1", vim.VsanHostDiskResultStateEligible, nil),
+			},
+			expectedHealth:       vsanDiskGroupAbsent,
+			expectedProblemDisks: []string{"disk naa.cache not found", "disk naa.capacity0 is degraded", "disk naa.capacity1 is eligible"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			group := diskGroup
+			group.Mounted = !test.unmounted
+			disks := make(map[string]vim.VsanHostDiskResult)
+			for _, disk := range test.disks {
+				disks[disk.Disk.CanonicalName] = disk
+			}
+
+			health, problemDisks := getVsanDiskGroupHealth(group, disks)
+			if health != test.expectedHealth {
+				t.Errorf("Expected health %s, got %s", test.expectedHealth, health)
+			}
+			if !reflect.DeepEqual(problemDisks, test.expectedProblemDisks) {
+				t.Errorf("Expected problem disks %v, got %v", test.expectedProblemDisks, problemDisks)
+			}
+		})
+	}
+}
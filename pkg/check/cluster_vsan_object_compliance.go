@@ -0,0 +1,151 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	vim "github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/govmomi/vsan"
+	vsantypes "github.com/vmware/govmomi/vsan/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Compliance status of vSAN objects that do not match their storage policy.
+	vsanObjectNonCompliant = "nonCompliant"
+)
+
+var (
+	vsanNonCompliantObjectsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vsan_noncompliant_node_objects_total",
+			Help:           "Number of vSAN objects of node VMs that are not healthy, are resyncing or do not comply with their storage policy.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, clusterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(vsanNonCompliantObjectsMetric)
+}
+
+// CheckClusterVsanObjectComplianceForNodeVMs tests that vSAN objects of node VMs, such as their home namespaces,
+// disks and swap files, are healthy and comply with their storage policies. Objects with reduced availability
+// or that are resyncing after a failure are not protected as the policy requires.
+// Compute clusters without vSAN are skipped.
+func CheckClusterVsanObjectComplianceForNodeVMs(ctx *CheckContext) error {
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return err
+	}
+	nodeVMs, err := getNodeVMNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Reset series of the vCenter to drop clusters that do not run nodes any longer.
+	resetVCenterMetric(vsanNonCompliantObjectsMetric, ctx.VMConfig.Workspace.VCenterIP)
+	var vsanClient *vsan.Client
+	var errs []error
+	for _, clusterRef := range clusterRefs {
+		cluster, err := getClusterConfigurationEx(ctx, clusterRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !isClusterVsanEnabled(cluster) {
+			klog.V(4).Infof("Compute cluster %s does not have vSAN enabled, skipping vSAN objects", cluster.Name)
+			continue
+		}
+		if vsanClient == nil {
+			vsanClient, err = newVsanClient(ctx.Context, ctx.VMClient)
+			if err != nil {
+				return fmt.Errorf("failed to create vSAN client: %s", err)
+			}
+		}
+
+		tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+		objects, err := vsanClient.VsanQueryObjectIdentities(tctx, clusterRef)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("compute cluster %s: failed to query vSAN objects: %s", cluster.Name, err))
+			continue
+		}
+		problems := getVsanNodeObjectProblems(objects, nodeVMs)
+		vsanNonCompliantObjectsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, cluster.Name).Set(float64(len(problems)))
+		for _, problem := range problems {
+			errs = append(errs, fmt.Errorf("compute cluster %s: %s", cluster.Name, problem))
+		}
+	}
+	return JoinErrors(errs)
+}
+
+// getNodeVMNames returns references to VMs of all nodes, VM -> node name. Node VMs that cannot
+// be found are skipped, node checks report them.
+func getNodeVMNames(ctx *CheckContext) (map[vim.ManagedObjectReference]string, error) {
+	refs, err := getNodeVMRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vms := make(map[vim.ManagedObjectReference]string)
+	for _, ref := range refs {
+		vms[ref.vmRef] = ref.node.Name
+	}
+	return vms, nil
+}
+
+// getVsanNodeObjectProblems returns sorted descriptions of vSAN objects of node VMs that are not healthy
+// or that do not comply with their storage policy, one for each object.
+func getVsanNodeObjectProblems(objects *vsantypes.VsanObjectIdentityAndHealth, nodeVMs map[vim.ManagedObjectReference]string) []string {
+	if objects == nil || objects.Health == nil {
+		return nil
+	}
+	// vSAN object UUID -> its identity, only for objects of node VMs
+	identities := make(map[string]vsantypes.VsanObjectIdentity)
+	for _, identity := range objects.Identities {
+		if identity.Vm == nil {
+			continue
+		}
+		if _, found := nodeVMs[*identity.Vm]; found {
+			identities[identity.Uuid] = identity
+		}
+	}
+
+	// vSAN object UUID -> list of its states
+	states := make(map[string][]string)
+	for _, health := range objects.Health.ObjectHealthDetail {
+		if health.Health == string(vsantypes.VsanObjectHealthStatehealthy) {
+			continue
+		}
+		for _, uuid := range health.ObjUuids {
+			if _, found := identities[uuid]; found {
+				states[uuid] = append(states[uuid], health.Health)
+			}
+		}
+	}
+	for _, compliance := range objects.Health.ObjectsComplianceDetail {
+		if compliance.ComplianceStatus != vsanObjectNonCompliant {
+			continue
+		}
+		if _, found := identities[compliance.ObjectUUID]; found {
+			states[compliance.ObjectUUID] = append(states[compliance.ObjectUUID], fmt.Sprintf("%s with storage policy %s", compliance.ComplianceStatus, compliance.Profile))
+		}
+	}
+
+	var problems []string
+	for uuid, objectStates := range states {
+		identity := identities[uuid]
+		object := fmt.Sprintf("vSAN object %s (%s)", uuid, identity.Type)
+		if identity.Description != "" {
+			object = fmt.Sprintf("vSAN object %s (%s %s)", uuid, identity.Type, identity.Description)
+		}
+		problems = append(problems, fmt.Sprintf("node %s: %s is %s", nodeVMs[*identity.Vm], object, strings.Join(objectStates, ", ")))
+	}
+	sort.Strings(problems)
+	return problems
+}
@@ -0,0 +1,106 @@
+package check
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	vim "github.com/vmware/govmomi/vim25/types"
+	vsantypes "github.com/vmware/govmomi/vsan/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckClusterVsanObjectComplianceForNodeVMsNoVsan(t *testing.T) {
+	// Stage
+	kubeClient := &fakeKubeClient{
+		infrastructure: infrastructure(),
+		nodes:          clusterNodes(),
+	}
+	ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+
+	// Act
+	err = CheckClusterVsanObjectComplianceForNodeVMs(ctx)
+
+	// Assert
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	// Clusters without vSAN are not reported
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(""), "vsphere_vsan_noncompliant_node_objects_total"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+}
+
+func TestGetVsanNodeObjectProblems(t *testing.T) {
+	nodeVM := vim.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-1"}
+	otherVM := vim.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-2"}
+	nodeVMs := map[vim.ManagedObjectReference]string{nodeVM: "node1"}
+	identities := []vsantypes.VsanObjectIdentity{
+		{Uuid: "uuid-home", Type: "vmnamespace", Vm: &nodeVM},
+		{Uuid: "uuid-disk", Type: "vdisk", Description: "node1/node1.vmdk", Vm: &nodeVM},
+		{Uuid: "uuid-other", Type: "vdisk", Vm: &otherVM},
+		{Uuid: "uuid-unassociated", Type: "other"},
+	}
+
+	tests := []struct {
+		name             string
+		health           *vsantypes.VsanObjectOverallHealth
+		expectedProblems []string
+	}{
+		{
+			name: "no health",
+		},
+		{
+			name: "healthy objects",
+			health: &vsantypes.VsanObjectOverallHealth{
+				ObjectHealthDetail: []vsantypes.VsanObjectHealth{
+					{NumObjects: 4, Health: "healthy", ObjUuids: []string{"uuid-home", "uuid-disk", "uuid-other", "uuid-unassociated"}},
+					{NumObjects: 0, Health: "inaccessible"},
+				},
+				ObjectsComplianceDetail: []vsantypes.VsanStorageComplianceResult{
+					{ObjectUUID: "uuid-disk", ComplianceStatus: "compliant", Profile: "policy"},
+				},
+			},
+		},
+		{
+			name: "resyncing and non-compliant objects",
+			health: &vsantypes.VsanObjectOverallHealth{
+				ObjectHealthDetail: []vsantypes.VsanObjectHealth{
+					{NumObjects: 1, Health: "healthy", ObjUuids: []string{"uuid-home"}},
+					{NumObjects: 2, Health: "reducedavailabilitywithactiverebuild", ObjUuids: []string{"uuid-disk", "uuid-other"}},
+					{NumObjects: 1, Health: "inaccessible", ObjUuids: []string{"uuid-unassociated"}},
+				},
+				ObjectsComplianceDetail: []vsantypes.VsanStorageComplianceResult{
+					{ObjectUUID: "uuid-home", ComplianceStatus: "nonCompliant", Profile: "policy"},
+					{ObjectUUID: "uuid-disk", ComplianceStatus: "nonCompliant", Profile: "policy"},
+					{ObjectUUID: "uuid-other", ComplianceStatus: "nonCompliant", Profile: "policy"},
+				},
+			},
+			expectedProblems: []string{
+				"node node1: vSAN object uuid-disk (vdisk node1/node1.vmdk) is reducedavailabilitywithactiverebuild, nonCompliant with storage policy policy",
+				"node node1: vSAN object uuid-home (vmnamespace) is nonCompliant with storage policy policy",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objects := &vsantypes.VsanObjectIdentityAndHealth{
+				Identities: identities,
+				Health:     test.health,
+			}
+			problems := getVsanNodeObjectProblems(objects, nodeVMs)
+			if !reflect.DeepEqual(problems, test.expectedProblems) {
+				t.Errorf("Expected problems %v, got %v", test.expectedProblems, problems)
+			}
+		})
+	}
+}
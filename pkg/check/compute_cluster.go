@@ -0,0 +1,308 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// NodeVMMatchProviderID means that the VM of a node was found by UUID in the node's ProviderID.
+	NodeVMMatchProviderID = "provider_id"
+	// NodeVMMatchName means that the node has no ProviderID and its VM was found by the node name.
+	NodeVMMatchName = "name"
+)
+
+// getNodeVMRef returns reference to the VM of given node. See FindNodeVM.
+func getNodeVMRef(ctx *CheckContext, dc *object.Datacenter, node *v1.Node) (vim.ManagedObjectReference, error) {
+	ref, _, err := FindNodeVM(ctx, dc, node)
+	return ref, err
+}
+
+// FindNodeVM returns reference to the VM of given node and how the VM was matched to the node.
+// The VM is found by UUID in the node's ProviderID. Nodes with empty ProviderID, for example when
+// the cloud provider has not initialized them yet, are matched to a VM with the same name, if BIOS
+// UUID of the VM matches system UUID of the node.
+func FindNodeVM(ctx *CheckContext, dc *object.Datacenter, node *v1.Node) (vim.ManagedObjectReference, string, error) {
+	if node.Spec.ProviderID == "" {
+		ref, err := findNodeVMByName(ctx, dc, node)
+		return ref, NodeVMMatchName, err
+	}
+
+	s := object.NewSearchIndex(ctx.VMClient)
+	vmUUID := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(node.Spec.ProviderID, "vsphere://")))
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	svm, err := s.FindByUuid(tctx, dc, vmUUID, true, nil)
+	if err != nil {
+		return vim.ManagedObjectReference{}, NodeVMMatchProviderID, fmt.Errorf("failed to find VM by UUID %s: %s", vmUUID, err)
+	}
+	if svm == nil {
+		return vim.ManagedObjectReference{}, NodeVMMatchProviderID, fmt.Errorf("unable to find VM by UUID %s", vmUUID)
+	}
+	return svm.Reference(), NodeVMMatchProviderID, nil
+}
+
+// findNodeVMByName returns reference to the VM with the same name as the node. BIOS UUID of the VM
+// must match system UUID of the node, when the node reports it.
+func findNodeVMByName(ctx *CheckContext, dc *object.Datacenter, node *v1.Node) (vim.ManagedObjectReference, error) {
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	finder := find.NewFinder(ctx.VMClient, false)
+	finder.SetDatacenter(dc)
+	vm, err := finder.VirtualMachine(tctx, node.Name)
+	if err != nil {
+		return vim.ManagedObjectReference{}, fmt.Errorf("node has no ProviderID and failed to find VM by name %s: %s", node.Name, err)
+	}
+
+	var vmMo mo.VirtualMachine
+	pc := property.DefaultCollector(ctx.VMClient)
+	if err := pc.RetrieveOne(tctx, vm.Reference(), []string{"config.uuid"}, &vmMo); err != nil {
+		return vim.ManagedObjectReference{}, fmt.Errorf("failed to get BIOS UUID of VM %s: %s", node.Name, err)
+	}
+	systemUUID := node.Status.NodeInfo.SystemUUID
+	if systemUUID == "" {
+		klog.V(4).Infof("Node %s does not report system UUID, matched VM %s by name only", node.Name, vm.Reference().Value)
+		return vm.Reference(), nil
+	}
+	if vmMo.Config == nil || !isSameBIOSUUID(vmMo.Config.Uuid, systemUUID) {
+		biosUUID := ""
+		if vmMo.Config != nil {
+			biosUUID = vmMo.Config.Uuid
+		}
+		return vim.ManagedObjectReference{}, fmt.Errorf("node has no ProviderID and VM %s found by name has BIOS UUID %q, while the node reports system UUID %q", node.Name, biosUUID, systemUUID)
+	}
+	return vm.Reference(), nil
+}
+
+// isSameBIOSUUID returns true if the BIOS UUID of a VM and system UUID reported by its guest OS are the same.
+// Guests of VMs with old hardware versions report the first three fields of the UUID byte swapped.
+func isSameBIOSUUID(biosUUID, systemUUID string) bool {
+	biosUUID = strings.ToLower(strings.TrimSpace(biosUUID))
+	systemUUID = strings.ToLower(strings.TrimSpace(systemUUID))
+	if biosUUID == systemUUID {
+		return true
+	}
+	fields := strings.Split(biosUUID, "-")
+	if len(fields) != 5 {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		fields[i] = swapUUIDBytes(fields[i])
+	}
+	return strings.Join(fields, "-") == systemUUID
+}
+
+// swapUUIDBytes reverses order of bytes in a hex encoded UUID field.
+func swapUUIDBytes(field string) string {
+	var swapped strings.Builder
+	for i := len(field); i >= 2; i -= 2 {
+		swapped.WriteString(field[i-2 : i])
+	}
+	return swapped.String()
+}
+
+// nodeVMRef is reference to the VM of a node.
+type nodeVMRef struct {
+	node  *v1.Node
+	vmRef vim.ManagedObjectReference
+}
+
+// getNodeVMRefs returns references to VMs of all nodes that run in the vCenter of ctx, in the order of nodes.
+// They are resolved once per round and shared by cluster checks through VMCache.
+func getNodeVMRefs(ctx *CheckContext) ([]nodeVMRef, error) {
+	if ctx.VMCache == nil {
+		return resolveNodeVMRefs(ctx)
+	}
+	return ctx.VMCache.getNodeVMRefs(ctx)
+}
+
+// resolveNodeVMRefs finds VMs of all nodes that run in the vCenter of ctx, in the order of nodes.
+// All datacenters of the vCenter in VMConfig are searched, starting with the Workspace datacenter.
+// Only the Workspace datacenter must exist, the others are skipped when they cannot be accessed.
+// Node VMs that cannot be found, for example because they run in another vCenter, are skipped,
+// node checks report them.
+func resolveNodeVMRefs(ctx *CheckContext) ([]nodeVMRef, error) {
+	nodes, err := ctx.KubeClient.ListNodes(ctx.Context)
+	if err != nil {
+		return nil, fmt.Errorf("error listing nodes: %s", err)
+	}
+	dcNames := getVCenterDatacenters(ctx, ctx.VMConfig.Workspace.VCenterIP)
+	if len(dcNames) == 0 {
+		dcNames = []string{ctx.VMConfig.Workspace.Datacenter}
+	}
+	var datacenters []*object.Datacenter
+	var errs []error
+	for _, dcName := range dcNames {
+		dc, err := getDatacenter(ctx, dcName)
+		if err != nil {
+			if dcName == ctx.VMConfig.Workspace.Datacenter {
+				return nil, err
+			}
+			klog.V(2).Infof("Skipping datacenter %s: %s", dcName, err)
+			errs = append(errs, err)
+			continue
+		}
+		datacenters = append(datacenters, dc)
+	}
+	if len(datacenters) == 0 {
+		return nil, JoinErrors(errs)
+	}
+
+	var refs []nodeVMRef
+	for _, node := range nodes {
+		var vmRef vim.ManagedObjectReference
+		var errs []error
+		for _, dc := range datacenters {
+			vmRef, err = getNodeVMRef(ctx, dc, node)
+			if err == nil {
+				break
+			}
+			errs = append(errs, err)
+		}
+		if err != nil {
+			klog.V(2).Infof("Skipping node %s: %s", node.Name, JoinErrors(errs))
+			continue
+		}
+		refs = append(refs, nodeVMRef{node: node, vmRef: vmRef})
+	}
+	return refs, nil
+}
+
+// getNodeVMs returns VMs of all nodes with given properties. Node VMs that cannot
+// be found are skipped, node checks report them. VMs are read from VMCache when all
+// properties are in NodeProperties.
+func getNodeVMs(ctx *CheckContext, properties []string) ([]mo.VirtualMachine, error) {
+	refs, err := getNodeVMRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := sets.NewString(NodeProperties...).HasAll(properties...)
+	pc := property.DefaultCollector(ctx.VMClient)
+	var vms []mo.VirtualMachine
+	for _, ref := range refs {
+		if cached {
+			vm, err := ctx.GetVM(ref.vmRef)
+			if err != nil {
+				klog.V(2).Infof("Skipping node %s: %s", ref.node.Name, err)
+				continue
+			}
+			vms = append(vms, *vm)
+			continue
+		}
+		var vm mo.VirtualMachine
+		tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+		err = pc.RetrieveOne(tctx, ref.vmRef, properties, &vm)
+		cancel()
+		if err != nil {
+			klog.V(2).Infof("Skipping node %s: failed to get VM properties: %s", ref.node.Name, err)
+			continue
+		}
+		vms = append(vms, vm)
+	}
+	return vms, nil
+}
+
+// getNodeComputeClusters returns references to all compute clusters that host at least one node VM,
+// sorted by their ID. Node VMs that run on standalone hosts or that cannot be found are skipped,
+// node checks report them.
+func getNodeComputeClusters(ctx *CheckContext) ([]vim.ManagedObjectReference, error) {
+	vms, err := getNodeVMs(ctx, []string{"runtime.host"})
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[vim.ManagedObjectReference]bool)
+	for _, vm := range vms {
+		if vm.Runtime.Host != nil {
+			hosts[*vm.Runtime.Host] = true
+		}
+	}
+
+	pc := property.DefaultCollector(ctx.VMClient)
+	clusters := make(map[vim.ManagedObjectReference]bool)
+	for hostRef := range hosts {
+		var host mo.HostSystem
+		tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+		err = pc.RetrieveOne(tctx, hostRef, []string{"parent"}, &host)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent of host %s: %s", hostRef.Value, err)
+		}
+		if host.Parent != nil && host.Parent.Type == "ClusterComputeResource" {
+			clusters[*host.Parent] = true
+		}
+	}
+
+	var refs []vim.ManagedObjectReference
+	for ref := range clusters {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Value < refs[j].Value })
+	return refs, nil
+}
+
+// getNodeDatastores returns references to all datastores used by node VMs and the default datastore
+// from vSphere configuration, sorted by their ID.
+func getNodeDatastores(ctx *CheckContext) ([]vim.ManagedObjectReference, error) {
+	vms, err := getNodeVMs(ctx, []string{"datastore"})
+	if err != nil {
+		return nil, err
+	}
+	datastores := make(map[vim.ManagedObjectReference]bool)
+	for _, vm := range vms {
+		for _, ref := range vm.Datastore {
+			datastores[ref] = true
+		}
+	}
+
+	if dsName := ctx.VMConfig.Workspace.DefaultDatastore; dsName != "" {
+		dc, err := getDatacenter(ctx, ctx.VMConfig.Workspace.Datacenter)
+		if err != nil {
+			return nil, err
+		}
+		ds, err := getDataStoreByName(ctx, dsName, dc)
+		if err != nil {
+			return nil, err
+		}
+		datastores[ds.Reference()] = true
+	}
+
+	var refs []vim.ManagedObjectReference
+	for ref := range datastores {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Value < refs[j].Value })
+	return refs, nil
+}
+
+// getComputeClusterHosts returns name of the compute cluster and its hosts with given properties.
+func getComputeClusterHosts(ctx *CheckContext, clusterRef vim.ManagedObjectReference, properties []string) (string, []mo.HostSystem, error) {
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+
+	var cluster mo.ClusterComputeResource
+	if err := pc.RetrieveOne(tctx, clusterRef, []string{"name", "host"}, &cluster); err != nil {
+		return "", nil, fmt.Errorf("failed to get compute cluster %s: %s", clusterRef.Value, err)
+	}
+	if len(cluster.Host) == 0 {
+		return cluster.Name, nil, nil
+	}
+	var hosts []mo.HostSystem
+	if err := pc.Retrieve(tctx, cluster.Host, properties, &hosts); err != nil {
+		return cluster.Name, nil, fmt.Errorf("failed to get hosts of compute cluster %s: %s", cluster.Name, err)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name < hosts[j].Name })
+	return cluster.Name, hosts, nil
+}
@@ -0,0 +1,100 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/mo"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	computeClusterNameCollisionsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_compute_cluster_name_collisions_total",
+			Help:           "Number of compute cluster names used in more than one datacenter of the vCenter configuration.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(computeClusterNameCollisionsMetric)
+}
+
+// CheckClusterComputeResourceNameUniqueness tests that compute clusters in datacenters of the Workspace vCenter
+// configuration have unique names. Compute clusters with the same name in several datacenters are ambiguous
+// when they are found by name without a datacenter, for example in failure domains.
+func CheckClusterComputeResourceNameUniqueness(ctx *CheckContext) error {
+	datacenters := getVCenterDatacenters(ctx, ctx.VMConfig.Workspace.VCenterIP)
+	if len(datacenters) < 2 {
+		klog.V(2).Infof("CheckClusterComputeResourceNameUniqueness: %d datacenters configured, skipping", len(datacenters))
+		computeClusterNameCollisionsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+
+	// compute cluster name -> datacenters with a cluster of that name
+	clusterDatacenters := make(map[string][]string)
+	for _, dcName := range datacenters {
+		names, err := getComputeClusterNames(ctx, dcName)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			clusterDatacenters[name] = append(clusterDatacenters[name], dcName)
+		}
+	}
+
+	var names []string
+	for name, dcs := range clusterDatacenters {
+		if len(dcs) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	computeClusterNameCollisionsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(len(names)))
+
+	var errs []error
+	for _, name := range names {
+		errs = append(errs, fmt.Errorf("compute cluster name %s is used in datacenters %s", name, strings.Join(clusterDatacenters[name], ", ")))
+	}
+	klog.V(2).Infof("CheckClusterComputeResourceNameUniqueness checked %d datacenters, %d problems found", len(datacenters), len(errs))
+	return JoinErrors(errs)
+}
+
+// getComputeClusterNames returns unique names of all compute clusters in the datacenter, including clusters in folders.
+func getComputeClusterNames(ctx *CheckContext, dcName string) ([]string, error) {
+	dc, err := getDatacenter(ctx, dcName)
+	if err != nil {
+		return nil, err
+	}
+
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	mgr := view.NewManager(ctx.VMClient)
+	v, err := mgr.CreateContainerView(tctx, dc.Reference(), []string{"ClusterComputeResource"}, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list compute clusters of datacenter %s: %s", dcName, err)
+	}
+	defer v.Destroy(tctx)
+
+	var clusters []mo.ClusterComputeResource
+	if err := v.Retrieve(tctx, []string{"ClusterComputeResource"}, []string{"name"}, &clusters); err != nil {
+		return nil, fmt.Errorf("failed to list compute clusters of datacenter %s: %s", dcName, err)
+	}
+	found := make(map[string]bool)
+	var names []string
+	for _, cluster := range clusters {
+		if !found[cluster.Name] {
+			names = append(names, cluster.Name)
+			found[cluster.Name] = true
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,98 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckClusterComputeResourceNameUniqueness(t *testing.T) {
+	tests := []struct {
+		name string
+		// Additional cluster to create in datacenter DC1, which has DC1_C0 by default
+		dc1Cluster    string
+		datacenters   string
+		expectError   string
+		expectedCount int
+	}{
+		{
+			name:        "single datacenter",
+			datacenters: "DC0",
+		},
+		{
+			name:        "unique names",
+			datacenters: "DC0, DC1",
+		},
+		{
+			name:          "duplicate name",
+			dc1Cluster:    "DC0_C0",
+			datacenters:   "DC0, DC1",
+			expectError:   "compute cluster name DC0_C0 is used in datacenters DC0, DC1",
+			expectedCount: 1,
+		},
+		{
+			name:        "duplicate name in a datacenter that is not configured",
+			dc1Cluster:  "DC0_C0",
+			datacenters: "DC0",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMConfig.VirtualCenter["dc0"].Datacenters = test.datacenters
+
+			if test.dc1Cluster != "" {
+				dc, err := getDatacenter(ctx, "DC1")
+				if err != nil {
+					t.Fatalf("Failed to get datacenter: %s", err)
+				}
+				folders, err := dc.Folders(ctx.Context)
+				if err != nil {
+					t.Fatalf("Failed to get datacenter folders: %s", err)
+				}
+				if _, err := folders.HostFolder.CreateCluster(ctx.Context, test.dc1Cluster, types.ClusterConfigSpecEx{}); err != nil {
+					t.Fatalf("Failed to create cluster: %s", err)
+				}
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckClusterComputeResourceNameUniqueness(ctx)
+
+			// Assert
+			if test.expectError == "" && err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if test.expectError != "" {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectError)
+				} else if err.Error() != test.expectError {
+					t.Errorf("Expected error %q, got %q", test.expectError, err.Error())
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_compute_cluster_name_collisions_total [ALPHA] Number of compute cluster names used in more than one datacenter of the vCenter configuration.
+# TYPE vsphere_compute_cluster_name_collisions_total gauge
+vsphere_compute_cluster_name_collisions_total{vcenter="dc0"} %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_compute_cluster_name_collisions_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package check
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func withSystemUUID(uuid string) func(*v1.Node) {
+	return func(node *v1.Node) {
+		node.Status.NodeInfo.SystemUUID = uuid
+	}
+}
+
+func TestFindNodeVM(t *testing.T) {
+	vm := defaultVMs[0]
+	tests := []struct {
+		name          string
+		node          *v1.Node
+		expectedMatch string
+		expectedError string
+	}{
+		{
+			name:          "ProviderID",
+			node:          node(vm.name, withProviderID("vsphere://"+vm.uuid)),
+			expectedMatch: NodeVMMatchProviderID,
+		},
+		{
+			name:          "unknown ProviderID",
+			node:          node(vm.name, withProviderID("vsphere://00000000-0000-0000-0000-000000000000")),
+			expectedMatch: NodeVMMatchProviderID,
+			expectedError: "unable to find VM by UUID 00000000-0000-0000-0000-000000000000",
+		},
+		{
+			name:          "name without system UUID",
+			node:          node(vm.name),
+			expectedMatch: NodeVMMatchName,
+		},
+		{
+			name:          "name with system UUID",
+			node:          node(vm.name, withSystemUUID("265104DE-1472-547C-B873-6DC7883FB6CB")),
+			expectedMatch: NodeVMMatchName,
+		},
+		{
+			name:          "name with byte swapped system UUID",
+			node:          node(vm.name, withSystemUUID("de045126-7214-7c54-b873-6dc7883fb6cb")),
+			expectedMatch: NodeVMMatchName,
+		},
+		{
+			name:          "name with different system UUID",
+			node:          node(vm.name, withSystemUUID("12f8928d-f144-5c57-89db-dd2d0902c9fa")),
+			expectedMatch: NodeVMMatchName,
+			expectedError: `node has no ProviderID and VM DC0_H0_VM0 found by name has BIOS UUID "265104de-1472-547c-b873-6dc7883fb6cb", while the node reports system UUID "12f8928d-f144-5c57-89db-dd2d0902c9fa"`,
+		},
+		{
+			name:          "unknown name",
+			node:          node("foo"),
+			expectedMatch: NodeVMMatchName,
+			expectedError: "node has no ProviderID and failed to find VM by name foo: vm 'foo' not found",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: []*v1.Node{test.node},
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			dc, err := getDatacenter(ctx, defaultDC)
+			if err != nil {
+				t.Fatalf("Failed to get datacenter: %s", err)
+			}
+
+			// Act
+			ref, match, err := FindNodeVM(ctx, dc, test.node)
+
+			// Assert
+			if match != test.expectedMatch {
+				t.Errorf("Expected match %q, got %q", test.expectedMatch, match)
+			}
+			if test.expectedError != "" {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			expectedVM, err := getVM(ctx, node(vm.name))
+			if err != nil {
+				t.Fatalf("Error getting vm %s: %s", vm.name, err)
+			}
+			if ref != expectedVM.Self {
+				t.Errorf("Expected VM %s, got %s", expectedVM.Self.Value, ref.Value)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Authentication problems returned by AuthenticationProblem.
+	authInvalidCredentials = "invalid username or password"
+	authAccountLocked      = "account is locked"
+	authPasswordExpired    = "password has expired"
+	authPermissionDenied   = "permission denied"
+)
+
+// CheckCredentials tests that the account of vsphere-problem-detector is logged in to each connected vCenter
+// and that it can read the vCenter inventory. Expired sessions are logged in again with the credentials
+// of the current round, so rotated or revoked credentials are reported here as a bad username or password,
+// a locked account or a denied permission, and not as generic failures of the other checks.
+func CheckCredentials(ctx *CheckContext) error {
+	clients := ctx.VMClients
+	if len(clients) == 0 {
+		clients = map[string]*vim25.Client{ctx.VMConfig.Workspace.VCenterIP: ctx.VMClient}
+	}
+	vcContext := *ctx
+	vcContext.VMClients = clients
+
+	var errs []error
+	for _, vCenter := range vcContext.connectedVCenters() {
+		if err := checkVCenterCredentials(ctx.Context, clients[vCenter]); err != nil {
+			errs = append(errs, DescribeAuthenticationError(vCenter, getVCenterUser(ctx, vCenter), err))
+			continue
+		}
+		klog.V(4).Infof("CheckCredentials: account %s is logged in to vCenter %s", getVCenterUser(ctx, vCenter), vCenter)
+	}
+	return JoinErrors(errs)
+}
+
+// checkVCenterCredentials returns error when the client has no session or it cannot read the root folder.
+func checkVCenterCredentials(ctx context.Context, client *vim25.Client) error {
+	tctx, cancel := context.WithTimeout(ctx, *Timeout)
+	defer cancel()
+	user, err := session.NewManager(client).UserSession(tctx)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("session is not authenticated")
+	}
+
+	var folder mo.Folder
+	pc := property.DefaultCollector(client)
+	return pc.RetrieveOne(tctx, client.ServiceContent.RootFolder, []string{"name"}, &folder)
+}
+
+// getVCenterUser returns name of the account used to log in to the vCenter, as far as it is known.
+func getVCenterUser(ctx *CheckContext, vCenter string) string {
+	if vCenter == ctx.VMConfig.Workspace.VCenterIP && ctx.Username != "" {
+		return ctx.Username
+	}
+	if vcConfig, ok := ctx.VMConfig.VirtualCenter[vCenter]; ok && vcConfig != nil {
+		return vcConfig.User
+	}
+	return ""
+}
+
+// DescribeAuthenticationError returns error of a failed vCenter login or call with its authentication
+// problem in plain words. Errors that are not authentication problems are returned with the vCenter name.
+func DescribeAuthenticationError(vCenter, username string, err error) error {
+	problem := AuthenticationProblem(err)
+	if problem == "" {
+		return fmt.Errorf("failed to connect to %s: %s", vCenter, err)
+	}
+	return fmt.Errorf("failed to authenticate to %s as %s: %s: %s", vCenter, username, problem, err)
+}
+
+// AuthenticationProblem returns the authentication problem of a vCenter error, i.e. a bad username or password,
+// a locked account, an expired password or a denied permission. It returns an empty string for other errors.
+func AuthenticationProblem(err error) string {
+	for ; err != nil; err = errors.Unwrap(err) {
+		var fault interface{}
+		var message string
+		switch {
+		case soap.IsSoapFault(err):
+			f := soap.ToSoapFault(err)
+			fault, message = f.VimFault(), f.String
+		case soap.IsVimFault(err):
+			fault = soap.ToVimFault(err)
+		default:
+			continue
+		}
+		switch fault.(type) {
+		case types.InvalidLogin, *types.InvalidLogin:
+			// vCenter SSO reports locked and disabled accounts as InvalidLogin with a different message.
+			if strings.Contains(strings.ToLower(message), "locked") {
+				return authAccountLocked
+			}
+			return authInvalidCredentials
+		case types.PasswordExpired, *types.PasswordExpired:
+			return authPasswordExpired
+		case types.NoPermission, *types.NoPermission:
+			return authPermissionDenied
+		}
+		return ""
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package check
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestCheckCredentials(t *testing.T) {
+	// Stage
+	kubeClient := &fakeKubeClient{}
+	ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+	ctx.VMClients = map[string]*vim25.Client{"dc0": ctx.VMClient}
+
+	// Act
+	err = CheckCredentials(ctx)
+
+	// Assert
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestDescribeAuthenticationError(t *testing.T) {
+	soapFault := func(message string, fault types.AnyType) error {
+		f := &soap.Fault{Code: "ServerFaultCode", String: message}
+		f.Detail.Fault = fault
+		return soap.WrapSoapFault(f)
+	}
+	tests := []struct {
+		name            string
+		err             error
+		expectedProblem string
+		expectedError   string
+	}{
+		{
+			name:            "invalid login",
+			err:             soapFault("Cannot complete login due to an incorrect user name or password.", types.InvalidLogin{}),
+			expectedProblem: authInvalidCredentials,
+			expectedError:   "failed to authenticate to vc1 as user@vsphere.local: invalid username or password: ServerFaultCode: Cannot complete login due to an incorrect user name or password.",
+		},
+		{
+			name:            "locked account",
+			err:             soapFault("User account is locked. Please contact your administrator.", types.InvalidLogin{}),
+			expectedProblem: authAccountLocked,
+			expectedError:   "failed to authenticate to vc1 as user@vsphere.local: account is locked: ServerFaultCode: User account is locked. Please contact your administrator.",
+		},
+		{
+			name:            "expired password",
+			err:             soapFault("", types.PasswordExpired{}),
+			expectedProblem: authPasswordExpired,
+			expectedError:   "failed to authenticate to vc1 as user@vsphere.local: password has expired: ServerFaultCode: PasswordExpired",
+		},
+		{
+			name:            "wrapped permission denied",
+			err:             fmt.Errorf("unable to login to vCenter: %w", soapFault("Permission to perform this operation was denied.", &types.NoPermission{})),
+			expectedProblem: authPermissionDenied,
+			expectedError:   "failed to authenticate to vc1 as user@vsphere.local: permission denied: unable to login to vCenter: ServerFaultCode: Permission to perform this operation was denied.",
+		},
+		{
+			name:            "vim fault",
+			err:             soap.WrapVimFault(&types.NoPermission{}),
+			expectedProblem: authPermissionDenied,
+			expectedError:   "failed to authenticate to vc1 as user@vsphere.local: permission denied: NoPermission",
+		},
+		{
+			name:          "other fault",
+			err:           soapFault("The object has already been deleted or has not been completely created", types.ManagedObjectNotFound{}),
+			expectedError: "failed to connect to vc1: ServerFaultCode: The object has already been deleted or has not been completely created",
+		},
+		{
+			name:          "connection error",
+			err:           errors.New("connection refused"),
+			expectedError: "failed to connect to vc1: connection refused",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if problem := AuthenticationProblem(test.err); problem != test.expectedProblem {
+				t.Errorf("Expected problem %q, got %q", test.expectedProblem, problem)
+			}
+			err := DescribeAuthenticationError("vc1", "user@vsphere.local", test.err)
+			if err.Error() != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+			}
+		})
+	}
+}
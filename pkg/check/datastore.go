@@ -3,6 +3,7 @@ package check
 import (
 	"context"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/vmware/govmomi/property"
@@ -11,7 +12,6 @@ import (
 	"k8s.io/component-base/metrics/legacyregistry"
 
 	configv1 "github.com/openshift/api/config/v1"
-	"github.com/vmware/govmomi/pbm"
 	"github.com/vmware/govmomi/pbm/types"
 	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25/mo"
@@ -25,7 +25,15 @@ const (
 	// Maximum length of <cluster-id>-dynamic-pvc-<uuid> for volume names.
 	// Kubernetes uses 90, https://github.com/kubernetes/kubernetes/blob/93d288e2a47fa6d497b50d37c8b3a04e91da4228/pkg/volume/vsphere_volume/vsphere_volume_util.go#L100
 	// Using 63 to work around https://bugzilla.redhat.com/show_bug.cgi?id=1926943
-	maxVolumeName         = 63
+	maxVolumeName = 63
+	// Maximum length of systemd-escaped path where kubelet mounts an in-tree vSphere volume.
+	// systemd cannot create mount units with longer names.
+	maxVolumePath = 255
+	// Directory where kubelet mounts in-tree vSphere volumes.
+	inTreeVolumeMountDir = "/var/lib/kubelet/plugins/kubernetes.io/vsphere-volume/mounts"
+	// Characters that break "[datastore] path" in-tree volume paths or URLs of datastore files.
+	unsupportedDatastoreNameChars = "[]%#?\\\""
+
 	dataCenterType        = "Datacenter"
 	DatastoreInfoProperty = "info"
 	SummaryProperty       = "summary"
@@ -73,7 +81,8 @@ func (d dataStoreTypeCollector) getDataStoreTypeCount() map[string]int {
 	return m
 }
 
-// CheckStorageClasses tests that datastore name in all StorageClasses in the cluster is short enough.
+// CheckStorageClasses tests that datastores in all StorageClasses in the cluster have names that in-tree
+// volumes can use and that they are not part of a Storage DRS datastore cluster.
 func CheckStorageClasses(ctx *CheckContext) error {
 	// reset the metric so as if types have changed we don't emit them again
 	dataStoreTypesMetric.Reset()
@@ -127,7 +136,8 @@ func CheckStorageClasses(ctx *CheckContext) error {
 	return JoinErrors(errs)
 }
 
-// CheckDefaultDatastore checks that the default data store name in vSphere config file is short enough.
+// CheckDefaultDatastore checks that the default data store in vSphere config file has a name that in-tree
+// volumes can use and that it is not part of a Storage DRS datastore cluster.
 func CheckDefaultDatastore(ctx *CheckContext) error {
 	return checkDefaultDatastoreWithDSType(ctx, nil)
 }
@@ -182,7 +192,7 @@ func checkStoragePolicy(ctx *CheckContext, policyName string, infrastructure *co
 func getPolicyDatastores(ctx *CheckContext, profileID types.PbmProfileId) ([]string, error) {
 	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
 	defer cancel()
-	c, err := pbm.NewClient(tctx, ctx.VMClient)
+	c, err := newPbmClient(tctx, ctx.VMClient)
 	if err != nil {
 		return nil, fmt.Errorf("getPolicyDatastores: error creating pbm client: %v", err)
 	}
@@ -244,7 +254,7 @@ func getPolicyDatastores(ctx *CheckContext, profileID types.PbmProfileId) ([]str
 func getPolicy(ctx *CheckContext, name string) ([]types.BasePbmProfile, error) {
 	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
 	defer cancel()
-	c, err := pbm.NewClient(tctx, ctx.VMClient)
+	c, err := newPbmClient(tctx, ctx.VMClient)
 	if err != nil {
 		return nil, fmt.Errorf("error creating pbm client: %v", err)
 	}
@@ -283,6 +293,9 @@ func getPolicy(ctx *CheckContext, name string) ([]types.BasePbmProfile, error) {
 
 func checkDataStore(ctx *CheckContext, dsName string, infrastructure *configv1.Infrastructure, dsTypes dataStoreTypeCollector) error {
 	var errs []error
+	if err := checkDatastoreName(dsName, infrastructure); err != nil {
+		errs = append(errs, err)
+	}
 	if err := checkForDatastoreCluster(ctx, dsName, dsTypes); err != nil {
 		errs = append(errs, err)
 	}
@@ -292,6 +305,68 @@ func checkDataStore(ctx *CheckContext, dsName string, infrastructure *configv1.I
 	return errors.NewAggregate(errs)
 }
 
+// checkDatastoreName checks that volumes provisioned on the datastore can be mounted: the datastore name has
+// no characters that break volume paths and the escaped mount path of the longest volume name is short enough.
+// The datastore can be an inventory path, only its last element is the datastore name.
+func checkDatastoreName(dsName string, infrastructure *configv1.Infrastructure) error {
+	var errs []error
+	name := path.Base(dsName)
+	if i := strings.IndexAny(name, unsupportedDatastoreNameChars); i >= 0 {
+		errs = append(errs, fmt.Errorf("datastore name %q contains unsupported character %q", name, name[i]))
+	}
+	for _, r := range name {
+		if r < ' ' || r == 0x7f {
+			errs = append(errs, fmt.Errorf("datastore name %q contains control character %q", name, r))
+			break
+		}
+	}
+
+	var clusterID string
+	if infrastructure != nil {
+		clusterID = infrastructure.Status.InfrastructureName
+	}
+	volumeName := generateVolumeName(clusterID, "pvc-00000000-0000-0000-0000-000000000000", maxVolumeName)
+	volumePath := fmt.Sprintf("[%s] 00000000-0000-0000-0000-000000000000/%s.vmdk", dsName, volumeName)
+	escapedPath := systemdEscapePath(path.Join(inTreeVolumeMountDir, volumePath))
+	if len(escapedPath) >= maxVolumePath {
+		errs = append(errs, fmt.Errorf("datastore name is too long: escaped volume path %s must be under %d characters, got %d", escapedPath, maxVolumePath, len(escapedPath)))
+	}
+	return errors.NewAggregate(errs)
+}
+
+// generateVolumeName returns name of an in-tree volume, shortened to maxLength as the in-tree volume plugin does.
+// Copied from https://github.com/kubernetes/kubernetes/blob/93d288e2a47fa6d497b50d37c8b3a04e91da4228/pkg/volume/vsphere_volume/vsphere_volume_util.go#L100
+func generateVolumeName(clusterName, pvName string, maxLength int) string {
+	prefix := clusterName + "-dynamic"
+	pvLen := len(pvName)
+	// cut the "<clusterName>-dynamic" to fit full pvName into maxLength
+	// +1 for the '-' dash
+	if pvLen+1+len(prefix) > maxLength {
+		prefix = prefix[:maxLength-pvLen-1]
+	}
+	return prefix + "-" + pvName
+}
+
+// systemdEscapePath escapes the path as "systemd-escape --path" does for names of mount units.
+func systemdEscapePath(p string) string {
+	p = strings.Trim(p, "/")
+	var escaped strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c == '/':
+			escaped.WriteByte('-')
+		case c == '.' && i == 0:
+			fmt.Fprintf(&escaped, "\\x%02x", c)
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == ':', c == '_', c == '.':
+			escaped.WriteByte(c)
+		default:
+			fmt.Fprintf(&escaped, "\\x%02x", c)
+		}
+	}
+	return escaped.String()
+}
+
 func checkForDatastoreCluster(ctx *CheckContext, dataStoreName string, dsTypes dataStoreTypeCollector) error {
 	matchingDC, err := getDatacenter(ctx, ctx.VMConfig.Workspace.Datacenter)
 	if err != nil {
@@ -319,6 +394,7 @@ func checkForDatastoreCluster(ctx *CheckContext, dataStoreName string, dsTypes d
 	dsType := dsMo.Summary.Type
 	klog.V(4).Infof("Datastore %s is of type %s", dataStoreName, dsType)
 	dsTypes.addDataStore(dataStoreName, dsType)
+	ctx.ClusterInfo.SetDatastoreType(dataStoreName, strings.ToLower(dsType))
 
 	// list datastore cluster
 	m := view.NewManager(ctx.VMClient)
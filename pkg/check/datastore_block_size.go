@@ -0,0 +1,123 @@
+package check
+
+import (
+	"fmt"
+
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	datastoreLabel = "datastore"
+)
+
+var (
+	datastoreMaxVolumeSizeMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_datastore_max_volume_size_bytes",
+			Help:           "Maximum size of a volume that can be created on a VMFS datastore used by the cluster.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, datastoreLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(datastoreMaxVolumeSizeMetric)
+}
+
+// CheckDatastoreBlockSizeForLargeVolumes tests that VMFS datastores used by node VMs and the default
+// datastore can store the largest volume requested by PVCs of vSphere StorageClasses. The maximum
+// file size of a VMFS datastore depends on its block size and it is small on old VMFS versions.
+func CheckDatastoreBlockSizeForLargeVolumes(ctx *CheckContext) error {
+	largestPVC, err := getLargestVSpherePVC(ctx)
+	if err != nil {
+		return err
+	}
+
+	dsRefs, err := getNodeDatastores(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Reset series of the vCenter to drop datastores that are not used any longer.
+	resetVCenterMetric(datastoreMaxVolumeSizeMetric, ctx.VMConfig.Workspace.VCenterIP)
+	var errs []error
+	for _, dsRef := range dsRefs {
+		dsMo, err := getDatastore(ctx, dsRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		vmfsInfo, ok := dsMo.Info.(*types.VmfsDatastoreInfo)
+		if !ok || vmfsInfo.Vmfs == nil {
+			klog.V(4).Infof("Datastore %s is not VMFS, skipping block size check", dsMo.Summary.Name)
+			continue
+		}
+		// The maximum virtual disk capacity is more precise than the maximum file size, when available.
+		maxSize := vmfsInfo.MaxVirtualDiskCapacity
+		if maxSize == 0 {
+			maxSize = vmfsInfo.MaxFileSize
+		}
+		if maxSize == 0 {
+			klog.V(4).Infof("Datastore %s does not report its maximum file size, skipping block size check", dsMo.Summary.Name)
+			continue
+		}
+		datastoreMaxVolumeSizeMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, dsMo.Summary.Name).Set(float64(maxSize))
+
+		if largestPVC == nil {
+			continue
+		}
+		requested := largestPVC.Spec.Resources.Requests[v1.ResourceStorage]
+		if requested.Value() > maxSize {
+			errs = append(errs, fmt.Errorf("datastore %s with VMFS block size %d MB has maximum file size %s, PVC %s/%s requests %s",
+				dsMo.Summary.Name, vmfsInfo.Vmfs.BlockSizeMb, resource.NewQuantity(maxSize, resource.BinarySI), largestPVC.Namespace, largestPVC.Name, requested.String()))
+		}
+	}
+
+	klog.V(2).Infof("CheckDatastoreBlockSizeForLargeVolumes checked %d datastores, %d problems found", len(dsRefs), len(errs))
+	return JoinErrors(errs)
+}
+
+// getLargestVSpherePVC returns the PVC of a vSphere StorageClass with the largest storage request,
+// or nil if there is no such PVC.
+func getLargestVSpherePVC(ctx *CheckContext) (*v1.PersistentVolumeClaim, error) {
+	scs, err := ctx.KubeClient.ListStorageClasses(ctx.Context)
+	if err != nil {
+		return nil, err
+	}
+	vSphereClasses := make(map[string]bool)
+	for _, sc := range scs {
+		if sc.Provisioner == "kubernetes.io/vsphere-volume" || sc.Provisioner == vSphereCSIDdriver {
+			vSphereClasses[sc.Name] = true
+		}
+	}
+	if len(vSphereClasses) == 0 {
+		return nil, nil
+	}
+
+	pvcs, err := ctx.KubeClient.ListPVCs(ctx.Context)
+	if err != nil {
+		return nil, err
+	}
+	var largest *v1.PersistentVolumeClaim
+	var largestSize int64
+	for _, pvc := range pvcs {
+		if pvc.Spec.StorageClassName == nil || !vSphereClasses[*pvc.Spec.StorageClassName] {
+			continue
+		}
+		requested, found := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+		if !found {
+			continue
+		}
+		if largest == nil || requested.Value() > largestSize {
+			largest = pvc
+			largestSize = requested.Value()
+		}
+	}
+	return largest, nil
+}
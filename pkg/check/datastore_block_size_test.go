@@ -0,0 +1,175 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func storageClass(name, provisioner string) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: name},
+		Provisioner: provisioner,
+	}
+}
+
+func sizedPVC(name, scName, size string) *v1.PersistentVolumeClaim {
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			StorageClassName: &scName,
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{
+					v1.ResourceStorage: resource.MustParse(size),
+				},
+			},
+		},
+	}
+}
+
+func TestCheckDatastoreBlockSizeForLargeVolumes(t *testing.T) {
+	storageClasses := []*storagev1.StorageClass{
+		storageClass("thin-csi", vSphereCSIDdriver),
+		storageClass("other", "example.com/other"),
+	}
+
+	tests := []struct {
+		name            string
+		vmfs            bool
+		maxFileSize     int64
+		maxDiskCapacity int64
+		pvcs            []*v1.PersistentVolumeClaim
+		expectedError   string
+		expectedMetric  bool
+	}{
+		{
+			name: "non-VMFS datastore",
+			vmfs: false,
+			pvcs: []*v1.PersistentVolumeClaim{sizedPVC("pvc1", "thin-csi", "1Ti")},
+		},
+		{
+			name:           "large enough datastore",
+			vmfs:           true,
+			maxFileSize:    64 * 1024 * gib,
+			pvcs:           []*v1.PersistentVolumeClaim{sizedPVC("pvc1", "thin-csi", "1Ti")},
+			expectedMetric: true,
+		},
+		{
+			name:           "no PVCs",
+			vmfs:           true,
+			maxFileSize:    256 * gib,
+			expectedMetric: true,
+		},
+		{
+			name:           "large PVC of a different StorageClass",
+			vmfs:           true,
+			maxFileSize:    256 * gib,
+			pvcs:           []*v1.PersistentVolumeClaim{sizedPVC("pvc1", "other", "1Ti")},
+			expectedMetric: true,
+		},
+		{
+			name:        "too large PVC",
+			vmfs:        true,
+			maxFileSize: 256 * gib,
+			pvcs: []*v1.PersistentVolumeClaim{
+				sizedPVC("pvc1", "thin-csi", "10Gi"),
+				sizedPVC("pvc2", "thin-csi", "1Ti"),
+				sizedPVC("pvc3", "thin-csi", "100Gi"),
+			},
+			expectedError:  "datastore LocalDS_0 with VMFS block size 1 MB has maximum file size 256Gi, PVC default/pvc2 requests 1Ti",
+			expectedMetric: true,
+		},
+		{
+			name:            "maximum disk capacity is preferred",
+			vmfs:            true,
+			maxFileSize:     64 * 1024 * gib,
+			maxDiskCapacity: 512 * gib,
+			pvcs:            []*v1.PersistentVolumeClaim{sizedPVC("pvc1", "thin-csi", "1Ti")},
+			expectedError:   "datastore LocalDS_0 with VMFS block size 1 MB has maximum file size 512Gi, PVC default/pvc1 requests 1Ti",
+			expectedMetric:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes:          defaultNodes(),
+				storageClasses: storageClasses,
+				pvcs:           test.pvcs,
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			if test.vmfs {
+				dc, err := getDatacenter(ctx, defaultDC)
+				if err != nil {
+					t.Fatalf("Failed to get datacenter: %s", err)
+				}
+				ds, err := getDataStoreByName(ctx, "LocalDS_0", dc)
+				if err != nil {
+					t.Fatalf("Failed to get datastore: %s", err)
+				}
+				simDS := simulator.Map.Get(ds.Reference()).(*simulator.Datastore)
+				info := *simDS.Info.GetDatastoreInfo()
+				info.MaxFileSize = test.maxFileSize
+				info.MaxVirtualDiskCapacity = test.maxDiskCapacity
+				simDS.Info = &types.VmfsDatastoreInfo{
+					DatastoreInfo: info,
+					Vmfs: &types.HostVmfsVolume{
+						BlockSizeMb: 1,
+					},
+				}
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckDatastoreBlockSizeForLargeVolumes(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := ""
+			if test.expectedMetric {
+				maxSize := test.maxDiskCapacity
+				if maxSize == 0 {
+					maxSize = test.maxFileSize
+				}
+				expectedMetrics = fmt.Sprintf(`
+# HELP vsphere_datastore_max_volume_size_bytes [ALPHA] Maximum size of a volume that can be created on a VMFS datastore used by the cluster.
+# TYPE vsphere_datastore_max_volume_size_bytes gauge
+vsphere_datastore_max_volume_size_bytes{datastore="LocalDS_0",vcenter="dc0"} %d
+`, maxSize)
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_datastore_max_volume_size_bytes"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
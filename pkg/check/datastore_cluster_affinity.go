@@ -0,0 +1,164 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	splitVMHomeMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_split_vm_home_total",
+			Help:           "Number of vSphere node VMs whose disks may be placed apart from their VM home by Storage DRS rules.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(splitVMHomeMetric)
+}
+
+// CheckDatastoreClusterAffinityRuleForVMHome tests that Storage DRS keeps disks of node VMs together
+// with their VM home in datastore clusters. Intra-VM anti-affinity rules, or disabled intra-VM affinity,
+// let Storage DRS scatter VM configuration and disks across datastores, which complicates recovery.
+func CheckDatastoreClusterAffinityRuleForVMHome(ctx *CheckContext) error {
+	vms, err := getNodeVMs(ctx, []string{"name", "datastore"})
+	if err != nil {
+		return err
+	}
+	pods, err := getDatastorePods(ctx, vms)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, vm := range vms {
+		var problems []string
+		for _, pod := range pods {
+			if !isVMInPod(vm, pod) {
+				continue
+			}
+			problems = append(problems, getVMHomeSplitRules(vm, pod)...)
+		}
+		if len(problems) > 0 {
+			errs = append(errs, fmt.Errorf("node VM %s may have disks placed apart from its VM home: %s", vm.Name, strings.Join(problems, ", ")))
+		}
+	}
+	splitVMHomeMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(len(errs)))
+
+	klog.V(2).Infof("CheckDatastoreClusterAffinityRuleForVMHome checked %d VMs in %d datastore clusters, %d problems found", len(vms), len(pods), len(errs))
+	return JoinErrors(errs)
+}
+
+// getDatastorePods returns datastore clusters (storage pods) with datastores of given VMs, sorted by name.
+func getDatastorePods(ctx *CheckContext, vms []mo.VirtualMachine) ([]mo.StoragePod, error) {
+	datastores := make(map[vim.ManagedObjectReference]bool)
+	for _, vm := range vms {
+		for _, ref := range vm.Datastore {
+			datastores[ref] = true
+		}
+	}
+	if len(datastores) == 0 {
+		return nil, nil
+	}
+	var dsRefs []vim.ManagedObjectReference
+	for ref := range datastores {
+		dsRefs = append(dsRefs, ref)
+	}
+
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var dsMos []mo.Datastore
+	if err := pc.Retrieve(tctx, dsRefs, []string{"parent"}, &dsMos); err != nil {
+		return nil, fmt.Errorf("failed to get datastore parents: %s", err)
+	}
+	podRefs := make(map[vim.ManagedObjectReference]bool)
+	for _, ds := range dsMos {
+		if ds.Parent != nil && ds.Parent.Type == "StoragePod" {
+			podRefs[*ds.Parent] = true
+		}
+	}
+	if len(podRefs) == 0 {
+		return nil, nil
+	}
+
+	var refs []vim.ManagedObjectReference
+	for ref := range podRefs {
+		refs = append(refs, ref)
+	}
+	var pods []mo.StoragePod
+	if err := pc.Retrieve(tctx, refs, []string{"name", "childEntity", "podStorageDrsEntry"}, &pods); err != nil {
+		return nil, fmt.Errorf("failed to get datastore clusters: %s", err)
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	return pods, nil
+}
+
+// isVMInPod returns true if the VM uses any datastore of the datastore cluster.
+func isVMInPod(vm mo.VirtualMachine, pod mo.StoragePod) bool {
+	for _, child := range pod.ChildEntity {
+		for _, ds := range vm.Datastore {
+			if child == ds {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getVMHomeSplitRules returns descriptions of Storage DRS settings of the datastore cluster that
+// allow disks of the VM to be placed apart from its VM home.
+func getVMHomeSplitRules(vm mo.VirtualMachine, pod mo.StoragePod) []string {
+	if pod.PodStorageDrsEntry == nil {
+		return nil
+	}
+	config := pod.PodStorageDrsEntry.StorageDrsConfig
+
+	var vmConfig *vim.StorageDrsVmConfigInfo
+	for i := range config.VmConfig {
+		if config.VmConfig[i].Vm != nil && *config.VmConfig[i].Vm == vm.Self {
+			vmConfig = &config.VmConfig[i]
+			break
+		}
+	}
+
+	var problems []string
+	switch {
+	case vmConfig != nil && vmConfig.IntraVmAffinity != nil:
+		if !*vmConfig.IntraVmAffinity {
+			problems = append(problems, fmt.Sprintf("datastore cluster %s does not keep VMDKs of the VM together", pod.Name))
+		}
+	case config.PodConfig.DefaultIntraVmAffinity != nil && !*config.PodConfig.DefaultIntraVmAffinity:
+		problems = append(problems, fmt.Sprintf("datastore cluster %s does not keep VMDKs together by default", pod.Name))
+	}
+	if vmConfig == nil {
+		return problems
+	}
+
+	if rule := vmConfig.IntraVmAntiAffinity; rule != nil && isRuleEnabled(rule.ClusterRuleInfo) && len(rule.DiskId) > 0 {
+		problems = append(problems, fmt.Sprintf("VMDK anti-affinity rule %q in datastore cluster %s", rule.Name, pod.Name))
+	}
+	for _, rule := range vmConfig.VirtualDiskRules {
+		if isRuleEnabled(rule.ClusterRuleInfo) && rule.DiskRuleType == string(vim.VirtualDiskRuleSpecRuleTypeAntiAffinity) {
+			problems = append(problems, fmt.Sprintf("VMDK anti-affinity rule %q in datastore cluster %s", rule.Name, pod.Name))
+		}
+	}
+	return problems
+}
+
+// isRuleEnabled returns true if the cluster rule is enabled. Rules are enabled by default.
+func isRuleEnabled(rule vim.ClusterRuleInfo) bool {
+	return rule.Enabled == nil || *rule.Enabled
+}
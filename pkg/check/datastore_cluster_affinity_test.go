@@ -0,0 +1,150 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckDatastoreClusterAffinityRuleForVMHome(t *testing.T) {
+	tests := []struct {
+		name                   string
+		defaultIntraVMAffinity *bool
+		// noPod skips creation of the datastore cluster
+		noPod         bool
+		vmConfig      *types.StorageDrsVmConfigInfo
+		expectedError string
+	}{
+		{
+			name:  "no datastore cluster",
+			noPod: true,
+		},
+		{
+			name: "datastore cluster with defaults",
+		},
+		{
+			name:                   "VMDKs not kept together by default",
+			defaultIntraVMAffinity: types.NewBool(false),
+			expectedError:          "node VM DC0_H0_VM0 may have disks placed apart from its VM home: datastore cluster POD0 does not keep VMDKs together by default",
+		},
+		{
+			name:                   "VM override keeps VMDKs together",
+			defaultIntraVMAffinity: types.NewBool(false),
+			vmConfig:               &types.StorageDrsVmConfigInfo{IntraVmAffinity: types.NewBool(true)},
+		},
+		{
+			name:          "VM override does not keep VMDKs together",
+			vmConfig:      &types.StorageDrsVmConfigInfo{IntraVmAffinity: types.NewBool(false)},
+			expectedError: "node VM DC0_H0_VM0 may have disks placed apart from its VM home: datastore cluster POD0 does not keep VMDKs of the VM together",
+		},
+		{
+			name: "VMDK anti-affinity rules",
+			vmConfig: &types.StorageDrsVmConfigInfo{
+				IntraVmAntiAffinity: &types.VirtualDiskAntiAffinityRuleSpec{
+					ClusterRuleInfo: types.ClusterRuleInfo{Name: "split-disks", Enabled: types.NewBool(true)},
+					DiskId:          []int32{2000, 2001},
+				},
+				VirtualDiskRules: []types.VirtualDiskRuleSpec{
+					{
+						ClusterRuleInfo: types.ClusterRuleInfo{Name: "disabled-rule", Enabled: types.NewBool(false)},
+						DiskRuleType:    string(types.VirtualDiskRuleSpecRuleTypeAntiAffinity),
+					},
+					{
+						ClusterRuleInfo: types.ClusterRuleInfo{Name: "separate-data"},
+						DiskRuleType:    string(types.VirtualDiskRuleSpecRuleTypeAntiAffinity),
+					},
+				},
+			},
+			expectedError: `node VM DC0_H0_VM0 may have disks placed apart from its VM home: VMDK anti-affinity rule "split-disks" in datastore cluster POD0, VMDK anti-affinity rule "separate-data" in datastore cluster POD0`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			if !test.noPod {
+				dc, err := getDatacenter(ctx, defaultDC)
+				if err != nil {
+					t.Fatalf("Failed to get datacenter: %s", err)
+				}
+				folders, err := dc.Folders(ctx.Context)
+				if err != nil {
+					t.Fatalf("Failed to get datacenter folders: %s", err)
+				}
+				pod, err := folders.DatastoreFolder.CreateStoragePod(ctx.Context, "POD0")
+				if err != nil {
+					t.Fatalf("Failed to create datastore cluster: %s", err)
+				}
+				ds, err := getDataStoreByName(ctx, "LocalDS_0", dc)
+				if err != nil {
+					t.Fatalf("Failed to get datastore: %s", err)
+				}
+				task, err := pod.MoveInto(ctx.Context, []types.ManagedObjectReference{ds.Reference()})
+				if err != nil {
+					t.Fatalf("Failed to move datastore to datastore cluster: %s", err)
+				}
+				if err := task.Wait(ctx.Context); err != nil {
+					t.Fatalf("Failed to move datastore to datastore cluster: %s", err)
+				}
+
+				node := kubeClient.nodes[0]
+				vm, err := getVM(ctx, node)
+				if err != nil {
+					t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+				}
+				simPod := simulator.Map.Get(pod.Reference()).(*simulator.StoragePod)
+				config := &simPod.PodStorageDrsEntry.StorageDrsConfig
+				config.PodConfig.DefaultIntraVmAffinity = test.defaultIntraVMAffinity
+				if test.vmConfig != nil {
+					vmConfig := *test.vmConfig
+					vmRef := vm.Reference()
+					vmConfig.Vm = &vmRef
+					config.VmConfig = []types.StorageDrsVmConfigInfo{vmConfig}
+				}
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckDatastoreClusterAffinityRuleForVMHome(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_split_vm_home_total [ALPHA] Number of vSphere node VMs whose disks may be placed apart from their VM home by Storage DRS rules.
+# TYPE vsphere_node_split_vm_home_total gauge
+vsphere_node_split_vm_home_total{vcenter="dc0"} %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_split_vm_home_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
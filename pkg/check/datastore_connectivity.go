@@ -0,0 +1,242 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	datastoreMissingMountsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_datastore_expected_mounts_missing_total",
+			Help:           "Number of pairs of ESXi host and datastore used by the cluster where the datastore is expected to be accessible on the host, but it is not.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(datastoreMissingMountsMetric)
+}
+
+// DatastoreConnectivity is accessibility of a single datastore on a single ESXi host.
+type DatastoreConnectivity struct {
+	// Host is name of the ESXi host.
+	Host string `json:"host"`
+	// Datastore is name of the datastore.
+	Datastore string `json:"datastore"`
+	// Mounted is true when the datastore is mounted on the host.
+	Mounted bool `json:"mounted"`
+	// Accessible is true when the datastore is mounted and accessible on the host.
+	Accessible bool `json:"accessible"`
+	// Expected is true when the datastore should be accessible on the host according to zones of the host
+	// and the datastore.
+	Expected bool `json:"expected"`
+	// Reason describes why the datastore is not accessible, empty when it is accessible.
+	Reason string `json:"reason,omitempty"`
+}
+
+// DatastoreConnectivityMatrix is accessibility of datastores used by the cluster on ESXi hosts
+// that run node VMs or that are in compute clusters with node VMs.
+type DatastoreConnectivityMatrix struct {
+	// Hosts are sorted names of the ESXi hosts.
+	Hosts []string `json:"hosts"`
+	// Datastores are sorted names of the datastores.
+	Datastores []string `json:"datastores"`
+	// Entries has an item for each host and datastore, sorted by host and datastore name.
+	Entries []DatastoreConnectivity `json:"entries"`
+}
+
+// CheckClusterDatastoreConnectivityMatrix tests that datastores used by node VMs and the default datastore are
+// accessible on all ESXi hosts where they are expected. Without a zone tag category in vSphere configuration,
+// all datastores are expected on all hosts that run node VMs or that are in compute clusters with node VMs.
+// With zones, a datastore with a zone tag is expected only on hosts in the same zone.
+// The matrix is added to the JSON report of the round when the report is enabled.
+func CheckClusterDatastoreConnectivityMatrix(ctx *CheckContext) error {
+	matrix, err := BuildDatastoreConnectivityMatrix(ctx)
+	if err != nil {
+		return err
+	}
+	if ctx.ReportSink != nil {
+		ctx.ReportSink.AddDatastoreConnectivityMatrix(matrix)
+	}
+
+	var errs []error
+	for _, entry := range matrix.Entries {
+		if entry.Expected && !entry.Accessible {
+			errs = append(errs, fmt.Errorf("datastore %s is not accessible on ESXi host %s: %s", entry.Datastore, entry.Host, entry.Reason))
+		}
+	}
+	datastoreMissingMountsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(len(errs)))
+
+	klog.V(2).Infof("CheckClusterDatastoreConnectivityMatrix checked %d datastores on %d hosts, %d problems found", len(matrix.Datastores), len(matrix.Hosts), len(errs))
+	return JoinErrors(errs)
+}
+
+// BuildDatastoreConnectivityMatrix returns accessibility of datastores used by node VMs and the default datastore
+// on ESXi hosts that run node VMs or that are in compute clusters with node VMs.
+func BuildDatastoreConnectivityMatrix(ctx *CheckContext) (*DatastoreConnectivityMatrix, error) {
+	hosts, err := getNodeClusterHosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hostNames, err := getHostNames(ctx, hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	dsRefs, err := getNodeDatastores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var datastores []mo.Datastore
+	if err := pc.Retrieve(tctx, dsRefs, []string{"name", "host"}, &datastores); err != nil {
+		return nil, fmt.Errorf("failed to get datastore host mounts: %s", err)
+	}
+
+	expected, err := getExpectedDatastoreHosts(ctx, datastores, hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := &DatastoreConnectivityMatrix{
+		Hosts:      []string{},
+		Datastores: []string{},
+		Entries:    []DatastoreConnectivity{},
+	}
+	for _, name := range hostNames {
+		matrix.Hosts = append(matrix.Hosts, name)
+	}
+	for _, ds := range datastores {
+		matrix.Datastores = append(matrix.Datastores, ds.Name)
+		mounts := make(map[vim.ManagedObjectReference]vim.HostMountInfo)
+		for _, mount := range ds.Host {
+			mounts[mount.Key] = mount.MountInfo
+		}
+		for host := range hosts {
+			entry := DatastoreConnectivity{
+				Host:      hostNames[host],
+				Datastore: ds.Name,
+				Expected:  expected == nil || expected[ds.Self][host],
+			}
+			if entry.Host == "" {
+				entry.Host = host.Value
+			}
+			mount, found := mounts[host]
+			switch {
+			case !found:
+				entry.Reason = "not mounted"
+			case mount.Mounted != nil && !*mount.Mounted:
+				entry.Reason = "unmounted"
+			case mount.Accessible != nil && !*mount.Accessible:
+				entry.Mounted = true
+				entry.Reason = fmt.Sprintf("inaccessible: %s", mount.InaccessibleReason)
+			default:
+				entry.Mounted = true
+				entry.Accessible = true
+			}
+			matrix.Entries = append(matrix.Entries, entry)
+		}
+	}
+	sort.Strings(matrix.Hosts)
+	sort.Strings(matrix.Datastores)
+	sort.Slice(matrix.Entries, func(i, j int) bool {
+		if matrix.Entries[i].Host != matrix.Entries[j].Host {
+			return matrix.Entries[i].Host < matrix.Entries[j].Host
+		}
+		return matrix.Entries[i].Datastore < matrix.Entries[j].Datastore
+	})
+	return matrix, nil
+}
+
+// getNodeClusterHosts returns ESXi hosts that run node VMs and all ESXi hosts in compute clusters with node VMs.
+func getNodeClusterHosts(ctx *CheckContext) (map[vim.ManagedObjectReference]bool, error) {
+	vms, err := getNodeVMs(ctx, []string{"runtime.host"})
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[vim.ManagedObjectReference]bool)
+	for _, vm := range vms {
+		if vm.Runtime.Host != nil {
+			hosts[*vm.Runtime.Host] = true
+		}
+	}
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, clusterRef := range clusterRefs {
+		_, clusterHosts, err := getComputeClusterHosts(ctx, clusterRef, []string{"name"})
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range clusterHosts {
+			hosts[host.Self] = true
+		}
+	}
+	return hosts, nil
+}
+
+// getExpectedDatastoreHosts returns hosts where the datastores are expected to be accessible, datastore -> host -> true.
+// A datastore without a zone tag is expected on all hosts, a datastore with zone tags only on hosts in one of its zones.
+// It returns nil when zones cannot be read and all datastores are expected on all hosts.
+func getExpectedDatastoreHosts(ctx *CheckContext, datastores []mo.Datastore, hosts map[vim.ManagedObjectReference]bool) (map[vim.ManagedObjectReference]map[vim.ManagedObjectReference]bool, error) {
+	zoneCategory := ctx.VMConfig.Labels.Zone
+	if zoneCategory == "" {
+		klog.V(4).Infof("Zone tag category is not configured, all datastores are expected on all hosts")
+		return nil, nil
+	}
+	if ctx.TagManager == nil {
+		return nil, fmt.Errorf("cannot check zone tags in category %s: vSphere tags are not available", zoneCategory)
+	}
+	zoneTags, err := getCategoryTags(ctx, zoneCategory)
+	if err != nil {
+		return nil, err
+	}
+	var refs []mo.Reference
+	for _, ds := range datastores {
+		refs = append(refs, ds.Self)
+	}
+	datastoreZones, err := getAttachedZones(ctx, refs, zoneTags)
+	if err != nil {
+		return nil, err
+	}
+	hostZones, err := getHostZones(ctx, hosts, zoneTags)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := make(map[vim.ManagedObjectReference]map[vim.ManagedObjectReference]bool)
+	for _, ds := range datastores {
+		zones := make(map[string]bool)
+		for _, zone := range datastoreZones[ds.Self] {
+			zones[zone] = true
+		}
+		expected[ds.Self] = make(map[vim.ManagedObjectReference]bool)
+		for host := range hosts {
+			if len(zones) == 0 {
+				expected[ds.Self][host] = true
+				continue
+			}
+			for _, zone := range hostZones[host].zones {
+				if zones[zone] {
+					expected[ds.Self][host] = true
+					break
+				}
+			}
+		}
+	}
+	return expected, nil
+}
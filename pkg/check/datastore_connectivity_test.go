@@ -0,0 +1,163 @@
+package check
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// fakeReportSink stores data reported by checks.
+type fakeReportSink struct {
+	matrix *DatastoreConnectivityMatrix
+}
+
+var _ ReportSink = &fakeReportSink{}
+
+func (s *fakeReportSink) AddDatastoreConnectivityMatrix(matrix *DatastoreConnectivityMatrix) {
+	s.matrix = matrix
+}
+
+func TestCheckClusterDatastoreConnectivityMatrix(t *testing.T) {
+	tests := []struct {
+		name           string
+		zoneCategory   string
+		datastoreZones []string
+		clusterZones   []string
+		// host name -> zone tags
+		hostZones       map[string][]string
+		expectedError   string
+		expectedCount   int
+		expectedEntries []DatastoreConnectivity
+	}{
+		{
+			name: "zones not configured",
+			expectedError: "datastore LocalDS_0 is not accessible on ESXi host DC0_C0_H0: not mounted;\n" +
+				"datastore LocalDS_0 is not accessible on ESXi host DC0_C0_H1: not mounted",
+			expectedCount: 2,
+			expectedEntries: []DatastoreConnectivity{
+				{Host: "DC0_C0_H0", Datastore: "LocalDS_0", Expected: true, Reason: "not mounted"},
+				{Host: "DC0_C0_H1", Datastore: "LocalDS_0", Expected: true, Reason: "not mounted"},
+				{Host: "DC0_C0_H2", Datastore: "LocalDS_0", Expected: true, Mounted: true, Accessible: true},
+			},
+		},
+		{
+			name:           "datastore in zone of the only host with it",
+			zoneCategory:   "k8s-zone",
+			datastoreZones: []string{"zone-a"},
+			clusterZones:   []string{"zone-b"},
+			hostZones: map[string][]string{
+				"DC0_C0_H2": {"zone-a"},
+			},
+			expectedEntries: []DatastoreConnectivity{
+				{Host: "DC0_C0_H0", Datastore: "LocalDS_0", Reason: "not mounted"},
+				{Host: "DC0_C0_H1", Datastore: "LocalDS_0", Reason: "not mounted"},
+				{Host: "DC0_C0_H2", Datastore: "LocalDS_0", Expected: true, Mounted: true, Accessible: true},
+			},
+		},
+		{
+			name:           "datastore in zone with a host without it",
+			zoneCategory:   "k8s-zone",
+			datastoreZones: []string{"zone-a"},
+			clusterZones:   []string{"zone-b"},
+			hostZones: map[string][]string{
+				"DC0_C0_H1": {"zone-a"},
+				"DC0_C0_H2": {"zone-a"},
+			},
+			expectedError: "datastore LocalDS_0 is not accessible on ESXi host DC0_C0_H1: not mounted",
+			expectedCount: 1,
+			expectedEntries: []DatastoreConnectivity{
+				{Host: "DC0_C0_H0", Datastore: "LocalDS_0", Reason: "not mounted"},
+				{Host: "DC0_C0_H1", Datastore: "LocalDS_0", Expected: true, Reason: "not mounted"},
+				{Host: "DC0_C0_H2", Datastore: "LocalDS_0", Expected: true, Mounted: true, Accessible: true},
+			},
+		},
+		{
+			name:         "datastore without zone",
+			zoneCategory: "k8s-zone",
+			clusterZones: []string{"zone-b"},
+			expectedError: "datastore LocalDS_0 is not accessible on ESXi host DC0_C0_H0: not mounted;\n" +
+				"datastore LocalDS_0 is not accessible on ESXi host DC0_C0_H1: not mounted",
+			expectedCount: 2,
+			expectedEntries: []DatastoreConnectivity{
+				{Host: "DC0_C0_H0", Datastore: "LocalDS_0", Expected: true, Reason: "not mounted"},
+				{Host: "DC0_C0_H1", Datastore: "LocalDS_0", Expected: true, Reason: "not mounted"},
+				{Host: "DC0_C0_H2", Datastore: "LocalDS_0", Expected: true, Mounted: true, Accessible: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMConfig.Labels.Zone = test.zoneCategory
+			sink := &fakeReportSink{}
+			ctx.ReportSink = sink
+
+			clusterRef := types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "clustercomputeresource-30"}
+			dc, err := getDatacenter(ctx, defaultDC)
+			if err != nil {
+				t.Fatalf("Failed to get datacenter: %s", err)
+			}
+			ds, err := getDataStoreByName(ctx, "LocalDS_0", dc)
+			if err != nil {
+				t.Fatalf("Failed to get datastore: %s", err)
+			}
+			_, hosts, err := getComputeClusterHosts(ctx, clusterRef, []string{"name"})
+			if err != nil {
+				t.Fatalf("Failed to get hosts: %s", err)
+			}
+			tagIDs := createZoneTags(t, ctx, "k8s-zone", "zone-a", "zone-b")
+			attachZoneTags(t, ctx, tagIDs, ds.Reference(), test.datastoreZones)
+			attachZoneTags(t, ctx, tagIDs, clusterRef, test.clusterZones)
+			for _, host := range hosts {
+				attachZoneTags(t, ctx, tagIDs, host.Reference(), test.hostZones[host.Name])
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckClusterDatastoreConnectivityMatrix(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+			if sink.matrix == nil {
+				t.Fatalf("Expected datastore connectivity matrix to be reported, got none")
+			}
+			if !reflect.DeepEqual(sink.matrix.Entries, test.expectedEntries) {
+				t.Errorf("Unexpected matrix entries: %+v, expected %+v", sink.matrix.Entries, test.expectedEntries)
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_datastore_expected_mounts_missing_total [ALPHA] Number of pairs of ESXi host and datastore used by the cluster where the datastore is expected to be accessible on the host, but it is not.
+# TYPE vsphere_datastore_expected_mounts_missing_total gauge
+vsphere_datastore_expected_mounts_missing_total{vcenter="dc0"} %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_datastore_expected_mounts_missing_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package check
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	minDatastoreFreeSpacePercent  = flag.Int("min-datastore-free-space-percent", 10, "Minimum free space of the default datastore and datastores in StorageClasses and PVs, in percent of their capacity. Datastores with less free space fail CheckDatastoreFreeSpace.")
+	warnDatastoreFreeSpacePercent = flag.Int("warn-datastore-free-space-percent", 20, "Free space of the default datastore and datastores in StorageClasses and PVs, in percent of their capacity, below which a warning is logged. It must not be lower than min-datastore-free-space-percent.")
+
+	datastoreFreeSpaceMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_datastore_free_space_percent",
+			Help:           "Free space of the default datastore and datastores in StorageClasses and PVs, in percent of their capacity.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{datastoreLabel},
+	)
+
+	datastoreCapacityBytesMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_datastore_capacity_bytes",
+			Help:           "Capacity of the default datastore and datastores in StorageClasses and PVs.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{datastoreLabel},
+	)
+
+	datastoreFreeSpaceBytesMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_datastore_free_space_bytes",
+			Help:           "Free space of the default datastore and datastores in StorageClasses and PVs.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{datastoreLabel},
+	)
+
+	datastoreFreeSpaceWarningsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_datastore_free_space_warnings_total",
+			Help:           "Number of datastores with free space below warn-datastore-free-space-percent, but not below min-datastore-free-space-percent.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(datastoreFreeSpaceMetric)
+	legacyregistry.MustRegister(datastoreCapacityBytesMetric)
+	legacyregistry.MustRegister(datastoreFreeSpaceBytesMetric)
+	legacyregistry.MustRegister(datastoreFreeSpaceWarningsMetric)
+}
+
+// CheckDatastoreFreeSpace tests that the default datastore from vSphere configuration, datastores
+// in StorageClasses and datastores of existing PVs have enough free space. Provisioning of new PVs starts
+// failing on a full datastore and running VMs with thin disks get suspended. Datastores below
+// warn-datastore-free-space-percent are only logged, so alerts on the metrics can fire before the check fails.
+// Datastore clusters are skipped, Storage DRS places volumes on their datastores.
+func CheckDatastoreFreeSpace(ctx *CheckContext) error {
+	if *minDatastoreFreeSpacePercent < 0 || *minDatastoreFreeSpacePercent > 100 {
+		return fmt.Errorf("invalid min-datastore-free-space-percent %d, it must be between 0 and 100", *minDatastoreFreeSpacePercent)
+	}
+	if *warnDatastoreFreeSpacePercent < *minDatastoreFreeSpacePercent || *warnDatastoreFreeSpacePercent > 100 {
+		return fmt.Errorf("invalid warn-datastore-free-space-percent %d, it must be between min-datastore-free-space-percent %d and 100", *warnDatastoreFreeSpacePercent, *minDatastoreFreeSpacePercent)
+	}
+
+	var errs []error
+	// datastore name -> description where the datastore is used
+	datastores := make(map[string]string)
+	if dsName := ctx.VMConfig.Workspace.DefaultDatastore; dsName != "" {
+		datastores[dsName] = "defaultDatastore in vSphere configuration"
+	}
+
+	scs, err := ctx.KubeClient.ListStorageClasses(ctx.Context)
+	if err != nil {
+		return err
+	}
+	for _, sc := range scs {
+		if sc.Provisioner != "kubernetes.io/vsphere-volume" {
+			continue
+		}
+		for k, v := range sc.Parameters {
+			if strings.ToLower(k) != dsParameter {
+				continue
+			}
+			if _, found := datastores[v]; !found {
+				datastores[v] = "StorageClass " + sc.Name
+			}
+		}
+	}
+
+	pvs, err := ctx.KubeClient.ListPVs(ctx.Context)
+	if err != nil {
+		return err
+	}
+	pvDatastores, err := getPVDatastores(ctx, pvs)
+	if err != nil {
+		// Check the datastores of PVs that were found.
+		errs = append(errs, fmt.Errorf("failed to get datastores of PVs: %s", err))
+	}
+	var pvNames []string
+	for pvName := range pvDatastores {
+		pvNames = append(pvNames, pvName)
+	}
+	sort.Strings(pvNames)
+	for _, pvName := range pvNames {
+		if _, found := datastores[pvDatastores[pvName]]; !found {
+			datastores[pvDatastores[pvName]] = "PersistentVolume " + pvName
+		}
+	}
+
+	dc, err := getDatacenter(ctx, ctx.VMConfig.Workspace.Datacenter)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range datastores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Reset the metrics to drop datastores that are not used any longer.
+	datastoreFreeSpaceMetric.Reset()
+	datastoreCapacityBytesMetric.Reset()
+	datastoreFreeSpaceBytesMetric.Reset()
+	warnings := 0
+	for _, name := range names {
+		ds, err := getDataStoreByName(ctx, name, dc)
+		if err != nil {
+			if isDatastoreCluster(ctx, name, dc) {
+				klog.V(2).Infof("CheckDatastoreFreeSpace: %s: %s is a datastore cluster, skipping", datastores[name], name)
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%s: datastore %s not found: %s", datastores[name], name, err))
+			continue
+		}
+		dsMo, err := getDatastore(ctx, ds.Reference())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", datastores[name], err))
+			continue
+		}
+		capacity, freeSpace := dsMo.Summary.Capacity, dsMo.Summary.FreeSpace
+		if capacity <= 0 {
+			klog.V(2).Infof("CheckDatastoreFreeSpace: datastore %s does not report its capacity, skipping", name)
+			continue
+		}
+
+		freePercent := float64(freeSpace) * 100 / float64(capacity)
+		datastoreFreeSpaceMetric.WithLabelValues(name).Set(freePercent)
+		datastoreCapacityBytesMetric.WithLabelValues(name).Set(float64(capacity))
+		datastoreFreeSpaceBytesMetric.WithLabelValues(name).Set(float64(freeSpace))
+		if freePercent < float64(*minDatastoreFreeSpacePercent) {
+			errs = append(errs, fmt.Errorf("%s: datastore %s has %s free of %s capacity (%.1f%%), less than %d%%",
+				datastores[name], name,
+				resource.NewQuantity(freeSpace, resource.BinarySI), resource.NewQuantity(capacity, resource.BinarySI),
+				freePercent, *minDatastoreFreeSpacePercent))
+			continue
+		}
+		if freePercent < float64(*warnDatastoreFreeSpacePercent) {
+			warnings++
+			klog.Warningf("CheckDatastoreFreeSpace: %s: datastore %s has %s free of %s capacity (%.1f%%), less than %d%%",
+				datastores[name], name,
+				resource.NewQuantity(freeSpace, resource.BinarySI), resource.NewQuantity(capacity, resource.BinarySI),
+				freePercent, *warnDatastoreFreeSpacePercent)
+			continue
+		}
+		klog.V(4).Infof("CheckDatastoreFreeSpace: datastore %s has %.1f%% free space", name, freePercent)
+	}
+	datastoreFreeSpaceWarningsMetric.WithLabelValues().Set(float64(warnings))
+
+	klog.V(2).Infof("CheckDatastoreFreeSpace checked %d datastores, %d problems found", len(names), len(errs))
+	return JoinErrors(errs)
+}
+
+// isDatastoreCluster returns true if the name is a datastore cluster (StoragePod) in the datacenter.
+func isDatastoreCluster(ctx *CheckContext, name string, dc *object.Datacenter) bool {
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	finder := find.NewFinder(ctx.VMClient, false)
+	finder.SetDatacenter(dc)
+	_, err := finder.DatastoreCluster(tctx, name)
+	return err == nil
+}
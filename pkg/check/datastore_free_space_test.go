@@ -0,0 +1,260 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckDatastoreFreeSpace(t *testing.T) {
+	const gi = 1024 * 1024 * 1024
+	tests := []struct {
+		name             string
+		defaultDatastore string
+		// datastore name in StorageClass parameters, empty for no StorageClass
+		storageClassDatastore string
+		// volume path of an in-tree PV, empty for no PV
+		pvVolumePath string
+		// datastore name -> capacity and free space in GiB
+		datastoreSpace   map[string][2]int64
+		expectedError    string
+		expectedMetrics  string
+		expectedWarnings int
+	}{
+		{
+			name:             "default datastore with free space",
+			defaultDatastore: "LocalDS_0",
+			datastoreSpace: map[string][2]int64{
+				"LocalDS_0": {100, 50},
+			},
+			expectedMetrics: `
+# HELP vsphere_datastore_free_space_percent [ALPHA] Free space of the default datastore and datastores in StorageClasses and PVs, in percent of their capacity.
+# TYPE vsphere_datastore_free_space_percent gauge
+vsphere_datastore_free_space_percent{datastore="LocalDS_0"} 50
+`,
+		},
+		{
+			name:             "default datastore almost full",
+			defaultDatastore: "LocalDS_0",
+			datastoreSpace: map[string][2]int64{
+				"LocalDS_0": {100, 5},
+			},
+			expectedError: "defaultDatastore in vSphere configuration: datastore LocalDS_0 has 5Gi free of 100Gi capacity (5.0%), less than 10%",
+			expectedMetrics: `
+# HELP vsphere_datastore_free_space_percent [ALPHA] Free space of the default datastore and datastores in StorageClasses and PVs, in percent of their capacity.
+# TYPE vsphere_datastore_free_space_percent gauge
+vsphere_datastore_free_space_percent{datastore="LocalDS_0"} 5
+`,
+		},
+		{
+			name:                  "StorageClass datastore almost full",
+			defaultDatastore:      "LocalDS_0",
+			storageClassDatastore: "LocalDS_1",
+			datastoreSpace: map[string][2]int64{
+				"LocalDS_0": {100, 10},
+				"LocalDS_1": {200, 1},
+			},
+			expectedError: "StorageClass test-sc: datastore LocalDS_1 has 1Gi free of 200Gi capacity (0.5%), less than 10%",
+			expectedMetrics: `
+# HELP vsphere_datastore_free_space_percent [ALPHA] Free space of the default datastore and datastores in StorageClasses and PVs, in percent of their capacity.
+# TYPE vsphere_datastore_free_space_percent gauge
+vsphere_datastore_free_space_percent{datastore="LocalDS_0"} 10
+vsphere_datastore_free_space_percent{datastore="LocalDS_1"} 0.5
+`,
+			expectedWarnings: 1,
+		},
+		{
+			name:             "PV datastore almost full",
+			defaultDatastore: "LocalDS_0",
+			pvVolumePath:     "[LocalDS_1] kubevols/test.vmdk",
+			datastoreSpace: map[string][2]int64{
+				"LocalDS_0": {100, 50},
+				"LocalDS_1": {100, 2},
+			},
+			expectedError: "PersistentVolume test-pv: datastore LocalDS_1 has 2Gi free of 100Gi capacity (2.0%), less than 10%",
+			expectedMetrics: `
+# HELP vsphere_datastore_free_space_percent [ALPHA] Free space of the default datastore and datastores in StorageClasses and PVs, in percent of their capacity.
+# TYPE vsphere_datastore_free_space_percent gauge
+vsphere_datastore_free_space_percent{datastore="LocalDS_0"} 50
+vsphere_datastore_free_space_percent{datastore="LocalDS_1"} 2
+`,
+		},
+		{
+			name:             "default datastore below warning threshold",
+			defaultDatastore: "LocalDS_0",
+			datastoreSpace: map[string][2]int64{
+				"LocalDS_0": {100, 15},
+			},
+			expectedMetrics: `
+# HELP vsphere_datastore_free_space_percent [ALPHA] Free space of the default datastore and datastores in StorageClasses and PVs, in percent of their capacity.
+# TYPE vsphere_datastore_free_space_percent gauge
+vsphere_datastore_free_space_percent{datastore="LocalDS_0"} 15
+`,
+			expectedWarnings: 1,
+		},
+		{
+			name:                  "StorageClass with missing datastore",
+			defaultDatastore:      "LocalDS_0",
+			storageClassDatastore: "foobar",
+			datastoreSpace: map[string][2]int64{
+				"LocalDS_0": {100, 50},
+			},
+			expectedError: "StorageClass test-sc: datastore foobar not found: failed to access datastore foobar: datastore 'foobar' not found",
+			expectedMetrics: `
+# HELP vsphere_datastore_free_space_percent [ALPHA] Free space of the default datastore and datastores in StorageClasses and PVs, in percent of their capacity.
+# TYPE vsphere_datastore_free_space_percent gauge
+vsphere_datastore_free_space_percent{datastore="LocalDS_0"} 50
+`,
+		},
+		{
+			name:                  "StorageClass with datastore cluster",
+			defaultDatastore:      "LocalDS_0",
+			storageClassDatastore: "DC0_POD0",
+			datastoreSpace: map[string][2]int64{
+				"LocalDS_0": {100, 50},
+			},
+			expectedMetrics: `
+# HELP vsphere_datastore_free_space_percent [ALPHA] Free space of the default datastore and datastores in StorageClasses and PVs, in percent of their capacity.
+# TYPE vsphere_datastore_free_space_percent gauge
+vsphere_datastore_free_space_percent{datastore="LocalDS_0"} 50
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			if test.storageClassDatastore != "" {
+				kubeClient.storageClasses = []*storagev1.StorageClass{
+					{
+						ObjectMeta:  metav1.ObjectMeta{Name: "test-sc"},
+						Provisioner: "kubernetes.io/vsphere-volume",
+						Parameters: map[string]string{
+							"datastore": test.storageClassDatastore,
+						},
+					},
+				}
+			}
+			if test.pvVolumePath != "" {
+				kubeClient.pvs = []*v1.PersistentVolume{
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "test-pv"},
+						Spec: v1.PersistentVolumeSpec{
+							PersistentVolumeSource: v1.PersistentVolumeSource{
+								VsphereVolume: &v1.VsphereVirtualDiskVolumeSource{VolumePath: test.pvVolumePath},
+							},
+						},
+					},
+				}
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMConfig.Workspace.DefaultDatastore = test.defaultDatastore
+
+			dc, err := getDatacenter(ctx, defaultDC)
+			if err != nil {
+				t.Fatalf("Failed to get datacenter: %s", err)
+			}
+			for name, space := range test.datastoreSpace {
+				ds, err := getDataStoreByName(ctx, name, dc)
+				if err != nil {
+					t.Fatalf("Failed to get datastore %s: %s", name, err)
+				}
+				simDatastore := simulator.Map.Get(ds.Reference()).(*simulator.Datastore)
+				simDatastore.Summary.Capacity = space[0] * gi
+				simDatastore.Summary.FreeSpace = space[1] * gi
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckDatastoreFreeSpace(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_datastore_free_space_percent"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+			expectedWarningsMetric := fmt.Sprintf(`
+# HELP vsphere_datastore_free_space_warnings_total [ALPHA] Number of datastores with free space below warn-datastore-free-space-percent, but not below min-datastore-free-space-percent.
+# TYPE vsphere_datastore_free_space_warnings_total gauge
+vsphere_datastore_free_space_warnings_total %d
+`, test.expectedWarnings)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedWarningsMetric), "vsphere_datastore_free_space_warnings_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+func TestCheckDatastoreFreeSpaceBytes(t *testing.T) {
+	// Stage
+	const gi = 1024 * 1024 * 1024
+	kubeClient := &fakeKubeClient{
+		nodes: defaultNodes(),
+	}
+	ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+	ctx.VMConfig.Workspace.DefaultDatastore = "LocalDS_0"
+
+	dc, err := getDatacenter(ctx, defaultDC)
+	if err != nil {
+		t.Fatalf("Failed to get datacenter: %s", err)
+	}
+	ds, err := getDataStoreByName(ctx, "LocalDS_0", dc)
+	if err != nil {
+		t.Fatalf("Failed to get datastore: %s", err)
+	}
+	simDatastore := simulator.Map.Get(ds.Reference()).(*simulator.Datastore)
+	simDatastore.Summary.Capacity = 100 * gi
+	simDatastore.Summary.FreeSpace = 40 * gi
+
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+
+	// Act
+	err = CheckDatastoreFreeSpace(ctx)
+
+	// Assert
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	expectedMetrics := `
+# HELP vsphere_datastore_capacity_bytes [ALPHA] Capacity of the default datastore and datastores in StorageClasses and PVs.
+# TYPE vsphere_datastore_capacity_bytes gauge
+vsphere_datastore_capacity_bytes{datastore="LocalDS_0"} 1.073741824e+11
+# HELP vsphere_datastore_free_space_bytes [ALPHA] Free space of the default datastore and datastores in StorageClasses and PVs.
+# TYPE vsphere_datastore_free_space_bytes gauge
+vsphere_datastore_free_space_bytes{datastore="LocalDS_0"} 4.294967296e+10
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_datastore_capacity_bytes", "vsphere_datastore_free_space_bytes"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+}
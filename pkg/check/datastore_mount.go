@@ -0,0 +1,128 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	datastoreMountInconsistentMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_datastore_mount_inconsistent_total",
+			Help:           "Number of datastores used by the cluster that are not mounted and accessible on all ESXi hosts running node VMs.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(datastoreMountInconsistentMetric)
+}
+
+// CheckDatastoreMountPathConsistencyAcrossHosts tests that datastores used by node VMs and the default
+// datastore are mounted and accessible on all ESXi hosts that run node VMs. Pods with volumes on a datastore
+// that is not available on some hosts fail to start when they are rescheduled to nodes on these hosts.
+func CheckDatastoreMountPathConsistencyAcrossHosts(ctx *CheckContext) error {
+	vms, err := getNodeVMs(ctx, []string{"runtime.host"})
+	if err != nil {
+		return err
+	}
+	hosts := make(map[vim.ManagedObjectReference]bool)
+	for _, vm := range vms {
+		if vm.Runtime.Host != nil {
+			hosts[*vm.Runtime.Host] = true
+		}
+	}
+	if len(hosts) == 0 {
+		klog.V(2).Infof("CheckDatastoreMountPathConsistencyAcrossHosts: no ESXi hosts with node VMs found, skipping")
+		datastoreMountInconsistentMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+	hostNames, err := getHostNames(ctx, hosts)
+	if err != nil {
+		return err
+	}
+
+	dsRefs, err := getNodeDatastores(ctx)
+	if err != nil {
+		return err
+	}
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var datastores []mo.Datastore
+	if err := pc.Retrieve(tctx, dsRefs, []string{"name", "host"}, &datastores); err != nil {
+		return fmt.Errorf("failed to get datastore host mounts: %s", err)
+	}
+	sort.Slice(datastores, func(i, j int) bool { return datastores[i].Name < datastores[j].Name })
+
+	var errs []error
+	for _, ds := range datastores {
+		problems := getDatastoreMountProblems(ds, hosts, hostNames)
+		if len(problems) > 0 {
+			errs = append(errs, fmt.Errorf("datastore %s is not mounted and accessible on all ESXi hosts with node VMs: %s", ds.Name, strings.Join(problems, ", ")))
+		}
+	}
+	datastoreMountInconsistentMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(len(errs)))
+
+	klog.V(2).Infof("CheckDatastoreMountPathConsistencyAcrossHosts checked %d datastores on %d hosts, %d problems found", len(datastores), len(hosts), len(errs))
+	return JoinErrors(errs)
+}
+
+// getDatastoreMountProblems returns sorted descriptions of hosts where the datastore is not mounted
+// or not accessible. Mount info that is not set by vSphere is not reported.
+func getDatastoreMountProblems(ds mo.Datastore, hosts map[vim.ManagedObjectReference]bool, hostNames map[vim.ManagedObjectReference]string) []string {
+	mounts := make(map[vim.ManagedObjectReference]vim.HostMountInfo)
+	for _, mount := range ds.Host {
+		mounts[mount.Key] = mount.MountInfo
+	}
+
+	var problems []string
+	for host := range hosts {
+		name := hostNames[host]
+		if name == "" {
+			name = host.Value
+		}
+		mount, found := mounts[host]
+		switch {
+		case !found:
+			problems = append(problems, fmt.Sprintf("%s (not mounted)", name))
+		case mount.Mounted != nil && !*mount.Mounted:
+			problems = append(problems, fmt.Sprintf("%s (unmounted)", name))
+		case mount.Accessible != nil && !*mount.Accessible:
+			problems = append(problems, fmt.Sprintf("%s (inaccessible: %s)", name, mount.InaccessibleReason))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// getHostNames returns names of given ESXi hosts, host -> name.
+func getHostNames(ctx *CheckContext, hosts map[vim.ManagedObjectReference]bool) (map[vim.ManagedObjectReference]string, error) {
+	var refs []vim.ManagedObjectReference
+	for ref := range hosts {
+		refs = append(refs, ref)
+	}
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var hostMos []mo.HostSystem
+	pc := property.DefaultCollector(ctx.VMClient)
+	if err := pc.Retrieve(tctx, refs, []string{"name"}, &hostMos); err != nil {
+		return nil, fmt.Errorf("failed to get ESXi host names: %s", err)
+	}
+	names := make(map[vim.ManagedObjectReference]string)
+	for _, host := range hostMos {
+		names[host.Self] = host.Name
+	}
+	return names, nil
+}
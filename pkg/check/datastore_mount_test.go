@@ -0,0 +1,117 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckDatastoreMountPathConsistencyAcrossHosts(t *testing.T) {
+	mounted := types.HostMountInfo{Mounted: types.NewBool(true), Accessible: types.NewBool(true)}
+
+	tests := []struct {
+		name string
+		// host ID -> mount info of LocalDS_0, hosts that are not present do not mount the datastore
+		mounts        map[string]types.HostMountInfo
+		expectedError string
+	}{
+		{
+			name: "mounted on all hosts",
+			mounts: map[string]types.HostMountInfo{
+				"host-37": mounted,
+				"host-45": mounted,
+				"host-53": mounted,
+			},
+		},
+		{
+			name: "missing on a host",
+			mounts: map[string]types.HostMountInfo{
+				"host-37": mounted,
+				"host-53": mounted,
+			},
+			expectedError: "datastore LocalDS_0 is not mounted and accessible on all ESXi hosts with node VMs: DC0_C0_H1 (not mounted)",
+		},
+		{
+			name: "unmounted and inaccessible",
+			mounts: map[string]types.HostMountInfo{
+				"host-37": {Mounted: types.NewBool(false), Accessible: types.NewBool(false)},
+				"host-45": {Mounted: types.NewBool(true), Accessible: types.NewBool(false), InaccessibleReason: "AllPathsDown_Start"},
+				"host-53": mounted,
+			},
+			expectedError: "datastore LocalDS_0 is not mounted and accessible on all ESXi hosts with node VMs: DC0_C0_H0 (unmounted), DC0_C0_H1 (inaccessible: AllPathsDown_Start)",
+		},
+		{
+			name: "unset mount info",
+			mounts: map[string]types.HostMountInfo{
+				"host-37": {},
+				"host-45": mounted,
+				"host-53": mounted,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			dc, err := getDatacenter(ctx, defaultDC)
+			if err != nil {
+				t.Fatalf("Failed to get datacenter: %s", err)
+			}
+			ds, err := getDataStoreByName(ctx, "LocalDS_0", dc)
+			if err != nil {
+				t.Fatalf("Failed to get datastore: %s", err)
+			}
+			simDS := simulator.Map.Get(ds.Reference()).(*simulator.Datastore)
+			simDS.Host = nil
+			for hostID, mountInfo := range test.mounts {
+				simDS.Host = append(simDS.Host, types.DatastoreHostMount{
+					Key:       types.ManagedObjectReference{Type: "HostSystem", Value: hostID},
+					MountInfo: mountInfo,
+				})
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckDatastoreMountPathConsistencyAcrossHosts(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_datastore_mount_inconsistent_total [ALPHA] Number of datastores used by the cluster that are not mounted and accessible on all ESXi hosts running node VMs.
+# TYPE vsphere_datastore_mount_inconsistent_total gauge
+vsphere_datastore_mount_inconsistent_total{vcenter="dc0"} %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_datastore_mount_inconsistent_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// kubevolsFolder is the datastore folder where the in-tree vSphere volume plugin creates volumes.
+	kubevolsFolder = "kubevols"
+	// dynamicPVCDiskInfix follows the cluster ID in names of disks provisioned by the in-tree vSphere volume plugin.
+	dynamicPVCDiskInfix = "-dynamic-pvc-"
+
+	volumeKindLabel = "kind"
+	// Values of volumeKindLabel.
+	volumeKindCNS    = "cns"
+	volumeKindInTree = "in-tree"
+)
+
+var (
+	orphanedVolumesMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_orphaned_volumes_total",
+			Help:           "Number of CNS volumes of the cluster and disks in kubevols folders of datastores that are not used by any PersistentVolume.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{volumeKindLabel},
+	)
+
+	orphanedVolumesBytesMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_orphaned_volumes_bytes",
+			Help:           "Total size of CNS volumes of the cluster and disks in kubevols folders of datastores that are not used by any PersistentVolume.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{volumeKindLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(orphanedVolumesMetric)
+	legacyregistry.MustRegister(orphanedVolumesBytesMetric)
+}
+
+// orphanedVolume is a volume in vSphere that no PersistentVolume uses.
+type orphanedVolume struct {
+	kind string
+	// description identifies the volume in the check error.
+	description string
+	sizeBytes   int64
+}
+
+// volumeReferences are volumes used by PersistentVolumes and VolumeAttachments of the cluster.
+type volumeReferences struct {
+	// pvNames are names of all PersistentVolumes.
+	pvNames map[string]bool
+	// csiVolumes are volume handles of vSphere CSI volumes.
+	csiVolumes map[string]bool
+	// inTreeVolumes are paths of in-tree vSphere volumes, "[datastore] path".
+	inTreeVolumes map[string]bool
+}
+
+// CheckOrphanedVolumes tests that all CNS volumes created by the cluster and all disks of the cluster in kubevols
+// folders of the default datastore and datastores of in-tree PVs are used by a PersistentVolume, or by an inline volume
+// of a VolumeAttachment. Failed detaches and clusters deleted without deleting their volumes leave such volumes
+// behind and they silently consume datastore space.
+func CheckOrphanedVolumes(ctx *CheckContext) error {
+	pvs, err := ctx.KubeClient.ListPVs(ctx.Context)
+	if err != nil {
+		return err
+	}
+	attachments, err := ctx.KubeClient.ListVolumeAttachments(ctx.Context)
+	if err != nil {
+		return err
+	}
+	infra, err := ctx.KubeClient.GetInfrastructure(ctx.Context)
+	if err != nil {
+		return err
+	}
+	refs := getVolumeReferences(pvs, attachments)
+
+	var errs []error
+	var orphans []orphanedVolume
+	if infra == nil || infra.Status.InfrastructureName == "" {
+		klog.V(2).Infof("CheckOrphanedVolumes: cluster ID is not known, skipping CNS volumes and in-tree disks")
+	} else {
+		clusterID := infra.Status.InfrastructureName
+		volumes, err := listClusterCNSVolumes(ctx, clusterID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list CNS volumes of cluster %s: %s", clusterID, err))
+		} else {
+			orphans = append(orphans, findOrphanedCNSVolumes(volumes, refs)...)
+		}
+
+		dc, err := getDatacenter(ctx, ctx.VMConfig.Workspace.Datacenter)
+		if err != nil {
+			return err
+		}
+		for _, dsName := range getInTreeDatastores(ctx.VMConfig.Workspace.DefaultDatastore, refs) {
+			ds, err := getDataStoreByName(ctx, dsName, dc)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to access datastore %s: %s", dsName, err))
+				continue
+			}
+			disks, err := listKubevolsDisks(ctx, ds)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			orphans = append(orphans, findOrphanedDisks(dsName, clusterID, disks, refs)...)
+		}
+	}
+
+	orphanedVolumesMetric.Reset()
+	orphanedVolumesBytesMetric.Reset()
+	for _, kind := range []string{volumeKindCNS, volumeKindInTree} {
+		orphanedVolumesMetric.WithLabelValues(kind).Set(0)
+		orphanedVolumesBytesMetric.WithLabelValues(kind).Set(0)
+	}
+	var totalBytes int64
+	var orphanErrs []error
+	for _, orphan := range orphans {
+		orphanedVolumesMetric.WithLabelValues(orphan.kind).Inc()
+		orphanedVolumesBytesMetric.WithLabelValues(orphan.kind).Add(float64(orphan.sizeBytes))
+		totalBytes += orphan.sizeBytes
+		orphanErrs = append(orphanErrs, fmt.Errorf("%s of size %s is not used by any PersistentVolume",
+			orphan.description, resource.NewQuantity(orphan.sizeBytes, resource.BinarySI)))
+	}
+	if len(orphans) > 0 {
+		errs = append(errs, fmt.Errorf("found %d orphaned volumes of total size %s", len(orphans), resource.NewQuantity(totalBytes, resource.BinarySI)))
+		errs = append(errs, orphanErrs...)
+	}
+
+	klog.V(2).Infof("CheckOrphanedVolumes found %d orphaned volumes, %d problems found", len(orphans), len(errs))
+	return JoinErrors(errs)
+}
+
+// getVolumeReferences returns volumes used by the PVs and by inline volumes of the VolumeAttachments.
+func getVolumeReferences(pvs []*v1.PersistentVolume, attachments []*storagev1.VolumeAttachment) *volumeReferences {
+	refs := &volumeReferences{
+		pvNames:       make(map[string]bool),
+		csiVolumes:    make(map[string]bool),
+		inTreeVolumes: make(map[string]bool),
+	}
+	addSource := func(source v1.PersistentVolumeSource) {
+		switch {
+		case source.VsphereVolume != nil:
+			if volumePath, ok := normalizeVolumePath(source.VsphereVolume.VolumePath); ok {
+				refs.inTreeVolumes[volumePath] = true
+			}
+		case source.CSI != nil && source.CSI.Driver == vSphereCSIDdriver:
+			refs.csiVolumes[source.CSI.VolumeHandle] = true
+		}
+	}
+	for _, pv := range pvs {
+		refs.pvNames[pv.Name] = true
+		addSource(pv.Spec.PersistentVolumeSource)
+	}
+	for _, va := range attachments {
+		if va.Spec.Source.InlineVolumeSpec != nil {
+			addSource(va.Spec.Source.InlineVolumeSpec.PersistentVolumeSource)
+		}
+	}
+	return refs
+}
+
+// normalizeVolumePath returns the in-tree volume path as "[datastore] path", without redundant slashes.
+func normalizeVolumePath(volumePath string) (string, bool) {
+	var dsPath object.DatastorePath
+	if !dsPath.FromString(volumePath) {
+		return "", false
+	}
+	dsPath.Path = strings.TrimPrefix(path.Clean("/"+dsPath.Path), "/")
+	return dsPath.String(), true
+}
+
+// getInTreeDatastores returns sorted names of the default datastore and datastores of in-tree volumes.
+func getInTreeDatastores(defaultDatastore string, refs *volumeReferences) []string {
+	datastores := make(map[string]bool)
+	if defaultDatastore != "" {
+		datastores[defaultDatastore] = true
+	}
+	for volumePath := range refs.inTreeVolumes {
+		var dsPath object.DatastorePath
+		if dsPath.FromString(volumePath) {
+			datastores[dsPath.Datastore] = true
+		}
+	}
+	var names []string
+	for name := range datastores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// findOrphanedCNSVolumes returns CNS volumes that are not used by any PV. Volumes of in-tree PVs migrated
+// to CSI have a different ID than the PV volume path, they're matched by the PV name in the CNS metadata.
+func findOrphanedCNSVolumes(volumes []cnstypes.CnsVolume, refs *volumeReferences) []orphanedVolume {
+	var orphans []orphanedVolume
+	for _, volume := range volumes {
+		if refs.csiVolumes[volume.VolumeId.Id] || hasPVMetadata(volume, refs.pvNames) {
+			continue
+		}
+		var sizeBytes int64
+		if volume.BackingObjectDetails != nil {
+			sizeBytes = volume.BackingObjectDetails.GetCnsBackingObjectDetails().CapacityInMb * 1024 * 1024
+		}
+		orphans = append(orphans, orphanedVolume{
+			kind:        volumeKindCNS,
+			description: fmt.Sprintf("CNS volume %s (%s) on datastore %s", volume.VolumeId.Id, volume.Name, volume.DatastoreUrl),
+			sizeBytes:   sizeBytes,
+		})
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].description < orphans[j].description })
+	return orphans
+}
+
+// hasPVMetadata returns true if the CNS metadata of the volume refer to one of the PVs.
+func hasPVMetadata(volume cnstypes.CnsVolume, pvNames map[string]bool) bool {
+	for _, entity := range volume.Metadata.EntityMetadata {
+		metadata, ok := entity.(*cnstypes.CnsKubernetesEntityMetadata)
+		if !ok {
+			continue
+		}
+		if metadata.EntityType == string(cnstypes.CnsKubernetesEntityTypePV) && pvNames[metadata.EntityName] {
+			return true
+		}
+	}
+	return false
+}
+
+// findOrphanedDisks returns disks in the kubevols folder of the datastore that were provisioned for the cluster
+// and are not used by any PV. The folder is shared by all clusters that use the datastore, disks of the cluster
+// are those named by the in-tree provisioner after the cluster ID, "<cluster ID>-dynamic-pvc-<UUID>.vmdk".
+func findOrphanedDisks(dsName, clusterID string, disks []types.BaseFileInfo, refs *volumeReferences) []orphanedVolume {
+	prefix := clusterID + dynamicPVCDiskInfix
+	var orphans []orphanedVolume
+	for _, disk := range disks {
+		info := disk.GetFileInfo()
+		if !strings.HasPrefix(info.Path, prefix) {
+			klog.V(4).Infof("CheckOrphanedVolumes: skipping disk %s in datastore %s, it was not provisioned for cluster %s", info.Path, dsName, clusterID)
+			continue
+		}
+		volumePath := object.DatastorePath{Datastore: dsName, Path: path.Join(kubevolsFolder, info.Path)}
+		if refs.inTreeVolumes[volumePath.String()] {
+			continue
+		}
+		orphans = append(orphans, orphanedVolume{
+			kind:        volumeKindInTree,
+			description: fmt.Sprintf("disk %s", volumePath.String()),
+			sizeBytes:   info.FileSize,
+		})
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].description < orphans[j].description })
+	return orphans
+}
+
+// listKubevolsDisks returns virtual disks in the kubevols folder of the datastore. The folder does not exist
+// until the first in-tree volume is provisioned, no disks are returned then.
+func listKubevolsDisks(ctx *CheckContext, ds *object.Datastore) ([]types.BaseFileInfo, error) {
+	dsName := ds.Name()
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	browser, err := ds.Browser(tctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create browser for datastore %s: %s", dsName, err)
+	}
+
+	// VmDiskFileQuery returns only disk descriptors, not their -flat.vmdk extents.
+	spec := types.HostDatastoreBrowserSearchSpec{
+		MatchPattern: []string{"*.vmdk"},
+		Query:        []types.BaseFileQuery{&types.VmDiskFileQuery{}},
+		Details: &types.FileQueryFlags{
+			FileType: true,
+			FileSize: true,
+		},
+	}
+	task, err := browser.SearchDatastore(tctx, ds.Path(kubevolsFolder), &spec)
+	var info *types.TaskInfo
+	if err == nil {
+		info, err = task.WaitForResult(tctx, nil)
+	}
+	if err != nil {
+		if types.IsFileNotFound(err) {
+			klog.V(4).Infof("CheckOrphanedVolumes: datastore %s has no %s folder", dsName, kubevolsFolder)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list datastore %s at path %s: %s", dsName, kubevolsFolder, err)
+	}
+	res, ok := info.Result.(types.HostDatastoreBrowserSearchResults)
+	if !ok {
+		return nil, fmt.Errorf("unknown data received from datastore %s browser: %T", dsName, info.Result)
+	}
+	return res.File, nil
+}
@@ -0,0 +1,273 @@
+package check
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	ocpv1 "github.com/openshift/api/config/v1"
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func inlineVolumeAttachment(name, volumePath string) *storagev1.VolumeAttachment {
+	return &storagev1.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: storagev1.VolumeAttachmentSpec{
+			Source: storagev1.VolumeAttachmentSource{
+				InlineVolumeSpec: &v1.PersistentVolumeSpec{
+					PersistentVolumeSource: v1.PersistentVolumeSource{
+						VsphereVolume: &v1.VsphereVirtualDiskVolumeSource{
+							VolumePath: volumePath,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// createKubevolsDisks creates virtual disks in kubevols folder of the datastore in the simulator.
+// The simulator keeps datastore files, the folder must be removed by removeKubevols.
+func createKubevolsDisks(ctx *CheckContext, dsName string, names ...string) error {
+	dc, err := getDatacenter(ctx, ctx.VMConfig.Workspace.Datacenter)
+	if err != nil {
+		return err
+	}
+	folder := object.DatastorePath{Datastore: dsName, Path: kubevolsFolder}
+	if err := object.NewFileManager(ctx.VMClient).MakeDirectory(context.TODO(), folder.String(), dc, true); err != nil {
+		return err
+	}
+	diskManager := object.NewVirtualDiskManager(ctx.VMClient)
+	for _, name := range names {
+		diskPath := object.DatastorePath{Datastore: dsName, Path: kubevolsFolder + "/" + name}
+		spec := &types.FileBackedVirtualDiskSpec{
+			VirtualDiskSpec: types.VirtualDiskSpec{
+				DiskType:    string(types.VirtualDiskTypeThin),
+				AdapterType: string(types.VirtualDiskAdapterTypeLsiLogic),
+			},
+			CapacityKb: 1024,
+		}
+		task, err := diskManager.CreateVirtualDisk(context.TODO(), diskPath.String(), dc, spec)
+		if err != nil {
+			return err
+		}
+		if err := task.Wait(context.TODO()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeKubevols removes kubevols folder of the datastore in the simulator.
+func removeKubevols(ctx *CheckContext, dsName string) error {
+	dc, err := getDatacenter(ctx, ctx.VMConfig.Workspace.Datacenter)
+	if err != nil {
+		return err
+	}
+	folder := object.DatastorePath{Datastore: dsName, Path: kubevolsFolder}
+	task, err := object.NewFileManager(ctx.VMClient).DeleteDatastoreFile(context.TODO(), folder.String(), dc)
+	if err != nil {
+		return err
+	}
+	return task.Wait(context.TODO())
+}
+
+func TestCheckOrphanedVolumes(t *testing.T) {
+	clusterInfrastructure := &ocpv1.Infrastructure{
+		Status: ocpv1.InfrastructureStatus{InfrastructureName: "cluster-abcd"},
+	}
+	// The simulator does not implement CNS.
+	cnsNotAvailableError := `failed to list CNS volumes of cluster cluster-abcd: error querying CNS volumes: POST "/vsanHealth": 404 Not Found`
+	tests := []struct {
+		name            string
+		infrastructure  *ocpv1.Infrastructure
+		disks           []string
+		pvs             []*v1.PersistentVolume
+		attachments     []*storagev1.VolumeAttachment
+		expectedError   string
+		expectedMetrics string
+	}{
+		{
+			name: "no kubevols folder",
+			expectedMetrics: `
+# HELP vsphere_orphaned_volumes_total [ALPHA] Number of CNS volumes of the cluster and disks in kubevols folders of datastores that are not used by any PersistentVolume.
+# TYPE vsphere_orphaned_volumes_total gauge
+vsphere_orphaned_volumes_total{kind="cns"} 0
+vsphere_orphaned_volumes_total{kind="in-tree"} 0
+`,
+		},
+		{
+			name:           "all disks used",
+			infrastructure: clusterInfrastructure,
+			disks:          []string{"cluster-abcd-dynamic-pvc-1.vmdk", "cluster-abcd-dynamic-pvc-inline.vmdk"},
+			pvs: []*v1.PersistentVolume{
+				inTreePV("pv-1", "[LocalDS_0] kubevols/cluster-abcd-dynamic-pvc-1.vmdk"),
+			},
+			attachments: []*storagev1.VolumeAttachment{
+				inlineVolumeAttachment("csi-1234", "[LocalDS_0] /kubevols//cluster-abcd-dynamic-pvc-inline.vmdk"),
+			},
+			expectedError: cnsNotAvailableError,
+			expectedMetrics: `
+# HELP vsphere_orphaned_volumes_total [ALPHA] Number of CNS volumes of the cluster and disks in kubevols folders of datastores that are not used by any PersistentVolume.
+# TYPE vsphere_orphaned_volumes_total gauge
+vsphere_orphaned_volumes_total{kind="cns"} 0
+vsphere_orphaned_volumes_total{kind="in-tree"} 0
+`,
+		},
+		{
+			name:           "orphaned disks",
+			infrastructure: clusterInfrastructure,
+			disks:          []string{"cluster-abcd-dynamic-pvc-1.vmdk", "cluster-abcd-dynamic-pvc-2.vmdk", "cluster-abcd-dynamic-pvc-3.vmdk"},
+			pvs: []*v1.PersistentVolume{
+				inTreePV("pv-1", "[LocalDS_0] kubevols/cluster-abcd-dynamic-pvc-1.vmdk"),
+			},
+			expectedError: cnsNotAvailableError + ";\n" +
+				"found 2 orphaned volumes of total size 0;\n" +
+				"disk [LocalDS_0] kubevols/cluster-abcd-dynamic-pvc-2.vmdk of size 0 is not used by any PersistentVolume;\n" +
+				"disk [LocalDS_0] kubevols/cluster-abcd-dynamic-pvc-3.vmdk of size 0 is not used by any PersistentVolume",
+			expectedMetrics: `
+# HELP vsphere_orphaned_volumes_total [ALPHA] Number of CNS volumes of the cluster and disks in kubevols folders of datastores that are not used by any PersistentVolume.
+# TYPE vsphere_orphaned_volumes_total gauge
+vsphere_orphaned_volumes_total{kind="cns"} 0
+vsphere_orphaned_volumes_total{kind="in-tree"} 2
+`,
+		},
+		{
+			// The kubevols folder is shared with other clusters and with disks created manually.
+			name:           "disks of other clusters",
+			infrastructure: clusterInfrastructure,
+			disks:          []string{"cluster-abcd-dynamic-pvc-1.vmdk", "cluster-efgh-dynamic-pvc-2.vmdk", "cluster-abcd-data.vmdk"},
+			pvs: []*v1.PersistentVolume{
+				inTreePV("pv-1", "[LocalDS_0] kubevols/cluster-abcd-dynamic-pvc-1.vmdk"),
+			},
+			expectedError: cnsNotAvailableError,
+			expectedMetrics: `
+# HELP vsphere_orphaned_volumes_total [ALPHA] Number of CNS volumes of the cluster and disks in kubevols folders of datastores that are not used by any PersistentVolume.
+# TYPE vsphere_orphaned_volumes_total gauge
+vsphere_orphaned_volumes_total{kind="cns"} 0
+vsphere_orphaned_volumes_total{kind="in-tree"} 0
+`,
+		},
+		{
+			name:  "cluster ID not known",
+			disks: []string{"cluster-abcd-dynamic-pvc-1.vmdk"},
+			expectedMetrics: `
+# HELP vsphere_orphaned_volumes_total [ALPHA] Number of CNS volumes of the cluster and disks in kubevols folders of datastores that are not used by any PersistentVolume.
+# TYPE vsphere_orphaned_volumes_total gauge
+vsphere_orphaned_volumes_total{kind="cns"} 0
+vsphere_orphaned_volumes_total{kind="in-tree"} 0
+`,
+		},
+		{
+			name:           "CNS not available",
+			infrastructure: clusterInfrastructure,
+			expectedError:  cnsNotAvailableError,
+			expectedMetrics: `
+# HELP vsphere_orphaned_volumes_total [ALPHA] Number of CNS volumes of the cluster and disks in kubevols folders of datastores that are not used by any PersistentVolume.
+# TYPE vsphere_orphaned_volumes_total gauge
+vsphere_orphaned_volumes_total{kind="cns"} 0
+vsphere_orphaned_volumes_total{kind="in-tree"} 0
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			legacyregistry.Reset()
+			kubeClient := &fakeKubeClient{
+				infrastructure: test.infrastructure,
+				pvs:            test.pvs,
+				attachments:    test.attachments,
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			if len(test.disks) > 0 {
+				if err := createKubevolsDisks(ctx, "LocalDS_0", test.disks...); err != nil {
+					t.Fatalf("Failed to create disks: %s", err)
+				}
+				defer func() {
+					if err := removeKubevols(ctx, "LocalDS_0"); err != nil {
+						t.Errorf("Failed to remove disks: %s", err)
+					}
+				}()
+			}
+
+			// Act
+			err = CheckOrphanedVolumes(ctx)
+
+			// Assert
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			if errString != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, errString)
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_orphaned_volumes_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+func TestFindOrphanedCNSVolumes(t *testing.T) {
+	volume := func(id, name string, capacityMb int64, pvName string) cnstypes.CnsVolume {
+		v := cnstypes.CnsVolume{
+			VolumeId:     cnstypes.CnsVolumeId{Id: id},
+			Name:         name,
+			DatastoreUrl: "ds:///vmfs/volumes/ds-1/",
+			BackingObjectDetails: &cnstypes.CnsBlockBackingDetails{
+				CnsBackingObjectDetails: cnstypes.CnsBackingObjectDetails{CapacityInMb: capacityMb},
+			},
+		}
+		if pvName != "" {
+			v.Metadata.EntityMetadata = []cnstypes.BaseCnsEntityMetadata{
+				&cnstypes.CnsKubernetesEntityMetadata{
+					CnsEntityMetadata: cnstypes.CnsEntityMetadata{EntityName: pvName},
+					EntityType:        string(cnstypes.CnsKubernetesEntityTypePV),
+				},
+			}
+		}
+		return v
+	}
+	volumes := []cnstypes.CnsVolume{
+		volume("id-1", "pvc-1", 1024, "pv-1"),
+		// Migrated in-tree volume, matched by the PV name in metadata.
+		volume("id-2", "kubevols-pv-2", 2048, "pv-2"),
+		// PV with the name in metadata was deleted.
+		volume("id-3", "pvc-3", 3072, "pv-3"),
+		volume("id-4", "pvc-4", 512, ""),
+	}
+	refs := getVolumeReferences([]*v1.PersistentVolume{
+		csiPV("pv-1", "id-1"),
+		inTreePV("pv-2", "[ds-1] kubevols/pv-2.vmdk"),
+	}, nil)
+
+	orphans := findOrphanedCNSVolumes(volumes, refs)
+
+	expected := []orphanedVolume{
+		{kind: volumeKindCNS, description: "CNS volume id-3 (pvc-3) on datastore ds:///vmfs/volumes/ds-1/", sizeBytes: 3072 * 1024 * 1024},
+		{kind: volumeKindCNS, description: "CNS volume id-4 (pvc-4) on datastore ds:///vmfs/volumes/ds-1/", sizeBytes: 512 * 1024 * 1024},
+	}
+	if len(orphans) != len(expected) {
+		t.Fatalf("Expected %d orphans, got %+v", len(expected), orphans)
+	}
+	for i := range expected {
+		if orphans[i] != expected[i] {
+			t.Errorf("Expected orphan %+v, got %+v", expected[i], orphans[i])
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package check
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/pbm/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// tagBasedPlacementNamespace is namespace of storage policy rules that place volumes by datastore tags.
+	tagBasedPlacementNamespace = "http://www.vmware.com/storage/tag"
+)
+
+var (
+	storagePolicyCoverageGapsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_storage_policy_coverage_gaps_total",
+			Help:           "Number of datastores used by node VMs that do not match tag based storage policies of StorageClasses.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(storagePolicyCoverageGapsMetric)
+}
+
+// CheckClusterStoragePolicyAppliesToAllNodeDatastores tests that tag based storage policies of StorageClasses
+// match all datastores used by node VMs and the default datastore. Volume provisioning with such a policy
+// fails or succeeds depending on where a pod gets scheduled when a datastore misses the tag.
+// Policies without tag based placement rules are skipped, they're expected to match only some datastores.
+func CheckClusterStoragePolicyAppliesToAllNodeDatastores(ctx *CheckContext) error {
+	scs, err := ctx.KubeClient.ListStorageClasses(ctx.Context)
+	if err != nil {
+		return err
+	}
+	// policy name -> StorageClasses that use it
+	policies := make(map[string][]string)
+	for _, sc := range scs {
+		if sc.Provisioner != "kubernetes.io/vsphere-volume" && sc.Provisioner != vSphereCSIDdriver {
+			continue
+		}
+		for k, v := range sc.Parameters {
+			if strings.ToLower(k) == storagePolicyParameter {
+				policies[v] = append(policies[v], sc.Name)
+			}
+		}
+	}
+	if len(policies) == 0 {
+		klog.V(4).Infof("CheckClusterStoragePolicyAppliesToAllNodeDatastores: no StorageClass with a storage policy found, skipping")
+		storagePolicyCoverageGapsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+
+	dsRefs, err := getNodeDatastores(ctx)
+	if err != nil {
+		return err
+	}
+	dsNames, err := getDatastoreNames(ctx, dsRefs)
+	if err != nil {
+		return err
+	}
+	var nodeDatastores []string
+	for _, name := range dsNames {
+		nodeDatastores = append(nodeDatastores, name)
+	}
+	sort.Strings(nodeDatastores)
+
+	var policyNames []string
+	for name := range policies {
+		policyNames = append(policyNames, name)
+	}
+	sort.Strings(policyNames)
+
+	var errs []error
+	gaps := 0
+	for _, policyName := range policyNames {
+		scNames := strings.Join(policies[policyName], ", ")
+		profiles, err := getPolicy(ctx, policyName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("StorageClass %s: %s", scNames, err))
+			continue
+		}
+		if len(profiles) != 1 {
+			errs = append(errs, fmt.Errorf("StorageClass %s: error listing storage policy %s: found %d policies", scNames, policyName, len(profiles)))
+			continue
+		}
+		if !isTagBasedPolicy(profiles[0]) {
+			klog.V(4).Infof("CheckClusterStoragePolicyAppliesToAllNodeDatastores: storage policy %s does not use tag based placement, skipping", policyName)
+			continue
+		}
+
+		policyDatastores, err := getPolicyDatastores(ctx, profiles[0].GetPbmProfile().ProfileId)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("StorageClass %s: storage policy %s: %s", scNames, policyName, err))
+			continue
+		}
+		uncovered := getUncoveredDatastores(nodeDatastores, policyDatastores)
+		if len(uncovered) == 0 {
+			klog.V(4).Infof("Storage policy %s covers all %d datastores used by nodes", policyName, len(nodeDatastores))
+			continue
+		}
+		gaps += len(uncovered)
+		errs = append(errs, fmt.Errorf("StorageClass %s: storage policy %s does not match datastores used by nodes: %s", scNames, policyName, strings.Join(uncovered, ", ")))
+	}
+	storagePolicyCoverageGapsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(gaps))
+
+	klog.V(2).Infof("CheckClusterStoragePolicyAppliesToAllNodeDatastores checked %d storage policies and %d datastores, %d coverage gaps found", len(policyNames), len(nodeDatastores), gaps)
+	return JoinErrors(errs)
+}
+
+// isTagBasedPolicy returns true if the storage policy has a tag based placement rule.
+func isTagBasedPolicy(profile types.BasePbmProfile) bool {
+	capabilityProfile, ok := profile.(*types.PbmCapabilityProfile)
+	if !ok {
+		return false
+	}
+	constraints, ok := capabilityProfile.Constraints.(*types.PbmCapabilitySubProfileConstraints)
+	if !ok {
+		return false
+	}
+	for _, subProfile := range constraints.SubProfiles {
+		for _, capability := range subProfile.Capability {
+			if capability.Id.Namespace == tagBasedPlacementNamespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getUncoveredDatastores returns sorted names of datastores that are not in policyDatastores.
+func getUncoveredDatastores(datastores, policyDatastores []string) []string {
+	covered := make(map[string]bool)
+	for _, name := range policyDatastores {
+		covered[name] = true
+	}
+	var uncovered []string
+	for _, name := range datastores {
+		if !covered[name] {
+			uncovered = append(uncovered, name)
+		}
+	}
+	sort.Strings(uncovered)
+	return uncovered
+}
@@ -0,0 +1,206 @@
+package check
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/pbm"
+	"github.com/vmware/govmomi/pbm/types"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckClusterStoragePolicyAppliesToAllNodeDatastores(t *testing.T) {
+	const tagPolicyName = "tag-policy"
+	tests := []struct {
+		name            string
+		storagePolicy   string
+		expectedError   string
+		expectedMetrics string
+	}{
+		{
+			name:          "tag based policy",
+			storagePolicy: tagPolicyName,
+			expectedMetrics: `
+# HELP vsphere_storage_policy_coverage_gaps_total [ALPHA] Number of datastores used by node VMs that do not match tag based storage policies of StorageClasses.
+# TYPE vsphere_storage_policy_coverage_gaps_total gauge
+vsphere_storage_policy_coverage_gaps_total{vcenter="dc0"} 0
+`,
+		},
+		{
+			name:          "policy without tags",
+			storagePolicy: "vSAN Default Storage Policy",
+			expectedMetrics: `
+# HELP vsphere_storage_policy_coverage_gaps_total [ALPHA] Number of datastores used by node VMs that do not match tag based storage policies of StorageClasses.
+# TYPE vsphere_storage_policy_coverage_gaps_total gauge
+vsphere_storage_policy_coverage_gaps_total{vcenter="dc0"} 0
+`,
+		},
+		{
+			name:          "missing policy",
+			storagePolicy: "foobar",
+			expectedError: "StorageClass test-sc: error getting pbm profiles: ServerFaultCode: InvalidArgument",
+			expectedMetrics: `
+# HELP vsphere_storage_policy_coverage_gaps_total [ALPHA] Number of datastores used by node VMs that do not match tag based storage policies of StorageClasses.
+# TYPE vsphere_storage_policy_coverage_gaps_total gauge
+vsphere_storage_policy_coverage_gaps_total{vcenter="dc0"} 0
+`,
+		},
+		{
+			name: "no storage policy",
+			expectedMetrics: `
+# HELP vsphere_storage_policy_coverage_gaps_total [ALPHA] Number of datastores used by node VMs that do not match tag based storage policies of StorageClasses.
+# TYPE vsphere_storage_policy_coverage_gaps_total gauge
+vsphere_storage_policy_coverage_gaps_total{vcenter="dc0"} 0
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			if test.storagePolicy != "" {
+				sc := storageClass("test-sc", vSphereCSIDdriver)
+				sc.Parameters = map[string]string{
+					"storagePolicyName": test.storagePolicy,
+				}
+				kubeClient.storageClasses = []*storagev1.StorageClass{sc}
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			// The simulator stores policies globally, remove the created one after the test.
+			deletePolicy, err := createTagBasedPolicy(ctx, tagPolicyName)
+			if err != nil {
+				t.Fatalf("Failed to create storage policy: %s", err)
+			}
+			defer deletePolicy()
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckClusterStoragePolicyAppliesToAllNodeDatastores(ctx)
+
+			// Assert
+			if err != nil && test.expectedError == "" {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if test.expectedError != "" {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_storage_policy_coverage_gaps_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+func createTagBasedPolicy(ctx *CheckContext, name string) (func(), error) {
+	c, err := pbm.NewClient(context.TODO(), ctx.VMClient)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := pbm.CreateCapabilityProfileSpec(pbm.CapabilityProfileCreateSpec{
+		Name:           name,
+		SubProfileName: "tag based placement",
+		Category:       string(types.PbmProfileCategoryEnumREQUIREMENT),
+		CapabilityList: []pbm.Capability{
+			{
+				ID:        "zone",
+				Namespace: tagBasedPlacementNamespace,
+				PropertyList: []pbm.Property{
+					{
+						ID:       "com.vmware.storage.tag.zone.property",
+						Value:    "zone-a",
+						DataType: "set",
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	id, err := c.CreateProfile(context.TODO(), *spec)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		_, _ = c.DeleteProfile(context.TODO(), []types.PbmProfileId{*id})
+	}, nil
+}
+
+func TestGetUncoveredDatastores(t *testing.T) {
+	tests := []struct {
+		name              string
+		datastores        []string
+		policyDatastores  []string
+		expectedUncovered []string
+	}{
+		{
+			name:             "all covered",
+			datastores:       []string{"ds-1", "ds-2"},
+			policyDatastores: []string{"ds-2", "ds-1", "ds-3"},
+		},
+		{
+			name:              "gaps",
+			datastores:        []string{"ds-3", "ds-1", "ds-2"},
+			policyDatastores:  []string{"ds-2"},
+			expectedUncovered: []string{"ds-1", "ds-3"},
+		},
+		{
+			name:              "no policy datastores",
+			datastores:        []string{"ds-1"},
+			expectedUncovered: []string{"ds-1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uncovered := getUncoveredDatastores(test.datastores, test.policyDatastores)
+			if !reflect.DeepEqual(uncovered, test.expectedUncovered) {
+				t.Errorf("expected uncovered datastores %v, got %v", test.expectedUncovered, uncovered)
+			}
+		})
+	}
+}
+
+func TestIsTagBasedPolicy(t *testing.T) {
+	profile := func(namespace string) types.BasePbmProfile {
+		return &types.PbmCapabilityProfile{
+			Constraints: &types.PbmCapabilitySubProfileConstraints{
+				SubProfiles: []types.PbmCapabilitySubProfile{
+					{
+						Capability: []types.PbmCapabilityInstance{
+							{Id: types.PbmCapabilityMetadataUniqueId{Namespace: namespace, Id: "rule"}},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	if !isTagBasedPolicy(profile(tagBasedPlacementNamespace)) {
+		t.Errorf("expected policy with tag rule to be tag based")
+	}
+	if isTagBasedPolicy(profile("VSAN")) {
+		t.Errorf("expected vSAN policy not to be tag based")
+	}
+	if isTagBasedPolicy(&types.PbmCapabilityProfile{}) {
+		t.Errorf("expected policy without constraints not to be tag based")
+	}
+}
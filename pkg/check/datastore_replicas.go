@@ -0,0 +1,108 @@
+package check
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// replicaPVCLabel is the label that groups PVCs of replicas of the same application. It has no default,
+	// well-known labels like app.kubernetes.io/instance group all PVCs of a release, not only replicas.
+	replicaPVCLabel = flag.String("replica-pvc-label", "", "Label of PVCs that identifies replicas of a single application, e.g. a label set in volumeClaimTemplates of a StatefulSet. Volumes of PVCs with the same label value should not share a datastore. The check is skipped when empty.")
+
+	replicaGroupsSingleDatastoreMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_replica_groups_single_datastore_total",
+			Help:           "Number of groups of replica PVCs that have all their volumes on a single datastore.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(replicaGroupsSingleDatastoreMetric)
+}
+
+// replicaGroup is a set of PVCs in a single namespace with the same value of replicaPVCLabel.
+type replicaGroup struct {
+	namespace string
+	value     string
+}
+
+// CheckDatastoreClusterAntiAffinityForReplicas tests that volumes of replicas of the same
+// application (e.g. PVCs of a StatefulSet) are not all stored on a single datastore.
+// Losing such datastore would lose all replicas at once.
+func CheckDatastoreClusterAntiAffinityForReplicas(ctx *CheckContext) error {
+	if *replicaPVCLabel == "" {
+		klog.V(4).Infof("CheckDatastoreClusterAntiAffinityForReplicas: replica PVC label is not set, skipping")
+		replicaGroupsSingleDatastoreMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+
+	pvcs, err := ctx.KubeClient.ListPVCs(ctx.Context)
+	if err != nil {
+		return err
+	}
+	pvs, err := ctx.KubeClient.ListPVs(ctx.Context)
+	if err != nil {
+		return err
+	}
+
+	// group -> names of PVs bound to PVCs in the group
+	groups := make(map[replicaGroup][]string)
+	for _, pvc := range pvcs {
+		value, found := pvc.Labels[*replicaPVCLabel]
+		if !found || pvc.Spec.VolumeName == "" {
+			continue
+		}
+		group := replicaGroup{namespace: pvc.Namespace, value: value}
+		groups[group] = append(groups[group], pvc.Spec.VolumeName)
+	}
+
+	pvDatastores, err := getPVDatastores(ctx, pvs)
+	if err != nil {
+		// CNS may not be available, check at least the volumes we know about.
+		klog.V(2).Infof("CheckDatastoreClusterAntiAffinityForReplicas: error getting datastores of some PVs: %s", err)
+	}
+
+	var errs []error
+	singleDatastoreGroups := 0
+	for group, pvNames := range groups {
+		datastores := make(map[string]int)
+		known := 0
+		for _, pvName := range pvNames {
+			if ds, found := pvDatastores[pvName]; found {
+				datastores[ds]++
+				known++
+			}
+		}
+		if known < len(pvNames) {
+			// Other volumes of the group may be on other datastores.
+			klog.V(4).Infof("CheckDatastoreClusterAntiAffinityForReplicas: datastores of %d of %d volumes of PVCs with label %s=%s in namespace %s are not known, skipping", len(pvNames)-known, len(pvNames), *replicaPVCLabel, group.value, group.namespace)
+			continue
+		}
+		if len(datastores) != 1 {
+			continue
+		}
+		for ds, count := range datastores {
+			if count < 2 {
+				// A single replica cannot share its datastore with anything
+				continue
+			}
+			singleDatastoreGroups++
+			errs = append(errs, fmt.Errorf("PVCs with label %s=%s in namespace %s: all %d volumes are on datastore %s", *replicaPVCLabel, group.value, group.namespace, count, ds))
+		}
+	}
+	replicaGroupsSingleDatastoreMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(singleDatastoreGroups))
+
+	// Make the error message stable
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	klog.V(2).Infof("CheckDatastoreClusterAntiAffinityForReplicas checked %d replica groups, %d problems found", len(groups), len(errs))
+	return JoinErrors(errs)
+}
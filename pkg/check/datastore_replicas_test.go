@@ -0,0 +1,172 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func replicaPVC(name, instance, pvName string) *v1.PersistentVolumeClaim {
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels: map[string]string{
+				"replica-group": instance,
+			},
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			VolumeName: pvName,
+		},
+	}
+}
+
+func inTreePV(name, volumePath string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				VsphereVolume: &v1.VsphereVirtualDiskVolumeSource{
+					VolumePath: volumePath,
+				},
+			},
+		},
+	}
+}
+
+func TestCheckDatastoreClusterAntiAffinityForReplicas(t *testing.T) {
+	tests := []struct {
+		name          string
+		pvcs          []*v1.PersistentVolumeClaim
+		pvs           []*v1.PersistentVolume
+		noLabel       bool
+		expectError   bool
+		expectedCount int
+	}{
+		{
+			name: "replicas on different datastores",
+			pvcs: []*v1.PersistentVolumeClaim{
+				replicaPVC("data-db-0", "db", "pv-0"),
+				replicaPVC("data-db-1", "db", "pv-1"),
+			},
+			pvs: []*v1.PersistentVolume{
+				inTreePV("pv-0", "[LocalDS_0] kubevols/pv-0.vmdk"),
+				inTreePV("pv-1", "[LocalDS_1] kubevols/pv-1.vmdk"),
+			},
+			expectError:   false,
+			expectedCount: 0,
+		},
+		{
+			name: "replicas on the same datastore",
+			pvcs: []*v1.PersistentVolumeClaim{
+				replicaPVC("data-db-0", "db", "pv-0"),
+				replicaPVC("data-db-1", "db", "pv-1"),
+				replicaPVC("data-cache-0", "cache", "pv-2"),
+			},
+			pvs: []*v1.PersistentVolume{
+				inTreePV("pv-0", "[LocalDS_0] kubevols/pv-0.vmdk"),
+				inTreePV("pv-1", "[LocalDS_0] kubevols/pv-1.vmdk"),
+				inTreePV("pv-2", "[LocalDS_0] kubevols/pv-2.vmdk"),
+			},
+			expectError:   true,
+			expectedCount: 1,
+		},
+		{
+			name: "replica PVC label not set",
+			pvcs: []*v1.PersistentVolumeClaim{
+				replicaPVC("data-db-0", "db", "pv-0"),
+				replicaPVC("data-db-1", "db", "pv-1"),
+			},
+			pvs: []*v1.PersistentVolume{
+				inTreePV("pv-0", "[LocalDS_0] kubevols/pv-0.vmdk"),
+				inTreePV("pv-1", "[LocalDS_0] kubevols/pv-1.vmdk"),
+			},
+			noLabel:       true,
+			expectError:   false,
+			expectedCount: 0,
+		},
+		{
+			name: "replicas with unknown datastore",
+			pvcs: []*v1.PersistentVolumeClaim{
+				replicaPVC("data-db-0", "db", "pv-0"),
+				replicaPVC("data-db-1", "db", "pv-1"),
+				// PV of another vCenter or without known datastore
+				replicaPVC("data-db-2", "db", "pv-2"),
+			},
+			pvs: []*v1.PersistentVolume{
+				inTreePV("pv-0", "[LocalDS_0] kubevols/pv-0.vmdk"),
+				inTreePV("pv-1", "[LocalDS_0] kubevols/pv-1.vmdk"),
+			},
+			expectError:   false,
+			expectedCount: 0,
+		},
+		{
+			name: "PVCs without the replica label",
+			pvcs: []*v1.PersistentVolumeClaim{
+				{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}, Spec: v1.PersistentVolumeClaimSpec{VolumeName: "pv-0"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"}, Spec: v1.PersistentVolumeClaimSpec{VolumeName: "pv-1"}},
+			},
+			pvs: []*v1.PersistentVolume{
+				inTreePV("pv-0", "[LocalDS_0] kubevols/pv-0.vmdk"),
+				inTreePV("pv-1", "[LocalDS_0] kubevols/pv-1.vmdk"),
+			},
+			expectError:   false,
+			expectedCount: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				infrastructure: infrastructure(),
+				nodes:          defaultNodes(),
+				pvs:            test.pvs,
+				pvcs:           test.pvcs,
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			oldLabel := *replicaPVCLabel
+			defer func() { *replicaPVCLabel = oldLabel }()
+			*replicaPVCLabel = "replica-group"
+			if test.noLabel {
+				*replicaPVCLabel = ""
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckDatastoreClusterAntiAffinityForReplicas(ctx)
+
+			// Assert
+			if err != nil && !test.expectError {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if err == nil && test.expectError {
+				t.Errorf("Expected error, got none")
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(replicaGroupsMetric(test.expectedCount)), "vsphere_replica_groups_single_datastore_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+func replicaGroupsMetric(count int) string {
+	return fmt.Sprintf(`
+# HELP vsphere_replica_groups_single_datastore_total [ALPHA] Number of groups of replica PVCs that have all their volumes on a single datastore.
+# TYPE vsphere_replica_groups_single_datastore_total gauge
+vsphere_replica_groups_single_datastore_total{vcenter="dc0"} %d
+`, count)
+}
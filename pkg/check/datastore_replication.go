@@ -0,0 +1,176 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/vmware/govmomi/pbm/methods"
+	"github.com/vmware/govmomi/pbm/types"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	datastoreReplicationUnhealthyMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_datastore_replication_unhealthy_total",
+			Help:           "Number of datastores used by node VMs that are not in a healthy replication group.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(datastoreReplicationUnhealthyMetric)
+}
+
+// vmReplicationState is replication state of a single VM, as reported by the storage provider.
+type vmReplicationState struct {
+	// groupID is ID of the replication group of the VM. Empty when the VM is not replicated.
+	groupID string
+	// fault is set when the storage provider failed to report the replication group.
+	fault string
+}
+
+// CheckDatastoreReplicationPairingForStretched tests that datastores used by node VMs are in a healthy
+// replication group, when the storage provider uses array based replication. A datastore without
+// replication in a stretched cluster risks data loss on site failure.
+// The check is skipped when the storage provider does not expose any replication groups.
+func CheckDatastoreReplicationPairingForStretched(ctx *CheckContext) error {
+	vms, err := getNodeVMs(ctx, []string{"name", "datastore"})
+	if err != nil {
+		return err
+	}
+
+	states, err := queryVMReplicationStates(ctx, vms)
+	if err != nil {
+		klog.V(2).Infof("CheckDatastoreReplicationPairingForStretched: replication groups are not available, skipping: %s", err)
+		datastoreReplicationUnhealthyMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+
+	problems := getDatastoreReplicationProblems(vms, states)
+	datastoreReplicationUnhealthyMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(len(problems)))
+	if len(problems) == 0 {
+		return nil
+	}
+
+	var dsRefs []vim.ManagedObjectReference
+	for dsRef := range problems {
+		dsRefs = append(dsRefs, dsRef)
+	}
+	dsNames, err := getDatastoreNames(ctx, dsRefs)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for dsRef, problem := range problems {
+		errs = append(errs, fmt.Errorf("datastore %s is not in a healthy replication group: %s", dsNames[dsRef], problem))
+	}
+	// Make the error message stable
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return JoinErrors(errs)
+}
+
+// queryVMReplicationStates returns replication state of given VMs, VM ID -> state.
+func queryVMReplicationStates(ctx *CheckContext, vms []mo.VirtualMachine) (map[string]vmReplicationState, error) {
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	c, err := newPbmClient(tctx, ctx.VMClient)
+	if err != nil {
+		return nil, fmt.Errorf("error creating pbm client: %v", err)
+	}
+	if c.ServiceContent.ReplicationManager == nil {
+		return nil, fmt.Errorf("pbm replication manager is not available")
+	}
+
+	req := types.PbmQueryReplicationGroups{
+		This: *c.ServiceContent.ReplicationManager,
+	}
+	for _, vm := range vms {
+		req.Entities = append(req.Entities, types.PbmServerObjectRef{
+			ObjectType: string(types.PbmObjectTypeVirtualMachine),
+			Key:        vm.Self.Value,
+			ServerUuid: ctx.VMClient.ServiceContent.About.InstanceUuid,
+		})
+	}
+	res, err := methods.PbmQueryReplicationGroups(tctx, c, &req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying replication groups: %v", err)
+	}
+
+	states := make(map[string]vmReplicationState)
+	for _, result := range res.Returnval {
+		var state vmReplicationState
+		switch {
+		case result.Fault != nil:
+			state.fault = result.Fault.LocalizedMessage
+			if state.fault == "" {
+				state.fault = fmt.Sprintf("%T", result.Fault.Fault)
+			}
+		case result.ReplicationGroupId != nil:
+			state.groupID = result.ReplicationGroupId.FaultDomainId.Id + "/" + result.ReplicationGroupId.DeviceGroupId.Id
+		}
+		states[result.Object.Key] = state
+	}
+	return states, nil
+}
+
+// getDatastoreReplicationProblems returns description of replication problem of each datastore used
+// by given VMs, datastore -> problem. No problems are reported when none of the VMs is replicated,
+// i.e. when array based replication is not used at all.
+func getDatastoreReplicationProblems(vms []mo.VirtualMachine, states map[string]vmReplicationState) map[vim.ManagedObjectReference]string {
+	replicated := false
+	for _, state := range states {
+		if state.groupID != "" {
+			replicated = true
+			break
+		}
+	}
+	problems := make(map[vim.ManagedObjectReference]string)
+	if !replicated {
+		return problems
+	}
+
+	for _, vm := range vms {
+		state := states[vm.Self.Value]
+		var problem string
+		switch {
+		case state.fault != "":
+			problem = fmt.Sprintf("replication group of VM %s cannot be determined: %s", vm.Name, state.fault)
+		case state.groupID == "":
+			problem = fmt.Sprintf("VM %s is not in any replication group", vm.Name)
+		default:
+			continue
+		}
+		for _, dsRef := range vm.Datastore {
+			if _, found := problems[dsRef]; !found {
+				problems[dsRef] = problem
+			}
+		}
+	}
+	return problems
+}
+
+// getDatastoreNames returns names of given datastores, datastore -> name.
+func getDatastoreNames(ctx *CheckContext, dsRefs []vim.ManagedObjectReference) (map[vim.ManagedObjectReference]string, error) {
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var datastores []mo.Datastore
+	pc := property.DefaultCollector(ctx.VMClient)
+	if err := pc.Retrieve(tctx, dsRefs, []string{"name"}, &datastores); err != nil {
+		return nil, fmt.Errorf("failed to get datastore names: %s", err)
+	}
+	names := make(map[vim.ManagedObjectReference]string)
+	for _, ds := range datastores {
+		names[ds.Self] = ds.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,113 @@
+package check
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckDatastoreReplicationPairingForStretched(t *testing.T) {
+	// Stage
+	kubeClient := &fakeKubeClient{
+		nodes: defaultNodes(),
+	}
+	ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+
+	// Act
+	err = CheckDatastoreReplicationPairingForStretched(ctx)
+
+	// Assert: vcsim does not expose any replication groups, the check is skipped
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	expectedMetrics := `
+# HELP vsphere_datastore_replication_unhealthy_total [ALPHA] Number of datastores used by node VMs that are not in a healthy replication group.
+# TYPE vsphere_datastore_replication_unhealthy_total gauge
+vsphere_datastore_replication_unhealthy_total{vcenter="dc0"} 0
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_datastore_replication_unhealthy_total"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+}
+
+func TestGetDatastoreReplicationProblems(t *testing.T) {
+	ds1 := types.ManagedObjectReference{Type: "Datastore", Value: "ds-1"}
+	ds2 := types.ManagedObjectReference{Type: "Datastore", Value: "ds-2"}
+	ds3 := types.ManagedObjectReference{Type: "Datastore", Value: "ds-3"}
+	vm := func(id string, datastores ...types.ManagedObjectReference) mo.VirtualMachine {
+		vm := mo.VirtualMachine{Datastore: datastores}
+		vm.Self = types.ManagedObjectReference{Type: "VirtualMachine", Value: id}
+		vm.Name = id
+		return vm
+	}
+
+	tests := []struct {
+		name             string
+		vms              []mo.VirtualMachine
+		states           map[string]vmReplicationState
+		expectedProblems map[types.ManagedObjectReference]string
+	}{
+		{
+			name: "no replication",
+			vms:  []mo.VirtualMachine{vm("vm-1", ds1), vm("vm-2", ds2)},
+			states: map[string]vmReplicationState{
+				"vm-1": {},
+				"vm-2": {},
+			},
+			expectedProblems: map[types.ManagedObjectReference]string{},
+		},
+		{
+			name: "all VMs replicated",
+			vms:  []mo.VirtualMachine{vm("vm-1", ds1), vm("vm-2", ds2)},
+			states: map[string]vmReplicationState{
+				"vm-1": {groupID: "fd/group1"},
+				"vm-2": {groupID: "fd/group2"},
+			},
+			expectedProblems: map[types.ManagedObjectReference]string{},
+		},
+		{
+			name: "VM not replicated",
+			vms:  []mo.VirtualMachine{vm("vm-1", ds1), vm("vm-2", ds2)},
+			states: map[string]vmReplicationState{
+				"vm-1": {groupID: "fd/group1"},
+				"vm-2": {},
+			},
+			expectedProblems: map[types.ManagedObjectReference]string{
+				ds2: "VM vm-2 is not in any replication group",
+			},
+		},
+		{
+			name: "replication fault",
+			vms:  []mo.VirtualMachine{vm("vm-1", ds1), vm("vm-2", ds2, ds3)},
+			states: map[string]vmReplicationState{
+				"vm-1": {groupID: "fd/group1"},
+				"vm-2": {fault: "pairing broken"},
+			},
+			expectedProblems: map[types.ManagedObjectReference]string{
+				ds2: "replication group of VM vm-2 cannot be determined: pairing broken",
+				ds3: "replication group of VM vm-2 cannot be determined: pairing broken",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems := getDatastoreReplicationProblems(test.vms, test.states)
+			if !reflect.DeepEqual(problems, test.expectedProblems) {
+				t.Errorf("expected problems %+v, got %+v", test.expectedProblems, problems)
+			}
+		})
+	}
+}
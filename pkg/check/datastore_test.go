@@ -40,6 +40,11 @@ var (
 			datastore:   "0-1-2-3-4-5-6-7-8-9", // 265 characters in the escaped path
 			expectError: true,
 		},
+		{
+			name:        "datastore with unsupported characters",
+			datastore:   "[LocalDS_1]",
+			expectError: true,
+		},
 		{
 			name:        "datastore which is part of a datastore cluster",
 			datastore:   "/DC0/datastore/DC0_POD0/LocalDS_2",
@@ -159,3 +164,47 @@ func TestCheckStorageClassesWithDatastore(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckDatastoreName(t *testing.T) {
+	tests := []struct {
+		name          string
+		datastore     string
+		expectedError string
+	}{
+		{
+			name:      "short datastore",
+			datastore: "LocalDS_1",
+		},
+		{
+			name:      "inventory path",
+			datastore: "/DC0/datastore/DC0_POD0/LocalDS_2",
+		},
+		{
+			name:          "long datastore",
+			datastore:     "01234567890123456789012345678901234567890123456789",
+			expectedError: `datastore name is too long: escaped volume path var-lib-kubelet-plugins-kubernetes.io-vsphere\x2dvolume-mounts-\x5b01234567890123456789012345678901234567890123456789\x5d\x2000000000\x2d0000\x2d0000\x2d0000\x2d000000000000-my\x2dcluster\x2did\x2ddynamic\x2dpvc\x2d00000000\x2d0000\x2d0000\x2d0000\x2d000000000000.vmdk must be under 255 characters, got 268`,
+		},
+		{
+			name:          "brackets",
+			datastore:     "ds[1]",
+			expectedError: `datastore name "ds[1]" contains unsupported character '['`,
+		},
+		{
+			name:          "control character",
+			datastore:     "ds\t1",
+			expectedError: `datastore name "ds\t1" contains control character '\t'`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkDatastoreName(test.datastore, infrastructure())
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			if errString != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, errString)
+			}
+		})
+	}
+}
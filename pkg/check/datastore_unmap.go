@@ -0,0 +1,63 @@
+package check
+
+import (
+	"fmt"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// UNMAP priority of VMFS datastores that have automatic space reclamation disabled.
+	unmapPriorityNone = "none"
+)
+
+var (
+	datastoreUnmapDisabledMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_datastore_unmap_disabled_total",
+			Help:           "Number of VMFS datastores used by the cluster with automatic space reclamation (UNMAP) disabled.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(datastoreUnmapDisabledMetric)
+}
+
+// CheckDatastoreUnmapSupport tests that VMFS datastores used by node VMs and the default datastore
+// have automatic space reclamation (UNMAP) enabled. Without it, space freed on thin provisioned
+// disks is never returned to the datastore, which slowly fills up.
+func CheckDatastoreUnmapSupport(ctx *CheckContext) error {
+	dsRefs, err := getNodeDatastores(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	unmapDisabled := 0
+	for _, dsRef := range dsRefs {
+		dsMo, err := getDatastore(ctx, dsRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		vmfsInfo, ok := dsMo.Info.(*types.VmfsDatastoreInfo)
+		if !ok || vmfsInfo.Vmfs == nil {
+			klog.V(4).Infof("Datastore %s is not VMFS, skipping UNMAP check", dsMo.Summary.Name)
+			continue
+		}
+		if vmfsInfo.Vmfs.UnmapPriority == unmapPriorityNone {
+			unmapDisabled++
+			errs = append(errs, fmt.Errorf("datastore %s has automatic space reclamation (UNMAP) disabled: unmapPriority is %s", dsMo.Summary.Name, vmfsInfo.Vmfs.UnmapPriority))
+		}
+	}
+	datastoreUnmapDisabledMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(unmapDisabled))
+
+	klog.V(2).Infof("CheckDatastoreUnmapSupport checked %d datastores, %d with UNMAP disabled", len(dsRefs), unmapDisabled)
+	return JoinErrors(errs)
+}
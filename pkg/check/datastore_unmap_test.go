@@ -0,0 +1,98 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckDatastoreUnmapSupport(t *testing.T) {
+	tests := []struct {
+		name          string
+		vmfs          bool
+		unmapPriority string
+		expectedError string
+	}{
+		{
+			name: "non-VMFS datastore",
+			vmfs: false,
+		},
+		{
+			name:          "VMFS datastore with UNMAP",
+			vmfs:          true,
+			unmapPriority: "low",
+		},
+		{
+			name:          "VMFS datastore without UNMAP",
+			vmfs:          true,
+			unmapPriority: "none",
+			expectedError: "datastore LocalDS_0 has automatic space reclamation (UNMAP) disabled: unmapPriority is none",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			if test.vmfs {
+				dc, err := getDatacenter(ctx, defaultDC)
+				if err != nil {
+					t.Fatalf("Failed to get datacenter: %s", err)
+				}
+				ds, err := getDataStoreByName(ctx, "LocalDS_0", dc)
+				if err != nil {
+					t.Fatalf("Failed to get datastore: %s", err)
+				}
+				simDS := simulator.Map.Get(ds.Reference()).(*simulator.Datastore)
+				simDS.Info = &types.VmfsDatastoreInfo{
+					DatastoreInfo: *simDS.Info.GetDatastoreInfo(),
+					Vmfs: &types.HostVmfsVolume{
+						UnmapPriority: test.unmapPriority,
+					},
+				}
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckDatastoreUnmapSupport(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_datastore_unmap_disabled_total [ALPHA] Number of VMFS datastores used by the cluster with automatic space reclamation (UNMAP) disabled.
+# TYPE vsphere_datastore_unmap_disabled_total gauge
+vsphere_datastore_unmap_disabled_total{vcenter="dc0"} %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_datastore_unmap_disabled_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
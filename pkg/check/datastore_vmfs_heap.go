@@ -0,0 +1,160 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	hostLabel = "host"
+
+	// Default maximum size of VMFS heap of ESXi 6.0 and newer.
+	vmfsHeapSizeMiB = 640
+	// Rough VMFS heap usage per TiB of capacity of a VMFS datastore with open files.
+	// The default heap can address about 256 TiB of open files.
+	vmfsHeapMiBPerTiB = 2.5
+	// Rough VMFS heap usage of a single open file.
+	vmfsHeapKiBPerOpenFile = 256
+	// A host is at risk when its estimated VMFS heap usage reaches this fraction of the heap size.
+	vmfsHeapPressureThreshold = 0.8
+
+	tib = 1024 * 1024 * 1024 * 1024
+)
+
+var (
+	vmfsHeapPressureMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_host_vmfs_heap_pressure_ratio",
+			Help:           "Estimated VMFS heap usage of an ESXi host running node VMs, as a fraction of the VMFS heap size.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, hostLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(vmfsHeapPressureMetric)
+}
+
+// vmfsHeapUsage is estimated VMFS heap usage of a single ESXi host.
+type vmfsHeapUsage struct {
+	heapMiB    float64
+	datastores []string
+}
+
+// add adds heap used by open files of the host on a VMFS datastore.
+func (u *vmfsHeapUsage) add(dsName string, capacity int64, openFiles int) {
+	u.heapMiB += float64(capacity)/float64(tib)*vmfsHeapMiBPerTiB + float64(openFiles)*vmfsHeapKiBPerOpenFile/1024
+	u.datastores = append(u.datastores, fmt.Sprintf("%s (%.0f TiB, %d open files)", dsName, float64(capacity)/float64(tib), openFiles))
+}
+
+// CheckDatastoreVMFSHeapExhaustionRisk estimates VMFS heap usage of ESXi hosts that run node VMs
+// from size of VMFS datastores used by node VMs and from files of VMs opened on these datastores.
+// Hosts with exhausted VMFS heap fail I/O to files on VMFS datastores, including disks of node VMs.
+func CheckDatastoreVMFSHeapExhaustionRisk(ctx *CheckContext) error {
+	vms, err := getNodeVMs(ctx, []string{"runtime.host"})
+	if err != nil {
+		return err
+	}
+	hosts := make(map[types.ManagedObjectReference]bool)
+	for _, vm := range vms {
+		if vm.Runtime.Host != nil {
+			hosts[*vm.Runtime.Host] = true
+		}
+	}
+
+	// Reset series of the vCenter to drop hosts that do not run node VMs any longer.
+	resetVCenterMetric(vmfsHeapPressureMetric, ctx.VMConfig.Workspace.VCenterIP)
+	if len(hosts) == 0 {
+		klog.V(2).Infof("CheckDatastoreVMFSHeapExhaustionRisk: no ESXi hosts with node VMs found, skipping")
+		return nil
+	}
+	hostNames, err := getHostNames(ctx, hosts)
+	if err != nil {
+		return err
+	}
+
+	dsRefs, err := getNodeDatastores(ctx)
+	if err != nil {
+		return err
+	}
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var datastores []mo.Datastore
+	if err := pc.Retrieve(tctx, dsRefs, []string{"name", "summary", "info", "vm"}, &datastores); err != nil {
+		return fmt.Errorf("failed to get datastores: %s", err)
+	}
+	sort.Slice(datastores, func(i, j int) bool { return datastores[i].Name < datastores[j].Name })
+
+	usage := make(map[types.ManagedObjectReference]*vmfsHeapUsage)
+	for host := range hosts {
+		usage[host] = &vmfsHeapUsage{}
+	}
+	for _, ds := range datastores {
+		if _, ok := ds.Info.(*types.VmfsDatastoreInfo); !ok {
+			klog.V(4).Infof("Datastore %s is not VMFS, skipping VMFS heap estimate", ds.Name)
+			continue
+		}
+		if len(ds.Vm) == 0 {
+			continue
+		}
+		var dsVMs []mo.VirtualMachine
+		if err := pc.Retrieve(tctx, ds.Vm, []string{"runtime.host", "runtime.powerState", "layoutEx.file"}, &dsVMs); err != nil {
+			return fmt.Errorf("failed to get VMs of datastore %s: %s", ds.Name, err)
+		}
+		for host, openFiles := range getOpenVMFSFiles(ds.Name, dsVMs, hosts) {
+			usage[host].add(ds.Name, ds.Summary.Capacity, openFiles)
+		}
+	}
+
+	var errs []error
+	for host, u := range usage {
+		name := hostNames[host]
+		if name == "" {
+			name = host.Value
+		}
+		pressure := u.heapMiB / vmfsHeapSizeMiB
+		vmfsHeapPressureMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, name).Set(pressure)
+		if pressure < vmfsHeapPressureThreshold {
+			klog.V(4).Infof("ESXi host %s has estimated VMFS heap usage %.0f MiB", name, u.heapMiB)
+			continue
+		}
+		errs = append(errs, fmt.Errorf("ESXi host %s is at risk of VMFS heap exhaustion: estimated heap usage %.0f MiB is %.0f%% of %d MiB, datastores: %s",
+			name, u.heapMiB, pressure*100, vmfsHeapSizeMiB, strings.Join(u.datastores, ", ")))
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+
+	klog.V(2).Infof("CheckDatastoreVMFSHeapExhaustionRisk checked %d hosts, %d problems found", len(hosts), len(errs))
+	return JoinErrors(errs)
+}
+
+// getOpenVMFSFiles returns number of files on the datastore opened by powered on VMs, for each of given hosts
+// that run at least one such VM.
+func getOpenVMFSFiles(dsName string, vms []mo.VirtualMachine, hosts map[types.ManagedObjectReference]bool) map[types.ManagedObjectReference]int {
+	prefix := fmt.Sprintf("[%s] ", dsName)
+	openFiles := make(map[types.ManagedObjectReference]int)
+	for _, vm := range vms {
+		if vm.Runtime.Host == nil || !hosts[*vm.Runtime.Host] {
+			continue
+		}
+		if vm.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOn || vm.LayoutEx == nil {
+			continue
+		}
+		for _, file := range vm.LayoutEx.File {
+			if strings.HasPrefix(file.Name, prefix) {
+				openFiles[*vm.Runtime.Host]++
+			}
+		}
+	}
+	return openFiles
+}
@@ -0,0 +1,127 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckDatastoreVMFSHeapExhaustionRisk(t *testing.T) {
+	tests := []struct {
+		name            string
+		vmfs            bool
+		capacity        int64
+		poweredOff      bool
+		expectedError   string
+		expectedMetrics string
+	}{
+		{
+			name:     "non-VMFS datastore",
+			capacity: 256 * tib,
+			expectedMetrics: `
+# HELP vsphere_host_vmfs_heap_pressure_ratio [ALPHA] Estimated VMFS heap usage of an ESXi host running node VMs, as a fraction of the VMFS heap size.
+# TYPE vsphere_host_vmfs_heap_pressure_ratio gauge
+vsphere_host_vmfs_heap_pressure_ratio{host="DC0_H0",vcenter="dc0"} 0
+`,
+		},
+		{
+			name:     "small VMFS datastore",
+			vmfs:     true,
+			capacity: 100 * tib,
+			// (100 TiB * 2.5 MiB + 14 open files * 256 KiB) / 640 MiB
+			expectedMetrics: `
+# HELP vsphere_host_vmfs_heap_pressure_ratio [ALPHA] Estimated VMFS heap usage of an ESXi host running node VMs, as a fraction of the VMFS heap size.
+# TYPE vsphere_host_vmfs_heap_pressure_ratio gauge
+vsphere_host_vmfs_heap_pressure_ratio{host="DC0_H0",vcenter="dc0"} 0.39609375
+`,
+		},
+		{
+			name:          "large VMFS datastore",
+			vmfs:          true,
+			capacity:      256 * tib,
+			expectedError: "ESXi host DC0_H0 is at risk of VMFS heap exhaustion: estimated heap usage 644 MiB is 101% of 640 MiB, datastores: LocalDS_0 (256 TiB, 14 open files)",
+			expectedMetrics: `
+# HELP vsphere_host_vmfs_heap_pressure_ratio [ALPHA] Estimated VMFS heap usage of an ESXi host running node VMs, as a fraction of the VMFS heap size.
+# TYPE vsphere_host_vmfs_heap_pressure_ratio gauge
+vsphere_host_vmfs_heap_pressure_ratio{host="DC0_H0",vcenter="dc0"} 1.00546875
+`,
+		},
+		{
+			name:       "large VMFS datastore without open files",
+			vmfs:       true,
+			capacity:   256 * tib,
+			poweredOff: true,
+			expectedMetrics: `
+# HELP vsphere_host_vmfs_heap_pressure_ratio [ALPHA] Estimated VMFS heap usage of an ESXi host running node VMs, as a fraction of the VMFS heap size.
+# TYPE vsphere_host_vmfs_heap_pressure_ratio gauge
+vsphere_host_vmfs_heap_pressure_ratio{host="DC0_H0",vcenter="dc0"} 0
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			dc, err := getDatacenter(ctx, defaultDC)
+			if err != nil {
+				t.Fatalf("Failed to get datacenter: %s", err)
+			}
+			ds, err := getDataStoreByName(ctx, "LocalDS_0", dc)
+			if err != nil {
+				t.Fatalf("Failed to get datastore: %s", err)
+			}
+			simDS := simulator.Map.Get(ds.Reference()).(*simulator.Datastore)
+			simDS.Summary.Capacity = test.capacity
+			if test.vmfs {
+				simDS.Info = &types.VmfsDatastoreInfo{
+					DatastoreInfo: *simDS.Info.GetDatastoreInfo(),
+					Vmfs:          &types.HostVmfsVolume{},
+				}
+			}
+			if test.poweredOff {
+				for _, node := range kubeClient.nodes {
+					vm, err := getVM(ctx, node)
+					if err != nil {
+						t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+					}
+					simVM := simulator.Map.Get(vm.Reference()).(*simulator.VirtualMachine)
+					simVM.Runtime.PowerState = types.VirtualMachinePowerStatePoweredOff
+				}
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckDatastoreVMFSHeapExhaustionRisk(ctx)
+
+			// Assert
+			if test.expectedError == "" && err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if test.expectedError != "" {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_host_vmfs_heap_pressure_ratio"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package check
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	vsanSlackSpacePercent = flag.Int("vsan-slack-space-percent", 25, "Percentage of vSAN datastore capacity that must stay free as slack space for rebuilds and rebalancing of vSAN objects.")
+
+	vsanSlackHeadroomMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vsan_slack_headroom_bytes",
+			Help:           "Free space of vSAN datastores in compute clusters with node VMs above the required slack space. Negative value means the datastore is filled past the slack space.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, clusterLabel, datastoreLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(vsanSlackHeadroomMetric)
+}
+
+// CheckDatastoreCapacityVsVsanSlackSpace tests that vSAN datastores of compute clusters that run node VMs keep
+// enough free space as slack space. vSAN needs the slack space to rebuild objects after a host or disk failure;
+// a datastore filled past it cannot restore redundancy of node VM disks and volumes.
+// Datastores other than vSAN are skipped.
+func CheckDatastoreCapacityVsVsanSlackSpace(ctx *CheckContext) error {
+	if *vsanSlackSpacePercent < 0 || *vsanSlackSpacePercent > 100 {
+		return fmt.Errorf("invalid vsan-slack-space-percent %d, it must be between 0 and 100", *vsanSlackSpacePercent)
+	}
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Reset series of the vCenter to drop clusters and datastores that do not run nodes any longer.
+	resetVCenterMetric(vsanSlackHeadroomMetric, ctx.VMConfig.Workspace.VCenterIP)
+	var errs []error
+	for _, clusterRef := range clusterRefs {
+		cluster, err := getClusterConfigurationEx(ctx, clusterRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !isClusterVsanEnabled(cluster) {
+			klog.V(4).Infof("Compute cluster %s does not have vSAN enabled, skipping slack space", cluster.Name)
+			continue
+		}
+		datastores, err := getClusterVsanDatastores(ctx, cluster)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, ds := range datastores {
+			headroom := getVsanSlackHeadroom(ds.Summary.Capacity, ds.Summary.FreeSpace, *vsanSlackSpacePercent)
+			vsanSlackHeadroomMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP, cluster.Name, ds.Summary.Name).Set(float64(headroom))
+			if headroom < 0 {
+				errs = append(errs, fmt.Errorf("compute cluster %s: vSAN datastore %s has %s free of %s capacity, which is %s below the required %d%% slack space",
+					cluster.Name, ds.Summary.Name,
+					resource.NewQuantity(ds.Summary.FreeSpace, resource.BinarySI), resource.NewQuantity(ds.Summary.Capacity, resource.BinarySI),
+					resource.NewQuantity(-headroom, resource.BinarySI), *vsanSlackSpacePercent))
+				continue
+			}
+			klog.V(4).Infof("Compute cluster %s: vSAN datastore %s has %d bytes of free space above slack space", cluster.Name, ds.Summary.Name, headroom)
+		}
+	}
+	return JoinErrors(errs)
+}
+
+// getClusterVsanDatastores returns vSAN datastores of a compute cluster with their summary.
+func getClusterVsanDatastores(ctx *CheckContext, cluster *mo.ClusterComputeResource) ([]mo.Datastore, error) {
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+
+	var cr mo.ClusterComputeResource
+	if err := pc.RetrieveOne(tctx, cluster.Self, []string{"datastore"}, &cr); err != nil {
+		return nil, fmt.Errorf("failed to get datastores of compute cluster %s: %s", cluster.Name, err)
+	}
+	if len(cr.Datastore) == 0 {
+		return nil, nil
+	}
+	var content []mo.Datastore
+	if err := pc.Retrieve(tctx, cr.Datastore, []string{"summary"}, &content); err != nil {
+		return nil, fmt.Errorf("failed to get datastores of compute cluster %s: %s", cluster.Name, err)
+	}
+	var datastores []mo.Datastore
+	for _, ds := range content {
+		if ds.Summary.Type == vsanDatastoreType {
+			datastores = append(datastores, ds)
+		}
+	}
+	return datastores, nil
+}
+
+// getVsanSlackHeadroom returns free space in bytes above the slack space, given as percentage of capacity.
+func getVsanSlackHeadroom(capacity, freeSpace int64, slackPercent int) int64 {
+	return freeSpace - capacity*int64(slackPercent)/100
+}
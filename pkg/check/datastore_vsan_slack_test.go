@@ -0,0 +1,116 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckDatastoreCapacityVsVsanSlackSpace(t *testing.T) {
+	const gib = 1024 * 1024 * 1024
+	tests := []struct {
+		name            string
+		vsanDisabled    bool
+		datastoreType   string
+		freeSpace       int64
+		expectedError   string
+		expectedMetrics string
+	}{
+		{
+			name:          "vSAN disabled",
+			vsanDisabled:  true,
+			datastoreType: "vsan",
+			freeSpace:     10 * gib,
+		},
+		{
+			name:          "no vSAN datastore",
+			datastoreType: "VMFS",
+			freeSpace:     10 * gib,
+		},
+		{
+			name:          "enough slack space",
+			datastoreType: "vsan",
+			freeSpace:     30 * gib,
+			expectedMetrics: `
+vsphere_vsan_slack_headroom_bytes{cluster="DC0_C0",datastore="LocalDS_0",vcenter="dc0"} 5.36870912e+09
+`,
+		},
+		{
+			name:          "exactly the slack space",
+			datastoreType: "vsan",
+			freeSpace:     25 * gib,
+			expectedMetrics: `
+vsphere_vsan_slack_headroom_bytes{cluster="DC0_C0",datastore="LocalDS_0",vcenter="dc0"} 0
+`,
+		},
+		{
+			name:          "filled past the slack space",
+			datastoreType: "vsan",
+			freeSpace:     10 * gib,
+			expectedError: "compute cluster DC0_C0: vSAN datastore LocalDS_0 has 10Gi free of 100Gi capacity, which is 15Gi below the required 25% slack space",
+			expectedMetrics: `
+vsphere_vsan_slack_headroom_bytes{cluster="DC0_C0",datastore="LocalDS_0",vcenter="dc0"} -1.610612736e+10
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			obj := simulator.Map.Get(types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "clustercomputeresource-30"})
+			cluster := obj.(*simulator.ClusterComputeResource)
+			cluster.ConfigurationEx.(*types.ClusterConfigInfoEx).VsanConfigInfo = &types.VsanClusterConfigInfo{
+				Enabled: types.NewBool(!test.vsanDisabled),
+			}
+			// Datastore names are not unique in the simulator, use the first one mounted to the cluster hosts.
+			firstHost := simulator.Map.Get(cluster.Host[0]).(*simulator.HostSystem)
+			ds := simulator.Map.Get(firstHost.Datastore[0]).(*simulator.Datastore)
+			ds.Summary.Type = test.datastoreType
+			ds.Summary.Capacity = 100 * gib
+			ds.Summary.FreeSpace = test.freeSpace
+			cluster.Datastore = []types.ManagedObjectReference{ds.Self}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckDatastoreCapacityVsVsanSlackSpace(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := ""
+			if test.expectedMetrics != "" {
+				expectedMetrics = `
+# HELP vsphere_vsan_slack_headroom_bytes [ALPHA] Free space of vSAN datastores in compute clusters with node VMs above the required slack space. Negative value means the datastore is filled past the slack space.
+# TYPE vsphere_vsan_slack_headroom_bytes gauge
+` + test.expectedMetrics
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_vsan_slack_headroom_bytes"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
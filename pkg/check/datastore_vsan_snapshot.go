@@ -0,0 +1,287 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/vmware/govmomi/pbm/methods"
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// vSAN storage policy capability with object space reservation in percent.
+	vsanCapabilityNamespace    = "VSAN"
+	vsanProportionalCapacityID = "proportionalCapacity"
+	vsanDatastoreType          = "vsan"
+)
+
+var (
+	vsanSnapshotReservationMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vsan_snapshot_reservation_insufficient_total",
+			Help:           "Number of vSAN objects of node VMs whose object space reservation does not fit free space of their datastore, so a snapshot of the object cannot be created.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(vsanSnapshotReservationMetric)
+}
+
+// vsanDiskReservation is a disk of a node VM stored on vSAN, with the object space reservation of its storage policy.
+type vsanDiskReservation struct {
+	vmName    string
+	diskLabel string
+	datastore vim.ManagedObjectReference
+	// capacity of the disk in bytes
+	capacity int64
+	// reservation is the object space reservation in percent of the capacity
+	reservation int32
+}
+
+// CheckDatastoreSnapshotSpaceReservation tests that snapshots of node VM disks stored on vSAN can be created.
+// A snapshot of a vSAN object inherits the storage policy of the object, with a non-zero object space
+// reservation the snapshot reserves the same portion of the disk capacity. Backup tools fail when
+// the vSAN datastore does not have enough free space for the reservation.
+// The check is skipped when node VMs do not use vSAN or when storage policies are not available.
+func CheckDatastoreSnapshotSpaceReservation(ctx *CheckContext) error {
+	vms, err := getNodeVMs(ctx, []string{"name", "config.hardware.device"})
+	if err != nil {
+		return err
+	}
+
+	datastores, err := getVSANDiskDatastores(ctx, vms)
+	if err != nil {
+		return err
+	}
+	if len(datastores) == 0 {
+		klog.V(4).Infof("CheckDatastoreSnapshotSpaceReservation: node VMs do not use vSAN, skipping")
+		vsanSnapshotReservationMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+
+	disks, err := queryVSANDiskReservations(ctx, vms, datastores)
+	if err != nil {
+		klog.V(2).Infof("CheckDatastoreSnapshotSpaceReservation: storage policies are not available, skipping: %s", err)
+		vsanSnapshotReservationMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+
+	problems := getSnapshotReservationProblems(disks, datastores)
+	vsanSnapshotReservationMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(len(problems)))
+	var errs []error
+	for _, problem := range problems {
+		errs = append(errs, fmt.Errorf("%s", problem))
+	}
+	klog.V(2).Infof("CheckDatastoreSnapshotSpaceReservation checked %d vSAN disks, %d problems found", len(disks), len(errs))
+	return JoinErrors(errs)
+}
+
+// getVSANDiskDatastores returns vSAN datastores with disks of given VMs.
+func getVSANDiskDatastores(ctx *CheckContext, vms []mo.VirtualMachine) (map[vim.ManagedObjectReference]mo.Datastore, error) {
+	dsRefs := make(map[vim.ManagedObjectReference]bool)
+	for _, vm := range vms {
+		for _, disk := range getVirtualDisks(&vm) {
+			if backing, ok := disk.Backing.(vim.BaseVirtualDeviceFileBackingInfo); ok {
+				if ref := backing.GetVirtualDeviceFileBackingInfo().Datastore; ref != nil {
+					dsRefs[*ref] = true
+				}
+			}
+		}
+	}
+	datastores := make(map[vim.ManagedObjectReference]mo.Datastore)
+	if len(dsRefs) == 0 {
+		return datastores, nil
+	}
+	var refs []vim.ManagedObjectReference
+	for ref := range dsRefs {
+		refs = append(refs, ref)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var content []mo.Datastore
+	pc := property.DefaultCollector(ctx.VMClient)
+	if err := pc.Retrieve(tctx, refs, []string{"summary"}, &content); err != nil {
+		return nil, fmt.Errorf("failed to get datastores: %s", err)
+	}
+	for _, ds := range content {
+		if ds.Summary.Type == vsanDatastoreType {
+			datastores[ds.Self] = ds
+		}
+	}
+	return datastores, nil
+}
+
+// queryVSANDiskReservations returns disks of given VMs stored on given datastores with object space reservation
+// of their storage policies.
+func queryVSANDiskReservations(ctx *CheckContext, vms []mo.VirtualMachine, datastores map[vim.ManagedObjectReference]mo.Datastore) ([]vsanDiskReservation, error) {
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	c, err := newPbmClient(tctx, ctx.VMClient)
+	if err != nil {
+		return nil, fmt.Errorf("error creating pbm client: %v", err)
+	}
+
+	// virtualDiskId of the disk -> the disk
+	disks := make(map[string]*vsanDiskReservation)
+	req := pbmtypes.PbmQueryAssociatedProfiles{
+		This: c.ServiceContent.ProfileManager,
+	}
+	for _, vm := range vms {
+		for _, disk := range getVirtualDisks(&vm) {
+			backing, ok := disk.Backing.(vim.BaseVirtualDeviceFileBackingInfo)
+			if !ok {
+				continue
+			}
+			dsRef := backing.GetVirtualDeviceFileBackingInfo().Datastore
+			if dsRef == nil {
+				continue
+			}
+			if _, found := datastores[*dsRef]; !found {
+				continue
+			}
+			key := vm.Self.Value + ":" + strconv.Itoa(int(disk.Key))
+			disks[key] = &vsanDiskReservation{
+				vmName:    vm.Name,
+				diskLabel: getDeviceLabel(&disk.VirtualDevice),
+				datastore: *dsRef,
+				capacity:  disk.CapacityInBytes,
+			}
+			req.Entities = append(req.Entities, pbmtypes.PbmServerObjectRef{
+				ObjectType: string(pbmtypes.PbmObjectTypeVirtualDiskId),
+				Key:        key,
+				ServerUuid: ctx.VMClient.ServiceContent.About.InstanceUuid,
+			})
+		}
+	}
+	if len(req.Entities) == 0 {
+		return nil, nil
+	}
+
+	res, err := methods.PbmQueryAssociatedProfiles(tctx, c, &req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying associated storage policies: %v", err)
+	}
+	// virtualDiskId -> storage policy ID
+	diskProfiles := make(map[string]string)
+	var profileIDs []pbmtypes.PbmProfileId
+	seen := make(map[string]bool)
+	for _, result := range res.Returnval {
+		if result.Fault != nil || len(result.ProfileId) == 0 {
+			continue
+		}
+		id := result.ProfileId[0]
+		diskProfiles[result.Object.Key] = id.UniqueId
+		if !seen[id.UniqueId] {
+			seen[id.UniqueId] = true
+			profileIDs = append(profileIDs, id)
+		}
+	}
+
+	reservations := make(map[string]int32)
+	if len(profileIDs) > 0 {
+		profiles, err := c.RetrieveContent(tctx, profileIDs)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving storage policies: %v", err)
+		}
+		for _, profile := range profiles {
+			reservations[profile.GetPbmProfile().ProfileId.UniqueId] = getObjectSpaceReservation(profile)
+		}
+	}
+
+	var result []vsanDiskReservation
+	for key, disk := range disks {
+		if profileID, found := diskProfiles[key]; found {
+			disk.reservation = reservations[profileID]
+		}
+		result = append(result, *disk)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].vmName != result[j].vmName {
+			return result[i].vmName < result[j].vmName
+		}
+		return result[i].diskLabel < result[j].diskLabel
+	})
+	return result, nil
+}
+
+// getObjectSpaceReservation returns vSAN object space reservation of a storage policy in percent.
+// It returns 0 when the policy does not reserve space.
+func getObjectSpaceReservation(profile pbmtypes.BasePbmProfile) int32 {
+	capabilityProfile, ok := profile.(*pbmtypes.PbmCapabilityProfile)
+	if !ok {
+		return 0
+	}
+	constraints, ok := capabilityProfile.Constraints.(*pbmtypes.PbmCapabilitySubProfileConstraints)
+	if !ok {
+		return 0
+	}
+	var reservation int32
+	for _, subProfile := range constraints.SubProfiles {
+		for _, capability := range subProfile.Capability {
+			if capability.Id.Namespace != vsanCapabilityNamespace || capability.Id.Id != vsanProportionalCapacityID {
+				continue
+			}
+			for _, constraint := range capability.Constraint {
+				for _, property := range constraint.PropertyInstance {
+					if property.Id != vsanProportionalCapacityID {
+						continue
+					}
+					if value, ok := property.Value.(int32); ok && value > reservation {
+						reservation = value
+					}
+				}
+			}
+		}
+	}
+	return reservation
+}
+
+// getSnapshotReservationProblems returns descriptions of disks whose snapshot would reserve more space than
+// is free on their datastore, in the order of the disks.
+func getSnapshotReservationProblems(disks []vsanDiskReservation, datastores map[vim.ManagedObjectReference]mo.Datastore) []string {
+	var problems []string
+	for _, disk := range disks {
+		if disk.reservation <= 0 {
+			continue
+		}
+		ds, found := datastores[disk.datastore]
+		if !found {
+			continue
+		}
+		required := disk.capacity * int64(disk.reservation) / 100
+		if required <= ds.Summary.FreeSpace {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("disk %q of node VM %s on vSAN datastore %s has object space reservation %d%%: a snapshot needs %s reserved, the datastore has %s free",
+			disk.diskLabel, disk.vmName, ds.Summary.Name, disk.reservation,
+			resource.NewQuantity(required, resource.BinarySI), resource.NewQuantity(ds.Summary.FreeSpace, resource.BinarySI)))
+	}
+	return problems
+}
+
+// getVirtualDisks returns all virtual disks of the VM.
+func getVirtualDisks(vm *mo.VirtualMachine) []*vim.VirtualDisk {
+	if vm.Config == nil {
+		return nil
+	}
+	var disks []*vim.VirtualDisk
+	for _, device := range vm.Config.Hardware.Device {
+		if disk, ok := device.(*vim.VirtualDisk); ok {
+			disks = append(disks, disk)
+		}
+	}
+	return disks
+}
@@ -0,0 +1,142 @@
+package check
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckDatastoreSnapshotSpaceReservation(t *testing.T) {
+	// Stage
+	kubeClient := &fakeKubeClient{
+		nodes: defaultNodes(),
+	}
+	ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+
+	// Act
+	err = CheckDatastoreSnapshotSpaceReservation(ctx)
+
+	// Assert: vcsim does not have any vSAN datastores, the check is skipped
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	expectedMetrics := `
+# HELP vsphere_vsan_snapshot_reservation_insufficient_total [ALPHA] Number of vSAN objects of node VMs whose object space reservation does not fit free space of their datastore, so a snapshot of the object cannot be created.
+# TYPE vsphere_vsan_snapshot_reservation_insufficient_total gauge
+vsphere_vsan_snapshot_reservation_insufficient_total{vcenter="dc0"} 0
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_vsan_snapshot_reservation_insufficient_total"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+}
+
+func TestGetObjectSpaceReservation(t *testing.T) {
+	profile := func(capabilities ...pbmtypes.PbmCapabilityInstance) pbmtypes.BasePbmProfile {
+		return &pbmtypes.PbmCapabilityProfile{
+			Constraints: &pbmtypes.PbmCapabilitySubProfileConstraints{
+				SubProfiles: []pbmtypes.PbmCapabilitySubProfile{{Capability: capabilities}},
+			},
+		}
+	}
+	capability := func(namespace, id string, value interface{}) pbmtypes.PbmCapabilityInstance {
+		return pbmtypes.PbmCapabilityInstance{
+			Id: pbmtypes.PbmCapabilityMetadataUniqueId{Namespace: namespace, Id: id},
+			Constraint: []pbmtypes.PbmCapabilityConstraintInstance{
+				{PropertyInstance: []pbmtypes.PbmCapabilityPropertyInstance{{Id: id, Value: value}}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name                string
+		profile             pbmtypes.BasePbmProfile
+		expectedReservation int32
+	}{
+		{
+			name:    "no capabilities",
+			profile: profile(),
+		},
+		{
+			name:    "other vSAN capabilities",
+			profile: profile(capability("VSAN", "hostFailuresToTolerate", int32(1))),
+		},
+		{
+			name:                "object space reservation",
+			profile:             profile(capability("VSAN", "hostFailuresToTolerate", int32(1)), capability("VSAN", "proportionalCapacity", int32(50))),
+			expectedReservation: 50,
+		},
+		{
+			name:    "proportional capacity of another vendor",
+			profile: profile(capability("com.example", "proportionalCapacity", int32(100))),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reservation := getObjectSpaceReservation(test.profile)
+			if reservation != test.expectedReservation {
+				t.Errorf("Expected reservation %d, got %d", test.expectedReservation, reservation)
+			}
+		})
+	}
+}
+
+func TestGetSnapshotReservationProblems(t *testing.T) {
+	vsanDS := types.ManagedObjectReference{Type: "Datastore", Value: "ds-1"}
+	datastores := map[types.ManagedObjectReference]mo.Datastore{
+		vsanDS: {Summary: types.DatastoreSummary{Name: "vsanDatastore", Type: "vsan", FreeSpace: 10 * gib}},
+	}
+	disk := func(label string, capacity int64, reservation int32) vsanDiskReservation {
+		return vsanDiskReservation{
+			vmName:      "node-1",
+			diskLabel:   label,
+			datastore:   vsanDS,
+			capacity:    capacity,
+			reservation: reservation,
+		}
+	}
+
+	tests := []struct {
+		name             string
+		disks            []vsanDiskReservation
+		expectedProblems []string
+	}{
+		{
+			name:  "no reservation",
+			disks: []vsanDiskReservation{disk("Hard disk 1", 100*gib, 0)},
+		},
+		{
+			name:  "reservation fits free space",
+			disks: []vsanDiskReservation{disk("Hard disk 1", 100*gib, 10), disk("Hard disk 2", 10*gib, 100)},
+		},
+		{
+			name:  "reservation does not fit free space",
+			disks: []vsanDiskReservation{disk("Hard disk 1", 100*gib, 50), disk("Hard disk 2", 10*gib, 100)},
+			expectedProblems: []string{
+				`disk "Hard disk 1" of node VM node-1 on vSAN datastore vsanDatastore has object space reservation 50%: a snapshot needs 50Gi reserved, the datastore has 10Gi free`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems := getSnapshotReservationProblems(test.disks, datastores)
+			if !reflect.DeepEqual(problems, test.expectedProblems) {
+				t.Errorf("Expected problems %q, got %q", test.expectedProblems, problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	datastoreCrossZoneMountsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_datastore_cross_zone_mounts_total",
+			Help:           "Number of mounts of zone tagged datastores used by the cluster on ESXi hosts in other zones.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(datastoreCrossZoneMountsMetric)
+}
+
+// hostZone is an ESXi host with its zones.
+type hostZone struct {
+	name  string
+	zones []string
+}
+
+// CheckDatastoreAccessibleFromFailureDomainHostsOnly tests that datastores used by node VMs that have a zone tag
+// are mounted only on ESXi hosts in the same zone. The zone of a host is its tag in the zone tag category from
+// vSphere configuration, or tag of its compute cluster or datacenter when the host is not tagged.
+// A datastore mounted in another zone allows volumes to be provisioned for nodes in a zone they do not belong to.
+func CheckDatastoreAccessibleFromFailureDomainHostsOnly(ctx *CheckContext) error {
+	zoneCategory := ctx.VMConfig.Labels.Zone
+	if zoneCategory == "" {
+		klog.V(4).Infof("CheckDatastoreAccessibleFromFailureDomainHostsOnly: zone tag category is not configured, skipping")
+		datastoreCrossZoneMountsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+	if ctx.TagManager == nil {
+		return fmt.Errorf("cannot check zone tags in category %s: vSphere tags are not available", zoneCategory)
+	}
+
+	zoneTags, err := getCategoryTags(ctx, zoneCategory)
+	if err != nil {
+		return err
+	}
+	dsRefs, err := getNodeDatastores(ctx)
+	if err != nil {
+		return err
+	}
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var datastores []mo.Datastore
+	if err := pc.Retrieve(tctx, dsRefs, []string{"name", "host"}, &datastores); err != nil {
+		return fmt.Errorf("failed to get datastore host mounts: %s", err)
+	}
+	sort.Slice(datastores, func(i, j int) bool { return datastores[i].Name < datastores[j].Name })
+
+	var refs []mo.Reference
+	for _, ref := range dsRefs {
+		refs = append(refs, ref)
+	}
+	datastoreZones, err := getAttachedZones(ctx, refs, zoneTags)
+	if err != nil {
+		return err
+	}
+	hosts := make(map[vim.ManagedObjectReference]bool)
+	for _, ds := range datastores {
+		if len(datastoreZones[ds.Self]) == 0 {
+			continue
+		}
+		for _, mount := range ds.Host {
+			hosts[mount.Key] = true
+		}
+	}
+	if len(hosts) == 0 {
+		klog.V(2).Infof("CheckDatastoreAccessibleFromFailureDomainHostsOnly: no datastores with zone tags found, skipping")
+		datastoreCrossZoneMountsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+	hostZones, err := getHostZones(ctx, hosts, zoneTags)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	crossZoneMounts := 0
+	for _, ds := range datastores {
+		zones := datastoreZones[ds.Self]
+		if len(zones) == 0 {
+			klog.V(4).Infof("Datastore %s has no zone tag", ds.Name)
+			continue
+		}
+		foreignHosts := getForeignZoneHosts(ds, zones, hostZones)
+		if len(foreignHosts) == 0 {
+			klog.V(4).Infof("Datastore %s is mounted only on hosts in zone %s", ds.Name, strings.Join(zones, ", "))
+			continue
+		}
+		crossZoneMounts += len(foreignHosts)
+		errs = append(errs, fmt.Errorf("datastore %s in zone %s is mounted on ESXi hosts in other zones: %s", ds.Name, strings.Join(zones, ", "), strings.Join(foreignHosts, ", ")))
+	}
+	datastoreCrossZoneMountsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(crossZoneMounts))
+
+	klog.V(2).Infof("CheckDatastoreAccessibleFromFailureDomainHostsOnly checked %d datastores, %d cross zone mounts found", len(datastores), crossZoneMounts)
+	return JoinErrors(errs)
+}
+
+// getForeignZoneHosts returns sorted descriptions of ESXi hosts that mount the datastore and are not in any
+// of the given zones. Hosts without any zone and unmounted datastores are not reported.
+func getForeignZoneHosts(ds mo.Datastore, zones []string, hostZones map[vim.ManagedObjectReference]hostZone) []string {
+	allowed := make(map[string]bool)
+	for _, zone := range zones {
+		allowed[zone] = true
+	}
+
+	var foreignHosts []string
+	for _, mount := range ds.Host {
+		if mount.MountInfo.Mounted != nil && !*mount.MountInfo.Mounted {
+			continue
+		}
+		host, found := hostZones[mount.Key]
+		if !found || len(host.zones) == 0 {
+			continue
+		}
+		inZone := false
+		for _, zone := range host.zones {
+			if allowed[zone] {
+				inZone = true
+				break
+			}
+		}
+		if !inZone {
+			foreignHosts = append(foreignHosts, fmt.Sprintf("%s (%s)", host.name, strings.Join(host.zones, ", ")))
+		}
+	}
+	sort.Strings(foreignHosts)
+	return foreignHosts
+}
+
+// getHostZones returns names and zones of given ESXi hosts. Zones of a host without a zone tag are
+// zones of its compute cluster, or zones of its datacenter when the compute cluster is not tagged either.
+func getHostZones(ctx *CheckContext, hosts map[vim.ManagedObjectReference]bool, zoneTags map[string]string) (map[vim.ManagedObjectReference]hostZone, error) {
+	var hostRefs []vim.ManagedObjectReference
+	for ref := range hosts {
+		hostRefs = append(hostRefs, ref)
+	}
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var hostMos []mo.HostSystem
+	pc := property.DefaultCollector(ctx.VMClient)
+	if err := pc.Retrieve(tctx, hostRefs, []string{"name", "parent"}, &hostMos); err != nil {
+		return nil, fmt.Errorf("failed to get ESXi hosts: %s", err)
+	}
+
+	// compute cluster -> its datacenter
+	datacenters := make(map[vim.ManagedObjectReference]vim.ManagedObjectReference)
+	objects := make(map[vim.ManagedObjectReference]bool)
+	for _, host := range hostMos {
+		objects[host.Self] = true
+		if host.Parent == nil {
+			continue
+		}
+		if _, found := datacenters[*host.Parent]; found {
+			continue
+		}
+		dcRef, err := getParentDatacenter(ctx, *host.Parent)
+		if err != nil {
+			return nil, err
+		}
+		datacenters[*host.Parent] = dcRef
+		objects[*host.Parent] = true
+		objects[dcRef] = true
+	}
+	var refs []mo.Reference
+	for ref := range objects {
+		refs = append(refs, ref)
+	}
+	objectZones, err := getAttachedZones(ctx, refs, zoneTags)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make(map[vim.ManagedObjectReference]hostZone)
+	for _, host := range hostMos {
+		hz := hostZone{name: host.Name, zones: objectZones[host.Self]}
+		if len(hz.zones) == 0 && host.Parent != nil {
+			hz.zones = objectZones[*host.Parent]
+			if len(hz.zones) == 0 {
+				hz.zones = objectZones[datacenters[*host.Parent]]
+			}
+		}
+		zones[host.Self] = hz
+	}
+	return zones, nil
+}
@@ -0,0 +1,155 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckDatastoreAccessibleFromFailureDomainHostsOnly(t *testing.T) {
+	tests := []struct {
+		name            string
+		zoneCategory    string
+		datastoreZones  []string
+		clusterZones    []string
+		datacenterZones []string
+		// host name -> zone tags
+		hostZones     map[string][]string
+		expectedError string
+		expectedCount int
+	}{
+		{
+			name:            "zones not configured",
+			datastoreZones:  []string{"zone-a"},
+			datacenterZones: []string{"zone-b"},
+		},
+		{
+			name:            "datastore without zone",
+			zoneCategory:    "k8s-zone",
+			clusterZones:    []string{"zone-a"},
+			datacenterZones: []string{"zone-b"},
+		},
+		{
+			name:            "datastore mounted in its zone",
+			zoneCategory:    "k8s-zone",
+			datastoreZones:  []string{"zone-a"},
+			datacenterZones: []string{"zone-a"},
+		},
+		{
+			name:            "datastore mounted on a host in other compute cluster zone",
+			zoneCategory:    "k8s-zone",
+			datastoreZones:  []string{"zone-a"},
+			clusterZones:    []string{"zone-b"},
+			datacenterZones: []string{"zone-a"},
+			expectedError:   "datastore LocalDS_0 in zone zone-a is mounted on ESXi hosts in other zones: DC0_C0_H2 (zone-b)",
+			expectedCount:   1,
+		},
+		{
+			name:            "datastore mounted on a host in other datacenter zone",
+			zoneCategory:    "k8s-zone",
+			datastoreZones:  []string{"zone-a"},
+			datacenterZones: []string{"zone-b"},
+			expectedError:   "datastore LocalDS_0 in zone zone-a is mounted on ESXi hosts in other zones: DC0_C0_H2 (zone-b)",
+			expectedCount:   1,
+		},
+		{
+			name:           "datastore mounted on a host with other zone tag",
+			zoneCategory:   "k8s-zone",
+			datastoreZones: []string{"zone-a"},
+			clusterZones:   []string{"zone-a"},
+			hostZones: map[string][]string{
+				"DC0_C0_H2": {"zone-b"},
+			},
+			expectedError: "datastore LocalDS_0 in zone zone-a is mounted on ESXi hosts in other zones: DC0_C0_H2 (zone-b)",
+			expectedCount: 1,
+		},
+		{
+			name:           "host zone tag overrides compute cluster zone",
+			zoneCategory:   "k8s-zone",
+			datastoreZones: []string{"zone-a"},
+			clusterZones:   []string{"zone-b"},
+			hostZones: map[string][]string{
+				"DC0_C0_H2": {"zone-a"},
+			},
+		},
+		{
+			name:            "datastore in multiple zones",
+			zoneCategory:    "k8s-zone",
+			datastoreZones:  []string{"zone-a", "zone-b"},
+			clusterZones:    []string{"zone-a"},
+			datacenterZones: []string{"zone-b"},
+		},
+		{
+			name:           "hosts without zones",
+			zoneCategory:   "k8s-zone",
+			datastoreZones: []string{"zone-a"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMConfig.Labels.Zone = test.zoneCategory
+
+			clusterRef := types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "clustercomputeresource-30"}
+			dc, err := getDatacenter(ctx, defaultDC)
+			if err != nil {
+				t.Fatalf("Failed to get datacenter: %s", err)
+			}
+			ds, err := getDataStoreByName(ctx, "LocalDS_0", dc)
+			if err != nil {
+				t.Fatalf("Failed to get datastore: %s", err)
+			}
+			_, hosts, err := getComputeClusterHosts(ctx, clusterRef, []string{"name"})
+			if err != nil {
+				t.Fatalf("Failed to get hosts: %s", err)
+			}
+			tagIDs := createZoneTags(t, ctx, "k8s-zone", "zone-a", "zone-b")
+			attachZoneTags(t, ctx, tagIDs, ds.Reference(), test.datastoreZones)
+			attachZoneTags(t, ctx, tagIDs, clusterRef, test.clusterZones)
+			attachZoneTags(t, ctx, tagIDs, dc.Reference(), test.datacenterZones)
+			for _, host := range hosts {
+				attachZoneTags(t, ctx, tagIDs, host.Reference(), test.hostZones[host.Name])
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckDatastoreAccessibleFromFailureDomainHostsOnly(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_datastore_cross_zone_mounts_total [ALPHA] Number of mounts of zone tagged datastores used by the cluster on ESXi hosts in other zones.
+# TYPE vsphere_datastore_cross_zone_mounts_total gauge
+vsphere_datastore_cross_zone_mounts_total{vcenter="dc0"} %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_datastore_cross_zone_mounts_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -4,8 +4,29 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
+// CheckTimeoutError is returned by an instrumented check that did not finish within its timeout.
+type CheckTimeoutError struct {
+	// Node is name of the node for node checks, empty for cluster checks.
+	Node    string
+	Timeout time.Duration
+}
+
+func (e *CheckTimeoutError) Error() string {
+	if e.Node != "" {
+		return fmt.Sprintf("node %s: check timed out after %s", e.Node, e.Timeout)
+	}
+	return fmt.Sprintf("check timed out after %s", e.Timeout)
+}
+
+// IsCheckTimeout returns true if the error is a CheckTimeoutError.
+func IsCheckTimeout(err error) bool {
+	var timeoutErr *CheckTimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
 func JoinErrors(errs []error) error {
 	switch {
 	case len(errs) == 0:
@@ -9,8 +9,12 @@ import (
 	"github.com/openshift/vsphere-problem-detector/pkg/util"
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/find"
+	_ "github.com/vmware/govmomi/pbm/simulator"
 	"github.com/vmware/govmomi/session"
 	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vapi/rest"
+	_ "github.com/vmware/govmomi/vapi/simulator"
+	"github.com/vmware/govmomi/vapi/tags"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
@@ -40,6 +44,14 @@ var (
 		{"DC0_H0_VM0", "265104de-1472-547c-b873-6dc7883fb6cb"},
 		{"DC0_H0_VM1", "12f8928d-f144-5c57-89db-dd2d0902c9fa"},
 	}
+
+	// Virtual machines generated by vSphere simulator in compute cluster DC0_C0.
+	clusterVMs = []simulatedVM{
+		{"DC0_C0_RP0_VM0", "cd0681bf-2f18-5c00-9b9b-8197c0095348"},
+		{"DC0_C0_RP0_VM1", "f7c371d6-2003-5a48-9859-3bc9a8b08908"},
+		{"DC0_C0_APP0_VM0", "bb58202a-c925-5cb6-b552-a8648ba3f1d5"},
+		{"DC0_C0_APP0_VM1", "baf53482-475d-59dc-8777-2b193e7af804"},
+	}
 )
 
 func connectToSimulator(s *simulator.Server) (*vim25.Client, error) {
@@ -82,6 +94,7 @@ func setupSimulator(kubeClient *fakeKubeClient, modelDir string) (ctx *CheckCont
 		return nil, nil, err
 	}
 	model.Service.TLS = new(tls.Config)
+	model.Service.RegisterEndpoints = true
 
 	s := model.Service.NewServer()
 	client, err := connectToSimulator(s)
@@ -102,6 +115,13 @@ func setupSimulator(kubeClient *fakeKubeClient, modelDir string) (ctx *CheckCont
 	}
 
 	ctx.Username = userSession.UserName
+	restClient := rest.NewClient(client)
+	if err := restClient.Login(context.TODO(), s.URL.User); err != nil {
+		s.Close()
+		model.Remove()
+		return nil, nil, fmt.Errorf("failed to log in to REST API of the simulator: %s", err)
+	}
+	ctx.TagManager = tags.NewManager(restClient)
 	ctx.VMConfig.Workspace.VCenterIP = "dc0"
 	ctx.VMConfig.VirtualCenter["dc0"].User = userSession.UserName
 
@@ -117,6 +137,8 @@ type fakeKubeClient struct {
 	nodes          []*v1.Node
 	storageClasses []*storagev1.StorageClass
 	pvs            []*v1.PersistentVolume
+	pvcs           []*v1.PersistentVolumeClaim
+	attachments    []*storagev1.VolumeAttachment
 }
 
 var _ KubeClient = &fakeKubeClient{}
@@ -137,6 +159,14 @@ func (f *fakeKubeClient) ListPVs(ctx context.Context) ([]*v1.PersistentVolume, e
 	return f.pvs, nil
 }
 
+func (f *fakeKubeClient) ListPVCs(ctx context.Context) ([]*v1.PersistentVolumeClaim, error) {
+	return f.pvcs, nil
+}
+
+func (f *fakeKubeClient) ListVolumeAttachments(ctx context.Context) ([]*storagev1.VolumeAttachment, error) {
+	return f.attachments, nil
+}
+
 func node(name string, modifiers ...func(*v1.Node)) *v1.Node {
 	n := &v1.Node{
 		ObjectMeta: metav1.ObjectMeta{
@@ -167,6 +197,16 @@ func defaultNodes() []*v1.Node {
 	return nodes
 }
 
+// clusterNodes returns nodes that run in compute cluster DC0_C0.
+func clusterNodes() []*v1.Node {
+	nodes := []*v1.Node{}
+	for _, vm := range clusterVMs {
+		node := node(vm.name, withProviderID("vsphere://"+vm.uuid))
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
 func infrastructure(modifiers ...func(*ocpv1.Infrastructure)) *ocpv1.Infrastructure {
 	infra := &ocpv1.Infrastructure{
 		ObjectMeta: metav1.ObjectMeta{
@@ -225,6 +265,18 @@ func setHardwareVersion(ctx *CheckContext, node *v1.Node, hardwareVersion string
 	return err
 }
 
+// getHostSystem returns simulated HostSystem with given ID.
+func getHostSystem(hostSystemId string) (*simulator.HostSystem, error) {
+	hsRef := simulator.Map.Get(types.ManagedObjectReference{
+		Type:  "HostSystem",
+		Value: hostSystemId,
+	})
+	if hsRef == nil {
+		return nil, fmt.Errorf("can't find HostSystem %s", hostSystemId)
+	}
+	return hsRef.(*simulator.HostSystem), nil
+}
+
 func customizeHostVersion(hostSystemId string, version string, apiVersion string) error {
 	hsRef := simulator.Map.Get(types.ManagedObjectReference{
 		Type:  "HostSystem",
@@ -0,0 +1,246 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	cpuFeatureDivergenceMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_cluster_cpu_feature_divergence_total",
+			Help:           "Number of vSphere compute clusters with node VMs whose hosts expose different CPU features.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(cpuFeatureDivergenceMetric)
+}
+
+// CheckHostCPUFeatureConsistency tests that all hosts of compute clusters that run node VMs
+// expose the same CPU features. Node VMs cannot be migrated by vMotion between hosts with
+// different CPU features, unless Enhanced vMotion Compatibility (EVC) is enabled in the cluster.
+func CheckHostCPUFeatureConsistency(ctx *CheckContext) error {
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	divergentClusters := 0
+	for _, clusterRef := range clusterRefs {
+		differences, clusterName, err := getClusterCPUFeatureDifferences(ctx, clusterRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(differences) == 0 {
+			continue
+		}
+		divergentClusters++
+		errs = append(errs, fmt.Errorf("compute cluster %s has hosts with different CPU features and EVC is disabled: %s", clusterName, strings.Join(differences, ", ")))
+	}
+	cpuFeatureDivergenceMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(divergentClusters))
+
+	klog.V(2).Infof("CheckHostCPUFeatureConsistency checked %d compute clusters, %d with different CPU features", len(clusterRefs), divergentClusters)
+	return JoinErrors(errs)
+}
+
+// getClusterCPUFeatureDifferences returns human readable list of CPUID feature flags that differ
+// among hosts in the cluster. It returns an empty list when the cluster has EVC enabled.
+func getClusterCPUFeatureDifferences(ctx *CheckContext, clusterRef vim.ManagedObjectReference) ([]string, string, error) {
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+
+	var cluster mo.ClusterComputeResource
+	if err := pc.RetrieveOne(tctx, clusterRef, []string{"name", "host", "summary"}, &cluster); err != nil {
+		return nil, clusterRef.Value, fmt.Errorf("failed to get compute cluster %s: %s", clusterRef.Value, err)
+	}
+	if summary, ok := cluster.Summary.(*vim.ClusterComputeResourceSummary); ok && summary.CurrentEVCModeKey != "" {
+		klog.V(4).Infof("Compute cluster %s has EVC mode %s", cluster.Name, summary.CurrentEVCModeKey)
+		return nil, cluster.Name, nil
+	}
+	if len(cluster.Host) < 2 {
+		return nil, cluster.Name, nil
+	}
+
+	var hosts []mo.HostSystem
+	if err := pc.Retrieve(tctx, cluster.Host, []string{"name", "hardware.cpuFeature"}, &hosts); err != nil {
+		return nil, cluster.Name, fmt.Errorf("failed to get hosts of compute cluster %s: %s", cluster.Name, err)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name < hosts[j].Name })
+
+	reference := hosts[0]
+	referenceFeatures := cpuFeatureRegisters(&reference)
+	var differences []string
+	for i := 1; i < len(hosts); i++ {
+		features := cpuFeatureRegisters(&hosts[i])
+		for _, difference := range diffCPUFeatureRegisters(referenceFeatures, features) {
+			differences = append(differences, fmt.Sprintf("host %s differs from host %s in %s", hosts[i].Name, reference.Name, difference))
+		}
+	}
+	return differences, cluster.Name, nil
+}
+
+// cpuFeatureLevel is a CPUID level with feature flags.
+type cpuFeatureLevel struct {
+	name string
+	// registers with feature flags, register -> bit -> feature name.
+	// Bits without a name are reported by their number.
+	registers map[string]map[int]string
+}
+
+// cpuFeatureLevels are CPUID levels and their registers that hold feature flags, by CPUID level.
+// The other registers hold family, model, stepping, APIC ID, cache sizes and so on, which differ
+// between hosts that run the same VMs just fine. Feature names are the ones of /proc/cpuinfo.
+var cpuFeatureLevels = map[int32]cpuFeatureLevel{
+	1: {
+		name: "level 1",
+		registers: map[string]map[int]string{
+			"ecx": {0: "sse3", 1: "pclmulqdq", 3: "monitor", 5: "vmx", 9: "ssse3", 12: "fma", 13: "cx16", 17: "pcid", 19: "sse4_1", 20: "sse4_2", 21: "x2apic", 22: "movbe", 23: "popcnt", 24: "tsc_deadline_timer", 25: "aes", 26: "xsave", 27: "osxsave", 28: "avx", 29: "f16c", 30: "rdrand", 31: "hypervisor"},
+			"edx": {0: "fpu", 4: "tsc", 5: "msr", 6: "pae", 8: "cx8", 9: "apic", 11: "sep", 12: "mtrr", 13: "pge", 15: "cmov", 16: "pat", 19: "clflush", 23: "mmx", 24: "fxsr", 25: "sse", 26: "sse2", 28: "ht"},
+		},
+	},
+	7: {
+		name: "level 7",
+		registers: map[string]map[int]string{
+			"ebx": {0: "fsgsbase", 3: "bmi1", 4: "hle", 5: "avx2", 7: "smep", 8: "bmi2", 9: "erms", 10: "invpcid", 11: "rtm", 14: "mpx", 16: "avx512f", 17: "avx512dq", 18: "rdseed", 19: "adx", 20: "smap", 21: "avx512ifma", 23: "clflushopt", 24: "clwb", 28: "avx512cd", 29: "sha_ni", 30: "avx512bw", 31: "avx512vl"},
+			"ecx": {1: "avx512vbmi", 2: "umip", 3: "pku", 6: "avx512_vbmi2", 8: "gfni", 9: "vaes", 10: "vpclmulqdq", 11: "avx512_vnni", 12: "avx512_bitalg", 14: "avx512_vpopcntdq", 22: "rdpid"},
+			"edx": {2: "avx512_4vnniw", 3: "avx512_4fmaps", 4: "fsrm", 10: "md_clear", 26: "spec_ctrl", 27: "intel_stibp", 28: "flush_l1d", 29: "arch_capabilities", 31: "spec_ctrl_ssbd"},
+		},
+	},
+	// vSphere reports CPUID levels as int32, extended level 0x80000001 wraps around.
+	-0x7fffffff: {
+		name: "level 0x80000001",
+		registers: map[string]map[int]string{
+			"ecx": {0: "lahf_lm", 5: "abm", 6: "sse4a", 8: "3dnowprefetch"},
+			"edx": {11: "syscall", 20: "nx", 26: "pdpe1gb", 27: "rdtscp", 29: "lm"},
+		},
+	},
+}
+
+// cpuFeatureRegister is a CPUID register with feature flags of a host.
+type cpuFeatureRegister struct {
+	// value as reported by vSphere, bits from the highest one, e.g. "0000:0000:0000:0000:0000:0000:0000:0001".
+	value string
+	// features is register bit -> feature name.
+	features map[int]string
+}
+
+// cpuFeatureRegisters returns CPUID feature flag registers of a host, "level <level> <register>" -> register.
+func cpuFeatureRegisters(host *mo.HostSystem) map[string]cpuFeatureRegister {
+	registers := make(map[string]cpuFeatureRegister)
+	if host.Hardware == nil {
+		return registers
+	}
+	for _, feature := range host.Hardware.CpuFeature {
+		level, found := cpuFeatureLevels[feature.Level]
+		if !found {
+			continue
+		}
+		prefix := level.name
+		if feature.Vendor != "" {
+			prefix = fmt.Sprintf("%s vendor %s", level.name, feature.Vendor)
+		}
+		values := map[string]string{"ebx": feature.Ebx, "ecx": feature.Ecx, "edx": feature.Edx}
+		for register, features := range level.registers {
+			registers[prefix+" "+register] = cpuFeatureRegister{value: values[register], features: features}
+		}
+	}
+	return registers
+}
+
+// diffCPUFeatureRegisters returns human readable differences of registers in a and b, sorted by register name.
+// Features are reported as missing when they are set in a and not in b, and as extra when they are set only in b.
+func diffCPUFeatureRegisters(a, b map[string]cpuFeatureRegister) []string {
+	var names []string
+	for name, register := range a {
+		if b[name].value != register.value {
+			names = append(names, name)
+		}
+	}
+	for name := range b {
+		if _, found := a[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diff []string
+	for _, name := range names {
+		ra, foundA := a[name]
+		rb, foundB := b[name]
+		switch {
+		case !foundA:
+			diff = append(diff, fmt.Sprintf("%s (%s, not reported by the other host)", name, rb.value))
+			continue
+		case !foundB:
+			diff = append(diff, fmt.Sprintf("%s (not reported, %s on the other host)", name, ra.value))
+			continue
+		}
+		desc := fmt.Sprintf("%s (%s vs. %s)", name, rb.value, ra.value)
+		bitsA, errA := parseCPUIDRegister(ra.value)
+		bitsB, errB := parseCPUIDRegister(rb.value)
+		if errA != nil || errB != nil {
+			diff = append(diff, desc)
+			continue
+		}
+		var missing, extra []string
+		for bit := 0; bit < 32; bit++ {
+			mask := uint32(1) << bit
+			if bitsA&mask == bitsB&mask {
+				continue
+			}
+			feature, found := ra.features[bit]
+			if !found {
+				feature = fmt.Sprintf("bit%d", bit)
+			}
+			if bitsA&mask != 0 {
+				missing = append(missing, feature)
+			} else {
+				extra = append(extra, feature)
+			}
+		}
+		if len(missing) > 0 {
+			desc += ": missing " + strings.Join(missing, " ")
+		}
+		if len(extra) > 0 {
+			if len(missing) > 0 {
+				desc += ","
+			} else {
+				desc += ":"
+			}
+			desc += " extra " + strings.Join(extra, " ")
+		}
+		diff = append(diff, desc)
+	}
+	return diff
+}
+
+// parseCPUIDRegister parses a CPUID register value reported by vSphere, i.e. 32 bits from the highest one,
+// optionally separated by colons.
+func parseCPUIDRegister(value string) (uint32, error) {
+	bits := strings.ReplaceAll(value, ":", "")
+	if len(bits) != 32 {
+		return 0, fmt.Errorf("unexpected length of CPUID register %q", value)
+	}
+	v, err := strconv.ParseUint(bits, 2, 32)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse CPUID register %q: %s", value, err)
+	}
+	return uint32(v), nil
+}
@@ -0,0 +1,159 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckHostCPUFeatureConsistency(t *testing.T) {
+	tests := []struct {
+		name            string
+		nodes           []*v1.Node
+		differentHostID string
+		changeFeatures  func(features []types.HostCpuIdInfo)
+		evcMode         string
+		expectedError   string
+	}{
+		{
+			name:  "nodes on standalone host",
+			nodes: defaultNodes(),
+		},
+		{
+			name:  "nodes in cluster with the same CPU features",
+			nodes: clusterNodes(),
+		},
+		{
+			name:            "nodes in cluster with different CPU features",
+			nodes:           clusterNodes(),
+			differentHostID: "host-45",
+			changeFeatures:  clearCPUFeature(1, "ecx"),
+			expectedError:   "compute cluster DC0_C0 has hosts with different CPU features and EVC is disabled: host DC0_C0_H1 differs from host DC0_C0_H0 in level 1 ecx (0000:0000:0000:0000:0000:0000:0000:0000 vs. 1001:0111:1011:1010:0010:0010:0010:1011): missing sse3 pclmulqdq monitor vmx ssse3 cx16 pcid sse4_1 sse4_2 x2apic popcnt tsc_deadline_timer aes xsave avx hypervisor",
+		},
+		{
+			name:            "nodes in cluster with hosts with some different CPU features",
+			nodes:           clusterNodes(),
+			differentHostID: "host-45",
+			changeFeatures: func(features []types.HostCpuIdInfo) {
+				for i := range features {
+					if features[i].Level == 1 {
+						// Clear aes (bit 25) and set fma (bit 12).
+						features[i].Ecx = "1001:0101:1011:1010:0011:0010:0010:1011"
+					}
+				}
+			},
+			expectedError: "compute cluster DC0_C0 has hosts with different CPU features and EVC is disabled: host DC0_C0_H1 differs from host DC0_C0_H0 in level 1 ecx (1001:0101:1011:1010:0011:0010:0010:1011 vs. 1001:0111:1011:1010:0010:0010:0010:1011): missing aes, extra fma",
+		},
+		{
+			name:            "nodes in cluster with different extended CPU features",
+			nodes:           clusterNodes(),
+			differentHostID: "host-45",
+			changeFeatures:  clearCPUFeature(-0x7fffffff, "edx"),
+			expectedError:   "compute cluster DC0_C0 has hosts with different CPU features and EVC is disabled: host DC0_C0_H1 differs from host DC0_C0_H0 in level 0x80000001 edx (0000:0000:0000:0000:0000:0000:0000:0000 vs. 0010:1000:0001:0000:0000:1000:0000:0000): missing syscall nx rdtscp lm",
+		},
+		{
+			name:            "nodes in cluster with hosts of different CPU stepping",
+			nodes:           clusterNodes(),
+			differentHostID: "host-45",
+			changeFeatures: func(features []types.HostCpuIdInfo) {
+				for i := range features {
+					if features[i].Level == 1 {
+						// Stepping ID is in the lowest 4 bits of level 1 eax.
+						features[i].Eax = features[i].Eax[:len(features[i].Eax)-4] + "0001"
+					}
+				}
+			},
+		},
+		{
+			name:            "nodes in cluster with different CPU features and EVC",
+			nodes:           clusterNodes(),
+			differentHostID: "host-45",
+			changeFeatures:  clearCPUFeature(1, "ecx"),
+			evcMode:         "intel-broadwell",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: test.nodes,
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			if test.differentHostID != "" {
+				hs, err := getHostSystem(test.differentHostID)
+				if err != nil {
+					t.Fatalf("Failed to get host: %s", err)
+				}
+				features := append([]types.HostCpuIdInfo{}, hs.Hardware.CpuFeature...)
+				test.changeFeatures(features)
+				hs.Hardware.CpuFeature = features
+			}
+			if test.evcMode != "" {
+				obj := simulator.Map.Get(types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "clustercomputeresource-30"})
+				cluster := obj.(*simulator.ClusterComputeResource)
+				cluster.Summary.(*types.ClusterComputeResourceSummary).CurrentEVCModeKey = test.evcMode
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckHostCPUFeatureConsistency(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedCount := 0
+			if test.expectedError != "" {
+				expectedCount = 1
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_cluster_cpu_feature_divergence_total [ALPHA] Number of vSphere compute clusters with node VMs whose hosts expose different CPU features.
+# TYPE vsphere_cluster_cpu_feature_divergence_total gauge
+vsphere_cluster_cpu_feature_divergence_total{vcenter="dc0"} %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_cluster_cpu_feature_divergence_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+// clearCPUFeature returns a function that clears all bits of a CPUID register of given level.
+func clearCPUFeature(level int32, register string) func(features []types.HostCpuIdInfo) {
+	return func(features []types.HostCpuIdInfo) {
+		cleared := "0000:0000:0000:0000:0000:0000:0000:0000"
+		for i := range features {
+			if features[i].Level != level {
+				continue
+			}
+			switch register {
+			case "ecx":
+				features[i].Ecx = cleared
+			case "edx":
+				features[i].Edx = cleared
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package check
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	hostTimeZoneDivergentMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_host_time_zone_divergent_total",
+			Help:           "Number of ESXi hosts with time zone different from the most common time zone in their compute cluster.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(hostTimeZoneDivergentMetric)
+}
+
+// CheckHostTimeZoneConsistency checks that all hosts of compute clusters that run node VMs use the same
+// time zone. Hosts in different time zones make log timestamps confusing and can shift scheduled tasks.
+// The check is advisory, it only logs a warning and reports the metric for hosts with a different time zone.
+func CheckHostTimeZoneConsistency(ctx *CheckContext) error {
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	divergentHosts := 0
+	for _, clusterRef := range clusterRefs {
+		clusterName, hosts, err := getComputeClusterHosts(ctx, clusterRef, []string{"name", "config.dateTimeInfo"})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// host name -> time zone name
+		timeZones := make(map[string]string)
+		counts := make(map[string]int)
+		for _, host := range hosts {
+			if host.Config == nil || host.Config.DateTimeInfo == nil {
+				klog.V(4).Infof("Host %s has no date and time configuration", host.Name)
+				continue
+			}
+			tz := host.Config.DateTimeInfo.TimeZone.Name
+			timeZones[host.Name] = tz
+			counts[tz]++
+		}
+
+		norm := mostCommonTimeZone(counts)
+		for _, host := range hosts {
+			tz, found := timeZones[host.Name]
+			if !found || tz == norm {
+				continue
+			}
+			divergentHosts++
+			klog.Warningf("Host %s in compute cluster %s has time zone %s, other hosts in the cluster use %s", host.Name, clusterName, tz, norm)
+		}
+	}
+	hostTimeZoneDivergentMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(divergentHosts))
+	klog.V(2).Infof("CheckHostTimeZoneConsistency checked %d compute clusters, %d hosts with divergent time zone", len(clusterRefs), divergentHosts)
+	return JoinErrors(errs)
+}
+
+// mostCommonTimeZone returns the time zone with the highest count. Ties are broken by name.
+func mostCommonTimeZone(counts map[string]int) string {
+	var norm string
+	max := 0
+	for tz, count := range counts {
+		if count > max || (count == max && tz < norm) {
+			norm = tz
+			max = count
+		}
+	}
+	return norm
+}
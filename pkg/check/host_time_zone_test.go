@@ -0,0 +1,84 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckHostTimeZoneConsistency(t *testing.T) {
+	tests := []struct {
+		name          string
+		timeZones     map[string]string
+		expectedCount int
+	}{
+		{
+			name:      "no time zone configuration",
+			timeZones: map[string]string{},
+		},
+		{
+			name: "same time zones",
+			timeZones: map[string]string{
+				"host-37": "UTC",
+				"host-45": "UTC",
+				"host-53": "UTC",
+			},
+		},
+		{
+			name: "divergent time zone",
+			timeZones: map[string]string{
+				"host-37": "UTC",
+				"host-45": "Europe/Prague",
+				"host-53": "UTC",
+			},
+			expectedCount: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			for hostID, tz := range test.timeZones {
+				hs, err := getHostSystem(hostID)
+				if err != nil {
+					t.Fatalf("Failed to get host: %s", err)
+				}
+				hs.Config.DateTimeInfo = &types.HostDateTimeInfo{
+					TimeZone: types.HostDateTimeSystemTimeZone{Key: tz, Name: tz},
+				}
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckHostTimeZoneConsistency(ctx)
+
+			// Assert
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_host_time_zone_divergent_total [ALPHA] Number of ESXi hosts with time zone different from the most common time zone in their compute cluster.
+# TYPE vsphere_host_time_zone_divergent_total gauge
+vsphere_host_time_zone_divergent_total{vcenter="dc0"} %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_host_time_zone_divergent_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
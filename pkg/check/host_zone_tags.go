@@ -0,0 +1,169 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	hostZoneTagConflictsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_host_zone_tag_conflicts_total",
+			Help:           "Number of ESXi hosts in compute clusters with node VMs that have zone tags conflicting with the zone of their compute cluster or datacenter.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(hostZoneTagConflictsMetric)
+}
+
+// CheckClusterHostAffinityForZoneTags checks that zone tags of ESXi hosts in compute clusters that run
+// node VMs match the zone of the compute cluster. The zone of a compute cluster is its tag in the zone
+// tag category from vSphere configuration, or tag of its datacenter when the cluster is not tagged.
+// A host with a different zone tag, or with multiple zone tags, confuses topology aware scheduling.
+func CheckClusterHostAffinityForZoneTags(ctx *CheckContext) error {
+	zoneCategory := ctx.VMConfig.Labels.Zone
+	if zoneCategory == "" {
+		klog.V(4).Infof("CheckClusterHostAffinityForZoneTags: zone tag category is not configured, skipping")
+		hostZoneTagConflictsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+	if ctx.TagManager == nil {
+		return fmt.Errorf("cannot check zone tags in category %s: vSphere tags are not available", zoneCategory)
+	}
+
+	zoneTags, err := getCategoryTags(ctx, zoneCategory)
+	if err != nil {
+		return err
+	}
+
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	conflicts := 0
+	for _, clusterRef := range clusterRefs {
+		clusterName, hosts, err := getComputeClusterHosts(ctx, clusterRef, []string{"name"})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dcRef, err := getParentDatacenter(ctx, clusterRef)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		refs := []mo.Reference{clusterRef, dcRef}
+		for _, host := range hosts {
+			refs = append(refs, host.Reference())
+		}
+		objectZones, err := getAttachedZones(ctx, refs, zoneTags)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		expectedZones := objectZones[clusterRef]
+		if len(expectedZones) == 0 {
+			expectedZones = objectZones[dcRef]
+		}
+		if len(expectedZones) > 1 {
+			errs = append(errs, fmt.Errorf("compute cluster %s has multiple zone tags in category %s: %s", clusterName, zoneCategory, strings.Join(expectedZones, ", ")))
+			continue
+		}
+
+		for _, host := range hosts {
+			hostZones := objectZones[host.Reference()]
+			if len(hostZones) == 0 {
+				continue
+			}
+			switch {
+			case len(hostZones) > 1:
+				errs = append(errs, fmt.Errorf("host %s in compute cluster %s has multiple zone tags in category %s: %s", host.Name, clusterName, zoneCategory, strings.Join(hostZones, ", ")))
+			case len(expectedZones) == 1 && hostZones[0] != expectedZones[0]:
+				errs = append(errs, fmt.Errorf("host %s in compute cluster %s has zone tag %s in category %s, but the compute cluster is in zone %s", host.Name, clusterName, hostZones[0], zoneCategory, expectedZones[0]))
+			default:
+				klog.V(4).Infof("Host %s in compute cluster %s has zone tag %s", host.Name, clusterName, hostZones[0])
+				continue
+			}
+			conflicts++
+		}
+	}
+	hostZoneTagConflictsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(conflicts))
+
+	klog.V(2).Infof("CheckClusterHostAffinityForZoneTags checked %d compute clusters, %d hosts with conflicting zone tags", len(clusterRefs), conflicts)
+	return JoinErrors(errs)
+}
+
+// getCategoryTags returns map tag ID -> tag name of all tags in the given tag category.
+func getCategoryTags(ctx *CheckContext, category string) (map[string]string, error) {
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	tags, err := ctx.TagManager.GetTagsForCategory(tctx, category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags in category %s: %s", category, err)
+	}
+	tagNames := make(map[string]string)
+	for _, tag := range tags {
+		tagNames[tag.ID] = tag.Name
+	}
+	return tagNames, nil
+}
+
+// getAttachedZones returns map object -> sorted names of tags from zoneTags attached to the object.
+// Objects without any zone tag are not present in the map.
+func getAttachedZones(ctx *CheckContext, refs []mo.Reference, zoneTags map[string]string) (map[vim.ManagedObjectReference][]string, error) {
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	attached, err := ctx.TagManager.ListAttachedTagsOnObjects(tctx, refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attached tags: %s", err)
+	}
+
+	zones := make(map[vim.ManagedObjectReference][]string)
+	for _, object := range attached {
+		ref := object.ObjectID.Reference()
+		for _, tagID := range object.TagIDs {
+			if name, found := zoneTags[tagID]; found {
+				zones[ref] = append(zones[ref], name)
+			}
+		}
+		sort.Strings(zones[ref])
+	}
+	return zones, nil
+}
+
+// getParentDatacenter returns reference to the datacenter that contains the given object.
+func getParentDatacenter(ctx *CheckContext, ref vim.ManagedObjectReference) (vim.ManagedObjectReference, error) {
+	pc := property.DefaultCollector(ctx.VMClient)
+	current := ref
+	for current.Type != dataCenterType {
+		var entity mo.ManagedEntity
+		tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+		err := pc.RetrieveOne(tctx, current, []string{"parent"}, &entity)
+		cancel()
+		if err != nil {
+			return vim.ManagedObjectReference{}, fmt.Errorf("failed to get parent of %s: %s", current.Value, err)
+		}
+		if entity.Parent == nil {
+			return vim.ManagedObjectReference{}, fmt.Errorf("failed to find datacenter of %s", ref.Value)
+		}
+		current = *entity.Parent
+	}
+	return current, nil
+}
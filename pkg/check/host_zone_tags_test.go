@@ -0,0 +1,235 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckClusterHostAffinityForZoneTags(t *testing.T) {
+	tests := []struct {
+		name            string
+		zoneCategory    string
+		clusterZones    []string
+		datacenterZones []string
+		// host name -> zone tags
+		hostZones       map[string][]string
+		expectedError   string
+		expectedMetrics string
+	}{
+		{
+			name:         "zones not configured",
+			zoneCategory: "",
+			hostZones: map[string][]string{
+				"DC0_C0_H0": {"zone-a", "zone-b"},
+			},
+			expectedMetrics: `
+# HELP vsphere_host_zone_tag_conflicts_total [ALPHA] Number of ESXi hosts in compute clusters with node VMs that have zone tags conflicting with the zone of their compute cluster or datacenter.
+# TYPE vsphere_host_zone_tag_conflicts_total gauge
+vsphere_host_zone_tag_conflicts_total{vcenter="dc0"} 0
+`,
+		},
+		{
+			name:         "no zone tags",
+			zoneCategory: "k8s-zone",
+			expectedMetrics: `
+# HELP vsphere_host_zone_tag_conflicts_total [ALPHA] Number of ESXi hosts in compute clusters with node VMs that have zone tags conflicting with the zone of their compute cluster or datacenter.
+# TYPE vsphere_host_zone_tag_conflicts_total gauge
+vsphere_host_zone_tag_conflicts_total{vcenter="dc0"} 0
+`,
+		},
+		{
+			name:         "hosts match cluster zone",
+			zoneCategory: "k8s-zone",
+			clusterZones: []string{"zone-a"},
+			hostZones: map[string][]string{
+				"DC0_C0_H0": {"zone-a"},
+				"DC0_C0_H1": {"zone-a"},
+			},
+			expectedMetrics: `
+# HELP vsphere_host_zone_tag_conflicts_total [ALPHA] Number of ESXi hosts in compute clusters with node VMs that have zone tags conflicting with the zone of their compute cluster or datacenter.
+# TYPE vsphere_host_zone_tag_conflicts_total gauge
+vsphere_host_zone_tag_conflicts_total{vcenter="dc0"} 0
+`,
+		},
+		{
+			name:            "host does not match cluster zone",
+			zoneCategory:    "k8s-zone",
+			clusterZones:    []string{"zone-a"},
+			datacenterZones: []string{"zone-b"},
+			hostZones: map[string][]string{
+				"DC0_C0_H0": {"zone-a"},
+				"DC0_C0_H1": {"zone-b"},
+			},
+			expectedError: "host DC0_C0_H1 in compute cluster DC0_C0 has zone tag zone-b in category k8s-zone, but the compute cluster is in zone zone-a",
+			expectedMetrics: `
+# HELP vsphere_host_zone_tag_conflicts_total [ALPHA] Number of ESXi hosts in compute clusters with node VMs that have zone tags conflicting with the zone of their compute cluster or datacenter.
+# TYPE vsphere_host_zone_tag_conflicts_total gauge
+vsphere_host_zone_tag_conflicts_total{vcenter="dc0"} 1
+`,
+		},
+		{
+			name:            "host does not match datacenter zone",
+			zoneCategory:    "k8s-zone",
+			datacenterZones: []string{"zone-a"},
+			hostZones: map[string][]string{
+				"DC0_C0_H2": {"zone-b"},
+			},
+			expectedError: "host DC0_C0_H2 in compute cluster DC0_C0 has zone tag zone-b in category k8s-zone, but the compute cluster is in zone zone-a",
+			expectedMetrics: `
+# HELP vsphere_host_zone_tag_conflicts_total [ALPHA] Number of ESXi hosts in compute clusters with node VMs that have zone tags conflicting with the zone of their compute cluster or datacenter.
+# TYPE vsphere_host_zone_tag_conflicts_total gauge
+vsphere_host_zone_tag_conflicts_total{vcenter="dc0"} 1
+`,
+		},
+		{
+			name:         "host with multiple zones",
+			zoneCategory: "k8s-zone",
+			hostZones: map[string][]string{
+				"DC0_C0_H0": {"zone-b", "zone-a"},
+				"DC0_C0_H1": {"zone-a", "zone-b"},
+			},
+			expectedError: "host DC0_C0_H0 in compute cluster DC0_C0 has multiple zone tags in category k8s-zone: zone-a, zone-b;\n" +
+				"host DC0_C0_H1 in compute cluster DC0_C0 has multiple zone tags in category k8s-zone: zone-a, zone-b",
+			expectedMetrics: `
+# HELP vsphere_host_zone_tag_conflicts_total [ALPHA] Number of ESXi hosts in compute clusters with node VMs that have zone tags conflicting with the zone of their compute cluster or datacenter.
+# TYPE vsphere_host_zone_tag_conflicts_total gauge
+vsphere_host_zone_tag_conflicts_total{vcenter="dc0"} 2
+`,
+		},
+		{
+			name:         "cluster with multiple zones",
+			zoneCategory: "k8s-zone",
+			clusterZones: []string{"zone-a", "zone-b"},
+			hostZones: map[string][]string{
+				"DC0_C0_H0": {"zone-a"},
+			},
+			expectedError: "compute cluster DC0_C0 has multiple zone tags in category k8s-zone: zone-a, zone-b",
+			expectedMetrics: `
+# HELP vsphere_host_zone_tag_conflicts_total [ALPHA] Number of ESXi hosts in compute clusters with node VMs that have zone tags conflicting with the zone of their compute cluster or datacenter.
+# TYPE vsphere_host_zone_tag_conflicts_total gauge
+vsphere_host_zone_tag_conflicts_total{vcenter="dc0"} 0
+`,
+		},
+		{
+			name:         "tags in other category are ignored",
+			zoneCategory: "other-zone",
+			clusterZones: []string{"zone-a"},
+			hostZones: map[string][]string{
+				"DC0_C0_H0": {"zone-b"},
+			},
+			expectedMetrics: `
+# HELP vsphere_host_zone_tag_conflicts_total [ALPHA] Number of ESXi hosts in compute clusters with node VMs that have zone tags conflicting with the zone of their compute cluster or datacenter.
+# TYPE vsphere_host_zone_tag_conflicts_total gauge
+vsphere_host_zone_tag_conflicts_total{vcenter="dc0"} 0
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: clusterNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMConfig.Labels.Zone = test.zoneCategory
+
+			clusterRef := types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "clustercomputeresource-30"}
+			dcRef, err := getParentDatacenter(ctx, clusterRef)
+			if err != nil {
+				t.Fatalf("Failed to get datacenter: %s", err)
+			}
+			_, hosts, err := getComputeClusterHosts(ctx, clusterRef, []string{"name"})
+			if err != nil {
+				t.Fatalf("Failed to get hosts: %s", err)
+			}
+			tagIDs := createZoneTags(t, ctx, "k8s-zone", "zone-a", "zone-b")
+			createZoneTags(t, ctx, "other-zone", "zone-c")
+			attachZoneTags(t, ctx, tagIDs, clusterRef, test.clusterZones)
+			attachZoneTags(t, ctx, tagIDs, dcRef, test.datacenterZones)
+			for _, host := range hosts {
+				attachZoneTags(t, ctx, tagIDs, host.Reference(), test.hostZones[host.Name])
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckClusterHostAffinityForZoneTags(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_host_zone_tag_conflicts_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+func TestCheckClusterHostAffinityForZoneTagsNoTagManager(t *testing.T) {
+	kubeClient := &fakeKubeClient{
+		nodes: clusterNodes(),
+	}
+	ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+	ctx.VMConfig.Labels.Zone = "k8s-zone"
+	ctx.TagManager = nil
+
+	err = CheckClusterHostAffinityForZoneTags(ctx)
+
+	expectedError := "cannot check zone tags in category k8s-zone: vSphere tags are not available"
+	if err == nil {
+		t.Errorf("Expected error %q, got none", expectedError)
+	} else if err.Error() != expectedError {
+		t.Errorf("Expected error %q, got %q", expectedError, err.Error())
+	}
+}
+
+// createZoneTags creates a tag category with given tags and returns map tag name -> tag ID.
+func createZoneTags(t *testing.T, ctx *CheckContext, category string, names ...string) map[string]string {
+	categoryID, err := ctx.TagManager.CreateCategory(ctx.Context, &tags.Category{Name: category, Cardinality: "MULTIPLE"})
+	if err != nil {
+		t.Fatalf("Failed to create category %s: %s", category, err)
+	}
+	tagIDs := make(map[string]string)
+	for _, name := range names {
+		id, err := ctx.TagManager.CreateTag(ctx.Context, &tags.Tag{Name: name, CategoryID: categoryID})
+		if err != nil {
+			t.Fatalf("Failed to create tag %s: %s", name, err)
+		}
+		tagIDs[name] = id
+	}
+	return tagIDs
+}
+
+func attachZoneTags(t *testing.T, ctx *CheckContext, tagIDs map[string]string, ref mo.Reference, names []string) {
+	for _, name := range names {
+		if err := ctx.TagManager.AttachTag(ctx.Context, tagIDs[name], ref); err != nil {
+			t.Fatalf("Failed to attach tag %s to %s: %s", name, ref.Reference().Value, err)
+		}
+	}
+}
@@ -39,5 +39,6 @@ func collectVCenterInfo(ctx *CheckContext) {
 	apiVersion := ctx.VMClient.ServiceContent.About.ApiVersion
 	uuid := ctx.VMClient.ServiceContent.About.InstanceUuid
 	ctx.ClusterInfo.SetVCenterVersion(version, apiVersion)
+	ctx.ClusterInfo.SetVCenterBuild(ctx.VMClient.ServiceContent.About.Build)
 	vCenterInfoMetric.WithLabelValues(version, apiVersion, uuid).Set(1.0)
 }
@@ -0,0 +1,171 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	checkLabel  = "check"
+	resultLabel = "result"
+
+	resultPass    = "pass"
+	resultFail    = "fail"
+	resultTimeout = "timeout"
+)
+
+// checkAbandonPeriod is how long runWithTimeout waits for a timed out check to return.
+var checkAbandonPeriod = time.Minute
+
+var (
+	checkDurationMetric = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Name:           "vsphere_check_duration_seconds",
+			Help:           "Duration of a single vSphere check in seconds. Node checks are measured for each node separately.",
+			Buckets:        []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60},
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{checkLabel},
+	)
+
+	checkResultMetric = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Name:           "vsphere_check_results_total",
+			Help:           "Number of vSphere check results, by check name and result (pass, fail or timeout). Node checks report a result for each node.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{checkLabel, resultLabel},
+	)
+
+	checkTimeoutMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_check_timeout_seconds",
+			Help:           "Timeout of a single vSphere check in seconds, 0 when the check has no timeout. Node checks time out on each node separately.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{checkLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(checkDurationMetric)
+	legacyregistry.MustRegister(checkResultMetric)
+	legacyregistry.MustRegister(checkTimeoutMetric)
+}
+
+// WithInstrumentation returns a ClusterCheck that runs the given check with its timeout (CheckTimeout,
+// unless overridden by check-timeouts), logs its start and result and records its duration and result
+// in metrics. A check that times out returns CheckTimeoutError.
+func WithInstrumentation(name string, check ClusterCheck) ClusterCheck {
+	return func(ctx *CheckContext) error {
+		klog.V(4).Infof("%s starting", name)
+		start := time.Now()
+		timeout := timeoutOf(name)
+		err := runWithTimeout(ctx, timeout, "", check)
+		recordCheckResult(name, time.Since(start), timeout, err)
+		if err != nil {
+			klog.V(2).Infof("%s failed: %s", name, err)
+		} else {
+			klog.V(2).Infof("%s passed", name)
+		}
+		return err
+	}
+}
+
+// WithNodeInstrumentation returns a NodeCheck that runs CheckNode of the given check with its timeout,
+// logs start and result of each CheckNode call and records its duration and result in metrics.
+func WithNodeInstrumentation(check NodeCheck) NodeCheck {
+	return &instrumentedNodeCheck{NodeCheck: check}
+}
+
+// instrumentedNodeCheck is a NodeCheck that instruments CheckNode of the embedded check.
+type instrumentedNodeCheck struct {
+	NodeCheck
+}
+
+var _ NodeCheck = &instrumentedNodeCheck{}
+
+func (c *instrumentedNodeCheck) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	name := c.Name()
+	klog.V(4).Infof("%s:%s starting", name, node.Name)
+	start := time.Now()
+	timeout := timeoutOf(name)
+	err := runWithTimeout(ctx, timeout, node.Name, func(ctx *CheckContext) error {
+		return c.NodeCheck.CheckNode(ctx, node, vm)
+	})
+	recordCheckResult(name, time.Since(start), timeout, err)
+	if err != nil {
+		klog.V(2).Infof("%s:%s failed: %s", name, node.Name, err)
+	} else {
+		klog.V(2).Infof("%s:%s passed", name, node.Name)
+	}
+	return err
+}
+
+// runWithTimeout runs the check with a copy of ctx whose Context expires after the timeout, the check
+// runs without any timeout when it is 0. It returns CheckTimeoutError when the check does not finish in time.
+// vSphere calls of the check get cancelled by the expired Context and runWithTimeout waits up to
+// checkAbandonPeriod for the check to return, so a timed out check does not outlive its round and does not
+// report results into FinishCheck or the next round. A check that ignores the Context is abandoned after
+// that period, so it does not block the worker forever.
+// A check that returns after its Context expired is reported as timed out too, its error is most likely
+// caused by the expiration.
+func runWithTimeout(ctx *CheckContext, timeout time.Duration, nodeName string, check ClusterCheck) error {
+	parent := ctx.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	checkContext := *ctx
+	if timeout <= 0 {
+		checkContext.Context = parent
+		return check(&checkContext)
+	}
+	tctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	checkContext.Context = tctx
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check(&checkContext)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && errors.Is(tctx.Err(), context.DeadlineExceeded) && parent.Err() == nil {
+			return &CheckTimeoutError{Node: nodeName, Timeout: timeout}
+		}
+		return err
+	case <-tctx.Done():
+		// Wait for the check to notice the expired Context, it should not run after the check is reported.
+		select {
+		case <-done:
+		case <-time.After(checkAbandonPeriod):
+			klog.Warningf("Check did not return %s after its timeout %s, abandoning it", checkAbandonPeriod, timeout)
+		}
+		if parent.Err() != nil {
+			// The whole round was cancelled, not just this check.
+			return parent.Err()
+		}
+		return &CheckTimeoutError{Node: nodeName, Timeout: timeout}
+	}
+}
+
+func recordCheckResult(name string, duration, timeout time.Duration, err error) {
+	checkDurationMetric.WithLabelValues(name).Observe(duration.Seconds())
+	checkTimeoutMetric.WithLabelValues(name).Set(timeout.Seconds())
+	result := resultPass
+	switch {
+	case IsCheckTimeout(err):
+		result = resultTimeout
+	case err != nil:
+		result = resultFail
+	}
+	checkResultMetric.WithLabelValues(name, result).Inc()
+}
@@ -0,0 +1,283 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// fakeNodeCheck is a NodeCheck that fails on nodes listed in failingNodes.
+type fakeNodeCheck struct {
+	failingNodes map[string]bool
+	started      bool
+	finished     bool
+}
+
+var _ NodeCheck = &fakeNodeCheck{}
+
+func (c *fakeNodeCheck) Name() string {
+	return "FakeNodeCheck"
+}
+
+func (c *fakeNodeCheck) StartCheck() error {
+	c.started = true
+	return nil
+}
+
+func (c *fakeNodeCheck) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if c.failingNodes[node.Name] {
+		return errors.New("fake error")
+	}
+	return nil
+}
+
+func (c *fakeNodeCheck) FinishCheck(ctx *CheckContext) {
+	c.finished = true
+}
+
+func TestWithInstrumentation(t *testing.T) {
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+	passing := WithInstrumentation("FakePassingCheck", func(ctx *CheckContext) error { return nil })
+	failing := WithInstrumentation("FakeFailingCheck", func(ctx *CheckContext) error { return errors.New("fake error") })
+
+	if err := passing(&CheckContext{}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := passing(&CheckContext{}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := failing(&CheckContext{}); err == nil || err.Error() != "fake error" {
+		t.Errorf("Expected the check error, got %v", err)
+	}
+
+	expectedMetrics := `
+# HELP vsphere_check_results_total [ALPHA] Number of vSphere check results, by check name and result (pass, fail or timeout). Node checks report a result for each node.
+# TYPE vsphere_check_results_total counter
+vsphere_check_results_total{check="FakeFailingCheck",result="fail"} 1
+vsphere_check_results_total{check="FakePassingCheck",result="pass"} 2
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_check_results_total"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+	if count, err := testutil.GatherAndCount(legacyregistry.DefaultGatherer, "vsphere_check_duration_seconds"); err != nil || count != 2 {
+		t.Errorf("Expected duration of 2 checks, got %d: %v", count, err)
+	}
+}
+
+func TestWithNodeInstrumentation(t *testing.T) {
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+	fake := &fakeNodeCheck{failingNodes: map[string]bool{"node2": true}}
+	check := WithNodeInstrumentation(fake)
+
+	if check.Name() != "FakeNodeCheck" {
+		t.Errorf("Expected name FakeNodeCheck, got %s", check.Name())
+	}
+	check.StartCheck()
+	if err := check.CheckNode(&CheckContext{}, node("node1"), &mo.VirtualMachine{}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := check.CheckNode(&CheckContext{}, node("node2"), &mo.VirtualMachine{}); err == nil {
+		t.Errorf("Expected error, got none")
+	}
+	check.FinishCheck(&CheckContext{})
+	if !fake.started || !fake.finished {
+		t.Errorf("Expected StartCheck and FinishCheck to be called on the wrapped check")
+	}
+
+	expectedMetrics := `
+# HELP vsphere_check_results_total [ALPHA] Number of vSphere check results, by check name and result (pass, fail or timeout). Node checks report a result for each node.
+# TYPE vsphere_check_results_total counter
+vsphere_check_results_total{check="FakeNodeCheck",result="fail"} 1
+vsphere_check_results_total{check="FakeNodeCheck",result="pass"} 1
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_check_results_total"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+}
+
+func TestWithInstrumentationTimeout(t *testing.T) {
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+	oldTimeout := *CheckTimeout
+	*CheckTimeout = 50 * time.Millisecond
+	defer func() { *CheckTimeout = oldTimeout }()
+
+	// slow returns a while after its Context expired, like a vSphere call that is slow to cancel.
+	slowReturned := false
+	slow := WithInstrumentation("FakeSlowCheck", func(ctx *CheckContext) error {
+		<-ctx.Context.Done()
+		time.Sleep(50 * time.Millisecond)
+		slowReturned = true
+		return nil
+	})
+	// cancelled returns the Context error, like a vSphere call cancelled by the timeout.
+	cancelled := WithInstrumentation("FakeCancelledCheck", func(ctx *CheckContext) error {
+		<-ctx.Context.Done()
+		return ctx.Context.Err()
+	})
+	failing := WithInstrumentation("FakeFailingCheck", func(ctx *CheckContext) error { return errors.New("fake error") })
+
+	err := slow(&CheckContext{})
+	if !IsCheckTimeout(err) {
+		t.Errorf("Expected timeout error, got %v", err)
+	} else if err.Error() != "check timed out after 50ms" {
+		t.Errorf("Unexpected timeout error: %s", err)
+	}
+	if !slowReturned {
+		t.Errorf("Expected the timed out check to return before its result is reported")
+	}
+	if err := cancelled(&CheckContext{Context: context.Background()}); !IsCheckTimeout(err) {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+	if err := failing(&CheckContext{}); err == nil || IsCheckTimeout(err) {
+		t.Errorf("Expected the check error, got %v", err)
+	}
+
+	// A cancelled round is not a timeout of the check
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cancelled(&CheckContext{Context: ctx}); err == nil || IsCheckTimeout(err) {
+		t.Errorf("Expected context error, got %v", err)
+	}
+
+	expectedMetrics := `
+# HELP vsphere_check_results_total [ALPHA] Number of vSphere check results, by check name and result (pass, fail or timeout). Node checks report a result for each node.
+# TYPE vsphere_check_results_total counter
+vsphere_check_results_total{check="FakeCancelledCheck",result="fail"} 1
+vsphere_check_results_total{check="FakeCancelledCheck",result="timeout"} 1
+vsphere_check_results_total{check="FakeFailingCheck",result="fail"} 1
+vsphere_check_results_total{check="FakeSlowCheck",result="timeout"} 1
+# HELP vsphere_check_timeout_seconds [ALPHA] Timeout of a single vSphere check in seconds, 0 when the check has no timeout. Node checks time out on each node separately.
+# TYPE vsphere_check_timeout_seconds gauge
+vsphere_check_timeout_seconds{check="FakeCancelledCheck"} 0.05
+vsphere_check_timeout_seconds{check="FakeFailingCheck"} 0.05
+vsphere_check_timeout_seconds{check="FakeSlowCheck"} 0.05
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_check_results_total", "vsphere_check_timeout_seconds"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+}
+
+func TestWithNodeInstrumentationTimeout(t *testing.T) {
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+	oldTimeout := *CheckTimeout
+	*CheckTimeout = 50 * time.Millisecond
+	defer func() { *CheckTimeout = oldTimeout }()
+
+	slow := &slowNodeCheck{delay: 50 * time.Millisecond}
+	check := WithNodeInstrumentation(slow)
+
+	check.StartCheck()
+	err := check.CheckNode(&CheckContext{}, node("node1"), &mo.VirtualMachine{})
+	if !IsCheckTimeout(err) {
+		t.Errorf("Expected timeout error, got %v", err)
+	} else if err.Error() != "node node1: check timed out after 50ms" {
+		t.Errorf("Unexpected timeout error: %s", err)
+	}
+	// The slow CheckNode must not run into FinishCheck and the next round.
+	if slow.running {
+		t.Errorf("Expected CheckNode to return before its timeout is reported")
+	}
+	check.FinishCheck(&CheckContext{})
+	if slow.checkedAfterFinish {
+		t.Errorf("Expected CheckNode to finish before FinishCheck")
+	}
+}
+
+func TestWithInstrumentationCheckTimeouts(t *testing.T) {
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+	oldTimeout := *CheckTimeout
+	*CheckTimeout = 50 * time.Millisecond
+	defer func() { *CheckTimeout = oldTimeout }()
+	checkTimeouts["FakeLongCheck"] = 0
+	checkTimeouts["FakeShortCheck"] = 10 * time.Millisecond
+	defer func() {
+		delete(checkTimeouts, "FakeLongCheck")
+		delete(checkTimeouts, "FakeShortCheck")
+	}()
+
+	// sleep returns after 100ms or when its Context expires.
+	sleep := func(ctx *CheckContext) error {
+		select {
+		case <-time.After(100 * time.Millisecond):
+			return nil
+		case <-ctx.Context.Done():
+			return ctx.Context.Err()
+		}
+	}
+	long := WithInstrumentation("FakeLongCheck", sleep)
+	short := WithInstrumentation("FakeShortCheck", sleep)
+
+	if err := long(&CheckContext{}); err != nil {
+		t.Errorf("Expected check without timeout to pass, got %v", err)
+	}
+	err := short(&CheckContext{})
+	if !IsCheckTimeout(err) {
+		t.Errorf("Expected timeout error, got %v", err)
+	} else if err.Error() != "check timed out after 10ms" {
+		t.Errorf("Unexpected timeout error: %s", err)
+	}
+
+	expectedMetrics := `
+# HELP vsphere_check_timeout_seconds [ALPHA] Timeout of a single vSphere check in seconds, 0 when the check has no timeout. Node checks time out on each node separately.
+# TYPE vsphere_check_timeout_seconds gauge
+vsphere_check_timeout_seconds{check="FakeLongCheck"} 0
+vsphere_check_timeout_seconds{check="FakeShortCheck"} 0.01
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_check_timeout_seconds"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+}
+
+func TestWithInstrumentationAbandonedCheck(t *testing.T) {
+	oldTimeout := *CheckTimeout
+	*CheckTimeout = 10 * time.Millisecond
+	oldAbandonPeriod := checkAbandonPeriod
+	checkAbandonPeriod = 50 * time.Millisecond
+	defer func() {
+		*CheckTimeout = oldTimeout
+		checkAbandonPeriod = oldAbandonPeriod
+	}()
+
+	// stuck ignores its Context, like a check blocked outside of vSphere calls.
+	release := make(chan struct{})
+	defer close(release)
+	stuck := WithInstrumentation("FakeStuckCheck", func(ctx *CheckContext) error {
+		<-release
+		return nil
+	})
+
+	if err := stuck(&CheckContext{}); !IsCheckTimeout(err) {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+}
+
+// slowNodeCheck is a NodeCheck whose CheckNode returns the delay after its Context expired.
+type slowNodeCheck struct {
+	fakeNodeCheck
+	delay              time.Duration
+	running            bool
+	checkedAfterFinish bool
+}
+
+func (c *slowNodeCheck) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	c.running = true
+	defer func() { c.running = false }()
+	<-ctx.Context.Done()
+	time.Sleep(c.delay)
+	if c.finished {
+		c.checkedAfterFinish = true
+	}
+	return ctx.Context.Err()
+}
@@ -7,6 +7,7 @@ import (
 
 	ocpv1 "github.com/openshift/api/config/v1"
 	"github.com/openshift/vsphere-problem-detector/pkg/util"
+	"github.com/vmware/govmomi/vapi/tags"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
 	v1 "k8s.io/api/core/v1"
@@ -17,16 +18,77 @@ import (
 var (
 	// Make the vSphere call timeout configurable.
 	Timeout = flag.Duration("vmware-timeout", 5*time.Minute, "Timeout of all VMware calls")
+	// CheckTimeout limits duration of a single check, so a hung vSphere call does not block other checks.
+	// It is off by default, cluster checks of large clusters make many vSphere calls, each limited by Timeout.
+	CheckTimeout = flag.Duration("check-timeout", 0, "Timeout of a single check, 0 for no timeout. Node checks time out on each node separately. A check that does not finish in time is reported as timed out. Use check-timeouts to override it for individual checks.")
+	// MinHardwareVersion is the minimum hardware version of node VMs, i.e. N in vmx-N.
+	MinHardwareVersion = flag.Int("min-hardware-version", 15, "Minimum hardware version of node VMs required by the vSphere CSI driver, e.g. 15 for vmx-15. Nodes with older hardware version fail CollectNodeHWVersion and block upgrade.")
 
 	// DefaultClusterChecks is the list of all checks.
 	DefaultClusterChecks map[string]ClusterCheck = map[string]ClusterCheck{
-		"CheckTaskPermissions":    CheckTaskPermissions,
-		"ClusterInfo":             CollectClusterInfo,
-		"CheckFolderPermissions":  CheckFolderPermissions,
-		"CheckDefaultDatastore":   CheckDefaultDatastore,
-		"CheckStorageClasses":     CheckStorageClasses,
-		"CountRWXVolumes":         CountRWXVolumes,
-		"CheckAccountPermissions": CheckAccountPermissions,
+		"CheckTaskPermissions":                                CheckTaskPermissions,
+		"ClusterInfo":                                         CollectClusterInfo,
+		"CheckFolderPermissions":                              CheckFolderPermissions,
+		"CheckDefaultDatastore":                               CheckDefaultDatastore,
+		"CheckStorageClasses":                                 CheckStorageClasses,
+		"CountRWXVolumes":                                     CountRWXVolumes,
+		"CheckAccountPermissions":                             CheckAccountPermissions,
+		"CheckDatastoreClusterAntiAffinityForReplicas":        CheckDatastoreClusterAntiAffinityForReplicas,
+		"CheckHostCPUFeatureConsistency":                      CheckHostCPUFeatureConsistency,
+		"CheckDatastoreUnmapSupport":                          CheckDatastoreUnmapSupport,
+		"CheckClusterProactiveHAEnabled":                      CheckClusterProactiveHAEnabled,
+		"CheckVCenterAPIRateLimitHeadroom":                    CheckVCenterAPIRateLimitHeadroom,
+		"CheckDatastoreReplicationPairingForStretched":        CheckDatastoreReplicationPairingForStretched,
+		"CheckClusterVMComponentProtectionEnabled":            CheckClusterVMComponentProtectionEnabled,
+		"CheckHostTimeZoneConsistency":                        CheckHostTimeZoneConsistency,
+		"CheckDatastoreMountPathConsistencyAcrossHosts":       CheckDatastoreMountPathConsistencyAcrossHosts,
+		"CheckClusterDPMEnabled":                              CheckClusterDPMEnabled,
+		"CheckDatastoreBlockSizeForLargeVolumes":              CheckDatastoreBlockSizeForLargeVolumes,
+		"CheckVCenterDeprecatedAPIUsage":                      CheckVCenterDeprecatedAPIUsage,
+		"CheckClusterNetworkIOControlEnabled":                 CheckClusterNetworkIOControlEnabled,
+		"CheckDatastoreClusterAffinityRuleForVMHome":          CheckDatastoreClusterAffinityRuleForVMHome,
+		"CheckClusterAdmissionControlPolicyType":              CheckClusterAdmissionControlPolicyType,
+		"CheckVCenterPluginHealth":                            CheckVCenterPluginHealth,
+		"CheckClusterResourcePoolSharesFairness":              CheckClusterResourcePoolSharesFairness,
+		"CheckClusterOverlappingDatastoreHeartbeatSelection":  CheckClusterOverlappingDatastoreHeartbeatSelection,
+		"CheckDatastoreSnapshotSpaceReservation":              CheckDatastoreSnapshotSpaceReservation,
+		"CheckClusterVsanDiskGroupHealth":                     CheckClusterVsanDiskGroupHealth,
+		"CheckDatastoreCapacityVsVsanSlackSpace":              CheckDatastoreCapacityVsVsanSlackSpace,
+		"CheckClusterHostAffinityForZoneTags":                 CheckClusterHostAffinityForZoneTags,
+		"CheckDatastoreFreeSpace":                             CheckDatastoreFreeSpace,
+		"CheckVCenterServiceContentCapabilities":              CheckVCenterServiceContentCapabilities,
+		"CheckVSphereVersionsSupported":                       CheckVSphereVersionsSupported,
+		"CheckClusterStoragePolicyAppliesToAllNodeDatastores": CheckClusterStoragePolicyAppliesToAllNodeDatastores,
+		"CheckDatastoreVMFSHeapExhaustionRisk":                CheckDatastoreVMFSHeapExhaustionRisk,
+		"CheckClusterVsanObjectComplianceForNodeVMs":          CheckClusterVsanObjectComplianceForNodeVMs,
+		"CheckClusterComputeResourceNameUniqueness":           CheckClusterComputeResourceNameUniqueness,
+		"CheckDatastoreAccessibleFromFailureDomainHostsOnly":  CheckDatastoreAccessibleFromFailureDomainHostsOnly,
+		"CheckClusterDatastoreConnectivityMatrix":             CheckClusterDatastoreConnectivityMatrix,
+		"CheckVCenterPasswordExpiryForServiceAccount":         CheckVCenterPasswordExpiryForServiceAccount,
+		"CheckUserPermissions":                                CheckUserPermissions,
+		"CheckZonalTopologyTags":                              CheckZonalTopologyTags,
+		"CheckOrphanedVolumes":                                CheckOrphanedVolumes,
+		"CheckVCenterConnectivity":                            CheckVCenterConnectivity,
+		"CheckWorkspaceInventoryPaths":                        CheckWorkspaceInventoryPaths,
+		"CheckCredentials":                                    CheckCredentials,
+	}
+	// WorkspaceClusterChecks are cluster checks that run only against the Workspace vCenter. They check objects
+	// from the Workspace section of vSphere configuration, store facts of the Workspace vCenter in ClusterInfo,
+	// use only the Kubernetes API or check all vCenters in VMClients themselves. The other cluster checks run
+	// against each connected vCenter and report their metrics with the vcenter label, see resetVCenterMetric.
+	WorkspaceClusterChecks = map[string]bool{
+		"ClusterInfo":                   true,
+		"CheckFolderPermissions":        true,
+		"CheckDefaultDatastore":         true,
+		"CheckStorageClasses":           true,
+		"CountRWXVolumes":               true,
+		"CheckAccountPermissions":       true,
+		"CheckDatastoreFreeSpace":       true,
+		"CheckVSphereVersionsSupported": true,
+		"CheckOrphanedVolumes":          true,
+		"CheckVCenterConnectivity":      true,
+		"CheckWorkspaceInventoryPaths":  true,
+		"CheckCredentials":              true,
 	}
 	DefaultNodeChecks []NodeCheck = []NodeCheck{
 		&CheckNodeDiskUUID{},
@@ -40,11 +102,73 @@ var (
 		&CheckNodeDiskPerf{},
 		&CheckComputeClusterPermissions{},
 		&CheckResourcePoolPermissions{},
+		&CheckNodeVMFaultToleranceState{},
+		&CheckNodeVMToolsUpgradeStatusStuck{},
+		&CheckNodeVMBootDeviceOrder{},
+		&CheckNodeVMToolsGuestOpsEnabled{},
+		&CheckNodeVMToolsUnattendedShutdownCapability{},
+		&CheckNodeVMDiskFragmentationAcrossDatastores{},
+		&CheckNodeVMGuestNetConnectivityFlags{},
+		&CheckNodeVMToolsScriptsLeftEnabled{},
+		&CheckNodeVMDiskSizeVsPVCSize{},
+		&CheckNodeVMToolsGuestInfoStale{},
+		&CheckNodeVMvGPUProfileConsistency{},
+		&CheckNodeVMToolsGuestFamilyMatch{},
+		&CheckNodeVMConnectedDevicesBlockingVMotion{},
+		&CheckNodeVMDiskIndependentOfSnapshotChain{},
+		&CheckNodeVMToolsSharedFolders{},
+		&CheckNodeVMMaxMksConnections{},
+		&CheckNodeVMDiskAllSameDatastoreAsHome{},
+		&CheckNodeVMToolsMemoryBalloonDisabled{},
+		&CheckNodeVMPMemUsage{},
+		&CheckNodeVMWWNConsistencyForNPIV{},
+		&CheckNodeVMToolsVersionBelowHostMinimum{},
+		&CheckNodeVMToolsUpgradeRequiredForHWVersion{},
+		&CheckNodeVMToolsInstallerMountedBlockingEject{},
+		&CheckNodeVMHardwareVersionVsVCenterMaxSupported{},
+		&CheckNodeVMDiskBackingParentChainDepth{},
+		&CheckNodeVMMemorySizeAlignment{},
+		&CheckNodeVMToolsOperationsRateLimited{},
+		&CheckNodeVMResourcePoolParentIsCluster{},
+		&CheckNodeVMToolsGuestSshKeyInjectionDisabled{},
+		&CheckNodeVMDiskKeyStabilityForCSI{},
+		&CheckNodeVMToolsGuestRebootPending{},
+		&CheckNodeVMConfigFileDatastoreMatchesExpectedZone{},
+		&CheckNodeVMToolsOperationTimeoutHistory{},
+		&CheckNodeVMHostAvailable{},
 	}
 
 	// NodeProperties is a list of properties that NodeCheck can rely on to be pre-filled.
-	// Add a property to this list when a NodeCheck uses it.
-	NodeProperties = []string{"config.extraConfig", "config.flags", "config.version", "runtime.host"}
+	// Add a property to this list when a NodeCheck uses it, or when a ClusterCheck reads it
+	// from node VMs, so getNodeVMs does not retrieve the VMs again.
+	NodeProperties = []string{
+		"config.extraConfig",
+		"config.flags",
+		"config.version",
+		"runtime.host",
+		"runtime.faultToleranceState",
+		"recentTask",
+		"config.bootOptions",
+		"guest.toolsRunningStatus",
+		"guest.guestOperationsReady",
+		"guest.guestStateChangeSupported",
+		"config.hardware.device",
+		"guest.net",
+		"guest.disk",
+		"guest.guestFamily",
+		"config.tools",
+		"config.files",
+		"guest.toolsVersion",
+		"guest.toolsVersionStatus2",
+		"runtime.toolsInstallerMounted",
+		"config.hardware.memoryMB",
+		"resourcePool",
+		"guest.guestState",
+		"name",
+		"parent",
+		"datastore",
+		"network",
+	}
 )
 
 // KubeClient is an interface between individual vSphere check and Kubernetes.
@@ -57,6 +181,10 @@ type KubeClient interface {
 	ListStorageClasses(ctx context.Context) ([]*storagev1.StorageClass, error)
 	// ListPVs returns list of all PVs in the cluster.
 	ListPVs(ctx context.Context) ([]*v1.PersistentVolume, error)
+	// ListPVCs returns list of all PVCs in the cluster.
+	ListPVCs(ctx context.Context) ([]*v1.PersistentVolumeClaim, error)
+	// ListVolumeAttachments returns list of all VolumeAttachments in the cluster.
+	ListVolumeAttachments(ctx context.Context) ([]*storagev1.VolumeAttachment, error)
 }
 
 type CheckContext struct {
@@ -67,6 +195,25 @@ type CheckContext struct {
 	AuthManager AuthManager
 	KubeClient  KubeClient
 	ClusterInfo *util.ClusterInfo
+	// VMClients holds connections to all vCenters from VMConfig that the detector is logged in to,
+	// vCenter name -> client. It includes VMClient, the connection to VMConfig.Workspace.VCenterIP.
+	VMClients map[string]*vim25.Client
+	// TagManager reads vSphere tags of the vCenter of VMClient. It is nil when the detector
+	// could not log in to the vCenter REST API.
+	TagManager *tags.Manager
+	// ResultStore holds results of previous rounds of checks.
+	ResultStore ResultStore
+	// ReportSink receives data of checks for the JSON report of the round. It may be nil.
+	ReportSink ReportSink
+	// VMCache holds node VMs of VMClient with NodeProperties retrieved in batches for the round,
+	// shared by node checks and cluster checks. It may be nil, VMs are then retrieved one by one.
+	VMCache *VMCache
+}
+
+// ReportSink collects data of checks that is not a check result, for the JSON report of the round.
+type ReportSink interface {
+	// AddDatastoreConnectivityMatrix stores accessibility of datastores on ESXi hosts.
+	AddDatastoreConnectivityMatrix(matrix *DatastoreConnectivityMatrix)
 }
 
 // Interface of a single vSphere cluster-level check. It gets connection to vSphere, vSphere config and connection to Kubernetes.
@@ -0,0 +1,128 @@
+package check
+
+import (
+	"strconv"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	buildLabel    = "build"
+	migratedLabel = "migrated"
+
+	// migratedToAnnotation is set by kube-controller-manager on in-tree PVs handled by a CSI driver.
+	migratedToAnnotation = "pv.kubernetes.io/migrated-to"
+)
+
+// Inventory metrics summarize the vSphere environment for telemetry, so old vCenter, ESXi and hardware
+// versions can be planned for deprecation. They're refreshed by CollectInventory after each round of checks.
+var (
+	inventoryVCenterMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_inventory_vcenter",
+			Help:           "Version of the Workspace vCenter found in the last round of checks. Value 1 means the vCenter has the version, API version and build.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{versionLabel, apiVersionLabel, buildLabel},
+	)
+
+	inventoryESXiHostsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_inventory_esxi_hosts",
+			Help:           "Number of ESXi hosts with given version found in the last round of checks.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{versionLabel, apiVersionLabel},
+	)
+
+	inventoryNodeHWVersionsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_inventory_node_hw_versions",
+			Help:           "Number of node VMs with given hardware version found in the last round of checks.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{hwVersionLabel},
+	)
+
+	inventoryDatastoresMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_inventory_datastores",
+			Help:           "Number of datastores with given type used by StorageClasses and the default datastore, found in the last round of checks.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{dataStoreType},
+	)
+
+	inventoryInTreeVolumesMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_inventory_in_tree_volumes",
+			Help:           "Number of in-tree vSphere PVs, by presence of the CSI migration annotation pv.kubernetes.io/migrated-to.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{migratedLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(inventoryVCenterMetric)
+	legacyregistry.MustRegister(inventoryESXiHostsMetric)
+	legacyregistry.MustRegister(inventoryNodeHWVersionsMetric)
+	legacyregistry.MustRegister(inventoryDatastoresMetric)
+	legacyregistry.MustRegister(inventoryInTreeVolumesMetric)
+}
+
+// CollectInventory updates inventory metrics from facts that checks stored in ClusterInfo during
+// the round of checks. It must be called when all checks are finished. Facts of disabled or skipped
+// checks are missing.
+// It's not a vSphere check per se, just like CollectClusterInfo.
+func CollectInventory(ctx *CheckContext) {
+	inventoryVCenterMetric.Reset()
+	version, apiVersion := ctx.ClusterInfo.GetVCenterVersion()
+	if version != "" {
+		inventoryVCenterMetric.WithLabelValues(version, apiVersion, ctx.ClusterInfo.GetVCenterBuild()).Set(1)
+	}
+
+	inventoryESXiHostsMetric.Reset()
+	hosts := 0
+	hostVersions := make(map[[2]string]int)
+	for _, v := range ctx.ClusterInfo.GetHostVersions() {
+		// Hosts that failed to load have no version.
+		if v.Version != "" {
+			hostVersions[[2]string{v.Version, v.APIVersion}]++
+			hosts++
+		}
+	}
+	for v, count := range hostVersions {
+		inventoryESXiHostsMetric.WithLabelValues(v[0], v[1]).Set(float64(count))
+	}
+
+	inventoryNodeHWVersionsMetric.Reset()
+	for hwVersion, count := range ctx.ClusterInfo.GetHardwareVersion() {
+		inventoryNodeHWVersionsMetric.WithLabelValues(hwVersion).Set(float64(count))
+	}
+
+	inventoryDatastoresMetric.Reset()
+	for dsType, count := range ctx.ClusterInfo.GetDatastoreTypes() {
+		inventoryDatastoresMetric.WithLabelValues(dsType).Set(float64(count))
+	}
+
+	pvs, err := ctx.KubeClient.ListPVs(ctx.Context)
+	if err != nil {
+		// Keep the volume counts of the previous round.
+		klog.Errorf("Failed to list PVs for inventory metrics: %s", err)
+		return
+	}
+	volumes := map[bool]int{false: 0, true: 0}
+	for _, pv := range pvs {
+		if pv.Spec.VsphereVolume == nil {
+			continue
+		}
+		volumes[pv.Annotations[migratedToAnnotation] == vSphereCSIDdriver]++
+	}
+	for migrated, count := range volumes {
+		inventoryInTreeVolumesMetric.WithLabelValues(strconv.FormatBool(migrated)).Set(float64(count))
+	}
+	klog.V(2).Infof("CollectInventory: %d ESXi hosts, %d in-tree volumes, %d of them migrated to CSI", hosts, volumes[false]+volumes[true], volumes[true])
+}
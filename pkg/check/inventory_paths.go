@@ -0,0 +1,108 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25"
+	"k8s.io/klog/v2"
+)
+
+// CheckWorkspaceInventoryPaths tests that datacenters of all connected vCenters and the folder, resource pool
+// and default datastore of the Workspace section of vSphere configuration exist. Objects renamed or moved
+// after installation break MachineSet scaling and volume provisioning with errors that do not name the object.
+// Relative paths are resolved in the Workspace datacenter, as the in-tree cloud provider does.
+func CheckWorkspaceInventoryPaths(ctx *CheckContext) error {
+	clients := ctx.VMClients
+	if len(clients) == 0 {
+		clients = map[string]*vim25.Client{ctx.VMConfig.Workspace.VCenterIP: ctx.VMClient}
+	}
+	vcContext := *ctx
+	vcContext.VMClients = clients
+
+	var errs []error
+	var workspaceDC *object.Datacenter
+	for _, vCenter := range vcContext.connectedVCenters() {
+		finder := find.NewFinder(clients[vCenter], false)
+		for _, dcName := range getVCenterDatacenters(ctx, vCenter) {
+			tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+			dc, err := finder.Datacenter(tctx, dcName)
+			cancel()
+			if err != nil {
+				errs = append(errs, describeInventoryPathError("datacenter", dcName, vCenter, err))
+				continue
+			}
+			if vCenter == ctx.VMConfig.Workspace.VCenterIP && dcName == ctx.VMConfig.Workspace.Datacenter {
+				workspaceDC = dc
+			}
+		}
+	}
+
+	if ctx.VMConfig.Workspace.Datacenter != "" && workspaceDC == nil {
+		// The missing datacenter is already reported, paths in it cannot be resolved.
+		return JoinErrors(errs)
+	}
+	finder := find.NewFinder(ctx.VMClient, false)
+	if workspaceDC != nil {
+		finder.SetDatacenter(workspaceDC)
+	}
+	paths := []struct {
+		kind string
+		path string
+		find func(context.Context, string) error
+	}{
+		{
+			kind: "folder",
+			path: ctx.VMConfig.Workspace.Folder,
+			find: func(ctx context.Context, path string) error {
+				_, err := finder.Folder(ctx, path)
+				return err
+			},
+		},
+		{
+			kind: "resource pool",
+			path: ctx.VMConfig.Workspace.ResourcePoolPath,
+			find: func(ctx context.Context, path string) error {
+				_, err := finder.ResourcePool(ctx, path)
+				return err
+			},
+		},
+		{
+			kind: "datastore",
+			path: ctx.VMConfig.Workspace.DefaultDatastore,
+			find: func(ctx context.Context, path string) error {
+				_, err := finder.Datastore(ctx, path)
+				return err
+			},
+		},
+	}
+	checked := 0
+	for _, p := range paths {
+		if p.path == "" {
+			continue
+		}
+		checked++
+		tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+		err := p.find(tctx, p.path)
+		cancel()
+		if err != nil {
+			errs = append(errs, describeInventoryPathError(p.kind, p.path, ctx.VMConfig.Workspace.VCenterIP, err))
+			continue
+		}
+		klog.V(4).Infof("CheckWorkspaceInventoryPaths: found %s %s", p.kind, p.path)
+	}
+	klog.V(2).Infof("CheckWorkspaceInventoryPaths checked %d Workspace paths, %d problems found", checked, len(errs))
+	return JoinErrors(errs)
+}
+
+// describeInventoryPathError returns error of a failed lookup of an object from vSphere configuration.
+func describeInventoryPathError(kind, path, vCenter string, err error) error {
+	var notFoundErr *find.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return fmt.Errorf("%s %s from vSphere configuration does not exist in vCenter %s, it may have been renamed or moved", kind, path, vCenter)
+	}
+	return fmt.Errorf("failed to look up %s %s in vCenter %s: %s", kind, path, vCenter, err)
+}
@@ -0,0 +1,108 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/vmware/govmomi/vim25"
+)
+
+func TestCheckWorkspaceInventoryPaths(t *testing.T) {
+	tests := []struct {
+		name             string
+		datacenter       string
+		datacenters      string
+		folder           string
+		resourcePoolPath string
+		defaultDatastore string
+		expectedError    string
+	}{
+		{
+			name:             "absolute paths",
+			datacenter:       "DC0",
+			datacenters:      "DC0",
+			folder:           "/DC0/vm",
+			resourcePoolPath: "/DC0/host/DC0_H0/Resources",
+			defaultDatastore: "LocalDS_0",
+		},
+		{
+			name:             "relative paths",
+			datacenter:       "DC0",
+			datacenters:      "DC0, DC1",
+			folder:           "vm",
+			resourcePoolPath: "DC0_C0/Resources",
+			defaultDatastore: "/DC0/datastore/LocalDS_0",
+		},
+		{
+			name:        "no optional paths",
+			datacenter:  "DC0",
+			datacenters: "DC0",
+		},
+		{
+			name:             "missing folder",
+			datacenter:       "DC0",
+			datacenters:      "DC0",
+			folder:           "/DC0/vm/renamed",
+			resourcePoolPath: "/DC0/host/DC0_H0/Resources",
+			defaultDatastore: "LocalDS_0",
+			expectedError:    "folder /DC0/vm/renamed from vSphere configuration does not exist in vCenter dc0, it may have been renamed or moved",
+		},
+		{
+			name:             "missing resource pool and datastore",
+			datacenter:       "DC0",
+			datacenters:      "DC0",
+			folder:           "/DC0/vm",
+			resourcePoolPath: "/DC0/host/DC0_C0/Resources/renamed",
+			defaultDatastore: "renamed",
+			expectedError: "resource pool /DC0/host/DC0_C0/Resources/renamed from vSphere configuration does not exist in vCenter dc0, it may have been renamed or moved;\n" +
+				"datastore renamed from vSphere configuration does not exist in vCenter dc0, it may have been renamed or moved",
+		},
+		{
+			name:             "missing datacenter",
+			datacenter:       "DC0",
+			datacenters:      "DC0, renamed",
+			folder:           "/DC0/vm",
+			resourcePoolPath: "/DC0/host/DC0_H0/Resources",
+			defaultDatastore: "LocalDS_0",
+			expectedError:    "datacenter renamed from vSphere configuration does not exist in vCenter dc0, it may have been renamed or moved",
+		},
+		{
+			name:             "missing Workspace datacenter",
+			datacenter:       "renamed",
+			datacenters:      "DC0",
+			folder:           "vm",
+			resourcePoolPath: "DC0_C0/Resources",
+			defaultDatastore: "LocalDS_0",
+			expectedError:    "datacenter renamed from vSphere configuration does not exist in vCenter dc0, it may have been renamed or moved",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMClients = map[string]*vim25.Client{"dc0": ctx.VMClient}
+			ctx.VMConfig.Workspace.Datacenter = test.datacenter
+			ctx.VMConfig.VirtualCenter["dc0"].Datacenters = test.datacenters
+			ctx.VMConfig.Workspace.Folder = test.folder
+			ctx.VMConfig.Workspace.ResourcePoolPath = test.resourcePoolPath
+			ctx.VMConfig.Workspace.DefaultDatastore = test.defaultDatastore
+
+			// Act
+			err = CheckWorkspaceInventoryPaths(ctx)
+
+			// Assert
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			if errString != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, errString)
+			}
+		})
+	}
+}
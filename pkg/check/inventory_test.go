@@ -0,0 +1,116 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCollectInventory(t *testing.T) {
+	inTreePV := func(name string, annotations map[string]string) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					VsphereVolume: &v1.VsphereVirtualDiskVolumeSource{VolumePath: "[LocalDS_0] kubevols/" + name + ".vmdk"},
+				},
+			},
+		}
+	}
+	csiPV := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "csi"},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: vSphereCSIDdriver, VolumeHandle: "0000"},
+			},
+		},
+	}
+
+	// Stage
+	kubeClient := &fakeKubeClient{
+		infrastructure: infrastructure(),
+		nodes:          defaultNodes(),
+		pvs: []*v1.PersistentVolume{
+			inTreePV("in-tree", nil),
+			inTreePV("migrated", map[string]string{migratedToAnnotation: vSphereCSIDdriver}),
+			csiPV,
+		},
+	}
+	ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+
+	// Facts collected by checks during the round.
+	if err := CollectClusterInfo(ctx); err != nil {
+		t.Fatalf("CollectClusterInfo failed: %s", err)
+	}
+	if err := checkForDatastoreCluster(ctx, "LocalDS_0", nil); err != nil {
+		t.Fatalf("checkForDatastoreCluster failed: %s", err)
+	}
+	ctx.ClusterInfo.SetHostVersion("host-1", "7.0.3", "7.0.3.0")
+	ctx.ClusterInfo.SetHostVersion("host-2", "7.0.3", "7.0.3.0")
+	ctx.ClusterInfo.SetHostVersion("host-3", "6.7.3", "6.7.3")
+	// A host that failed to load.
+	ctx.ClusterInfo.MarkHostForProcessing("host-4")
+	ctx.ClusterInfo.SetHardwareVersion("vmx-15")
+	ctx.ClusterInfo.SetHardwareVersion("vmx-15")
+	ctx.ClusterInfo.SetHardwareVersion("vmx-13")
+
+	// Act
+	CollectInventory(ctx)
+
+	// Assert
+	expectedMetrics := `
+# HELP vsphere_inventory_datastores [ALPHA] Number of datastores with given type used by StorageClasses and the default datastore, found in the last round of checks.
+# TYPE vsphere_inventory_datastores gauge
+vsphere_inventory_datastores{type="other"} 1
+# HELP vsphere_inventory_esxi_hosts [ALPHA] Number of ESXi hosts with given version found in the last round of checks.
+# TYPE vsphere_inventory_esxi_hosts gauge
+vsphere_inventory_esxi_hosts{api_version="6.7.3",version="6.7.3"} 1
+vsphere_inventory_esxi_hosts{api_version="7.0.3.0",version="7.0.3"} 2
+# HELP vsphere_inventory_in_tree_volumes [ALPHA] Number of in-tree vSphere PVs, by presence of the CSI migration annotation pv.kubernetes.io/migrated-to.
+# TYPE vsphere_inventory_in_tree_volumes gauge
+vsphere_inventory_in_tree_volumes{migrated="false"} 1
+vsphere_inventory_in_tree_volumes{migrated="true"} 1
+# HELP vsphere_inventory_node_hw_versions [ALPHA] Number of node VMs with given hardware version found in the last round of checks.
+# TYPE vsphere_inventory_node_hw_versions gauge
+vsphere_inventory_node_hw_versions{hw_version="vmx-13"} 1
+vsphere_inventory_node_hw_versions{hw_version="vmx-15"} 2
+# HELP vsphere_inventory_vcenter [ALPHA] Version of the Workspace vCenter found in the last round of checks. Value 1 means the vCenter has the version, API version and build.
+# TYPE vsphere_inventory_vcenter gauge
+vsphere_inventory_vcenter{api_version="6.5",build="5973321",version="6.5.0"} 1
+`
+	metrics := []string{
+		"vsphere_inventory_datastores",
+		"vsphere_inventory_esxi_hosts",
+		"vsphere_inventory_in_tree_volumes",
+		"vsphere_inventory_node_hw_versions",
+		"vsphere_inventory_vcenter",
+	}
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), metrics...); err != nil {
+		t.Errorf("Unexpected metrics: %s", err)
+	}
+
+	// The next round does not report facts that are gone.
+	ctx.ClusterInfo.Reset()
+	kubeClient.pvs = nil
+	CollectInventory(ctx)
+	expectedMetrics = `
+# HELP vsphere_inventory_in_tree_volumes [ALPHA] Number of in-tree vSphere PVs, by presence of the CSI migration annotation pv.kubernetes.io/migrated-to.
+# TYPE vsphere_inventory_in_tree_volumes gauge
+vsphere_inventory_in_tree_volumes{migrated="false"} 0
+vsphere_inventory_in_tree_volumes{migrated="true"} 0
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), metrics...); err != nil {
+		t.Errorf("Unexpected metrics after reset: %s", err)
+	}
+}
@@ -0,0 +1,95 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMBootDeviceOrder makes sure that node VMs boot from disk first. A node VM
+// that boots from network or CD-ROM first can fail to boot after a transient condition.
+type CheckNodeVMBootDeviceOrder struct {
+	notDiskFirstLock  sync.Mutex
+	notDiskFirstCount int
+}
+
+var _ NodeCheck = &CheckNodeVMBootDeviceOrder{}
+
+var (
+	bootOrderNotDiskFirstMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_boot_order_not_disk_first_total",
+			Help:           "Number of vSphere node VMs that are not configured to boot from disk first.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(bootOrderNotDiskFirstMetric)
+}
+
+func (c *CheckNodeVMBootDeviceOrder) Name() string {
+	return "CheckNodeVMBootDeviceOrder"
+}
+
+func (c *CheckNodeVMBootDeviceOrder) StartCheck() error {
+	c.notDiskFirstLock.Lock()
+	defer c.notDiskFirstLock.Unlock()
+	c.notDiskFirstCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMBootDeviceOrder) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil || vm.Config.BootOptions == nil || len(vm.Config.BootOptions.BootOrder) == 0 {
+		// The default boot order is used, it boots from the first bootable device.
+		klog.V(4).Infof("... the node has no boot order set")
+		return nil
+	}
+
+	bootOrder := vm.Config.BootOptions.BootOrder
+	if _, ok := bootOrder[0].(*types.VirtualMachineBootOptionsBootableDiskDevice); ok {
+		klog.V(4).Infof("... the node boots from disk first")
+		return nil
+	}
+
+	c.notDiskFirstLock.Lock()
+	c.notDiskFirstCount++
+	c.notDiskFirstLock.Unlock()
+	return fmt.Errorf("node %s does not boot from disk first: bootOptions.bootOrder is %s", node.Name, formatBootOrder(bootOrder))
+}
+
+func (c *CheckNodeVMBootDeviceOrder) FinishCheck(ctx *CheckContext) {
+	c.notDiskFirstLock.Lock()
+	defer c.notDiskFirstLock.Unlock()
+	bootOrderNotDiskFirstMetric.WithLabelValues().Set(float64(c.notDiskFirstCount))
+	return
+}
+
+// formatBootOrder returns human readable boot order, e.g. "[cdrom, disk]".
+func formatBootOrder(bootOrder []types.BaseVirtualMachineBootOptionsBootableDevice) string {
+	var devices []string
+	for _, device := range bootOrder {
+		switch device.(type) {
+		case *types.VirtualMachineBootOptionsBootableDiskDevice:
+			devices = append(devices, "disk")
+		case *types.VirtualMachineBootOptionsBootableCdromDevice:
+			devices = append(devices, "cdrom")
+		case *types.VirtualMachineBootOptionsBootableEthernetDevice:
+			devices = append(devices, "ethernet")
+		case *types.VirtualMachineBootOptionsBootableFloppyDevice:
+			devices = append(devices, "floppy")
+		default:
+			devices = append(devices, "unknown")
+		}
+	}
+	return "[" + strings.Join(devices, ", ") + "]"
+}
@@ -0,0 +1,111 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMBootDeviceOrder(t *testing.T) {
+	tests := []struct {
+		name          string
+		bootOrder     []types.BaseVirtualMachineBootOptionsBootableDevice
+		expectedError string
+	}{
+		{
+			name:      "default boot order",
+			bootOrder: nil,
+		},
+		{
+			name: "disk first",
+			bootOrder: []types.BaseVirtualMachineBootOptionsBootableDevice{
+				&types.VirtualMachineBootOptionsBootableDiskDevice{DeviceKey: 2000},
+				&types.VirtualMachineBootOptionsBootableCdromDevice{},
+			},
+		},
+		{
+			name: "cdrom first",
+			bootOrder: []types.BaseVirtualMachineBootOptionsBootableDevice{
+				&types.VirtualMachineBootOptionsBootableCdromDevice{},
+				&types.VirtualMachineBootOptionsBootableDiskDevice{DeviceKey: 2000},
+			},
+			expectedError: "node DC0_H0_VM0 does not boot from disk first: bootOptions.bootOrder is [cdrom, disk]",
+		},
+		{
+			name: "network only",
+			bootOrder: []types.BaseVirtualMachineBootOptionsBootableDevice{
+				&types.VirtualMachineBootOptionsBootableEthernetDevice{DeviceKey: 4000},
+			},
+			expectedError: "node DC0_H0_VM0 does not boot from disk first: bootOptions.bootOrder is [ethernet]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMBootDeviceOrder{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			if test.bootOrder != nil {
+				err = customizeVM(ctx, node, &types.VirtualMachineConfigSpec{
+					BootOptions: &types.VirtualMachineBootOptions{
+						BootOrder: test.bootOrder,
+					},
+				})
+				if err != nil {
+					t.Fatalf("Failed to customize node: %s", err)
+				}
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_boot_order_not_disk_first_total [ALPHA] Number of vSphere node VMs that are not configured to boot from disk first.
+# TYPE vsphere_node_boot_order_not_disk_first_total gauge
+vsphere_node_boot_order_not_disk_first_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_boot_order_not_disk_first_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
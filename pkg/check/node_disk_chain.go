@@ -0,0 +1,112 @@
+package check
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// maxDiskBackingChainDepth is the maximum nr. of parents in backing chain of a single node VM disk.
+	maxDiskBackingChainDepth = flag.Int("max-disk-backing-chain-depth", 3, "Maximum depth of backing parent chain of a node VM disk, i.e. the number of snapshot delta disks above the base disk.")
+
+	nodeDiskBackingChainDepthMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_disk_backing_chain_depth",
+			Help:           "Maximum depth of backing parent chain of disks of a vSphere node VM.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{nodeLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(nodeDiskBackingChainDepthMetric)
+}
+
+// CheckNodeVMDiskBackingParentChainDepth makes sure that disks of node VMs do not have too deep backing parent
+// chains. Each snapshot adds a delta disk to the chain and each read may need to go through all of them,
+// which degrades I/O of the node.
+type CheckNodeVMDiskBackingParentChainDepth struct{}
+
+var _ NodeCheck = &CheckNodeVMDiskBackingParentChainDepth{}
+
+func (c *CheckNodeVMDiskBackingParentChainDepth) Name() string {
+	return "CheckNodeVMDiskBackingParentChainDepth"
+}
+
+func (c *CheckNodeVMDiskBackingParentChainDepth) StartCheck() error {
+	// Drop nodes that do not exist any longer.
+	nodeDiskBackingChainDepthMetric.Reset()
+	return nil
+}
+
+func (c *CheckNodeVMDiskBackingParentChainDepth) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil {
+		return nil
+	}
+
+	maxDepth := 0
+	var deepDisks []string
+	for _, device := range vm.Config.Hardware.Device {
+		disk, ok := device.(*types.VirtualDisk)
+		if !ok {
+			continue
+		}
+		fileName, depth := getDiskBackingChainDepth(disk.Backing)
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		if depth > *maxDiskBackingChainDepth {
+			deepDisks = append(deepDisks, fmt.Sprintf("%q (%s, depth %d)", getDeviceLabel(&disk.VirtualDevice), fileName, depth))
+		}
+	}
+	nodeDiskBackingChainDepthMetric.WithLabelValues(node.Name).Set(float64(maxDepth))
+
+	if len(deepDisks) == 0 {
+		klog.V(4).Infof("... the node has maximum disk backing chain depth %d", maxDepth)
+		return nil
+	}
+	return fmt.Errorf("node %s has disks with backing chain deeper than %d: %s", node.Name, *maxDiskBackingChainDepth, strings.Join(deepDisks, ", "))
+}
+
+func (c *CheckNodeVMDiskBackingParentChainDepth) FinishCheck(ctx *CheckContext) {
+	return
+}
+
+// getDiskBackingChainDepth returns file name of the disk backing and the number of its parents.
+// Backings that cannot have parents have depth 0.
+func getDiskBackingChainDepth(backing types.BaseVirtualDeviceBackingInfo) (string, int) {
+	fileName := ""
+	if fileBacking, ok := backing.(types.BaseVirtualDeviceFileBackingInfo); ok {
+		fileName = fileBacking.GetVirtualDeviceFileBackingInfo().FileName
+	}
+
+	depth := 0
+	switch b := backing.(type) {
+	case *types.VirtualDiskFlatVer2BackingInfo:
+		for parent := b.Parent; parent != nil; parent = parent.Parent {
+			depth++
+		}
+	case *types.VirtualDiskSeSparseBackingInfo:
+		for parent := b.Parent; parent != nil; parent = parent.Parent {
+			depth++
+		}
+	case *types.VirtualDiskSparseVer2BackingInfo:
+		for parent := b.Parent; parent != nil; parent = parent.Parent {
+			depth++
+		}
+	case *types.VirtualDiskRawDiskMappingVer1BackingInfo:
+		for parent := b.Parent; parent != nil; parent = parent.Parent {
+			depth++
+		}
+	}
+	return fileName, depth
+}
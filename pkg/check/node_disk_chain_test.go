@@ -0,0 +1,139 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMDiskBackingParentChainDepth(t *testing.T) {
+	tests := []struct {
+		name            string
+		extraDisks      []types.BaseVirtualDevice
+		expectedError   string
+		expectedMetrics string
+	}{
+		{
+			name: "no snapshots",
+			expectedMetrics: `
+# HELP vsphere_node_disk_backing_chain_depth [ALPHA] Maximum depth of backing parent chain of disks of a vSphere node VM.
+# TYPE vsphere_node_disk_backing_chain_depth gauge
+vsphere_node_disk_backing_chain_depth{node="DC0_H0_VM0"} 0
+`,
+		},
+		{
+			name: "chain at the limit",
+			extraDisks: []types.BaseVirtualDevice{
+				diskWithParents("[LocalDS_0] DC0_H0_VM0/disk1-000003.vmdk", 3),
+			},
+			expectedMetrics: `
+# HELP vsphere_node_disk_backing_chain_depth [ALPHA] Maximum depth of backing parent chain of disks of a vSphere node VM.
+# TYPE vsphere_node_disk_backing_chain_depth gauge
+vsphere_node_disk_backing_chain_depth{node="DC0_H0_VM0"} 3
+`,
+		},
+		{
+			name: "chains above the limit",
+			extraDisks: []types.BaseVirtualDevice{
+				diskWithParents("[LocalDS_0] DC0_H0_VM0/disk1-000004.vmdk", 4),
+				diskWithParents("[LocalDS_0] DC0_H0_VM0/disk2-000001.vmdk", 1),
+				diskWithParents("[LocalDS_0] DC0_H0_VM0/disk3-000006.vmdk", 6),
+			},
+			expectedError: `node DC0_H0_VM0 has disks with backing chain deeper than 3: "device 0" ([LocalDS_0] DC0_H0_VM0/disk1-000004.vmdk, depth 4), "device 0" ([LocalDS_0] DC0_H0_VM0/disk3-000006.vmdk, depth 6)`,
+			expectedMetrics: `
+# HELP vsphere_node_disk_backing_chain_depth [ALPHA] Maximum depth of backing parent chain of disks of a vSphere node VM.
+# TYPE vsphere_node_disk_backing_chain_depth gauge
+vsphere_node_disk_backing_chain_depth{node="DC0_H0_VM0"} 6
+`,
+		},
+		{
+			name: "seSparse chain above the limit",
+			extraDisks: []types.BaseVirtualDevice{
+				&types.VirtualDisk{
+					VirtualDevice: types.VirtualDevice{
+						Key: 2001,
+						Backing: &types.VirtualDiskSeSparseBackingInfo{
+							VirtualDeviceFileBackingInfo: types.VirtualDeviceFileBackingInfo{FileName: "[LocalDS_0] DC0_H0_VM0/disk1-000004.vmdk"},
+							Parent: &types.VirtualDiskSeSparseBackingInfo{
+								Parent: &types.VirtualDiskSeSparseBackingInfo{
+									Parent: &types.VirtualDiskSeSparseBackingInfo{
+										Parent: &types.VirtualDiskSeSparseBackingInfo{},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedError: `node DC0_H0_VM0 has disks with backing chain deeper than 3: "device 2001" ([LocalDS_0] DC0_H0_VM0/disk1-000004.vmdk, depth 4)`,
+			expectedMetrics: `
+# HELP vsphere_node_disk_backing_chain_depth [ALPHA] Maximum depth of backing parent chain of disks of a vSphere node VM.
+# TYPE vsphere_node_disk_backing_chain_depth gauge
+vsphere_node_disk_backing_chain_depth{node="DC0_H0_VM0"} 4
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMDiskBackingParentChainDepth{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			vm.Config.Hardware.Device = append(vm.Config.Hardware.Device, test.extraDisks...)
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_node_disk_backing_chain_depth"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+// diskWithParents returns a VirtualDisk stored in given file with given number of parents in its backing chain.
+func diskWithParents(fileName string, parents int) *types.VirtualDisk {
+	d := disk(fileName, "")
+	backing := d.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+	for i := 0; i < parents; i++ {
+		backing.Parent = &types.VirtualDiskFlatVer2BackingInfo{}
+		backing = backing.Parent
+	}
+	return d
+}
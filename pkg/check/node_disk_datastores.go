@@ -0,0 +1,102 @@
+package check
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	nodeLabel = "node"
+)
+
+var (
+	// maxNodeDiskDatastores is the maximum nr. of datastores that disks of a single node VM can use.
+	maxNodeDiskDatastores = flag.Int("max-node-disk-datastores", 2, "Maximum number of distinct datastores that disks of a single node VM may reside on. Disks attached by the CSI driver are not counted.")
+
+	nodeDiskDatastoresMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_disk_datastores",
+			Help:           "Number of distinct datastores with disks of a vSphere node VM.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{nodeLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(nodeDiskDatastoresMetric)
+}
+
+// CheckNodeVMDiskFragmentationAcrossDatastores makes sure that disks of a single node VM are not
+// spread across too many datastores. Such VMs are hard to back up and migrate.
+type CheckNodeVMDiskFragmentationAcrossDatastores struct{}
+
+var _ NodeCheck = &CheckNodeVMDiskFragmentationAcrossDatastores{}
+
+func (c *CheckNodeVMDiskFragmentationAcrossDatastores) Name() string {
+	return "CheckNodeVMDiskFragmentationAcrossDatastores"
+}
+
+func (c *CheckNodeVMDiskFragmentationAcrossDatastores) StartCheck() error {
+	// Drop nodes that do not exist any longer.
+	nodeDiskDatastoresMetric.Reset()
+	return nil
+}
+
+func (c *CheckNodeVMDiskFragmentationAcrossDatastores) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	datastores := getVMDiskDatastores(vm)
+	nodeDiskDatastoresMetric.WithLabelValues(node.Name).Set(float64(len(datastores)))
+	if len(datastores) <= *maxNodeDiskDatastores {
+		klog.V(4).Infof("... the node has disks on datastores %v", datastores)
+		return nil
+	}
+	return fmt.Errorf("node %s has disks on %d datastores, more than %d: %s", node.Name, len(datastores), *maxNodeDiskDatastores, strings.Join(datastores, ", "))
+}
+
+func (c *CheckNodeVMDiskFragmentationAcrossDatastores) FinishCheck(ctx *CheckContext) {
+	return
+}
+
+// getVMDiskDatastores returns sorted names of datastores with disks of the VM. First class disks,
+// i.e. volumes attached by the CSI driver, are skipped.
+func getVMDiskDatastores(vm *mo.VirtualMachine) []string {
+	if vm.Config == nil {
+		return nil
+	}
+	datastores := make(map[string]bool)
+	for _, device := range vm.Config.Hardware.Device {
+		disk, ok := device.(*types.VirtualDisk)
+		if !ok {
+			continue
+		}
+		if disk.VDiskId != nil && disk.VDiskId.Id != "" {
+			continue
+		}
+		backing, ok := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo)
+		if !ok {
+			continue
+		}
+		var dsPath object.DatastorePath
+		if !dsPath.FromString(backing.GetVirtualDeviceFileBackingInfo().FileName) {
+			continue
+		}
+		datastores[dsPath.Datastore] = true
+	}
+
+	var names []string
+	for name := range datastores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
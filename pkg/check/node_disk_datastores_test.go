@@ -0,0 +1,130 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMDiskFragmentationAcrossDatastores(t *testing.T) {
+	tests := []struct {
+		name            string
+		extraDisks      []types.BaseVirtualDevice
+		expectedError   string
+		expectedMetrics string
+	}{
+		{
+			name: "single disk",
+			expectedMetrics: `
+# HELP vsphere_node_disk_datastores [ALPHA] Number of distinct datastores with disks of a vSphere node VM.
+# TYPE vsphere_node_disk_datastores gauge
+vsphere_node_disk_datastores{node="DC0_H0_VM0"} 1
+`,
+		},
+		{
+			name: "disks on two datastores",
+			extraDisks: []types.BaseVirtualDevice{
+				disk("[LocalDS_1] DC0_H0_VM0/disk1.vmdk", ""),
+			},
+			expectedMetrics: `
+# HELP vsphere_node_disk_datastores [ALPHA] Number of distinct datastores with disks of a vSphere node VM.
+# TYPE vsphere_node_disk_datastores gauge
+vsphere_node_disk_datastores{node="DC0_H0_VM0"} 2
+`,
+		},
+		{
+			name: "disks on three datastores",
+			extraDisks: []types.BaseVirtualDevice{
+				disk("[LocalDS_1] DC0_H0_VM0/disk1.vmdk", ""),
+				disk("[LocalDS_2] DC0_H0_VM0/disk2.vmdk", ""),
+			},
+			expectedError: "node DC0_H0_VM0 has disks on 3 datastores, more than 2: LocalDS_0, LocalDS_1, LocalDS_2",
+			expectedMetrics: `
+# HELP vsphere_node_disk_datastores [ALPHA] Number of distinct datastores with disks of a vSphere node VM.
+# TYPE vsphere_node_disk_datastores gauge
+vsphere_node_disk_datastores{node="DC0_H0_VM0"} 3
+`,
+		},
+		{
+			name: "CSI volumes on other datastores",
+			extraDisks: []types.BaseVirtualDevice{
+				disk("[LocalDS_1] fcd/volume1.vmdk", "volume1"),
+				disk("[LocalDS_2] fcd/volume2.vmdk", "volume2"),
+			},
+			expectedMetrics: `
+# HELP vsphere_node_disk_datastores [ALPHA] Number of distinct datastores with disks of a vSphere node VM.
+# TYPE vsphere_node_disk_datastores gauge
+vsphere_node_disk_datastores{node="DC0_H0_VM0"} 1
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMDiskFragmentationAcrossDatastores{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			vm.Config.Hardware.Device = append(vm.Config.Hardware.Device, test.extraDisks...)
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_node_disk_datastores"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+// disk returns a VirtualDisk stored in given file. Non-empty fcdID makes it a first class disk.
+func disk(fileName string, fcdID string) *types.VirtualDisk {
+	d := &types.VirtualDisk{
+		VirtualDevice: types.VirtualDevice{
+			Backing: &types.VirtualDiskFlatVer2BackingInfo{
+				VirtualDeviceFileBackingInfo: types.VirtualDeviceFileBackingInfo{
+					FileName: fileName,
+				},
+			},
+		},
+	}
+	if fcdID != "" {
+		d.VDiskId = &types.ID{Id: fcdID}
+	}
+	return d
+}
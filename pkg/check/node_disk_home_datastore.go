@@ -0,0 +1,91 @@
+package check
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// requireDisksOnHomeDatastore enables CheckNodeVMDiskAllSameDatastoreAsHome.
+	requireDisksOnHomeDatastore = flag.Bool("require-disks-on-home-datastore", false, "Require all disks of node VMs to be on the same datastore as the VM home (the .vmx file). Disks attached by the CSI driver are not checked.")
+
+	diskNotOnHomeDatastoreMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_disks_not_on_home_datastore_total",
+			Help:           "Number of vSphere node VMs with disks outside of the datastore of their VM home.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(diskNotOnHomeDatastoreMetric)
+}
+
+// CheckNodeVMDiskAllSameDatastoreAsHome makes sure that all disks of node VMs are on the same datastore
+// as their VM home. It is an optional policy, that makes recovery of node VMs simpler. The check runs only
+// when enabled by the require-disks-on-home-datastore flag.
+type CheckNodeVMDiskAllSameDatastoreAsHome struct {
+	notOnHomeLock  sync.Mutex
+	notOnHomeCount int
+}
+
+var _ NodeCheck = &CheckNodeVMDiskAllSameDatastoreAsHome{}
+
+func (c *CheckNodeVMDiskAllSameDatastoreAsHome) Name() string {
+	return "CheckNodeVMDiskAllSameDatastoreAsHome"
+}
+
+func (c *CheckNodeVMDiskAllSameDatastoreAsHome) StartCheck() error {
+	c.notOnHomeLock.Lock()
+	defer c.notOnHomeLock.Unlock()
+	c.notOnHomeCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMDiskAllSameDatastoreAsHome) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if !*requireDisksOnHomeDatastore {
+		klog.V(4).Infof("... disks on home datastore are not required, skipping")
+		return nil
+	}
+	if vm.Config == nil {
+		return fmt.Errorf("error getting VM home of node %s: vm.config is empty", node.Name)
+	}
+	var home object.DatastorePath
+	if !home.FromString(vm.Config.Files.VmPathName) {
+		return fmt.Errorf("error getting VM home of node %s: failed to parse vmPathName %q", node.Name, vm.Config.Files.VmPathName)
+	}
+
+	var other []string
+	for _, ds := range getVMDiskDatastores(vm) {
+		if ds != home.Datastore {
+			other = append(other, ds)
+		}
+	}
+	if len(other) == 0 {
+		klog.V(4).Infof("... the node has all disks on its home datastore %s", home.Datastore)
+		return nil
+	}
+
+	c.notOnHomeLock.Lock()
+	c.notOnHomeCount++
+	c.notOnHomeLock.Unlock()
+	return fmt.Errorf("node %s has VM home on datastore %s, but it has disks also on datastores: %s", node.Name, home.Datastore, strings.Join(other, ", "))
+}
+
+func (c *CheckNodeVMDiskAllSameDatastoreAsHome) FinishCheck(ctx *CheckContext) {
+	c.notOnHomeLock.Lock()
+	defer c.notOnHomeLock.Unlock()
+	diskNotOnHomeDatastoreMetric.WithLabelValues().Set(float64(c.notOnHomeCount))
+	return
+}
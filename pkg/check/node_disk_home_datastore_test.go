@@ -0,0 +1,111 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMDiskAllSameDatastoreAsHome(t *testing.T) {
+	tests := []struct {
+		name          string
+		enabled       bool
+		disks         []types.BaseVirtualDevice
+		expectedError string
+	}{
+		{
+			name:    "disabled",
+			enabled: false,
+			disks: []types.BaseVirtualDevice{
+				disk("[other] DC0_H0_VM0/data.vmdk", ""),
+			},
+		},
+		{
+			name:    "disks on home datastore",
+			enabled: true,
+			disks: []types.BaseVirtualDevice{
+				disk("[LocalDS_0] DC0_H0_VM0/data.vmdk", ""),
+			},
+		},
+		{
+			name:    "CSI volume on other datastore",
+			enabled: true,
+			disks: []types.BaseVirtualDevice{
+				disk("[other] fcd/pv.vmdk", "fcd-1"),
+			},
+		},
+		{
+			name:    "disks on other datastores",
+			enabled: true,
+			disks: []types.BaseVirtualDevice{
+				disk("[other2] DC0_H0_VM0/data.vmdk", ""),
+				disk("[other1] DC0_H0_VM0/scratch.vmdk", ""),
+			},
+			expectedError: "node DC0_H0_VM0 has VM home on datastore LocalDS_0, but it has disks also on datastores: other1, other2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMDiskAllSameDatastoreAsHome{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			origRequireDisksOnHomeDatastore := *requireDisksOnHomeDatastore
+			*requireDisksOnHomeDatastore = test.enabled
+			defer func() { *requireDisksOnHomeDatastore = origRequireDisksOnHomeDatastore }()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			vm.Config.Hardware.Device = append(vm.Config.Hardware.Device, test.disks...)
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_disks_not_on_home_datastore_total [ALPHA] Number of vSphere node VMs with disks outside of the datastore of their VM home.
+# TYPE vsphere_node_disks_not_on_home_datastore_total gauge
+vsphere_node_disks_not_on_home_datastore_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_disks_not_on_home_datastore_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMDiskIndependentOfSnapshotChain makes sure that node VMs have no independent disks.
+// Independent disks are not included in VM snapshots, so backup tools that use snapshots silently
+// skip them. First class disks, i.e. volumes attached by the CSI driver, are independent by design
+// and they are not reported.
+type CheckNodeVMDiskIndependentOfSnapshotChain struct {
+	independentDisksLock  sync.Mutex
+	independentDisksCount int
+}
+
+var _ NodeCheck = &CheckNodeVMDiskIndependentOfSnapshotChain{}
+
+var (
+	independentDisksMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_independent_disks_total",
+			Help:           "Number of independent disks of vSphere node VMs that are excluded from VM snapshots.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(independentDisksMetric)
+}
+
+func (c *CheckNodeVMDiskIndependentOfSnapshotChain) Name() string {
+	return "CheckNodeVMDiskIndependentOfSnapshotChain"
+}
+
+func (c *CheckNodeVMDiskIndependentOfSnapshotChain) StartCheck() error {
+	c.independentDisksLock.Lock()
+	defer c.independentDisksLock.Unlock()
+	c.independentDisksCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMDiskIndependentOfSnapshotChain) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	disks := getVMIndependentDisks(vm)
+	if len(disks) == 0 {
+		klog.V(4).Infof("... the node has no independent disks")
+		return nil
+	}
+
+	c.independentDisksLock.Lock()
+	c.independentDisksCount += len(disks)
+	c.independentDisksLock.Unlock()
+	return fmt.Errorf("node %s has independent disks that are not included in VM snapshots used for backup: %s", node.Name, strings.Join(disks, ", "))
+}
+
+func (c *CheckNodeVMDiskIndependentOfSnapshotChain) FinishCheck(ctx *CheckContext) {
+	c.independentDisksLock.Lock()
+	defer c.independentDisksLock.Unlock()
+	independentDisksMetric.WithLabelValues().Set(float64(c.independentDisksCount))
+	return
+}
+
+// getVMIndependentDisks returns descriptions of independent disks of the VM, in the order of the disks.
+// First class disks are skipped.
+func getVMIndependentDisks(vm *mo.VirtualMachine) []string {
+	if vm.Config == nil {
+		return nil
+	}
+	var disks []string
+	for _, device := range vm.Config.Hardware.Device {
+		disk, ok := device.(*types.VirtualDisk)
+		if !ok {
+			continue
+		}
+		if disk.VDiskId != nil && disk.VDiskId.Id != "" {
+			continue
+		}
+		var fileName, diskMode string
+		switch backing := disk.Backing.(type) {
+		case *types.VirtualDiskFlatVer2BackingInfo:
+			fileName, diskMode = backing.FileName, backing.DiskMode
+		case *types.VirtualDiskSeSparseBackingInfo:
+			fileName, diskMode = backing.FileName, backing.DiskMode
+		case *types.VirtualDiskSparseVer2BackingInfo:
+			fileName, diskMode = backing.FileName, backing.DiskMode
+		case *types.VirtualDiskRawDiskMappingVer1BackingInfo:
+			fileName, diskMode = backing.FileName, backing.DiskMode
+		default:
+			continue
+		}
+		switch types.VirtualDiskMode(diskMode) {
+		case types.VirtualDiskModeIndependent_persistent, types.VirtualDiskModeIndependent_nonpersistent:
+			disks = append(disks, fmt.Sprintf("%q (%s, mode %s)", getDeviceLabel(&disk.VirtualDevice), fileName, diskMode))
+		}
+	}
+	return disks
+}
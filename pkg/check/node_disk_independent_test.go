@@ -0,0 +1,107 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func diskWithMode(key int32, label, fileName, fcdID string, mode types.VirtualDiskMode) *types.VirtualDisk {
+	d := disk(fileName, fcdID)
+	d.Key = key
+	d.DeviceInfo = &types.Description{Label: label}
+	d.Backing.(*types.VirtualDiskFlatVer2BackingInfo).DiskMode = string(mode)
+	return d
+}
+
+func TestCheckNodeVMDiskIndependentOfSnapshotChain(t *testing.T) {
+	tests := []struct {
+		name          string
+		disks         []types.BaseVirtualDevice
+		expectedError string
+		expectedCount int
+	}{
+		{
+			name: "no additional disks",
+		},
+		{
+			name: "persistent disk",
+			disks: []types.BaseVirtualDevice{
+				diskWithMode(2001, "Hard disk 2", "[LocalDS_0] vm/data.vmdk", "", types.VirtualDiskModePersistent),
+			},
+		},
+		{
+			name: "independent CSI volume",
+			disks: []types.BaseVirtualDevice{
+				diskWithMode(2001, "Hard disk 2", "[LocalDS_0] fcd/pv.vmdk", "fcd-1", types.VirtualDiskModeIndependent_persistent),
+			},
+		},
+		{
+			name: "independent disks",
+			disks: []types.BaseVirtualDevice{
+				diskWithMode(2001, "Hard disk 2", "[LocalDS_0] vm/data.vmdk", "", types.VirtualDiskModeIndependent_persistent),
+				diskWithMode(2002, "Hard disk 3", "[LocalDS_0] vm/scratch.vmdk", "", types.VirtualDiskModeIndependent_nonpersistent),
+			},
+			expectedError: `node DC0_H0_VM0 has independent disks that are not included in VM snapshots used for backup: "Hard disk 2" ([LocalDS_0] vm/data.vmdk, mode independent_persistent), "Hard disk 3" ([LocalDS_0] vm/scratch.vmdk, mode independent_nonpersistent)`,
+			expectedCount: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMDiskIndependentOfSnapshotChain{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			vm.Config.Hardware.Device = append(vm.Config.Hardware.Device, test.disks...)
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_independent_disks_total [ALPHA] Number of independent disks of vSphere node VMs that are excluded from VM snapshots.
+# TYPE vsphere_node_independent_disks_total gauge
+vsphere_node_independent_disks_total %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_independent_disks_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
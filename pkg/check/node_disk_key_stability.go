@@ -0,0 +1,132 @@
+package check
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	diskKeyShiftedMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_disk_key_shifted_total",
+			Help:           "Number of disks of vSphere node VMs whose device key changed since the previous round of checks.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(diskKeyShiftedMetric)
+}
+
+// diskKeySample is the last seen device keys of disks of a node.
+type diskKeySample struct {
+	// backing file path -> device key
+	keys map[string]int32
+	// seen is true when the node was checked in the current round
+	seen bool
+}
+
+// CheckNodeVMDiskKeyStabilityForCSI makes sure that device keys of node VM disks do not change.
+// The vSphere CSI driver finds attached volumes by their devices, a disk that gets a different device key
+// while its backing file stays attached can make a mount point to a wrong device. vSphere does not report
+// history of device keys, therefore the check remembers keys of disks of each node from the previous round.
+type CheckNodeVMDiskKeyStabilityForCSI struct {
+	samplesLock  sync.Mutex
+	samples      map[string]*diskKeySample
+	shiftedCount int
+}
+
+var _ NodeCheck = &CheckNodeVMDiskKeyStabilityForCSI{}
+
+func (c *CheckNodeVMDiskKeyStabilityForCSI) Name() string {
+	return "CheckNodeVMDiskKeyStabilityForCSI"
+}
+
+func (c *CheckNodeVMDiskKeyStabilityForCSI) StartCheck() error {
+	c.samplesLock.Lock()
+	defer c.samplesLock.Unlock()
+	if c.samples == nil {
+		c.samples = make(map[string]*diskKeySample)
+	}
+	for _, sample := range c.samples {
+		sample.seen = false
+	}
+	c.shiftedCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMDiskKeyStabilityForCSI) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil {
+		klog.V(4).Infof("... the node has no configuration")
+		return nil
+	}
+	keys := getVMDiskKeys(vm.Config.Hardware.Device)
+
+	c.samplesLock.Lock()
+	defer c.samplesLock.Unlock()
+	sample, found := c.samples[node.Name]
+	c.samples[node.Name] = &diskKeySample{keys: keys, seen: true}
+	if !found {
+		klog.V(4).Infof("... recorded device keys of %d disks of the node", len(keys))
+		return nil
+	}
+
+	var paths []string
+	for path := range keys {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var errs []error
+	for _, path := range paths {
+		oldKey, found := sample.keys[path]
+		if !found || oldKey == keys[path] {
+			continue
+		}
+		c.shiftedCount++
+		errs = append(errs, fmt.Errorf("node %s disk %s changed device key from %d to %d", node.Name, path, oldKey, keys[path]))
+	}
+	if len(errs) == 0 {
+		klog.V(4).Infof("... the node has stable device keys of %d disks", len(keys))
+	}
+	return JoinErrors(errs)
+}
+
+func (c *CheckNodeVMDiskKeyStabilityForCSI) FinishCheck(ctx *CheckContext) {
+	c.samplesLock.Lock()
+	defer c.samplesLock.Unlock()
+	// Forget nodes that were not checked
+	for name, sample := range c.samples {
+		if !sample.seen {
+			delete(c.samples, name)
+		}
+	}
+	diskKeyShiftedMetric.WithLabelValues().Set(float64(c.shiftedCount))
+	return
+}
+
+// getVMDiskKeys returns device keys of disks with file backing, backing file path -> device key.
+func getVMDiskKeys(devices []types.BaseVirtualDevice) map[string]int32 {
+	keys := make(map[string]int32)
+	for _, device := range devices {
+		disk, ok := device.(*types.VirtualDisk)
+		if !ok {
+			continue
+		}
+		backing, ok := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo)
+		if !ok {
+			continue
+		}
+		keys[backing.GetVirtualDeviceFileBackingInfo().FileName] = disk.Key
+	}
+	return keys
+}
@@ -0,0 +1,125 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMDiskKeyStabilityForCSI(t *testing.T) {
+	tests := []struct {
+		name        string
+		updateDisk  func(disk *types.VirtualDisk)
+		expectError bool
+	}{
+		{
+			name:        "unchanged disks",
+			expectError: false,
+		},
+		{
+			name: "disk key changed",
+			updateDisk: func(disk *types.VirtualDisk) {
+				disk.Key++
+			},
+			expectError: true,
+		},
+		{
+			name: "disk replaced",
+			updateDisk: func(disk *types.VirtualDisk) {
+				disk.Key++
+				disk.Backing.(types.BaseVirtualDeviceFileBackingInfo).GetVirtualDeviceFileBackingInfo().FileName = "[LocalDS_0] DC0_H0_VM0/disk2.vmdk"
+			},
+			expectError: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMDiskKeyStabilityForCSI{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+
+			// The first round records the disk keys
+			if err := check.StartCheck(); err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			if err := check.CheckNode(ctx, node, vm); err != nil {
+				t.Errorf("Unexpected error in the first round: %s", err)
+			}
+			check.FinishCheck(ctx)
+
+			expectedError := ""
+			if test.updateDisk != nil {
+				vm, err = getVM(ctx, node)
+				if err != nil {
+					t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+				}
+				disk := getFirstVirtualDisk(t, vm)
+				path := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo).GetVirtualDeviceFileBackingInfo().FileName
+				oldKey := disk.Key
+				test.updateDisk(disk)
+				expectedError = fmt.Sprintf("node %s disk %s changed device key from %d to %d", node.Name, path, oldKey, disk.Key)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectError {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", expectedError)
+				} else if err.Error() != expectedError {
+					t.Errorf("Expected error %q, got %q", expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_disk_key_shifted_total [ALPHA] Number of disks of vSphere node VMs whose device key changed since the previous round of checks.
+# TYPE vsphere_node_disk_key_shifted_total gauge
+vsphere_node_disk_key_shifted_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_disk_key_shifted_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+func getFirstVirtualDisk(t *testing.T, vm *mo.VirtualMachine) *types.VirtualDisk {
+	for _, device := range vm.Config.Hardware.Device {
+		if disk, ok := device.(*types.VirtualDisk); ok {
+			return disk
+		}
+	}
+	t.Fatalf("VM %s has no disk", vm.Name)
+	return nil
+}
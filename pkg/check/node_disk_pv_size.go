@@ -0,0 +1,119 @@
+package check
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Relative difference between size of a PV and its virtual disk that is still considered a match.
+	diskSizeMismatchTolerance = 0.01
+)
+
+// CheckNodeVMDiskSizeVsPVCSize makes sure that size of virtual disks attached to node VMs matches
+// capacity of their PVs. A mismatch shows an incomplete volume resize.
+type CheckNodeVMDiskSizeVsPVCSize struct {
+	mismatchLock  sync.Mutex
+	mismatchCount int
+}
+
+var _ NodeCheck = &CheckNodeVMDiskSizeVsPVCSize{}
+
+var (
+	volumeSizeMismatchMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_volume_size_mismatch_total",
+			Help:           "Number of volumes attached to vSphere node VMs with virtual disk size different from PV capacity.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(volumeSizeMismatchMetric)
+}
+
+func (c *CheckNodeVMDiskSizeVsPVCSize) Name() string {
+	return "CheckNodeVMDiskSizeVsPVCSize"
+}
+
+func (c *CheckNodeVMDiskSizeVsPVCSize) StartCheck() error {
+	c.mismatchLock.Lock()
+	defer c.mismatchLock.Unlock()
+	c.mismatchCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMDiskSizeVsPVCSize) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil {
+		return nil
+	}
+	pvs, err := ctx.KubeClient.ListPVs(ctx.Context)
+	if err != nil {
+		return err
+	}
+
+	// Index PVs by their virtual disk: CSI volumes by first class disk ID, in-tree volumes by disk path.
+	csiPVs := make(map[string]*v1.PersistentVolume)
+	inTreePVs := make(map[string]*v1.PersistentVolume)
+	for _, pv := range pvs {
+		switch {
+		case pv.Spec.CSI != nil && pv.Spec.CSI.Driver == vSphereCSIDdriver:
+			csiPVs[pv.Spec.CSI.VolumeHandle] = pv
+		case pv.Spec.VsphereVolume != nil:
+			inTreePVs[pv.Spec.VsphereVolume.VolumePath] = pv
+		}
+	}
+
+	devices := object.VirtualDeviceList(vm.Config.Hardware.Device)
+	var errs []error
+	for _, device := range devices.SelectByType((*types.VirtualDisk)(nil)) {
+		disk := device.(*types.VirtualDisk)
+		var pv *v1.PersistentVolume
+		if disk.VDiskId != nil {
+			pv = csiPVs[disk.VDiskId.Id]
+		}
+		if backing, ok := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo); pv == nil && ok {
+			pv = inTreePVs[backing.GetVirtualDeviceFileBackingInfo().FileName]
+		}
+		if pv == nil {
+			continue
+		}
+
+		capacity, found := pv.Spec.Capacity[v1.ResourceStorage]
+		if !found {
+			continue
+		}
+		pvSize := capacity.Value()
+		diskSize := disk.CapacityInBytes
+		if pvSize == 0 || math.Abs(float64(diskSize-pvSize))/float64(pvSize) <= diskSizeMismatchTolerance {
+			klog.V(4).Infof("... disk %s of PV %s has size %d bytes", devices.Name(device), pv.Name, diskSize)
+			continue
+		}
+		errs = append(errs, fmt.Errorf("node %s: disk %s of PV %s has size %d bytes, but the PV capacity is %s (%d bytes)", node.Name, devices.Name(device), pv.Name, diskSize, capacity.String(), pvSize))
+	}
+
+	if len(errs) > 0 {
+		c.mismatchLock.Lock()
+		c.mismatchCount += len(errs)
+		c.mismatchLock.Unlock()
+	}
+	return JoinErrors(errs)
+}
+
+func (c *CheckNodeVMDiskSizeVsPVCSize) FinishCheck(ctx *CheckContext) {
+	c.mismatchLock.Lock()
+	defer c.mismatchLock.Unlock()
+	volumeSizeMismatchMetric.WithLabelValues().Set(float64(c.mismatchCount))
+	return
+}
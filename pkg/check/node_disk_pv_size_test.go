@@ -0,0 +1,154 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	gib = 1024 * 1024 * 1024
+)
+
+func csiPV(name, volumeHandle string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{
+					Driver:       vSphereCSIDdriver,
+					VolumeHandle: volumeHandle,
+				},
+			},
+		},
+	}
+}
+
+func withCapacity(pv *v1.PersistentVolume, capacity string) *v1.PersistentVolume {
+	pv.Spec.Capacity = v1.ResourceList{
+		v1.ResourceStorage: resource.MustParse(capacity),
+	}
+	return pv
+}
+
+func diskWithSize(fileName, fcdID string, size int64) *types.VirtualDisk {
+	d := disk(fileName, fcdID)
+	d.CapacityInBytes = size
+	return d
+}
+
+func TestCheckNodeVMDiskSizeVsPVCSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		pvs           []*v1.PersistentVolume
+		disks         []types.BaseVirtualDevice
+		expectedError string
+		expectedCount int
+	}{
+		{
+			name: "no PVs",
+		},
+		{
+			name: "matching sizes",
+			pvs: []*v1.PersistentVolume{
+				withCapacity(csiPV("pv-csi", "fcd-1"), "10Gi"),
+				withCapacity(inTreePV("pv-intree", "[LocalDS_0] kubevols/pv.vmdk"), "5Gi"),
+			},
+			disks: []types.BaseVirtualDevice{
+				diskWithSize("[LocalDS_0] fcd/fcd-1.vmdk", "fcd-1", 10*gib),
+				diskWithSize("[LocalDS_0] kubevols/pv.vmdk", "", 5*gib),
+			},
+		},
+		{
+			name: "CSI volume not resized",
+			pvs: []*v1.PersistentVolume{
+				withCapacity(csiPV("pv-csi", "fcd-1"), "20Gi"),
+			},
+			disks: []types.BaseVirtualDevice{
+				diskWithSize("[LocalDS_0] fcd/fcd-1.vmdk", "fcd-1", 10*gib),
+			},
+			expectedError: "node DC0_H0_VM0: disk disk-0-1 of PV pv-csi has size 10737418240 bytes, but the PV capacity is 20Gi (21474836480 bytes)",
+			expectedCount: 1,
+		},
+		{
+			name: "in-tree volume larger than PV",
+			pvs: []*v1.PersistentVolume{
+				withCapacity(inTreePV("pv-intree", "[LocalDS_0] kubevols/pv.vmdk"), "5Gi"),
+			},
+			disks: []types.BaseVirtualDevice{
+				diskWithSize("[LocalDS_0] kubevols/pv.vmdk", "", 8*gib),
+			},
+			expectedError: "node DC0_H0_VM0: disk disk-0-1 of PV pv-intree has size 8589934592 bytes, but the PV capacity is 5Gi (5368709120 bytes)",
+			expectedCount: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMDiskSizeVsPVCSize{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+				pvs:   test.pvs,
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			for i, d := range test.disks {
+				// Make the device names stable
+				d.GetVirtualDevice().Key = int32(3000 + i)
+				d.GetVirtualDevice().UnitNumber = types.NewInt32(int32(i + 1))
+				vm.Config.Hardware.Device = append(vm.Config.Hardware.Device, d)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_volume_size_mismatch_total [ALPHA] Number of volumes attached to vSphere node VMs with virtual disk size different from PV capacity.
+# TYPE vsphere_node_volume_size_mismatch_total gauge
+vsphere_node_volume_size_mismatch_total %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_volume_size_mismatch_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package check
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMFaultToleranceState makes sure that Fault Tolerance is not enabled on any node VM.
+// FT doubles resource usage of the VM and it is not supported for OCP nodes.
+type CheckNodeVMFaultToleranceState struct {
+	ftEnabledLock  sync.Mutex
+	ftEnabledCount int
+}
+
+var _ NodeCheck = &CheckNodeVMFaultToleranceState{}
+
+var (
+	ftEnabledMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_ft_enabled_total",
+			Help:           "Number of vSphere node VMs with Fault Tolerance enabled.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(ftEnabledMetric)
+}
+
+func (c *CheckNodeVMFaultToleranceState) Name() string {
+	return "CheckNodeVMFaultToleranceState"
+}
+
+func (c *CheckNodeVMFaultToleranceState) StartCheck() error {
+	c.ftEnabledLock.Lock()
+	defer c.ftEnabledLock.Unlock()
+	c.ftEnabledCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMFaultToleranceState) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	state := vm.Runtime.FaultToleranceState
+	switch state {
+	case "", types.VirtualMachineFaultToleranceStateNotConfigured, types.VirtualMachineFaultToleranceStateDisabled:
+		klog.V(4).Infof("... the node has Fault Tolerance state %q", state)
+		return nil
+	}
+
+	c.ftEnabledLock.Lock()
+	c.ftEnabledCount++
+	c.ftEnabledLock.Unlock()
+	return fmt.Errorf("node %s has Fault Tolerance enabled: runtime.faultToleranceState is %s", node.Name, state)
+}
+
+func (c *CheckNodeVMFaultToleranceState) FinishCheck(ctx *CheckContext) {
+	c.ftEnabledLock.Lock()
+	defer c.ftEnabledLock.Unlock()
+	ftEnabledMetric.WithLabelValues().Set(float64(c.ftEnabledCount))
+	return
+}
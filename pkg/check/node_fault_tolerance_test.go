@@ -0,0 +1,104 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMFaultToleranceState(t *testing.T) {
+	tests := []struct {
+		name            string
+		ftState         string
+		expectError     bool
+		expectedMetrics string
+	}{
+		{
+			name:        "FT not configured",
+			ftState:     "notConfigured",
+			expectError: false,
+			expectedMetrics: `
+# HELP vsphere_node_ft_enabled_total [ALPHA] Number of vSphere node VMs with Fault Tolerance enabled.
+# TYPE vsphere_node_ft_enabled_total gauge
+vsphere_node_ft_enabled_total 0
+`,
+		},
+		{
+			name:        "FT disabled",
+			ftState:     "disabled",
+			expectError: false,
+			expectedMetrics: `
+# HELP vsphere_node_ft_enabled_total [ALPHA] Number of vSphere node VMs with Fault Tolerance enabled.
+# TYPE vsphere_node_ft_enabled_total gauge
+vsphere_node_ft_enabled_total 0
+`,
+		},
+		{
+			name:        "FT running",
+			ftState:     "running",
+			expectError: true,
+			expectedMetrics: `
+# HELP vsphere_node_ft_enabled_total [ALPHA] Number of vSphere node VMs with Fault Tolerance enabled.
+# TYPE vsphere_node_ft_enabled_total gauge
+vsphere_node_ft_enabled_total 1
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMFaultToleranceState{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			// Set FT state of the first VM, the second one keeps the default.
+			node := kubeClient.nodes[0]
+			err = customizeVM(ctx, node, &types.VirtualMachineConfigSpec{
+				ExtraConfig: []types.BaseOptionValue{
+					&types.OptionValue{
+						Key: "SET.runtime.faultToleranceState", Value: test.ftState,
+					},
+				}})
+			if err != nil {
+				t.Fatalf("Failed to customize node: %s", err)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if err != nil && !test.expectError {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if err == nil && test.expectError {
+				t.Errorf("Expected error, got none")
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_node_ft_enabled_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package check
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// extraConfig keys set by vSphere guest customization or read by guest agents that inject ssh keys.
+	// RHCOS nodes get their configuration, including ssh keys, only from Ignition in guestinfo.ignition.config.data.
+	guestCustomizationKeys = []string{
+		// Guest customization package deployed to the guest by VMware Tools.
+		"tools.deployPkg.fileName",
+		// Status of guest customization reported by VMware Tools.
+		"guestinfo.gc.status",
+		// cloud-init metadata and user data, including ssh keys.
+		"guestinfo.metadata",
+		"guestinfo.userdata",
+	}
+
+	guestCustomizationEnabledMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_guest_customization_enabled_total",
+			Help:           "Number of vSphere node VMs with guest customization or ssh key injection through VMware Tools configured.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(guestCustomizationEnabledMetric)
+}
+
+// CheckNodeVMToolsGuestSshKeyInjectionDisabled makes sure that node VMs are not configured for vSphere guest
+// customization or ssh key injection through VMware Tools. RHCOS nodes are configured by Ignition, this
+// configuration indicates that the VM was provisioned in an unsupported way, e.g. cloned from a template with
+// a customization spec.
+type CheckNodeVMToolsGuestSshKeyInjectionDisabled struct {
+	customizationEnabledLock  sync.Mutex
+	customizationEnabledCount int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsGuestSshKeyInjectionDisabled{}
+
+func (c *CheckNodeVMToolsGuestSshKeyInjectionDisabled) Name() string {
+	return "CheckNodeVMToolsGuestSshKeyInjectionDisabled"
+}
+
+func (c *CheckNodeVMToolsGuestSshKeyInjectionDisabled) StartCheck() error {
+	c.customizationEnabledLock.Lock()
+	defer c.customizationEnabledLock.Unlock()
+	c.customizationEnabledCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsGuestSshKeyInjectionDisabled) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil {
+		klog.V(4).Infof("... the node has no configuration")
+		return nil
+	}
+
+	var found []string
+	for _, option := range vm.Config.ExtraConfig {
+		o := option.GetOptionValue()
+		for _, key := range guestCustomizationKeys {
+			if strings.EqualFold(o.Key, key) && strings.TrimSpace(fmt.Sprintf("%v", o.Value)) != "" {
+				found = append(found, key)
+			}
+		}
+	}
+	if len(found) == 0 {
+		klog.V(4).Infof("... the node has no guest customization configured")
+		return nil
+	}
+	sort.Strings(found)
+
+	c.customizationEnabledLock.Lock()
+	c.customizationEnabledCount++
+	c.customizationEnabledLock.Unlock()
+	return fmt.Errorf("node %s has guest customization or ssh key injection configured in extraConfig: %s", node.Name, strings.Join(found, ", "))
+}
+
+func (c *CheckNodeVMToolsGuestSshKeyInjectionDisabled) FinishCheck(ctx *CheckContext) {
+	c.customizationEnabledLock.Lock()
+	defer c.customizationEnabledLock.Unlock()
+	guestCustomizationEnabledMetric.WithLabelValues().Set(float64(c.customizationEnabledCount))
+	return
+}
@@ -0,0 +1,117 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsGuestSshKeyInjectionDisabled(t *testing.T) {
+	tests := []struct {
+		name          string
+		extraConfig   map[string]string
+		expectedCount int
+		expectedError string
+	}{
+		{
+			name: "no extraConfig",
+		},
+		{
+			name: "Ignition config",
+			extraConfig: map[string]string{
+				"guestinfo.ignition.config.data": "e30K",
+			},
+		},
+		{
+			name: "empty cloud-init metadata",
+			extraConfig: map[string]string{
+				"guestinfo.metadata": "",
+			},
+		},
+		{
+			name: "guest customization",
+			extraConfig: map[string]string{
+				"tools.deployPkg.fileName": "imc-cust.cab",
+				"guestinfo.gc.status":      "Successful",
+			},
+			expectedCount: 1,
+			expectedError: "node DC0_H0_VM0 has guest customization or ssh key injection configured in extraConfig: guestinfo.gc.status, tools.deployPkg.fileName",
+		},
+		{
+			name: "cloud-init key injection",
+			extraConfig: map[string]string{
+				"guestinfo.metadata": "aW5zdGFuY2UtaWQ6IG5vZGUK",
+				"guestinfo.userdata": "I2Nsb3VkLWNvbmZpZwo=",
+			},
+			expectedCount: 1,
+			expectedError: "node DC0_H0_VM0 has guest customization or ssh key injection configured in extraConfig: guestinfo.metadata, guestinfo.userdata",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMToolsGuestSshKeyInjectionDisabled{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			if len(test.extraConfig) > 0 {
+				var extraConfig []types.BaseOptionValue
+				for key, value := range test.extraConfig {
+					extraConfig = append(extraConfig, &types.OptionValue{Key: key, Value: value})
+				}
+				err = customizeVM(ctx, node, &types.VirtualMachineConfigSpec{ExtraConfig: extraConfig})
+				if err != nil {
+					t.Fatalf("Failed to customize node: %s", err)
+				}
+			}
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_guest_customization_enabled_total [ALPHA] Number of vSphere node VMs with guest customization or ssh key injection through VMware Tools configured.
+# TYPE vsphere_node_guest_customization_enabled_total gauge
+vsphere_node_guest_customization_enabled_total %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_guest_customization_enabled_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
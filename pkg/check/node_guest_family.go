@@ -0,0 +1,93 @@
+package check
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMToolsGuestFamilyMatch makes sure that the guest OS family reported by VMware Tools
+// matches the operating system of the node. A Linux node with a Windows guest (or vice versa)
+// was most likely created from a wrong template or badly cloned.
+type CheckNodeVMToolsGuestFamilyMatch struct {
+	guestFamilyLock   sync.Mutex
+	guestFamilyCounts map[string]int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsGuestFamilyMatch{}
+
+const (
+	guestFamilyLabel = "guest_family"
+)
+
+var (
+	guestFamilyMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_guest_family_total",
+			Help:           "Number of vSphere node VMs with given guest OS family reported by VMware Tools.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{guestFamilyLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(guestFamilyMetric)
+}
+
+func (c *CheckNodeVMToolsGuestFamilyMatch) Name() string {
+	return "CheckNodeVMToolsGuestFamilyMatch"
+}
+
+func (c *CheckNodeVMToolsGuestFamilyMatch) StartCheck() error {
+	c.guestFamilyLock.Lock()
+	defer c.guestFamilyLock.Unlock()
+	c.guestFamilyCounts = make(map[string]int)
+	return nil
+}
+
+func (c *CheckNodeVMToolsGuestFamilyMatch) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Guest == nil || vm.Guest.GuestFamily == "" {
+		// guest.guestFamily is reported only when VMware Tools are running.
+		klog.V(4).Infof("... the node has unknown guest family, skipping")
+		return nil
+	}
+	family := vm.Guest.GuestFamily
+
+	c.guestFamilyLock.Lock()
+	c.guestFamilyCounts[family]++
+	c.guestFamilyLock.Unlock()
+
+	expectedFamily := getExpectedGuestFamily(node)
+	if family != expectedFamily {
+		return fmt.Errorf("node %s has unexpected guest family: guest.guestFamily is %s, expected %s", node.Name, family, expectedFamily)
+	}
+	klog.V(4).Infof("... the node has guest family %s", family)
+	return nil
+}
+
+func (c *CheckNodeVMToolsGuestFamilyMatch) FinishCheck(ctx *CheckContext) {
+	c.guestFamilyLock.Lock()
+	defer c.guestFamilyLock.Unlock()
+	// Reset series of the vCenter to drop families that are not present any longer.
+	guestFamilyMetric.Reset()
+	for family, count := range c.guestFamilyCounts {
+		guestFamilyMetric.WithLabelValues(family).Set(float64(count))
+	}
+	return
+}
+
+// getExpectedGuestFamily returns guest family that matches the operating system from the node
+// kubernetes.io/os label. Nodes without the label are considered Linux.
+func getExpectedGuestFamily(node *v1.Node) string {
+	if node.Labels[v1.LabelOSStable] == "windows" {
+		return string(types.VirtualMachineGuestOsFamilyWindowsGuest)
+	}
+	return string(types.VirtualMachineGuestOsFamilyLinuxGuest)
+}
@@ -0,0 +1,109 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsGuestFamilyMatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		nodeOS        string
+		guestFamily   types.VirtualMachineGuestOsFamily
+		expectedError string
+	}{
+		{
+			name:        "unknown guest family",
+			guestFamily: "",
+		},
+		{
+			name:        "Linux node",
+			guestFamily: types.VirtualMachineGuestOsFamilyLinuxGuest,
+		},
+		{
+			name:          "Linux node with Windows guest",
+			guestFamily:   types.VirtualMachineGuestOsFamilyWindowsGuest,
+			expectedError: "node DC0_H0_VM0 has unexpected guest family: guest.guestFamily is windowsGuest, expected linuxGuest",
+		},
+		{
+			name:        "Windows node",
+			nodeOS:      "windows",
+			guestFamily: types.VirtualMachineGuestOsFamilyWindowsGuest,
+		},
+		{
+			name:          "Windows node with Linux guest",
+			nodeOS:        "windows",
+			guestFamily:   types.VirtualMachineGuestOsFamilyLinuxGuest,
+			expectedError: "node DC0_H0_VM0 has unexpected guest family: guest.guestFamily is linuxGuest, expected windowsGuest",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMToolsGuestFamilyMatch{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			if test.nodeOS != "" {
+				node.Labels = map[string]string{v1.LabelOSStable: test.nodeOS}
+			}
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			// vcsim does not run VMware Tools, set the guest state directly
+			vm.Guest = &types.GuestInfo{
+				GuestFamily: string(test.guestFamily),
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := ""
+			if test.guestFamily != "" {
+				expectedMetrics = fmt.Sprintf(`
+# HELP vsphere_node_guest_family_total [ALPHA] Number of vSphere node VMs with given guest OS family reported by VMware Tools.
+# TYPE vsphere_node_guest_family_total gauge
+vsphere_node_guest_family_total{guest_family="%s"} 1
+`, test.guestFamily)
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_guest_family_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
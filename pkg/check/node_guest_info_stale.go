@@ -0,0 +1,124 @@
+package check
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// guestInfoStaleTimeout is the time after which unchanged guest info is considered stale.
+	guestInfoStaleTimeout = flag.Duration("guest-info-stale-timeout", time.Hour, "Time after which guest.net and guest.disk of a node VM that did not change while VMware Tools are running are reported as stale.")
+
+	guestInfoStaleMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_guest_info_stale_total",
+			Help:           "Number of vSphere node VMs with stale guest info.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(guestInfoStaleMetric)
+}
+
+// guestInfoSample is the last seen guest info of a node.
+type guestInfoSample struct {
+	// fingerprint of guest.net and guest.disk
+	fingerprint string
+	// time when the fingerprint changed for the last time
+	changed time.Time
+	// seen is true when the node was checked in the current round
+	seen bool
+}
+
+// CheckNodeVMToolsGuestInfoStale makes sure that VMware Tools update guest info of node VMs.
+// vSphere does not report when the guest info was updated, therefore the check remembers when
+// guest.net and guest.disk of each node changed. Guest info that does not change for a long time,
+// while VMware Tools are running, shows that the VMware Tools agent may be hung.
+type CheckNodeVMToolsGuestInfoStale struct {
+	samplesLock sync.Mutex
+	samples     map[string]*guestInfoSample
+	staleCount  int
+	// clock returns the current time, time.Now when nil.
+	clock func() time.Time
+}
+
+var _ NodeCheck = &CheckNodeVMToolsGuestInfoStale{}
+
+func (c *CheckNodeVMToolsGuestInfoStale) Name() string {
+	return "CheckNodeVMToolsGuestInfoStale"
+}
+
+func (c *CheckNodeVMToolsGuestInfoStale) StartCheck() error {
+	c.samplesLock.Lock()
+	defer c.samplesLock.Unlock()
+	if c.samples == nil {
+		c.samples = make(map[string]*guestInfoSample)
+	}
+	for _, sample := range c.samples {
+		sample.seen = false
+	}
+	c.staleCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsGuestInfoStale) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	c.samplesLock.Lock()
+	defer c.samplesLock.Unlock()
+
+	if vm.Guest == nil || vm.Guest.ToolsRunningStatus != string(types.VirtualMachineToolsRunningStatusGuestToolsRunning) {
+		// Guest info is not updated when VMware Tools are not running, start again when they run.
+		klog.V(4).Infof("... VMware Tools are not running on the node, skipping guest info check")
+		delete(c.samples, node.Name)
+		return nil
+	}
+
+	now := c.now()
+	fingerprint := fmt.Sprintf("%+v %+v", vm.Guest.Net, vm.Guest.Disk)
+	sample, found := c.samples[node.Name]
+	if !found || sample.fingerprint != fingerprint {
+		c.samples[node.Name] = &guestInfoSample{fingerprint: fingerprint, changed: now, seen: true}
+		klog.V(4).Infof("... the node has fresh guest info")
+		return nil
+	}
+	sample.seen = true
+
+	unchanged := now.Sub(sample.changed)
+	if unchanged < *guestInfoStaleTimeout {
+		klog.V(4).Infof("... the node has guest info unchanged for %s", unchanged)
+		return nil
+	}
+	c.staleCount++
+	return fmt.Errorf("node %s has stale guest info: guest.net and guest.disk did not change since %s while VMware Tools are running", node.Name, sample.changed.Format(time.RFC3339))
+}
+
+func (c *CheckNodeVMToolsGuestInfoStale) FinishCheck(ctx *CheckContext) {
+	c.samplesLock.Lock()
+	defer c.samplesLock.Unlock()
+	// Forget nodes that were not checked
+	for name, sample := range c.samples {
+		if !sample.seen {
+			delete(c.samples, name)
+		}
+	}
+	guestInfoStaleMetric.WithLabelValues().Set(float64(c.staleCount))
+	return
+}
+
+func (c *CheckNodeVMToolsGuestInfoStale) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	return time.Now()
+}
@@ -0,0 +1,178 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsGuestInfoStale(t *testing.T) {
+	initialGuest := func(toolsRunningStatus types.VirtualMachineToolsRunningStatus) *types.GuestInfo {
+		return &types.GuestInfo{
+			ToolsRunningStatus: string(toolsRunningStatus),
+			Net: []types.GuestNicInfo{
+				{Network: "VM Network", IpAddress: []string{"10.0.0.10"}, Connected: true},
+			},
+			Disk: []types.GuestDiskInfo{
+				{DiskPath: "/", Capacity: 100 * gib, FreeSpace: 50 * gib},
+			},
+		}
+	}
+
+	tests := []struct {
+		name               string
+		toolsRunningStatus types.VirtualMachineToolsRunningStatus
+		elapsed            time.Duration
+		updateGuest        func(guest *types.GuestInfo)
+		expectError        bool
+	}{
+		{
+			name:               "tools not running",
+			toolsRunningStatus: types.VirtualMachineToolsRunningStatusGuestToolsNotRunning,
+			elapsed:            2 * time.Hour,
+			expectError:        false,
+		},
+		{
+			name:               "unchanged guest info within timeout",
+			toolsRunningStatus: types.VirtualMachineToolsRunningStatusGuestToolsRunning,
+			elapsed:            30 * time.Minute,
+			expectError:        false,
+		},
+		{
+			name:               "guest disk updated",
+			toolsRunningStatus: types.VirtualMachineToolsRunningStatusGuestToolsRunning,
+			elapsed:            2 * time.Hour,
+			updateGuest: func(guest *types.GuestInfo) {
+				guest.Disk[0].FreeSpace = 49 * gib
+			},
+			expectError: false,
+		},
+		{
+			name:               "guest net updated",
+			toolsRunningStatus: types.VirtualMachineToolsRunningStatusGuestToolsRunning,
+			elapsed:            2 * time.Hour,
+			updateGuest: func(guest *types.GuestInfo) {
+				guest.Net[0].IpAddress = []string{"10.0.0.11"}
+			},
+			expectError: false,
+		},
+		{
+			name:               "stale guest info",
+			toolsRunningStatus: types.VirtualMachineToolsRunningStatusGuestToolsRunning,
+			elapsed:            2 * time.Hour,
+			expectError:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+			check := CheckNodeVMToolsGuestInfoStale{
+				clock: func() time.Time { return now },
+			}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			// vcsim does not run VMware Tools, set the guest state directly
+			vm.Guest = initialGuest(test.toolsRunningStatus)
+
+			// The first round records the guest info
+			if err := check.StartCheck(); err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			if err := check.CheckNode(ctx, node, vm); err != nil {
+				t.Errorf("Unexpected error in the first round: %s", err)
+			}
+			check.FinishCheck(ctx)
+
+			now = now.Add(test.elapsed)
+			vm.Guest = initialGuest(test.toolsRunningStatus)
+			if test.updateGuest != nil {
+				test.updateGuest(vm.Guest)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if err != nil && !test.expectError {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if err == nil && test.expectError {
+				t.Errorf("Expected error, got none")
+			}
+			expectedCount := 0
+			if test.expectError {
+				expectedCount = 1
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_guest_info_stale_total [ALPHA] Number of vSphere node VMs with stale guest info.
+# TYPE vsphere_node_guest_info_stale_total gauge
+vsphere_node_guest_info_stale_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_guest_info_stale_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+func TestCheckNodeVMToolsGuestInfoStaleForgetsNodes(t *testing.T) {
+	// Stage
+	check := CheckNodeVMToolsGuestInfoStale{}
+	kubeClient := &fakeKubeClient{
+		nodes: defaultNodes(),
+	}
+	ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+
+	node := kubeClient.nodes[0]
+	vm, err := getVM(ctx, node)
+	if err != nil {
+		t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+	}
+	// vcsim does not run VMware Tools, set the guest state directly
+	vm.Guest = &types.GuestInfo{
+		ToolsRunningStatus: string(types.VirtualMachineToolsRunningStatusGuestToolsRunning),
+	}
+	check.StartCheck()
+	check.CheckNode(ctx, node, vm)
+	check.FinishCheck(ctx)
+
+	// Act: a round without the node
+	check.StartCheck()
+	check.FinishCheck(ctx)
+
+	// Assert
+	if len(check.samples) != 0 {
+		t.Errorf("Expected no remembered nodes, got %d", len(check.samples))
+	}
+}
@@ -0,0 +1,72 @@
+package check
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMToolsGuestOpsEnabled makes sure that guest operations are permitted on node VMs.
+// CSI driver and node lifecycle operations that rely on guest operations fail without them.
+type CheckNodeVMToolsGuestOpsEnabled struct {
+	guestOpsDisabledLock  sync.Mutex
+	guestOpsDisabledCount int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsGuestOpsEnabled{}
+
+var (
+	guestOpsDisabledMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_guest_ops_disabled_total",
+			Help:           "Number of vSphere node VMs with guest operations disabled.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(guestOpsDisabledMetric)
+}
+
+func (c *CheckNodeVMToolsGuestOpsEnabled) Name() string {
+	return "CheckNodeVMToolsGuestOpsEnabled"
+}
+
+func (c *CheckNodeVMToolsGuestOpsEnabled) StartCheck() error {
+	c.guestOpsDisabledLock.Lock()
+	defer c.guestOpsDisabledLock.Unlock()
+	c.guestOpsDisabledCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsGuestOpsEnabled) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Guest == nil || vm.Guest.ToolsRunningStatus != string(types.VirtualMachineToolsRunningStatusGuestToolsRunning) {
+		// guestOperationsReady is meaningful only when VMware Tools run in the guest.
+		klog.V(4).Infof("... VMware Tools are not running on the node, skipping guest operations check")
+		return nil
+	}
+	if vm.Guest.GuestOperationsReady != nil && *vm.Guest.GuestOperationsReady {
+		klog.V(4).Infof("... the node has guest operations ready")
+		return nil
+	}
+
+	c.guestOpsDisabledLock.Lock()
+	c.guestOpsDisabledCount++
+	c.guestOpsDisabledLock.Unlock()
+	return fmt.Errorf("node %s has guest operations disabled: guest.guestOperationsReady is false", node.Name)
+}
+
+func (c *CheckNodeVMToolsGuestOpsEnabled) FinishCheck(ctx *CheckContext) {
+	c.guestOpsDisabledLock.Lock()
+	defer c.guestOpsDisabledLock.Unlock()
+	guestOpsDisabledMetric.WithLabelValues().Set(float64(c.guestOpsDisabledCount))
+	return
+}
@@ -0,0 +1,102 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Prefix of DescriptionId of tasks of GuestOperationsManager.
+	guestOperationTaskDescriptionIDPrefix = "vm.guest."
+)
+
+var (
+	guestOpsRateLimitedMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_guest_ops_rate_limited_total",
+			Help:           "Number of recent guest operations of a vSphere node VM that failed because vCenter rate limited them.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{nodeLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(guestOpsRateLimitedMetric)
+}
+
+// CheckNodeVMToolsOperationsRateLimited makes sure that recent guest operations of node VMs
+// were not rate limited by vCenter. vCenter reports rate limited operations as "resource in use"
+// faults and node lifecycle operations that use them slow down.
+type CheckNodeVMToolsOperationsRateLimited struct{}
+
+var _ NodeCheck = &CheckNodeVMToolsOperationsRateLimited{}
+
+func (c *CheckNodeVMToolsOperationsRateLimited) Name() string {
+	return "CheckNodeVMToolsOperationsRateLimited"
+}
+
+func (c *CheckNodeVMToolsOperationsRateLimited) StartCheck() error {
+	// Drop nodes that do not exist any longer.
+	guestOpsRateLimitedMetric.Reset()
+	return nil
+}
+
+func (c *CheckNodeVMToolsOperationsRateLimited) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if len(vm.RecentTask) == 0 {
+		klog.V(4).Infof("... the node has no recent tasks")
+		guestOpsRateLimitedMetric.WithLabelValues(node.Name).Set(0)
+		return nil
+	}
+
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var tasks []mo.Task
+	pc := property.DefaultCollector(ctx.VMClient)
+	if err := pc.Retrieve(tctx, vm.RecentTask, []string{"info"}, &tasks); err != nil {
+		return fmt.Errorf("failed to get recent tasks of node %s: %s", node.Name, err)
+	}
+
+	guestOps, rateLimited := 0, 0
+	for _, task := range tasks {
+		info := task.Info
+		if !isGuestOperationTask(info.DescriptionId) {
+			continue
+		}
+		if info.State != types.TaskInfoStateSuccess && info.State != types.TaskInfoStateError {
+			continue
+		}
+		guestOps++
+		if info.Error == nil {
+			continue
+		}
+		if _, ok := info.Error.Fault.(*types.ResourceInUse); ok {
+			rateLimited++
+		}
+	}
+	guestOpsRateLimitedMetric.WithLabelValues(node.Name).Set(float64(rateLimited))
+
+	if rateLimited == 0 {
+		klog.V(4).Infof("... the node has %d recent guest operations, none rate limited", guestOps)
+		return nil
+	}
+	return fmt.Errorf("node %s has %d of %d recent guest operations rate limited by vCenter (%.0f%%)", node.Name, rateLimited, guestOps, float64(rateLimited)*100/float64(guestOps))
+}
+
+func (c *CheckNodeVMToolsOperationsRateLimited) FinishCheck(ctx *CheckContext) {
+	return
+}
+
+// isGuestOperationTask returns true if the task with given DescriptionId runs a guest operation.
+func isGuestOperationTask(descriptionID string) bool {
+	return strings.HasPrefix(descriptionID, guestOperationTaskDescriptionIDPrefix) || descriptionID == upgradeToolsTaskDescriptionID
+}
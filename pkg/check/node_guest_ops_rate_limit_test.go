@@ -0,0 +1,140 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsOperationsRateLimited(t *testing.T) {
+	type task struct {
+		descriptionID string
+		fault         types.BaseMethodFault
+	}
+	tests := []struct {
+		name            string
+		tasks           []task
+		expectError     string
+		expectedMetrics string
+	}{
+		{
+			name: "no recent tasks",
+			expectedMetrics: `
+# HELP vsphere_node_guest_ops_rate_limited_total [ALPHA] Number of recent guest operations of a vSphere node VM that failed because vCenter rate limited them.
+# TYPE vsphere_node_guest_ops_rate_limited_total gauge
+vsphere_node_guest_ops_rate_limited_total{node="DC0_H0_VM0"} 0
+`,
+		},
+		{
+			name: "successful guest operations",
+			tasks: []task{
+				{descriptionID: "vm.guest.ProcessManager.startProgram"},
+				{descriptionID: upgradeToolsTaskDescriptionID},
+			},
+			expectedMetrics: `
+# HELP vsphere_node_guest_ops_rate_limited_total [ALPHA] Number of recent guest operations of a vSphere node VM that failed because vCenter rate limited them.
+# TYPE vsphere_node_guest_ops_rate_limited_total gauge
+vsphere_node_guest_ops_rate_limited_total{node="DC0_H0_VM0"} 0
+`,
+		},
+		{
+			name: "other task rate limited",
+			tasks: []task{
+				{descriptionID: "VirtualMachine.reconfigure", fault: &types.ResourceInUse{}},
+			},
+			expectedMetrics: `
+# HELP vsphere_node_guest_ops_rate_limited_total [ALPHA] Number of recent guest operations of a vSphere node VM that failed because vCenter rate limited them.
+# TYPE vsphere_node_guest_ops_rate_limited_total gauge
+vsphere_node_guest_ops_rate_limited_total{node="DC0_H0_VM0"} 0
+`,
+		},
+		{
+			name: "guest operation failed with other fault",
+			tasks: []task{
+				{descriptionID: "vm.guest.FileManager.initiateFileTransferToGuest", fault: &types.GuestOperationsUnavailable{}},
+			},
+			expectedMetrics: `
+# HELP vsphere_node_guest_ops_rate_limited_total [ALPHA] Number of recent guest operations of a vSphere node VM that failed because vCenter rate limited them.
+# TYPE vsphere_node_guest_ops_rate_limited_total gauge
+vsphere_node_guest_ops_rate_limited_total{node="DC0_H0_VM0"} 0
+`,
+		},
+		{
+			name: "guest operations rate limited",
+			tasks: []task{
+				{descriptionID: "vm.guest.ProcessManager.startProgram", fault: &types.ResourceInUse{}},
+				{descriptionID: "vm.guest.ProcessManager.startProgram", fault: &types.ResourceInUse{}},
+				{descriptionID: "vm.guest.ProcessManager.startProgram"},
+				{descriptionID: upgradeToolsTaskDescriptionID},
+			},
+			expectError: "node DC0_H0_VM0 has 2 of 4 recent guest operations rate limited by vCenter (50%)",
+			expectedMetrics: `
+# HELP vsphere_node_guest_ops_rate_limited_total [ALPHA] Number of recent guest operations of a vSphere node VM that failed because vCenter rate limited them.
+# TYPE vsphere_node_guest_ops_rate_limited_total gauge
+vsphere_node_guest_ops_rate_limited_total{node="DC0_H0_VM0"} 2
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMToolsOperationsRateLimited{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			for _, tt := range test.tasks {
+				// vcsim does not track recent tasks of VMs, create the task directly
+				task := simulator.CreateTask(vm, "task", nil)
+				task.Info.DescriptionId = tt.descriptionID
+				task.Info.State = types.TaskInfoStateSuccess
+				if tt.fault != nil {
+					task.Info.State = types.TaskInfoStateError
+					task.Info.Error = &types.LocalizedMethodFault{Fault: tt.fault}
+				}
+				vm.RecentTask = append(vm.RecentTask, task.Self)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectError == "" && err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if test.expectError != "" {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectError)
+				} else if err.Error() != test.expectError {
+					t.Errorf("Expected error %q, got %q", test.expectError, err.Error())
+				}
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_node_guest_ops_rate_limited_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
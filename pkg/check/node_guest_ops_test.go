@@ -0,0 +1,95 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsGuestOpsEnabled(t *testing.T) {
+	tests := []struct {
+		name                 string
+		toolsRunningStatus   types.VirtualMachineToolsRunningStatus
+		guestOperationsReady *bool
+		expectError          bool
+	}{
+		{
+			name:               "tools not running",
+			toolsRunningStatus: types.VirtualMachineToolsRunningStatusGuestToolsNotRunning,
+			expectError:        false,
+		},
+		{
+			name:                 "guest operations ready",
+			toolsRunningStatus:   types.VirtualMachineToolsRunningStatusGuestToolsRunning,
+			guestOperationsReady: types.NewBool(true),
+			expectError:          false,
+		},
+		{
+			name:                 "guest operations disabled",
+			toolsRunningStatus:   types.VirtualMachineToolsRunningStatusGuestToolsRunning,
+			guestOperationsReady: types.NewBool(false),
+			expectError:          true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMToolsGuestOpsEnabled{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			// vcsim does not run VMware Tools, set the guest state directly
+			vm.Guest = &types.GuestInfo{
+				ToolsRunningStatus:   string(test.toolsRunningStatus),
+				GuestOperationsReady: test.guestOperationsReady,
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if err != nil && !test.expectError {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if err == nil && test.expectError {
+				t.Errorf("Expected error, got none")
+			}
+			expectedCount := 0
+			if test.expectError {
+				expectedCount = 1
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_guest_ops_disabled_total [ALPHA] Number of vSphere node VMs with guest operations disabled.
+# TYPE vsphere_node_guest_ops_disabled_total gauge
+vsphere_node_guest_ops_disabled_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_guest_ops_disabled_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package check
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// guestOperationTimeout is the time after which a running guest operation is considered timed out.
+	guestOperationTimeout = flag.Duration("guest-operation-timeout", 5*time.Minute, "Time after which an unfinished guest operation of a node VM is counted as timed out.")
+	// guestOperationTimeoutThreshold is the number of timed out guest operations of a node VM that is reported.
+	guestOperationTimeoutThreshold = flag.Int("guest-operation-timeout-threshold", 2, "Number of recent guest operations of a node VM that timed out, at which the node is reported.")
+
+	guestOpsTimeoutsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_guest_ops_timeouts_total",
+			Help:           "Number of recent guest operations of a vSphere node VM that timed out or are running for too long.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{nodeLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(guestOpsTimeoutsMetric)
+}
+
+// CheckNodeVMToolsOperationTimeoutHistory makes sure that recent guest operations of node VMs do not
+// time out repeatedly. Guest operations that fail with a timeout or that run longer than guest-operation-timeout
+// indicate a hung VMware Tools agent, which breaks node drains and operations of the CSI driver in the guest.
+// A single timeout is not reported, only guest-operation-timeout-threshold or more of them.
+type CheckNodeVMToolsOperationTimeoutHistory struct{}
+
+var _ NodeCheck = &CheckNodeVMToolsOperationTimeoutHistory{}
+
+func (c *CheckNodeVMToolsOperationTimeoutHistory) Name() string {
+	return "CheckNodeVMToolsOperationTimeoutHistory"
+}
+
+func (c *CheckNodeVMToolsOperationTimeoutHistory) StartCheck() error {
+	// Drop nodes that do not exist any longer.
+	guestOpsTimeoutsMetric.Reset()
+	return nil
+}
+
+func (c *CheckNodeVMToolsOperationTimeoutHistory) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if len(vm.RecentTask) == 0 {
+		klog.V(4).Infof("... the node has no recent tasks")
+		guestOpsTimeoutsMetric.WithLabelValues(node.Name).Set(0)
+		return nil
+	}
+
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var tasks []mo.Task
+	pc := property.DefaultCollector(ctx.VMClient)
+	if err := pc.Retrieve(tctx, vm.RecentTask, []string{"info"}, &tasks); err != nil {
+		return fmt.Errorf("failed to get recent tasks of node %s: %s", node.Name, err)
+	}
+
+	now := time.Now()
+	guestOps, timeouts := 0, 0
+	for _, task := range tasks {
+		info := task.Info
+		if !strings.HasPrefix(info.DescriptionId, guestOperationTaskDescriptionIDPrefix) {
+			continue
+		}
+		guestOps++
+		if isGuestOperationTimedOut(info, now) {
+			timeouts++
+		}
+	}
+	guestOpsTimeoutsMetric.WithLabelValues(node.Name).Set(float64(timeouts))
+
+	if timeouts < *guestOperationTimeoutThreshold {
+		klog.V(4).Infof("... the node has %d recent guest operations, %d timed out", guestOps, timeouts)
+		return nil
+	}
+	return fmt.Errorf("node %s has %d of %d recent guest operations timed out, VMware Tools in the guest may be hung", node.Name, timeouts, guestOps)
+}
+
+func (c *CheckNodeVMToolsOperationTimeoutHistory) FinishCheck(ctx *CheckContext) {
+	return
+}
+
+// isGuestOperationTimedOut returns true if the guest operation task failed with a timeout or it is
+// queued or running for longer than guest-operation-timeout.
+func isGuestOperationTimedOut(info types.TaskInfo, now time.Time) bool {
+	switch info.State {
+	case types.TaskInfoStateError:
+		if info.Error == nil {
+			return false
+		}
+		_, ok := info.Error.Fault.(*types.Timedout)
+		return ok
+	case types.TaskInfoStateRunning, types.TaskInfoStateQueued:
+		started := info.QueueTime
+		if info.StartTime != nil {
+			started = *info.StartTime
+		}
+		return now.Sub(started) >= *guestOperationTimeout
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsOperationTimeoutHistory(t *testing.T) {
+	type task struct {
+		descriptionID string
+		state         types.TaskInfoState
+		fault         types.BaseMethodFault
+		// age of a running task
+		age time.Duration
+	}
+	tests := []struct {
+		name          string
+		tasks         []task
+		expectError   string
+		expectedCount int
+	}{
+		{
+			name: "no recent tasks",
+		},
+		{
+			name: "successful guest operations",
+			tasks: []task{
+				{descriptionID: "vm.guest.ProcessManager.startProgram", state: types.TaskInfoStateSuccess},
+				{descriptionID: "vm.guest.ProcessManager.listProcesses", state: types.TaskInfoStateRunning, age: time.Minute},
+			},
+		},
+		{
+			name: "single timeout",
+			tasks: []task{
+				{descriptionID: "vm.guest.ProcessManager.startProgram", state: types.TaskInfoStateError, fault: &types.Timedout{}},
+				{descriptionID: "vm.guest.ProcessManager.startProgram", state: types.TaskInfoStateSuccess},
+			},
+			expectedCount: 1,
+		},
+		{
+			name: "other tasks timed out",
+			tasks: []task{
+				{descriptionID: "VirtualMachine.reconfigure", state: types.TaskInfoStateError, fault: &types.Timedout{}},
+				{descriptionID: upgradeToolsTaskDescriptionID, state: types.TaskInfoStateRunning, age: time.Hour},
+			},
+		},
+		{
+			name: "guest operation failed with other fault",
+			tasks: []task{
+				{descriptionID: "vm.guest.FileManager.initiateFileTransferToGuest", state: types.TaskInfoStateError, fault: &types.GuestOperationsUnavailable{}},
+				{descriptionID: "vm.guest.FileManager.initiateFileTransferToGuest", state: types.TaskInfoStateError, fault: &types.GuestOperationsUnavailable{}},
+			},
+		},
+		{
+			name: "repeated timeouts",
+			tasks: []task{
+				{descriptionID: "vm.guest.ProcessManager.startProgram", state: types.TaskInfoStateError, fault: &types.Timedout{}},
+				{descriptionID: "vm.guest.ProcessManager.startProgram", state: types.TaskInfoStateSuccess},
+				{descriptionID: "vm.guest.ProcessManager.listProcesses", state: types.TaskInfoStateRunning, age: time.Hour},
+			},
+			expectError:   "node DC0_H0_VM0 has 2 of 3 recent guest operations timed out, VMware Tools in the guest may be hung",
+			expectedCount: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMToolsOperationTimeoutHistory{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			for _, tt := range test.tasks {
+				// vcsim does not track recent tasks of VMs, create the task directly
+				task := simulator.CreateTask(vm, "task", nil)
+				task.Info.DescriptionId = tt.descriptionID
+				task.Info.State = tt.state
+				if tt.fault != nil {
+					task.Info.Error = &types.LocalizedMethodFault{Fault: tt.fault}
+				}
+				if tt.age != 0 {
+					started := time.Now().Add(-tt.age)
+					task.Info.QueueTime = started
+					task.Info.StartTime = &started
+				}
+				vm.RecentTask = append(vm.RecentTask, task.Self)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectError == "" && err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if test.expectError != "" {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectError)
+				} else if err.Error() != test.expectError {
+					t.Errorf("Expected error %q, got %q", test.expectError, err.Error())
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_guest_ops_timeouts_total [ALPHA] Number of recent guest operations of a vSphere node VM that timed out or are running for too long.
+# TYPE vsphere_node_guest_ops_timeouts_total gauge
+vsphere_node_guest_ops_timeouts_total{node="DC0_H0_VM0"} %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_guest_ops_timeouts_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package check
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	guestRebootPendingMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_guest_reboot_pending_total",
+			Help:           "Number of vSphere node VMs whose guest reports a pending reboot through VMware Tools.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(guestRebootPendingMetric)
+}
+
+// CheckNodeVMToolsGuestRebootPending makes sure that no node VM has a guest reboot pending.
+// VMware Tools report the guest as resetting from the time a reboot was requested until the guest
+// runs again, a node that stays there may be in an inconsistent state in the middle of an update.
+// Nodes whose VMware Tools do not report guest state changes are skipped.
+type CheckNodeVMToolsGuestRebootPending struct {
+	rebootPendingLock  sync.Mutex
+	rebootPendingCount int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsGuestRebootPending{}
+
+func (c *CheckNodeVMToolsGuestRebootPending) Name() string {
+	return "CheckNodeVMToolsGuestRebootPending"
+}
+
+func (c *CheckNodeVMToolsGuestRebootPending) StartCheck() error {
+	c.rebootPendingLock.Lock()
+	defer c.rebootPendingLock.Unlock()
+	c.rebootPendingCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsGuestRebootPending) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Guest == nil || vm.Guest.ToolsRunningStatus != string(types.VirtualMachineToolsRunningStatusGuestToolsRunning) {
+		klog.V(4).Infof("... VMware Tools are not running on the node, skipping")
+		return nil
+	}
+	if vm.Guest.GuestStateChangeSupported == nil || !*vm.Guest.GuestStateChangeSupported {
+		klog.V(4).Infof("... VMware Tools of the node do not report guest state changes, skipping")
+		return nil
+	}
+	if vm.Guest.GuestState != string(types.VirtualMachineGuestStateResetting) {
+		klog.V(4).Infof("... the node has guest state %q", vm.Guest.GuestState)
+		return nil
+	}
+
+	c.rebootPendingLock.Lock()
+	c.rebootPendingCount++
+	c.rebootPendingLock.Unlock()
+	return fmt.Errorf("node %s has a guest reboot pending: guest.guestState is %s", node.Name, vm.Guest.GuestState)
+}
+
+func (c *CheckNodeVMToolsGuestRebootPending) FinishCheck(ctx *CheckContext) {
+	c.rebootPendingLock.Lock()
+	defer c.rebootPendingLock.Unlock()
+	guestRebootPendingMetric.WithLabelValues().Set(float64(c.rebootPendingCount))
+	return
+}
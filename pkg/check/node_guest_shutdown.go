@@ -0,0 +1,73 @@
+package check
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMToolsUnattendedShutdownCapability makes sure that node VMs can be shut down gracefully
+// through VMware Tools. Without it, shutdown of a node ends with a hard power off.
+type CheckNodeVMToolsUnattendedShutdownCapability struct {
+	noShutdownLock  sync.Mutex
+	noShutdownCount int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsUnattendedShutdownCapability{}
+
+var (
+	noGuestShutdownMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_guest_shutdown_unsupported_total",
+			Help:           "Number of vSphere node VMs that cannot be shut down gracefully through VMware Tools.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(noGuestShutdownMetric)
+}
+
+func (c *CheckNodeVMToolsUnattendedShutdownCapability) Name() string {
+	return "CheckNodeVMToolsUnattendedShutdownCapability"
+}
+
+func (c *CheckNodeVMToolsUnattendedShutdownCapability) StartCheck() error {
+	c.noShutdownLock.Lock()
+	defer c.noShutdownLock.Unlock()
+	c.noShutdownCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsUnattendedShutdownCapability) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	var reason string
+	switch {
+	case vm.Guest == nil || vm.Guest.ToolsRunningStatus != string(types.VirtualMachineToolsRunningStatusGuestToolsRunning):
+		reason = "VMware Tools are not running"
+	case vm.Guest.GuestStateChangeSupported == nil || !*vm.Guest.GuestStateChangeSupported:
+		reason = "guest.guestStateChangeSupported is false"
+	default:
+		klog.V(4).Infof("... the node supports guest shutdown")
+		return nil
+	}
+
+	c.noShutdownLock.Lock()
+	c.noShutdownCount++
+	c.noShutdownLock.Unlock()
+	return fmt.Errorf("node %s cannot be shut down gracefully: %s", node.Name, reason)
+}
+
+func (c *CheckNodeVMToolsUnattendedShutdownCapability) FinishCheck(ctx *CheckContext) {
+	c.noShutdownLock.Lock()
+	defer c.noShutdownLock.Unlock()
+	noGuestShutdownMetric.WithLabelValues().Set(float64(c.noShutdownCount))
+	return
+}
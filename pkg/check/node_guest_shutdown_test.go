@@ -0,0 +1,98 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsUnattendedShutdownCapability(t *testing.T) {
+	tests := []struct {
+		name                      string
+		toolsRunningStatus        types.VirtualMachineToolsRunningStatus
+		guestStateChangeSupported *bool
+		expectedError             string
+	}{
+		{
+			name:                      "tools not running",
+			toolsRunningStatus:        types.VirtualMachineToolsRunningStatusGuestToolsNotRunning,
+			guestStateChangeSupported: types.NewBool(true),
+			expectedError:             "node DC0_H0_VM0 cannot be shut down gracefully: VMware Tools are not running",
+		},
+		{
+			name:                      "shutdown supported",
+			toolsRunningStatus:        types.VirtualMachineToolsRunningStatusGuestToolsRunning,
+			guestStateChangeSupported: types.NewBool(true),
+		},
+		{
+			name:                      "shutdown not supported",
+			toolsRunningStatus:        types.VirtualMachineToolsRunningStatusGuestToolsRunning,
+			guestStateChangeSupported: types.NewBool(false),
+			expectedError:             "node DC0_H0_VM0 cannot be shut down gracefully: guest.guestStateChangeSupported is false",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMToolsUnattendedShutdownCapability{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			// vcsim does not run VMware Tools, set the guest state directly
+			vm.Guest = &types.GuestInfo{
+				ToolsRunningStatus:        string(test.toolsRunningStatus),
+				GuestStateChangeSupported: test.guestStateChangeSupported,
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_guest_shutdown_unsupported_total [ALPHA] Number of vSphere node VMs that cannot be shut down gracefully through VMware Tools.
+# TYPE vsphere_node_guest_shutdown_unsupported_total gauge
+vsphere_node_guest_shutdown_unsupported_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_guest_shutdown_unsupported_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	homeDatastoreZoneMismatchMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_home_datastore_zone_mismatch_total",
+			Help:           "Number of vSphere node VMs with VM home on a datastore with zone tags that do not match the zone of the node.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(homeDatastoreZoneMismatchMetric)
+}
+
+// CheckNodeVMConfigFileDatastoreMatchesExpectedZone makes sure that the VM home (the .vmx file) of node VMs
+// is on a datastore in the zone of the node. The zone of the datastore is its tag in the zone tag category
+// from vSphere configuration, datastores without a zone tag and nodes without a zone label are not checked.
+// A node VM with its home in another zone does not survive a failure of that zone, even when its disks do.
+type CheckNodeVMConfigFileDatastoreMatchesExpectedZone struct {
+	mismatchLock  sync.Mutex
+	mismatchCount int
+	// zoneTags caches tags of the zone category for the current round of checks, tag ID -> tag name.
+	zoneTags map[string]string
+}
+
+var _ NodeCheck = &CheckNodeVMConfigFileDatastoreMatchesExpectedZone{}
+
+func (c *CheckNodeVMConfigFileDatastoreMatchesExpectedZone) Name() string {
+	return "CheckNodeVMConfigFileDatastoreMatchesExpectedZone"
+}
+
+func (c *CheckNodeVMConfigFileDatastoreMatchesExpectedZone) StartCheck() error {
+	c.mismatchLock.Lock()
+	defer c.mismatchLock.Unlock()
+	c.mismatchCount = 0
+	c.zoneTags = nil
+	return nil
+}
+
+func (c *CheckNodeVMConfigFileDatastoreMatchesExpectedZone) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	zoneCategory := ctx.VMConfig.Labels.Zone
+	if zoneCategory == "" {
+		klog.V(4).Infof("... zone tag category is not configured, skipping")
+		return nil
+	}
+	nodeZone := getNodeZone(node)
+	if nodeZone == "" {
+		klog.V(4).Infof("... the node has no zone label, skipping")
+		return nil
+	}
+	if ctx.TagManager == nil {
+		klog.V(4).Infof("... vSphere tags are not available, skipping")
+		return nil
+	}
+	if vm.Config == nil {
+		return fmt.Errorf("error getting VM home of node %s: vm.config is empty", node.Name)
+	}
+	var home object.DatastorePath
+	if !home.FromString(vm.Config.Files.VmPathName) {
+		return fmt.Errorf("error getting VM home of node %s: failed to parse vmPathName %q", node.Name, vm.Config.Files.VmPathName)
+	}
+
+	zoneTags, err := c.getZoneTags(ctx, zoneCategory)
+	if err != nil {
+		return err
+	}
+	dc, err := getDatacenter(ctx, ctx.VMConfig.Workspace.Datacenter)
+	if err != nil {
+		return err
+	}
+	ds, err := getDataStoreByName(ctx, home.Datastore, dc)
+	if err != nil {
+		return err
+	}
+	objectZones, err := getAttachedZones(ctx, []mo.Reference{ds.Reference()}, zoneTags)
+	if err != nil {
+		return err
+	}
+	dsZones := objectZones[ds.Reference()]
+	if len(dsZones) == 0 {
+		klog.V(4).Infof("... the node has VM home on datastore %s without zone tag", home.Datastore)
+		return nil
+	}
+	for _, zone := range dsZones {
+		if zone == nodeZone {
+			klog.V(4).Infof("... the node has VM home on datastore %s in zone %s", home.Datastore, nodeZone)
+			return nil
+		}
+	}
+
+	c.mismatchLock.Lock()
+	c.mismatchCount++
+	c.mismatchLock.Unlock()
+	return fmt.Errorf("node %s in zone %s has VM home on datastore %s in zone %s", node.Name, nodeZone, home.Datastore, strings.Join(dsZones, ", "))
+}
+
+func (c *CheckNodeVMConfigFileDatastoreMatchesExpectedZone) FinishCheck(ctx *CheckContext) {
+	c.mismatchLock.Lock()
+	defer c.mismatchLock.Unlock()
+	homeDatastoreZoneMismatchMetric.WithLabelValues().Set(float64(c.mismatchCount))
+	return
+}
+
+// getZoneTags returns tags of the zone category, they are read from vSphere once per round of checks.
+func (c *CheckNodeVMConfigFileDatastoreMatchesExpectedZone) getZoneTags(ctx *CheckContext, zoneCategory string) (map[string]string, error) {
+	c.mismatchLock.Lock()
+	defer c.mismatchLock.Unlock()
+	if c.zoneTags != nil {
+		return c.zoneTags, nil
+	}
+	zoneTags, err := getCategoryTags(ctx, zoneCategory)
+	if err != nil {
+		return nil, err
+	}
+	c.zoneTags = zoneTags
+	return zoneTags, nil
+}
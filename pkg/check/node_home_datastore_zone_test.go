@@ -0,0 +1,130 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMConfigFileDatastoreMatchesExpectedZone(t *testing.T) {
+	tests := []struct {
+		name           string
+		zoneCategory   string
+		nodeLabels     map[string]string
+		datastoreZones []string
+		expectedError  string
+	}{
+		{
+			name:           "zones not configured",
+			nodeLabels:     map[string]string{v1.LabelTopologyZone: "zone-a"},
+			datastoreZones: []string{"zone-b"},
+		},
+		{
+			name:           "node without zone",
+			zoneCategory:   "k8s-zone",
+			datastoreZones: []string{"zone-b"},
+		},
+		{
+			name:         "datastore without zone",
+			zoneCategory: "k8s-zone",
+			nodeLabels:   map[string]string{v1.LabelTopologyZone: "zone-a"},
+		},
+		{
+			name:           "datastore in node zone",
+			zoneCategory:   "k8s-zone",
+			nodeLabels:     map[string]string{v1.LabelTopologyZone: "zone-a"},
+			datastoreZones: []string{"zone-a"},
+		},
+		{
+			name:           "datastore in multiple zones",
+			zoneCategory:   "k8s-zone",
+			nodeLabels:     map[string]string{v1.LabelTopologyZone: "zone-a"},
+			datastoreZones: []string{"zone-a", "zone-b"},
+		},
+		{
+			name:           "datastore in other zone",
+			zoneCategory:   "k8s-zone",
+			nodeLabels:     map[string]string{v1.LabelTopologyZone: "zone-a"},
+			datastoreZones: []string{"zone-b"},
+			expectedError:  "node DC0_H0_VM0 in zone zone-a has VM home on datastore LocalDS_0 in zone zone-b",
+		},
+		{
+			name:           "datastore in other zone with beta label",
+			zoneCategory:   "k8s-zone",
+			nodeLabels:     map[string]string{v1.LabelFailureDomainBetaZone: "zone-a"},
+			datastoreZones: []string{"zone-b"},
+			expectedError:  "node DC0_H0_VM0 in zone zone-a has VM home on datastore LocalDS_0 in zone zone-b",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMConfigFileDatastoreMatchesExpectedZone{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMConfig.Labels.Zone = test.zoneCategory
+
+			node := kubeClient.nodes[0]
+			node.Labels = test.nodeLabels
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			dc, err := getDatacenter(ctx, defaultDC)
+			if err != nil {
+				t.Fatalf("Failed to get datacenter: %s", err)
+			}
+			ds, err := getDataStoreByName(ctx, "LocalDS_0", dc)
+			if err != nil {
+				t.Fatalf("Failed to get datastore: %s", err)
+			}
+			tagIDs := createZoneTags(t, ctx, "k8s-zone", "zone-a", "zone-b")
+			attachZoneTags(t, ctx, tagIDs, ds.Reference(), test.datastoreZones)
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_home_datastore_zone_mismatch_total [ALPHA] Number of vSphere node VMs with VM home on a datastore with zone tags that do not match the zone of the node.
+# TYPE vsphere_node_home_datastore_zone_mismatch_total gauge
+vsphere_node_home_datastore_zone_mismatch_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_home_datastore_zone_mismatch_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	hostStateLabel = "state"
+	// Values of hostStateLabel, in addition to HostSystemConnectionState values.
+	hostStateMaintenance = "maintenance"
+)
+
+// CheckNodeVMHostAvailable makes sure that node VMs run on ESXi hosts that are connected and not in maintenance
+// mode. Volume attach and detach operations on VMs of a disconnected or not responding host hang until
+// the host is back, and hosts in maintenance mode are about to evacuate or power off their VMs.
+type CheckNodeVMHostAvailable struct {
+	lock sync.Mutex
+	// hosts caches runtime info of ESXi hosts loaded in this round of checks, several nodes share a host.
+	hosts map[hostKey]*mo.HostSystem
+	// unavailable counts nodes on unavailable hosts, by hostStateLabel.
+	unavailable map[string]int
+}
+
+var _ NodeCheck = &CheckNodeVMHostAvailable{}
+
+// hostKey identifies an ESXi host, references are unique only within a vCenter.
+type hostKey struct {
+	vCenter string
+	ref     types.ManagedObjectReference
+}
+
+var (
+	nodeHostUnavailableMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_host_unavailable_total",
+			Help:           "Number of vSphere node VMs that run on an ESXi host that is in maintenance mode, disconnected or not responding.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{hostStateLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(nodeHostUnavailableMetric)
+}
+
+func (c *CheckNodeVMHostAvailable) Name() string {
+	return "CheckNodeVMHostAvailable"
+}
+
+func (c *CheckNodeVMHostAvailable) StartCheck() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.hosts = make(map[hostKey]*mo.HostSystem)
+	c.unavailable = make(map[string]int)
+	return nil
+}
+
+func (c *CheckNodeVMHostAvailable) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Runtime.Host == nil {
+		return fmt.Errorf("error getting ESXi host for node %s: vm.runtime.host is empty", node.Name)
+	}
+	host, err := c.getHost(ctx, *vm.Runtime.Host)
+	if err != nil {
+		return fmt.Errorf("failed to get ESXi host %s of node %s: %s", vm.Runtime.Host.Value, node.Name, err)
+	}
+
+	var state, problem string
+	switch {
+	case host.Runtime.ConnectionState == types.HostSystemConnectionStateDisconnected:
+		state, problem = string(host.Runtime.ConnectionState), "is disconnected from vCenter"
+	case host.Runtime.ConnectionState == types.HostSystemConnectionStateNotResponding:
+		state, problem = string(host.Runtime.ConnectionState), "is not responding"
+	case host.Runtime.InMaintenanceMode:
+		state, problem = hostStateMaintenance, "is in maintenance mode"
+	default:
+		klog.V(4).Infof("... the node runs on host %s in connection state %s", host.Name, host.Runtime.ConnectionState)
+		return nil
+	}
+
+	c.lock.Lock()
+	c.unavailable[state]++
+	c.lock.Unlock()
+	return fmt.Errorf("node %s runs on ESXi host %s that %s, volume attach and detach operations on the node may hang", node.Name, host.Name, problem)
+}
+
+func (c *CheckNodeVMHostAvailable) FinishCheck(ctx *CheckContext) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, state := range []string{string(types.HostSystemConnectionStateDisconnected), string(types.HostSystemConnectionStateNotResponding), hostStateMaintenance} {
+		nodeHostUnavailableMetric.WithLabelValues(state).Set(float64(c.unavailable[state]))
+	}
+	return
+}
+
+// getHost returns name and runtime info of the ESXi host, loading it from vCenter only once in each round.
+func (c *CheckNodeVMHostAvailable) getHost(ctx *CheckContext, ref types.ManagedObjectReference) (*mo.HostSystem, error) {
+	key := hostKey{vCenter: ctx.VMConfig.Workspace.VCenterIP, ref: ref}
+	c.lock.Lock()
+	host, found := c.hosts[key]
+	c.lock.Unlock()
+	if found {
+		return host, nil
+	}
+
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	host = &mo.HostSystem{}
+	if err := pc.RetrieveOne(tctx, ref, []string{"name", "runtime.connectionState", "runtime.inMaintenanceMode"}, host); err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	c.hosts[key] = host
+	c.lock.Unlock()
+	return host, nil
+}
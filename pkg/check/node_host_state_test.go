@@ -0,0 +1,133 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMHostAvailable(t *testing.T) {
+	tests := []struct {
+		name              string
+		connectionState   types.HostSystemConnectionState
+		inMaintenanceMode bool
+		// expectedProblem of the host reported for each node, empty when the host is available.
+		expectedProblem string
+		expectedMetrics string
+	}{
+		{
+			name:            "connected host",
+			connectionState: types.HostSystemConnectionStateConnected,
+			expectedMetrics: `
+# HELP vsphere_node_host_unavailable_total [ALPHA] Number of vSphere node VMs that run on an ESXi host that is in maintenance mode, disconnected or not responding.
+# TYPE vsphere_node_host_unavailable_total gauge
+vsphere_node_host_unavailable_total{state="disconnected"} 0
+vsphere_node_host_unavailable_total{state="maintenance"} 0
+vsphere_node_host_unavailable_total{state="notResponding"} 0
+`,
+		},
+		{
+			name:              "host in maintenance mode",
+			connectionState:   types.HostSystemConnectionStateConnected,
+			inMaintenanceMode: true,
+			expectedProblem:   "is in maintenance mode",
+			expectedMetrics: `
+# HELP vsphere_node_host_unavailable_total [ALPHA] Number of vSphere node VMs that run on an ESXi host that is in maintenance mode, disconnected or not responding.
+# TYPE vsphere_node_host_unavailable_total gauge
+vsphere_node_host_unavailable_total{state="disconnected"} 0
+vsphere_node_host_unavailable_total{state="maintenance"} 2
+vsphere_node_host_unavailable_total{state="notResponding"} 0
+`,
+		},
+		{
+			name:            "disconnected host",
+			connectionState: types.HostSystemConnectionStateDisconnected,
+			expectedProblem: "is disconnected from vCenter",
+			expectedMetrics: `
+# HELP vsphere_node_host_unavailable_total [ALPHA] Number of vSphere node VMs that run on an ESXi host that is in maintenance mode, disconnected or not responding.
+# TYPE vsphere_node_host_unavailable_total gauge
+vsphere_node_host_unavailable_total{state="disconnected"} 2
+vsphere_node_host_unavailable_total{state="maintenance"} 0
+vsphere_node_host_unavailable_total{state="notResponding"} 0
+`,
+		},
+		{
+			name:              "not responding host in maintenance mode",
+			connectionState:   types.HostSystemConnectionStateNotResponding,
+			inMaintenanceMode: true,
+			expectedProblem:   "is not responding",
+			expectedMetrics: `
+# HELP vsphere_node_host_unavailable_total [ALPHA] Number of vSphere node VMs that run on an ESXi host that is in maintenance mode, disconnected or not responding.
+# TYPE vsphere_node_host_unavailable_total gauge
+vsphere_node_host_unavailable_total{state="disconnected"} 0
+vsphere_node_host_unavailable_total{state="maintenance"} 0
+vsphere_node_host_unavailable_total{state="notResponding"} 2
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMHostAvailable{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			hs, err := getHostSystem(defaultHostId)
+			if err != nil {
+				t.Fatalf("Failed to get host: %s", err)
+			}
+			hs.Runtime.ConnectionState = test.connectionState
+			hs.Runtime.InMaintenanceMode = test.inMaintenanceMode
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			// Both nodes run on the same host.
+			var errs []error
+			for _, node := range kubeClient.nodes {
+				vm, err := getVM(ctx, node)
+				if err != nil {
+					t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+				}
+				if err := check.CheckNode(ctx, node, vm); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			check.FinishCheck(ctx)
+
+			// Assert
+			var expectedErrors []string
+			if test.expectedProblem != "" {
+				for _, node := range kubeClient.nodes {
+					expectedErrors = append(expectedErrors, fmt.Sprintf("node %s runs on ESXi host DC0_H0 that %s, volume attach and detach operations on the node may hang", node.Name, test.expectedProblem))
+				}
+			}
+			var errStrings []string
+			for _, err := range errs {
+				errStrings = append(errStrings, err.Error())
+			}
+			if strings.Join(errStrings, "\n") != strings.Join(expectedErrors, "\n") {
+				t.Errorf("Expected errors %q, got %q", expectedErrors, errStrings)
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_node_host_unavailable_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package check
 
 import (
+	"fmt"
+
 	"github.com/vmware/govmomi/vim25/mo"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/component-base/metrics"
@@ -8,7 +10,8 @@ import (
 	"k8s.io/klog/v2"
 )
 
-// CollectNodeHWVersion emits metric with HW version of each VM
+// CollectNodeHWVersion emits metric with HW version of each VM and makes sure that
+// the HW version is at least MinHardwareVersion.
 type CollectNodeHWVersion struct {
 	lastMetricEmission map[string]int
 }
@@ -46,6 +49,15 @@ func (c *CollectNodeHWVersion) CheckNode(ctx *CheckContext, node *v1.Node, vm *m
 	hwVersion := vm.Config.Version
 	klog.V(2).Infof("Node %s has HW version %s", node.Name, hwVersion)
 	ctx.ClusterInfo.SetHardwareVersion(hwVersion)
+
+	version, err := parseHWVersion(hwVersion)
+	if err != nil {
+		klog.V(2).Infof("Cannot check minimum hardware version of node %s: %s", node.Name, err)
+		return nil
+	}
+	if version < *MinHardwareVersion {
+		return fmt.Errorf("node %s has hardware version %s, expected vmx-%d or newer", node.Name, hwVersion, *MinHardwareVersion)
+	}
 	return nil
 }
 
@@ -0,0 +1,128 @@
+package check
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/blang/semver"
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// vCenterHWVersion is the highest virtual hardware version supported by a vCenter API version.
+type vCenterHWVersion struct {
+	apiVersion string
+	hwVersion  int
+}
+
+var (
+	// vCenterMaxHWVersions lists the highest hardware version supported by vCenter releases, sorted by the API version.
+	// A vCenter supports the hardware version of the last entry that is not newer than the vCenter.
+	// Update the list with new vSphere releases.
+	vCenterMaxHWVersions = []vCenterHWVersion{
+		{"6.0.0", 11},
+		{"6.5.0", 13},
+		{"6.7.0", 14},
+		{"6.7.2", 15}, // 6.7 U2
+		{"7.0.0", 17},
+		{"7.0.1", 18}, // 7.0 U1
+		{"7.0.2", 19}, // 7.0 U2
+		{"8.0.0", 20},
+		{"8.0.2", 21}, // 8.0 U2
+	}
+
+	hwVersionAboveVCenterMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_hw_version_above_vcenter_max_total",
+			Help:           "Number of vSphere node VMs with hardware version newer than the connected vCenter supports.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(hwVersionAboveVCenterMetric)
+}
+
+// CheckNodeVMHardwareVersionVsVCenterMaxSupported makes sure that no node VM has hardware version newer
+// than the vCenter that manages it supports. vCenter cannot fully manage such VMs, e.g. reconfigure them.
+// vCenter versions that are newer than the known releases are not checked.
+type CheckNodeVMHardwareVersionVsVCenterMaxSupported struct {
+	aboveMaxLock  sync.Mutex
+	aboveMaxCount int
+}
+
+var _ NodeCheck = &CheckNodeVMHardwareVersionVsVCenterMaxSupported{}
+
+func (c *CheckNodeVMHardwareVersionVsVCenterMaxSupported) Name() string {
+	return "CheckNodeVMHardwareVersionVsVCenterMaxSupported"
+}
+
+func (c *CheckNodeVMHardwareVersionVsVCenterMaxSupported) StartCheck() error {
+	c.aboveMaxLock.Lock()
+	defer c.aboveMaxLock.Unlock()
+	c.aboveMaxCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMHardwareVersionVsVCenterMaxSupported) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil {
+		klog.V(4).Infof("... the node has no config")
+		return nil
+	}
+	hwVersion, err := parseHWVersion(vm.Config.Version)
+	if err != nil {
+		klog.V(2).Infof("Failed to parse hardware version of node %s: %s", node.Name, err)
+		return nil
+	}
+	apiVersion := ctx.VMClient.ServiceContent.About.ApiVersion
+	maxVersion, err := getVCenterMaxHWVersion(apiVersion)
+	if err != nil {
+		return err
+	}
+	if maxVersion == 0 {
+		klog.V(4).Infof("... the maximum hardware version of vCenter API version %s is not known", apiVersion)
+		return nil
+	}
+	if hwVersion <= maxVersion {
+		klog.V(4).Infof("... the node has hardware version %s supported by vCenter API version %s", vm.Config.Version, apiVersion)
+		return nil
+	}
+
+	c.aboveMaxLock.Lock()
+	c.aboveMaxCount++
+	c.aboveMaxLock.Unlock()
+	return fmt.Errorf("node %s has hardware version %s, but vCenter %s (API version %s) supports hardware versions up to vmx-%d", node.Name, vm.Config.Version, ctx.VMClient.ServiceContent.About.Version, apiVersion, maxVersion)
+}
+
+func (c *CheckNodeVMHardwareVersionVsVCenterMaxSupported) FinishCheck(ctx *CheckContext) {
+	c.aboveMaxLock.Lock()
+	defer c.aboveMaxLock.Unlock()
+	hwVersionAboveVCenterMetric.WithLabelValues().Set(float64(c.aboveMaxCount))
+	return
+}
+
+// getVCenterMaxHWVersion returns the highest hardware version supported by the vCenter API version.
+// It returns 0 when the version is older than the known releases, or has a newer major version.
+func getVCenterMaxHWVersion(apiVersion string) (int, error) {
+	version, err := semver.ParseTolerant(parseForSemver(apiVersion))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse vCenter API version %q: %s", apiVersion, err)
+	}
+	last := vCenterMaxHWVersions[len(vCenterMaxHWVersions)-1]
+	if version.Major > semver.MustParse(last.apiVersion).Major {
+		return 0, nil
+	}
+	maxVersion := 0
+	for _, entry := range vCenterMaxHWVersions {
+		if isVersionBefore(version, entry.apiVersion) {
+			break
+		}
+		maxVersion = entry.hwVersion
+	}
+	return maxVersion, nil
+}
@@ -0,0 +1,117 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMHardwareVersionVsVCenterMaxSupported(t *testing.T) {
+	tests := []struct {
+		name          string
+		apiVersion    string
+		hwVersion     string
+		expectedError string
+	}{
+		{
+			name:       "supported hardware version",
+			apiVersion: "7.0.1.0",
+			hwVersion:  "vmx-18",
+		},
+		{
+			name:       "older hardware version",
+			apiVersion: "6.7.3",
+			hwVersion:  "vmx-13",
+		},
+		{
+			name:          "hardware version above vCenter update",
+			apiVersion:    "7.0.1.0",
+			hwVersion:     "vmx-19",
+			expectedError: "node DC0_H0_VM0 has hardware version vmx-19, but vCenter 6.5.0 (API version 7.0.1.0) supports hardware versions up to vmx-18",
+		},
+		{
+			name:          "hardware version above vCenter release",
+			apiVersion:    "6.5",
+			hwVersion:     "vmx-14",
+			expectedError: "node DC0_H0_VM0 has hardware version vmx-14, but vCenter 6.5.0 (API version 6.5) supports hardware versions up to vmx-13",
+		},
+		{
+			name:       "unknown future vCenter",
+			apiVersion: "9.0.0.0",
+			hwVersion:  "vmx-30",
+		},
+		{
+			name:       "vCenter older than known releases",
+			apiVersion: "5.5",
+			hwVersion:  "vmx-13",
+		},
+		{
+			name:          "invalid vCenter version",
+			apiVersion:    "foo",
+			hwVersion:     "vmx-13",
+			expectedError: `failed to parse vCenter API version "foo": Invalid character(s) found in major number "foo"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMHardwareVersionVsVCenterMaxSupported{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			vm.Config.Version = test.hwVersion
+			ctx.VMClient.ServiceContent.About.ApiVersion = test.apiVersion
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if strings.HasPrefix(test.expectedError, "node ") {
+					expectedCount = 1
+				}
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_hw_version_above_vcenter_max_total [ALPHA] Number of vSphere node VMs with hardware version newer than the connected vCenter supports.
+# TYPE vsphere_node_hw_version_above_vcenter_max_total gauge
+vsphere_node_hw_version_above_vcenter_max_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_hw_version_above_vcenter_max_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
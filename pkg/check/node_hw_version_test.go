@@ -9,6 +9,11 @@ import (
 )
 
 func TestCollectNodeHWVersion(t *testing.T) {
+	// The simulator VMs have vmx-13, which is tested by TestCollectNodeHWVersionMinimum.
+	oldMin := *MinHardwareVersion
+	*MinHardwareVersion = 13
+	defer func() { *MinHardwareVersion = oldMin }()
+
 	tests := []struct {
 		name            string
 		hwVersions      []string
@@ -108,3 +113,86 @@ vsphere_node_hw_version_total{hw_version="vmx-15"} 2
 		})
 	}
 }
+
+func TestCollectNodeHWVersionMinimum(t *testing.T) {
+	tests := []struct {
+		name               string
+		hwVersion          string
+		minHardwareVersion int
+		expectedError      string
+	}{
+		{
+			name:               "below the minimum",
+			hwVersion:          "vmx-13",
+			minHardwareVersion: 15,
+			expectedError:      "node DC0_H0_VM0 has hardware version vmx-13, expected vmx-15 or newer",
+		},
+		{
+			name:               "at the minimum",
+			hwVersion:          "vmx-15",
+			minHardwareVersion: 15,
+		},
+		{
+			name:               "above the minimum",
+			hwVersion:          "vmx-19",
+			minHardwareVersion: 15,
+		},
+		{
+			name:               "older minimum",
+			hwVersion:          "vmx-13",
+			minHardwareVersion: 11,
+		},
+		{
+			name:               "newer minimum",
+			hwVersion:          "vmx-17",
+			minHardwareVersion: 19,
+			expectedError:      "node DC0_H0_VM0 has hardware version vmx-17, expected vmx-19 or newer",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			oldMin := *MinHardwareVersion
+			*MinHardwareVersion = test.minHardwareVersion
+			defer func() { *MinHardwareVersion = oldMin }()
+
+			check := CollectNodeHWVersion{
+				lastMetricEmission: map[string]int{},
+			}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			if err := setHardwareVersion(ctx, node, test.hwVersion); err != nil {
+				t.Fatalf("Failed to customize node: %s", err)
+			}
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+
+			// Act
+			err = check.CheckNode(ctx, node, vm)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+		})
+	}
+}
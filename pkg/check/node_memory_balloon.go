@@ -0,0 +1,109 @@
+package check
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// extraConfig key that limits memory reclaimed by the balloon driver, in MB. 0 disables the balloon driver.
+	maxMemCtlKey = "sched.mem.maxmemctl"
+
+	// Values of the memory-balloon-policy flag.
+	memoryBalloonPolicyEnabled  = "enabled"
+	memoryBalloonPolicyDisabled = "disabled"
+	memoryBalloonPolicyAny      = "any"
+)
+
+var (
+	// memoryBalloonPolicy is the expected state of the memory balloon driver of node VMs.
+	memoryBalloonPolicy = flag.String("memory-balloon-policy", memoryBalloonPolicyEnabled, "Expected state of the memory balloon driver of node VMs: 'enabled' (the vSphere default), 'disabled' (sched.mem.maxmemctl = 0, e.g. for latency sensitive workloads) or 'any' to not check it.")
+
+	memoryBalloonNonDefaultMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_memory_balloon_non_default_total",
+			Help:           "Number of vSphere node VMs with memory balloon driver disabled or limited by sched.mem.maxmemctl.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(memoryBalloonNonDefaultMetric)
+}
+
+// CheckNodeVMToolsMemoryBalloonDisabled makes sure that the memory balloon driver of node VMs is configured
+// as required by the memory-balloon-policy flag. Some clusters with latency sensitive workloads disable
+// the balloon driver, a node VM with a different configuration indicates configuration drift.
+type CheckNodeVMToolsMemoryBalloonDisabled struct {
+	nonDefaultLock  sync.Mutex
+	nonDefaultCount int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsMemoryBalloonDisabled{}
+
+func (c *CheckNodeVMToolsMemoryBalloonDisabled) Name() string {
+	return "CheckNodeVMToolsMemoryBalloonDisabled"
+}
+
+func (c *CheckNodeVMToolsMemoryBalloonDisabled) StartCheck() error {
+	switch *memoryBalloonPolicy {
+	case memoryBalloonPolicyEnabled, memoryBalloonPolicyDisabled, memoryBalloonPolicyAny:
+	default:
+		return fmt.Errorf("invalid memory-balloon-policy %q, expected one of %s, %s, %s", *memoryBalloonPolicy, memoryBalloonPolicyEnabled, memoryBalloonPolicyDisabled, memoryBalloonPolicyAny)
+	}
+	c.nonDefaultLock.Lock()
+	defer c.nonDefaultLock.Unlock()
+	c.nonDefaultCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsMemoryBalloonDisabled) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil {
+		klog.V(4).Infof("... the node has no configuration")
+		return nil
+	}
+
+	maxMemCtl := ""
+	for _, option := range vm.Config.ExtraConfig {
+		o := option.GetOptionValue()
+		if strings.EqualFold(o.Key, maxMemCtlKey) {
+			maxMemCtl = strings.TrimSpace(fmt.Sprintf("%v", o.Value))
+		}
+	}
+
+	if maxMemCtl != "" {
+		c.nonDefaultLock.Lock()
+		c.nonDefaultCount++
+		c.nonDefaultLock.Unlock()
+	}
+
+	switch {
+	case *memoryBalloonPolicy == memoryBalloonPolicyEnabled && maxMemCtl != "":
+		return fmt.Errorf("node %s has memory balloon driver disabled or limited, expected enabled: %s=%s", node.Name, maxMemCtlKey, maxMemCtl)
+	case *memoryBalloonPolicy == memoryBalloonPolicyDisabled && maxMemCtl != "0":
+		setting := "is not set"
+		if maxMemCtl != "" {
+			setting = "is " + maxMemCtl
+		}
+		return fmt.Errorf("node %s has memory balloon driver enabled, expected disabled: %s %s", node.Name, maxMemCtlKey, setting)
+	}
+	klog.V(4).Infof("... the node has %s=%q", maxMemCtlKey, maxMemCtl)
+	return nil
+}
+
+func (c *CheckNodeVMToolsMemoryBalloonDisabled) FinishCheck(ctx *CheckContext) {
+	c.nonDefaultLock.Lock()
+	defer c.nonDefaultLock.Unlock()
+	memoryBalloonNonDefaultMetric.WithLabelValues().Set(float64(c.nonDefaultCount))
+	return
+}
@@ -0,0 +1,143 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsMemoryBalloonDisabled(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		maxMemCtl     string
+		expectedCount int
+		expectedError string
+	}{
+		{
+			name:   "default policy, balloon enabled",
+			policy: memoryBalloonPolicyEnabled,
+		},
+		{
+			name:          "default policy, balloon disabled",
+			policy:        memoryBalloonPolicyEnabled,
+			maxMemCtl:     "0",
+			expectedCount: 1,
+			expectedError: "node DC0_H0_VM0 has memory balloon driver disabled or limited, expected enabled: sched.mem.maxmemctl=0",
+		},
+		{
+			name:          "default policy, balloon limited",
+			policy:        memoryBalloonPolicyEnabled,
+			maxMemCtl:     "512",
+			expectedCount: 1,
+			expectedError: "node DC0_H0_VM0 has memory balloon driver disabled or limited, expected enabled: sched.mem.maxmemctl=512",
+		},
+		{
+			name:          "disabled policy, balloon disabled",
+			policy:        memoryBalloonPolicyDisabled,
+			maxMemCtl:     "0",
+			expectedCount: 1,
+		},
+		{
+			name:          "disabled policy, balloon enabled",
+			policy:        memoryBalloonPolicyDisabled,
+			expectedError: "node DC0_H0_VM0 has memory balloon driver enabled, expected disabled: sched.mem.maxmemctl is not set",
+		},
+		{
+			name:          "disabled policy, balloon limited",
+			policy:        memoryBalloonPolicyDisabled,
+			maxMemCtl:     "512",
+			expectedCount: 1,
+			expectedError: "node DC0_H0_VM0 has memory balloon driver enabled, expected disabled: sched.mem.maxmemctl is 512",
+		},
+		{
+			name:          "any policy",
+			policy:        memoryBalloonPolicyAny,
+			maxMemCtl:     "0",
+			expectedCount: 1,
+		},
+	}
+
+	oldPolicy := *memoryBalloonPolicy
+	defer func() { *memoryBalloonPolicy = oldPolicy }()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			*memoryBalloonPolicy = test.policy
+			check := CheckNodeVMToolsMemoryBalloonDisabled{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			if test.maxMemCtl != "" {
+				extraConfig := []types.BaseOptionValue{
+					&types.OptionValue{Key: maxMemCtlKey, Value: test.maxMemCtl},
+				}
+				err = customizeVM(ctx, node, &types.VirtualMachineConfigSpec{ExtraConfig: extraConfig})
+				if err != nil {
+					t.Fatalf("Failed to customize node: %s", err)
+				}
+			}
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_memory_balloon_non_default_total [ALPHA] Number of vSphere node VMs with memory balloon driver disabled or limited by sched.mem.maxmemctl.
+# TYPE vsphere_node_memory_balloon_non_default_total gauge
+vsphere_node_memory_balloon_non_default_total %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_memory_balloon_non_default_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+func TestCheckNodeVMToolsMemoryBalloonDisabledInvalidPolicy(t *testing.T) {
+	oldPolicy := *memoryBalloonPolicy
+	defer func() { *memoryBalloonPolicy = oldPolicy }()
+
+	*memoryBalloonPolicy = "off"
+	check := CheckNodeVMToolsMemoryBalloonDisabled{}
+	err := check.StartCheck()
+	expectedError := `invalid memory-balloon-policy "off", expected one of enabled, disabled, any`
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("Expected error %q, got %v", expectedError, err)
+	}
+}
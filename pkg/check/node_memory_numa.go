@@ -0,0 +1,105 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMMemorySizeAlignment makes sure that memory of node VMs fits into a single NUMA node of their
+// ESXi host. Memory of a larger VM straddles NUMA nodes and part of it is remote to the vCPUs that use it.
+type CheckNodeVMMemorySizeAlignment struct {
+	numaUnalignedLock  sync.Mutex
+	numaUnalignedCount int
+}
+
+var _ NodeCheck = &CheckNodeVMMemorySizeAlignment{}
+
+var (
+	numaUnalignedMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_memory_numa_unaligned_total",
+			Help:           "Number of vSphere node VMs with memory larger than a single NUMA node of their ESXi host.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(numaUnalignedMetric)
+}
+
+func (c *CheckNodeVMMemorySizeAlignment) Name() string {
+	return "CheckNodeVMMemorySizeAlignment"
+}
+
+func (c *CheckNodeVMMemorySizeAlignment) StartCheck() error {
+	c.numaUnalignedLock.Lock()
+	defer c.numaUnalignedLock.Unlock()
+	c.numaUnalignedCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMMemorySizeAlignment) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil || vm.Runtime.Host == nil {
+		return nil
+	}
+	memoryMB := int64(vm.Config.Hardware.MemoryMB)
+
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var host mo.HostSystem
+	if err := pc.RetrieveOne(tctx, *vm.Runtime.Host, []string{"name", "hardware.memorySize", "hardware.numaInfo"}, &host); err != nil {
+		return fmt.Errorf("failed to get ESXi host %s of node %s: %s", vm.Runtime.Host.Value, node.Name, err)
+	}
+	numaNodeMB := getHostNumaNodeMemoryMB(&host)
+	if numaNodeMB == 0 {
+		klog.V(4).Infof("... the node runs on host %s without NUMA information", host.Name)
+		return nil
+	}
+	if memoryMB <= numaNodeMB {
+		klog.V(4).Infof("... the node has %d MB of memory, which fits into a NUMA node of host %s with %d MB", memoryMB, host.Name, numaNodeMB)
+		return nil
+	}
+
+	c.numaUnalignedLock.Lock()
+	c.numaUnalignedCount++
+	c.numaUnalignedLock.Unlock()
+	return fmt.Errorf("node %s has %d MB of memory, which straddles NUMA nodes of host %s with %d MB of memory each", node.Name, memoryMB, host.Name, numaNodeMB)
+}
+
+func (c *CheckNodeVMMemorySizeAlignment) FinishCheck(ctx *CheckContext) {
+	c.numaUnalignedLock.Lock()
+	defer c.numaUnalignedLock.Unlock()
+	numaUnalignedMetric.WithLabelValues().Set(float64(c.numaUnalignedCount))
+	return
+}
+
+// getHostNumaNodeMemoryMB returns memory of the smallest NUMA node of the host in MB, or memory of the host
+// divided by the number of NUMA nodes when the host does not report memory of each node.
+// It returns 0 when the host does not report NUMA information.
+func getHostNumaNodeMemoryMB(host *mo.HostSystem) int64 {
+	if host.Hardware == nil || host.Hardware.NumaInfo == nil || host.Hardware.NumaInfo.NumNodes <= 0 {
+		return 0
+	}
+	const mb = 1024 * 1024
+	var smallest int64
+	for _, numaNode := range host.Hardware.NumaInfo.NumaNode {
+		if numaNode.MemoryRangeLength > 0 && (smallest == 0 || numaNode.MemoryRangeLength < smallest) {
+			smallest = numaNode.MemoryRangeLength
+		}
+	}
+	if smallest == 0 {
+		smallest = host.Hardware.MemorySize / int64(host.Hardware.NumaInfo.NumNodes)
+	}
+	return smallest / mb
+}
@@ -0,0 +1,162 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMMemorySizeAlignment(t *testing.T) {
+	const gi = 1024 * 1024 * 1024
+	tests := []struct {
+		name            string
+		memoryMB        int32
+		hostMemory      int64
+		numaInfo        *types.HostNumaInfo
+		expectedError   string
+		expectedMetrics string
+	}{
+		{
+			name:       "memory fits into a NUMA node",
+			memoryMB:   4096,
+			hostMemory: 16 * gi,
+			numaInfo: &types.HostNumaInfo{
+				NumNodes: 2,
+				NumaNode: []types.HostNumaNode{{MemoryRangeLength: 8 * gi}, {MemoryRangeLength: 8 * gi}},
+			},
+			expectedMetrics: `
+# HELP vsphere_node_memory_numa_unaligned_total [ALPHA] Number of vSphere node VMs with memory larger than a single NUMA node of their ESXi host.
+# TYPE vsphere_node_memory_numa_unaligned_total gauge
+vsphere_node_memory_numa_unaligned_total 0
+`,
+		},
+		{
+			name:       "memory of a whole NUMA node",
+			memoryMB:   8192,
+			hostMemory: 16 * gi,
+			numaInfo: &types.HostNumaInfo{
+				NumNodes: 2,
+				NumaNode: []types.HostNumaNode{{MemoryRangeLength: 8 * gi}, {MemoryRangeLength: 8 * gi}},
+			},
+			expectedMetrics: `
+# HELP vsphere_node_memory_numa_unaligned_total [ALPHA] Number of vSphere node VMs with memory larger than a single NUMA node of their ESXi host.
+# TYPE vsphere_node_memory_numa_unaligned_total gauge
+vsphere_node_memory_numa_unaligned_total 0
+`,
+		},
+		{
+			name:       "memory straddles NUMA nodes",
+			memoryMB:   12288,
+			hostMemory: 16 * gi,
+			numaInfo: &types.HostNumaInfo{
+				NumNodes: 2,
+				NumaNode: []types.HostNumaNode{{MemoryRangeLength: 8 * gi}, {MemoryRangeLength: 8 * gi}},
+			},
+			expectedError: "node DC0_H0_VM0 has 12288 MB of memory, which straddles NUMA nodes of host DC0_H0 with 8192 MB of memory each",
+			expectedMetrics: `
+# HELP vsphere_node_memory_numa_unaligned_total [ALPHA] Number of vSphere node VMs with memory larger than a single NUMA node of their ESXi host.
+# TYPE vsphere_node_memory_numa_unaligned_total gauge
+vsphere_node_memory_numa_unaligned_total 1
+`,
+		},
+		{
+			name:       "uneven NUMA nodes",
+			memoryMB:   6144,
+			hostMemory: 12 * gi,
+			numaInfo: &types.HostNumaInfo{
+				NumNodes: 2,
+				NumaNode: []types.HostNumaNode{{MemoryRangeLength: 8 * gi}, {MemoryRangeLength: 4 * gi}},
+			},
+			expectedError: "node DC0_H0_VM0 has 6144 MB of memory, which straddles NUMA nodes of host DC0_H0 with 4096 MB of memory each",
+			expectedMetrics: `
+# HELP vsphere_node_memory_numa_unaligned_total [ALPHA] Number of vSphere node VMs with memory larger than a single NUMA node of their ESXi host.
+# TYPE vsphere_node_memory_numa_unaligned_total gauge
+vsphere_node_memory_numa_unaligned_total 1
+`,
+		},
+		{
+			name:          "NUMA nodes without memory ranges",
+			memoryMB:      6144,
+			hostMemory:    16 * gi,
+			numaInfo:      &types.HostNumaInfo{NumNodes: 4},
+			expectedError: "node DC0_H0_VM0 has 6144 MB of memory, which straddles NUMA nodes of host DC0_H0 with 4096 MB of memory each",
+			expectedMetrics: `
+# HELP vsphere_node_memory_numa_unaligned_total [ALPHA] Number of vSphere node VMs with memory larger than a single NUMA node of their ESXi host.
+# TYPE vsphere_node_memory_numa_unaligned_total gauge
+vsphere_node_memory_numa_unaligned_total 1
+`,
+		},
+		{
+			name:       "no NUMA information",
+			memoryMB:   65536,
+			hostMemory: 16 * gi,
+			expectedMetrics: `
+# HELP vsphere_node_memory_numa_unaligned_total [ALPHA] Number of vSphere node VMs with memory larger than a single NUMA node of their ESXi host.
+# TYPE vsphere_node_memory_numa_unaligned_total gauge
+vsphere_node_memory_numa_unaligned_total 0
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMMemorySizeAlignment{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			hs, err := getHostSystem(defaultHostId)
+			if err != nil {
+				t.Fatalf("Failed to get host: %s", err)
+			}
+			// The simulated hosts share their hardware info, modify a copy
+			hardware := *hs.Hardware
+			hardware.MemorySize = test.hostMemory
+			hardware.NumaInfo = test.numaInfo
+			hs.Hardware = &hardware
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			vm.Config.Hardware.MemoryMB = test.memoryMB
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_node_memory_numa_unaligned_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
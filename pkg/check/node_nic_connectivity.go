@@ -0,0 +1,97 @@
+package check
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMGuestNetConnectivityFlags makes sure that all NICs of node VMs are set to connect
+// on boot and that their connected state reported by the guest matches the device state.
+// A NIC that does not connect on boot loses networking after the node reboots.
+type CheckNodeVMGuestNetConnectivityFlags struct {
+	notStartConnectedLock  sync.Mutex
+	notStartConnectedCount int
+}
+
+var _ NodeCheck = &CheckNodeVMGuestNetConnectivityFlags{}
+
+var (
+	nicNotStartConnectedMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_nic_not_start_connected_total",
+			Help:           "Number of NICs of vSphere node VMs that are not set to connect on boot.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(nicNotStartConnectedMetric)
+}
+
+func (c *CheckNodeVMGuestNetConnectivityFlags) Name() string {
+	return "CheckNodeVMGuestNetConnectivityFlags"
+}
+
+func (c *CheckNodeVMGuestNetConnectivityFlags) StartCheck() error {
+	c.notStartConnectedLock.Lock()
+	defer c.notStartConnectedLock.Unlock()
+	c.notStartConnectedCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMGuestNetConnectivityFlags) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil {
+		return nil
+	}
+
+	// device key -> connected state reported by the guest
+	guestConnected := make(map[int32]bool)
+	if vm.Guest != nil {
+		for _, nic := range vm.Guest.Net {
+			guestConnected[nic.DeviceConfigId] = nic.Connected
+		}
+	}
+
+	devices := object.VirtualDeviceList(vm.Config.Hardware.Device)
+	var errs []error
+	notStartConnected := 0
+	for _, device := range devices.SelectByType((*types.VirtualEthernetCard)(nil)) {
+		name := devices.Name(device)
+		connectable := device.GetVirtualDevice().Connectable
+		if connectable == nil {
+			klog.V(4).Infof("... NIC %s has no connection info", name)
+			continue
+		}
+		if !connectable.StartConnected {
+			notStartConnected++
+			errs = append(errs, fmt.Errorf("node %s: NIC %s is not set to connect on boot: connectable.startConnected is false", node.Name, name))
+		}
+		if connected, found := guestConnected[device.GetVirtualDevice().Key]; found && connected != connectable.Connected {
+			errs = append(errs, fmt.Errorf("node %s: NIC %s has inconsistent connected state: guest.net connected is %t, connectable.connected is %t", node.Name, name, connected, connectable.Connected))
+		}
+	}
+
+	if notStartConnected > 0 {
+		c.notStartConnectedLock.Lock()
+		c.notStartConnectedCount += notStartConnected
+		c.notStartConnectedLock.Unlock()
+	}
+	return JoinErrors(errs)
+}
+
+func (c *CheckNodeVMGuestNetConnectivityFlags) FinishCheck(ctx *CheckContext) {
+	c.notStartConnectedLock.Lock()
+	defer c.notStartConnectedLock.Unlock()
+	nicNotStartConnectedMetric.WithLabelValues().Set(float64(c.notStartConnectedCount))
+	return
+}
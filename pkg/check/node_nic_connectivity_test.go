@@ -0,0 +1,113 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMGuestNetConnectivityFlags(t *testing.T) {
+	tests := []struct {
+		name              string
+		startConnected    bool
+		connected         bool
+		guestConnected    bool
+		expectedError     string
+		notStartConnected int
+	}{
+		{
+			name:           "connected NIC",
+			startConnected: true,
+			connected:      true,
+			guestConnected: true,
+		},
+		{
+			name:              "NIC not connected on boot",
+			startConnected:    false,
+			connected:         true,
+			guestConnected:    true,
+			expectedError:     "node DC0_H0_VM0: NIC ethernet-0 is not set to connect on boot: connectable.startConnected is false",
+			notStartConnected: 1,
+		},
+		{
+			name:           "inconsistent connected state",
+			startConnected: true,
+			connected:      true,
+			guestConnected: false,
+			expectedError:  "node DC0_H0_VM0: NIC ethernet-0 has inconsistent connected state: guest.net connected is false, connectable.connected is true",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMGuestNetConnectivityFlags{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			nics := object.VirtualDeviceList(vm.Config.Hardware.Device).SelectByType((*types.VirtualEthernetCard)(nil))
+			if len(nics) != 1 {
+				t.Fatalf("Expected 1 NIC, got %d", len(nics))
+			}
+			nic := nics[0].GetVirtualDevice()
+			nic.Connectable = &types.VirtualDeviceConnectInfo{
+				StartConnected: test.startConnected,
+				Connected:      test.connected,
+			}
+			// vcsim does not run VMware Tools, set the guest state directly
+			vm.Guest = &types.GuestInfo{
+				Net: []types.GuestNicInfo{
+					{DeviceConfigId: nic.Key, Connected: test.guestConnected},
+				},
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_nic_not_start_connected_total [ALPHA] Number of NICs of vSphere node VMs that are not set to connect on boot.
+# TYPE vsphere_node_nic_not_start_connected_total gauge
+vsphere_node_nic_not_start_connected_total %d
+`, test.notStartConnected)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_nic_not_start_connected_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMWWNConsistencyForNPIV makes sure that node VMs do not have N-Port ID Virtualization (NPIV) configured.
+// RHCOS nodes do not use NPIV and WWNs copied from a VM template conflict in the SAN fabric and break zoning.
+type CheckNodeVMWWNConsistencyForNPIV struct {
+	npivLock  sync.Mutex
+	npivCount int
+}
+
+var _ NodeCheck = &CheckNodeVMWWNConsistencyForNPIV{}
+
+var (
+	npivMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_npiv_total",
+			Help:           "Number of vSphere node VMs with N-Port ID Virtualization (NPIV) configured.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(npivMetric)
+}
+
+func (c *CheckNodeVMWWNConsistencyForNPIV) Name() string {
+	return "CheckNodeVMWWNConsistencyForNPIV"
+}
+
+func (c *CheckNodeVMWWNConsistencyForNPIV) StartCheck() error {
+	c.npivLock.Lock()
+	defer c.npivLock.Unlock()
+	c.npivCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMWWNConsistencyForNPIV) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil {
+		klog.V(4).Infof("... the node has no configuration")
+		return nil
+	}
+	config := vm.Config
+	if config.NpivWorldWideNameType == "" && len(config.NpivNodeWorldWideName) == 0 && len(config.NpivPortWorldWideName) == 0 {
+		klog.V(4).Infof("... the node has no NPIV configuration")
+		return nil
+	}
+
+	c.npivLock.Lock()
+	c.npivCount++
+	c.npivLock.Unlock()
+	return fmt.Errorf("node %s has NPIV configured: npivWorldWideNameType is %q, node WWNs [%s], port WWNs [%s]", node.Name, config.NpivWorldWideNameType, formatWWNs(config.NpivNodeWorldWideName), formatWWNs(config.NpivPortWorldWideName))
+}
+
+func (c *CheckNodeVMWWNConsistencyForNPIV) FinishCheck(ctx *CheckContext) {
+	c.npivLock.Lock()
+	defer c.npivLock.Unlock()
+	npivMetric.WithLabelValues().Set(float64(c.npivCount))
+	return
+}
+
+// formatWWNs returns comma separated World Wide Names in their usual hexadecimal form.
+func formatWWNs(wwns []int64) string {
+	var formatted []string
+	for _, wwn := range wwns {
+		formatted = append(formatted, fmt.Sprintf("%016x", uint64(wwn)))
+	}
+	return strings.Join(formatted, ", ")
+}
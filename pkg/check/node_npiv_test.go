@@ -0,0 +1,94 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMWWNConsistencyForNPIV(t *testing.T) {
+	tests := []struct {
+		name          string
+		wwnType       string
+		nodeWWNs      []int64
+		portWWNs      []int64
+		expectedError string
+	}{
+		{
+			name: "no NPIV",
+		},
+		{
+			name:          "NPIV generated by vCenter",
+			wwnType:       "vc",
+			nodeWWNs:      []int64{0x282b000c29000001},
+			portWWNs:      []int64{0x282b000c29000002, 0x282b000c29000003},
+			expectedError: `node DC0_H0_VM0 has NPIV configured: npivWorldWideNameType is "vc", node WWNs [282b000c29000001], port WWNs [282b000c29000002, 282b000c29000003]`,
+		},
+		{
+			name:          "WWNs without type",
+			portWWNs:      []int64{0x282b000c29000002},
+			expectedError: `node DC0_H0_VM0 has NPIV configured: npivWorldWideNameType is "", node WWNs [], port WWNs [282b000c29000002]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMWWNConsistencyForNPIV{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			vm.Config.NpivWorldWideNameType = test.wwnType
+			vm.Config.NpivNodeWorldWideName = test.nodeWWNs
+			vm.Config.NpivPortWorldWideName = test.portWWNs
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_npiv_total [ALPHA] Number of vSphere node VMs with N-Port ID Virtualization (NPIV) configured.
+# TYPE vsphere_node_npiv_total gauge
+vsphere_node_npiv_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_npiv_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
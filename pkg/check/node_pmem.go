@@ -0,0 +1,135 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMPMemUsage reports node VMs that use persistent memory (PMem), either as NVDIMM devices or as
+// disks stored on a PMem datastore. PMem is local to the ESXi host, such VMs have limited vMotion and HA
+// support and need special handling during maintenance. The check is advisory, it only logs a warning
+// and reports the metric.
+type CheckNodeVMPMemUsage struct {
+	pmemLock  sync.Mutex
+	pmemCount int
+}
+
+var _ NodeCheck = &CheckNodeVMPMemUsage{}
+
+var (
+	pmemMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_pmem_total",
+			Help:           "Number of vSphere node VMs with NVDIMM devices or disks on persistent memory (PMem) datastores.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(pmemMetric)
+}
+
+func (c *CheckNodeVMPMemUsage) Name() string {
+	return "CheckNodeVMPMemUsage"
+}
+
+func (c *CheckNodeVMPMemUsage) StartCheck() error {
+	c.pmemLock.Lock()
+	defer c.pmemLock.Unlock()
+	c.pmemCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMPMemUsage) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	devices, err := getVMPMemDevices(ctx, vm)
+	if err != nil {
+		return fmt.Errorf("failed to check persistent memory of node %s: %s", node.Name, err)
+	}
+	if len(devices) == 0 {
+		klog.V(4).Infof("... the node does not use persistent memory")
+		return nil
+	}
+
+	c.pmemLock.Lock()
+	c.pmemCount++
+	c.pmemLock.Unlock()
+	klog.Warningf("Node %s uses persistent memory (PMem) that limits vMotion and HA of the VM: %s", node.Name, strings.Join(devices, ", "))
+	return nil
+}
+
+func (c *CheckNodeVMPMemUsage) FinishCheck(ctx *CheckContext) {
+	c.pmemLock.Lock()
+	defer c.pmemLock.Unlock()
+	pmemMetric.WithLabelValues().Set(float64(c.pmemCount))
+	return
+}
+
+// getVMPMemDevices returns descriptions of NVDIMM devices of the VM and of its disks stored on PMem datastores,
+// in the order of the devices.
+func getVMPMemDevices(ctx *CheckContext, vm *mo.VirtualMachine) ([]string, error) {
+	if vm.Config == nil {
+		return nil, nil
+	}
+
+	// Find types of datastores with the VM disks
+	diskDatastores := make(map[types.ManagedObjectReference]bool)
+	for _, device := range vm.Config.Hardware.Device {
+		if _, ok := device.(*types.VirtualDisk); !ok {
+			continue
+		}
+		if backing, ok := device.GetVirtualDevice().Backing.(types.BaseVirtualDeviceFileBackingInfo); ok {
+			if ref := backing.GetVirtualDeviceFileBackingInfo().Datastore; ref != nil {
+				diskDatastores[*ref] = true
+			}
+		}
+	}
+	pmemDatastores := make(map[types.ManagedObjectReference]bool)
+	if len(diskDatastores) > 0 {
+		var refs []types.ManagedObjectReference
+		for ref := range diskDatastores {
+			refs = append(refs, ref)
+		}
+		var datastores []mo.Datastore
+		pc := property.DefaultCollector(ctx.VMClient)
+		tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+		defer cancel()
+		if err := pc.Retrieve(tctx, refs, []string{"summary.type"}, &datastores); err != nil {
+			return nil, fmt.Errorf("failed to get datastores: %s", err)
+		}
+		for _, ds := range datastores {
+			if ds.Summary.Type == string(types.HostFileSystemVolumeFileSystemTypePMEM) {
+				pmemDatastores[ds.Reference()] = true
+			}
+		}
+	}
+
+	var devices []string
+	for _, device := range vm.Config.Hardware.Device {
+		switch d := device.(type) {
+		case *types.VirtualNVDIMM:
+			devices = append(devices, fmt.Sprintf("NVDIMM %q (%d MB)", getDeviceLabel(&d.VirtualDevice), d.CapacityInMB))
+		case *types.VirtualDisk:
+			backing, ok := d.Backing.(types.BaseVirtualDeviceFileBackingInfo)
+			if !ok {
+				continue
+			}
+			info := backing.GetVirtualDeviceFileBackingInfo()
+			if info.Datastore != nil && pmemDatastores[*info.Datastore] {
+				devices = append(devices, fmt.Sprintf("disk %q (%s)", getDeviceLabel(&d.VirtualDevice), info.FileName))
+			}
+		}
+	}
+	return devices, nil
+}
@@ -0,0 +1,111 @@
+package check
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMPMemUsage(t *testing.T) {
+	tests := []struct {
+		name            string
+		nvdimm          bool
+		pmemDatastore   bool
+		expectedDevices []string
+	}{
+		{
+			name: "no PMem",
+		},
+		{
+			name:            "NVDIMM",
+			nvdimm:          true,
+			expectedDevices: []string{`NVDIMM "NVDIMM 1" (1024 MB)`},
+		},
+		{
+			name:            "disk on PMem datastore",
+			pmemDatastore:   true,
+			expectedDevices: []string{`disk "disk-202-0" ([LocalDS_0] DC0_H0_VM0/disk1.vmdk)`},
+		},
+		{
+			name:            "NVDIMM and disk on PMem datastore",
+			nvdimm:          true,
+			pmemDatastore:   true,
+			expectedDevices: []string{`disk "disk-202-0" ([LocalDS_0] DC0_H0_VM0/disk1.vmdk)`, `NVDIMM "NVDIMM 1" (1024 MB)`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMPMemUsage{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			if test.pmemDatastore {
+				for _, device := range vm.Config.Hardware.Device {
+					if disk, ok := device.(*types.VirtualDisk); ok {
+						ref := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo).Datastore
+						ds := simulator.Map.Get(*ref).(*simulator.Datastore)
+						ds.Summary.Type = string(types.HostFileSystemVolumeFileSystemTypePMEM)
+					}
+				}
+			}
+			if test.nvdimm {
+				nvdimm := &types.VirtualNVDIMM{VirtualDevice: labeledDevice(27000, "NVDIMM 1", true), CapacityInMB: 1024}
+				vm.Config.Hardware.Device = append(vm.Config.Hardware.Device, nvdimm)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			devices, err := getVMPMemDevices(ctx, vm)
+			if err != nil {
+				t.Fatalf("getVMPMemDevices failed: %s", err)
+			}
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(devices, test.expectedDevices) {
+				t.Errorf("Expected PMem devices %q, got %q", test.expectedDevices, devices)
+			}
+			expectedCount := 0
+			if len(test.expectedDevices) > 0 {
+				expectedCount = 1
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_pmem_total [ALPHA] Number of vSphere node VMs with NVDIMM devices or disks on persistent memory (PMem) datastores.
+# TYPE vsphere_node_pmem_total gauge
+vsphere_node_pmem_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_pmem_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package check
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Prefix of extraConfig keys of VM remote display (MKS and VNC) settings.
+	remoteDisplayPrefix = "remotedisplay."
+)
+
+var (
+	// remoteDisplayAllowedValues lists remote display settings that may be set on node VMs, lowercase
+	// key -> allowed lowercase values. The allowed values are vSphere defaults and values recommended
+	// by the vSphere Security Configuration Guide. All other remote display settings are reported.
+	remoteDisplayAllowedValues = map[string][]string{
+		"remotedisplay.maxconnections": {"1"},
+		"remotedisplay.vnc.enabled":    {"false"},
+	}
+
+	remoteDisplayNonDefaultMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_remote_display_non_default_total",
+			Help:           "Number of vSphere node VMs with non-default remote display settings.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(remoteDisplayNonDefaultMetric)
+}
+
+// CheckNodeVMMaxMksConnections makes sure that node VMs have default remote display (MKS) settings.
+// Changed remote display settings, such as more console connections or an enabled VNC server, allow
+// more access to the node console than necessary and they indicate configuration drift.
+type CheckNodeVMMaxMksConnections struct {
+	nonDefaultLock  sync.Mutex
+	nonDefaultCount int
+}
+
+var _ NodeCheck = &CheckNodeVMMaxMksConnections{}
+
+func (c *CheckNodeVMMaxMksConnections) Name() string {
+	return "CheckNodeVMMaxMksConnections"
+}
+
+func (c *CheckNodeVMMaxMksConnections) StartCheck() error {
+	c.nonDefaultLock.Lock()
+	defer c.nonDefaultLock.Unlock()
+	c.nonDefaultCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMMaxMksConnections) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil {
+		klog.V(4).Infof("... the node has no configuration")
+		return nil
+	}
+
+	var settings []string
+	for _, option := range vm.Config.ExtraConfig {
+		o := option.GetOptionValue()
+		key := strings.ToLower(o.Key)
+		if !strings.HasPrefix(key, remoteDisplayPrefix) {
+			continue
+		}
+		value := fmt.Sprintf("%v", o.Value)
+		if isRemoteDisplayValueAllowed(key, value) {
+			continue
+		}
+		settings = append(settings, fmt.Sprintf("%s=%s", o.Key, value))
+	}
+	if len(settings) == 0 {
+		klog.V(4).Infof("... the node has default remote display settings")
+		return nil
+	}
+	sort.Strings(settings)
+
+	c.nonDefaultLock.Lock()
+	c.nonDefaultCount++
+	c.nonDefaultLock.Unlock()
+	return fmt.Errorf("node %s has non-default remote display settings: %s", node.Name, strings.Join(settings, ", "))
+}
+
+func (c *CheckNodeVMMaxMksConnections) FinishCheck(ctx *CheckContext) {
+	c.nonDefaultLock.Lock()
+	defer c.nonDefaultLock.Unlock()
+	remoteDisplayNonDefaultMetric.WithLabelValues().Set(float64(c.nonDefaultCount))
+	return
+}
+
+// isRemoteDisplayValueAllowed returns true if the lowercase remote display key may be set to given value.
+func isRemoteDisplayValueAllowed(key, value string) bool {
+	for _, allowed := range remoteDisplayAllowedValues[key] {
+		if strings.EqualFold(value, allowed) {
+			return true
+		}
+	}
+	return false
+}
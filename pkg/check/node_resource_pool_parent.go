@@ -0,0 +1,142 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Maximum number of resource pools between the root resource pool of a cluster and a node VM.
+	maxResourcePoolNesting = 1
+)
+
+// CheckNodeVMResourcePoolParentIsCluster makes sure that node VMs run in the root resource pool of their cluster
+// or in a resource pool directly under it. The installer does not expect node VMs in nested resource pools
+// or in vApps. Deeper nesting is allowed when a resource pool is configured in Workspace.ResourcePoolPath.
+type CheckNodeVMResourcePoolParentIsCluster struct {
+	unexpectedParentLock  sync.Mutex
+	unexpectedParentCount int
+}
+
+var _ NodeCheck = &CheckNodeVMResourcePoolParentIsCluster{}
+
+var (
+	resourcePoolUnexpectedParentMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_resource_pool_unexpected_parent_total",
+			Help:           "Number of vSphere node VMs in resource pools that are nested or in vApps.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(resourcePoolUnexpectedParentMetric)
+}
+
+func (c *CheckNodeVMResourcePoolParentIsCluster) Name() string {
+	return "CheckNodeVMResourcePoolParentIsCluster"
+}
+
+func (c *CheckNodeVMResourcePoolParentIsCluster) StartCheck() error {
+	c.unexpectedParentLock.Lock()
+	defer c.unexpectedParentLock.Unlock()
+	c.unexpectedParentCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMResourcePoolParentIsCluster) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.ResourcePool == nil {
+		klog.V(4).Infof("... the node VM has no resource pool")
+		return nil
+	}
+
+	chain, err := getResourcePoolChain(ctx, *vm.ResourcePool)
+	if err != nil {
+		return fmt.Errorf("failed to get resource pool parents of node %s: %s", node.Name, err)
+	}
+	problem := getResourcePoolChainProblem(chain, ctx.VMConfig.Workspace.ResourcePoolPath != "")
+	if problem == "" {
+		klog.V(4).Infof("... the node VM is in resource pool %s", formatResourcePoolChain(chain))
+		return nil
+	}
+
+	c.unexpectedParentLock.Lock()
+	c.unexpectedParentCount++
+	c.unexpectedParentLock.Unlock()
+	return fmt.Errorf("node %s VM %s: %s", node.Name, problem, formatResourcePoolChain(chain))
+}
+
+func (c *CheckNodeVMResourcePoolParentIsCluster) FinishCheck(ctx *CheckContext) {
+	c.unexpectedParentLock.Lock()
+	defer c.unexpectedParentLock.Unlock()
+	resourcePoolUnexpectedParentMetric.WithLabelValues().Set(float64(c.unexpectedParentCount))
+	return
+}
+
+// getResourcePoolChain returns the resource pool and all its parents up to the first parent that is not
+// a resource pool or a vApp, usually the compute resource that owns the pools. The pool is the last one.
+func getResourcePoolChain(ctx *CheckContext, pool types.ManagedObjectReference) ([]mo.ManagedEntity, error) {
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	pc := property.DefaultCollector(ctx.VMClient)
+
+	var chain []mo.ManagedEntity
+	ref := &pool
+	for ref != nil {
+		var entity mo.ManagedEntity
+		if err := pc.RetrieveOne(tctx, *ref, []string{"name", "parent"}, &entity); err != nil {
+			return nil, err
+		}
+		chain = append([]mo.ManagedEntity{entity}, chain...)
+		if !isResourcePoolType(ref.Type) {
+			break
+		}
+		ref = entity.Parent
+	}
+	return chain, nil
+}
+
+// getResourcePoolChainProblem returns description of unexpected parentage of a resource pool
+// or an empty string when the chain is as expected.
+func getResourcePoolChainProblem(chain []mo.ManagedEntity, nestingAllowed bool) string {
+	owner := chain[0].Self.Type
+	if owner != "ClusterComputeResource" && owner != "ComputeResource" {
+		return "is in resource pool that does not belong to a cluster or a host"
+	}
+	for _, entity := range chain[1:] {
+		if entity.Self.Type == "VirtualApp" {
+			return fmt.Sprintf("is in vApp %s", entity.Name)
+		}
+	}
+	// The owner and its root resource pool are not counted
+	nesting := len(chain) - 2
+	if nesting > maxResourcePoolNesting && !nestingAllowed {
+		return fmt.Sprintf("is in resource pool nested %d levels below the root resource pool", nesting)
+	}
+	return ""
+}
+
+// formatResourcePoolChain returns names of the resource pool and its parents as a path.
+func formatResourcePoolChain(chain []mo.ManagedEntity) string {
+	var names []string
+	for _, entity := range chain {
+		names = append(names, entity.Name)
+	}
+	return strings.Join(names, "/")
+}
+
+func isResourcePoolType(kind string) bool {
+	return kind == "ResourcePool" || kind == "VirtualApp"
+}
@@ -0,0 +1,125 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMResourcePoolParentIsCluster(t *testing.T) {
+	tests := []struct {
+		name             string
+		node             *v1.Node
+		pools            []string
+		resourcePoolPath string
+		expectError      string
+		expectedCount    int
+	}{
+		{
+			name: "root resource pool",
+			node: defaultNodes()[0],
+		},
+		{
+			name:  "resource pool under root",
+			node:  defaultNodes()[0],
+			pools: []string{"pool1"},
+		},
+		{
+			name:          "nested resource pool",
+			node:          defaultNodes()[0],
+			pools:         []string{"pool1", "pool2"},
+			expectError:   "node DC0_H0_VM0 VM is in resource pool nested 2 levels below the root resource pool: DC0_H0/Resources/pool1/pool2",
+			expectedCount: 1,
+		},
+		{
+			name:             "configured nested resource pool",
+			node:             defaultNodes()[0],
+			pools:            []string{"pool1", "pool2"},
+			resourcePoolPath: "/DC0/host/DC0_H0/Resources/pool1/pool2",
+		},
+		{
+			name:          "vApp",
+			node:          clusterNodes()[2],
+			expectError:   "node DC0_C0_APP0_VM0 VM is in vApp DC0_C0_APP0: DC0_C0/Resources/DC0_C0_APP0",
+			expectedCount: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMResourcePoolParentIsCluster{}
+			kubeClient := &fakeKubeClient{
+				nodes: []*v1.Node{test.node},
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMConfig.Workspace.ResourcePoolPath = test.resourcePoolPath
+
+			node := test.node
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			if len(test.pools) > 0 {
+				finder := find.NewFinder(ctx.VMClient, true)
+				pool, err := finder.ResourcePool(ctx.Context, defaultHostPath+defaultHost+"/Resources")
+				if err != nil {
+					t.Fatalf("Failed to find root resource pool: %s", err)
+				}
+				for _, name := range test.pools {
+					pool, err = pool.Create(ctx.Context, name, types.DefaultResourceConfigSpec())
+					if err != nil {
+						t.Fatalf("Failed to create resource pool %s: %s", name, err)
+					}
+				}
+				// Move the VM to the last pool directly, vcsim does not move VMs between pools
+				ref := pool.Reference()
+				simVM := simulator.Map.Get(vm.Reference()).(*simulator.VirtualMachine)
+				simVM.ResourcePool = &ref
+				vm.ResourcePool = &ref
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if test.expectError == "" && err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if test.expectError != "" {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectError)
+				} else if err.Error() != test.expectError {
+					t.Errorf("Expected error %q, got %q", test.expectError, err.Error())
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_resource_pool_unexpected_parent_total [ALPHA] Number of vSphere node VMs in resource pools that are nested or in vApps.
+# TYPE vsphere_node_resource_pool_unexpected_parent_total gauge
+vsphere_node_resource_pool_unexpected_parent_total %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_resource_pool_unexpected_parent_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package check
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// extraConfig key that disables the HGFS (shared folders) server in VMware Tools.
+	hgfsServerSetDisableKey = "isolation.tools.hgfsServerSet.disable"
+)
+
+var (
+	// sharedFolderEnabledRegexp matches extraConfig keys of individual shared folders, e.g. sharedFolder0.enabled.
+	sharedFolderEnabledRegexp = regexp.MustCompile(`^sharedFolder[0-9]+\.enabled$`)
+
+	sharedFoldersEnabledMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_shared_folders_enabled_total",
+			Help:           "Number of vSphere node VMs with VMware Tools shared folders (HGFS) enabled.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(sharedFoldersEnabledMetric)
+}
+
+// CheckNodeVMToolsSharedFolders makes sure that VMware Tools shared folders (HGFS) are not enabled on
+// node VMs. Shared folders expose host files to the guest, they are not used by RHCOS and they are
+// a security and stability risk.
+type CheckNodeVMToolsSharedFolders struct {
+	sharedFoldersLock  sync.Mutex
+	sharedFoldersCount int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsSharedFolders{}
+
+func (c *CheckNodeVMToolsSharedFolders) Name() string {
+	return "CheckNodeVMToolsSharedFolders"
+}
+
+func (c *CheckNodeVMToolsSharedFolders) StartCheck() error {
+	c.sharedFoldersLock.Lock()
+	defer c.sharedFoldersLock.Unlock()
+	c.sharedFoldersCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsSharedFolders) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil {
+		klog.V(4).Infof("... the node has no configuration")
+		return nil
+	}
+
+	var enabled []string
+	for _, option := range vm.Config.ExtraConfig {
+		o := option.GetOptionValue()
+		value := fmt.Sprintf("%v", o.Value)
+		switch {
+		case o.Key == hgfsServerSetDisableKey && strings.EqualFold(value, "false"):
+			enabled = append(enabled, fmt.Sprintf("%s=%s", o.Key, value))
+		case sharedFolderEnabledRegexp.MatchString(o.Key) && strings.EqualFold(value, "true"):
+			enabled = append(enabled, fmt.Sprintf("%s=%s", o.Key, value))
+		}
+	}
+	if len(enabled) == 0 {
+		klog.V(4).Infof("... the node has shared folders disabled")
+		return nil
+	}
+	sort.Strings(enabled)
+
+	c.sharedFoldersLock.Lock()
+	c.sharedFoldersCount++
+	c.sharedFoldersLock.Unlock()
+	return fmt.Errorf("node %s has VMware Tools shared folders (HGFS) enabled: %s", node.Name, strings.Join(enabled, ", "))
+}
+
+func (c *CheckNodeVMToolsSharedFolders) FinishCheck(ctx *CheckContext) {
+	c.sharedFoldersLock.Lock()
+	defer c.sharedFoldersLock.Unlock()
+	sharedFoldersEnabledMetric.WithLabelValues().Set(float64(c.sharedFoldersCount))
+	return
+}
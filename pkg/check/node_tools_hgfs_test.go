@@ -0,0 +1,110 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsSharedFolders(t *testing.T) {
+	tests := []struct {
+		name          string
+		extraConfig   map[string]string
+		expectedError string
+	}{
+		{
+			name: "no extraConfig",
+		},
+		{
+			name: "HGFS disabled",
+			extraConfig: map[string]string{
+				"isolation.tools.hgfsServerSet.disable": "TRUE",
+				"sharedFolder0.enabled":                 "FALSE",
+			},
+		},
+		{
+			name: "HGFS server enabled",
+			extraConfig: map[string]string{
+				"isolation.tools.hgfsServerSet.disable": "FALSE",
+			},
+			expectedError: "node DC0_H0_VM0 has VMware Tools shared folders (HGFS) enabled: isolation.tools.hgfsServerSet.disable=FALSE",
+		},
+		{
+			name: "shared folders enabled",
+			extraConfig: map[string]string{
+				"sharedFolder0.enabled": "TRUE",
+				"sharedFolder1.enabled": "true",
+			},
+			expectedError: "node DC0_H0_VM0 has VMware Tools shared folders (HGFS) enabled: sharedFolder0.enabled=TRUE, sharedFolder1.enabled=true",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMToolsSharedFolders{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			if len(test.extraConfig) > 0 {
+				var extraConfig []types.BaseOptionValue
+				for key, value := range test.extraConfig {
+					extraConfig = append(extraConfig, &types.OptionValue{Key: key, Value: value})
+				}
+				err = customizeVM(ctx, node, &types.VirtualMachineConfigSpec{ExtraConfig: extraConfig})
+				if err != nil {
+					t.Fatalf("Failed to customize node: %s", err)
+				}
+			}
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_shared_folders_enabled_total [ALPHA] Number of vSphere node VMs with VMware Tools shared folders (HGFS) enabled.
+# TYPE vsphere_node_shared_folders_enabled_total gauge
+vsphere_node_shared_folders_enabled_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_shared_folders_enabled_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
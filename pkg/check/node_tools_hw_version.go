@@ -0,0 +1,142 @@
+package check
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	toolsHWVersionMismatchMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_tools_hw_version_mismatch_total",
+			Help:           "Number of vSphere node VMs with VMware Tools older than required by their virtual hardware version.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+
+	// minToolsVersionForHWVersion maps virtual hardware version -> encoded version of VMware Tools
+	// bundled with the ESXi release that introduced the hardware version. Older tools do not know
+	// the virtual devices of the hardware version.
+	// Tools version is encoded as major*1024 + minor*32 + patch, the same way as guest.toolsVersion.
+	minToolsVersionForHWVersion = map[int]int{
+		13: encodeToolsVersion(10, 1, 0), // ESXi 6.5
+		14: encodeToolsVersion(10, 2, 0), // ESXi 6.7
+		15: encodeToolsVersion(10, 3, 5), // ESXi 6.7 U2
+		17: encodeToolsVersion(11, 0, 5), // ESXi 7.0
+		18: encodeToolsVersion(11, 1, 1), // ESXi 7.0 U1
+		19: encodeToolsVersion(11, 2, 5), // ESXi 7.0 U2
+		20: encodeToolsVersion(12, 0, 6), // ESXi 8.0
+		21: encodeToolsVersion(12, 2, 5), // ESXi 8.0 U2
+	}
+)
+
+func init() {
+	legacyregistry.MustRegister(toolsHWVersionMismatchMetric)
+}
+
+// CheckNodeVMToolsUpgradeRequiredForHWVersion makes sure that VMware Tools of node VMs are not older
+// than the hardware version of the VM requires. After a hardware version upgrade, old tools may
+// not handle the new virtual devices correctly.
+type CheckNodeVMToolsUpgradeRequiredForHWVersion struct {
+	mismatchLock  sync.Mutex
+	mismatchCount int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsUpgradeRequiredForHWVersion{}
+
+func (c *CheckNodeVMToolsUpgradeRequiredForHWVersion) Name() string {
+	return "CheckNodeVMToolsUpgradeRequiredForHWVersion"
+}
+
+func (c *CheckNodeVMToolsUpgradeRequiredForHWVersion) StartCheck() error {
+	c.mismatchLock.Lock()
+	defer c.mismatchLock.Unlock()
+	c.mismatchCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsUpgradeRequiredForHWVersion) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil || vm.Guest == nil {
+		klog.V(4).Infof("... the node has no config or guest info")
+		return nil
+	}
+	hwVersion, err := parseHWVersion(vm.Config.Version)
+	if err != nil {
+		klog.V(2).Infof("Failed to parse hardware version of node %s: %s", node.Name, err)
+		return nil
+	}
+	toolsVersion, err := strconv.Atoi(vm.Guest.ToolsVersion)
+	if err != nil || toolsVersion == 0 {
+		klog.V(4).Infof("... the node has no VMware Tools version: %q", vm.Guest.ToolsVersion)
+		return nil
+	}
+
+	minVersion := getMinToolsVersionForHWVersion(hwVersion)
+	if toolsVersion >= minVersion {
+		klog.V(4).Infof("... the node has VMware Tools %s matching hardware version %s", formatToolsVersion(toolsVersion), vm.Config.Version)
+		return nil
+	}
+
+	c.mismatchLock.Lock()
+	c.mismatchCount++
+	c.mismatchLock.Unlock()
+	return fmt.Errorf("node %s has hardware version %s, but its VMware Tools version %s (%d) is older than the required %s, upgrade VMware Tools", node.Name, vm.Config.Version, formatToolsVersion(toolsVersion), toolsVersion, formatToolsVersion(minVersion))
+}
+
+func (c *CheckNodeVMToolsUpgradeRequiredForHWVersion) FinishCheck(ctx *CheckContext) {
+	c.mismatchLock.Lock()
+	defer c.mismatchLock.Unlock()
+	toolsHWVersionMismatchMetric.WithLabelValues().Set(float64(c.mismatchCount))
+	return
+}
+
+// parseHWVersion parses hardware version in format "vmx-13".
+func parseHWVersion(version string) (int, error) {
+	if !strings.HasPrefix(version, "vmx-") {
+		return 0, fmt.Errorf("unknown hardware version format %q", version)
+	}
+	hwVersion, err := strconv.Atoi(strings.TrimPrefix(version, "vmx-"))
+	if err != nil {
+		return 0, fmt.Errorf("unknown hardware version format %q: %s", version, err)
+	}
+	return hwVersion, nil
+}
+
+// getMinToolsVersionForHWVersion returns encoded minimum VMware Tools version for given hardware version.
+// Hardware versions not present in minToolsVersionForHWVersion inherit the requirement of the closest
+// older one. It returns 0 when there is no requirement.
+func getMinToolsVersionForHWVersion(hwVersion int) int {
+	var hwVersions []int
+	for v := range minToolsVersionForHWVersion {
+		hwVersions = append(hwVersions, v)
+	}
+	sort.Ints(hwVersions)
+
+	minVersion := 0
+	for _, v := range hwVersions {
+		if v > hwVersion {
+			break
+		}
+		minVersion = minToolsVersionForHWVersion[v]
+	}
+	return minVersion
+}
+
+func encodeToolsVersion(major, minor, patch int) int {
+	return major*1024 + minor*32 + patch
+}
+
+// formatToolsVersion returns human readable form of encoded VMware Tools version, e.g. 11333 -> "11.2.5".
+func formatToolsVersion(version int) string {
+	return fmt.Sprintf("%d.%d.%d", version/1024, (version/32)%32, version%32)
+}
@@ -0,0 +1,119 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsUpgradeRequiredForHWVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		hwVersion     string
+		toolsVersion  string
+		expectedError string
+	}{
+		{
+			name:         "tools match hardware version",
+			hwVersion:    "vmx-13",
+			toolsVersion: "10272",
+		},
+		{
+			name:         "newer tools",
+			hwVersion:    "vmx-15",
+			toolsVersion: "11333",
+		},
+		{
+			name:         "hardware version without known requirement",
+			hwVersion:    "vmx-11",
+			toolsVersion: "9216",
+		},
+		{
+			name:         "hardware version inherits requirement of older version",
+			hwVersion:    "vmx-16",
+			toolsVersion: "10341",
+		},
+		{
+			name:         "tools not installed",
+			hwVersion:    "vmx-19",
+			toolsVersion: "0",
+		},
+		{
+			name:         "unknown hardware version format",
+			hwVersion:    "foo",
+			toolsVersion: "9216",
+		},
+		{
+			name:          "tools too old for hardware version",
+			hwVersion:     "vmx-13",
+			toolsVersion:  "10240",
+			expectedError: "node DC0_H0_VM0 has hardware version vmx-13, but its VMware Tools version 10.0.0 (10240) is older than the required 10.1.0, upgrade VMware Tools",
+		},
+		{
+			name:          "tools too old after hardware upgrade",
+			hwVersion:     "vmx-19",
+			toolsVersion:  "11297",
+			expectedError: "node DC0_H0_VM0 has hardware version vmx-19, but its VMware Tools version 11.1.1 (11297) is older than the required 11.2.5, upgrade VMware Tools",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMToolsUpgradeRequiredForHWVersion{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			vm.Config.Version = test.hwVersion
+			vm.Guest.ToolsVersion = test.toolsVersion
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_tools_hw_version_mismatch_total [ALPHA] Number of vSphere node VMs with VMware Tools older than required by their virtual hardware version.
+# TYPE vsphere_node_tools_hw_version_mismatch_total gauge
+vsphere_node_tools_hw_version_mismatch_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_tools_hw_version_mismatch_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package check
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	toolsInstallerMountedMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_tools_installer_mounted_total",
+			Help:           "Number of vSphere node VMs with VMware Tools installer mounted.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(toolsInstallerMountedMetric)
+}
+
+// CheckNodeVMToolsInstallerMountedBlockingEject makes sure that no node VM has VMware Tools installer mounted.
+// The mounted installer ISO blocks vMotion of the VM and it usually means that a VMware Tools upgrade got stuck.
+type CheckNodeVMToolsInstallerMountedBlockingEject struct {
+	installerMountedLock  sync.Mutex
+	installerMountedCount int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsInstallerMountedBlockingEject{}
+
+func (c *CheckNodeVMToolsInstallerMountedBlockingEject) Name() string {
+	return "CheckNodeVMToolsInstallerMountedBlockingEject"
+}
+
+func (c *CheckNodeVMToolsInstallerMountedBlockingEject) StartCheck() error {
+	c.installerMountedLock.Lock()
+	defer c.installerMountedLock.Unlock()
+	c.installerMountedCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsInstallerMountedBlockingEject) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if !vm.Runtime.ToolsInstallerMounted {
+		klog.V(4).Infof("... the node does not have VMware Tools installer mounted")
+		return nil
+	}
+
+	c.installerMountedLock.Lock()
+	c.installerMountedCount++
+	c.installerMountedLock.Unlock()
+	return fmt.Errorf("node %s has VMware Tools installer mounted, which blocks vMotion of the VM: finish or cancel the VMware Tools upgrade to unmount it", node.Name)
+}
+
+func (c *CheckNodeVMToolsInstallerMountedBlockingEject) FinishCheck(ctx *CheckContext) {
+	c.installerMountedLock.Lock()
+	defer c.installerMountedLock.Unlock()
+	toolsInstallerMountedMetric.WithLabelValues().Set(float64(c.installerMountedCount))
+	return
+}
@@ -0,0 +1,95 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	toolsBelowMinimumMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_tools_below_minimum_total",
+			Help:           "Number of vSphere node VMs with VMware Tools older than the minimum version supported by their ESXi host.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(toolsBelowMinimumMetric)
+}
+
+// CheckNodeVMToolsVersionBelowHostMinimum makes sure that VMware Tools of node VMs are not older than
+// the minimum version supported by the ESXi host where the VM runs. ESXi refuses guest operations with
+// such tools. vSphere does not expose the minimum version itself, the check relies on the ESXi host
+// evaluation in guest.toolsVersionStatus2. Tools that are only behind the recommended version are not
+// reported.
+type CheckNodeVMToolsVersionBelowHostMinimum struct {
+	belowMinimumLock  sync.Mutex
+	belowMinimumCount int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsVersionBelowHostMinimum{}
+
+func (c *CheckNodeVMToolsVersionBelowHostMinimum) Name() string {
+	return "CheckNodeVMToolsVersionBelowHostMinimum"
+}
+
+func (c *CheckNodeVMToolsVersionBelowHostMinimum) StartCheck() error {
+	c.belowMinimumLock.Lock()
+	defer c.belowMinimumLock.Unlock()
+	c.belowMinimumCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsVersionBelowHostMinimum) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Guest == nil {
+		klog.V(4).Infof("... the node has no guest info")
+		return nil
+	}
+	status := types.VirtualMachineToolsVersionStatus(vm.Guest.ToolsVersionStatus2)
+	switch status {
+	case types.VirtualMachineToolsVersionStatusGuestToolsTooOld, types.VirtualMachineToolsVersionStatusGuestToolsBlacklisted:
+	default:
+		klog.V(4).Infof("... the node has VMware Tools version %s with status %q", vm.Guest.ToolsVersion, status)
+		return nil
+	}
+
+	c.belowMinimumLock.Lock()
+	c.belowMinimumCount++
+	c.belowMinimumLock.Unlock()
+
+	hostDescription := "its ESXi host"
+	if vm.Runtime.Host != nil {
+		tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+		defer cancel()
+		var host mo.HostSystem
+		pc := property.DefaultCollector(ctx.VMClient)
+		if err := pc.RetrieveOne(tctx, *vm.Runtime.Host, []string{"name", "summary.config.product"}, &host); err != nil {
+			klog.V(2).Infof("Failed to get ESXi host %s of node %s: %s", vm.Runtime.Host.Value, node.Name, err)
+		} else {
+			hostDescription = "ESXi host " + host.Name
+			if product := host.Summary.Config.Product; product != nil {
+				hostDescription = fmt.Sprintf("ESXi host %s (%s build %s)", host.Name, product.Version, product.Build)
+			}
+		}
+	}
+	return fmt.Errorf("node %s has VMware Tools version %s below the minimum supported by %s, guest operations will fail: guest.toolsVersionStatus2 is %s", node.Name, vm.Guest.ToolsVersion, hostDescription, status)
+}
+
+func (c *CheckNodeVMToolsVersionBelowHostMinimum) FinishCheck(ctx *CheckContext) {
+	c.belowMinimumLock.Lock()
+	defer c.belowMinimumLock.Unlock()
+	toolsBelowMinimumMetric.WithLabelValues().Set(float64(c.belowMinimumCount))
+	return
+}
@@ -0,0 +1,105 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsVersionBelowHostMinimum(t *testing.T) {
+	tests := []struct {
+		name          string
+		toolsVersion  string
+		toolsStatus   types.VirtualMachineToolsVersionStatus
+		expectedError string
+	}{
+		{
+			name:         "current tools",
+			toolsVersion: "11333",
+			toolsStatus:  types.VirtualMachineToolsVersionStatusGuestToolsCurrent,
+		},
+		{
+			name:         "tools behind recommended version",
+			toolsVersion: "10346",
+			toolsStatus:  types.VirtualMachineToolsVersionStatusGuestToolsSupportedOld,
+		},
+		{
+			name:         "unmanaged tools",
+			toolsVersion: "2147483647",
+			toolsStatus:  types.VirtualMachineToolsVersionStatusGuestToolsUnmanaged,
+		},
+		{
+			name:          "tools too old",
+			toolsVersion:  "9216",
+			toolsStatus:   types.VirtualMachineToolsVersionStatusGuestToolsTooOld,
+			expectedError: "node DC0_H0_VM0 has VMware Tools version 9216 below the minimum supported by ESXi host DC0_H0 (6.5.0 build 5969303), guest operations will fail: guest.toolsVersionStatus2 is guestToolsTooOld",
+		},
+		{
+			name:          "blacklisted tools",
+			toolsVersion:  "10240",
+			toolsStatus:   types.VirtualMachineToolsVersionStatusGuestToolsBlacklisted,
+			expectedError: "node DC0_H0_VM0 has VMware Tools version 10240 below the minimum supported by ESXi host DC0_H0 (6.5.0 build 5969303), guest operations will fail: guest.toolsVersionStatus2 is guestToolsBlacklisted",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMToolsVersionBelowHostMinimum{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			vm.Guest.ToolsVersion = test.toolsVersion
+			vm.Guest.ToolsVersionStatus2 = string(test.toolsStatus)
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_tools_below_minimum_total [ALPHA] Number of vSphere node VMs with VMware Tools older than the minimum version supported by their ESXi host.
+# TYPE vsphere_node_tools_below_minimum_total gauge
+vsphere_node_tools_below_minimum_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_tools_below_minimum_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
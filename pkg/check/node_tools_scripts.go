@@ -0,0 +1,89 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMToolsScriptsLeftEnabled makes sure that VMware Tools power operation scripts are not
+// enabled on node VMs. The scripts can interfere with RHCOS lifecycle.
+type CheckNodeVMToolsScriptsLeftEnabled struct {
+	scriptsEnabledLock  sync.Mutex
+	scriptsEnabledCount int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsScriptsLeftEnabled{}
+
+var (
+	toolsScriptsEnabledMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_tools_scripts_enabled_total",
+			Help:           "Number of vSphere node VMs with VMware Tools power operation scripts enabled.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(toolsScriptsEnabledMetric)
+}
+
+func (c *CheckNodeVMToolsScriptsLeftEnabled) Name() string {
+	return "CheckNodeVMToolsScriptsLeftEnabled"
+}
+
+func (c *CheckNodeVMToolsScriptsLeftEnabled) StartCheck() error {
+	c.scriptsEnabledLock.Lock()
+	defer c.scriptsEnabledLock.Unlock()
+	c.scriptsEnabledCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsScriptsLeftEnabled) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil || vm.Config.Tools == nil {
+		klog.V(4).Infof("... the node has no VMware Tools configuration")
+		return nil
+	}
+
+	tools := vm.Config.Tools
+	scripts := []struct {
+		name    string
+		enabled *bool
+	}{
+		{"afterPowerOn", tools.AfterPowerOn},
+		{"afterResume", tools.AfterResume},
+		{"beforeGuestStandby", tools.BeforeGuestStandby},
+		{"beforeGuestShutdown", tools.BeforeGuestShutdown},
+		{"beforeGuestReboot", tools.BeforeGuestReboot},
+	}
+	var enabled []string
+	for _, script := range scripts {
+		if script.enabled != nil && *script.enabled {
+			enabled = append(enabled, script.name)
+		}
+	}
+	if len(enabled) == 0 {
+		klog.V(4).Infof("... the node has no VMware Tools scripts enabled")
+		return nil
+	}
+
+	c.scriptsEnabledLock.Lock()
+	c.scriptsEnabledCount++
+	c.scriptsEnabledLock.Unlock()
+	return fmt.Errorf("node %s has VMware Tools power operation scripts enabled: %s", node.Name, strings.Join(enabled, ", "))
+}
+
+func (c *CheckNodeVMToolsScriptsLeftEnabled) FinishCheck(ctx *CheckContext) {
+	c.scriptsEnabledLock.Lock()
+	defer c.scriptsEnabledLock.Unlock()
+	toolsScriptsEnabledMetric.WithLabelValues().Set(float64(c.scriptsEnabledCount))
+	return
+}
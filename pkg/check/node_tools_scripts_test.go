@@ -0,0 +1,99 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsScriptsLeftEnabled(t *testing.T) {
+	tests := []struct {
+		name          string
+		tools         *types.ToolsConfigInfo
+		expectedError string
+	}{
+		{
+			name:  "no tools config",
+			tools: nil,
+		},
+		{
+			name: "scripts disabled",
+			tools: &types.ToolsConfigInfo{
+				AfterPowerOn:        types.NewBool(false),
+				AfterResume:         types.NewBool(false),
+				BeforeGuestStandby:  types.NewBool(false),
+				BeforeGuestShutdown: types.NewBool(false),
+				BeforeGuestReboot:   types.NewBool(false),
+			},
+		},
+		{
+			name: "scripts enabled",
+			tools: &types.ToolsConfigInfo{
+				AfterPowerOn:        types.NewBool(true),
+				AfterResume:         types.NewBool(false),
+				BeforeGuestShutdown: types.NewBool(true),
+			},
+			expectedError: "node DC0_H0_VM0 has VMware Tools power operation scripts enabled: afterPowerOn, beforeGuestShutdown",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMToolsScriptsLeftEnabled{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			vm.Config.Tools = test.tools
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_tools_scripts_enabled_total [ALPHA] Number of vSphere node VMs with VMware Tools power operation scripts enabled.
+# TYPE vsphere_node_tools_scripts_enabled_total gauge
+vsphere_node_tools_scripts_enabled_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_tools_scripts_enabled_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
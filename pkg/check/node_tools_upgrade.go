@@ -0,0 +1,107 @@
+package check
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DescriptionId of UpgradeTools_Task
+	upgradeToolsTaskDescriptionID = "VirtualMachine.upgradeTools"
+)
+
+var (
+	// toolsUpgradeStuckTimeout is the time after which a running VMware Tools upgrade is considered stuck.
+	toolsUpgradeStuckTimeout = flag.Duration("tools-upgrade-stuck-timeout", time.Hour, "Time after which an unfinished VMware Tools upgrade of a node VM is reported as stuck.")
+
+	toolsUpgradeStuckMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_tools_upgrade_stuck_total",
+			Help:           "Number of vSphere node VMs with VMware Tools upgrade stuck in progress.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(toolsUpgradeStuckMetric)
+}
+
+// CheckNodeVMToolsUpgradeStatusStuck makes sure that no node VM has VMware Tools upgrade
+// that is running for too long. Such upgrade indicates a failed guest operation.
+type CheckNodeVMToolsUpgradeStatusStuck struct {
+	stuckLock  sync.Mutex
+	stuckCount int
+}
+
+var _ NodeCheck = &CheckNodeVMToolsUpgradeStatusStuck{}
+
+func (c *CheckNodeVMToolsUpgradeStatusStuck) Name() string {
+	return "CheckNodeVMToolsUpgradeStatusStuck"
+}
+
+func (c *CheckNodeVMToolsUpgradeStatusStuck) StartCheck() error {
+	c.stuckLock.Lock()
+	defer c.stuckLock.Unlock()
+	c.stuckCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMToolsUpgradeStatusStuck) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if len(vm.RecentTask) == 0 {
+		klog.V(4).Infof("... the node has no recent tasks")
+		return nil
+	}
+
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var tasks []mo.Task
+	pc := property.DefaultCollector(ctx.VMClient)
+	if err := pc.Retrieve(tctx, vm.RecentTask, []string{"info"}, &tasks); err != nil {
+		return fmt.Errorf("failed to get recent tasks of node %s: %s", node.Name, err)
+	}
+
+	now := time.Now()
+	for _, task := range tasks {
+		info := task.Info
+		if info.DescriptionId != upgradeToolsTaskDescriptionID {
+			continue
+		}
+		if info.State != types.TaskInfoStateRunning && info.State != types.TaskInfoStateQueued {
+			continue
+		}
+		started := info.QueueTime
+		if info.StartTime != nil {
+			started = *info.StartTime
+		}
+		if now.Sub(started) < *toolsUpgradeStuckTimeout {
+			klog.V(4).Infof("... the node has VMware Tools upgrade %s since %s", info.State, started)
+			continue
+		}
+
+		c.stuckLock.Lock()
+		c.stuckCount++
+		c.stuckLock.Unlock()
+		return fmt.Errorf("node %s has VMware Tools upgrade stuck: task %s is %s since %s", node.Name, info.Key, info.State, started.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (c *CheckNodeVMToolsUpgradeStatusStuck) FinishCheck(ctx *CheckContext) {
+	c.stuckLock.Lock()
+	defer c.stuckLock.Unlock()
+	toolsUpgradeStuckMetric.WithLabelValues().Set(float64(c.stuckCount))
+	return
+}
@@ -0,0 +1,117 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckNodeVMToolsUpgradeStatusStuck(t *testing.T) {
+	tests := []struct {
+		name          string
+		descriptionID string
+		state         types.TaskInfoState
+		age           time.Duration
+		expectError   bool
+	}{
+		{
+			name:        "no recent tasks",
+			expectError: false,
+		},
+		{
+			name:          "tools upgrade finished",
+			descriptionID: upgradeToolsTaskDescriptionID,
+			state:         types.TaskInfoStateSuccess,
+			age:           2 * time.Hour,
+			expectError:   false,
+		},
+		{
+			name:          "tools upgrade running shortly",
+			descriptionID: upgradeToolsTaskDescriptionID,
+			state:         types.TaskInfoStateRunning,
+			age:           time.Minute,
+			expectError:   false,
+		},
+		{
+			name:          "other task running for a long time",
+			descriptionID: "VirtualMachine.reconfigure",
+			state:         types.TaskInfoStateRunning,
+			age:           2 * time.Hour,
+			expectError:   false,
+		},
+		{
+			name:          "tools upgrade stuck",
+			descriptionID: upgradeToolsTaskDescriptionID,
+			state:         types.TaskInfoStateRunning,
+			age:           2 * time.Hour,
+			expectError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMToolsUpgradeStatusStuck{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			if test.descriptionID != "" {
+				// vcsim does not track recent tasks of VMs, create the task directly
+				task := simulator.CreateTask(vm, "task", nil)
+				started := time.Now().Add(-test.age)
+				task.Info.DescriptionId = test.descriptionID
+				task.Info.State = test.state
+				task.Info.StartTime = &started
+				vm.RecentTask = append(vm.RecentTask, task.Self)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			if err != nil && !test.expectError {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if err == nil && test.expectError {
+				t.Errorf("Expected error, got none")
+			}
+			expectedCount := 0
+			if test.expectError {
+				expectedCount = 1
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_tools_upgrade_stuck_total [ALPHA] Number of vSphere node VMs with VMware Tools upgrade stuck in progress.
+# TYPE vsphere_node_tools_upgrade_stuck_total gauge
+vsphere_node_tools_upgrade_stuck_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_tools_upgrade_stuck_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
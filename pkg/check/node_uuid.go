@@ -2,6 +2,7 @@ package check
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/vmware/govmomi/vim25/mo"
 	v1 "k8s.io/api/core/v1"
@@ -9,8 +10,13 @@ import (
 )
 
 // CheckNodeDiskUUID makes sure that all nodes have disk.enableUUID=TRUE.
+// Without it, volumes get attached to the VM, but they do not show up in /dev/disk/by-id
+// and pods that use them never start. disk.EnableUUID in config.extraConfig is checked first,
+// config.flags.diskUuidEnabled is used when the VM does not have the option.
 type CheckNodeDiskUUID struct{}
 
+const diskEnableUUIDOption = "disk.EnableUUID"
+
 var _ NodeCheck = &CheckNodeDiskUUID{}
 
 func (c *CheckNodeDiskUUID) Name() string {
@@ -22,7 +28,15 @@ func (c *CheckNodeDiskUUID) StartCheck() error {
 }
 
 func (c *CheckNodeDiskUUID) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
-	if vm.Config.Flags.DiskUuidEnabled == nil {
+	if value, found := getDiskEnableUUIDOption(vm); found {
+		if !strings.EqualFold(value, "TRUE") {
+			return fmt.Errorf("the node has %s = %q in config.extraConfig, TRUE is required", diskEnableUUIDOption, value)
+		}
+		klog.V(4).Infof("... the node has correct %s", diskEnableUUIDOption)
+		return nil
+	}
+
+	if vm.Config == nil || vm.Config.Flags.DiskUuidEnabled == nil {
 		return fmt.Errorf("the node has empty disk.enableUUID")
 	}
 	if *vm.Config.Flags.DiskUuidEnabled == false {
@@ -32,6 +46,22 @@ func (c *CheckNodeDiskUUID) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.V
 	return nil
 }
 
+// getDiskEnableUUIDOption returns value of disk.EnableUUID in config.extraConfig of the VM.
+// VMX option names are case-insensitive.
+func getDiskEnableUUIDOption(vm *mo.VirtualMachine) (string, bool) {
+	if vm.Config == nil {
+		return "", false
+	}
+	for _, option := range vm.Config.ExtraConfig {
+		value := option.GetOptionValue()
+		if value == nil || !strings.EqualFold(value.Key, diskEnableUUIDOption) {
+			continue
+		}
+		return fmt.Sprintf("%v", value.Value), true
+	}
+	return "", false
+}
+
 func (c *CheckNodeDiskUUID) FinishCheck(ctx *CheckContext) {
 	return
 }
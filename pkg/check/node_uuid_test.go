@@ -72,3 +72,95 @@ func TestCheckNodeDiskUUID(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckNodeDiskUUIDExtraConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		extraConfig   []types.BaseOptionValue
+		expectedError string
+	}{
+		{
+			name: "TRUE",
+			extraConfig: []types.BaseOptionValue{
+				&types.OptionValue{Key: "disk.EnableUUID", Value: "TRUE"},
+			},
+		},
+		{
+			name: "lowercase key and value",
+			extraConfig: []types.BaseOptionValue{
+				&types.OptionValue{Key: "disk.enableuuid", Value: "true"},
+			},
+		},
+		{
+			name: "FALSE",
+			extraConfig: []types.BaseOptionValue{
+				&types.OptionValue{Key: "disk.EnableUUID", Value: "FALSE"},
+			},
+			expectedError: `the node has disk.EnableUUID = "FALSE" in config.extraConfig, TRUE is required`,
+		},
+		{
+			name: "invalid value",
+			extraConfig: []types.BaseOptionValue{
+				&types.OptionValue{Key: "disk.EnableUUID", Value: "yes"},
+			},
+			expectedError: `the node has disk.EnableUUID = "yes" in config.extraConfig, TRUE is required`,
+		},
+		{
+			name:          "missing",
+			expectedError: "the node has empty disk.enableUUID",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeDiskUUID{}
+			err := check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			if test.extraConfig != nil {
+				err = customizeVM(ctx, node, &types.VirtualMachineConfigSpec{
+					ExtraConfig: test.extraConfig,
+				})
+				if err != nil {
+					t.Fatalf("Failed to customize node: %s", err)
+				}
+			}
+
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Errorf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			// Make sure only extraConfig decides the result when it has disk.EnableUUID
+			vm.Config.Flags.DiskUuidEnabled = nil
+
+			// Act
+			err = check.CheckNode(ctx, node, vm)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+		})
+	}
+}
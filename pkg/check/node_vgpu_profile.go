@@ -0,0 +1,131 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMvGPUProfileConsistency makes sure that vGPU profiles of node VMs are supported by the
+// ESXi host where the VMs run. A VM with a vGPU profile that the host GPUs do not provide fails to power on.
+type CheckNodeVMvGPUProfileConsistency struct {
+	vGPUUnsatisfiableLock  sync.Mutex
+	vGPUUnsatisfiableCount int
+}
+
+var _ NodeCheck = &CheckNodeVMvGPUProfileConsistency{}
+
+var (
+	vGPUUnsatisfiableMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_vgpu_profile_unsatisfiable_total",
+			Help:           "Number of vSphere node VMs with vGPU profiles not supported by their ESXi host.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(vGPUUnsatisfiableMetric)
+}
+
+func (c *CheckNodeVMvGPUProfileConsistency) Name() string {
+	return "CheckNodeVMvGPUProfileConsistency"
+}
+
+func (c *CheckNodeVMvGPUProfileConsistency) StartCheck() error {
+	c.vGPUUnsatisfiableLock.Lock()
+	defer c.vGPUUnsatisfiableLock.Unlock()
+	c.vGPUUnsatisfiableCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMvGPUProfileConsistency) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	profiles := getVMvGPUProfiles(vm)
+	if len(profiles) == 0 {
+		klog.V(4).Infof("... the node has no vGPU devices")
+		return nil
+	}
+
+	hostRef := vm.Runtime.Host
+	if hostRef == nil {
+		return fmt.Errorf("error getting ESXi host for node %s: vm.runtime.host is empty", node.Name)
+	}
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var host mo.HostSystem
+	if err := pc.RetrieveOne(tctx, *hostRef, []string{"name", "config.sharedPassthruGpuTypes"}, &host); err != nil {
+		return fmt.Errorf("failed to load ESXi host %s for node %s: %s", hostRef.Value, node.Name, err)
+	}
+	var hostTypes []string
+	if host.Config != nil {
+		hostTypes = host.Config.SharedPassthruGpuTypes
+	}
+
+	supported := make(map[string]bool)
+	for _, gpuType := range hostTypes {
+		supported[gpuType] = true
+	}
+	var unsatisfiable []string
+	for _, profile := range profiles {
+		if !supported[profile] {
+			unsatisfiable = append(unsatisfiable, profile)
+		}
+	}
+	if len(unsatisfiable) == 0 {
+		klog.V(4).Infof("... the node has vGPU profiles %v supported by ESXi host %s", profiles, host.Name)
+		return nil
+	}
+
+	c.vGPUUnsatisfiableLock.Lock()
+	c.vGPUUnsatisfiableCount++
+	c.vGPUUnsatisfiableLock.Unlock()
+
+	hostCapability := "no shared passthrough GPU types"
+	if len(hostTypes) > 0 {
+		sorted := append([]string{}, hostTypes...)
+		sort.Strings(sorted)
+		hostCapability = "shared passthrough GPU types " + strings.Join(sorted, ", ")
+	}
+	return fmt.Errorf("node %s has vGPU profiles not supported by ESXi host %s: %s, the host provides %s", node.Name, host.Name, strings.Join(unsatisfiable, ", "), hostCapability)
+}
+
+func (c *CheckNodeVMvGPUProfileConsistency) FinishCheck(ctx *CheckContext) {
+	c.vGPUUnsatisfiableLock.Lock()
+	defer c.vGPUUnsatisfiableLock.Unlock()
+	vGPUUnsatisfiableMetric.WithLabelValues().Set(float64(c.vGPUUnsatisfiableCount))
+	return
+}
+
+// getVMvGPUProfiles returns sorted vGPU profiles of all shared PCI passthrough devices of the VM.
+func getVMvGPUProfiles(vm *mo.VirtualMachine) []string {
+	if vm.Config == nil {
+		return nil
+	}
+	var profiles []string
+	for _, device := range vm.Config.Hardware.Device {
+		passthrough, ok := device.(*types.VirtualPCIPassthrough)
+		if !ok {
+			continue
+		}
+		backing, ok := passthrough.Backing.(*types.VirtualPCIPassthroughVmiopBackingInfo)
+		if !ok || backing.Vgpu == "" {
+			continue
+		}
+		profiles = append(profiles, backing.Vgpu)
+	}
+	sort.Strings(profiles)
+	return profiles
+}
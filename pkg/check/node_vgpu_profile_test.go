@@ -0,0 +1,115 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func vGPUDevice(profile string) *types.VirtualPCIPassthrough {
+	return &types.VirtualPCIPassthrough{
+		VirtualDevice: types.VirtualDevice{
+			Key: 13000,
+			Backing: &types.VirtualPCIPassthroughVmiopBackingInfo{
+				Vgpu: profile,
+			},
+		},
+	}
+}
+
+func TestCheckNodeVMvGPUProfileConsistency(t *testing.T) {
+	tests := []struct {
+		name          string
+		vmProfiles    []string
+		hostGPUTypes  []string
+		expectedError string
+	}{
+		{
+			name: "no vGPU",
+		},
+		{
+			name:         "supported vGPU profile",
+			vmProfiles:   []string{"grid_t4-4q"},
+			hostGPUTypes: []string{"grid_t4-8q", "grid_t4-4q"},
+		},
+		{
+			name:          "unsupported vGPU profile",
+			vmProfiles:    []string{"grid_t4-4q"},
+			hostGPUTypes:  []string{"grid_t4-8q", "grid_t4-2q"},
+			expectedError: "node DC0_H0_VM0 has vGPU profiles not supported by ESXi host DC0_H0: grid_t4-4q, the host provides shared passthrough GPU types grid_t4-2q, grid_t4-8q",
+		},
+		{
+			name:          "host without GPU",
+			vmProfiles:    []string{"grid_t4-4q"},
+			expectedError: "node DC0_H0_VM0 has vGPU profiles not supported by ESXi host DC0_H0: grid_t4-4q, the host provides no shared passthrough GPU types",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMvGPUProfileConsistency{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			host, err := getHostSystem(defaultHostId)
+			if err != nil {
+				t.Fatalf("Failed to get host: %s", err)
+			}
+			host.Config.SharedPassthruGpuTypes = test.hostGPUTypes
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			for _, profile := range test.vmProfiles {
+				vm.Config.Hardware.Device = append(vm.Config.Hardware.Device, vGPUDevice(profile))
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_vgpu_profile_unsatisfiable_total [ALPHA] Number of vSphere node VMs with vGPU profiles not supported by their ESXi host.
+# TYPE vsphere_node_vgpu_profile_unsatisfiable_total gauge
+vsphere_node_vgpu_profile_unsatisfiable_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_vgpu_profile_unsatisfiable_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// CheckNodeVMConnectedDevicesBlockingVMotion makes sure that node VMs have no devices attached that
+// prevent their migration with vMotion: CD-ROMs connected to a host or client device, USB devices,
+// PCI passthrough devices and RDM disks on SCSI controllers with bus sharing. All such devices of
+// a node are reported together, so it is clear what must be detached to make the node VM migratable.
+type CheckNodeVMConnectedDevicesBlockingVMotion struct {
+	notMigratableLock  sync.Mutex
+	notMigratableCount int
+}
+
+var _ NodeCheck = &CheckNodeVMConnectedDevicesBlockingVMotion{}
+
+var (
+	vMotionBlockedMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_vmotion_blocked_total",
+			Help:           "Number of vSphere node VMs with devices attached that prevent their migration with vMotion.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(vMotionBlockedMetric)
+}
+
+func (c *CheckNodeVMConnectedDevicesBlockingVMotion) Name() string {
+	return "CheckNodeVMConnectedDevicesBlockingVMotion"
+}
+
+func (c *CheckNodeVMConnectedDevicesBlockingVMotion) StartCheck() error {
+	c.notMigratableLock.Lock()
+	defer c.notMigratableLock.Unlock()
+	c.notMigratableCount = 0
+	return nil
+}
+
+func (c *CheckNodeVMConnectedDevicesBlockingVMotion) CheckNode(ctx *CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	if vm.Config == nil {
+		return fmt.Errorf("error getting devices of node %s: vm.config is empty", node.Name)
+	}
+	blocking := getVMotionBlockingDevices(vm.Config.Hardware.Device)
+	if len(blocking) == 0 {
+		klog.V(4).Infof("... the node has no devices that block vMotion")
+		return nil
+	}
+
+	c.notMigratableLock.Lock()
+	c.notMigratableCount++
+	c.notMigratableLock.Unlock()
+	return fmt.Errorf("node %s cannot be migrated with vMotion, it has attached: %s", node.Name, strings.Join(blocking, ", "))
+}
+
+func (c *CheckNodeVMConnectedDevicesBlockingVMotion) FinishCheck(ctx *CheckContext) {
+	c.notMigratableLock.Lock()
+	defer c.notMigratableLock.Unlock()
+	vMotionBlockedMetric.WithLabelValues().Set(float64(c.notMigratableCount))
+	return
+}
+
+// getVMotionBlockingDevices returns descriptions of devices that prevent vMotion of a VM, in the order of the devices.
+func getVMotionBlockingDevices(devices []types.BaseVirtualDevice) []string {
+	// controller key -> SCSI bus sharing of the controller
+	busSharing := make(map[int32]types.VirtualSCSISharing)
+	for _, device := range devices {
+		if controller, ok := device.(types.BaseVirtualSCSIController); ok {
+			c := controller.GetVirtualSCSIController()
+			busSharing[c.Key] = c.SharedBus
+		}
+	}
+
+	var blocking []string
+	for _, device := range devices {
+		d := device.GetVirtualDevice()
+		label := getDeviceLabel(d)
+		switch dev := device.(type) {
+		case *types.VirtualCdrom:
+			if !isDeviceConnected(d) {
+				continue
+			}
+			if _, iso := dev.Backing.(*types.VirtualCdromIsoBackingInfo); iso {
+				continue
+			}
+			blocking = append(blocking, fmt.Sprintf("CD-ROM %q connected to a host or client device", label))
+		case *types.VirtualUSB:
+			if !isDeviceConnected(d) {
+				continue
+			}
+			blocking = append(blocking, fmt.Sprintf("USB device %q", label))
+		case *types.VirtualPCIPassthrough:
+			if _, vGPU := dev.Backing.(*types.VirtualPCIPassthroughVmiopBackingInfo); vGPU {
+				// vGPU devices can be migrated when vGPU vMotion is enabled in vCenter.
+				continue
+			}
+			blocking = append(blocking, fmt.Sprintf("PCI passthrough device %q", label))
+		case *types.VirtualDisk:
+			rdm, ok := dev.Backing.(*types.VirtualDiskRawDiskMappingVer1BackingInfo)
+			if !ok {
+				continue
+			}
+			sharing := busSharing[d.ControllerKey]
+			if sharing == "" || sharing == types.VirtualSCSISharingNoSharing {
+				continue
+			}
+			blocking = append(blocking, fmt.Sprintf("RDM disk %q (%s) on SCSI controller with bus sharing %s", label, rdm.CompatibilityMode, sharing))
+		}
+	}
+	return blocking
+}
+
+// getDeviceLabel returns label of a device, e.g. "CD/DVD drive 1".
+func getDeviceLabel(d *types.VirtualDevice) string {
+	if d.DeviceInfo != nil && d.DeviceInfo.GetDescription() != nil && d.DeviceInfo.GetDescription().Label != "" {
+		return d.DeviceInfo.GetDescription().Label
+	}
+	return fmt.Sprintf("device %d", d.Key)
+}
+
+// isDeviceConnected returns true if the device is currently connected.
+func isDeviceConnected(d *types.VirtualDevice) bool {
+	return d.Connectable != nil && d.Connectable.Connected
+}
@@ -0,0 +1,137 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func labeledDevice(key int32, label string, connected bool) types.VirtualDevice {
+	return types.VirtualDevice{
+		Key:         key,
+		DeviceInfo:  &types.Description{Label: label},
+		Connectable: &types.VirtualDeviceConnectInfo{Connected: connected},
+	}
+}
+
+func TestCheckNodeVMConnectedDevicesBlockingVMotion(t *testing.T) {
+	isoCdrom := &types.VirtualCdrom{VirtualDevice: labeledDevice(3000, "CD/DVD drive 1", true)}
+	isoCdrom.Backing = &types.VirtualCdromIsoBackingInfo{}
+	hostCdrom := &types.VirtualCdrom{VirtualDevice: labeledDevice(3001, "CD/DVD drive 2", true)}
+	hostCdrom.Backing = &types.VirtualCdromAtapiBackingInfo{}
+	disconnectedCdrom := &types.VirtualCdrom{VirtualDevice: labeledDevice(3002, "CD/DVD drive 3", false)}
+	disconnectedCdrom.Backing = &types.VirtualCdromAtapiBackingInfo{}
+	usb := &types.VirtualUSB{VirtualDevice: labeledDevice(4000, "USB 1", true)}
+	passthrough := &types.VirtualPCIPassthrough{VirtualDevice: labeledDevice(13000, "PCI device 0", true)}
+	passthrough.Backing = &types.VirtualPCIPassthroughDeviceBackingInfo{}
+	vGPU := &types.VirtualPCIPassthrough{VirtualDevice: labeledDevice(13001, "PCI device 1", true)}
+	vGPU.Backing = &types.VirtualPCIPassthroughVmiopBackingInfo{Vgpu: "grid_t4-4q"}
+	sharedController := &types.ParaVirtualSCSIController{
+		VirtualSCSIController: types.VirtualSCSIController{
+			VirtualController: types.VirtualController{VirtualDevice: types.VirtualDevice{Key: 1001}},
+			SharedBus:         types.VirtualSCSISharingPhysicalSharing,
+		},
+	}
+	nonSharedController := &types.ParaVirtualSCSIController{
+		VirtualSCSIController: types.VirtualSCSIController{
+			VirtualController: types.VirtualController{VirtualDevice: types.VirtualDevice{Key: 1002}},
+			SharedBus:         types.VirtualSCSISharingNoSharing,
+		},
+	}
+	rdm := func(key, controllerKey int32, label string) *types.VirtualDisk {
+		d := &types.VirtualDisk{VirtualDevice: labeledDevice(key, label, true)}
+		d.ControllerKey = controllerKey
+		d.Backing = &types.VirtualDiskRawDiskMappingVer1BackingInfo{CompatibilityMode: "physicalMode"}
+		return d
+	}
+
+	tests := []struct {
+		name          string
+		devices       []types.BaseVirtualDevice
+		expectedError string
+	}{
+		{
+			name: "no additional devices",
+		},
+		{
+			name:    "migratable devices",
+			devices: []types.BaseVirtualDevice{isoCdrom, disconnectedCdrom, vGPU, nonSharedController, rdm(2000, 1002, "Hard disk 2")},
+		},
+		{
+			name:          "connected CD-ROM",
+			devices:       []types.BaseVirtualDevice{hostCdrom},
+			expectedError: `node DC0_H0_VM0 cannot be migrated with vMotion, it has attached: CD-ROM "CD/DVD drive 2" connected to a host or client device`,
+		},
+		{
+			name:          "all blocking devices",
+			devices:       []types.BaseVirtualDevice{hostCdrom, usb, passthrough, sharedController, rdm(2001, 1001, "Hard disk 3")},
+			expectedError: `node DC0_H0_VM0 cannot be migrated with vMotion, it has attached: CD-ROM "CD/DVD drive 2" connected to a host or client device, USB device "USB 1", PCI passthrough device "PCI device 0", RDM disk "Hard disk 3" (physicalMode) on SCSI controller with bus sharing physicalSharing`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			check := CheckNodeVMConnectedDevicesBlockingVMotion{}
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			node := kubeClient.nodes[0]
+			vm, err := getVM(ctx, node)
+			if err != nil {
+				t.Fatalf("Error getting vm for node %s: %s", node.Name, err)
+			}
+			// vcsim connects the default CD-ROM to a client device, disconnect it
+			for _, device := range vm.Config.Hardware.Device {
+				if cdrom, ok := device.(*types.VirtualCdrom); ok {
+					cdrom.Connectable.Connected = false
+				}
+			}
+			vm.Config.Hardware.Device = append(vm.Config.Hardware.Device, test.devices...)
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = check.StartCheck()
+			if err != nil {
+				t.Errorf("StartCheck failed: %s", err)
+			}
+			err = check.CheckNode(ctx, node, vm)
+			check.FinishCheck(ctx)
+
+			// Assert
+			expectedCount := 0
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				expectedCount = 1
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_node_vmotion_blocked_total [ALPHA] Number of vSphere node VMs with devices attached that prevent their migration with vMotion.
+# TYPE vsphere_node_vmotion_blocked_total gauge
+vsphere_node_vmotion_blocked_total %d
+`, expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_vmotion_blocked_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
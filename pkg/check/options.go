@@ -0,0 +1,160 @@
+package check
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	cliflag "k8s.io/component-base/cli/flag"
+)
+
+var (
+	// enabledChecks is filled from command line, check name -> enabled.
+	enabledChecks = map[string]bool{}
+
+	// checkTimeouts is filled from command line, check name -> timeout.
+	checkTimeouts = durationMap{}
+
+	// zone is filled from command line.
+	zone = flag.String("zone", "", "Run checks only for nodes in the given zone, i.e. nodes with label "+v1.LabelTopologyZone+" (or "+v1.LabelFailureDomainBetaZone+") equal to the value. Cluster checks see only nodes in the zone. Nodes in all zones are checked when empty.")
+)
+
+func init() {
+	flag.Var(cliflag.NewMapStringBool(&enabledChecks), "enable-checks", "A set of key=value pairs that enable or disable individual checks by name, e.g. CheckNodePerf=false. Checks that are not listed are enabled.")
+	flag.Var(checkTimeouts, "check-timeouts", "A set of key=value pairs that override check-timeout of individual checks by name, e.g. CheckFolderPermissions=10m,CheckNodePerf=0. 0 disables the timeout of the check.")
+}
+
+// durationMap is a flag.Value of comma separated key=duration pairs.
+type durationMap map[string]time.Duration
+
+var _ flag.Value = durationMap{}
+
+func (m durationMap) String() string {
+	var pairs []string
+	for key, value := range m {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m durationMap) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("malformed pair, expect string=duration: %s", pair)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return fmt.Errorf("invalid duration of %s: %s", kv[0], err)
+		}
+		m[strings.TrimSpace(kv[0])] = d
+	}
+	return nil
+}
+
+// timeoutOf returns timeout of the check with given name, 0 when the check has no timeout.
+func timeoutOf(name string) time.Duration {
+	if timeout, found := checkTimeouts[name]; found {
+		return timeout
+	}
+	return *CheckTimeout
+}
+
+// CheckOptions configures which checks are performed. It is not tied to a single
+// round of checks, the same options are used by all rounds.
+type CheckOptions struct {
+	// EnabledChecks enables or disables individual checks, check name -> enabled.
+	// Checks that are not present in the map are enabled.
+	EnabledChecks map[string]bool
+	// Zone limits the checks to nodes in the zone. All nodes are checked when empty.
+	Zone string
+}
+
+// NewCheckOptions returns CheckOptions configured from command line flags.
+func NewCheckOptions() *CheckOptions {
+	opts := &CheckOptions{
+		EnabledChecks: make(map[string]bool),
+		Zone:          *zone,
+	}
+	for name, enabled := range enabledChecks {
+		opts.EnabledChecks[name] = enabled
+	}
+	return opts
+}
+
+// WithEnabledChecks returns a copy of the options, with checks enabled or disabled by given enabled map,
+// check name -> enabled. The map overrides EnabledChecks of the options.
+func (o *CheckOptions) WithEnabledChecks(enabled map[string]bool) *CheckOptions {
+	opts := &CheckOptions{
+		EnabledChecks: make(map[string]bool),
+	}
+	if o != nil {
+		opts.Zone = o.Zone
+		for name, checkEnabled := range o.EnabledChecks {
+			opts.EnabledChecks[name] = checkEnabled
+		}
+	}
+	for name, checkEnabled := range enabled {
+		opts.EnabledChecks[name] = checkEnabled
+	}
+	return opts
+}
+
+// IsEnabled returns true if the check with given name should run.
+func (o *CheckOptions) IsEnabled(name string) bool {
+	if o == nil {
+		return true
+	}
+	enabled, found := o.EnabledChecks[name]
+	if !found {
+		return true
+	}
+	return enabled
+}
+
+// InZone returns true if the node is in the Zone of the options. All nodes are in the zone when Zone is empty.
+func (o *CheckOptions) InZone(node *v1.Node) bool {
+	if o == nil || o.Zone == "" {
+		return true
+	}
+	return getNodeZone(node) == o.Zone
+}
+
+// getNodeZone returns zone of the node from its label, or an empty string when the node has no zone label.
+func getNodeZone(node *v1.Node) string {
+	if nodeZone, found := node.Labels[v1.LabelTopologyZone]; found {
+		return nodeZone
+	}
+	return node.Labels[v1.LabelFailureDomainBetaZone]
+}
+
+// UnknownChecks returns sorted names of checks that are present in EnabledChecks,
+// but they do not match any of the given checks.
+func (o *CheckOptions) UnknownChecks(clusterChecks map[string]ClusterCheck, nodeChecks []NodeCheck) []string {
+	if o == nil {
+		return nil
+	}
+	known := make(map[string]bool)
+	for name := range clusterChecks {
+		known[name] = true
+	}
+	for _, nodeCheck := range nodeChecks {
+		known[nodeCheck.Name()] = true
+	}
+
+	var unknown []string
+	for name := range o.EnabledChecks {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
@@ -0,0 +1,206 @@
+package check
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckOptionsIsEnabled(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       *CheckOptions
+		check         string
+		expectEnabled bool
+	}{
+		{
+			name:          "nil options",
+			options:       nil,
+			check:         "CheckFolderPermissions",
+			expectEnabled: true,
+		},
+		{
+			name:          "check not listed",
+			options:       &CheckOptions{EnabledChecks: map[string]bool{"CheckNodePerf": false}},
+			check:         "CheckFolderPermissions",
+			expectEnabled: true,
+		},
+		{
+			name:          "check disabled",
+			options:       &CheckOptions{EnabledChecks: map[string]bool{"CheckNodePerf": false}},
+			check:         "CheckNodePerf",
+			expectEnabled: false,
+		},
+		{
+			name:          "check enabled",
+			options:       &CheckOptions{EnabledChecks: map[string]bool{"CheckNodePerf": true}},
+			check:         "CheckNodePerf",
+			expectEnabled: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enabled := test.options.IsEnabled(test.check)
+			if enabled != test.expectEnabled {
+				t.Errorf("expected %s enabled=%t, got %t", test.check, test.expectEnabled, enabled)
+			}
+		})
+	}
+}
+
+func TestCheckOptionsUnknownChecks(t *testing.T) {
+	options := &CheckOptions{
+		EnabledChecks: map[string]bool{
+			"CheckNodePerf":          false,
+			"CheckFolderPermissions": true,
+			"CheckFoo":               false,
+			"CheckBar":               true,
+		},
+	}
+	unknown := options.UnknownChecks(DefaultClusterChecks, DefaultNodeChecks)
+	expected := []string{"CheckBar", "CheckFoo"}
+	if !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("expected unknown checks %v, got %v", expected, unknown)
+	}
+}
+
+func TestCheckOptionsWithEnabledChecks(t *testing.T) {
+	options := &CheckOptions{
+		EnabledChecks: map[string]bool{
+			"CheckNodePerf":          false,
+			"CheckFolderPermissions": false,
+		},
+		Zone: "zone-a",
+	}
+	withEnabled := options.WithEnabledChecks(map[string]bool{
+		"CheckFolderPermissions": true,
+		"CheckDefaultDatastore":  false,
+	})
+	expected := map[string]bool{
+		"CheckNodePerf":          false,
+		"CheckFolderPermissions": true,
+		"CheckDefaultDatastore":  false,
+	}
+	if !reflect.DeepEqual(withEnabled.EnabledChecks, expected) {
+		t.Errorf("expected enabled checks %v, got %v", expected, withEnabled.EnabledChecks)
+	}
+	if withEnabled.Zone != "zone-a" {
+		t.Errorf("expected zone zone-a, got %q", withEnabled.Zone)
+	}
+	if options.EnabledChecks["CheckFolderPermissions"] {
+		t.Errorf("expected the original options to be unchanged")
+	}
+}
+
+func TestCheckOptionsInZone(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      *CheckOptions
+		labels       map[string]string
+		expectInZone bool
+	}{
+		{
+			name:         "nil options",
+			labels:       map[string]string{"topology.kubernetes.io/zone": "zone-a"},
+			expectInZone: true,
+		},
+		{
+			name:         "no zone",
+			options:      &CheckOptions{},
+			expectInZone: true,
+		},
+		{
+			name:         "node in the zone",
+			options:      &CheckOptions{Zone: "zone-a"},
+			labels:       map[string]string{"topology.kubernetes.io/zone": "zone-a"},
+			expectInZone: true,
+		},
+		{
+			name:         "node in another zone",
+			options:      &CheckOptions{Zone: "zone-a"},
+			labels:       map[string]string{"topology.kubernetes.io/zone": "zone-b"},
+			expectInZone: false,
+		},
+		{
+			name:         "node in the zone with beta label",
+			options:      &CheckOptions{Zone: "zone-a"},
+			labels:       map[string]string{"failure-domain.beta.kubernetes.io/zone": "zone-a"},
+			expectInZone: true,
+		},
+		{
+			name:    "GA label takes precedence",
+			options: &CheckOptions{Zone: "zone-a"},
+			labels: map[string]string{
+				"topology.kubernetes.io/zone":            "zone-b",
+				"failure-domain.beta.kubernetes.io/zone": "zone-a",
+			},
+			expectInZone: false,
+		},
+		{
+			name:         "node without zone",
+			options:      &CheckOptions{Zone: "zone-a"},
+			expectInZone: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := node("node1")
+			n.Labels = test.labels
+			inZone := test.options.InZone(n)
+			if inZone != test.expectInZone {
+				t.Errorf("expected InZone=%t, got %t", test.expectInZone, inZone)
+			}
+		})
+	}
+}
+
+func TestDurationMapSet(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    durationMap
+		expectError bool
+	}{
+		{
+			name:     "empty",
+			value:    "",
+			expected: durationMap{},
+		},
+		{
+			name:     "several checks",
+			value:    "CheckFolderPermissions=10m, CheckNodePerf=0",
+			expected: durationMap{"CheckFolderPermissions": 10 * time.Minute, "CheckNodePerf": 0},
+		},
+		{
+			name:        "missing duration",
+			value:       "CheckFolderPermissions",
+			expectError: true,
+		},
+		{
+			name:        "invalid duration",
+			value:       "CheckFolderPermissions=10",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := durationMap{}
+			err := m.Set(test.value)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(m, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, m)
+			}
+		})
+	}
+}
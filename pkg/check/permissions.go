@@ -6,6 +6,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
 	"k8s.io/klog/v2"
 )
@@ -200,6 +201,96 @@ func checkDatastorePrivileges(ctx *CheckContext, dataStoreName string) error {
 	return nil
 }
 
+// checkDetectorPrivileges checks that the account has privileges required by checks of vsphere-problem-detector
+// on the objects where the checks need them, see privilegeGroups. Privileges of other checks are needed on
+// the vCenter root folder.
+func checkDetectorPrivileges(ctx *CheckContext) error {
+	if _, ok := ctx.VMConfig.VirtualCenter[ctx.VMConfig.Workspace.VCenterIP]; !ok {
+		return errors.New("vcenter instance not found in the virtual center map")
+	}
+
+	required := make(map[permissionGroup][]string)
+	for _, privilege := range getDeclaredPrivileges() {
+		group, found := privilegeGroups[privilege]
+		if !found {
+			group = permissionVcenter
+		}
+		required[group] = append(required[group], privilege)
+	}
+
+	dc, err := getDatacenter(ctx, ctx.VMConfig.Workspace.Datacenter)
+	if err != nil {
+		klog.Errorf("error getting datacenter %s: %v", ctx.VMConfig.Workspace.Datacenter, err)
+		return err
+	}
+	ds, err := getDataStoreByName(ctx, ctx.VMConfig.Workspace.DefaultDatastore, dc)
+	if err != nil {
+		klog.Errorf("error getting datastore %s: %v", ctx.VMConfig.Workspace.DefaultDatastore, err)
+		return err
+	}
+	entities := []struct {
+		group permissionGroup
+		name  string
+		ref   types.ManagedObjectReference
+	}{
+		{permissionVcenter, "vcenter", ctx.VMClient.ServiceContent.RootFolder},
+		{permissionDatacenter, "datacenter " + ctx.VMConfig.Workspace.Datacenter, dc.Reference()},
+		{permissionDatastore, "datastore " + ctx.VMConfig.Workspace.DefaultDatastore, ds.Reference()},
+	}
+
+	var errs []error
+	for _, entity := range entities {
+		if len(required[entity.group]) == 0 {
+			continue
+		}
+		if err := comparePrivileges(ctx.Context, ctx.Username, entity.ref, ctx.AuthManager, required[entity.group]); err != nil {
+			errs = append(errs, fmt.Errorf("missing privileges for vsphere-problem-detector checks on %s: %s", entity.name, err.Error()))
+		}
+	}
+	return JoinErrors(errs)
+}
+
+// checkResourcePoolPrivileges checks privileges of the resource pool from vSphere configuration.
+func checkResourcePoolPrivileges(ctx *CheckContext, resourcePoolPath string) error {
+	if _, ok := ctx.VMConfig.VirtualCenter[ctx.VMConfig.Workspace.VCenterIP]; !ok {
+		return errors.New("vcenter instance not found in the virtual center map")
+	}
+
+	finder := find.NewFinder(ctx.VMClient)
+	resourcePool, err := finder.ResourcePool(ctx.Context, resourcePoolPath)
+	if err != nil {
+		klog.Errorf("error getting resource pool %s: %v", resourcePoolPath, err)
+		return err
+	}
+	if err := comparePrivileges(ctx.Context, ctx.Username, resourcePool.Reference(), ctx.AuthManager, permissions[permissionResourcePool]); err != nil {
+		return fmt.Errorf("missing privileges for resource pool %s: %s", resourcePoolPath, err.Error())
+	}
+	return nil
+}
+
+// isPrivilegeAPISupported returns false when vCenter does not implement AuthorizationManager.FetchUserPrivilegeOnEntities.
+// Other errors are left to the individual privilege checks.
+func isPrivilegeAPISupported(ctx *CheckContext) bool {
+	rootFolder := ctx.VMClient.ServiceContent.RootFolder
+	_, err := ctx.AuthManager.FetchUserPrivilegeOnEntities(ctx.Context, []types.ManagedObjectReference{rootFolder}, ctx.Username)
+	if err == nil {
+		return true
+	}
+	var fault interface{}
+	switch {
+	case soap.IsSoapFault(err):
+		fault = soap.ToSoapFault(err).VimFault()
+	case soap.IsVimFault(err):
+		fault = soap.ToVimFault(err)
+	}
+	switch fault.(type) {
+	case types.MethodNotFound, *types.MethodNotFound, types.NotImplemented, *types.NotImplemented, types.NotSupported, *types.NotSupported:
+		klog.Warningf("vCenter does not support checking privileges of user %s, skipping privilege checks: %s", ctx.Username, err)
+		return false
+	}
+	return true
+}
+
 func getFolderReference(ctx context.Context, path string, finder *find.Finder) (*types.ManagedObjectReference, error) {
 	folderObj, err := finder.Folder(ctx, path)
 	if err != nil {
@@ -212,6 +303,10 @@ func getFolderReference(ctx context.Context, path string, finder *find.Finder) (
 // CheckAccountPermissions will attempt to validate that the necessary credentials are held by the account performing the
 // installation. each group of privileges will be checked for missing privileges.
 func CheckAccountPermissions(ctx *CheckContext) error {
+	if !isPrivilegeAPISupported(ctx) {
+		return nil
+	}
+
 	var errs []error
 	err := checkDatastorePrivileges(ctx, ctx.VMConfig.Workspace.DefaultDatastore)
 	if err != nil {
@@ -235,6 +330,18 @@ func CheckAccountPermissions(ctx *CheckContext) error {
 		}
 	}
 
+	if ctx.VMConfig.Workspace.ResourcePoolPath != "" {
+		err = checkResourcePoolPrivileges(ctx, ctx.VMConfig.Workspace.ResourcePoolPath)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err = checkDetectorPrivileges(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
 	if len(errs) > 0 {
 		return join(errs)
 	}
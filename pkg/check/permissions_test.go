@@ -10,6 +10,7 @@ import (
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/session"
 	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
 	vim25types "github.com/vmware/govmomi/vim25/types"
 
 	"github.com/golang/mock/gomock"
@@ -283,6 +284,19 @@ func TestPermissionValidate(t *testing.T) {
 			validationMethod:     vmLevelPrivilegeCheck,
 			existingResourcePool: true,
 		},
+		{
+			name:                 "valid Permissions with resource pool",
+			authManager:          validPermissionsAuthManagerClient,
+			validationMethod:     clusterLevelPrivilegeCheck,
+			existingResourcePool: true,
+		},
+		{
+			name:                 "missing configured resource pool Permissions",
+			authManager:          missingResourcePoolPermissionClient,
+			expectErr:            "missing privileges for resource pool /DC0/host/DC0_H0/Resources: VirtualMachine.Config.AddNewDisk",
+			validationMethod:     clusterLevelPrivilegeCheck,
+			existingResourcePool: true,
+		},
 		{
 			name:             "missing datacenter Permissions",
 			authManager:      missingDatacenterPermissionsClient,
@@ -323,3 +337,135 @@ func TestPermissionValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestDetectorPrivilegesScopedPerEntity(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	kubeClient := &fakeKubeClient{
+		infrastructure: infrastructure(),
+		nodes:          defaultNodes(),
+	}
+	simctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+
+	finder := find.NewFinder(simctx.VMClient)
+	dc, err := finder.Datacenter(simctx.Context, "DC0")
+	if err != nil {
+		t.Fatalf("failed to find datacenter: %s", err)
+	}
+	ds, err := finder.Datastore(simctx.Context, "/DC0/datastore/LocalDS_0")
+	if err != nil {
+		t.Fatalf("failed to find datastore: %s", err)
+	}
+
+	tests := []struct {
+		name                 string
+		rootPrivileges       []string
+		datacenterPrivileges []string
+		datastorePrivileges  []string
+		expectErr            string
+	}{
+		{
+			// A least privilege account has only the privileges needed on each object, Datastore.Browse
+			// is granted only on the datastore and the root folder has no System.Read.
+			name:                 "least privilege account",
+			rootPrivileges:       []string{privilegeStorageProfileView, privilegeCnsSearchable},
+			datacenterPrivileges: []string{privilegeSystemRead},
+			datastorePrivileges:  []string{privilegeSystemRead, privilegeDatastoreBrowse},
+		},
+		{
+			name:                 "datastore browse granted only on root folder",
+			rootPrivileges:       []string{privilegeStorageProfileView, privilegeCnsSearchable, privilegeDatastoreBrowse},
+			datacenterPrivileges: []string{privilegeSystemRead},
+			datastorePrivileges:  []string{privilegeSystemRead},
+			expectErr:            "missing privileges for vsphere-problem-detector checks on datastore LocalDS_0: Datastore.Browse",
+		},
+		{
+			name:                 "missing vcenter privileges",
+			rootPrivileges:       []string{privilegeSystemRead, privilegeCnsSearchable},
+			datacenterPrivileges: []string{privilegeSystemRead},
+			datastorePrivileges:  []string{privilegeSystemRead, privilegeDatastoreBrowse},
+			expectErr:            "missing privileges for vsphere-problem-detector checks on vcenter: StorageProfile.View",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authManager := mock.NewMockAuthManager(mockCtrl)
+			for ref, privileges := range map[vim25types.ManagedObjectReference][]string{
+				simctx.VMClient.ServiceContent.RootFolder: test.rootPrivileges,
+				dc.Reference(): test.datacenterPrivileges,
+				ds.Reference(): test.datastorePrivileges,
+			} {
+				authManager.EXPECT().FetchUserPrivilegeOnEntities(gomock.Any(), []vim25types.ManagedObjectReference{ref}, gomock.Any()).Return([]vim25types.UserPrivilegeResult{
+					{Privileges: privileges},
+				}, nil).AnyTimes()
+			}
+			simctx.AuthManager = authManager
+
+			err := checkDetectorPrivileges(simctx)
+			if test.expectErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.expectErr)
+			}
+		})
+	}
+}
+
+func TestPermissionValidateUnsupported(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	kubeClient := &fakeKubeClient{
+		infrastructure: infrastructure(),
+		nodes:          defaultNodes(),
+	}
+	simctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+
+	notImplemented := &soap.Fault{}
+	notImplemented.Detail.Fault = vim25types.NotImplemented{}
+
+	tests := []struct {
+		name      string
+		fault     error
+		expectErr string
+	}{
+		{
+			name:  "method not found",
+			fault: soap.WrapVimFault(&vim25types.MethodNotFound{Method: "FetchUserPrivilegeOnEntities"}),
+		},
+		{
+			name:  "not implemented",
+			fault: soap.WrapSoapFault(notImplemented),
+		},
+		{
+			name:      "other error",
+			fault:     errors.New("connection refused"),
+			expectErr: "missing privileges for datastore LocalDS_0: unable to retrieve privileges: connection refused",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authManager := mock.NewMockAuthManager(mockCtrl)
+			authManager.EXPECT().FetchUserPrivilegeOnEntities(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, test.fault).AnyTimes()
+			simctx.AuthManager = authManager
+
+			err := CheckAccountPermissions(simctx)
+			if test.expectErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Regexp(t, test.expectErr, err)
+			}
+		})
+	}
+}
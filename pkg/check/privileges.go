@@ -0,0 +1,170 @@
+package check
+
+import (
+	"sort"
+)
+
+const (
+	// Privilege to read inventory objects and their properties.
+	privilegeSystemRead = "System.Read"
+	// Privilege to list files on datastores.
+	privilegeDatastoreBrowse = "Datastore.Browse"
+	// Privilege to read storage policies.
+	privilegeStorageProfileView = "StorageProfile.View"
+	// Privilege to query CNS volumes.
+	privilegeCnsSearchable = "Cns.Searchable"
+)
+
+var (
+	// basePrivileges are required by every round of checks, regardless of the checks that are enabled,
+	// e.g. to find node VMs.
+	basePrivileges = []string{
+		privilegeSystemRead,
+	}
+
+	// privilegeGroups are permission groups of the objects on which privileges of checks are needed,
+	// so accounts with privileges granted only on the objects used by the cluster pass CheckAccountPermissions.
+	// Privileges that are not listed are needed on the vCenter root folder.
+	privilegeGroups = map[string]permissionGroup{
+		// Checks read the inventory of the Workspace datacenter.
+		privilegeSystemRead: permissionDatacenter,
+		// Checks list files of the default datastore.
+		privilegeDatastoreBrowse: permissionDatastore,
+	}
+
+	// checkPrivileges are vSphere privileges required by individual checks, check name -> privileges.
+	// Every check that calls vSphere API must declare its privileges here. Checks that use only
+	// Kubernetes API are not listed.
+	checkPrivileges = map[string][]string{
+		// Cluster checks
+		"CheckTaskPermissions":                                {privilegeSystemRead},
+		"ClusterInfo":                                         {privilegeSystemRead},
+		"CheckFolderPermissions":                              {privilegeSystemRead, privilegeDatastoreBrowse},
+		"CheckDefaultDatastore":                               {privilegeSystemRead, privilegeStorageProfileView},
+		"CheckStorageClasses":                                 {privilegeSystemRead, privilegeStorageProfileView},
+		"CheckAccountPermissions":                             {privilegeSystemRead},
+		"CheckDatastoreClusterAntiAffinityForReplicas":        {privilegeSystemRead, privilegeCnsSearchable},
+		"CheckHostCPUFeatureConsistency":                      {privilegeSystemRead},
+		"CheckDatastoreUnmapSupport":                          {privilegeSystemRead},
+		"CheckClusterProactiveHAEnabled":                      {privilegeSystemRead},
+		"CheckVCenterAPIRateLimitHeadroom":                    {privilegeSystemRead},
+		"CheckDatastoreReplicationPairingForStretched":        {privilegeSystemRead, privilegeStorageProfileView},
+		"CheckClusterVMComponentProtectionEnabled":            {privilegeSystemRead},
+		"CheckHostTimeZoneConsistency":                        {privilegeSystemRead},
+		"CheckDatastoreMountPathConsistencyAcrossHosts":       {privilegeSystemRead},
+		"CheckClusterDPMEnabled":                              {privilegeSystemRead},
+		"CheckDatastoreBlockSizeForLargeVolumes":              {privilegeSystemRead},
+		"CheckVCenterDeprecatedAPIUsage":                      {privilegeSystemRead},
+		"CheckClusterNetworkIOControlEnabled":                 {privilegeSystemRead},
+		"CheckDatastoreClusterAffinityRuleForVMHome":          {privilegeSystemRead},
+		"CheckClusterAdmissionControlPolicyType":              {privilegeSystemRead},
+		"CheckVCenterPluginHealth":                            {privilegeSystemRead},
+		"CheckClusterResourcePoolSharesFairness":              {privilegeSystemRead},
+		"CheckClusterOverlappingDatastoreHeartbeatSelection":  {privilegeSystemRead},
+		"CheckDatastoreSnapshotSpaceReservation":              {privilegeSystemRead, privilegeStorageProfileView},
+		"CheckClusterVsanDiskGroupHealth":                     {privilegeSystemRead},
+		"CheckDatastoreCapacityVsVsanSlackSpace":              {privilegeSystemRead},
+		"CheckClusterHostAffinityForZoneTags":                 {privilegeSystemRead},
+		"CheckDatastoreFreeSpace":                             {privilegeSystemRead, privilegeCnsSearchable},
+		"CheckVCenterServiceContentCapabilities":              {privilegeSystemRead},
+		"CheckVSphereVersionsSupported":                       {privilegeSystemRead},
+		"CheckClusterStoragePolicyAppliesToAllNodeDatastores": {privilegeSystemRead, privilegeStorageProfileView},
+		"CheckDatastoreVMFSHeapExhaustionRisk":                {privilegeSystemRead},
+		"CheckClusterVsanObjectComplianceForNodeVMs":          {privilegeSystemRead},
+		"CheckClusterComputeResourceNameUniqueness":           {privilegeSystemRead},
+		"CheckDatastoreAccessibleFromFailureDomainHostsOnly":  {privilegeSystemRead},
+		"CheckClusterDatastoreConnectivityMatrix":             {privilegeSystemRead},
+		"CheckVCenterPasswordExpiryForServiceAccount":         {privilegeSystemRead},
+		"CheckUserPermissions":                                {privilegeSystemRead},
+		"CheckZonalTopologyTags":                              {privilegeSystemRead},
+		"CheckOrphanedVolumes":                                {privilegeSystemRead, privilegeDatastoreBrowse, privilegeCnsSearchable},
+		"CheckVCenterConnectivity":                            {privilegeSystemRead},
+		"CheckWorkspaceInventoryPaths":                        {privilegeSystemRead},
+		"CheckCredentials":                                    {privilegeSystemRead},
+
+		// Node checks
+		"CheckNodeDiskUUID":                                 {privilegeSystemRead},
+		"CollectNodeHWVersion":                              {privilegeSystemRead},
+		"CollectNodeESXiVersion":                            {privilegeSystemRead},
+		"CheckNodePerf":                                     {privilegeSystemRead},
+		"CheckComputeClusterPermissions":                    {privilegeSystemRead},
+		"CheckResourcePoolPermissions":                      {privilegeSystemRead},
+		"CheckNodeVMBootDeviceOrder":                        {privilegeSystemRead},
+		"CheckNodeVMConfigFileDatastoreMatchesExpectedZone": {privilegeSystemRead},
+		"CheckNodeVMConnectedDevicesBlockingVMotion":        {privilegeSystemRead},
+		"CheckNodeVMDiskAllSameDatastoreAsHome":             {privilegeSystemRead},
+		"CheckNodeVMDiskBackingParentChainDepth":            {privilegeSystemRead},
+		"CheckNodeVMDiskFragmentationAcrossDatastores":      {privilegeSystemRead},
+		"CheckNodeVMDiskIndependentOfSnapshotChain":         {privilegeSystemRead},
+		"CheckNodeVMDiskKeyStabilityForCSI":                 {privilegeSystemRead},
+		"CheckNodeVMDiskSizeVsPVCSize":                      {privilegeSystemRead},
+		"CheckNodeVMFaultToleranceState":                    {privilegeSystemRead},
+		"CheckNodeVMGuestNetConnectivityFlags":              {privilegeSystemRead},
+		"CheckNodeVMHardwareVersionVsVCenterMaxSupported":   {privilegeSystemRead},
+		"CheckNodeVMHostAvailable":                          {privilegeSystemRead},
+		"CheckNodeVMMaxMksConnections":                      {privilegeSystemRead},
+		"CheckNodeVMMemorySizeAlignment":                    {privilegeSystemRead},
+		"CheckNodeVMPMemUsage":                              {privilegeSystemRead},
+		"CheckNodeVMResourcePoolParentIsCluster":            {privilegeSystemRead},
+		"CheckNodeVMToolsGuestFamilyMatch":                  {privilegeSystemRead},
+		"CheckNodeVMToolsGuestInfoStale":                    {privilegeSystemRead},
+		"CheckNodeVMToolsGuestOpsEnabled":                   {privilegeSystemRead},
+		"CheckNodeVMToolsGuestRebootPending":                {privilegeSystemRead},
+		"CheckNodeVMToolsGuestSshKeyInjectionDisabled":      {privilegeSystemRead},
+		"CheckNodeVMToolsInstallerMountedBlockingEject":     {privilegeSystemRead},
+		"CheckNodeVMToolsMemoryBalloonDisabled":             {privilegeSystemRead},
+		"CheckNodeVMToolsOperationTimeoutHistory":           {privilegeSystemRead},
+		"CheckNodeVMToolsOperationsRateLimited":             {privilegeSystemRead},
+		"CheckNodeVMToolsScriptsLeftEnabled":                {privilegeSystemRead},
+		"CheckNodeVMToolsSharedFolders":                     {privilegeSystemRead},
+		"CheckNodeVMToolsUnattendedShutdownCapability":      {privilegeSystemRead},
+		"CheckNodeVMToolsUpgradeStatusStuck":                {privilegeSystemRead},
+		"CheckNodeVMToolsUpgradeRequiredForHWVersion":       {privilegeSystemRead},
+		"CheckNodeVMToolsVersionBelowHostMinimum":           {privilegeSystemRead},
+		"CheckNodeVMWWNConsistencyForNPIV":                  {privilegeSystemRead},
+		"CheckNodeVMvGPUProfileConsistency":                 {privilegeSystemRead},
+	}
+)
+
+// RequiredPrivileges returns sorted vSphere privileges needed to run all DefaultClusterChecks
+// and DefaultNodeChecks. Admins can grant them to a role used by vsphere-problem-detector.
+func RequiredPrivileges() []string {
+	var names []string
+	for name := range DefaultClusterChecks {
+		names = append(names, name)
+	}
+	for _, nodeCheck := range DefaultNodeChecks {
+		names = append(names, nodeCheck.Name())
+	}
+	return getRequiredPrivileges(names)
+}
+
+// getDeclaredPrivileges returns sorted union of basePrivileges and privileges of all checks in checkPrivileges.
+// It is the same as RequiredPrivileges, but it can be used by checks themselves, which cannot reference
+// DefaultClusterChecks.
+func getDeclaredPrivileges() []string {
+	var names []string
+	for name := range checkPrivileges {
+		names = append(names, name)
+	}
+	return getRequiredPrivileges(names)
+}
+
+// getRequiredPrivileges returns sorted union of basePrivileges and privileges of given checks.
+func getRequiredPrivileges(checkNames []string) []string {
+	set := make(map[string]bool)
+	for _, privilege := range basePrivileges {
+		set[privilege] = true
+	}
+	for _, name := range checkNames {
+		for _, privilege := range checkPrivileges[name] {
+			set[privilege] = true
+		}
+	}
+	privileges := make([]string, 0, len(set))
+	for privilege := range set {
+		privileges = append(privileges, privilege)
+	}
+	sort.Strings(privileges)
+	return privileges
+}
@@ -0,0 +1,96 @@
+package check
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// checksWithoutVSphereCalls are checks that use only Kubernetes API and do not need any vSphere privileges.
+var checksWithoutVSphereCalls = map[string]bool{
+	"CountRWXVolumes":     true,
+	"CheckNodeProviderID": true,
+}
+
+func TestCheckPrivilegesDeclared(t *testing.T) {
+	registered := make(map[string]bool)
+	for name := range DefaultClusterChecks {
+		registered[name] = true
+	}
+	for _, nodeCheck := range DefaultNodeChecks {
+		registered[nodeCheck.Name()] = true
+	}
+
+	for name := range registered {
+		privileges := checkPrivileges[name]
+		if checksWithoutVSphereCalls[name] {
+			if len(privileges) > 0 {
+				t.Errorf("Check %s does not call vSphere API, but it declares privileges %v", name, privileges)
+			}
+			continue
+		}
+		if len(privileges) == 0 {
+			t.Errorf("Check %s does not declare any vSphere privileges in checkPrivileges", name)
+		}
+	}
+	for name := range checkPrivileges {
+		if !registered[name] {
+			t.Errorf("checkPrivileges contains unknown check %s", name)
+		}
+	}
+}
+
+func TestRequiredPrivileges(t *testing.T) {
+	privileges := RequiredPrivileges()
+	if !sort.StringsAreSorted(privileges) {
+		t.Errorf("Expected sorted privileges, got %v", privileges)
+	}
+	seen := make(map[string]bool)
+	for _, privilege := range privileges {
+		if seen[privilege] {
+			t.Errorf("Duplicate privilege %s", privilege)
+		}
+		seen[privilege] = true
+	}
+	for _, privilege := range basePrivileges {
+		if !seen[privilege] {
+			t.Errorf("Base privilege %s is missing", privilege)
+		}
+	}
+	// CheckAccountPermissions uses getDeclaredPrivileges, both must be in sync.
+	if declared := getDeclaredPrivileges(); !reflect.DeepEqual(privileges, declared) {
+		t.Errorf("RequiredPrivileges %v differ from declared privileges %v", privileges, declared)
+	}
+}
+
+func TestGetRequiredPrivileges(t *testing.T) {
+	tests := []struct {
+		name               string
+		checks             []string
+		expectedPrivileges []string
+	}{
+		{
+			name:               "no checks",
+			expectedPrivileges: []string{"System.Read"},
+		},
+		{
+			name:               "Kubernetes only check",
+			checks:             []string{"CountRWXVolumes"},
+			expectedPrivileges: []string{"System.Read"},
+		},
+		{
+			name:               "multiple checks",
+			checks:             []string{"CheckFolderPermissions", "CheckStorageClasses", "CheckDefaultDatastore"},
+			expectedPrivileges: []string{"Datastore.Browse", "StorageProfile.View", "System.Read"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			privileges := getRequiredPrivileges(test.checks)
+			if !reflect.DeepEqual(privileges, test.expectedPrivileges) {
+				t.Errorf("Expected privileges %v, got %v", test.expectedPrivileges, privileges)
+			}
+		})
+	}
+}
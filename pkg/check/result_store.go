@@ -0,0 +1,112 @@
+package check
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CheckRun is the outcome of a single round of checks.
+type CheckRun struct {
+	// StartTime is the time when the round started.
+	StartTime time.Time
+	// FinishTime is the time when all checks of the round completed.
+	FinishTime time.Time
+	// Zone is the zone the round was limited to, empty when all nodes were checked.
+	Zone string
+	// Results of individual checks, check name -> result.
+	Results map[string]CheckRunResult
+}
+
+// copyRun returns a deep copy of the run.
+func copyRun(run *CheckRun) *CheckRun {
+	c := *run
+	c.Results = make(map[string]CheckRunResult, len(run.Results))
+	for name, res := range run.Results {
+		res.Errors = append([]string(nil), res.Errors...)
+		c.Results[name] = res
+	}
+	return &c
+}
+
+// CheckRunResult is the outcome of a single check in a CheckRun.
+type CheckRunResult struct {
+	// Errors reported by the check. Empty when the check succeeded.
+	Errors []string
+	// Disabled is true when the check was not performed.
+	Disabled bool
+	// TimedOut is true when the check did not finish within its timeout, on at least one node for node checks.
+	TimedOut bool
+}
+
+// ResultStore persists CheckRuns, so checks can compare results of several rounds.
+// Runs are stored and returned as copies, callers may modify them freely.
+type ResultStore interface {
+	// Save stores a new run.
+	Save(ctx context.Context, run *CheckRun) error
+	// LoadLatest returns the most recent run, or nil when there is none.
+	LoadLatest(ctx context.Context) (*CheckRun, error)
+	// LoadRange returns runs that started between from and to (inclusive), the oldest first.
+	LoadRange(ctx context.Context, from, to time.Time) ([]*CheckRun, error)
+}
+
+// MemoryResultStore is a ResultStore that keeps a limited number of the most recent runs in memory.
+// The history is lost when the process restarts.
+type MemoryResultStore struct {
+	lock    sync.Mutex
+	maxRuns int
+	// runs sorted by StartTime, the oldest first
+	runs []*CheckRun
+}
+
+var _ ResultStore = &MemoryResultStore{}
+
+// NewMemoryResultStore returns a new MemoryResultStore that keeps up to maxRuns runs.
+func NewMemoryResultStore(maxRuns int) *MemoryResultStore {
+	return &MemoryResultStore{
+		maxRuns: maxRuns,
+	}
+}
+
+func (s *MemoryResultStore) Save(ctx context.Context, run *CheckRun) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Keep the runs sorted, runs are saved in order in most cases.
+	i := len(s.runs)
+	for i > 0 && s.runs[i-1].StartTime.After(run.StartTime) {
+		i--
+	}
+	s.runs = append(s.runs, nil)
+	copy(s.runs[i+1:], s.runs[i:])
+	s.runs[i] = copyRun(run)
+
+	if s.maxRuns > 0 && len(s.runs) > s.maxRuns {
+		s.runs = s.runs[len(s.runs)-s.maxRuns:]
+	}
+	return nil
+}
+
+func (s *MemoryResultStore) LoadLatest(ctx context.Context) (*CheckRun, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.runs) == 0 {
+		return nil, nil
+	}
+	return copyRun(s.runs[len(s.runs)-1]), nil
+}
+
+func (s *MemoryResultStore) LoadRange(ctx context.Context, from, to time.Time) ([]*CheckRun, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var runs []*CheckRun
+	for _, run := range s.runs {
+		if run.StartTime.Before(from) || run.StartTime.After(to) {
+			continue
+		}
+		runs = append(runs, copyRun(run))
+	}
+	return runs, nil
+}
@@ -0,0 +1,67 @@
+package check
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryResultStore(t *testing.T) {
+	ctx := context.TODO()
+	base := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := func(minutes int) *CheckRun {
+		return &CheckRun{
+			StartTime: base.Add(time.Duration(minutes) * time.Minute),
+			Results: map[string]CheckRunResult{
+				"CheckFoo": {},
+			},
+		}
+	}
+
+	store := NewMemoryResultStore(3)
+	latest, err := store.LoadLatest(ctx)
+	if err != nil {
+		t.Fatalf("LoadLatest failed: %s", err)
+	}
+	if latest != nil {
+		t.Errorf("expected no run in empty store, got %+v", latest)
+	}
+
+	// Save out of order and more runs than the store keeps
+	runs := []*CheckRun{run(0), run(10), run(30), run(20)}
+	for _, r := range runs {
+		if err := store.Save(ctx, r); err != nil {
+			t.Fatalf("Save failed: %s", err)
+		}
+	}
+
+	latest, err = store.LoadLatest(ctx)
+	if err != nil {
+		t.Fatalf("LoadLatest failed: %s", err)
+	}
+	if !reflect.DeepEqual(latest, runs[2]) {
+		t.Errorf("expected latest run %+v, got %+v", runs[2], latest)
+	}
+
+	// Modifications of loaded runs must not change the stored history
+	latest.Results["CheckFoo"] = CheckRunResult{Errors: []string{"error"}}
+	runs[2].Results["CheckBar"] = CheckRunResult{}
+	latest, err = store.LoadLatest(ctx)
+	if err != nil {
+		t.Fatalf("LoadLatest failed: %s", err)
+	}
+	if !reflect.DeepEqual(latest, run(30)) {
+		t.Errorf("expected unmodified latest run %+v, got %+v", run(30), latest)
+	}
+
+	loaded, err := store.LoadRange(ctx, base, base.Add(20*time.Minute))
+	if err != nil {
+		t.Fatalf("LoadRange failed: %s", err)
+	}
+	// run(0) was evicted
+	expected := []*CheckRun{runs[1], runs[3]}
+	if !reflect.DeepEqual(loaded, expected) {
+		t.Errorf("expected runs %+v, got %+v", expected, loaded)
+	}
+}
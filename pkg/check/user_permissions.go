@@ -0,0 +1,153 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	userMissingPrivilegesMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_user_missing_privileges_total",
+			Help:           "Number of vSphere objects used by the cluster on which the configured user misses privileges required by OpenShift.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(userMissingPrivilegesMetric)
+}
+
+// privilegeObject is a vSphere object used by the cluster and the group of privileges the user needs on it.
+type privilegeObject struct {
+	// kind is human friendly type of the object, e.g. "datastore"
+	kind  string
+	name  string
+	ref   vim.ManagedObjectReference
+	group permissionGroup
+}
+
+// CheckUserPermissions tests that the configured user holds privileges required by OpenShift on the vSphere
+// objects the cluster actually uses: the vCenter, its datacenters, compute clusters and datastores of node VMs
+// and folders with node VMs. Unlike CheckAccountPermissions, which checks objects named in vSphere configuration,
+// the objects are found from node VMs. Missing privileges are reported for each object separately.
+// Compute clusters are not checked when the configuration has a pre-existing resource pool.
+func CheckUserPermissions(ctx *CheckContext) error {
+	if !isPrivilegeAPISupported(ctx) {
+		userMissingPrivilegesMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+
+	objects, err := getPrivilegeObjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, object := range objects {
+		if err := comparePrivileges(ctx.Context, ctx.Username, object.ref, ctx.AuthManager, permissions[object.group]); err != nil {
+			errs = append(errs, fmt.Errorf("missing privileges for %s %s: %s", object.kind, object.name, err))
+		}
+	}
+	userMissingPrivilegesMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(len(errs)))
+	klog.V(2).Infof("CheckUserPermissions checked %d objects, %d problems found", len(objects), len(errs))
+	return JoinErrors(errs)
+}
+
+// getPrivilegeObjects returns vSphere objects used by the cluster, with the vCenter root folder first,
+// then datacenters, compute clusters, datastores and folders, each sorted by name.
+func getPrivilegeObjects(ctx *CheckContext) ([]privilegeObject, error) {
+	objects := []privilegeObject{
+		{kind: "vCenter", name: ctx.VMConfig.Workspace.VCenterIP, ref: ctx.VMClient.ServiceContent.RootFolder, group: permissionVcenter},
+	}
+
+	var dcObjects []privilegeObject
+	for _, dcName := range getVCenterDatacenters(ctx, ctx.VMConfig.Workspace.VCenterIP) {
+		dc, err := getDatacenter(ctx, dcName)
+		if err != nil {
+			return nil, err
+		}
+		dcObjects = append(dcObjects, privilegeObject{kind: "datacenter", name: dcName, ref: dc.Reference(), group: permissionDatacenter})
+	}
+	objects = append(objects, sortPrivilegeObjects(dcObjects)...)
+
+	if ctx.VMConfig.Workspace.ResourcePoolPath == "" {
+		clusterRefs, err := getNodeComputeClusters(ctx)
+		if err != nil {
+			return nil, err
+		}
+		clusterObjects, err := newPrivilegeObjects(ctx, "compute cluster", clusterRefs, permissionCluster)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, clusterObjects...)
+	}
+
+	dsRefs, err := getNodeDatastores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dsObjects, err := newPrivilegeObjects(ctx, "datastore", dsRefs, permissionDatastore)
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, dsObjects...)
+
+	vms, err := getNodeVMs(ctx, []string{"parent"})
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[vim.ManagedObjectReference]bool)
+	var folderRefs []vim.ManagedObjectReference
+	for _, vm := range vms {
+		if vm.Parent != nil && !found[*vm.Parent] {
+			folderRefs = append(folderRefs, *vm.Parent)
+			found[*vm.Parent] = true
+		}
+	}
+	folderObjects, err := newPrivilegeObjects(ctx, "folder", folderRefs, permissionFolder)
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, folderObjects...)
+	return objects, nil
+}
+
+// newPrivilegeObjects returns privilegeObjects of given objects with their names, sorted by name.
+func newPrivilegeObjects(ctx *CheckContext, kind string, refs []vim.ManagedObjectReference, group permissionGroup) ([]privilegeObject, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var entities []mo.ManagedEntity
+	if err := pc.Retrieve(tctx, refs, []string{"name"}, &entities); err != nil {
+		return nil, fmt.Errorf("failed to get names of %s objects: %s", kind, err)
+	}
+	var objects []privilegeObject
+	for _, entity := range entities {
+		objects = append(objects, privilegeObject{kind: kind, name: entity.Name, ref: entity.Reference(), group: group})
+	}
+	return sortPrivilegeObjects(objects), nil
+}
+
+func sortPrivilegeObjects(objects []privilegeObject) []privilegeObject {
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].name != objects[j].name {
+			return objects[i].name < objects[j].name
+		}
+		return objects[i].ref.Value < objects[j].ref.Value
+	})
+	return objects
+}
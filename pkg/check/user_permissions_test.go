@@ -0,0 +1,138 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// fakePrivilegeAuthManager grants all privileges of all permission groups, except the missing ones.
+type fakePrivilegeAuthManager struct {
+	// entity -> privileges the user does not have on it
+	missing map[vim.ManagedObjectReference][]string
+}
+
+var _ AuthManager = &fakePrivilegeAuthManager{}
+
+func (m *fakePrivilegeAuthManager) FetchUserPrivilegeOnEntities(ctx context.Context, entities []vim.ManagedObjectReference, userName string) ([]vim.UserPrivilegeResult, error) {
+	var results []vim.UserPrivilegeResult
+	for _, entity := range entities {
+		missing := make(map[string]bool)
+		for _, privilege := range m.missing[entity] {
+			missing[privilege] = true
+		}
+		var privileges []string
+		for _, group := range permissions {
+			for _, privilege := range group {
+				if !missing[privilege] {
+					privileges = append(privileges, privilege)
+				}
+			}
+		}
+		results = append(results, vim.UserPrivilegeResult{Entity: entity, Privileges: privileges})
+	}
+	return results, nil
+}
+
+func TestCheckUserPermissions(t *testing.T) {
+	tests := []struct {
+		name string
+		// object -> missing privileges, objects are "datastore", "folder", "datacenter" or "vcenter"
+		missing       map[string][]string
+		expectError   string
+		expectedCount int
+	}{
+		{
+			name: "all privileges",
+		},
+		{
+			name: "missing datastore privilege",
+			missing: map[string][]string{
+				"datastore": {"Datastore.AllocateSpace"},
+			},
+			expectError:   "missing privileges for datastore LocalDS_0: Datastore.AllocateSpace",
+			expectedCount: 1,
+		},
+		{
+			name: "missing privileges on several objects",
+			missing: map[string][]string{
+				"vcenter":    {"Cns.Searchable"},
+				"datacenter": {"System.Read"},
+				"folder":     {"VirtualMachine.Config.AddNewDisk", "VirtualMachine.Config.Rename"},
+			},
+			expectError: "missing privileges for vCenter dc0: Cns.Searchable;\n" +
+				"missing privileges for datacenter DC0: System.Read;\n" +
+				"missing privileges for folder vm: VirtualMachine.Config.AddNewDisk, VirtualMachine.Config.Rename",
+			expectedCount: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			dc, err := getDatacenter(ctx, defaultDC)
+			if err != nil {
+				t.Fatalf("Failed to get datacenter: %s", err)
+			}
+			ds, err := getDataStoreByName(ctx, "LocalDS_0", dc)
+			if err != nil {
+				t.Fatalf("Failed to get datastore: %s", err)
+			}
+			folders, err := dc.Folders(ctx.Context)
+			if err != nil {
+				t.Fatalf("Failed to get datacenter folders: %s", err)
+			}
+			refs := map[string]vim.ManagedObjectReference{
+				"vcenter":    ctx.VMClient.ServiceContent.RootFolder,
+				"datacenter": dc.Reference(),
+				"datastore":  ds.Reference(),
+				"folder":     folders.VmFolder.Reference(),
+			}
+			authManager := &fakePrivilegeAuthManager{missing: make(map[vim.ManagedObjectReference][]string)}
+			for object, privileges := range test.missing {
+				authManager.missing[refs[object]] = privileges
+			}
+			ctx.AuthManager = authManager
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckUserPermissions(ctx)
+
+			// Assert
+			if test.expectError == "" && err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if test.expectError != "" {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectError)
+				} else if err.Error() != test.expectError {
+					t.Errorf("Expected error %q, got %q", test.expectError, err.Error())
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_user_missing_privileges_total [ALPHA] Number of vSphere objects used by the cluster on which the configured user misses privileges required by OpenShift.
+# TYPE vsphere_user_missing_privileges_total gauge
+vsphere_user_missing_privileges_total{vcenter="dc0"} %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_user_missing_privileges_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -5,13 +5,76 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/vmware/govmomi/cns"
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/pbm"
+	pbmmethods "github.com/vmware/govmomi/pbm/methods"
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
 	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
 	vim "github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/govmomi/vsan"
 )
 
+// rateLimitExemptKey is the context key of WithoutRateLimit.
+type rateLimitExemptKey struct{}
+
+// WithoutRateLimit returns a context whose vSphere API calls are not delayed by the client side API rate limit
+// shared by all checks. It is meant for the few calls whose duration is measured, so it does not include
+// the time spent waiting for other checks.
+func WithoutRateLimit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rateLimitExemptKey{}, true)
+}
+
+// IsRateLimitExempt returns true when vSphere API calls made with ctx are not rate limited, see WithoutRateLimit.
+func IsRateLimitExempt(ctx context.Context) bool {
+	exempt, _ := ctx.Value(rateLimitExemptKey{}).(bool)
+	return exempt
+}
+
+// UseClientProxy makes a client of a vCenter service use the same proxy as the vCenter client. Clients created
+// by soap.Client.NewServiceClient use the proxy of http.DefaultTransport instead.
+func UseClientProxy(serviceClient *soap.Client, client *vim25.Client) {
+	serviceClient.DefaultTransport().Proxy = client.Client.DefaultTransport().Proxy
+}
+
+// newPbmClient returns a PBM client like pbm.NewClient, with the proxy of the vCenter client.
+func newPbmClient(ctx context.Context, client *vim25.Client) (*pbm.Client, error) {
+	sc := client.Client.NewServiceClient(pbm.Path, pbm.Namespace)
+	UseClientProxy(sc, client)
+	req := pbmtypes.PbmRetrieveServiceContent{
+		This: pbm.ServiceInstance,
+	}
+	res, err := pbmmethods.PbmRetrieveServiceContent(ctx, sc, &req)
+	if err != nil {
+		return nil, err
+	}
+	return &pbm.Client{Client: sc, ServiceContent: res.Returnval, RoundTripper: sc}, nil
+}
+
+// newCnsClient returns a CNS client like cns.NewClient, with the proxy of the vCenter client.
+func newCnsClient(ctx context.Context, client *vim25.Client) (*cns.Client, error) {
+	c, err := cns.NewClient(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	UseClientProxy(c.Client, client)
+	return c, nil
+}
+
+// newVsanClient returns a vSAN health client like vsan.NewClient, with the proxy of the vCenter client.
+func newVsanClient(ctx context.Context, client *vim25.Client) (*vsan.Client, error) {
+	c, err := vsan.NewClient(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	UseClientProxy(c.Client, client)
+	return c, nil
+}
+
 func getDatacenter(ctx *CheckContext, dcName string) (*object.Datacenter, error) {
 	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
 	defer cancel()
@@ -0,0 +1,64 @@
+package check
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/methods"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Nr. of calls to measure vCenter API latency.
+	apiLatencySamples = 5
+)
+
+var (
+	// apiLatencyThreshold is the vCenter API latency that is reported as degraded responsiveness.
+	apiLatencyThreshold = flag.Duration("vcenter-api-latency-threshold", 2*time.Second, "Median latency of a lightweight vCenter API call above which vCenter API responsiveness is reported as degraded.")
+
+	apiLatencyMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vcenter_api_latency_seconds",
+			Help:           "Median latency of a lightweight vCenter API call in seconds.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(apiLatencyMetric)
+}
+
+// CheckVCenterAPIRateLimitHeadroom measures latency of a lightweight vCenter API call. High latency is
+// an early warning that vCenter is overloaded or throttles API clients, including the CSI driver.
+// The calls bypass the API rate limit of the detector, so waiting for the other checks is not measured.
+func CheckVCenterAPIRateLimitHeadroom(ctx *CheckContext) error {
+	var latencies []time.Duration
+	for i := 0; i < apiLatencySamples; i++ {
+		tctx, cancel := context.WithTimeout(WithoutRateLimit(ctx.Context), *Timeout)
+		start := time.Now()
+		_, err := methods.GetCurrentTime(tctx, ctx.VMClient)
+		latency := time.Since(start)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to get vCenter current time: %s", err)
+		}
+		latencies = append(latencies, latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	median := latencies[len(latencies)/2]
+	apiLatencyMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(median.Seconds())
+
+	klog.V(2).Infof("CheckVCenterAPIRateLimitHeadroom: median vCenter API latency is %s", median)
+	if median > *apiLatencyThreshold {
+		return fmt.Errorf("vCenter API responsiveness is degraded: median latency of %d calls is %s, more than %s", apiLatencySamples, median, *apiLatencyThreshold)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package check
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckVCenterAPIRateLimitHeadroom(t *testing.T) {
+	tests := []struct {
+		name        string
+		threshold   time.Duration
+		expectError bool
+	}{
+		{
+			name:        "responsive vCenter",
+			threshold:   time.Minute,
+			expectError: false,
+		},
+		{
+			name:        "degraded vCenter",
+			threshold:   time.Nanosecond,
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			oldThreshold := *apiLatencyThreshold
+			*apiLatencyThreshold = test.threshold
+			defer func() { *apiLatencyThreshold = oldThreshold }()
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckVCenterAPIRateLimitHeadroom(ctx)
+
+			// Assert
+			if err != nil && !test.expectError {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if err == nil && test.expectError {
+				t.Errorf("Expected error, got none")
+			}
+			metricFamilies, err := legacyregistry.DefaultGatherer.Gather()
+			if err != nil {
+				t.Fatalf("Failed to gather metrics: %s", err)
+			}
+			found := false
+			for _, mf := range metricFamilies {
+				if mf.GetName() != "vsphere_vcenter_api_latency_seconds" {
+					continue
+				}
+				found = true
+				if !strings.Contains(mf.GetHelp(), "Median latency") || len(mf.GetMetric()) != 1 || mf.GetMetric()[0].GetGauge().GetValue() <= 0 {
+					t.Errorf("Unexpected metric: %s", mf.String())
+				}
+			}
+			if !found {
+				t.Errorf("Metric vsphere_vcenter_api_latency_seconds not found")
+			}
+		})
+	}
+}
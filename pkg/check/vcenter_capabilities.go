@@ -0,0 +1,98 @@
+package check
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// vCenterAPIType is about.apiType of vCenter, ESXi hosts report HostAgent.
+	vCenterAPIType = "VirtualCenter"
+)
+
+var (
+	vCenterMissingCapabilitiesMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vcenter_missing_capabilities_total",
+			Help:           "Number of capabilities required by the vSphere CSI driver that vCenter does not advertise.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(vCenterMissingCapabilitiesMetric)
+}
+
+// serviceContentCapability is a capability advertised by a managed object in ServiceContent.
+type serviceContentCapability struct {
+	// name is the name of the ServiceContent property.
+	name string
+	// description explains what the CSI driver needs the capability for.
+	description string
+	ref         func(content *types.ServiceContent) *types.ManagedObjectReference
+}
+
+// requiredServiceContentCapabilities are ServiceContent managers the vSphere CSI driver depends on.
+var requiredServiceContentCapabilities = []serviceContentCapability{
+	{
+		name:        "vStorageObjectManager",
+		description: "first class disks used as volumes",
+		ref: func(content *types.ServiceContent) *types.ManagedObjectReference {
+			return content.VStorageObjectManager
+		},
+	},
+	{
+		name:        "searchIndex",
+		description: "discovery of node VMs by their UUID",
+		ref: func(content *types.ServiceContent) *types.ManagedObjectReference {
+			return content.SearchIndex
+		},
+	},
+	{
+		name:        "viewManager",
+		description: "listing of datastores and VMs",
+		ref: func(content *types.ServiceContent) *types.ManagedObjectReference {
+			return content.ViewManager
+		},
+	},
+}
+
+// CheckVCenterServiceContentCapabilities tests that the connected vCenter advertises capabilities the vSphere
+// CSI driver requires: it must be a vCenter and not a standalone ESXi host, its ServiceContent must include
+// managers used by the driver and the storage policy (PBM) service must be available.
+// Minimal or restricted vCenter deployments may lack some of them and the driver cannot work there.
+func CheckVCenterServiceContentCapabilities(ctx *CheckContext) error {
+	content := ctx.VMClient.ServiceContent
+	vCenter := ctx.VMConfig.Workspace.VCenterIP
+
+	var errs []error
+	if content.About.ApiType != vCenterAPIType {
+		errs = append(errs, fmt.Errorf("vCenter %s does not advertise capability %s required by the vSphere CSI driver: about.apiType is %q, the driver requires a vCenter", vCenter, vCenterAPIType, content.About.ApiType))
+	}
+	for _, capability := range requiredServiceContentCapabilities {
+		if capability.ref(&content) == nil {
+			errs = append(errs, fmt.Errorf("vCenter %s does not advertise capability %s required by the vSphere CSI driver for %s", vCenter, capability.name, capability.description))
+			continue
+		}
+		klog.V(4).Infof("vCenter %s advertises %s", vCenter, capability.name)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	if _, err := newPbmClient(tctx, ctx.VMClient); err != nil {
+		errs = append(errs, fmt.Errorf("vCenter %s does not advertise capability pbm required by the vSphere CSI driver for storage policies: %s", vCenter, err))
+	} else {
+		klog.V(4).Infof("vCenter %s advertises pbm", vCenter)
+	}
+
+	vCenterMissingCapabilitiesMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(len(errs)))
+	klog.V(2).Infof("CheckVCenterServiceContentCapabilities: vCenter %s misses %d required capabilities", vCenter, len(errs))
+	return JoinErrors(errs)
+}
@@ -0,0 +1,95 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckVCenterServiceContentCapabilities(t *testing.T) {
+	tests := []struct {
+		name            string
+		modifyContent   func(content *types.ServiceContent)
+		expectedError   string
+		expectedMetrics string
+	}{
+		{
+			name: "all capabilities",
+			expectedMetrics: `
+# HELP vsphere_vcenter_missing_capabilities_total [ALPHA] Number of capabilities required by the vSphere CSI driver that vCenter does not advertise.
+# TYPE vsphere_vcenter_missing_capabilities_total gauge
+vsphere_vcenter_missing_capabilities_total{vcenter="dc0"} 0
+`,
+		},
+		{
+			name: "missing vStorageObjectManager",
+			modifyContent: func(content *types.ServiceContent) {
+				content.VStorageObjectManager = nil
+			},
+			expectedError: "vCenter dc0 does not advertise capability vStorageObjectManager required by the vSphere CSI driver for first class disks used as volumes",
+			expectedMetrics: `
+# HELP vsphere_vcenter_missing_capabilities_total [ALPHA] Number of capabilities required by the vSphere CSI driver that vCenter does not advertise.
+# TYPE vsphere_vcenter_missing_capabilities_total gauge
+vsphere_vcenter_missing_capabilities_total{vcenter="dc0"} 1
+`,
+		},
+		{
+			name: "ESXi host",
+			modifyContent: func(content *types.ServiceContent) {
+				content.About.ApiType = "HostAgent"
+				content.VStorageObjectManager = nil
+				content.SearchIndex = nil
+			},
+			expectedError: "vCenter dc0 does not advertise capability VirtualCenter required by the vSphere CSI driver: about.apiType is \"HostAgent\", the driver requires a vCenter;\n" +
+				"vCenter dc0 does not advertise capability vStorageObjectManager required by the vSphere CSI driver for first class disks used as volumes;\n" +
+				"vCenter dc0 does not advertise capability searchIndex required by the vSphere CSI driver for discovery of node VMs by their UUID",
+			expectedMetrics: `
+# HELP vsphere_vcenter_missing_capabilities_total [ALPHA] Number of capabilities required by the vSphere CSI driver that vCenter does not advertise.
+# TYPE vsphere_vcenter_missing_capabilities_total gauge
+vsphere_vcenter_missing_capabilities_total{vcenter="dc0"} 3
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			if test.modifyContent != nil {
+				test.modifyContent(&ctx.VMClient.ServiceContent)
+			}
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckVCenterServiceContentCapabilities(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_vcenter_missing_capabilities_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package check
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	vCenterLabel = "vcenter"
+)
+
+var (
+	// roundTripThreshold is the round-trip time of a vCenter API call that is reported as a slow vCenter.
+	roundTripThreshold = flag.Duration("vcenter-round-trip-threshold", 5*time.Second, "Round-trip time of a lightweight vCenter API call on a new connection above which the vCenter is reported as slow.")
+
+	roundTripMetric = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Name:           "vsphere_vcenter_api_round_trip_seconds",
+			Help:           "Round-trip time of a lightweight vCenter API call in seconds, including DNS resolution, TCP connection and TLS handshake of a new connection to the vCenter.",
+			Buckets:        []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(roundTripMetric)
+}
+
+// CheckVCenterConnectivity measures round-trip time of a lightweight API call to each connected vCenter
+// over a new connection, so DNS resolution and TLS handshake are verified in each round of checks.
+// Slow vCenters are a common cause of volume attach and detach timeouts.
+func CheckVCenterConnectivity(ctx *CheckContext) error {
+	clients := ctx.VMClients
+	if len(clients) == 0 {
+		clients = map[string]*vim25.Client{ctx.VMConfig.Workspace.VCenterIP: ctx.VMClient}
+	}
+	vcContext := *ctx
+	vcContext.VMClients = clients
+
+	var errs []error
+	for _, vCenter := range vcContext.connectedVCenters() {
+		roundTrip, err := measureRoundTrip(ctx.Context, clients[vCenter])
+		if err != nil {
+			errs = append(errs, describeConnectivityError(vCenter, err))
+			continue
+		}
+		roundTripMetric.WithLabelValues(vCenter).Observe(roundTrip.Seconds())
+		klog.V(2).Infof("CheckVCenterConnectivity: round-trip time of vCenter %s is %s", vCenter, roundTrip)
+		if roundTrip > *roundTripThreshold {
+			errs = append(errs, fmt.Errorf("vCenter %s is slow: round-trip time of an API call is %s, more than %s", vCenter, roundTrip, *roundTripThreshold))
+		}
+	}
+	return JoinErrors(errs)
+}
+
+// measureRoundTrip returns duration of CurrentTime call to the vCenter on a new connection.
+func measureRoundTrip(ctx context.Context, client *vim25.Client) (time.Duration, error) {
+	tctx, cancel := context.WithTimeout(ctx, *Timeout)
+	defer cancel()
+	// Connections in use by other checks stay open, the call opens a new one.
+	client.CloseIdleConnections()
+	start := time.Now()
+	// Call the SOAP client directly, so the time does not include waiting for the client rate limit.
+	_, err := methods.GetCurrentTime(tctx, client.Client)
+	return time.Since(start), err
+}
+
+// describeConnectivityError returns error of a failed vCenter call with the cause in plain words.
+func describeConnectivityError(vCenter string, err error) error {
+	var dnsErr *net.DNSError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateErr x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return fmt.Errorf("failed to resolve vCenter %s: %s", vCenter, err)
+	case errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr), errors.As(err, &certificateErr),
+		errors.As(err, &recordHeaderErr), strings.Contains(err.Error(), "thumbprint does not match"):
+		return fmt.Errorf("TLS handshake with vCenter %s failed: %s", vCenter, err)
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("vCenter %s did not respond within %s: %s", vCenter, *Timeout, err)
+	}
+	return fmt.Errorf("failed to call API of vCenter %s: %s", vCenter, err)
+}
@@ -0,0 +1,133 @@
+package check
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckVCenterConnectivity(t *testing.T) {
+	tests := []struct {
+		name          string
+		threshold     time.Duration
+		expectedError string
+	}{
+		{
+			name:      "responsive vCenter",
+			threshold: time.Minute,
+		},
+		{
+			name:          "slow vCenter",
+			threshold:     time.Nanosecond,
+			expectedError: "vCenter dc0 is slow: round-trip time of an API call is ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			oldThreshold := *roundTripThreshold
+			*roundTripThreshold = test.threshold
+			defer func() { *roundTripThreshold = oldThreshold }()
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckVCenterConnectivity(ctx)
+
+			// Assert
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			if test.expectedError == "" && errString != "" {
+				t.Errorf("Unexpected error: %s", errString)
+			}
+			if !strings.HasPrefix(errString, test.expectedError) {
+				t.Errorf("Expected error starting with %q, got %q", test.expectedError, errString)
+			}
+			metricFamilies, err := legacyregistry.DefaultGatherer.Gather()
+			if err != nil {
+				t.Fatalf("Failed to gather metrics: %s", err)
+			}
+			found := false
+			for _, mf := range metricFamilies {
+				if mf.GetName() != "vsphere_vcenter_api_round_trip_seconds" {
+					continue
+				}
+				found = true
+				if len(mf.GetMetric()) != 1 {
+					t.Fatalf("Expected a single histogram, got %s", mf.String())
+				}
+				m := mf.GetMetric()[0]
+				if len(m.GetLabel()) != 1 || m.GetLabel()[0].GetValue() != "dc0" || m.GetHistogram().GetSampleCount() != 1 {
+					t.Errorf("Unexpected metric: %s", mf.String())
+				}
+			}
+			if !found {
+				t.Errorf("Metric vsphere_vcenter_api_round_trip_seconds not found")
+			}
+		})
+	}
+}
+
+func TestDescribeConnectivityError(t *testing.T) {
+	urlError := func(err error) error {
+		return &url.Error{Op: "Post", URL: "https://vcenter.example.com/sdk", Err: err}
+	}
+	tests := []struct {
+		name          string
+		err           error
+		expectedError string
+	}{
+		{
+			name:          "DNS",
+			err:           urlError(&net.DNSError{Err: "no such host", Name: "vcenter.example.com"}),
+			expectedError: `failed to resolve vCenter vc1: Post "https://vcenter.example.com/sdk": lookup vcenter.example.com: no such host`,
+		},
+		{
+			name:          "unknown certificate authority",
+			err:           urlError(x509.UnknownAuthorityError{}),
+			expectedError: `TLS handshake with vCenter vc1 failed: Post "https://vcenter.example.com/sdk": x509: certificate signed by unknown authority`,
+		},
+		{
+			name:          "thumbprint",
+			err:           urlError(errors.New(`host "vcenter.example.com:443" thumbprint does not match "AA:BB"`)),
+			expectedError: `TLS handshake with vCenter vc1 failed: Post "https://vcenter.example.com/sdk": host "vcenter.example.com:443" thumbprint does not match "AA:BB"`,
+		},
+		{
+			name:          "timeout",
+			err:           fmt.Errorf("call failed: %w", context.DeadlineExceeded),
+			expectedError: fmt.Sprintf("vCenter vc1 did not respond within %s: call failed: context deadline exceeded", *Timeout),
+		},
+		{
+			name:          "other",
+			err:           urlError(errors.New("connection refused")),
+			expectedError: `failed to call API of vCenter vc1: Post "https://vcenter.example.com/sdk": connection refused`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := describeConnectivityError("vc1", test.err)
+			if err.Error() != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+			}
+		})
+	}
+}
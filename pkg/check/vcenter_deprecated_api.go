@@ -0,0 +1,97 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// apiDeprecation describes a vCenter API used by OCP that is deprecated in vCenter.
+type apiDeprecation struct {
+	// api is the name of the vCenter API.
+	api string
+	// usedBy lists OCP components that use the API.
+	usedBy string
+	// deprecatedIn is the first vCenter version that deprecates the API.
+	deprecatedIn string
+	// removedIn is the first vCenter version without the API, empty when the removal was not announced yet.
+	removedIn string
+}
+
+var (
+	// deprecatedAPIs lists deprecations of vCenter APIs that the detector, the in-tree vSphere volume plugin
+	// or the vSphere CSI driver rely on. Update the list when VMware announces new deprecations.
+	deprecatedAPIs = []apiDeprecation{
+		{
+			api:          "VirtualDiskManager",
+			usedBy:       "the in-tree vSphere volume plugin",
+			deprecatedIn: "8.0.0",
+		},
+	}
+
+	vCenterDeprecatedAPIsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vcenter_deprecated_apis_total",
+			Help:           "Number of vCenter APIs used by the cluster that are deprecated or removed in the connected vCenter.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(vCenterDeprecatedAPIsMetric)
+}
+
+// CheckVCenterDeprecatedAPIUsage tests that the connected vCenter still provides all APIs that are used
+// by the cluster. APIs deprecated by the vCenter are only logged as a warning, so admins can prepare
+// for their removal before upgrading vCenter. APIs already removed from the vCenter are reported as errors.
+func CheckVCenterDeprecatedAPIUsage(ctx *CheckContext) error {
+	version := ctx.VMClient.ServiceContent.About.Version
+	vCenterVersion, err := semver.ParseTolerant(parseForSemver(version))
+	if err != nil {
+		return fmt.Errorf("failed to parse vCenter version %q: %s", version, err)
+	}
+
+	var errs []error
+	affected := 0
+	for _, deprecation := range deprecatedAPIs {
+		if deprecation.removedIn != "" && !isVersionBefore(vCenterVersion, deprecation.removedIn) {
+			affected++
+			errs = append(errs, fmt.Errorf("vCenter %s does not provide API %s used by %s, the API was removed in vCenter %s", version, deprecation.api, deprecation.usedBy, deprecation.removedIn))
+			continue
+		}
+		if isVersionBefore(vCenterVersion, deprecation.deprecatedIn) {
+			continue
+		}
+		affected++
+		removal := "a future vCenter release"
+		if deprecation.removedIn != "" {
+			removal = "vCenter " + deprecation.removedIn
+		}
+		klog.Warningf("vCenter %s deprecates API %s used by %s, the API will be removed in %s", version, deprecation.api, deprecation.usedBy, removal)
+	}
+	vCenterDeprecatedAPIsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(affected))
+
+	klog.V(2).Infof("CheckVCenterDeprecatedAPIUsage checked %d deprecated APIs, %d affect vCenter %s", len(deprecatedAPIs), affected, version)
+	return JoinErrors(errs)
+}
+
+// isVersionBefore returns true if version is lower than other version.
+// The other version is from deprecatedAPIs and it is expected to be valid.
+func isVersionBefore(version semver.Version, other string) bool {
+	return version.LT(semver.MustParse(other))
+}
+
+// parseForSemver returns the first three components of a version, e.g. "7.0.3" for "7.0.3.01000".
+func parseForSemver(version string) string {
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return strings.Join(parts[0:3], ".")
+	}
+	return version
+}
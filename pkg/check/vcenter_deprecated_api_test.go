@@ -0,0 +1,125 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver"
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckVCenterDeprecatedAPIUsage(t *testing.T) {
+	deprecations := []apiDeprecation{
+		{
+			api:          "DeprecatedAPI",
+			usedBy:       "the detector",
+			deprecatedIn: "7.0.2",
+		},
+		{
+			api:          "RemovedAPI",
+			usedBy:       "the vSphere CSI driver",
+			deprecatedIn: "7.0.0",
+			removedIn:    "8.0.0",
+		},
+	}
+
+	tests := []struct {
+		name          string
+		version       string
+		expectedError string
+		expectedCount int
+	}{
+		{
+			name:    "old vCenter",
+			version: "6.7.3",
+		},
+		{
+			name:          "vCenter with deprecated API",
+			version:       "7.0.0",
+			expectedCount: 1,
+		},
+		{
+			name:          "vCenter with deprecated APIs",
+			version:       "7.0.3.01000",
+			expectedCount: 2,
+		},
+		{
+			name:          "vCenter with removed API",
+			version:       "8.0.1",
+			expectedError: "vCenter 8.0.1 does not provide API RemovedAPI used by the vSphere CSI driver, the API was removed in vCenter 8.0.0",
+			expectedCount: 2,
+		},
+		{
+			name:          "invalid version",
+			version:       "foo",
+			expectedError: `failed to parse vCenter version "foo": Invalid character(s) found in major number "foo"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMClient.ServiceContent.About.Version = test.version
+
+			origDeprecatedAPIs := deprecatedAPIs
+			deprecatedAPIs = deprecations
+			defer func() { deprecatedAPIs = origDeprecatedAPIs }()
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckVCenterDeprecatedAPIUsage(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+				if test.expectedCount == 0 {
+					// The metric is not reported at all
+					return
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_vcenter_deprecated_apis_total [ALPHA] Number of vCenter APIs used by the cluster that are deprecated or removed in the connected vCenter.
+# TYPE vsphere_vcenter_deprecated_apis_total gauge
+vsphere_vcenter_deprecated_apis_total{vcenter="dc0"} %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_vcenter_deprecated_apis_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+func TestDeprecatedAPIsVersions(t *testing.T) {
+	// isVersionBefore panics on invalid versions in deprecatedAPIs
+	for _, deprecation := range deprecatedAPIs {
+		versions := []string{deprecation.deprecatedIn}
+		if deprecation.removedIn != "" {
+			versions = append(versions, deprecation.removedIn)
+		}
+		for _, version := range versions {
+			if _, err := semver.Parse(version); err != nil {
+				t.Errorf("Invalid version %q of API %s: %s", version, deprecation.api, err)
+			}
+		}
+	}
+}
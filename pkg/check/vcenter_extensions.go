@@ -0,0 +1,86 @@
+package check
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// expectedVCenterExtensions is a comma separated list of keys of vCenter extensions that must be registered.
+	expectedVCenterExtensions = flag.String("expected-vcenter-extensions", "com.vmware.vim.sps", "Comma separated list of keys of vCenter extensions that the vSphere CSI driver depends on and that must be registered in vCenter.")
+	// vCenterExtensionHeartbeatTimeout is the time after which an extension without a heartbeat is considered unhealthy.
+	vCenterExtensionHeartbeatTimeout = flag.Duration("vcenter-extension-heartbeat-timeout", time.Hour, "Time after which a vCenter extension that reports heartbeats, but did not report any, is considered unhealthy.")
+
+	vCenterExtensionsUnhealthyMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vcenter_extensions_unhealthy_total",
+			Help:           "Number of expected vCenter extensions that are not registered or not healthy.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(vCenterExtensionsUnhealthyMetric)
+}
+
+// CheckVCenterPluginHealth tests that vCenter extensions that the vSphere CSI driver depends on are registered
+// in vCenter and that they report heartbeats, if they report heartbeats at all.
+func CheckVCenterPluginHealth(ctx *CheckContext) error {
+	var keys []string
+	for _, key := range strings.Split(*expectedVCenterExtensions, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		klog.V(4).Infof("CheckVCenterPluginHealth: no vCenter extensions are expected, skipping")
+		vCenterExtensionsUnhealthyMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	m, err := object.GetExtensionManager(ctx.VMClient)
+	if err != nil {
+		return fmt.Errorf("failed to get vCenter extension manager: %s", err)
+	}
+	extensions, err := m.List(tctx)
+	if err != nil {
+		return fmt.Errorf("failed to list vCenter extensions: %s", err)
+	}
+	registered := make(map[string]types.Extension)
+	for _, extension := range extensions {
+		registered[extension.Key] = extension
+	}
+
+	var errs []error
+	now := time.Now()
+	for _, key := range keys {
+		extension, found := registered[key]
+		if !found {
+			errs = append(errs, fmt.Errorf("vCenter extension %s is not registered", key))
+			continue
+		}
+		// Extensions that do not report heartbeats have empty lastHeartbeatTime.
+		if !extension.LastHeartbeatTime.IsZero() && now.Sub(extension.LastHeartbeatTime) > *vCenterExtensionHeartbeatTimeout {
+			errs = append(errs, fmt.Errorf("vCenter extension %s version %s is not healthy: last heartbeat at %s", key, extension.Version, extension.LastHeartbeatTime.Format(time.RFC3339)))
+			continue
+		}
+		klog.V(4).Infof("vCenter extension %s version %s is registered", key, extension.Version)
+	}
+	vCenterExtensionsUnhealthyMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(len(errs)))
+
+	klog.V(2).Infof("CheckVCenterPluginHealth checked %d vCenter extensions, %d problems found", len(keys), len(errs))
+	return JoinErrors(errs)
+}
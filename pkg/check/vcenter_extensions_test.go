@@ -0,0 +1,111 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// fakeExtensionManager is ExtensionManager for vcsim, which does not implement it.
+type fakeExtensionManager struct {
+	mo.ExtensionManager
+}
+
+func TestCheckVCenterPluginHealth(t *testing.T) {
+	tests := []struct {
+		name          string
+		expected      string
+		extensions    []types.Extension
+		expectedError string
+		expectedCount int
+	}{
+		{
+			name:     "no expected extensions",
+			expected: "",
+		},
+		{
+			name:     "registered extensions",
+			expected: "com.example.a, com.example.b",
+			extensions: []types.Extension{
+				{Key: "com.example.a", Version: "1.0"},
+				{Key: "com.example.b", Version: "2.0", LastHeartbeatTime: time.Now().Add(-time.Minute)},
+				{Key: "com.example.c", Version: "3.0"},
+			},
+		},
+		{
+			name:     "missing extension",
+			expected: "com.example.a,com.example.b",
+			extensions: []types.Extension{
+				{Key: "com.example.a", Version: "1.0"},
+			},
+			expectedError: "vCenter extension com.example.b is not registered",
+			expectedCount: 1,
+		},
+		{
+			name:     "extension without heartbeat",
+			expected: "com.example.a",
+			extensions: []types.Extension{
+				{Key: "com.example.a", Version: "1.0", LastHeartbeatTime: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+			},
+			expectedError: "vCenter extension com.example.a version 1.0 is not healthy: last heartbeat at 2022-01-01T00:00:00Z",
+			expectedCount: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			m := &fakeExtensionManager{}
+			m.Self = *ctx.VMClient.ServiceContent.ExtensionManager
+			m.ExtensionList = test.extensions
+			simulator.Map.Put(m)
+
+			origExpectedVCenterExtensions := *expectedVCenterExtensions
+			*expectedVCenterExtensions = test.expected
+			defer func() { *expectedVCenterExtensions = origExpectedVCenterExtensions }()
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckVCenterPluginHealth(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_vcenter_extensions_unhealthy_total [ALPHA] Number of expected vCenter extensions that are not registered or not healthy.
+# TYPE vsphere_vcenter_extensions_unhealthy_total gauge
+vsphere_vcenter_extensions_unhealthy_total{vcenter="dc0"} %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_vcenter_extensions_unhealthy_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package check
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Identity source of vCenter users that are local accounts of the vCenter appliance.
+	localOSDomain = "localos"
+	// REST API of local accounts of the vCenter appliance.
+	localAccountsAPIPath = "/api/appliance/local-accounts/"
+)
+
+var (
+	// passwordExpiryWarningDays is the number of days before password expiry when the check starts to fail.
+	passwordExpiryWarningDays = flag.Int("password-expiry-warning-days", 14, "Number of days before expiry of the password of the vCenter user of the detector when the expiry is reported.")
+
+	passwordExpiryDaysMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vcenter_password_expiry_days",
+			Help:           "Number of days until the password of the vCenter user of the detector expires. Not reported when vCenter does not expose the password expiry.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(passwordExpiryDaysMetric)
+}
+
+// localAccountInfo is the part of a local account of the vCenter appliance returned by its REST API.
+type localAccountInfo struct {
+	PasswordExpiresAt *time.Time `json:"password_expires_at,omitempty"`
+}
+
+// CheckVCenterPasswordExpiryForServiceAccount checks that the password of the vCenter user of the detector does
+// not expire within password-expiry-warning-days. The detector and the CSI driver fail to log in once it expires.
+// Only local accounts of the vCenter appliance expose their password expiry, the check is skipped for users
+// of other identity sources, such as vsphere.local, and when the expiry is not available.
+func CheckVCenterPasswordExpiryForServiceAccount(ctx *CheckContext) error {
+	name, domain := splitUsername(ctx.Username)
+	if !strings.EqualFold(domain, localOSDomain) {
+		klog.V(2).Infof("CheckVCenterPasswordExpiryForServiceAccount: identity source %q of user %s does not expose password expiry, skipping", domain, ctx.Username)
+		resetVCenterMetric(passwordExpiryDaysMetric, ctx.VMConfig.Workspace.VCenterIP)
+		return nil
+	}
+	if ctx.TagManager == nil {
+		klog.V(2).Infof("CheckVCenterPasswordExpiryForServiceAccount: vCenter REST API is not available, skipping")
+		resetVCenterMetric(passwordExpiryDaysMetric, ctx.VMConfig.Workspace.VCenterIP)
+		return nil
+	}
+
+	info, err := getLocalAccountInfo(ctx, name)
+	if err != nil {
+		klog.V(2).Infof("CheckVCenterPasswordExpiryForServiceAccount: failed to get local account %s, skipping: %s", name, err)
+		resetVCenterMetric(passwordExpiryDaysMetric, ctx.VMConfig.Workspace.VCenterIP)
+		return nil
+	}
+	if info.PasswordExpiresAt == nil {
+		klog.V(2).Infof("CheckVCenterPasswordExpiryForServiceAccount: password of user %s does not expire", ctx.Username)
+		resetVCenterMetric(passwordExpiryDaysMetric, ctx.VMConfig.Workspace.VCenterIP)
+		return nil
+	}
+
+	days, err := getPasswordExpiryProblem(ctx.Username, *info.PasswordExpiresAt, time.Now())
+	passwordExpiryDaysMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(days))
+	if err == nil {
+		klog.V(2).Infof("CheckVCenterPasswordExpiryForServiceAccount: password of user %s expires in %d days", ctx.Username, days)
+	}
+	return err
+}
+
+// getLocalAccountInfo returns the local account of the vCenter appliance with the given name.
+func getLocalAccountInfo(ctx *CheckContext, name string) (*localAccountInfo, error) {
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	restClient := ctx.TagManager.Client
+	req, err := http.NewRequest(http.MethodGet, restClient.Resource(localAccountsAPIPath+url.PathEscape(name)).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var info localAccountInfo
+	if err := restClient.Do(tctx, req, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// getPasswordExpiryProblem returns the number of whole days until the password expires, negative when it already
+// expired, and an error when the expiry is within password-expiry-warning-days.
+func getPasswordExpiryProblem(username string, expiresAt, now time.Time) (int, error) {
+	days := int(math.Floor(expiresAt.Sub(now).Hours() / 24))
+	switch {
+	case !expiresAt.After(now):
+		return days, fmt.Errorf("password of vCenter user %s expired on %s", username, expiresAt.UTC().Format(time.RFC3339))
+	case days < *passwordExpiryWarningDays:
+		return days, fmt.Errorf("password of vCenter user %s expires in %d days, on %s", username, days, expiresAt.UTC().Format(time.RFC3339))
+	}
+	return days, nil
+}
+
+// splitUsername returns name and domain of a vCenter user in "DOMAIN\name" or "name@domain" format.
+// The domain is empty when the user has none.
+func splitUsername(username string) (string, string) {
+	if i := strings.Index(username, "\\"); i >= 0 {
+		return username[i+1:], username[:i]
+	}
+	if i := strings.LastIndex(username, "@"); i >= 0 {
+		return username[:i], username[i+1:]
+	}
+	return username, ""
+}
@@ -0,0 +1,143 @@
+package check
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckVCenterPasswordExpiryForServiceAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+	}{
+		{
+			name:     "vsphere.local user",
+			username: "VSPHERE.LOCAL\\detector",
+		},
+		{
+			name:     "user without domain",
+			username: "detector",
+		},
+		{
+			name:     "local account not exposed by vCenter",
+			username: "detector@localos",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.Username = test.username
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+			// Expiry of the user of this vCenter from the last round and of the user of another vCenter.
+			passwordExpiryDaysMetric.WithLabelValues("dc0").Set(5)
+			passwordExpiryDaysMetric.WithLabelValues("vc2").Set(30)
+
+			// Act
+			err = CheckVCenterPasswordExpiryForServiceAccount(ctx)
+
+			// Assert
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			// vcsim does not expose password expiry, the metric is not reported for it
+			expectedMetrics := `
+# HELP vsphere_vcenter_password_expiry_days [ALPHA] Number of days until the password of the vCenter user of the detector expires. Not reported when vCenter does not expose the password expiry.
+# TYPE vsphere_vcenter_password_expiry_days gauge
+vsphere_vcenter_password_expiry_days{vcenter="vc2"} 30
+`
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_vcenter_password_expiry_days"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetPasswordExpiryProblem(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		expiresAt     time.Time
+		expectedDays  int
+		expectedError string
+	}{
+		{
+			name:         "expiry outside of the window",
+			expiresAt:    now.Add(30 * 24 * time.Hour),
+			expectedDays: 30,
+		},
+		{
+			name:          "expiry within the window",
+			expiresAt:     now.Add(10*24*time.Hour + time.Hour),
+			expectedDays:  10,
+			expectedError: "password of vCenter user detector@localos expires in 10 days, on 2022-06-11T13:00:00Z",
+		},
+		{
+			name:          "expiry today",
+			expiresAt:     now.Add(time.Hour),
+			expectedDays:  0,
+			expectedError: "password of vCenter user detector@localos expires in 0 days, on 2022-06-01T13:00:00Z",
+		},
+		{
+			name:          "expired",
+			expiresAt:     now.Add(-36 * time.Hour),
+			expectedDays:  -2,
+			expectedError: "password of vCenter user detector@localos expired on 2022-05-31T00:00:00Z",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			days, err := getPasswordExpiryProblem("detector@localos", test.expiresAt, now)
+			if days != test.expectedDays {
+				t.Errorf("Expected %d days, got %d", test.expectedDays, days)
+			}
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestSplitUsername(t *testing.T) {
+	tests := []struct {
+		username       string
+		expectedName   string
+		expectedDomain string
+	}{
+		{username: "VSPHERE.LOCAL\\Administrator", expectedName: "Administrator", expectedDomain: "VSPHERE.LOCAL"},
+		{username: "root@localos", expectedName: "root", expectedDomain: "localos"},
+		{username: "user", expectedName: "user", expectedDomain: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.username, func(t *testing.T) {
+			name, domain := splitUsername(test.username)
+			if name != test.expectedName || domain != test.expectedDomain {
+				t.Errorf("Expected %q, %q, got %q, %q", test.expectedName, test.expectedDomain, name, domain)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package check
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/vmware/govmomi/vim25"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/klog/v2"
+)
+
+// NodeVCenter is the vCenter and the datacenter that run VM of a node.
+type NodeVCenter struct {
+	// VCenter is name of the vCenter, as used in VMConfig.VirtualCenter.
+	VCenter string
+	// Datacenter is name of the datacenter with the VM.
+	Datacenter string
+	// Client is connection to the vCenter.
+	Client *vim25.Client
+}
+
+// VMClientForNode returns connection to the vCenter that runs VM of the node.
+// With a single connected vCenter, it returns VMClient and the Workspace datacenter without any vSphere call.
+// With multiple vCenters, datacenters of all connected vCenters are searched for the VM as in FindNodeVM,
+// starting with the Workspace vCenter. The Infrastructure object does not carry failure domains,
+// so node zone labels cannot be mapped to vCenters.
+func (c *CheckContext) VMClientForNode(node *v1.Node) (*NodeVCenter, error) {
+	if len(c.VMClients) <= 1 {
+		return &NodeVCenter{
+			VCenter:    c.VMConfig.Workspace.VCenterIP,
+			Datacenter: c.VMConfig.Workspace.Datacenter,
+			Client:     c.VMClient,
+		}, nil
+	}
+
+	var errs []error
+	for _, vCenter := range c.connectedVCenters() {
+		// Search the vCenter through a copy of the context with the vCenter connection.
+		vcContext := *c
+		vcContext.VMClient = c.VMClients[vCenter]
+		for _, dcName := range getVCenterDatacenters(c, vCenter) {
+			dc, err := getDatacenter(&vcContext, dcName)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("vCenter %s: %s", vCenter, err))
+				continue
+			}
+			if _, err := getNodeVMRef(&vcContext, dc, node); err != nil {
+				klog.V(4).Infof("VM of node %s not found in datacenter %s of vCenter %s: %s", node.Name, dcName, vCenter, err)
+				continue
+			}
+			klog.V(4).Infof("Found VM of node %s in datacenter %s of vCenter %s", node.Name, dcName, vCenter)
+			return &NodeVCenter{
+				VCenter:    vCenter,
+				Datacenter: dcName,
+				Client:     vcContext.VMClient,
+			}, nil
+		}
+	}
+	if node.Spec.ProviderID == "" {
+		errs = append([]error{fmt.Errorf("unable to find VM by name %s in vCenters %s", node.Name, strings.Join(c.connectedVCenters(), ", "))}, errs...)
+		return nil, JoinErrors(errs)
+	}
+	vmUUID := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(node.Spec.ProviderID, "vsphere://")))
+	errs = append([]error{fmt.Errorf("unable to find VM by UUID %s in vCenters %s", vmUUID, strings.Join(c.connectedVCenters(), ", "))}, errs...)
+	return nil, JoinErrors(errs)
+}
+
+// connectedVCenters returns names of all vCenters in VMClients. The Workspace vCenter is the first one,
+// the others are sorted by name.
+func (c *CheckContext) connectedVCenters() []string {
+	var vCenters []string
+	for vCenter := range c.VMClients {
+		if vCenter != c.VMConfig.Workspace.VCenterIP {
+			vCenters = append(vCenters, vCenter)
+		}
+	}
+	sort.Strings(vCenters)
+	if _, found := c.VMClients[c.VMConfig.Workspace.VCenterIP]; found {
+		vCenters = append([]string{c.VMConfig.Workspace.VCenterIP}, vCenters...)
+	}
+	return vCenters
+}
+
+// getVCenterDatacenters returns names of datacenters of the vCenter in VMConfig. The Workspace datacenter
+// is the first one for the Workspace vCenter.
+func getVCenterDatacenters(ctx *CheckContext, vCenter string) []string {
+	var datacenters []string
+	found := make(map[string]bool)
+	if vCenter == ctx.VMConfig.Workspace.VCenterIP && ctx.VMConfig.Workspace.Datacenter != "" {
+		datacenters = append(datacenters, ctx.VMConfig.Workspace.Datacenter)
+		found[ctx.VMConfig.Workspace.Datacenter] = true
+	}
+	if vcConfig, ok := ctx.VMConfig.VirtualCenter[vCenter]; ok && vcConfig != nil {
+		for _, dc := range strings.Split(vcConfig.Datacenters, ",") {
+			dc = strings.TrimSpace(dc)
+			if dc != "" && !found[dc] {
+				datacenters = append(datacenters, dc)
+				found[dc] = true
+			}
+		}
+	}
+	return datacenters
+}
+
+// resetVCenterMetric deletes all series of the vCenter from a metric with the vcenter label. Cluster checks
+// that run against each connected vCenter use it instead of Reset(), which would also drop the series
+// reported by the same check in the other vCenters.
+func resetVCenterMetric(metric *metrics.GaugeVec, vCenter string) {
+	if !metric.IsCreated() {
+		return
+	}
+	ch := make(chan prometheus.Metric)
+	go func() {
+		metric.GaugeVec.Collect(ch)
+		close(ch)
+	}()
+	var series []map[string]string
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			continue
+		}
+		labels := make(map[string]string)
+		for _, pair := range pb.Label {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+		if labels[vCenterLabel] == vCenter {
+			series = append(series, labels)
+		}
+	}
+	for _, labels := range series {
+		metric.Delete(labels)
+	}
+}
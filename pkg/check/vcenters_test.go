@@ -0,0 +1,251 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/legacy-cloud-providers/vsphere"
+)
+
+func TestVMClientForNode(t *testing.T) {
+	tests := []struct {
+		name string
+		// Workspace datacenter, the default DC0 is used when empty
+		workspaceDatacenter string
+		// Datacenters of additional vCenter vc2, no vc2 is connected when empty
+		vc2Datacenters     string
+		node               *v1.Node
+		expectedVCenter    string
+		expectedDatacenter string
+		expectedError      string
+	}{
+		{
+			name:               "single vCenter",
+			node:               node("DC0_H0_VM0", withProviderID("vsphere://265104de-1472-547c-b873-6dc7883fb6cb")),
+			expectedVCenter:    "dc0",
+			expectedDatacenter: "DC0",
+		},
+		{
+			name:               "single vCenter does not search for the VM",
+			node:               node("unknown", withProviderID("vsphere://00000000-0000-0000-0000-000000000000")),
+			expectedVCenter:    "dc0",
+			expectedDatacenter: "DC0",
+		},
+		{
+			name:               "VM in the Workspace vCenter",
+			vc2Datacenters:     "DC0",
+			node:               node("DC0_H0_VM0", withProviderID("vsphere://265104de-1472-547c-b873-6dc7883fb6cb")),
+			expectedVCenter:    "dc0",
+			expectedDatacenter: "DC0",
+		},
+		{
+			name:                "VM in the other vCenter",
+			workspaceDatacenter: "DC9",
+			vc2Datacenters:      "DC8, DC0",
+			node:                node("DC0_H0_VM0", withProviderID("vsphere://265104de-1472-547c-b873-6dc7883fb6cb")),
+			expectedVCenter:     "vc2",
+			expectedDatacenter:  "DC0",
+		},
+		{
+			name:           "VM not found",
+			vc2Datacenters: "DC0",
+			node:           node("unknown", withProviderID("vsphere://00000000-0000-0000-0000-000000000000")),
+			expectedError:  "unable to find VM by UUID 00000000-0000-0000-0000-000000000000 in vCenters dc0, vc2",
+		},
+		{
+			name:                "VM not found with missing datacenter",
+			workspaceDatacenter: "DC9",
+			vc2Datacenters:      "DC0",
+			node:                node("unknown", withProviderID("vsphere://00000000-0000-0000-0000-000000000000")),
+			expectedError: "unable to find VM by UUID 00000000-0000-0000-0000-000000000000 in vCenters dc0, vc2;\n" +
+				"vCenter dc0: failed to access datacenter DC9: datacenter 'DC9' not found",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			if test.workspaceDatacenter != "" {
+				ctx.VMConfig.Workspace.Datacenter = test.workspaceDatacenter
+				ctx.VMConfig.VirtualCenter["dc0"].Datacenters = test.workspaceDatacenter
+			}
+			// The second vCenter is the same simulator with a different name.
+			ctx.VMClients = map[string]*vim25.Client{"dc0": ctx.VMClient}
+			if test.vc2Datacenters != "" {
+				ctx.VMConfig.VirtualCenter["vc2"] = &vsphere.VirtualCenterConfig{Datacenters: test.vc2Datacenters}
+				ctx.VMClients["vc2"] = ctx.VMClient
+			}
+
+			// Act
+			nodeVCenter, err := ctx.VMClientForNode(test.node)
+
+			// Assert
+			if test.expectedError != "" {
+				if err == nil {
+					t.Fatalf("Expected error %q, got none", test.expectedError)
+				}
+				if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if nodeVCenter.VCenter != test.expectedVCenter {
+				t.Errorf("Expected vCenter %s, got %s", test.expectedVCenter, nodeVCenter.VCenter)
+			}
+			if nodeVCenter.Datacenter != test.expectedDatacenter {
+				t.Errorf("Expected datacenter %s, got %s", test.expectedDatacenter, nodeVCenter.Datacenter)
+			}
+			if nodeVCenter.Client != ctx.VMClients[test.expectedVCenter] {
+				t.Errorf("Expected client of vCenter %s", test.expectedVCenter)
+			}
+		})
+	}
+}
+
+func TestGetNodeVMRefs(t *testing.T) {
+	tests := []struct {
+		name string
+		// Datacenters of the Workspace vCenter
+		datacenters string
+		// No Workspace datacenter, as in contexts of cluster checks of other vCenters
+		noWorkspaceDatacenter bool
+		expectedNodes         []string
+		expectedError         string
+	}{
+		{
+			name:          "all datacenters",
+			datacenters:   "DC0, DC1",
+			expectedNodes: []string{"DC0_H0_VM0", "DC1_H0_VM0"},
+		},
+		{
+			name:          "missing datacenter is skipped",
+			datacenters:   "DC0, DC9, DC1",
+			expectedNodes: []string{"DC0_H0_VM0", "DC1_H0_VM0"},
+		},
+		{
+			name:                  "all datacenters without Workspace datacenter",
+			datacenters:           "DC9, DC1, DC0",
+			noWorkspaceDatacenter: true,
+			expectedNodes:         []string{"DC0_H0_VM0", "DC1_H0_VM0"},
+		},
+		{
+			name:                  "no datacenter without Workspace datacenter",
+			datacenters:           "DC9",
+			noWorkspaceDatacenter: true,
+			expectedError:         "failed to access datacenter DC9: datacenter 'DC9' not found",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: []*v1.Node{
+					node("DC0_H0_VM0", withProviderID("vsphere://265104de-1472-547c-b873-6dc7883fb6cb")),
+					node("DC1_H0_VM0", withProviderID("vsphere://7930a567-e3b5-5e70-8d60-d32f5c963f60")),
+					node("unknown", withProviderID("vsphere://00000000-0000-0000-0000-000000000000")),
+				},
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMConfig.VirtualCenter["dc0"].Datacenters = test.datacenters
+			if test.noWorkspaceDatacenter {
+				ctx.VMConfig.Workspace.Datacenter = ""
+			}
+
+			// Act
+			refs, err := getNodeVMRefs(ctx)
+
+			// Assert
+			if test.expectedError != "" {
+				if err == nil || err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %v", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			var nodes []string
+			for _, ref := range refs {
+				nodes = append(nodes, ref.node.Name)
+			}
+			if strings.Join(nodes, ",") != strings.Join(test.expectedNodes, ",") {
+				t.Errorf("Expected nodes %v, got %v", test.expectedNodes, nodes)
+			}
+		})
+	}
+}
+
+func TestGetNodeVMRefsMissingWorkspaceDatacenter(t *testing.T) {
+	// Stage
+	kubeClient := &fakeKubeClient{
+		nodes: defaultNodes(),
+	}
+	ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+	ctx.VMConfig.Workspace.Datacenter = "DC9"
+
+	// Act
+	_, err = getNodeVMRefs(ctx)
+
+	// Assert
+	expectedError := "failed to access datacenter DC9: datacenter 'DC9' not found"
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("Expected error %q, got %v", expectedError, err)
+	}
+}
+
+func TestResetVCenterMetric(t *testing.T) {
+	metric := metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_test_reset_vcenter",
+			Help:           "Test metric.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel, clusterLabel},
+	)
+	// Not registered yet.
+	resetVCenterMetric(metric, "vc1")
+
+	registry := metrics.NewKubeRegistry()
+	registry.MustRegister(metric)
+	metric.WithLabelValues("vc1", "C1").Set(1)
+	metric.WithLabelValues("vc1", "C2").Set(2)
+	metric.WithLabelValues("vc2", "C1").Set(3)
+
+	resetVCenterMetric(metric, "vc1")
+	metric.WithLabelValues("vc1", "C3").Set(4)
+
+	expectedMetrics := `
+# HELP vsphere_test_reset_vcenter [ALPHA] Test metric.
+# TYPE vsphere_test_reset_vcenter gauge
+vsphere_test_reset_vcenter{cluster="C1",vcenter="vc2"} 3
+vsphere_test_reset_vcenter{cluster="C3",vcenter="vc1"} 4
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expectedMetrics), "vsphere_test_reset_vcenter"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+}
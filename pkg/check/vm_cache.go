@@ -0,0 +1,134 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Max. number of VMs retrieved by a single PropertyCollector call.
+	vmPropertiesPageSize = 100
+)
+
+// VMCache holds VMs with NodeProperties of a single vCenter, so node checks of a round do not
+// retrieve VMs one by one. It holds also the node VMs resolved for cluster checks, so each cluster
+// check of a round does not search for the VM of each node again.
+type VMCache struct {
+	lock sync.Mutex
+	vms  map[vim.ManagedObjectReference]*mo.VirtualMachine
+
+	// nodeVMsLock serializes resolution of nodeVMRefs, concurrent cluster checks wait for the first one.
+	nodeVMsLock sync.Mutex
+	nodeVMRefs  []nodeVMRef
+	// nodeVMsResolved is true when nodeVMRefs were resolved in this round.
+	nodeVMsResolved bool
+}
+
+// NewVMCache returns an empty VMCache. A new cache should be used for each round of checks.
+func NewVMCache() *VMCache {
+	return &VMCache{
+		vms: make(map[vim.ManagedObjectReference]*mo.VirtualMachine),
+	}
+}
+
+func (c *VMCache) get(ref vim.ManagedObjectReference) (*mo.VirtualMachine, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	vm, found := c.vms[ref]
+	return vm, found
+}
+
+func (c *VMCache) add(vms []mo.VirtualMachine) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i := range vms {
+		c.vms[vms[i].Reference()] = &vms[i]
+	}
+}
+
+// PrefetchVMs retrieves NodeProperties of the VMs that are not cached yet in pages of vmPropertiesPageSize VMs and stores them
+// in VMCache. A page that fails, for example because one of its VMs was deleted, is not cached and
+// its VMs are retrieved one by one by GetVM, which reports the error of the right VM.
+func (c *CheckContext) PrefetchVMs(refs []vim.ManagedObjectReference) {
+	if c.VMCache == nil {
+		return
+	}
+	var missing []vim.ManagedObjectReference
+	for _, ref := range refs {
+		if _, found := c.VMCache.get(ref); !found {
+			missing = append(missing, ref)
+		}
+	}
+	refs = missing
+
+	pc := property.DefaultCollector(c.VMClient)
+	for start := 0; start < len(refs); start += vmPropertiesPageSize {
+		end := start + vmPropertiesPageSize
+		if end > len(refs) {
+			end = len(refs)
+		}
+		var vms []mo.VirtualMachine
+		tctx, cancel := context.WithTimeout(c.Context, *Timeout)
+		err := pc.Retrieve(tctx, refs[start:end], NodeProperties, &vms)
+		cancel()
+		if err != nil {
+			klog.V(2).Infof("Failed to retrieve properties of %d VMs, they will be retrieved one by one: %s", end-start, err)
+			continue
+		}
+		c.VMCache.add(vms)
+	}
+}
+
+// GetVM returns the VM with NodeProperties, from VMCache when it was prefetched, otherwise from the vCenter.
+func (c *CheckContext) GetVM(ref vim.ManagedObjectReference) (*mo.VirtualMachine, error) {
+	if c.VMCache != nil {
+		if vm, found := c.VMCache.get(ref); found {
+			return vm, nil
+		}
+	}
+
+	pc := property.DefaultCollector(c.VMClient)
+	tctx, cancel := context.WithTimeout(c.Context, *Timeout)
+	defer cancel()
+	var vm mo.VirtualMachine
+	if err := pc.RetrieveOne(tctx, ref, NodeProperties, &vm); err != nil {
+		return nil, fmt.Errorf("failed to get properties of VM %s: %s", ref.Value, err)
+	}
+	if c.VMCache != nil {
+		c.VMCache.add([]mo.VirtualMachine{vm})
+	}
+	return &vm, nil
+}
+
+// getNodeVMRefs returns node VMs of the vCenter of ctx, see resolveNodeVMRefs. They are resolved and their
+// NodeProperties prefetched only once per round, by the first cluster check that needs them.
+// Node VMs resolved with an expired Context are not cached, nodes may have been skipped because of it.
+func (c *VMCache) getNodeVMRefs(ctx *CheckContext) ([]nodeVMRef, error) {
+	c.nodeVMsLock.Lock()
+	defer c.nodeVMsLock.Unlock()
+	if c.nodeVMsResolved {
+		return c.nodeVMRefs, nil
+	}
+
+	refs, err := resolveNodeVMRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Context.Err() != nil {
+		return refs, nil
+	}
+	vmRefs := make([]vim.ManagedObjectReference, 0, len(refs))
+	for _, ref := range refs {
+		vmRefs = append(vmRefs, ref.vmRef)
+	}
+	ctx.PrefetchVMs(vmRefs)
+	c.nodeVMRefs = refs
+	c.nodeVMsResolved = true
+	return refs, nil
+}
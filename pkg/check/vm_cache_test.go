@@ -0,0 +1,137 @@
+package check
+
+import (
+	"testing"
+
+	vim "github.com/vmware/govmomi/vim25/types"
+)
+
+func TestVMCache(t *testing.T) {
+	tests := []struct {
+		name string
+		// Add a VM that does not exist to the prefetched VMs
+		withMissingVM bool
+		// Number of VMs expected in the cache after prefetch
+		expectedCached int
+	}{
+		{
+			name:           "all VMs prefetched",
+			expectedCached: 2,
+		},
+		{
+			name:           "missing VM",
+			withMissingVM:  true,
+			expectedCached: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMCache = NewVMCache()
+
+			var refs []vim.ManagedObjectReference
+			for _, node := range defaultNodes() {
+				vm, err := getVM(ctx, node)
+				if err != nil {
+					t.Fatalf("Failed to get VM of node %s: %s", node.Name, err)
+				}
+				refs = append(refs, vm.Reference())
+			}
+			missingRef := vim.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-missing"}
+			if test.withMissingVM {
+				refs = append(refs, missingRef)
+			}
+
+			// Act
+			ctx.PrefetchVMs(refs)
+
+			// Assert
+			if len(ctx.VMCache.vms) != test.expectedCached {
+				t.Errorf("Expected %d cached VMs, got %d", test.expectedCached, len(ctx.VMCache.vms))
+			}
+			for _, ref := range refs[:2] {
+				vm, err := ctx.GetVM(ref)
+				if err != nil {
+					t.Errorf("Unexpected error getting VM %s: %s", ref.Value, err)
+					continue
+				}
+				if vm.Config == nil || vm.Runtime.Host == nil {
+					t.Errorf("Expected VM %s with NodeProperties", ref.Value)
+				}
+				if cached, _ := ctx.VMCache.get(ref); cached == nil {
+					t.Errorf("Expected VM %s to be cached", ref.Value)
+				}
+			}
+			if test.withMissingVM {
+				if _, err := ctx.GetVM(missingRef); err == nil {
+					t.Errorf("Expected error getting missing VM, got none")
+				}
+			}
+		})
+	}
+}
+
+func TestVMCacheNodeVMs(t *testing.T) {
+	// Stage
+	kubeClient := &fakeKubeClient{
+		nodes: defaultNodes(),
+	}
+	ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+	if err != nil {
+		t.Fatalf("setupSimulator failed: %s", err)
+	}
+	defer cleanup()
+	refs, err := resolveNodeVMRefs(ctx)
+	if err != nil || len(refs) == 0 {
+		t.Fatalf("Failed to resolve node VMs: %v", err)
+	}
+	ctx.VMCache = NewVMCache()
+
+	// Act
+	vms, err := getNodeVMs(ctx, []string{"name", "runtime.host"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(vms) != len(refs) {
+		t.Errorf("Expected %d VMs, got %d", len(refs), len(vms))
+	}
+	if len(ctx.VMCache.vms) != len(refs) {
+		t.Errorf("Expected %d prefetched VMs, got %d", len(refs), len(ctx.VMCache.vms))
+	}
+	for _, vm := range vms {
+		if vm.Name == "" || vm.Runtime.Host == nil || vm.Config == nil {
+			t.Errorf("Expected VM %s with NodeProperties", vm.Reference().Value)
+		}
+	}
+
+	// Other cluster checks of the round do not search for the node VMs again.
+	kubeClient.nodes = nil
+	vms, err = getNodeVMs(ctx, []string{"datastore"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(vms) != len(refs) {
+		t.Errorf("Expected %d VMs resolved in the round, got %d", len(refs), len(vms))
+	}
+
+	// The next round resolves the node VMs again.
+	ctx.VMCache = NewVMCache()
+	vms, err = getNodeVMs(ctx, []string{"datastore"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(vms) != 0 {
+		t.Errorf("Expected no VMs in the next round, got %d", len(vms))
+	}
+}
@@ -0,0 +1,135 @@
+package check
+
+import (
+	"context"
+	"fmt"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// getPVDatastores returns map PV name -> name of the datastore where the PV is stored.
+// In-tree vSphere volumes carry the datastore in their volume path, CSI volumes
+// are looked up in CNS. PVs that cannot be resolved are not present in the map.
+func getPVDatastores(ctx *CheckContext, pvs []*v1.PersistentVolume) (map[string]string, error) {
+	pvDatastores := make(map[string]string)
+	// volume ID -> PV name
+	csiVolumes := make(map[string]string)
+
+	for _, pv := range pvs {
+		switch {
+		case pv.Spec.VsphereVolume != nil:
+			var dsPath object.DatastorePath
+			if !dsPath.FromString(pv.Spec.VsphereVolume.VolumePath) {
+				klog.V(2).Infof("Failed to parse volume path %q of PV %s", pv.Spec.VsphereVolume.VolumePath, pv.Name)
+				continue
+			}
+			pvDatastores[pv.Name] = dsPath.Datastore
+		case pv.Spec.CSI != nil && pv.Spec.CSI.Driver == vSphereCSIDdriver:
+			if rwxVolumeRegx.MatchString(pv.Spec.CSI.VolumeHandle) {
+				// File volumes are not stored on a datastore
+				continue
+			}
+			csiVolumes[pv.Spec.CSI.VolumeHandle] = pv.Name
+		}
+	}
+
+	if len(csiVolumes) == 0 {
+		return pvDatastores, nil
+	}
+
+	volumes, err := queryCNSVolumes(ctx, csiVolumes)
+	if err != nil {
+		return pvDatastores, err
+	}
+	dsNames, err := getDatastoreNamesByURL(ctx)
+	if err != nil {
+		return pvDatastores, err
+	}
+	for _, volume := range volumes {
+		pvName, found := csiVolumes[volume.VolumeId.Id]
+		if !found {
+			continue
+		}
+		dsName, found := dsNames[volume.DatastoreUrl]
+		if !found {
+			klog.V(2).Infof("Failed to find datastore with URL %s of PV %s", volume.DatastoreUrl, pvName)
+			continue
+		}
+		pvDatastores[pvName] = dsName
+	}
+	return pvDatastores, nil
+}
+
+// queryCNSVolumes returns CNS volumes with given IDs.
+func queryCNSVolumes(ctx *CheckContext, volumeIDs map[string]string) ([]cnstypes.CnsVolume, error) {
+	filter := cnstypes.CnsQueryFilter{}
+	for id := range volumeIDs {
+		filter.VolumeIds = append(filter.VolumeIds, cnstypes.CnsVolumeId{Id: id})
+	}
+	return queryCNS(ctx, filter)
+}
+
+// listClusterCNSVolumes returns all CNS volumes created by the container cluster with the given ID.
+func listClusterCNSVolumes(ctx *CheckContext, clusterID string) ([]cnstypes.CnsVolume, error) {
+	filter := cnstypes.CnsQueryFilter{
+		ContainerClusterIds: []string{clusterID},
+	}
+	return queryCNS(ctx, filter)
+}
+
+// queryCNS returns all CNS volumes that match the filter, in as many queries as needed.
+func queryCNS(ctx *CheckContext, filter cnstypes.CnsQueryFilter) ([]cnstypes.CnsVolume, error) {
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	c, err := newCnsClient(tctx, ctx.VMClient)
+	if err != nil {
+		return nil, fmt.Errorf("error creating CNS client: %s", err)
+	}
+
+	var volumes []cnstypes.CnsVolume
+	for {
+		tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+		res, err := c.QueryVolume(tctx, filter)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("error querying CNS volumes: %s", err)
+		}
+		volumes = append(volumes, res.Volumes...)
+		if len(res.Volumes) == 0 || res.Cursor.Offset >= res.Cursor.TotalRecords {
+			break
+		}
+		filter.Cursor = &res.Cursor
+	}
+	return volumes, nil
+}
+
+// getDatastoreNamesByURL returns map datastore URL -> datastore name of all datastores in vSphere.
+func getDatastoreNamesByURL(ctx *CheckContext) (map[string]string, error) {
+	kind := []string{"Datastore"}
+	m := view.NewManager(ctx.VMClient)
+
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	v, err := m.CreateContainerView(tctx, ctx.VMClient.ServiceContent.RootFolder, kind, true)
+	if err != nil {
+		return nil, fmt.Errorf("error creating container view: %s", err)
+	}
+	defer v.Destroy(tctx)
+
+	var content []mo.Datastore
+	err = v.Retrieve(tctx, kind, []string{SummaryProperty}, &content)
+	if err != nil {
+		return nil, fmt.Errorf("error listing datastores: %s", err)
+	}
+
+	dsNames := make(map[string]string)
+	for _, ds := range content {
+		dsNames[ds.Summary.Url] = ds.Summary.Name
+	}
+	return dsNames, nil
+}
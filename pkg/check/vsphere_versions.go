@@ -0,0 +1,127 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/blang/semver"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// MinESXiVersion is the minimum supported API version of ESXi hosts that run node VMs.
+	MinESXiVersion = "6.7.3"
+	// MinVCenterVersion is the minimum supported API version of vCenter.
+	MinVCenterVersion = "6.7.3"
+)
+
+var (
+	unsupportedVersionsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_unsupported_versions_total",
+			Help:           "Number of vCenters and ESXi hosts that run node VMs with a version below the minimum supported one.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(unsupportedVersionsMetric)
+}
+
+// CheckVSphereVersionsSupported tests that vCenter and all ESXi hosts that run node VMs have at least
+// the minimum supported version. Versions of all hosts are stored in ClusterInfo, so they're available
+// to the operator status even when a host is above the floor.
+func CheckVSphereVersionsSupported(ctx *CheckContext) error {
+	about := ctx.VMClient.ServiceContent.About
+	ctx.ClusterInfo.SetVCenterVersion(about.Version, about.ApiVersion)
+	ctx.ClusterInfo.SetVCenterBuild(about.Build)
+
+	var errs []error
+	supported, err := isSupportedVersion(about.ApiVersion, MinVCenterVersion)
+	switch {
+	case err != nil:
+		errs = append(errs, fmt.Errorf("failed to parse vCenter API version %q: %s", about.ApiVersion, err))
+	case !supported:
+		klog.Warningf("vCenter %s runs version %s build %s (API version %s), below the minimum supported %s", ctx.VMConfig.Workspace.VCenterIP, about.Version, about.Build, about.ApiVersion, MinVCenterVersion)
+		errs = append(errs, fmt.Errorf("vCenter %s runs version %s build %s (API version %s), which is below the minimum supported %s", ctx.VMConfig.Workspace.VCenterIP, about.Version, about.Build, about.ApiVersion, MinVCenterVersion))
+	default:
+		klog.V(4).Infof("vCenter %s runs supported version %s build %s (API version %s)", ctx.VMConfig.Workspace.VCenterIP, about.Version, about.Build, about.ApiVersion)
+	}
+
+	hosts, err := getNodeHosts(ctx, []string{"name", "config.product"})
+	if err != nil {
+		return err
+	}
+	for _, host := range hosts {
+		if host.Config == nil {
+			errs = append(errs, fmt.Errorf("error getting version of ESXi host %s: host.config is nil", host.Name))
+			continue
+		}
+		product := host.Config.Product
+		ctx.ClusterInfo.SetHostVersion(host.Self.Value, product.Version, product.ApiVersion)
+		ctx.ClusterInfo.SetHostName(host.Self.Value, host.Name)
+
+		supported, err := isSupportedVersion(product.ApiVersion, MinESXiVersion)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("failed to parse API version %q of ESXi host %s: %s", product.ApiVersion, host.Name, err))
+		case !supported:
+			klog.Warningf("ESXi host %s runs version %s build %s (API version %s), below the minimum supported %s", host.Name, product.Version, product.Build, product.ApiVersion, MinESXiVersion)
+			errs = append(errs, fmt.Errorf("ESXi host %s runs version %s build %s (API version %s), which is below the minimum supported %s", host.Name, product.Version, product.Build, product.ApiVersion, MinESXiVersion))
+		default:
+			klog.V(4).Infof("ESXi host %s runs supported version %s build %s (API version %s)", host.Name, product.Version, product.Build, product.ApiVersion)
+		}
+	}
+	unsupportedVersionsMetric.WithLabelValues().Set(float64(len(errs)))
+
+	klog.V(2).Infof("CheckVSphereVersionsSupported checked vCenter and %d ESXi hosts, %d problems found", len(hosts), len(errs))
+	return JoinErrors(errs)
+}
+
+// isSupportedVersion returns true if the version is the same or newer than the minimum version.
+func isSupportedVersion(version, minimum string) (bool, error) {
+	v, err := semver.ParseTolerant(parseForSemver(version))
+	if err != nil {
+		return false, err
+	}
+	return !isVersionBefore(v, minimum), nil
+}
+
+// getNodeHosts returns all ESXi hosts that run at least one node VM with given properties, sorted by their name.
+func getNodeHosts(ctx *CheckContext, properties []string) ([]mo.HostSystem, error) {
+	vms, err := getNodeVMs(ctx, []string{"runtime.host"})
+	if err != nil {
+		return nil, err
+	}
+	refMap := make(map[vim.ManagedObjectReference]bool)
+	for _, vm := range vms {
+		if vm.Runtime.Host != nil {
+			refMap[*vm.Runtime.Host] = true
+		}
+	}
+	if len(refMap) == 0 {
+		return nil, nil
+	}
+	var refs []vim.ManagedObjectReference
+	for ref := range refMap {
+		refs = append(refs, ref)
+	}
+
+	pc := property.DefaultCollector(ctx.VMClient)
+	tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+	defer cancel()
+	var hosts []mo.HostSystem
+	if err := pc.Retrieve(tctx, refs, properties, &hosts); err != nil {
+		return nil, fmt.Errorf("failed to get ESXi hosts of node VMs: %s", err)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name < hosts[j].Name })
+	return hosts, nil
+}
@@ -0,0 +1,121 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckVSphereVersionsSupported(t *testing.T) {
+	tests := []struct {
+		name              string
+		vCenterAPIVersion string
+		esxiVersion       string
+		esxiAPIVersion    string
+		expectError       string
+		expectedMetrics   string
+	}{
+		{
+			name:              "supported versions",
+			vCenterAPIVersion: "7.0.3.0",
+			esxiVersion:       "7.0.3",
+			esxiAPIVersion:    "7.0.3.0",
+			expectedMetrics: `
+        # HELP vsphere_unsupported_versions_total [ALPHA] Number of vCenters and ESXi hosts that run node VMs with a version below the minimum supported one.
+        # TYPE vsphere_unsupported_versions_total gauge
+        vsphere_unsupported_versions_total 0
+`,
+		},
+		{
+			name:              "minimum versions",
+			vCenterAPIVersion: "6.7.3",
+			esxiVersion:       "6.7.0",
+			esxiAPIVersion:    "6.7.3",
+			expectedMetrics: `
+        # HELP vsphere_unsupported_versions_total [ALPHA] Number of vCenters and ESXi hosts that run node VMs with a version below the minimum supported one.
+        # TYPE vsphere_unsupported_versions_total gauge
+        vsphere_unsupported_versions_total 0
+`,
+		},
+		{
+			name:              "old ESXi host",
+			vCenterAPIVersion: "7.0.3.0",
+			esxiVersion:       "6.7.0",
+			esxiAPIVersion:    "6.7.2",
+			expectError:       "ESXi host DC0_H0 runs version 6.7.0 build 5969303 (API version 6.7.2), which is below the minimum supported 6.7.3",
+			expectedMetrics: `
+        # HELP vsphere_unsupported_versions_total [ALPHA] Number of vCenters and ESXi hosts that run node VMs with a version below the minimum supported one.
+        # TYPE vsphere_unsupported_versions_total gauge
+        vsphere_unsupported_versions_total 1
+`,
+		},
+		{
+			name:              "old vCenter and ESXi host",
+			vCenterAPIVersion: "6.5",
+			esxiVersion:       "6.5.0",
+			esxiAPIVersion:    "6.5",
+			expectError: "vCenter dc0 runs version 6.5.0 build 5973321 (API version 6.5), which is below the minimum supported 6.7.3;\n" +
+				"ESXi host DC0_H0 runs version 6.5.0 build 5969303 (API version 6.5), which is below the minimum supported 6.7.3",
+			expectedMetrics: `
+        # HELP vsphere_unsupported_versions_total [ALPHA] Number of vCenters and ESXi hosts that run node VMs with a version below the minimum supported one.
+        # TYPE vsphere_unsupported_versions_total gauge
+        vsphere_unsupported_versions_total 2
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			kubeClient := &fakeKubeClient{
+				nodes: defaultNodes(),
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+
+			ctx.VMClient.ServiceContent.About.ApiVersion = test.vCenterAPIVersion
+			if err := customizeHostVersion(defaultHostId, test.esxiVersion, test.esxiAPIVersion); err != nil {
+				t.Fatalf("Failed to customize host: %s", err)
+			}
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckVSphereVersionsSupported(ctx)
+
+			// Assert
+			if test.expectError == "" && err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if test.expectError != "" {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectError)
+				} else if err.Error() != test.expectError {
+					t.Errorf("Expected error %q, got %q", test.expectError, err.Error())
+				}
+			}
+
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(test.expectedMetrics), "vsphere_unsupported_versions_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+
+			versions := ctx.ClusterInfo.GetHostVersions()
+			if got := versions[defaultHostId]; got.Version != test.esxiVersion || got.APIVersion != test.esxiAPIVersion {
+				t.Errorf("Expected ClusterInfo host version %s/%s, got %+v", test.esxiVersion, test.esxiAPIVersion, got)
+			}
+			if name := ctx.ClusterInfo.GetHostNames()[defaultHostId]; name != "DC0_H0" {
+				t.Errorf("Expected ClusterInfo host name DC0_H0, got %q", name)
+			}
+			if version, apiVersion := ctx.ClusterInfo.GetVCenterVersion(); version != "6.5.0" || apiVersion != test.vCenterAPIVersion {
+				t.Errorf("Expected ClusterInfo vCenter version 6.5.0/%s, got %s/%s", test.vCenterAPIVersion, version, apiVersion)
+			}
+			if build := ctx.ClusterInfo.GetVCenterBuild(); build != "5973321" {
+				t.Errorf("Expected ClusterInfo vCenter build 5973321, got %q", build)
+			}
+		})
+	}
+}
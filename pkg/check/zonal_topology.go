@@ -0,0 +1,209 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	zonalTopologyProblemsMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_zonal_topology_problems_total",
+			Help:           "Number of problems with region and zone tags of compute clusters and node VMs found by CheckZonalTopologyTags.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{vCenterLabel},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(zonalTopologyProblemsMetric)
+}
+
+// topologyCategory is a tag category used for zonal topology and its tags, tag ID -> tag name.
+type topologyCategory struct {
+	// kind is "region" or "zone"
+	kind string
+	name string
+	tags map[string]string
+}
+
+// CheckZonalTopologyTags tests tags used for zonal topology in clusters with vSphere zones: the region and zone
+// tag categories from vSphere configuration must exist and have tags, every compute cluster that runs node VMs
+// must be in exactly one region and one zone, either by its own tags or tags of its datacenter, and every node VM
+// must resolve to exactly one region and zone through its ESXi host, compute cluster or datacenter.
+// The zone of a node VM must match the zone label of the node.
+// The Infrastructure object does not carry failure domains, so compute clusters of node VMs are checked as
+// failure domains. The check is skipped when no tag categories are configured.
+func CheckZonalTopologyTags(ctx *CheckContext) error {
+	region, zone := ctx.VMConfig.Labels.Region, ctx.VMConfig.Labels.Zone
+	if region == "" && zone == "" {
+		klog.V(4).Infof("CheckZonalTopologyTags: region and zone tag categories are not configured, skipping")
+		zonalTopologyProblemsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(0)
+		return nil
+	}
+	if region == "" || zone == "" {
+		zonalTopologyProblemsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(1)
+		return fmt.Errorf("vSphere configuration must have both region and zone tag categories, got region %q and zone %q", region, zone)
+	}
+	if ctx.TagManager == nil {
+		return fmt.Errorf("cannot check tags in categories %s and %s: vSphere tags are not available", region, zone)
+	}
+
+	var errs []error
+	var categories []topologyCategory
+	for _, category := range []topologyCategory{{kind: "region", name: region}, {kind: "zone", name: zone}} {
+		tags, err := getCategoryTags(ctx, category.name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(tags) == 0 {
+			errs = append(errs, fmt.Errorf("%s tag category %s has no tags", category.kind, category.name))
+			continue
+		}
+		category.tags = tags
+		categories = append(categories, category)
+	}
+	if len(errs) > 0 {
+		zonalTopologyProblemsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(len(errs)))
+		return JoinErrors(errs)
+	}
+
+	clusterErrs, err := checkComputeClusterTopology(ctx, categories)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, clusterErrs...)
+
+	nodeErrs, err := checkNodeTopology(ctx, categories)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, nodeErrs...)
+
+	zonalTopologyProblemsMetric.WithLabelValues(ctx.VMConfig.Workspace.VCenterIP).Set(float64(len(errs)))
+	klog.V(2).Infof("CheckZonalTopologyTags: %d problems found", len(errs))
+	return JoinErrors(errs)
+}
+
+// checkComputeClusterTopology returns errors of compute clusters of node VMs that are not in exactly one
+// region and zone. A compute cluster without a tag in a category inherits the tag of its datacenter.
+func checkComputeClusterTopology(ctx *CheckContext, categories []topologyCategory) ([]error, error) {
+	clusterRefs, err := getNodeComputeClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, clusterRef := range clusterRefs {
+		var cluster mo.ClusterComputeResource
+		pc := property.DefaultCollector(ctx.VMClient)
+		tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+		err := pc.RetrieveOne(tctx, clusterRef, []string{"name"}, &cluster)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get compute cluster %s: %s", clusterRef.Value, err)
+		}
+		dcRef, err := getParentDatacenter(ctx, clusterRef)
+		if err != nil {
+			return nil, err
+		}
+		for _, category := range categories {
+			objectTags, err := getAttachedZones(ctx, []mo.Reference{clusterRef, dcRef}, category.tags)
+			if err != nil {
+				return nil, err
+			}
+			tags := objectTags[clusterRef]
+			if len(tags) == 0 {
+				tags = objectTags[dcRef]
+			}
+			if err := checkTopologyTags(fmt.Sprintf("compute cluster %s", cluster.Name), category, tags); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs, nil
+}
+
+// checkNodeTopology returns errors of node VMs that do not resolve to exactly one region and zone
+// through their ESXi hosts, or whose zone does not match the zone label of the node.
+func checkNodeTopology(ctx *CheckContext, categories []topologyCategory) ([]error, error) {
+	refs, err := getNodeVMRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pc := property.DefaultCollector(ctx.VMClient)
+	// node name -> ESXi host of its VM
+	nodeHosts := make(map[string]vim.ManagedObjectReference)
+	hosts := make(map[vim.ManagedObjectReference]bool)
+	var nodeNames []string
+	nodeZoneLabels := make(map[string]string)
+	for _, ref := range refs {
+		var vm mo.VirtualMachine
+		tctx, cancel := context.WithTimeout(ctx.Context, *Timeout)
+		err := pc.RetrieveOne(tctx, ref.vmRef, []string{"runtime.host"}, &vm)
+		cancel()
+		if err != nil {
+			klog.V(2).Infof("Skipping node %s: failed to get VM properties: %s", ref.node.Name, err)
+			continue
+		}
+		if vm.Runtime.Host == nil {
+			continue
+		}
+		nodeHosts[ref.node.Name] = *vm.Runtime.Host
+		hosts[*vm.Runtime.Host] = true
+		nodeNames = append(nodeNames, ref.node.Name)
+		nodeZoneLabels[ref.node.Name] = getNodeZone(ref.node)
+	}
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+	sort.Strings(nodeNames)
+
+	// category kind -> ESXi host -> its tags in the category
+	hostTags := make(map[string]map[vim.ManagedObjectReference]hostZone)
+	for _, category := range categories {
+		tags, err := getHostZones(ctx, hosts, category.tags)
+		if err != nil {
+			return nil, err
+		}
+		hostTags[category.kind] = tags
+	}
+
+	var errs []error
+	for _, nodeName := range nodeNames {
+		for _, category := range categories {
+			host := hostTags[category.kind][nodeHosts[nodeName]]
+			if err := checkTopologyTags(fmt.Sprintf("node %s on ESXi host %s", nodeName, host.name), category, host.zones); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if category.kind == "zone" && nodeZoneLabels[nodeName] != "" && nodeZoneLabels[nodeName] != host.zones[0] {
+				errs = append(errs, fmt.Errorf("node %s has zone label %s, but its VM runs in zone %s", nodeName, nodeZoneLabels[nodeName], host.zones[0]))
+			}
+		}
+	}
+	return errs, nil
+}
+
+// checkTopologyTags returns an error when the object does not have exactly one tag in the category.
+func checkTopologyTags(object string, category topologyCategory, tags []string) error {
+	switch len(tags) {
+	case 0:
+		return fmt.Errorf("%s has no tag in %s category %s", object, category.kind, category.name)
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("%s has multiple tags in %s category %s: %s", object, category.kind, category.name, strings.Join(tags, ", "))
+	}
+}
@@ -0,0 +1,152 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestCheckZonalTopologyTags(t *testing.T) {
+	tests := []struct {
+		name           string
+		regionCategory string
+		zoneCategory   string
+		clusterRegions []string
+		clusterZones   []string
+		// Region tags of the datacenter, inherited by the compute cluster
+		datacenterRegions []string
+		// Zone label of node DC0_C0_APP0_VM0
+		nodeZoneLabel string
+		expectedError string
+		expectedCount int
+	}{
+		{
+			name: "zonal topology not configured",
+		},
+		{
+			name:              "cluster and datacenter tags",
+			regionCategory:    "k8s-region",
+			zoneCategory:      "k8s-zone",
+			clusterZones:      []string{"zone-a"},
+			datacenterRegions: []string{"region-a"},
+			nodeZoneLabel:     "zone-a",
+		},
+		{
+			name:          "only zone category",
+			zoneCategory:  "k8s-zone",
+			expectedError: `vSphere configuration must have both region and zone tag categories, got region "" and zone "k8s-zone"`,
+			expectedCount: 1,
+		},
+		{
+			name:           "category without tags",
+			regionCategory: "empty-region",
+			zoneCategory:   "k8s-zone",
+			expectedError:  "region tag category empty-region has no tags",
+			expectedCount:  1,
+		},
+		{
+			name:              "cluster with multiple zones",
+			regionCategory:    "k8s-region",
+			zoneCategory:      "k8s-zone",
+			clusterZones:      []string{"zone-b", "zone-a"},
+			datacenterRegions: []string{"region-a"},
+			expectedError: "5 errors found, listing first 5:\n" +
+				"compute cluster DC0_C0 has multiple tags in zone category k8s-zone: zone-a, zone-b;\n" +
+				"node DC0_C0_APP0_VM0 on ESXi host DC0_C0_H0 has multiple tags in zone category k8s-zone: zone-a, zone-b;\n" +
+				"node DC0_C0_APP0_VM1 on ESXi host DC0_C0_H2 has multiple tags in zone category k8s-zone: zone-a, zone-b;\n" +
+				"node DC0_C0_RP0_VM0 on ESXi host DC0_C0_H1 has multiple tags in zone category k8s-zone: zone-a, zone-b",
+			expectedCount: 5,
+		},
+		{
+			name:           "missing region",
+			regionCategory: "k8s-region",
+			zoneCategory:   "k8s-zone",
+			clusterZones:   []string{"zone-a"},
+			expectedError: "5 errors found, listing first 5:\n" +
+				"compute cluster DC0_C0 has no tag in region category k8s-region;\n" +
+				"node DC0_C0_APP0_VM0 on ESXi host DC0_C0_H0 has no tag in region category k8s-region;\n" +
+				"node DC0_C0_APP0_VM1 on ESXi host DC0_C0_H2 has no tag in region category k8s-region;\n" +
+				"node DC0_C0_RP0_VM0 on ESXi host DC0_C0_H1 has no tag in region category k8s-region",
+			expectedCount: 5,
+		},
+		{
+			name:              "node zone label mismatch",
+			regionCategory:    "k8s-region",
+			zoneCategory:      "k8s-zone",
+			clusterRegions:    []string{"region-a"},
+			clusterZones:      []string{"zone-a"},
+			datacenterRegions: []string{"region-b"},
+			nodeZoneLabel:     "zone-b",
+			expectedError:     "node DC0_C0_APP0_VM0 has zone label zone-b, but its VM runs in zone zone-a",
+			expectedCount:     1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			nodes := clusterNodes()
+			if test.nodeZoneLabel != "" {
+				for _, node := range nodes {
+					if node.Name == "DC0_C0_APP0_VM0" {
+						node.Labels = map[string]string{v1.LabelTopologyZone: test.nodeZoneLabel}
+					}
+				}
+			}
+			kubeClient := &fakeKubeClient{
+				nodes: nodes,
+			}
+			ctx, cleanup, err := setupSimulator(kubeClient, defaultModel)
+			if err != nil {
+				t.Fatalf("setupSimulator failed: %s", err)
+			}
+			defer cleanup()
+			ctx.VMConfig.Labels.Region = test.regionCategory
+			ctx.VMConfig.Labels.Zone = test.zoneCategory
+
+			clusterRef := types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "clustercomputeresource-30"}
+			dcRef, err := getParentDatacenter(ctx, clusterRef)
+			if err != nil {
+				t.Fatalf("Failed to get datacenter: %s", err)
+			}
+			regionTagIDs := createZoneTags(t, ctx, "k8s-region", "region-a", "region-b")
+			zoneTagIDs := createZoneTags(t, ctx, "k8s-zone", "zone-a", "zone-b")
+			createZoneTags(t, ctx, "empty-region")
+			attachZoneTags(t, ctx, regionTagIDs, clusterRef, test.clusterRegions)
+			attachZoneTags(t, ctx, regionTagIDs, dcRef, test.datacenterRegions)
+			attachZoneTags(t, ctx, zoneTagIDs, clusterRef, test.clusterZones)
+
+			// Reset metrics from previous tests. Note: the tests can't run in parallel!
+			legacyregistry.Reset()
+
+			// Act
+			err = CheckZonalTopologyTags(ctx)
+
+			// Assert
+			if test.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			} else {
+				if err == nil {
+					t.Errorf("Expected error %q, got none", test.expectedError)
+				} else if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err.Error())
+				}
+			}
+			expectedMetrics := fmt.Sprintf(`
+# HELP vsphere_zonal_topology_problems_total [ALPHA] Number of problems with region and zone tags of compute clusters and node VMs found by CheckZonalTopologyTags.
+# TYPE vsphere_zonal_topology_problems_total gauge
+vsphere_zonal_topology_problems_total{vcenter="dc0"} %d
+`, test.expectedCount)
+			if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_zonal_topology_problems_total"); err != nil {
+				t.Errorf("Unexpected metric: %s", err)
+			}
+		})
+	}
+}
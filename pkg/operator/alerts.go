@@ -0,0 +1,100 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	alertRuleName  = "vsphere-problem-detector-checks"
+	alertGroupName = "vsphere-problem-detector"
+	// alertFor is how long a check must fail before its alert fires. Checks run once per hour by default,
+	// a single failed round is enough.
+	alertFor      = "10m"
+	alertSeverity = "warning"
+)
+
+// prometheusRule is monitoring.coreos.com/v1 PrometheusRule, with only the fields used by the generated alerts.
+type prometheusRule struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Metadata   prometheusMetadata `json:"metadata"`
+	Spec       prometheusRuleSpec `json:"spec"`
+}
+
+type prometheusMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type prometheusRuleSpec struct {
+	Groups []prometheusRuleGroup `json:"groups"`
+}
+
+type prometheusRuleGroup struct {
+	Name  string            `json:"name"`
+	Rules []prometheusAlert `json:"rules"`
+}
+
+type prometheusAlert struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// newPrometheusRule returns PrometheusRule with an alert for each of the given checks, sorted by check name.
+// The alert fires when vsphere_problem_detector_check_status reports that the check failed.
+func newPrometheusRule(checkNames []string) *prometheusRule {
+	names := append([]string(nil), checkNames...)
+	sort.Strings(names)
+	group := prometheusRuleGroup{Name: alertGroupName}
+	for _, name := range names {
+		group.Rules = append(group.Rules, prometheusAlert{
+			Alert: alertName(name),
+			Expr:  fmt.Sprintf(`vsphere_problem_detector_check_status{%s=%q,%s=%q} == 1`, checkNameLabel, name, statusLabel, checkStatusFailed),
+			For:   alertFor,
+			Labels: map[string]string{
+				"severity":     alertSeverity,
+				checkNameLabel: name,
+			},
+			Annotations: map[string]string{
+				"summary": fmt.Sprintf("vSphere check %s is failing.", name),
+				"description": fmt.Sprintf("vSphere problem detector check %s failed in the last round of checks. "+
+					"Events with reason %s on Nodes and in namespace %s show the errors.", name, checkFailedEventReason, operatorNamespace),
+			},
+		})
+	}
+	return &prometheusRule{
+		APIVersion: "monitoring.coreos.com/v1",
+		Kind:       "PrometheusRule",
+		Metadata: prometheusMetadata{
+			Name:      alertRuleName,
+			Namespace: operatorNamespace,
+		},
+		Spec: prometheusRuleSpec{
+			Groups: []prometheusRuleGroup{group},
+		},
+	}
+}
+
+// alertName returns name of the alert of the check, e.g. VSphereCheckNodeDiskUUIDFailing.
+func alertName(checkName string) string {
+	return "VSphere" + checkName + "Failing"
+}
+
+// GenerateAlertRules returns YAML of PrometheusRule with alerts for all default checks.
+func GenerateAlertRules() ([]byte, error) {
+	var names []string
+	for name := range check.DefaultClusterChecks {
+		names = append(names, name)
+	}
+	for _, nodeCheck := range check.DefaultNodeChecks {
+		names = append(names, nodeCheck.Name())
+	}
+	return yaml.Marshal(newPrometheusRule(names))
+}
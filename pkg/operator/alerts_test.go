@@ -0,0 +1,73 @@
+package operator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	"sigs.k8s.io/yaml"
+)
+
+func TestNewPrometheusRule(t *testing.T) {
+	rule := newPrometheusRule([]string{"CheckNodeDiskUUID", "CheckFolderPermissions"})
+
+	if rule.Kind != "PrometheusRule" || rule.Metadata.Namespace != operatorNamespace {
+		t.Errorf("Expected PrometheusRule in namespace %s, got %s in %s", operatorNamespace, rule.Kind, rule.Metadata.Namespace)
+	}
+	if len(rule.Spec.Groups) != 1 {
+		t.Fatalf("Expected 1 rule group, got %d", len(rule.Spec.Groups))
+	}
+	alerts := rule.Spec.Groups[0].Rules
+	expectedAlerts := []prometheusAlert{
+		{
+			Alert:  "VSphereCheckFolderPermissionsFailing",
+			Expr:   `vsphere_problem_detector_check_status{check="CheckFolderPermissions",status="failed"} == 1`,
+			For:    "10m",
+			Labels: map[string]string{"severity": "warning", "check": "CheckFolderPermissions"},
+		},
+		{
+			Alert:  "VSphereCheckNodeDiskUUIDFailing",
+			Expr:   `vsphere_problem_detector_check_status{check="CheckNodeDiskUUID",status="failed"} == 1`,
+			For:    "10m",
+			Labels: map[string]string{"severity": "warning", "check": "CheckNodeDiskUUID"},
+		},
+	}
+	if len(alerts) != len(expectedAlerts) {
+		t.Fatalf("Expected %d alerts, got %d", len(expectedAlerts), len(alerts))
+	}
+	for i, expected := range expectedAlerts {
+		alert := alerts[i]
+		if alert.Alert != expected.Alert || alert.Expr != expected.Expr || alert.For != expected.For || !reflect.DeepEqual(alert.Labels, expected.Labels) {
+			t.Errorf("Expected alert %+v, got %+v", expected, alert)
+		}
+		if alert.Annotations["summary"] == "" || alert.Annotations["description"] == "" {
+			t.Errorf("Expected summary and description of alert %s, got %+v", alert.Alert, alert.Annotations)
+		}
+	}
+
+	data, err := yaml.Marshal(rule)
+	if err != nil {
+		t.Fatalf("Failed to marshal PrometheusRule: %s", err)
+	}
+	if !strings.HasPrefix(string(data), "apiVersion: monitoring.coreos.com/v1\nkind: PrometheusRule\n") {
+		t.Errorf("Unexpected PrometheusRule YAML:\n%s", string(data))
+	}
+}
+
+func TestGenerateAlertRules(t *testing.T) {
+	data, err := GenerateAlertRules()
+	if err != nil {
+		t.Fatalf("Failed to generate alert rules: %s", err)
+	}
+	for name := range check.DefaultClusterChecks {
+		if !strings.Contains(string(data), "alert: "+alertName(name)+"\n") {
+			t.Errorf("Expected alert of cluster check %s", name)
+		}
+	}
+	for _, nodeCheck := range check.DefaultNodeChecks {
+		if !strings.Contains(string(data), "alert: "+alertName(nodeCheck.Name())+"\n") {
+			t.Errorf("Expected alert of node check %s", nodeCheck.Name())
+		}
+	}
+}
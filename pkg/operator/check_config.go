@@ -0,0 +1,161 @@
+package operator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	cliflag "k8s.io/component-base/cli/flag"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// checkConfigMapName is the optional ConfigMap in the operator namespace with configuration of the checks.
+	checkConfigMapName = "vsphere-problem-detector-config"
+	// checkConfigEnableChecksKey enables or disables checks, in the same format as the enable-checks flag,
+	// e.g. "CheckNodePerf=false,CheckFolderPermissions=true".
+	checkConfigEnableChecksKey = "enableChecks"
+	// checkConfigIntervalKey is the interval of checks after a successful round, e.g. "30m".
+	checkConfigIntervalKey = "checkInterval"
+	// checkConfigCheckIntervalsKey overrides the interval of individual checks, e.g. "CheckDatastoreFreeSpace=1h,CheckNodePerf=10m".
+	checkConfigCheckIntervalsKey = "checkIntervals"
+)
+
+// checkConfig is configuration of the checks from ConfigMap checkConfigMapName. It is reloaded on each sync,
+// so admins can change it without restarting the operator.
+type checkConfig struct {
+	// enabledChecks enables or disables individual checks, check name -> enabled. It overrides
+	// the enable-checks flag.
+	enabledChecks map[string]bool
+	// interval is the default interval of all checks. defaultBackoff.Cap is used when zero.
+	interval time.Duration
+	// checkIntervals are intervals of individual checks, check name -> interval. Checks that are not
+	// present use interval.
+	checkIntervals map[string]time.Duration
+}
+
+// parseCheckConfig parses data of ConfigMap checkConfigMapName. Unknown keys are ignored.
+func parseCheckConfig(data map[string]string) (*checkConfig, error) {
+	cfg := &checkConfig{
+		enabledChecks:  make(map[string]bool),
+		checkIntervals: make(map[string]time.Duration),
+	}
+	if value := data[checkConfigEnableChecksKey]; value != "" {
+		if err := cliflag.NewMapStringBool(&cfg.enabledChecks).Set(value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", checkConfigEnableChecksKey, err)
+		}
+	}
+	if value := data[checkConfigIntervalKey]; value != "" {
+		interval, err := parseCheckInterval(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", checkConfigIntervalKey, err)
+		}
+		cfg.interval = interval
+	}
+	if value := data[checkConfigCheckIntervalsKey]; value != "" {
+		intervals := make(map[string]string)
+		if err := cliflag.NewMapStringString(&intervals).Set(value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", checkConfigCheckIntervalsKey, err)
+		}
+		for name, value := range intervals {
+			interval, err := parseCheckInterval(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s of %s: %s", checkConfigCheckIntervalsKey, name, err)
+			}
+			cfg.checkIntervals[name] = interval
+		}
+	}
+	return cfg, nil
+}
+
+func parseCheckInterval(value string) (time.Duration, error) {
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if interval < time.Minute {
+		return 0, fmt.Errorf("interval %s is shorter than 1m", value)
+	}
+	return interval, nil
+}
+
+// checkInterval returns the interval of the check with the given name.
+func (cfg *checkConfig) checkInterval(name string) time.Duration {
+	if cfg != nil {
+		if interval, found := cfg.checkIntervals[name]; found {
+			return interval
+		}
+		if cfg.interval != 0 {
+			return cfg.interval
+		}
+	}
+	return defaultBackoff.Cap
+}
+
+// checkNames returns sorted names of checks that are present in the config.
+func (cfg *checkConfig) checkNames() []string {
+	var names []string
+	for name := range cfg.enabledChecks {
+		names = append(names, name)
+	}
+	for name := range cfg.checkIntervals {
+		if _, found := cfg.enabledChecks[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getCheckConfig returns configuration of the checks from ConfigMap checkConfigMapName, or an empty
+// configuration when the ConfigMap does not exist.
+func (c *vSphereProblemDetectorController) getCheckConfig() (*checkConfig, error) {
+	cm, err := c.configMapLister.ConfigMaps(operatorNamespace).Get(checkConfigMapName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return parseCheckConfig(nil)
+		}
+		return nil, err
+	}
+	cfg, err := parseCheckConfig(cm.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ConfigMap %s/%s: %s", operatorNamespace, checkConfigMapName, err)
+	}
+	return cfg, nil
+}
+
+// applyCheckConfig loads configuration of the checks and applies it when it has changed. The next round
+// of checks then runs immediately, checks that are not due yet are skipped in it. An invalid config
+// is reported and the previous one is kept.
+func (c *vSphereProblemDetectorController) applyCheckConfig() {
+	if c.configMapLister == nil {
+		return
+	}
+	cfg, err := c.getCheckConfig()
+	if err != nil {
+		klog.Errorf("Failed to load configuration of checks: %s", err)
+		c.eventRecorder.Warningf("InvalidVSphereCheckConfig", "Failed to load configuration of checks: %s", err)
+		return
+	}
+	if reflect.DeepEqual(cfg, c.checkConfig) {
+		return
+	}
+	klog.V(2).Infof("Configuration of checks changed: enabled checks %v, interval %s, check intervals %v", cfg.enabledChecks, cfg.checkInterval(""), cfg.checkIntervals)
+	c.checkConfig = cfg
+	c.checkOptions = c.defaultCheckOptions.WithEnabledChecks(cfg.enabledChecks)
+
+	configured := &check.CheckOptions{EnabledChecks: make(map[string]bool)}
+	for _, name := range cfg.checkNames() {
+		configured.EnabledChecks[name] = true
+	}
+	if unknown := configured.UnknownChecks(c.clusterChecks, c.nodeChecks); len(unknown) > 0 {
+		klog.Warningf("Unknown checks in ConfigMap %s/%s: %s", operatorNamespace, checkConfigMapName, strings.Join(unknown, ", "))
+		c.eventRecorder.Warningf("UnknownVSphereChecks", "Unknown checks in ConfigMap %s/%s: %s", operatorNamespace, checkConfigMapName, strings.Join(unknown, ", "))
+	}
+	// Run the checks with the new config right away.
+	c.nextCheck = time.Time{}
+}
@@ -0,0 +1,183 @@
+package operator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestParseCheckConfig(t *testing.T) {
+	tests := []struct {
+		name                   string
+		data                   map[string]string
+		expectedEnabledChecks  map[string]bool
+		expectedInterval       time.Duration
+		expectedCheckIntervals map[string]time.Duration
+		expectedError          string
+	}{
+		{
+			name:                   "empty config",
+			expectedEnabledChecks:  map[string]bool{},
+			expectedCheckIntervals: map[string]time.Duration{},
+		},
+		{
+			name: "all options",
+			data: map[string]string{
+				checkConfigEnableChecksKey:   "CheckNodePerf=false,CheckFolderPermissions=true",
+				checkConfigIntervalKey:       "30m",
+				checkConfigCheckIntervalsKey: "CheckDatastoreFreeSpace=1h,CheckNodeDiskUUID=10m",
+				"unknown":                    "value",
+			},
+			expectedEnabledChecks: map[string]bool{
+				"CheckNodePerf":          false,
+				"CheckFolderPermissions": true,
+			},
+			expectedInterval: 30 * time.Minute,
+			expectedCheckIntervals: map[string]time.Duration{
+				"CheckDatastoreFreeSpace": time.Hour,
+				"CheckNodeDiskUUID":       10 * time.Minute,
+			},
+		},
+		{
+			name:          "invalid enableChecks",
+			data:          map[string]string{checkConfigEnableChecksKey: "CheckNodePerf=maybe"},
+			expectedError: `invalid enableChecks: invalid value of CheckNodePerf: maybe, err: strconv.ParseBool: parsing "maybe": invalid syntax`,
+		},
+		{
+			name:          "invalid checkInterval",
+			data:          map[string]string{checkConfigIntervalKey: "hourly"},
+			expectedError: `invalid checkInterval: time: invalid duration "hourly"`,
+		},
+		{
+			name:          "too short checkIntervals",
+			data:          map[string]string{checkConfigCheckIntervalsKey: "CheckNodePerf=10s"},
+			expectedError: "invalid checkIntervals of CheckNodePerf: interval 10s is shorter than 1m",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := parseCheckConfig(test.data)
+			if test.expectedError != "" {
+				if err == nil {
+					t.Fatalf("Expected error %q, got none", test.expectedError)
+				}
+				if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(cfg.enabledChecks, test.expectedEnabledChecks) {
+				t.Errorf("Expected enabled checks %v, got %v", test.expectedEnabledChecks, cfg.enabledChecks)
+			}
+			if cfg.interval != test.expectedInterval {
+				t.Errorf("Expected interval %s, got %s", test.expectedInterval, cfg.interval)
+			}
+			if !reflect.DeepEqual(cfg.checkIntervals, test.expectedCheckIntervals) {
+				t.Errorf("Expected check intervals %v, got %v", test.expectedCheckIntervals, cfg.checkIntervals)
+			}
+		})
+	}
+}
+
+func TestCheckConfigCheckInterval(t *testing.T) {
+	var nilConfig *checkConfig
+	if interval := nilConfig.checkInterval("CheckNodePerf"); interval != defaultBackoff.Cap {
+		t.Errorf("Expected interval %s without config, got %s", defaultBackoff.Cap, interval)
+	}
+	cfg := &checkConfig{
+		interval:       30 * time.Minute,
+		checkIntervals: map[string]time.Duration{"CheckNodePerf": 10 * time.Minute},
+	}
+	if interval := cfg.checkInterval("CheckNodePerf"); interval != 10*time.Minute {
+		t.Errorf("Expected interval 10m of CheckNodePerf, got %s", interval)
+	}
+	if interval := cfg.checkInterval("CheckFolderPermissions"); interval != 30*time.Minute {
+		t.Errorf("Expected interval 30m of CheckFolderPermissions, got %s", interval)
+	}
+}
+
+func TestApplyCheckConfig(t *testing.T) {
+	// Stage
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	defaultOptions := &check.CheckOptions{
+		EnabledChecks: map[string]bool{"CheckNodePerf": false},
+	}
+	ctrl := &vSphereProblemDetectorController{
+		configMapLister:     corelister.NewConfigMapLister(indexer),
+		eventRecorder:       events.NewInMemoryRecorder("vsphere-problem-detector"),
+		checkOptions:        defaultOptions,
+		defaultCheckOptions: defaultOptions,
+		clusterChecks: map[string]check.ClusterCheck{
+			"CheckFolderPermissions": check.CheckFolderPermissions,
+		},
+		lastCheck: time.Now(),
+		nextCheck: time.Now().Add(time.Hour),
+	}
+
+	// Act: no ConfigMap
+	ctrl.applyCheckConfig()
+
+	// Assert
+	if !ctrl.checkOptions.IsEnabled("CheckFolderPermissions") || ctrl.checkOptions.IsEnabled("CheckNodePerf") {
+		t.Errorf("Expected only the flag options without ConfigMap, got %v", ctrl.checkOptions.EnabledChecks)
+	}
+	if !ctrl.nextCheck.IsZero() {
+		t.Errorf("Expected checks to run immediately after the config was loaded, got next check at %s", ctrl.nextCheck)
+	}
+
+	// Act: ConfigMap created
+	indexer.Add(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: checkConfigMapName, Namespace: operatorNamespace},
+		Data: map[string]string{
+			checkConfigEnableChecksKey: "CheckFolderPermissions=false,CheckNodePerf=true",
+		},
+	})
+	ctrl.nextCheck = time.Now().Add(time.Hour)
+	ctrl.applyCheckConfig()
+
+	// Assert
+	if ctrl.checkOptions.IsEnabled("CheckFolderPermissions") || !ctrl.checkOptions.IsEnabled("CheckNodePerf") {
+		t.Errorf("Expected checks enabled by ConfigMap, got %v", ctrl.checkOptions.EnabledChecks)
+	}
+	if !ctrl.nextCheck.IsZero() {
+		t.Errorf("Expected checks to run immediately after the config changed, got next check at %s", ctrl.nextCheck)
+	}
+	if defaultOptions.IsEnabled("CheckNodePerf") {
+		t.Errorf("Expected the flag options to be unchanged")
+	}
+
+	// Act: ConfigMap not changed
+	nextCheck := time.Now().Add(time.Hour)
+	ctrl.nextCheck = nextCheck
+	ctrl.applyCheckConfig()
+
+	// Assert
+	if !ctrl.nextCheck.Equal(nextCheck) {
+		t.Errorf("Expected next check at %s when the config did not change, got %s", nextCheck, ctrl.nextCheck)
+	}
+
+	// Act: invalid ConfigMap
+	indexer.Update(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: checkConfigMapName, Namespace: operatorNamespace},
+		Data: map[string]string{
+			checkConfigIntervalKey: "hourly",
+		},
+	})
+	ctrl.applyCheckConfig()
+
+	// Assert
+	if ctrl.checkOptions.IsEnabled("CheckFolderPermissions") {
+		t.Errorf("Expected the previous config to be kept after an invalid one, got %v", ctrl.checkOptions.EnabledChecks)
+	}
+}
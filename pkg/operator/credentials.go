@@ -0,0 +1,71 @@
+package operator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+// detectCredentialsRotation schedules all checks to run in this sync when the secrets with vCenter
+// credentials changed since the last sync. Sessions of vCenters are logged in again with the new
+// credentials when the checks connect. Changes of the secrets already trigger a sync through their
+// informers, but the checks would wait for the next check interval or error backoff otherwise.
+func (c *vSphereProblemDetectorController) detectCredentialsRotation() {
+	if c.secretLister == nil || c.csiSecretLister == nil {
+		return
+	}
+	fingerprint, err := c.getCredentialsFingerprint()
+	if err != nil {
+		klog.Errorf("Failed to get vSphere credentials: %s", err)
+		return
+	}
+	if fingerprint == c.credentialsFingerprint {
+		return
+	}
+	if c.credentialsFingerprint != "" {
+		klog.V(2).Infof("vSphere credentials changed, running all checks now")
+		c.nextCheck = time.Time{}
+		c.backoff = defaultBackoff
+		c.schedule.reset()
+	}
+	c.credentialsFingerprint = fingerprint
+}
+
+// getCredentialsFingerprint returns hash of the data of all secrets with vCenter credentials, i.e. the cloud
+// credentials secret and the vSphere CSI driver config secret. Missing secrets are hashed as empty.
+func (c *vSphereProblemDetectorController) getCredentialsFingerprint() (string, error) {
+	cloudCredentials, err := c.secretLister.Secrets(operatorNamespace).Get(cloudCredentialsSecretName)
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	csiConfig, err := c.csiSecretLister.Secrets(csiDriverNamespace).Get(csiConfigSecretName)
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, secret := range []*v1.Secret{cloudCredentials, csiConfig} {
+		// Separate the secrets, so a key does not move from one to the other unnoticed.
+		hash.Write([]byte{0xff})
+		if secret == nil {
+			continue
+		}
+		keys := make([]string, 0, len(secret.Data))
+		for key := range secret.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			hash.Write([]byte(key))
+			hash.Write([]byte{0})
+			hash.Write(secret.Data[key])
+			hash.Write([]byte{0})
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
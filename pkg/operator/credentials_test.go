@@ -0,0 +1,84 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestDetectCredentialsRotation(t *testing.T) {
+	cloudCredentials := func(password string) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: cloudCredentialsSecretName, Namespace: operatorNamespace},
+			Data: map[string][]byte{
+				"vcenter.example.com.username": []byte("user"),
+				"vcenter.example.com.password": []byte(password),
+			},
+		}
+	}
+	csiConfig := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: csiConfigSecretName, Namespace: csiDriverNamespace},
+		Data:       map[string][]byte{csiConfigSecretKey: []byte("[Global]\n")},
+	}
+	secretIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	secretIndexer.Add(cloudCredentials("pass"))
+	csiSecretIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+
+	nextCheck := time.Now().Add(time.Hour)
+	c := &vSphereProblemDetectorController{
+		secretLister:    corelister.NewSecretLister(secretIndexer),
+		csiSecretLister: corelister.NewSecretLister(csiSecretIndexer),
+		schedule:        newCheckSchedule(),
+		nextCheck:       nextCheck,
+		backoff:         defaultBackoff,
+	}
+	// Simulate checks that ran and failed a few times.
+	c.schedule.lastRun["CheckFoo"] = time.Now()
+	c.backoff.Step()
+	c.backoff.Step()
+
+	expectRotation := func(step string, rotated bool) {
+		t.Helper()
+		c.detectCredentialsRotation()
+		if rotated {
+			if !c.nextCheck.IsZero() {
+				t.Errorf("%s: expected the checks to run now, next check is at %s", step, c.nextCheck)
+			}
+			if c.backoff.Duration != defaultBackoff.Duration {
+				t.Errorf("%s: expected the backoff to be reset, got %s", step, c.backoff.Duration)
+			}
+			if len(c.schedule.lastRun) != 0 {
+				t.Errorf("%s: expected the schedule to be reset, got %v", step, c.schedule.lastRun)
+			}
+		} else if c.nextCheck != nextCheck {
+			t.Errorf("%s: expected the next check at %s, got %s", step, nextCheck, c.nextCheck)
+		}
+		// Prepare for the next step.
+		c.nextCheck = nextCheck
+		c.schedule.lastRun["CheckFoo"] = time.Now()
+		c.backoff.Step()
+	}
+
+	expectRotation("first sync", false)
+	expectRotation("unchanged secrets", false)
+
+	// Changes of metadata are not rotation.
+	relabeled := cloudCredentials("pass")
+	relabeled.Labels = map[string]string{"foo": "bar"}
+	secretIndexer.Update(relabeled)
+	expectRotation("changed labels", false)
+
+	secretIndexer.Update(cloudCredentials("newpass"))
+	expectRotation("changed password", true)
+
+	csiSecretIndexer.Add(csiConfig)
+	expectRotation("new CSI driver config", true)
+
+	secretIndexer.Delete(cloudCredentials("newpass"))
+	expectRotation("deleted cloud credentials", true)
+	expectRotation("still deleted cloud credentials", false)
+}
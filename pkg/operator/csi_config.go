@@ -0,0 +1,164 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/gcfg.v1"
+	"k8s.io/klog/v2"
+	"k8s.io/legacy-cloud-providers/vsphere"
+)
+
+const (
+	csiDriverNamespace  = "openshift-cluster-csi-drivers"
+	csiConfigSecretName = "vsphere-csi-config-secret"
+	csiConfigSecretKey  = "cloud.conf"
+)
+
+// csiConfig is configuration of the vSphere CSI driver, vsphere.conf. Only the fields used by the checks
+// are parsed, the other sections and options are ignored.
+type csiConfig struct {
+	Global struct {
+		ClusterID    string `gcfg:"cluster-id"`
+		User         string `gcfg:"user"`
+		Password     string `gcfg:"password"`
+		VCenterPort  string `gcfg:"port"`
+		InsecureFlag string `gcfg:"insecure-flag"`
+		CAFile       string `gcfg:"ca-file"`
+		Thumbprint   string `gcfg:"thumbprint"`
+		Datacenters  string `gcfg:"datacenters"`
+	}
+	VirtualCenter map[string]*csiVirtualCenterConfig
+}
+
+// csiVirtualCenterConfig is a [VirtualCenter] section of the vSphere CSI driver config.
+// Empty options are inherited from [Global].
+type csiVirtualCenterConfig struct {
+	User        string `gcfg:"user"`
+	Password    string `gcfg:"password"`
+	VCenterPort string `gcfg:"port"`
+	Datacenters string `gcfg:"datacenters"`
+	Thumbprint  string `gcfg:"thumbprint"`
+}
+
+// vCenterCredentials are the user name and password of a vCenter.
+type vCenterCredentials struct {
+	username string
+	password string
+}
+
+func parseCSIConfig(data string) (*csiConfig, error) {
+	var cfg csiConfig
+	// The CSI driver has many options the checks do not need, ignore them.
+	if err := gcfg.FatalOnly(gcfg.ReadStringInto(&cfg, data)); err != nil {
+		return nil, err
+	}
+	if len(cfg.VirtualCenter) == 0 {
+		return nil, fmt.Errorf("no VirtualCenter section found")
+	}
+	return &cfg, nil
+}
+
+// getCSIConfig returns the vSphere CSI driver config from its secret.
+func (c *vSphereChecker) getCSIConfig() (*csiConfig, error) {
+	secret, err := c.controller.csiSecretLister.Secrets(csiDriverNamespace).Get(csiConfigSecretName)
+	if err != nil {
+		return nil, err
+	}
+	data, found := secret.Data[csiConfigSecretKey]
+	if !found {
+		return nil, fmt.Errorf("secret %s/%s does not contain key %q", csiDriverNamespace, csiConfigSecretName, csiConfigSecretKey)
+	}
+	cfg, err := parseCSIConfig(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse secret %s/%s: %s", csiDriverNamespace, csiConfigSecretName, err)
+	}
+	return cfg, nil
+}
+
+// mergeCSIConfig returns VSphereConfig with vCenters and datacenters from the vSphere CSI driver config and
+// credentials of the vCenters that have them in the CSI driver config, vCenter name -> credentials.
+// The CSI driver config has no Workspace section. Workspace of the legacy config is kept when it points to
+// a vCenter and a datacenter of the CSI driver config, otherwise it points to the first datacenter of the
+// first vCenter, without any folder, datastore or resource pool. All other values, such as zone labels,
+// are taken from the legacy config, which may be nil.
+func mergeCSIConfig(csi *csiConfig, legacy *vsphere.VSphereConfig) (*vsphere.VSphereConfig, map[string]vCenterCredentials, error) {
+	cfg := &vsphere.VSphereConfig{}
+	if legacy != nil {
+		*cfg = *legacy
+	}
+	cfg.Global.VCenterPort = csi.Global.VCenterPort
+	cfg.Global.CAFile = csi.Global.CAFile
+	cfg.Global.Thumbprint = csi.Global.Thumbprint
+	cfg.Global.Datacenters = csi.Global.Datacenters
+	cfg.Global.InsecureFlag = false
+	if csi.Global.InsecureFlag != "" {
+		insecure, err := strconv.ParseBool(csi.Global.InsecureFlag)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid insecure-flag %q: %s", csi.Global.InsecureFlag, err)
+		}
+		cfg.Global.InsecureFlag = insecure
+	}
+
+	credentials := make(map[string]vCenterCredentials)
+	cfg.VirtualCenter = make(map[string]*vsphere.VirtualCenterConfig)
+	var vCenters []string
+	for vCenter, vcConfig := range csi.VirtualCenter {
+		vCenters = append(vCenters, vCenter)
+		vc := &vsphere.VirtualCenterConfig{
+			VCenterPort: defaultString(vcConfig.VCenterPort, csi.Global.VCenterPort),
+			Datacenters: defaultString(vcConfig.Datacenters, csi.Global.Datacenters),
+			Thumbprint:  defaultString(vcConfig.Thumbprint, csi.Global.Thumbprint),
+		}
+		cfg.VirtualCenter[vCenter] = vc
+		username := defaultString(vcConfig.User, csi.Global.User)
+		password := defaultString(vcConfig.Password, csi.Global.Password)
+		if username != "" && password != "" {
+			credentials[vCenter] = vCenterCredentials{username: username, password: password}
+		}
+	}
+	sort.Strings(vCenters)
+
+	if legacy != nil && hasDatacenter(cfg.VirtualCenter[legacy.Workspace.VCenterIP], legacy.Workspace.Datacenter) {
+		return cfg, credentials, nil
+	}
+	cfg.Workspace.VCenterIP = vCenters[0]
+	cfg.Workspace.Datacenter = ""
+	for _, dc := range strings.Split(cfg.VirtualCenter[vCenters[0]].Datacenters, ",") {
+		if dc = strings.TrimSpace(dc); dc != "" {
+			cfg.Workspace.Datacenter = dc
+			break
+		}
+	}
+	if cfg.Workspace.Datacenter == "" {
+		return nil, nil, fmt.Errorf("vCenter %s has no datacenters", vCenters[0])
+	}
+	cfg.Workspace.Folder = ""
+	cfg.Workspace.DefaultDatastore = ""
+	cfg.Workspace.ResourcePoolPath = ""
+	klog.V(2).Infof("Workspace of the legacy cloud provider config does not match the vSphere CSI driver config, using vCenter %s and datacenter %s", cfg.Workspace.VCenterIP, cfg.Workspace.Datacenter)
+	return cfg, credentials, nil
+}
+
+// hasDatacenter returns true when the vCenter config lists the datacenter.
+func hasDatacenter(vc *vsphere.VirtualCenterConfig, datacenter string) bool {
+	if vc == nil || datacenter == "" {
+		return false
+	}
+	for _, dc := range strings.Split(vc.Datacenters, ",") {
+		if strings.TrimSpace(dc) == datacenter {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultString returns value, or defaultValue when value is empty.
+func defaultString(value, defaultValue string) string {
+	if value != "" {
+		return value
+	}
+	return defaultValue
+}
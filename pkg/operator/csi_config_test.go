@@ -0,0 +1,191 @@
+package operator
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/legacy-cloud-providers/vsphere"
+)
+
+const (
+	testCSIConfig = `
+[Global]
+cluster-id = "test-cluster"
+insecure-flag = "true"
+port = "443"
+
+[VirtualCenter "vc1"]
+user = "csi-user"
+password = "csi-password"
+datacenters = "DC0, DC1"
+
+[VirtualCenter "vc2"]
+datacenters = "DC2"
+
+[Labels]
+topology-categories = "openshift-region, openshift-zone"
+
+[Snapshot]
+global-max-snapshots-per-block-volume = 3
+`
+	testLegacyConfig = `
+[Global]
+secret-name = "vsphere-creds"
+secret-namespace = "kube-system"
+insecure-flag = "1"
+
+[Workspace]
+server = "vc1"
+datacenter = "DC1"
+default-datastore = "LocalDS_0"
+folder = "/DC1/vm/cluster"
+
+[VirtualCenter "vc1"]
+datacenters = "DC1"
+
+[Labels]
+zone = "k8s-zone"
+region = "k8s-region"
+`
+)
+
+func TestParseCSIConfig(t *testing.T) {
+	cfg, err := parseCSIConfig(testCSIConfig)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if cfg.Global.ClusterID != "test-cluster" {
+		t.Errorf("Expected cluster-id test-cluster, got %q", cfg.Global.ClusterID)
+	}
+	if len(cfg.VirtualCenter) != 2 {
+		t.Fatalf("Expected 2 vCenters, got %d", len(cfg.VirtualCenter))
+	}
+	if cfg.VirtualCenter["vc1"].Datacenters != "DC0, DC1" {
+		t.Errorf("Expected datacenters of vc1 DC0, DC1, got %q", cfg.VirtualCenter["vc1"].Datacenters)
+	}
+
+	if _, err := parseCSIConfig("[Global]\ncluster-id = \"test-cluster\"\n"); err == nil {
+		t.Errorf("Expected error for config without vCenters, got none")
+	}
+}
+
+func TestMergeCSIConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		// Legacy cloud provider config, none when empty
+		legacyConfig        string
+		csiConfig           string
+		expectedVCenter     string
+		expectedDatacenter  string
+		expectedFolder      string
+		expectedZoneLabel   string
+		expectedCredentials map[string]vCenterCredentials
+		expectedError       string
+	}{
+		{
+			name:               "legacy Workspace matches",
+			legacyConfig:       testLegacyConfig,
+			csiConfig:          testCSIConfig,
+			expectedVCenter:    "vc1",
+			expectedDatacenter: "DC1",
+			expectedFolder:     "/DC1/vm/cluster",
+			expectedZoneLabel:  "k8s-zone",
+			expectedCredentials: map[string]vCenterCredentials{
+				"vc1": {username: "csi-user", password: "csi-password"},
+			},
+		},
+		{
+			name:               "stale legacy Workspace",
+			legacyConfig:       testLegacyConfig,
+			csiConfig:          "[VirtualCenter \"vc2\"]\ndatacenters = \"DC2\"\n",
+			expectedVCenter:    "vc2",
+			expectedDatacenter: "DC2",
+			expectedZoneLabel:  "k8s-zone",
+		},
+		{
+			name:               "no legacy config",
+			csiConfig:          testCSIConfig,
+			expectedVCenter:    "vc1",
+			expectedDatacenter: "DC0",
+			expectedCredentials: map[string]vCenterCredentials{
+				"vc1": {username: "csi-user", password: "csi-password"},
+			},
+		},
+		{
+			name:               "global credentials",
+			csiConfig:          "[Global]\nuser = \"global-user\"\npassword = \"global-password\"\ndatacenters = \"DC0\"\n[VirtualCenter \"vc1\"]\n[VirtualCenter \"vc2\"]\nuser = \"vc2-user\"\n",
+			expectedVCenter:    "vc1",
+			expectedDatacenter: "DC0",
+			expectedCredentials: map[string]vCenterCredentials{
+				"vc1": {username: "global-user", password: "global-password"},
+				"vc2": {username: "vc2-user", password: "global-password"},
+			},
+		},
+		{
+			name:          "no datacenters",
+			csiConfig:     "[VirtualCenter \"vc1\"]\n",
+			expectedError: "vCenter vc1 has no datacenters",
+		},
+		{
+			name:          "invalid insecure-flag",
+			csiConfig:     "[Global]\ninsecure-flag = \"maybe\"\n[VirtualCenter \"vc1\"]\ndatacenters = \"DC0\"\n",
+			expectedError: `invalid insecure-flag "maybe": strconv.ParseBool: parsing "maybe": invalid syntax`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Stage
+			var legacy *vsphere.VSphereConfig
+			if test.legacyConfig != "" {
+				var err error
+				legacy, err = parseConfig(test.legacyConfig)
+				if err != nil {
+					t.Fatalf("Failed to parse legacy config: %s", err)
+				}
+			}
+			csi, err := parseCSIConfig(test.csiConfig)
+			if err != nil {
+				t.Fatalf("Failed to parse CSI driver config: %s", err)
+			}
+
+			// Act
+			cfg, credentials, err := mergeCSIConfig(csi, legacy)
+
+			// Assert
+			if test.expectedError != "" {
+				if err == nil {
+					t.Fatalf("Expected error %q, got none", test.expectedError)
+				}
+				if err.Error() != test.expectedError {
+					t.Errorf("Expected error %q, got %q", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if cfg.Workspace.VCenterIP != test.expectedVCenter {
+				t.Errorf("Expected Workspace vCenter %q, got %q", test.expectedVCenter, cfg.Workspace.VCenterIP)
+			}
+			if cfg.Workspace.Datacenter != test.expectedDatacenter {
+				t.Errorf("Expected Workspace datacenter %q, got %q", test.expectedDatacenter, cfg.Workspace.Datacenter)
+			}
+			if cfg.Workspace.Folder != test.expectedFolder {
+				t.Errorf("Expected Workspace folder %q, got %q", test.expectedFolder, cfg.Workspace.Folder)
+			}
+			if cfg.Labels.Zone != test.expectedZoneLabel {
+				t.Errorf("Expected zone label %q, got %q", test.expectedZoneLabel, cfg.Labels.Zone)
+			}
+			if len(cfg.VirtualCenter) != len(csi.VirtualCenter) {
+				t.Errorf("Expected %d vCenters, got %d", len(csi.VirtualCenter), len(cfg.VirtualCenter))
+			}
+			if test.expectedCredentials == nil {
+				test.expectedCredentials = map[string]vCenterCredentials{}
+			}
+			if !reflect.DeepEqual(credentials, test.expectedCredentials) {
+				t.Errorf("Expected credentials %+v, got %+v", test.expectedCredentials, credentials)
+			}
+		})
+	}
+}
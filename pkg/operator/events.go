@@ -0,0 +1,68 @@
+package operator
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// checkFailedEventReason is the reason of Events recorded when a check starts failing.
+	checkFailedEventReason = "VSphereCheckFailed"
+	eventSourceComponent   = "vsphere-problem-detector"
+)
+
+// checkTarget is a check on a single node for node checks, or a cluster check with an empty node.
+type checkTarget struct {
+	name string
+	node string
+}
+
+// newCheckEventRecorder returns EventRecorder that records Events of failing checks on Nodes and the operator namespace.
+func newCheckEventRecorder(kubeClient kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventSourceComponent})
+}
+
+// recordFailureEvents records an Event for each check that failed in this round, but did not fail in the previous
+// one (it passed, it was disabled or it did not run). Events of node checks are recorded on the Node, events of
+// cluster checks on the operator namespace. Unlike the events of the operator, which are sent after each round
+// for all checks, these are sent only once when a check starts failing, so they're suitable for alerting.
+func (c *vSphereProblemDetectorController) recordFailureEvents(results []checkResult) {
+	if c.checkEventRecorder == nil {
+		return
+	}
+	failing := make(map[checkTarget]bool)
+	for _, res := range results {
+		if res.Disabled || res.Error == nil {
+			continue
+		}
+		target := checkTarget{name: res.Name, node: res.Node}
+		failing[target] = true
+		if c.failingChecks[target] {
+			continue
+		}
+
+		if res.Node == "" {
+			ref := &v1.ObjectReference{
+				Kind:       "Namespace",
+				APIVersion: "v1",
+				Name:       operatorNamespace,
+				Namespace:  operatorNamespace,
+			}
+			c.checkEventRecorder.Eventf(ref, v1.EventTypeWarning, checkFailedEventReason, "Check %s failed: %s", res.Name, res.Error)
+			continue
+		}
+		node, err := c.nodeLister.Get(res.Node)
+		if err != nil {
+			klog.V(2).Infof("Failed to record event of check %s on node %s: %s", res.Name, res.Node, err)
+			continue
+		}
+		c.checkEventRecorder.Eventf(node, v1.EventTypeWarning, checkFailedEventReason, "Check %s failed on node %s: %s", res.Name, res.Node, res.Error)
+	}
+	c.failingChecks = failing
+}
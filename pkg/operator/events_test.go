@@ -0,0 +1,134 @@
+package operator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRecordFailureEvents(t *testing.T) {
+	nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	nodeIndexer.Add(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}})
+	recorder := record.NewFakeRecorder(100)
+	ctrl := &vSphereProblemDetectorController{
+		nodeLister:         corelister.NewNodeLister(nodeIndexer),
+		checkEventRecorder: recorder,
+	}
+
+	rounds := []struct {
+		name           string
+		results        []checkResult
+		expectedEvents []string
+	}{
+		{
+			name: "first round",
+			results: []checkResult{
+				{Name: "CheckA"},
+				{Name: "CheckB", Error: errors.New("cluster error")},
+				{Name: "CheckC", Node: "node1", Error: errors.New("node error")},
+				// Events of nodes that do not exist are not recorded
+				{Name: "CheckC", Node: "node2", Error: errors.New("node error")},
+				{Name: "CheckD", Disabled: true},
+			},
+			expectedEvents: []string{
+				"Warning VSphereCheckFailed Check CheckB failed: cluster error",
+				"Warning VSphereCheckFailed Check CheckC failed on node node1: node error",
+			},
+		},
+		{
+			name: "checks keep failing",
+			results: []checkResult{
+				{Name: "CheckA"},
+				{Name: "CheckB", Error: errors.New("another cluster error")},
+				{Name: "CheckC", Node: "node1", Error: errors.New("node error")},
+			},
+		},
+		{
+			name: "checks recover and start failing",
+			results: []checkResult{
+				{Name: "CheckA", Error: errors.New("cluster error")},
+				{Name: "CheckB"},
+				{Name: "CheckC", Node: "node1"},
+			},
+			expectedEvents: []string{
+				"Warning VSphereCheckFailed Check CheckA failed: cluster error",
+			},
+		},
+		{
+			name: "check fails again",
+			results: []checkResult{
+				{Name: "CheckA", Error: errors.New("cluster error")},
+				{Name: "CheckB", Error: errors.New("cluster error")},
+			},
+			expectedEvents: []string{
+				"Warning VSphereCheckFailed Check CheckB failed: cluster error",
+			},
+		},
+	}
+
+	for _, round := range rounds {
+		ctrl.recordFailureEvents(round.results)
+
+		var events []string
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		if !reflect.DeepEqual(events, round.expectedEvents) {
+			t.Errorf("%s: expected events %q, got %q", round.name, round.expectedEvents, events)
+		}
+	}
+}
+
+func TestReportResultsDisabledOnce(t *testing.T) {
+	recorder := events.NewInMemoryRecorder("vsphere-problem-detector")
+	ctrl := &vSphereProblemDetectorController{
+		eventRecorder: recorder,
+	}
+
+	rounds := []struct {
+		name           string
+		results        []checkResult
+		expectedEvents []string
+	}{
+		{
+			name:           "check disabled",
+			results:        []checkResult{{Name: "CheckA", Disabled: true}},
+			expectedEvents: []string{"DisabledVSphereCheckA"},
+		},
+		{
+			name:    "check stays disabled",
+			results: []checkResult{{Name: "CheckA", Disabled: true}},
+		},
+		{
+			name:           "check enabled",
+			results:        []checkResult{{Name: "CheckA"}},
+			expectedEvents: []string{"SucceededVSphereCheckA"},
+		},
+		{
+			name:           "check disabled again",
+			results:        []checkResult{{Name: "CheckA", Disabled: true}},
+			expectedEvents: []string{"DisabledVSphereCheckA"},
+		},
+	}
+
+	recorded := 0
+	for _, round := range rounds {
+		ctrl.reportResults(round.results)
+
+		var reasons []string
+		for _, event := range recorder.Events()[recorded:] {
+			reasons = append(reasons, event.Reason)
+		}
+		recorded = len(recorder.Events())
+		if !reflect.DeepEqual(reasons, round.expectedEvents) {
+			t.Errorf("%s: expected events %v, got %v", round.name, round.expectedEvents, reasons)
+		}
+	}
+}
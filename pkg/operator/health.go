@@ -0,0 +1,112 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	"k8s.io/apiserver/pkg/server/mux"
+	"k8s.io/klog/v2"
+)
+
+const (
+	healthPath = "/healthz/checks"
+)
+
+// HealthStatus is the status of the detector reported by HealthHandler.
+type HealthStatus struct {
+	// LastRunStartTime is the time when the last completed round of checks started.
+	// Nil when no round has completed yet.
+	LastRunStartTime *time.Time `json:"lastRunStartTime,omitempty"`
+	// LastRunFinishTime is the time when the last completed round of checks finished.
+	// Nil when no round has completed yet.
+	LastRunFinishTime *time.Time `json:"lastRunFinishTime,omitempty"`
+	// Zone is the zone the last round of checks was limited to, empty when all nodes were checked.
+	Zone string `json:"zone,omitempty"`
+	// ChecksRun is the number of checks performed in the last round.
+	ChecksRun int `json:"checksRun"`
+	// ChecksFailed is the number of checks that failed in the last round, without checks that timed out.
+	ChecksFailed int `json:"checksFailed"`
+	// ChecksTimedOut is the number of checks that did not finish within their timeout in the last round.
+	// Timeouts usually mean slow vCenter rather than a problem with the configuration.
+	ChecksTimedOut int `json:"checksTimedOut"`
+	// ChecksDisabled is the number of checks that were disabled in the last round.
+	ChecksDisabled int `json:"checksDisabled"`
+}
+
+// HealthHandler serves HealthStatus of the last round of checks stored in a ResultStore as JSON.
+// Monitoring can use it to tell a detector that is stuck (old LastRunFinishTime) from a detector
+// that runs and finds problems (ChecksFailed > 0).
+type HealthHandler struct {
+	resultStore check.ResultStore
+}
+
+var _ http.Handler = &HealthHandler{}
+
+// NewHealthHandler returns a new HealthHandler that reads results from the given store.
+func NewHealthHandler(resultStore check.ResultStore) *HealthHandler {
+	return &HealthHandler{
+		resultStore: resultStore,
+	}
+}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, err := h.getStatus(req.Context())
+	if err != nil {
+		klog.Errorf("Failed to load check results: %s", err)
+		http.Error(w, "failed to load check results", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		klog.V(2).Infof("Failed to write health status: %s", err)
+	}
+}
+
+func (h *HealthHandler) getStatus(ctx context.Context) (*HealthStatus, error) {
+	status := &HealthStatus{}
+	run, err := h.resultStore.LoadLatest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if run == nil {
+		return status, nil
+	}
+
+	startTime, finishTime := run.StartTime, run.FinishTime
+	status.LastRunStartTime = &startTime
+	if !finishTime.IsZero() {
+		status.LastRunFinishTime = &finishTime
+	}
+	status.Zone = run.Zone
+	for _, result := range run.Results {
+		if result.Disabled {
+			status.ChecksDisabled++
+			continue
+		}
+		status.ChecksRun++
+		switch {
+		case result.TimedOut:
+			status.ChecksTimedOut++
+		case len(result.Errors) > 0:
+			status.ChecksFailed++
+		}
+	}
+	return status, nil
+}
+
+// registerHealthHandlers registers the health and results handlers in the mux of the metrics server of the operator,
+// so they are served over HTTPS and only to clients authorized by its delegated authentication and authorization.
+func registerHealthHandlers(mux *mux.PathRecorderMux, healthHandler, resultsHandler http.Handler) {
+	mux.Handle(healthPath, healthHandler)
+	mux.Handle(resultsPath, resultsHandler)
+	klog.V(2).Infof("Serving health status at %s and check results at %s", healthPath, resultsPath)
+}
@@ -0,0 +1,110 @@
+package operator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	"k8s.io/apiserver/pkg/server/mux"
+)
+
+func TestHealthHandler(t *testing.T) {
+	startTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name           string
+		runs           []*check.CheckRun
+		method         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "no runs",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"checksRun":0,"checksFailed":0,"checksTimedOut":0,"checksDisabled":0}`,
+		},
+		{
+			name: "last run is reported",
+			runs: []*check.CheckRun{
+				{
+					StartTime:  startTime.Add(-time.Hour),
+					FinishTime: startTime.Add(-time.Hour + time.Minute),
+					Results: map[string]check.CheckRunResult{
+						"CheckA": {Errors: []string{"error"}},
+					},
+				},
+				{
+					StartTime:  startTime,
+					FinishTime: startTime.Add(time.Minute),
+					Zone:       "zone-a",
+					Results: map[string]check.CheckRunResult{
+						"CheckA": {},
+						"CheckB": {Errors: []string{"error 1", "error 2"}},
+						"CheckC": {Errors: []string{"error"}},
+						"CheckD": {Disabled: true},
+						"CheckE": {Errors: []string{"check timed out after 30s"}, TimedOut: true},
+					},
+				},
+			},
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"lastRunStartTime":"2022-01-02T03:04:05Z","lastRunFinishTime":"2022-01-02T03:05:05Z","zone":"zone-a","checksRun":4,"checksFailed":2,"checksTimedOut":1,"checksDisabled":1}`,
+		},
+		{
+			name:           "unsupported method",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   "method not allowed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := check.NewMemoryResultStore(10)
+			for _, run := range test.runs {
+				if err := store.Save(context.TODO(), run); err != nil {
+					t.Fatalf("Failed to save run: %s", err)
+				}
+			}
+			handler := NewHealthHandler(store)
+
+			req := httptest.NewRequest(test.method, healthPath, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.expectedStatus {
+				t.Errorf("Expected status %d, got %d", test.expectedStatus, rec.Code)
+			}
+			body := strings.TrimSpace(rec.Body.String())
+			if body != test.expectedBody {
+				t.Errorf("Expected body %s, got %s", test.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestRegisterHealthHandlers(t *testing.T) {
+	pathMux := mux.NewPathRecorderMux("test")
+	registerHealthHandlers(pathMux, NewHealthHandler(check.NewMemoryResultStore(10)), NewResultsHandler(NewReportStore()))
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{path: healthPath, expectedStatus: http.StatusOK},
+		// No round of checks has completed yet.
+		{path: resultsPath, expectedStatus: http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		rec := httptest.NewRecorder()
+		pathMux.ServeHTTP(rec, req)
+		if rec.Code != test.expectedStatus {
+			t.Errorf("Expected status %d at %s, got %d", test.expectedStatus, test.path, rec.Code)
+		}
+	}
+}
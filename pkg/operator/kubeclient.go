@@ -18,8 +18,23 @@ func (c *vSphereProblemDetectorController) GetInfrastructure(ctx context.Context
 	return c.infraLister.Get(infrastructureName)
 }
 
+// ListNodes returns nodes in the zone of checkOptions, so both node and cluster checks
+// see only the nodes in the zone.
 func (c *vSphereProblemDetectorController) ListNodes(ctx context.Context) ([]*v1.Node, error) {
-	return c.nodeLister.List(labels.Everything())
+	nodes, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	if c.zone() == "" {
+		return nodes, nil
+	}
+	var zoneNodes []*v1.Node
+	for _, node := range nodes {
+		if c.checkOptions.InZone(node) {
+			zoneNodes = append(zoneNodes, node)
+		}
+	}
+	return zoneNodes, nil
 }
 
 func (c *vSphereProblemDetectorController) ListStorageClasses(ctx context.Context) ([]*storagev1.StorageClass, error) {
@@ -29,3 +44,11 @@ func (c *vSphereProblemDetectorController) ListStorageClasses(ctx context.Contex
 func (c *vSphereProblemDetectorController) ListPVs(ctx context.Context) ([]*v1.PersistentVolume, error) {
 	return c.pvLister.List(labels.Everything())
 }
+
+func (c *vSphereProblemDetectorController) ListPVCs(ctx context.Context) ([]*v1.PersistentVolumeClaim, error) {
+	return c.pvcLister.List(labels.Everything())
+}
+
+func (c *vSphereProblemDetectorController) ListVolumeAttachments(ctx context.Context) ([]*storagev1.VolumeAttachment, error) {
+	return c.vaLister.List(labels.Everything())
+}
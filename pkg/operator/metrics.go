@@ -9,8 +9,22 @@ const (
 	checkNameLabel = "check"
 	nodeNameLabel  = "node"
 	reasonLabel    = "reason"
+	statusLabel    = "status"
+	matchLabel     = "match"
+	vCenterLabel   = "vcenter"
+
+	checkStatusPassed   = "passed"
+	checkStatusFailed   = "failed"
+	checkStatusTimeout  = "timeout"
+	checkStatusDisabled = "disabled"
+
+	// nodeVMMatchNone is value of matchLabel for nodes that were not matched to any VM.
+	nodeVMMatchNone = "none"
 )
 
+// checkStatuses are all values of statusLabel.
+var checkStatuses = []string{checkStatusPassed, checkStatusFailed, checkStatusTimeout, checkStatusDisabled}
+
 // This file contains operator metrics, especially status of each check.
 // For other metrics exposed by this operator, see pkg/check.
 
@@ -33,6 +47,15 @@ var (
 		[]string{checkNameLabel},
 	)
 
+	clusterCheckVCenterErrorMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_cluster_check_vcenter_errors",
+			Help:           "Indicates failing vSphere cluster-level checks performed by vsphere-problem-detector in each vCenter. Value of 1 means - a particular check is failing in the vCenter of the vcenter label.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{checkNameLabel, vCenterLabel},
+	)
+
 	nodeCheckTotalMetric = metrics.NewCounterVec(
 		&metrics.CounterOpts{
 			Name:           "vsphere_node_check_total",
@@ -51,6 +74,33 @@ var (
 		[]string{checkNameLabel, nodeNameLabel},
 	)
 
+	nodeCheckVCenterErrorMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_check_vcenter_errors",
+			Help:           "Indicates failing vSphere node-level checks performed by vsphere-problem-detector in each vCenter. Value of 1 means - a particular check is failing on a node whose VM runs in the vCenter of the vcenter label.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{checkNameLabel, nodeNameLabel, vCenterLabel},
+	)
+
+	checkStatusMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_problem_detector_check_status",
+			Help:           "Status of vSphere checks in the last round of checks performed by vsphere-problem-detector. Value of 1 means - a particular check has the status (passed, failed, timeout or disabled), 0 otherwise. A node-level check fails when it fails on at least one node.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{checkNameLabel, statusLabel},
+	)
+
+	nodeVMMatchMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_node_vm_match",
+			Help:           "How vsphere-problem-detector matched a node to its VM in the last round of checks. Value of 1 means - the node was matched by UUID in its ProviderID (provider_id), by its name when ProviderID is empty (name) or not matched at all (none). The vcenter label is the vCenter that runs the VM, empty when the node was not matched.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{nodeNameLabel, matchLabel, vCenterLabel},
+	)
+
 	syncErrrorMetric = metrics.NewGaugeVec(
 		&metrics.GaugeOpts{
 			Name:           "vsphere_sync_errors",
@@ -59,12 +109,25 @@ var (
 		},
 		[]string{reasonLabel},
 	)
+
+	vCenterSessionsMetric = metrics.NewGauge(
+		&metrics.GaugeOpts{
+			Name:           "vsphere_vcenter_sessions",
+			Help:           "Number of vCenter sessions kept open by vsphere-problem-detector. A single session of each vCenter is reused by all rounds of checks.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
 )
 
 func init() {
 	legacyregistry.MustRegister(clusterCheckTotalMetric)
 	legacyregistry.MustRegister(clusterCheckErrrorMetric)
+	legacyregistry.MustRegister(clusterCheckVCenterErrorMetric)
 	legacyregistry.MustRegister(nodeCheckTotalMetric)
 	legacyregistry.MustRegister(nodeCheckErrrorMetric)
+	legacyregistry.MustRegister(nodeCheckVCenterErrorMetric)
+	legacyregistry.MustRegister(checkStatusMetric)
+	legacyregistry.MustRegister(nodeVMMatchMetric)
 	legacyregistry.MustRegister(syncErrrorMetric)
+	legacyregistry.MustRegister(vCenterSessionsMetric)
 }
@@ -22,6 +22,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	corelister "k8s.io/client-go/listers/core/v1"
 	storagelister "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 )
 
@@ -29,17 +30,44 @@ type vSphereProblemDetectorController struct {
 	operatorClient       *OperatorClient
 	kubeClient           kubernetes.Interface
 	infraLister          infralister.InfrastructureLister
+	proxyLister          infralister.ProxyLister
 	secretLister         corelister.SecretLister
 	nodeLister           corelister.NodeLister
 	pvLister             corelister.PersistentVolumeLister
+	pvcLister            corelister.PersistentVolumeClaimLister
 	scLister             storagelister.StorageClassLister
+	vaLister             storagelister.VolumeAttachmentLister
 	cloudConfigMapLister corelister.ConfigMapLister
+	csiSecretLister      corelister.SecretLister
+	configMapLister      corelister.ConfigMapLister
 	eventRecorder        events.Recorder
 
 	// List of checks to perform (useful for unit-tests: replace with a dummy check).
 	clusterChecks map[string]check.ClusterCheck
 	nodeChecks    []check.NodeCheck
 	checkerFunc   func(c *vSphereProblemDetectorController) vSphereCheckerInterface
+	// Options of the checks that persist across syncs, defaultCheckOptions with checkConfig applied.
+	checkOptions *check.CheckOptions
+	// Options of the checks from command line flags.
+	defaultCheckOptions *check.CheckOptions
+	// Configuration of the checks from ConfigMap checkConfigMapName, nil before it's loaded.
+	checkConfig *checkConfig
+	// When individual checks ran, to skip checks that are not due.
+	schedule *checkSchedule
+	// History of check results.
+	resultStore check.ResultStore
+	// Report of the last round of checks, served at resultsPath.
+	reportStore *ReportStore
+	// Records Events on Nodes and the operator namespace when checks start failing.
+	checkEventRecorder record.EventRecorder
+	// Checks that failed in the last round, see recordFailureEvents.
+	failingChecks map[checkTarget]bool
+	// Checks that were disabled in the last round, see reportResults.
+	disabledChecks map[string]bool
+	// Sessions of vCenters, reused by all rounds of checks.
+	sessions *sessionManager
+	// Hash of the secrets with vCenter credentials in the last sync, see detectCredentialsRotation.
+	credentialsFingerprint string
 
 	lastCheck time.Time
 	nextCheck time.Time
@@ -53,22 +81,35 @@ type clusterCheckResult struct {
 }
 
 type checkResult struct {
-	Name  string
-	Error error
+	Name string
+	// Node is the name of the node checked by a node check, empty for cluster checks.
+	Node string
+	// VCenter is the vCenter the check ran against, empty when the check did not run or
+	// when VM of the node was not found.
+	VCenter string
+	Error   error
+	// Disabled is true when the check was not performed, because it was disabled in CheckOptions.
+	Disabled bool
+	// TimedOut is true when the check did not finish within its timeout. For node checks,
+	// it is true when the check timed out on at least one node.
+	TimedOut bool
+	// StartTime is when the check started, zero when it did not run.
+	StartTime time.Time
+	// Duration is how long the check ran.
+	Duration time.Duration
 }
 
 const (
 	controllerName             = "VSphereProblemDetectorController"
 	infrastructureName         = "cluster"
 	cloudCredentialsSecretName = "vsphere-cloud-credentials"
-	// TODO: make it configurable?
+	// Default number of checks that run in parallel, see check-workers.
 	parallelVSPhereCalls = 10
 	// Size of golang channel buffer
 	channelBufferSize     = 100
-	minHostVersion        = "6.7.3"
-	minVCenterVersion     = "6.7.3"
 	hardwareVersionPrefix = "vmx-"
-	minHardwareVersion    = 15
+	// Nr. of rounds of checks kept in the default result store.
+	resultStoreRuns = 100
 )
 
 var (
@@ -88,36 +129,66 @@ func NewVSphereProblemDetectorController(
 	kubeClient kubernetes.Interface,
 	namespacedInformer v1helpers.KubeInformersForNamespaces,
 	configInformer infrainformer.InfrastructureInformer,
+	proxyInformer infrainformer.ProxyInformer,
+	resultStore check.ResultStore,
+	reportStore *ReportStore,
+	sessions *sessionManager,
 	eventRecorder events.Recorder) factory.Controller {
 
 	secretInformer := namespacedInformer.InformersFor(operatorNamespace).Core().V1().Secrets()
 	cloudConfigMapInformer := namespacedInformer.InformersFor(cloudConfigNamespace).Core().V1().ConfigMaps()
+	csiSecretInformer := namespacedInformer.InformersFor(csiDriverNamespace).Core().V1().Secrets()
+	configMapInformer := namespacedInformer.InformersFor(operatorNamespace).Core().V1().ConfigMaps()
 	nodeInformer := namespacedInformer.InformersFor("").Core().V1().Nodes()
 	pvInformer := namespacedInformer.InformersFor("").Core().V1().PersistentVolumes()
+	pvcInformer := namespacedInformer.InformersFor("").Core().V1().PersistentVolumeClaims()
 	scInformer := namespacedInformer.InformersFor("").Storage().V1().StorageClasses()
+	vaInformer := namespacedInformer.InformersFor("").Storage().V1().VolumeAttachments()
+	checkOptions := check.NewCheckOptions()
 	c := &vSphereProblemDetectorController{
 		operatorClient:       operatorClient,
 		kubeClient:           kubeClient,
 		secretLister:         secretInformer.Lister(),
 		nodeLister:           nodeInformer.Lister(),
 		pvLister:             pvInformer.Lister(),
+		pvcLister:            pvcInformer.Lister(),
 		scLister:             scInformer.Lister(),
+		vaLister:             vaInformer.Lister(),
 		cloudConfigMapLister: cloudConfigMapInformer.Lister(),
+		csiSecretLister:      csiSecretInformer.Lister(),
+		configMapLister:      configMapInformer.Lister(),
 		infraLister:          configInformer.Lister(),
+		proxyLister:          proxyInformer.Lister(),
 		eventRecorder:        eventRecorder.WithComponentSuffix(controllerName),
-		clusterChecks:        check.DefaultClusterChecks,
-		nodeChecks:           check.DefaultNodeChecks,
+		clusterChecks:        instrumentClusterChecks(check.DefaultClusterChecks),
+		nodeChecks:           instrumentNodeChecks(check.DefaultNodeChecks),
 		backoff:              defaultBackoff,
 		checkerFunc:          newVSphereChecker,
+		checkOptions:         checkOptions,
+		defaultCheckOptions:  checkOptions,
+		schedule:             newCheckSchedule(),
+		resultStore:          resultStore,
+		reportStore:          reportStore,
+		sessions:             sessions,
+		checkEventRecorder:   newCheckEventRecorder(kubeClient),
 		nextCheck:            time.Time{}, // Explicitly set to zero to run checks on the first sync().
 	}
+	if unknown := c.defaultCheckOptions.UnknownChecks(c.clusterChecks, c.nodeChecks); len(unknown) > 0 {
+		klog.Warningf("Unknown checks configured to be enabled or disabled: %s", strings.Join(unknown, ", "))
+		c.eventRecorder.Warningf("UnknownVSphereChecks", "Unknown checks configured to be enabled or disabled: %s", strings.Join(unknown, ", "))
+	}
 	return factory.New().WithSync(c.sync).WithSyncDegradedOnError(operatorClient).WithInformers(
 		configInformer.Informer(),
+		proxyInformer.Informer(),
 		secretInformer.Informer(),
 		nodeInformer.Informer(),
 		pvInformer.Informer(),
+		pvcInformer.Informer(),
 		scInformer.Informer(),
+		vaInformer.Informer(),
 		cloudConfigMapInformer.Informer(),
+		csiSecretInformer.Informer(),
+		configMapInformer.Informer(),
 	).ToController(controllerName, c.eventRecorder)
 }
 
@@ -142,6 +213,8 @@ func (c *vSphereProblemDetectorController) sync(ctx context.Context, syncCtx fac
 		return nil
 	}
 
+	c.applyCheckConfig()
+	c.detectCredentialsRotation()
 	clusterInfo := util.NewClusterInfo()
 	delay, lastCheckResult, checkPerformed := c.runSyncChecks(ctx, clusterInfo)
 
@@ -209,13 +282,17 @@ func (c *vSphereProblemDetectorController) runSyncChecks(ctx context.Context, cl
 
 func (c *vSphereProblemDetectorController) checkForDeprecation(clusterInfo *util.ClusterInfo) (bool, string) {
 	esxiVersions := clusterInfo.GetHostVersions()
+	hostNames := clusterInfo.GetHostNames()
 	for host, esxiVersion := range esxiVersions {
-		hasMinimum, err := isMinimumVersion(minHostVersion, esxiVersion.APIVersion)
+		hasMinimum, err := isMinimumVersion(check.MinESXiVersion, esxiVersion.APIVersion)
 		if err != nil {
 			klog.Errorf("error parsing host version: %v", err)
 			continue
 		}
 		if !hasMinimum {
+			if name, found := hostNames[host]; found {
+				host = name
+			}
 			return true, fmt.Sprintf("host %s is on esxi version %s", host, esxiVersion.APIVersion)
 		}
 	}
@@ -227,13 +304,13 @@ func (c *vSphereProblemDetectorController) checkForDeprecation(clusterInfo *util
 			klog.Errorf("error parsing hardware version %s: %v", hwVersion, err)
 			continue
 		}
-		if versionInt < minHardwareVersion {
+		if versionInt < int64(*check.MinHardwareVersion) {
 			return true, fmt.Sprintf("one or more VMs are on hardware version %s", hwVersion)
 		}
 	}
 
 	_, vcenterAPIVersion := clusterInfo.GetVCenterVersion()
-	hasMinimum, err := isMinimumVersion(minVCenterVersion, vcenterAPIVersion)
+	hasMinimum, err := isMinimumVersion(check.MinVCenterVersion, vcenterAPIVersion)
 	if err != nil {
 		klog.Errorf("error parsing vcenter version: %v", err)
 	}
@@ -274,6 +351,10 @@ func (c *vSphereProblemDetectorController) runChecks(ctx context.Context, cluste
 	nextErrorDelay := c.backoff.Step()
 	c.lastCheck = time.Now()
 
+	if zone := c.zone(); zone != "" {
+		klog.V(2).Infof("Running checks only for nodes in zone %s", zone)
+	}
+	c.schedule.startRound(c.lastCheck, c.checkNames(), c.checkConfig.checkInterval)
 	checker := c.checkerFunc(c)
 	resultCollector, err := checker.runChecks(ctx, clusterInfo)
 	if err != nil {
@@ -282,7 +363,21 @@ func (c *vSphereProblemDetectorController) runChecks(ctx context.Context, cluste
 	klog.V(4).Infof("All checks complete")
 
 	results, checkError := resultCollector.Collect()
+	c.schedule.finishRound(c.lastCheck, resultCollector.Results())
 	c.reportResults(results)
+	c.recordFailureEvents(resultCollector.Results())
+	reportCheckStatus(results)
+	reportClusterCheckErrors(resultCollector.Results())
+	c.saveResults(ctx, results)
+	report := resultCollector.Report(c.lastCheck, time.Now(), c.zone())
+	if c.reportStore != nil {
+		c.reportStore.Save(report)
+	}
+	if *checkReportFile != "" {
+		if err := writeCheckReport(*checkReportFile, report); err != nil {
+			klog.Errorf("Failed to write check report to %s: %s", *checkReportFile, err)
+		}
+	}
 	var nextDelay time.Duration
 	if checkError != nil {
 		// Use exponential backoff
@@ -290,22 +385,145 @@ func (c *vSphereProblemDetectorController) runChecks(ctx context.Context, cluste
 	} else {
 		// Reset the backoff on success
 		c.backoff = defaultBackoff
-		// Delay after success is after the maximum backoff
-		// (i.e. retry as slow as allowed).
-		nextDelay = defaultBackoff.Cap
+		// Delay after success is the check interval, defaultBackoff.Cap by default
+		// (i.e. retry as slow as allowed), or shorter when a check is due earlier.
+		nextDelay = c.schedule.nextRun(time.Now(), c.checkNames(), c.checkConfig.checkInterval, c.checkConfig.checkInterval(""))
 	}
 	return nextDelay, checkError
 }
 
-// reportResults sends events for all checks.
+// zone returns the zone the checks are limited to, empty when all nodes are checked.
+func (c *vSphereProblemDetectorController) zone() string {
+	if c.checkOptions == nil {
+		return ""
+	}
+	return c.checkOptions.Zone
+}
+
+// checkNames returns names of all cluster and node checks.
+func (c *vSphereProblemDetectorController) checkNames() []string {
+	names := make([]string, 0, len(c.clusterChecks)+len(c.nodeChecks))
+	for name := range c.clusterChecks {
+		names = append(names, name)
+	}
+	for _, nodeCheck := range c.nodeChecks {
+		names = append(names, nodeCheck.Name())
+	}
+	return names
+}
+
+// instrumentClusterChecks wraps all checks with logging and metrics.
+func instrumentClusterChecks(checks map[string]check.ClusterCheck) map[string]check.ClusterCheck {
+	instrumented := make(map[string]check.ClusterCheck, len(checks))
+	for name, checkFunc := range checks {
+		instrumented[name] = check.WithInstrumentation(name, checkFunc)
+	}
+	return instrumented
+}
+
+// instrumentNodeChecks wraps all checks with logging and metrics.
+func instrumentNodeChecks(checks []check.NodeCheck) []check.NodeCheck {
+	instrumented := make([]check.NodeCheck, 0, len(checks))
+	for _, nodeCheck := range checks {
+		instrumented = append(instrumented, check.WithNodeInstrumentation(nodeCheck))
+	}
+	return instrumented
+}
+
+// reportResults sends events for all checks. Disabled checks get an event only in the round they become disabled.
 func (c *vSphereProblemDetectorController) reportResults(results []checkResult) {
+	disabled := make(map[string]bool)
 	for _, res := range results {
-		if res.Error != nil {
+		if res.Disabled {
+			disabled[res.Name] = true
+			if !c.disabledChecks[res.Name] {
+				c.eventRecorder.Eventf("DisabledVSphere"+res.Name, "Check disabled")
+			}
+			continue
+		}
+		if res.TimedOut {
+			c.eventRecorder.Warningf("TimedOutVSphere"+res.Name, res.Error.Error())
+		} else if res.Error != nil {
 			c.eventRecorder.Warningf("FailedVSphere"+res.Name, res.Error.Error())
 		} else {
 			c.eventRecorder.Eventf("SucceededVSphere"+res.Name, "Check succeeded")
 		}
 	}
+	c.disabledChecks = disabled
+}
+
+// reportCheckStatus sets status metric of all checks. Each check has all statuses reported,
+// the one of the last round with value 1 and the others with 0.
+func reportCheckStatus(results []checkResult) {
+	for _, res := range results {
+		status := getCheckStatus(res)
+		for _, s := range checkStatuses {
+			value := 0.0
+			if s == status {
+				value = 1
+			}
+			checkStatusMetric.WithLabelValues(res.Name, s).Set(value)
+		}
+	}
+}
+
+// reportClusterCheckErrors sets vsphere_cluster_check_errors of all cluster checks in results of a round,
+// 1 when the check failed in at least one vCenter, 0 otherwise. Node check results are ignored.
+func reportClusterCheckErrors(results []checkResult) {
+	failed := make(map[string]bool)
+	for _, res := range results {
+		if res.Node != "" {
+			continue
+		}
+		failed[res.Name] = failed[res.Name] || (!res.Disabled && res.Error != nil)
+	}
+	for name, f := range failed {
+		value := 0.0
+		if f {
+			value = 1
+		}
+		clusterCheckErrrorMetric.WithLabelValues(name).Set(value)
+	}
+}
+
+// getCheckStatus returns one of checkStatuses for the check result.
+func getCheckStatus(res checkResult) string {
+	switch {
+	case res.Disabled:
+		return checkStatusDisabled
+	case res.TimedOut:
+		return checkStatusTimeout
+	case res.Error != nil:
+		return checkStatusFailed
+	default:
+		return checkStatusPassed
+	}
+}
+
+// saveResults stores results of the last round of checks in the result store.
+func (c *vSphereProblemDetectorController) saveResults(ctx context.Context, results []checkResult) {
+	if c.resultStore == nil {
+		return
+	}
+	run := &check.CheckRun{
+		StartTime:  c.lastCheck,
+		FinishTime: time.Now(),
+		Zone:       c.zone(),
+		Results:    make(map[string]check.CheckRunResult),
+	}
+	for _, res := range results {
+		runResult := check.CheckRunResult{
+			Disabled: res.Disabled,
+			TimedOut: res.TimedOut,
+		}
+		if res.Error != nil {
+			runResult.Errors = []string{res.Error.Error()}
+		}
+		run.Results[res.Name] = runResult
+	}
+	if err := c.resultStore.Save(ctx, run); err != nil {
+		klog.Errorf("Failed to save check results: %s", err)
+	}
 }
 
 func (c *vSphereProblemDetectorController) platformSupported() (bool, error) {
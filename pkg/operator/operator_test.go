@@ -3,6 +3,7 @@ package operator
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,9 +14,12 @@ import (
 	opinformers "github.com/openshift/client-go/operator/informers/externalversions"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
 	"github.com/openshift/vsphere-problem-detector/pkg/util"
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/component-base/metrics/legacyregistry"
 )
 
 func TestCheckForDeprecation(t *testing.T) {
@@ -23,6 +27,8 @@ func TestCheckForDeprecation(t *testing.T) {
 		name         string
 		clusterInfo  *util.ClusterInfo
 		isDeprecated bool
+		// minHardwareVersion overrides check.MinHardwareVersion when set
+		minHardwareVersion int
 	}{
 		{
 			name: "on Vsphere 6.5 platform with older HW version",
@@ -120,11 +126,42 @@ func TestCheckForDeprecation(t *testing.T) {
 			}),
 			isDeprecated: true,
 		},
+		{
+			name: "on Vsphere 7.0.1 platform with hardware version at configured minimum",
+			clusterInfo: util.MakeClusterInfo(map[string]string{
+				"host_name":           "foo.bar",
+				"host_version":        "7.0.1",
+				"host_api_version":    "7.0.1",
+				"vcenter_api_version": "7.0.1",
+				"vcenter_version":     "7.0.1",
+				"hw_version":          "vmx-13",
+			}),
+			isDeprecated:       false,
+			minHardwareVersion: 13,
+		},
+		{
+			name: "on Vsphere 7.0.1 platform with hardware version below configured minimum",
+			clusterInfo: util.MakeClusterInfo(map[string]string{
+				"host_name":           "foo.bar",
+				"host_version":        "7.0.1",
+				"host_api_version":    "7.0.1",
+				"vcenter_api_version": "7.0.1",
+				"vcenter_version":     "7.0.1",
+				"hw_version":          "vmx-15",
+			}),
+			isDeprecated:       true,
+			minHardwareVersion: 17,
+		},
 	}
 
 	for _, tc := range tests {
 		info := tc.clusterInfo
 		t.Run(tc.name, func(t *testing.T) {
+			if tc.minHardwareVersion != 0 {
+				oldMin := *check.MinHardwareVersion
+				*check.MinHardwareVersion = tc.minHardwareVersion
+				defer func() { *check.MinHardwareVersion = oldMin }()
+			}
 			vsphereProblemOperator := &vSphereProblemDetectorController{}
 			result, _ := vsphereProblemOperator.checkForDeprecation(info)
 			if result != tc.isDeprecated {
@@ -412,3 +449,56 @@ func (t testInfraLister) List(selector labels.Selector) (ret []*configv1.Infrast
 func (t testInfraLister) Get(name string) (*configv1.Infrastructure, error) {
 	return testInfra.DeepCopy(), nil
 }
+
+func TestReportCheckStatus(t *testing.T) {
+	results := []checkResult{
+		{Name: "CheckPassed"},
+		{Name: "CheckFailed", Error: fmt.Errorf("error")},
+		{Name: "CheckTimedOut", Error: &check.CheckTimeoutError{Timeout: time.Second}, TimedOut: true},
+		{Name: "CheckDisabled", Disabled: true},
+	}
+	expectedMetrics := `
+# HELP vsphere_problem_detector_check_status [ALPHA] Status of vSphere checks in the last round of checks performed by vsphere-problem-detector. Value of 1 means - a particular check has the status (passed, failed, timeout or disabled), 0 otherwise. A node-level check fails when it fails on at least one node.
+# TYPE vsphere_problem_detector_check_status gauge
+vsphere_problem_detector_check_status{check="CheckDisabled",status="disabled"} 1
+vsphere_problem_detector_check_status{check="CheckDisabled",status="failed"} 0
+vsphere_problem_detector_check_status{check="CheckDisabled",status="passed"} 0
+vsphere_problem_detector_check_status{check="CheckDisabled",status="timeout"} 0
+vsphere_problem_detector_check_status{check="CheckFailed",status="disabled"} 0
+vsphere_problem_detector_check_status{check="CheckFailed",status="failed"} 1
+vsphere_problem_detector_check_status{check="CheckFailed",status="passed"} 0
+vsphere_problem_detector_check_status{check="CheckFailed",status="timeout"} 0
+vsphere_problem_detector_check_status{check="CheckPassed",status="disabled"} 0
+vsphere_problem_detector_check_status{check="CheckPassed",status="failed"} 0
+vsphere_problem_detector_check_status{check="CheckPassed",status="passed"} 1
+vsphere_problem_detector_check_status{check="CheckPassed",status="timeout"} 0
+vsphere_problem_detector_check_status{check="CheckTimedOut",status="disabled"} 0
+vsphere_problem_detector_check_status{check="CheckTimedOut",status="failed"} 0
+vsphere_problem_detector_check_status{check="CheckTimedOut",status="passed"} 0
+vsphere_problem_detector_check_status{check="CheckTimedOut",status="timeout"} 1
+`
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+
+	reportCheckStatus(results)
+
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_problem_detector_check_status"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+
+	// A check that starts passing resets its failed status
+	legacyregistry.Reset()
+	reportCheckStatus([]checkResult{{Name: "CheckFailed", Error: fmt.Errorf("error")}})
+	reportCheckStatus([]checkResult{{Name: "CheckFailed"}})
+	expectedMetrics = `
+# HELP vsphere_problem_detector_check_status [ALPHA] Status of vSphere checks in the last round of checks performed by vsphere-problem-detector. Value of 1 means - a particular check has the status (passed, failed, timeout or disabled), 0 otherwise. A node-level check fails when it fails on at least one node.
+# TYPE vsphere_problem_detector_check_status gauge
+vsphere_problem_detector_check_status{check="CheckFailed",status="disabled"} 0
+vsphere_problem_detector_check_status{check="CheckFailed",status="failed"} 0
+vsphere_problem_detector_check_status{check="CheckFailed",status="passed"} 1
+vsphere_problem_detector_check_status{check="CheckFailed",status="timeout"} 0
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_problem_detector_check_status"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+}
@@ -2,6 +2,8 @@ package operator
 
 import (
 	"context"
+	"flag"
+	"sort"
 	"sync"
 
 	"github.com/openshift/vsphere-problem-detector/pkg/check"
@@ -9,6 +11,10 @@ import (
 	"k8s.io/klog/v2"
 )
 
+var (
+	checkWorkers = flag.Int("check-workers", parallelVSPhereCalls, "Number of checks that run in parallel. Node checks of each node run in a single worker, so nodes are checked in parallel.")
+)
+
 // CheckThreadPool runs individual functions (presumably checks) as go routines.
 // It makes sure that only limited number of functions actually run in parallel.
 type CheckThreadPool struct {
@@ -74,19 +80,43 @@ func (r *CheckThreadPool) Wait(ctx context.Context) error {
 // as the checks run in parallel.
 type ResultCollector struct {
 	resultsMutex sync.Mutex
-	// map check name -> list of all check results incl. all nil errors.
-	// For cluster level checks, only one error is expected, for node
-	// level checks, every node will have a single error here.
-	results map[string][]error
+	// set of checks that were disabled
+	disabled map[string]bool
+	// set of checks that timed out, at least on one node for node checks
+	timedOut map[string]bool
+	// all results in the order they were added, including results of each node
+	all []checkResult
+	// map node name -> how the node was matched to its VM
+	vmMatches map[string]string
+	// accessibility of datastores on ESXi hosts, nil when no check reported it
+	datastoreConnectivity *check.DatastoreConnectivityMatrix
 }
 
 // NewResultCollector creates a new ResultCollector
 func NewResultsCollector() *ResultCollector {
 	return &ResultCollector{
-		results: make(map[string][]error),
+		disabled:  make(map[string]bool),
+		timedOut:  make(map[string]bool),
+		vmMatches: make(map[string]string),
 	}
 }
 
+// AddNodeVMMatch stores how a node was matched to its VM, nodeVMMatchNone when it was not matched.
+func (r *ResultCollector) AddNodeVMMatch(node, match string) {
+	r.resultsMutex.Lock()
+	defer r.resultsMutex.Unlock()
+
+	r.vmMatches[node] = match
+}
+
+// AddDatastoreConnectivityMatrix stores accessibility of datastores on ESXi hosts reported by a check.
+func (r *ResultCollector) AddDatastoreConnectivityMatrix(matrix *check.DatastoreConnectivityMatrix) {
+	r.resultsMutex.Lock()
+	defer r.resultsMutex.Unlock()
+
+	r.datastoreConnectivity = matrix
+}
+
 // AddResult stores result of a single check.
 // It is allowed to store result of a single check
 // several times, e.g. once for each node.
@@ -97,37 +127,81 @@ func (r *ResultCollector) AddResult(res checkResult) {
 	r.resultsMutex.Lock()
 	defer r.resultsMutex.Unlock()
 
-	oldRes, found := r.results[name]
-	if !found {
-		r.results[name] = []error{res.Error}
+	r.all = append(r.all, res)
+	if res.Disabled {
+		r.disabled[name] = true
 		return
 	}
+	if res.TimedOut {
+		r.timedOut[name] = true
+	}
+}
+
+// Results returns all results in the order they were added, including results of each node.
+func (r *ResultCollector) Results() []checkResult {
+	r.resultsMutex.Lock()
+	defer r.resultsMutex.Unlock()
 
-	r.results[name] = append(oldRes, res.Error)
+	return append([]checkResult(nil), r.all...)
 }
 
 // Collect returns currently accumulated checks.
 // It merges results of the same check into a single error
 // It returns status of each check and overall
 // succeeded / failed status of all checks.
+// Checks are sorted by name and their errors by node name and vCenter, so the result does not depend
+// on the order in which the checks finished.
 func (r *ResultCollector) Collect() ([]checkResult, error) {
 	r.resultsMutex.Lock()
 	defer r.resultsMutex.Unlock()
 
+	// map check name -> all results of the check, one for each node for node checks
+	results := make(map[string][]checkResult)
+	for _, res := range r.all {
+		if !res.Disabled {
+			results[res.Name] = append(results[res.Name], res)
+		}
+	}
+	var names []string
+	for name := range results {
+		names = append(names, name)
+	}
+	for name := range r.disabled {
+		if _, found := results[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
 	var allErrs []error
 	var checkResults []checkResult
-	for name, checkErrors := range r.results {
+	for _, name := range names {
+		if _, found := results[name]; !found {
+			checkResults = append(checkResults, checkResult{
+				Name:     name,
+				Disabled: true,
+			})
+			continue
+		}
 		res := checkResult{
-			Name: name,
+			Name:     name,
+			TimedOut: r.timedOut[name],
 		}
+		nodeResults := results[name]
+		sort.SliceStable(nodeResults, func(i, j int) bool {
+			if nodeResults[i].Node != nodeResults[j].Node {
+				return nodeResults[i].Node < nodeResults[j].Node
+			}
+			return nodeResults[i].VCenter < nodeResults[j].VCenter
+		})
 		var errs []error
 		// Filter out all nil errors
-		for _, err := range checkErrors {
-			if err == nil {
+		for _, nodeRes := range nodeResults {
+			if nodeRes.Error == nil {
 				continue
 			}
-			errs = append(errs, err)
-			allErrs = append(allErrs, err)
+			errs = append(errs, nodeRes.Error)
+			allErrs = append(allErrs, nodeRes.Error)
 		}
 		if len(errs) == 0 {
 			res.Error = nil
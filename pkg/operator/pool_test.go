@@ -2,9 +2,13 @@ package operator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
 )
 
 func TestThreadPool(t *testing.T) {
@@ -39,3 +43,54 @@ func runSomeTask(taskID string) {
 	fmt.Printf("Running task: %s\n", taskID)
 	time.Sleep(5 * time.Second)
 }
+
+func TestResultCollectorTimedOut(t *testing.T) {
+	collector := NewResultsCollector()
+	collector.AddResult(checkResult{Name: "ClusterCheck", Error: &check.CheckTimeoutError{Timeout: time.Second}, TimedOut: true})
+	collector.AddResult(checkResult{Name: "NodeCheck"})
+	collector.AddResult(checkResult{Name: "NodeCheck", Error: &check.CheckTimeoutError{Node: "node2", Timeout: time.Second}, TimedOut: true})
+	collector.AddResult(checkResult{Name: "FailingCheck", Error: errors.New("fake error")})
+
+	results, err := collector.Collect()
+	if err == nil {
+		t.Errorf("Expected error, got none")
+	}
+	for _, res := range results {
+		expectTimedOut := res.Name != "FailingCheck"
+		if res.TimedOut != expectTimedOut {
+			t.Errorf("Expected check %s TimedOut %t, got %t", res.Name, expectTimedOut, res.TimedOut)
+		}
+		if res.Error == nil {
+			t.Errorf("Expected check %s to fail", res.Name)
+		}
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected 3 results, got %d", len(results))
+	}
+}
+
+func TestResultCollectorOrder(t *testing.T) {
+	collector := NewResultsCollector()
+	collector.AddResult(checkResult{Name: "NodeCheck", Node: "node3", Error: errors.New("node3 failed")})
+	collector.AddResult(checkResult{Name: "ClusterCheck"})
+	collector.AddResult(checkResult{Name: "NodeCheck", Node: "node1", Error: errors.New("node1 failed")})
+	collector.AddResult(checkResult{Name: "DisabledCheck", Disabled: true})
+	collector.AddResult(checkResult{Name: "NodeCheck", Node: "node2"})
+
+	results, _ := collector.Collect()
+	var names []string
+	for _, res := range results {
+		names = append(names, res.Name)
+	}
+	expectedNames := []string{"ClusterCheck", "DisabledCheck", "NodeCheck"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("Expected checks %v, got %v", expectedNames, names)
+	}
+	if !results[1].Disabled {
+		t.Errorf("Expected DisabledCheck to be disabled")
+	}
+	expectedError := "node1 failed;\nnode3 failed"
+	if results[2].Error == nil || results[2].Error.Error() != expectedError {
+		t.Errorf("Expected NodeCheck error %q, got %v", expectedError, results[2].Error)
+	}
+}
@@ -0,0 +1,102 @@
+package operator
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/vmware/govmomi/vim25/soap"
+	"golang.org/x/net/http/httpproxy"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	proxyName = "cluster"
+	// trustedCABundleKey is the key of the CA bundle in the ConfigMap referenced by Proxy spec.trustedCA.
+	trustedCABundleKey = "ca-bundle.crt"
+)
+
+// clusterProxy is the cluster-wide egress proxy and the CA bundle trusted by the cluster.
+type clusterProxy struct {
+	httpProxy  string
+	httpsProxy string
+	noProxy    string
+	// caBundle are PEM encoded certificates trusted in addition to the system ones, empty when not configured.
+	caBundle string
+}
+
+// getClusterProxy returns the cluster-wide proxy from the status of Proxy "cluster" and the CA bundle from
+// the ConfigMap in its spec.trustedCA. It returns nil when the cluster has no Proxy.
+func (c *vSphereChecker) getClusterProxy() (*clusterProxy, error) {
+	if c.controller.proxyLister == nil {
+		return nil, nil
+	}
+	proxy, err := c.controller.proxyLister.Get(proxyName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get cluster proxy: %s", err)
+	}
+	cfg := &clusterProxy{
+		httpProxy:  proxy.Status.HTTPProxy,
+		httpsProxy: proxy.Status.HTTPSProxy,
+		noProxy:    proxy.Status.NoProxy,
+	}
+	if name := proxy.Spec.TrustedCA.Name; name != "" {
+		cm, err := c.controller.cloudConfigMapLister.ConfigMaps(cloudConfigNamespace).Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get trusted CA bundle: %s", err)
+		}
+		bundle, found := cm.Data[trustedCABundleKey]
+		if !found {
+			return nil, fmt.Errorf("trusted CA bundle %s/%s does not contain key %q", cloudConfigNamespace, name, trustedCABundleKey)
+		}
+		cfg.caBundle = bundle
+	}
+	klog.V(4).Infof("Using cluster proxy http: %q, https: %q, no proxy: %q, trusted CA bundle: %t", cfg.httpProxy, cfg.httpsProxy, cfg.noProxy, cfg.caBundle != "")
+	return cfg, nil
+}
+
+// proxyFunc returns http.Transport Proxy function that uses the cluster proxy, or the proxy from
+// environment variables when p is nil.
+func (p *clusterProxy) proxyFunc() func(*http.Request) (*url.URL, error) {
+	if p == nil {
+		return http.ProxyFromEnvironment
+	}
+	proxyURL := (&httpproxy.Config{
+		HTTPProxy:  p.httpProxy,
+		HTTPSProxy: p.httpsProxy,
+		NoProxy:    p.noProxy,
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyURL(req.URL)
+	}
+}
+
+// configureClient makes the SOAP client use the cluster proxy and trust the CA bundle of the cluster.
+// Clients of vCenter services created from the client share its TLS configuration, they use its proxy
+// only when configured by check.UseClientProxy. http.DefaultTransport is never changed, it is shared
+// with all other clients of the process.
+func (p *clusterProxy) configureClient(client *soap.Client) error {
+	if p == nil {
+		return nil
+	}
+	transport := client.DefaultTransport()
+	transport.Proxy = p.proxyFunc()
+	if p.caBundle == "" {
+		return nil
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		klog.V(2).Infof("Failed to load system CAs, trusting only the CA bundle of the cluster: %s", err)
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM([]byte(p.caBundle)) {
+		return fmt.Errorf("no valid certificate found in trusted CA bundle")
+	}
+	transport.TLSClientConfig.RootCAs = pool
+	return nil
+}
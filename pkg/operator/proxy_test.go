@@ -0,0 +1,208 @@
+package operator
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	ocpv1 "github.com/openshift/api/config/v1"
+	infralister "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestGetClusterProxy(t *testing.T) {
+	proxy := &ocpv1.Proxy{
+		ObjectMeta: metav1.ObjectMeta{Name: proxyName},
+		Spec: ocpv1.ProxySpec{
+			TrustedCA: ocpv1.ConfigMapNameReference{Name: "user-ca-bundle"},
+		},
+		Status: ocpv1.ProxyStatus{
+			HTTPProxy:  "http://proxy.example.com:3128",
+			HTTPSProxy: "http://proxy.example.com:3129",
+			NoProxy:    ".cluster.local,vcenter.internal",
+		},
+	}
+	caBundle := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "user-ca-bundle", Namespace: cloudConfigNamespace},
+		Data:       map[string]string{trustedCABundleKey: "bundle"},
+	}
+
+	tests := []struct {
+		name          string
+		proxy         *ocpv1.Proxy
+		configMap     *v1.ConfigMap
+		expectedProxy *clusterProxy
+		expectedError string
+	}{
+		{
+			name: "no proxy",
+		},
+		{
+			name:      "proxy with CA bundle",
+			proxy:     proxy,
+			configMap: caBundle,
+			expectedProxy: &clusterProxy{
+				httpProxy:  "http://proxy.example.com:3128",
+				httpsProxy: "http://proxy.example.com:3129",
+				noProxy:    ".cluster.local,vcenter.internal",
+				caBundle:   "bundle",
+			},
+		},
+		{
+			name:          "missing CA bundle",
+			proxy:         proxy,
+			expectedError: `failed to get trusted CA bundle: configmap "user-ca-bundle" not found`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proxyIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if test.proxy != nil {
+				proxyIndexer.Add(test.proxy)
+			}
+			cmIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			if test.configMap != nil {
+				cmIndexer.Add(test.configMap)
+			}
+			checker := &vSphereChecker{
+				controller: &vSphereProblemDetectorController{
+					proxyLister:          infralister.NewProxyLister(proxyIndexer),
+					cloudConfigMapLister: corelister.NewConfigMapLister(cmIndexer),
+				},
+			}
+
+			clusterProxy, err := checker.getClusterProxy()
+
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			if errString != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, errString)
+			}
+			if test.expectedProxy == nil {
+				if clusterProxy != nil {
+					t.Errorf("Expected no proxy, got %+v", clusterProxy)
+				}
+				return
+			}
+			if clusterProxy == nil || *clusterProxy != *test.expectedProxy {
+				t.Errorf("Expected proxy %+v, got %+v", test.expectedProxy, clusterProxy)
+			}
+		})
+	}
+}
+
+func TestClusterProxyFunc(t *testing.T) {
+	proxy := &clusterProxy{
+		httpsProxy: "http://proxy.example.com:3129",
+		noProxy:    ".cluster.local,vcenter.internal",
+	}
+	proxyFunc := proxy.proxyFunc()
+
+	tests := []struct {
+		url           string
+		expectedProxy string
+	}{
+		{
+			url:           "https://vcenter.example.com/sdk",
+			expectedProxy: "http://proxy.example.com:3129",
+		},
+		{
+			url: "https://vcenter.internal/sdk",
+		},
+		{
+			url: "https://api.cluster.local/sdk",
+		},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodPost, test.url, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %s", err)
+		}
+		proxyURL, err := proxyFunc(req)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.url, err)
+			continue
+		}
+		got := ""
+		if proxyURL != nil {
+			got = proxyURL.String()
+		}
+		if got != test.expectedProxy {
+			t.Errorf("%s: expected proxy %q, got %q", test.url, test.expectedProxy, got)
+		}
+	}
+}
+
+func TestClusterProxyConfigureClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %s", err)
+	}
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	// Without the CA bundle, the server certificate is not trusted.
+	client := soap.NewClient(serverURL, false)
+	if _, err := (&http.Client{Transport: client.DefaultTransport()}).Get(server.URL); err == nil {
+		t.Errorf("Expected error without trusted CA bundle")
+	}
+
+	client = soap.NewClient(serverURL, false)
+	proxy := &clusterProxy{caBundle: string(caBundle)}
+	if err := proxy.configureClient(client); err != nil {
+		t.Fatalf("Failed to configure client: %s", err)
+	}
+	res, err := (&http.Client{Transport: client.DefaultTransport()}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the server to be trusted with the CA bundle, got: %s", err)
+	}
+	res.Body.Close()
+
+	proxy = &clusterProxy{caBundle: "not a certificate"}
+	err = proxy.configureClient(soap.NewClient(serverURL, false))
+	if err == nil || err.Error() != "no valid certificate found in trusted CA bundle" {
+		t.Errorf("Expected invalid CA bundle error, got %v", err)
+	}
+}
+
+func TestClusterProxyServiceClient(t *testing.T) {
+	serverURL, err := url.Parse("https://vcenter.example.com/sdk")
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %s", err)
+	}
+	client := soap.NewClient(serverURL, true)
+	proxy := &clusterProxy{httpsProxy: "http://proxy.example.com:3129"}
+	if err := proxy.configureClient(client); err != nil {
+		t.Fatalf("Failed to configure client: %s", err)
+	}
+
+	serviceClient := client.NewServiceClient("/pbm", "pbm")
+	check.UseClientProxy(serviceClient, &vim25.Client{Client: client})
+
+	req, err := http.NewRequest(http.MethodGet, "https://vcenter.example.com/pbm", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %s", err)
+	}
+	proxyURL, err := serviceClient.DefaultTransport().Proxy(req)
+	if err != nil || proxyURL == nil || proxyURL.String() != "http://proxy.example.com:3129" {
+		t.Errorf("Expected the service client to use the cluster proxy, got %v, %v", proxyURL, err)
+	}
+	// The process-wide transport is not changed.
+	if defaultProxy := http.DefaultTransport.(*http.Transport).Proxy; defaultProxy != nil {
+		if proxyURL, _ := defaultProxy(req); proxyURL != nil && proxyURL.String() == "http://proxy.example.com:3129" {
+			t.Errorf("Expected http.DefaultTransport not to use the cluster proxy")
+		}
+	}
+}
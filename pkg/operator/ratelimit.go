@@ -0,0 +1,52 @@
+package operator
+
+import (
+	"context"
+	"flag"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	"github.com/vmware/govmomi/vim25/soap"
+	"golang.org/x/time/rate"
+)
+
+var (
+	vCenterAPIQPS   = flag.Float64("vcenter-api-qps", 20, "Maximum average number of vSphere API calls per second made by all checks to a single vCenter. The limit is disabled when zero.")
+	vCenterAPIBurst = flag.Int("vcenter-api-burst", 40, "Maximum number of vSphere API calls made by all checks to a single vCenter at once, above vcenter-api-qps.")
+)
+
+// rateLimitedRoundTripper limits the rate of vSphere API calls made through a client. All checks share
+// the client of a vCenter, so they share the limit too. Calls with check.WithoutRateLimit context are
+// not limited.
+type rateLimitedRoundTripper struct {
+	soap.RoundTripper
+	limiter *rate.Limiter
+}
+
+var _ soap.RoundTripper = &rateLimitedRoundTripper{}
+
+// newRateLimitedRoundTripper returns rt limited by vcenter-api-qps and vcenter-api-burst, or rt itself
+// when the limit is disabled.
+func newRateLimitedRoundTripper(rt soap.RoundTripper) soap.RoundTripper {
+	if *vCenterAPIQPS <= 0 {
+		return rt
+	}
+	burst := *vCenterAPIBurst
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimitedRoundTripper{
+		RoundTripper: rt,
+		limiter:      rate.NewLimiter(rate.Limit(*vCenterAPIQPS), burst),
+	}
+}
+
+func (r *rateLimitedRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	if check.IsRateLimitExempt(ctx) {
+		return r.RoundTripper.RoundTrip(ctx, req, res)
+	}
+	// Wait returns an error when ctx expires before the call is allowed.
+	if err := r.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return r.RoundTripper.RoundTrip(ctx, req, res)
+}
@@ -0,0 +1,91 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	"github.com/vmware/govmomi/vim25/soap"
+	"golang.org/x/time/rate"
+)
+
+// countingRoundTripper counts vSphere API calls.
+type countingRoundTripper struct {
+	calls int
+}
+
+func (c *countingRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	c.calls++
+	return nil
+}
+
+func TestRateLimitedRoundTripper(t *testing.T) {
+	// Stage
+	counter := &countingRoundTripper{}
+	rt := &rateLimitedRoundTripper{
+		RoundTripper: counter,
+		limiter:      rate.NewLimiter(rate.Every(time.Hour), 2),
+	}
+
+	// Act
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	var errs []error
+	for i := 0; i < 3; i++ {
+		errs = append(errs, rt.RoundTrip(ctx, nil, nil))
+	}
+
+	// Assert
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("Expected the burst of calls to succeed, got %v", errs[0:2])
+	}
+	if errs[2] == nil {
+		t.Errorf("Expected the call above the limit to fail, got none")
+	}
+	if counter.calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", counter.calls)
+	}
+}
+
+func TestRateLimitedRoundTripperExempt(t *testing.T) {
+	// Stage
+	counter := &countingRoundTripper{}
+	rt := &rateLimitedRoundTripper{
+		RoundTripper: counter,
+		limiter:      rate.NewLimiter(rate.Every(time.Hour), 1),
+	}
+
+	// Act
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	var errs []error
+	for i := 0; i < 3; i++ {
+		errs = append(errs, rt.RoundTrip(check.WithoutRateLimit(ctx), nil, nil))
+	}
+	limitedErr := rt.RoundTrip(ctx, nil, nil)
+
+	// Assert
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Expected exempt call %d to succeed, got %s", i, err)
+		}
+	}
+	if limitedErr != nil {
+		t.Errorf("Expected exempt calls to leave the burst to other calls, got %s", limitedErr)
+	}
+	if counter.calls != 4 {
+		t.Errorf("Expected 4 calls, got %d", counter.calls)
+	}
+}
+
+func TestNewRateLimitedRoundTripperDisabled(t *testing.T) {
+	oldQPS := *vCenterAPIQPS
+	defer func() { *vCenterAPIQPS = oldQPS }()
+	*vCenterAPIQPS = 0
+
+	counter := &countingRoundTripper{}
+	if rt := newRateLimitedRoundTripper(counter); rt != counter {
+		t.Errorf("Expected no rate limit with zero vcenter-api-qps")
+	}
+}
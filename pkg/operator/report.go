@@ -0,0 +1,140 @@
+package operator
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+)
+
+const (
+	reportTargetCluster = "cluster"
+	reportTargetNode    = "node"
+)
+
+var (
+	// checkReportFile is the path where the JSON report of the last round of checks is written.
+	checkReportFile = flag.String("check-report-file", os.Getenv("CHECK_REPORT_FILE"), "Path of a file where to write results of the last round of checks as JSON, for consumption by other tools. Defaults to CHECK_REPORT_FILE environment variable. The report is not written when empty.")
+)
+
+// CheckReport is a machine readable report of a single round of checks.
+type CheckReport struct {
+	// StartTime is the time when the round started.
+	StartTime time.Time `json:"startTime"`
+	// FinishTime is the time when all checks of the round completed.
+	FinishTime time.Time `json:"finishTime"`
+	// Zone is the zone the round was limited to, empty when all nodes were checked.
+	Zone string `json:"zone,omitempty"`
+	// ClusterChecks are results of cluster checks, sorted by check name.
+	ClusterChecks []CheckReportResult `json:"clusterChecks"`
+	// NodeChecks are results of node checks grouped by node name, each sorted by check name.
+	NodeChecks map[string][]CheckReportResult `json:"nodeChecks"`
+	// NodeVMMatches is how each node was matched to its VM, by "provider_id", by "name" or "none".
+	NodeVMMatches map[string]string `json:"nodeVMMatches,omitempty"`
+	// DatastoreConnectivity is accessibility of datastores used by the cluster on ESXi hosts,
+	// empty when CheckClusterDatastoreConnectivityMatrix did not run.
+	DatastoreConnectivity *check.DatastoreConnectivityMatrix `json:"datastoreConnectivity,omitempty"`
+}
+
+// CheckReportResult is the outcome of a single check on a single target.
+type CheckReportResult struct {
+	// Name of the check.
+	Name string `json:"name"`
+	// Target is the kind of checked object, "cluster" or "node".
+	Target string `json:"target"`
+	// Node is the name of the checked node, empty for cluster checks.
+	Node string `json:"node,omitempty"`
+	// VCenter is the vCenter the check ran against, empty when the check did not run on any vCenter.
+	VCenter string `json:"vCenter,omitempty"`
+	// Outcome is one of "passed", "failed", "timeout" or "disabled".
+	Outcome string `json:"outcome"`
+	// Message is the error reported by the check, empty when the check passed.
+	Message string `json:"message,omitempty"`
+	// Timestamp is when the check started. Checks that did not run and checks that were not due
+	// in the round and report results of their previous run have the start of the round.
+	Timestamp time.Time `json:"timestamp"`
+	// DurationSeconds is how long the check ran.
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// Report returns CheckReport with all results added to the collector so far. Unlike Collect,
+// it does not merge results of node checks, each node has its own result.
+func (r *ResultCollector) Report(startTime, finishTime time.Time, zone string) *CheckReport {
+	r.resultsMutex.Lock()
+	defer r.resultsMutex.Unlock()
+
+	report := &CheckReport{
+		StartTime:             startTime,
+		FinishTime:            finishTime,
+		Zone:                  zone,
+		ClusterChecks:         []CheckReportResult{},
+		NodeChecks:            make(map[string][]CheckReportResult),
+		NodeVMMatches:         make(map[string]string),
+		DatastoreConnectivity: r.datastoreConnectivity,
+	}
+	for node, match := range r.vmMatches {
+		report.NodeVMMatches[node] = match
+	}
+	for _, res := range r.all {
+		result := CheckReportResult{
+			Name:            res.Name,
+			Target:          reportTargetCluster,
+			Node:            res.Node,
+			VCenter:         res.VCenter,
+			Outcome:         getCheckStatus(res),
+			Timestamp:       res.StartTime,
+			DurationSeconds: res.Duration.Seconds(),
+		}
+		if result.Timestamp.IsZero() {
+			result.Timestamp = startTime
+		}
+		if res.Error != nil {
+			result.Message = res.Error.Error()
+		}
+
+		if res.Node == "" {
+			report.ClusterChecks = append(report.ClusterChecks, result)
+			continue
+		}
+		result.Target = reportTargetNode
+		report.NodeChecks[res.Node] = append(report.NodeChecks[res.Node], result)
+	}
+
+	sort.Slice(report.ClusterChecks, func(i, j int) bool {
+		if report.ClusterChecks[i].Name != report.ClusterChecks[j].Name {
+			return report.ClusterChecks[i].Name < report.ClusterChecks[j].Name
+		}
+		return report.ClusterChecks[i].VCenter < report.ClusterChecks[j].VCenter
+	})
+	for _, results := range report.NodeChecks {
+		results := results
+		sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	}
+	return report
+}
+
+// writeCheckReport writes the report as JSON to the given file. The file is replaced atomically,
+// so readers never see a partially written report.
+func writeCheckReport(path string, report *CheckReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
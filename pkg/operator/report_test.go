@@ -0,0 +1,282 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/legacy-cloud-providers/vsphere"
+)
+
+// failingNodeCheck is a NodeCheck that fails on all nodes.
+type failingNodeCheck struct{}
+
+var _ check.NodeCheck = &failingNodeCheck{}
+
+func (c *failingNodeCheck) Name() string {
+	return "FailingNodeCheck"
+}
+
+func (c *failingNodeCheck) StartCheck() error {
+	return nil
+}
+
+func (c *failingNodeCheck) CheckNode(ctx *check.CheckContext, node *v1.Node, vm *mo.VirtualMachine) error {
+	return errors.New("node check failed")
+}
+
+func (c *failingNodeCheck) FinishCheck(ctx *check.CheckContext) {
+	return
+}
+
+func TestCheckReport(t *testing.T) {
+	ctx := context.TODO()
+	model := simulator.VPX()
+	defer model.Remove()
+	if err := model.Create(); err != nil {
+		t.Fatalf("Failed to create vSphere simulator: %s", err)
+	}
+	server := model.Service.NewServer()
+	defer server.Close()
+	client, err := govmomi.NewClient(ctx, server.URL, true)
+	if err != nil {
+		t.Fatalf("Failed to connect to vSphere simulator: %s", err)
+	}
+
+	finder := find.NewFinder(client.Client)
+	vm, err := finder.VirtualMachine(ctx, "/DC0/vm/DC0_H0_VM0")
+	if err != nil {
+		t.Fatalf("Failed to find VM: %s", err)
+	}
+	var vmMo mo.VirtualMachine
+	if err := vm.Properties(ctx, vm.Reference(), []string{"config.uuid"}, &vmMo); err != nil {
+		t.Fatalf("Failed to get VM UUID: %s", err)
+	}
+
+	nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	nodeIndexer.Add(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Spec:       v1.NodeSpec{ProviderID: "vsphere://" + vmMo.Config.Uuid},
+	})
+	nodeIndexer.Add(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node2"},
+		Spec:       v1.NodeSpec{ProviderID: "vsphere://00000000-0000-0000-0000-000000000000"},
+	})
+
+	controller := &vSphereProblemDetectorController{
+		nodeLister: corelister.NewNodeLister(nodeIndexer),
+		clusterChecks: map[string]check.ClusterCheck{
+			"PassingCheck":  func(ctx *check.CheckContext) error { return nil },
+			"FailingCheck":  func(ctx *check.CheckContext) error { return errors.New("cluster check failed") },
+			"DisabledCheck": func(ctx *check.CheckContext) error { return nil },
+			"TimedOutCheck": func(ctx *check.CheckContext) error { return &check.CheckTimeoutError{Timeout: time.Second} },
+		},
+		nodeChecks: []check.NodeCheck{&check.CheckNodeProviderID{}, &failingNodeCheck{}},
+		checkOptions: &check.CheckOptions{
+			EnabledChecks: map[string]bool{"DisabledCheck": false},
+		},
+	}
+	checker := &vSphereChecker{controller: controller}
+	vmConfig := &vsphere.VSphereConfig{}
+	vmConfig.Workspace.VCenterIP = "vc1"
+	vmConfig.Workspace.Datacenter = "DC0"
+	checkContext := &check.CheckContext{
+		Context:  ctx,
+		VMConfig: vmConfig,
+		VMClient: client.Client,
+	}
+
+	// Act
+	resultCollector := NewResultsCollector()
+	checkRunner := NewCheckThreadPool(parallelVSPhereCalls, channelBufferSize)
+	checker.enqueueClusterChecks(checkContext, checkRunner, resultCollector)
+	if err := checker.enqueueNodeChecks(checkContext, checkRunner, resultCollector); err != nil {
+		t.Fatalf("Failed to enqueue node checks: %s", err)
+	}
+	if err := checkRunner.Wait(ctx); err != nil {
+		t.Fatalf("Failed to wait for checks: %s", err)
+	}
+	checker.finishNodeChecks(checkContext)
+
+	report := resultCollector.Report(time.Unix(0, 0).UTC(), time.Unix(60, 0).UTC(), "")
+	// Durations and start times of checks are not stable
+	for i := range report.ClusterChecks {
+		report.ClusterChecks[i].DurationSeconds = 0
+		report.ClusterChecks[i].Timestamp = report.StartTime
+	}
+	for _, results := range report.NodeChecks {
+		for i := range results {
+			results[i].DurationSeconds = 0
+			results[i].Timestamp = report.StartTime
+		}
+	}
+
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	if err := writeCheckReport(reportFile, report); err != nil {
+		t.Fatalf("Failed to write report: %s", err)
+	}
+	data, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatalf("Failed to read report: %s", err)
+	}
+
+	// Assert
+	expectedJSON := `{
+  "startTime": "1970-01-01T00:00:00Z",
+  "finishTime": "1970-01-01T00:01:00Z",
+  "clusterChecks": [
+    {
+      "name": "DisabledCheck",
+      "target": "cluster",
+      "outcome": "disabled",
+      "timestamp": "1970-01-01T00:00:00Z",
+      "durationSeconds": 0
+    },
+    {
+      "name": "FailingCheck",
+      "target": "cluster",
+      "vCenter": "vc1",
+      "outcome": "failed",
+      "message": "cluster check failed",
+      "timestamp": "1970-01-01T00:00:00Z",
+      "durationSeconds": 0
+    },
+    {
+      "name": "PassingCheck",
+      "target": "cluster",
+      "vCenter": "vc1",
+      "outcome": "passed",
+      "timestamp": "1970-01-01T00:00:00Z",
+      "durationSeconds": 0
+    },
+    {
+      "name": "TimedOutCheck",
+      "target": "cluster",
+      "vCenter": "vc1",
+      "outcome": "timeout",
+      "message": "check timed out after 1s",
+      "timestamp": "1970-01-01T00:00:00Z",
+      "durationSeconds": 0
+    }
+  ],
+  "nodeChecks": {
+    "node1": [
+      {
+        "name": "CheckNodeProviderID",
+        "target": "node",
+        "node": "node1",
+        "vCenter": "vc1",
+        "outcome": "passed",
+        "timestamp": "1970-01-01T00:00:00Z",
+        "durationSeconds": 0
+      },
+      {
+        "name": "FailingNodeCheck",
+        "target": "node",
+        "node": "node1",
+        "vCenter": "vc1",
+        "outcome": "failed",
+        "message": "node check failed",
+        "timestamp": "1970-01-01T00:00:00Z",
+        "durationSeconds": 0
+      }
+    ],
+    "node2": [
+      {
+        "name": "CheckNodeProviderID",
+        "target": "node",
+        "node": "node2",
+        "outcome": "failed",
+        "message": "unable to find VM by UUID 00000000-0000-0000-0000-000000000000",
+        "timestamp": "1970-01-01T00:00:00Z",
+        "durationSeconds": 0
+      },
+      {
+        "name": "FailingNodeCheck",
+        "target": "node",
+        "node": "node2",
+        "outcome": "failed",
+        "message": "unable to find VM by UUID 00000000-0000-0000-0000-000000000000",
+        "timestamp": "1970-01-01T00:00:00Z",
+        "durationSeconds": 0
+      }
+    ]
+  },
+  "nodeVMMatches": {
+    "node1": "provider_id",
+    "node2": "none"
+  }
+}`
+	if string(data) != expectedJSON {
+		t.Errorf("Unexpected report:\n%s\nexpected:\n%s", string(data), expectedJSON)
+	}
+
+	var parsed CheckReport
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Errorf("Failed to parse report: %s", err)
+	}
+}
+
+func TestCheckReportDatastoreConnectivity(t *testing.T) {
+	// Stage
+	resultCollector := NewResultsCollector()
+	resultCollector.AddDatastoreConnectivityMatrix(&check.DatastoreConnectivityMatrix{
+		Hosts:      []string{"host1", "host2"},
+		Datastores: []string{"ds1"},
+		Entries: []check.DatastoreConnectivity{
+			{Host: "host1", Datastore: "ds1", Mounted: true, Accessible: true, Expected: true},
+			{Host: "host2", Datastore: "ds1", Expected: true, Reason: "not mounted"},
+		},
+	})
+
+	// Act
+	report := resultCollector.Report(time.Unix(0, 0).UTC(), time.Unix(60, 0).UTC(), "")
+	data, err := json.MarshalIndent(report.DatastoreConnectivity, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal report: %s", err)
+	}
+
+	// Assert
+	expectedJSON := `{
+  "hosts": [
+    "host1",
+    "host2"
+  ],
+  "datastores": [
+    "ds1"
+  ],
+  "entries": [
+    {
+      "host": "host1",
+      "datastore": "ds1",
+      "mounted": true,
+      "accessible": true,
+      "expected": true
+    },
+    {
+      "host": "host2",
+      "datastore": "ds1",
+      "mounted": false,
+      "accessible": false,
+      "expected": true,
+      "reason": "not mounted"
+    }
+  ]
+}`
+	if string(data) != expectedJSON {
+		t.Errorf("Unexpected report:\n%s\nexpected:\n%s", string(data), expectedJSON)
+	}
+}
@@ -0,0 +1,74 @@
+package operator
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	resultsPath = "/results"
+)
+
+// ReportStore keeps CheckReport of the last round of checks in memory.
+type ReportStore struct {
+	mutex  sync.RWMutex
+	report *CheckReport
+}
+
+// NewReportStore returns a new, empty ReportStore.
+func NewReportStore() *ReportStore {
+	return &ReportStore{}
+}
+
+// Save replaces the stored report with the given one.
+func (s *ReportStore) Save(report *CheckReport) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.report = report
+}
+
+// Latest returns the report of the last round of checks, nil when no round has completed yet.
+func (s *ReportStore) Latest() *CheckReport {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.report
+}
+
+// ResultsHandler serves CheckReport of the last round of checks as JSON, with the outcome
+// and error message of each check on each node. must-gather can collect it to tell which
+// checks failed and why.
+type ResultsHandler struct {
+	reportStore *ReportStore
+}
+
+var _ http.Handler = &ResultsHandler{}
+
+// NewResultsHandler returns a new ResultsHandler that reads reports from the given store.
+func NewResultsHandler(reportStore *ReportStore) *ResultsHandler {
+	return &ResultsHandler{
+		reportStore: reportStore,
+	}
+}
+
+func (h *ResultsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := h.reportStore.Latest()
+	if report == nil {
+		http.Error(w, "no round of checks has completed yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		klog.V(2).Infof("Failed to write check results: %s", err)
+	}
+}
@@ -0,0 +1,73 @@
+package operator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultsHandler(t *testing.T) {
+	startTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	collector := NewResultsCollector()
+	collector.AddResult(checkResult{Name: "CheckA", VCenter: "vc1", StartTime: startTime.Add(time.Second), Duration: time.Second})
+	collector.AddResult(checkResult{Name: "CheckB", Node: "node1", VCenter: "vc1", StartTime: startTime.Add(2 * time.Second), Error: errors.New("error")})
+	collector.AddResult(checkResult{Name: "CheckC", Disabled: true})
+	report := collector.Report(startTime, startTime.Add(time.Minute), "")
+
+	tests := []struct {
+		name           string
+		report         *CheckReport
+		method         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "no report",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "no round of checks has completed yet",
+		},
+		{
+			name:           "last report",
+			report:         report,
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"startTime":"2022-01-02T03:04:05Z","finishTime":"2022-01-02T03:05:05Z",` +
+				`"clusterChecks":[{"name":"CheckA","target":"cluster","vCenter":"vc1","outcome":"passed","timestamp":"2022-01-02T03:04:06Z","durationSeconds":1},` +
+				`{"name":"CheckC","target":"cluster","outcome":"disabled","timestamp":"2022-01-02T03:04:05Z","durationSeconds":0}],` +
+				`"nodeChecks":{"node1":[{"name":"CheckB","target":"node","node":"node1","vCenter":"vc1","outcome":"failed","message":"error","timestamp":"2022-01-02T03:04:07Z","durationSeconds":0}]}}`,
+		},
+		{
+			name:           "unsupported method",
+			report:         report,
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   "method not allowed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewReportStore()
+			if test.report != nil {
+				store.Save(test.report)
+			}
+			handler := NewResultsHandler(store)
+
+			req := httptest.NewRequest(test.method, resultsPath, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.expectedStatus {
+				t.Errorf("Expected status %d, got %d", test.expectedStatus, rec.Code)
+			}
+			body := strings.TrimSpace(rec.Body.String())
+			if body != test.expectedBody {
+				t.Errorf("Expected body %s, got %s", test.expectedBody, body)
+			}
+		})
+	}
+}
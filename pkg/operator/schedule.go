@@ -0,0 +1,116 @@
+package operator
+
+import (
+	"time"
+)
+
+// checkSchedule tracks when individual checks ran, so checks with a long interval run less often than
+// the others. A check that is not due in a round of checks is skipped and results of its last run
+// are reported instead.
+// The schedule is updated only between rounds, it's safe to read it from checks running in parallel.
+type checkSchedule struct {
+	// check name -> start of the last round in which the check ran
+	lastRun map[string]time.Time
+	// check name -> results of the last successful run of the check, one for each node for node checks
+	lastResults map[string][]checkResult
+	// set of checks skipped in the current round
+	skipped map[string]bool
+}
+
+func newCheckSchedule() *checkSchedule {
+	return &checkSchedule{
+		lastRun:     make(map[string]time.Time),
+		lastResults: make(map[string][]checkResult),
+		skipped:     make(map[string]bool),
+	}
+}
+
+// startRound marks checks whose interval has not elapsed since their last run as skipped in the round
+// that starts at start.
+func (s *checkSchedule) startRound(start time.Time, names []string, interval func(name string) time.Duration) {
+	if s == nil {
+		return
+	}
+	s.skipped = make(map[string]bool)
+	for _, name := range names {
+		lastRun, found := s.lastRun[name]
+		if found && start.Sub(lastRun) < interval(name) {
+			s.skipped[name] = true
+		}
+	}
+}
+
+// isSkipped returns true when the check does not run in the current round.
+func (s *checkSchedule) isSkipped(name string) bool {
+	if s == nil {
+		return false
+	}
+	return s.skipped[name]
+}
+
+// skippedResults returns results of the last run of a skipped check.
+func (s *checkSchedule) skippedResults(name string) []checkResult {
+	if s == nil {
+		return nil
+	}
+	return s.lastResults[name]
+}
+
+// finishRound stores results of the checks that ran in the round that started at start.
+// Disabled checks and checks that failed are forgotten, so they run in the next round, as soon
+// as they are enabled again or with exponential backoff after the failure.
+func (s *checkSchedule) finishRound(start time.Time, results []checkResult) {
+	if s == nil {
+		return
+	}
+	ran := make(map[string][]checkResult)
+	forget := make(map[string]bool)
+	for _, res := range results {
+		if res.Disabled || res.Error != nil {
+			forget[res.Name] = true
+			continue
+		}
+		if !s.skipped[res.Name] {
+			ran[res.Name] = append(ran[res.Name], res)
+		}
+	}
+	for name := range forget {
+		delete(s.lastRun, name)
+		delete(s.lastResults, name)
+		delete(ran, name)
+	}
+	for name, checkResults := range ran {
+		s.lastRun[name] = start
+		s.lastResults[name] = checkResults
+	}
+}
+
+// reset forgets all previous runs of checks, so all of them run in the next round.
+func (s *checkSchedule) reset() {
+	if s == nil {
+		return
+	}
+	s.lastRun = make(map[string]time.Time)
+	s.lastResults = make(map[string][]checkResult)
+}
+
+// nextRun returns how long to wait until the first of the checks is due, but at most maxDelay.
+func (s *checkSchedule) nextRun(now time.Time, names []string, interval func(name string) time.Duration, maxDelay time.Duration) time.Duration {
+	if s == nil {
+		return maxDelay
+	}
+	delay := maxDelay
+	for _, name := range names {
+		lastRun, found := s.lastRun[name]
+		if !found {
+			continue
+		}
+		if due := lastRun.Add(interval(name)).Sub(now); due < delay {
+			delay = due
+		}
+	}
+	if delay < defaultBackoff.Duration {
+		delay = defaultBackoff.Duration
+	}
+	return delay
+}
@@ -0,0 +1,76 @@
+package operator
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckSchedule(t *testing.T) {
+	intervals := map[string]time.Duration{
+		"CheckFast": 10 * time.Minute,
+		"CheckSlow": time.Hour,
+	}
+	interval := func(name string) time.Duration { return intervals[name] }
+	names := []string{"CheckFast", "CheckSlow"}
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newCheckSchedule()
+
+	// First round: all checks run.
+	s.startRound(start, names, interval)
+	if s.isSkipped("CheckFast") || s.isSkipped("CheckSlow") {
+		t.Fatalf("Expected all checks to run in the first round, skipped %v", s.skipped)
+	}
+	s.finishRound(start, []checkResult{
+		{Name: "CheckFast"},
+		{Name: "CheckSlow", Node: "node1"},
+		{Name: "CheckSlow", Node: "node2"},
+	})
+	if delay := s.nextRun(start.Add(time.Second), names, interval, time.Hour); delay != 10*time.Minute-time.Second {
+		t.Errorf("Expected next round in 10m after the first round, got %s", delay)
+	}
+
+	// Second round: only CheckFast is due.
+	second := start.Add(10 * time.Minute)
+	s.startRound(second, names, interval)
+	if s.isSkipped("CheckFast") || !s.isSkipped("CheckSlow") {
+		t.Fatalf("Expected only CheckSlow to be skipped in the second round, skipped %v", s.skipped)
+	}
+	expectedResults := []checkResult{{Name: "CheckSlow", Node: "node1"}, {Name: "CheckSlow", Node: "node2"}}
+	if results := s.skippedResults("CheckSlow"); !reflect.DeepEqual(results, expectedResults) {
+		t.Errorf("Expected results of the last run of CheckSlow %+v, got %+v", expectedResults, results)
+	}
+	// The carried results of CheckSlow are reported again in the round.
+	s.finishRound(second, append([]checkResult{{Name: "CheckFast", Error: fmt.Errorf("error")}}, expectedResults...))
+
+	// Third round: failed CheckFast runs again, CheckSlow keeps its last run.
+	third := second.Add(time.Minute)
+	s.startRound(third, names, interval)
+	if s.isSkipped("CheckFast") || !s.isSkipped("CheckSlow") {
+		t.Fatalf("Expected only CheckSlow to be skipped in the third round, skipped %v", s.skipped)
+	}
+	if lastRun := s.lastRun["CheckSlow"]; !lastRun.Equal(start) {
+		t.Errorf("Expected last run of CheckSlow at %s, got %s", start, lastRun)
+	}
+	s.finishRound(third, []checkResult{{Name: "CheckFast"}, {Name: "CheckSlow", Disabled: true}})
+	if _, found := s.lastRun["CheckSlow"]; found {
+		t.Errorf("Expected disabled CheckSlow to be forgotten")
+	}
+}
+
+func TestCheckScheduleNextRun(t *testing.T) {
+	interval := func(name string) time.Duration { return time.Hour }
+	now := time.Now()
+
+	var nilSchedule *checkSchedule
+	if delay := nilSchedule.nextRun(now, []string{"CheckFast"}, interval, 30*time.Minute); delay != 30*time.Minute {
+		t.Errorf("Expected maximum delay without schedule, got %s", delay)
+	}
+
+	s := newCheckSchedule()
+	s.lastRun["CheckFast"] = now.Add(-2 * time.Hour)
+	if delay := s.nextRun(now, []string{"CheckFast"}, interval, 30*time.Minute); delay != defaultBackoff.Duration {
+		t.Errorf("Expected minimum delay %s for an overdue check, got %s", defaultBackoff.Duration, delay)
+	}
+}
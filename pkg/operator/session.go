@@ -0,0 +1,325 @@
+package operator
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	"github.com/openshift/vsphere-problem-detector/pkg/version"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/session/keepalive"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/klog/v2"
+	"k8s.io/legacy-cloud-providers/vsphere"
+)
+
+var (
+	vCenterKeepAliveInterval = flag.Duration("vcenter-keepalive-interval", 5*time.Minute, "How often to refresh vCenter sessions between rounds of checks, so they do not expire as idle.")
+)
+
+// sessionManager keeps a single authenticated session of each vCenter, shared by all rounds of checks.
+// Sessions are refreshed by keep-alive calls, logged in again when vCenter reports them as not
+// authenticated and logged out by logoutAll.
+type sessionManager struct {
+	mu sync.Mutex
+	// sessions holds session of each connected vCenter, vCenter name -> session.
+	sessions map[string]*vCenterSession
+	// closed is true after logoutAll, no new sessions are created then.
+	closed bool
+}
+
+// vCenterSession is the session of a single vCenter.
+type vCenterSession struct {
+	vCenter string
+	// params the session was created with, the session is created again when they change.
+	params  sessionParams
+	client  *govmomi.Client
+	relogin *reloginRoundTripper
+	// restClient is logged in to REST API of the vCenter, nil until restClient() is called.
+	restClient *rest.Client
+}
+
+// sessionParams are parameters of a vCenter connection.
+type sessionParams struct {
+	username string
+	password string
+	insecure bool
+	proxy    clusterProxy
+}
+
+func newSessionManager() *sessionManager {
+	return &sessionManager{
+		sessions: make(map[string]*vCenterSession),
+	}
+}
+
+// client returns a client logged in to the vCenter. The session of the vCenter is reused when it was
+// created with the same parameters and it is still authenticated, otherwise a new login is performed.
+func (m *sessionManager) client(ctx context.Context, cfg *vsphere.VSphereConfig, vCenter, username, password string, proxy *clusterProxy) (*govmomi.Client, error) {
+	params := sessionParams{
+		username: username,
+		password: password,
+		insecure: cfg.Global.InsecureFlag,
+	}
+	if proxy != nil {
+		params.proxy = *proxy
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, fmt.Errorf("vCenter sessions are closed")
+	}
+	if s, found := m.sessions[vCenter]; found {
+		if s.params == params {
+			err := s.ensureLoggedIn(ctx)
+			if err == nil {
+				klog.V(4).Infof("Reusing session of vCenter %s", vCenter)
+				return s.client, nil
+			}
+			klog.V(2).Infof("Failed to reuse session of vCenter %s, logging in again: %s", vCenter, err)
+		} else {
+			klog.V(2).Infof("Connection parameters of vCenter %s changed, logging in again", vCenter)
+		}
+		s.logout(ctx)
+		delete(m.sessions, vCenter)
+		vCenterSessionsMetric.Set(float64(len(m.sessions)))
+	}
+
+	s, err := newSession(ctx, vCenter, params, proxy)
+	if err != nil {
+		return nil, err
+	}
+	m.sessions[vCenter] = s
+	vCenterSessionsMetric.Set(float64(len(m.sessions)))
+	return s.client, nil
+}
+
+// restClient returns a client logged in to REST API of the vCenter, with the credentials of its session.
+// client() must be called for the vCenter first.
+func (m *sessionManager) restClient(ctx context.Context, vCenter string) (*rest.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, found := m.sessions[vCenter]
+	if !found {
+		return nil, fmt.Errorf("vCenter %s is not connected", vCenter)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, *check.Timeout)
+	defer cancel()
+	if s.restClient != nil {
+		restSession, err := s.restClient.Session(tctx)
+		if err != nil {
+			return nil, err
+		}
+		if restSession != nil {
+			return s.restClient, nil
+		}
+		klog.V(2).Infof("REST API session of vCenter %s is not authenticated, logging in again", vCenter)
+	} else {
+		s.restClient = rest.NewClient(s.client.Client)
+		check.UseClientProxy(s.restClient.Client, s.client.Client)
+		// The handler starts on login and stops on logout.
+		s.restClient.Transport = keepalive.NewHandlerREST(s.restClient, *vCenterKeepAliveInterval, nil)
+	}
+	if err := s.restClient.Login(tctx, url.UserPassword(s.params.username, s.params.password)); err != nil {
+		s.restClient = nil
+		return nil, err
+	}
+	return s.restClient, nil
+}
+
+// logoutAll logs out of all vCenters. No new sessions are created afterwards.
+func (m *sessionManager) logoutAll(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for vCenter, s := range m.sessions {
+		s.logout(ctx)
+		delete(m.sessions, vCenter)
+	}
+	vCenterSessionsMetric.Set(0)
+}
+
+// newSession logs in to the vCenter, through the cluster proxy when it is not nil.
+func newSession(ctx context.Context, vCenter string, params sessionParams, proxy *clusterProxy) (*vCenterSession, error) {
+	serverURL, err := soap.ParseURL(vCenter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %s", err)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, *check.Timeout)
+	defer cancel()
+	klog.V(4).Infof("Connecting to %s as %s, insecure %t", vCenter, params.username, params.insecure)
+
+	// Set user to nil there for prevent login during client creation.
+	// See https://github.com/vmware/govmomi/blob/master/client.go#L91
+	serverURL.User = nil
+	// Same as govmomi.NewClient, with the proxy and CA bundle configured before the first call.
+	soapClient := soap.NewClient(serverURL, params.insecure)
+	if err := proxy.configureClient(soapClient); err != nil {
+		return nil, err
+	}
+	vimClient, err := vim25.NewClient(tctx, soapClient)
+	if err != nil {
+		return nil, err
+	}
+	s := &vCenterSession{
+		vCenter: vCenter,
+		params:  params,
+		client: &govmomi.Client{
+			Client:         vimClient,
+			SessionManager: session.NewManager(vimClient),
+		},
+	}
+
+	// Keep the session alive between rounds of checks. The handler starts on login and stops on logout.
+	keepAlive := keepalive.NewHandlerSOAP(vimClient.RoundTripper, *vCenterKeepAliveInterval, s.keepAlive)
+	s.relogin = &reloginRoundTripper{
+		RoundTripper:   keepAlive,
+		vCenter:        vCenter,
+		sessionManager: *vimClient.ServiceContent.SessionManager,
+		username:       params.username,
+		password:       params.password,
+	}
+	// All checks share the client, limit their calls to the vCenter.
+	vimClient.RoundTripper = newRateLimitedRoundTripper(s.relogin)
+
+	// Set up user agent before login for being able to track vpdo component in vcenter sessions list
+	vpdVersion := version.Get()
+	vimClient.UserAgent = fmt.Sprintf("vsphere-problem-detector/%s", vpdVersion)
+
+	if err := s.client.Login(tctx, url.UserPassword(params.username, params.password)); err != nil {
+		return nil, fmt.Errorf("unable to login to vCenter: %w", err)
+	}
+	return s, nil
+}
+
+// ensureLoggedIn logs in to the vCenter again, when its session is not authenticated.
+func (s *vCenterSession) ensureLoggedIn(ctx context.Context) error {
+	tctx, cancel := context.WithTimeout(ctx, *check.Timeout)
+	defer cancel()
+	generation := s.relogin.currentGeneration()
+	user, err := s.client.SessionManager.UserSession(tctx)
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		return nil
+	}
+	return s.relogin.login(tctx, generation)
+}
+
+// keepAlive refreshes the session, it is called periodically by the keep-alive handler.
+// Errors are only logged, the handler would stop otherwise.
+func (s *vCenterSession) keepAlive() error {
+	ctx, cancel := context.WithTimeout(context.Background(), *check.Timeout)
+	defer cancel()
+	if err := s.ensureLoggedIn(ctx); err != nil {
+		klog.Warningf("Failed to keep session of vCenter %s alive: %s", s.vCenter, err)
+	}
+	return nil
+}
+
+// logout logs out of REST API and of the vCenter.
+func (s *vCenterSession) logout(ctx context.Context) {
+	tctx, cancel := context.WithTimeout(ctx, *check.Timeout)
+	defer cancel()
+	if s.restClient != nil {
+		if err := s.restClient.Logout(tctx); err != nil {
+			klog.Errorf("Failed to logout from REST API of %s: %v", s.vCenter, err)
+		}
+		s.restClient = nil
+	}
+	if err := s.client.Logout(tctx); err != nil {
+		klog.Errorf("Failed to logout from %s: %v", s.vCenter, err)
+		return
+	}
+	klog.V(2).Infof("Logged out from %s", s.vCenter)
+}
+
+// reloginRoundTripper logs in to the vCenter again and repeats the call, when vCenter reports that
+// the session is not authenticated, e.g. after vCenter restart or when the session expired.
+type reloginRoundTripper struct {
+	soap.RoundTripper
+	vCenter        string
+	sessionManager vim.ManagedObjectReference
+	username       string
+	password       string
+
+	mu sync.Mutex
+	// generation is incremented on each successful login, so concurrent calls that fail with the same
+	// session log in only once.
+	generation int
+}
+
+var _ soap.RoundTripper = &reloginRoundTripper{}
+
+func (r *reloginRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	switch req.(type) {
+	case *methods.LoginBody, *methods.LogoutBody:
+		return r.RoundTripper.RoundTrip(ctx, req, res)
+	}
+
+	generation := r.currentGeneration()
+	err := r.RoundTripper.RoundTrip(ctx, req, res)
+	if !isNotAuthenticated(err) {
+		return err
+	}
+	if err := r.login(ctx, generation); err != nil {
+		return err
+	}
+	// The response holds the fault of the first call.
+	if v := reflect.ValueOf(res); v.Kind() == reflect.Ptr && !v.IsNil() {
+		v.Elem().Set(reflect.Zero(v.Elem().Type()))
+	}
+	return r.RoundTripper.RoundTrip(ctx, req, res)
+}
+
+func (r *reloginRoundTripper) currentGeneration() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generation
+}
+
+// login logs in to the vCenter, unless another call logged in since generation.
+func (r *reloginRoundTripper) login(ctx context.Context, generation int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.generation != generation {
+		return nil
+	}
+	klog.V(2).Infof("Session of vCenter %s is not authenticated, logging in again", r.vCenter)
+	req := vim.Login{
+		This:     r.sessionManager,
+		UserName: r.username,
+		Password: r.password,
+	}
+	if _, err := methods.Login(ctx, r.RoundTripper, &req); err != nil {
+		return fmt.Errorf("failed to login to vCenter %s again: %w", r.vCenter, err)
+	}
+	r.generation++
+	return nil
+}
+
+// isNotAuthenticated returns true if the error is NotAuthenticated fault.
+func isNotAuthenticated(err error) bool {
+	if err == nil || !soap.IsSoapFault(err) {
+		return false
+	}
+	switch soap.ToSoapFault(err).VimFault().(type) {
+	case vim.NotAuthenticated, *vim.NotAuthenticated:
+		return true
+	}
+	return false
+}
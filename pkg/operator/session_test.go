@@ -0,0 +1,161 @@
+package operator
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/simulator"
+	_ "github.com/vmware/govmomi/vapi/simulator"
+	"github.com/vmware/govmomi/vim25/methods"
+	vim "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/legacy-cloud-providers/vsphere"
+)
+
+func startSessionSimulator(t *testing.T) (*simulator.Server, func()) {
+	model := simulator.VPX()
+	if err := model.Create(); err != nil {
+		t.Fatalf("Failed to create vSphere simulator: %s", err)
+	}
+	model.Service.TLS = new(tls.Config)
+	model.Service.RegisterEndpoints = true
+	server := model.Service.NewServer()
+	return server, func() {
+		server.Close()
+		model.Remove()
+	}
+}
+
+func insecureConfig() *vsphere.VSphereConfig {
+	cfg := &vsphere.VSphereConfig{}
+	cfg.Global.InsecureFlag = true
+	return cfg
+}
+
+func expectSessionsMetric(t *testing.T, count string) {
+	t.Helper()
+	expected := `
+# HELP vsphere_vcenter_sessions [ALPHA] Number of vCenter sessions kept open by vsphere-problem-detector. A single session of each vCenter is reused by all rounds of checks.
+# TYPE vsphere_vcenter_sessions gauge
+vsphere_vcenter_sessions ` + count + `
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expected), "vsphere_vcenter_sessions"); err != nil {
+		t.Errorf("Unexpected metric: %s", err)
+	}
+}
+
+func expectLoggedIn(t *testing.T, client *govmomi.Client, loggedIn bool) *vim.UserSession {
+	t.Helper()
+	user, err := client.SessionManager.UserSession(context.TODO())
+	if err != nil {
+		t.Fatalf("Failed to get user session: %s", err)
+	}
+	if (user != nil) != loggedIn {
+		t.Errorf("Expected logged in %t, got session %+v", loggedIn, user)
+	}
+	return user
+}
+
+func TestSessionManagerReuse(t *testing.T) {
+	ctx := context.TODO()
+	server, cleanup := startSessionSimulator(t)
+	defer cleanup()
+	vCenter := server.URL.Host
+	m := newSessionManager()
+
+	client, err := m.client(ctx, insecureConfig(), vCenter, "user", "pass", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+	expectLoggedIn(t, client, true)
+	restClient, err := m.restClient(ctx, vCenter)
+	if err != nil {
+		t.Fatalf("Failed to log in to REST API: %s", err)
+	}
+
+	// The next round reuses the session.
+	reused, err := m.client(ctx, insecureConfig(), vCenter, "user", "pass", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+	if reused != client {
+		t.Errorf("Expected the session to be reused")
+	}
+	reusedREST, err := m.restClient(ctx, vCenter)
+	if err != nil {
+		t.Fatalf("Failed to log in to REST API: %s", err)
+	}
+	if reusedREST != restClient {
+		t.Errorf("Expected the REST API session to be reused")
+	}
+	expectSessionsMetric(t, "1")
+
+	// Changed credentials log out of the old session.
+	changed, err := m.client(ctx, insecureConfig(), vCenter, "user", "newpass", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+	if changed == client {
+		t.Errorf("Expected a new session after credentials changed")
+	}
+	expectLoggedIn(t, client, false)
+	expectLoggedIn(t, changed, true)
+	expectSessionsMetric(t, "1")
+
+	m.logoutAll(ctx)
+	expectLoggedIn(t, changed, false)
+	expectSessionsMetric(t, "0")
+	if _, err := m.client(ctx, insecureConfig(), vCenter, "user", "pass", nil); err == nil {
+		t.Errorf("Expected error after logout")
+	}
+}
+
+func TestSessionManagerRelogin(t *testing.T) {
+	ctx := context.TODO()
+	server, cleanup := startSessionSimulator(t)
+	defer cleanup()
+	vCenter := server.URL.Host
+	m := newSessionManager()
+	defer m.logoutAll(ctx)
+
+	admin, err := govmomi.NewClient(ctx, server.URL, true)
+	if err != nil {
+		t.Fatalf("Failed to connect to vSphere simulator: %s", err)
+	}
+	terminate := func(user *vim.UserSession) {
+		if err := session.NewManager(admin.Client).TerminateSession(ctx, []string{user.Key}); err != nil {
+			t.Fatalf("Failed to terminate session: %s", err)
+		}
+	}
+
+	client, err := m.client(ctx, insecureConfig(), vCenter, "user", "pass", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+
+	// A call with an expired session logs in again transparently.
+	expired := expectLoggedIn(t, client, true)
+	terminate(expired)
+	if _, err := methods.GetCurrentTime(ctx, client.Client); err != nil {
+		t.Errorf("Expected the call to succeed after login, got: %s", err)
+	}
+	if user := expectLoggedIn(t, client, true); user != nil && user.Key == expired.Key {
+		t.Errorf("Expected a new session, got the terminated one")
+	}
+
+	// The next round logs in again to the same client.
+	terminate(expectLoggedIn(t, client, true))
+	reused, err := m.client(ctx, insecureConfig(), vCenter, "user", "pass", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+	if reused != client {
+		t.Errorf("Expected the session to be reused")
+	}
+	expectLoggedIn(t, client, true)
+}
@@ -0,0 +1,220 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	configclient "github.com/openshift/client-go/config/clientset/versioned"
+	configinformer "github.com/openshift/client-go/config/informers/externalversions"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	"github.com/openshift/vsphere-problem-detector/pkg/util"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// OutputTable prints results of standalone checks as a human readable table.
+	OutputTable = "table"
+	// OutputJSON prints results of standalone checks as CheckReport JSON.
+	OutputJSON = "json"
+)
+
+// StandaloneOptions configure a single round of checks run outside of the cluster, see RunStandaloneChecks.
+type StandaloneOptions struct {
+	// Kubeconfig is the path to kubeconfig of the cluster. The default client config loading rules
+	// (KUBECONFIG environment variable, ~/.kube/config) apply when empty.
+	Kubeconfig string
+	// CloudConfigFile is the path to the vSphere cloud provider config. The config of the cluster is used when empty.
+	CloudConfigFile string
+	// Username and Password are used for all vCenters. Credentials of the cluster are used when empty.
+	Username string
+	Password string
+	// Output is the format of the results, OutputTable or OutputJSON.
+	Output string
+}
+
+// Validate checks the options are complete.
+func (o *StandaloneOptions) Validate() error {
+	if o.Output != OutputTable && o.Output != OutputJSON {
+		return fmt.Errorf("unsupported output %q, use %q or %q", o.Output, OutputTable, OutputJSON)
+	}
+	if (o.Username == "") != (o.Password == "") {
+		return fmt.Errorf("both username and password must be set")
+	}
+	return nil
+}
+
+// RunStandaloneChecks runs all enabled checks once against the cluster and vCenters in the options and prints
+// the results to out. It returns true when at least one check failed or timed out.
+// Unlike the operator, it does not record events or update conditions.
+func RunStandaloneChecks(ctx context.Context, opts *StandaloneOptions, out io.Writer) (bool, error) {
+	if err := opts.Validate(); err != nil {
+		return false, err
+	}
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = opts.Kubeconfig
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to load kubeconfig: %s", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return false, err
+	}
+	configClient, err := configclient.NewForConfig(restConfig)
+	if err != nil {
+		return false, err
+	}
+
+	var cloudConfig string
+	if opts.CloudConfigFile != "" {
+		data, err := os.ReadFile(opts.CloudConfigFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to read cloud config: %s", err)
+		}
+		cloudConfig = string(data)
+	}
+	var credentials *vCenterCredentials
+	if opts.Username != "" {
+		credentials = &vCenterCredentials{username: opts.Username, password: opts.Password}
+	}
+
+	kubeInformers := v1helpers.NewKubeInformersForNamespaces(kubeClient, operatorNamespace, cloudConfigNamespace, csiDriverNamespace, "")
+	configInformers := configinformer.NewSharedInformerFactory(configClient, resync)
+	c := &vSphereProblemDetectorController{
+		kubeClient:           kubeClient,
+		secretLister:         kubeInformers.InformersFor(operatorNamespace).Core().V1().Secrets().Lister(),
+		nodeLister:           kubeInformers.InformersFor("").Core().V1().Nodes().Lister(),
+		pvLister:             kubeInformers.InformersFor("").Core().V1().PersistentVolumes().Lister(),
+		pvcLister:            kubeInformers.InformersFor("").Core().V1().PersistentVolumeClaims().Lister(),
+		scLister:             kubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
+		vaLister:             kubeInformers.InformersFor("").Storage().V1().VolumeAttachments().Lister(),
+		cloudConfigMapLister: kubeInformers.InformersFor(cloudConfigNamespace).Core().V1().ConfigMaps().Lister(),
+		csiSecretLister:      kubeInformers.InformersFor(csiDriverNamespace).Core().V1().Secrets().Lister(),
+		infraLister:          configInformers.Config().V1().Infrastructures().Lister(),
+		proxyLister:          configInformers.Config().V1().Proxies().Lister(),
+		clusterChecks:        instrumentClusterChecks(check.DefaultClusterChecks),
+		nodeChecks:           instrumentNodeChecks(check.DefaultNodeChecks),
+		checkOptions:         check.NewCheckOptions(),
+		resultStore:          check.NewMemoryResultStore(1),
+		sessions:             newSessionManager(),
+		checkerFunc: func(c *vSphereProblemDetectorController) vSphereCheckerInterface {
+			return &vSphereChecker{controller: c, cloudConfig: cloudConfig, credentials: credentials}
+		},
+	}
+	if unknown := c.checkOptions.UnknownChecks(c.clusterChecks, c.nodeChecks); len(unknown) > 0 {
+		klog.Warningf("Unknown checks configured to be enabled or disabled: %s", strings.Join(unknown, ", "))
+	}
+
+	kubeInformers.Start(ctx.Done())
+	configInformers.Start(ctx.Done())
+	klog.V(2).Infof("Waiting for informers to sync")
+	if !waitForInformers(ctx, kubeInformers, configInformers) {
+		return false, fmt.Errorf("failed to sync informers: %s", ctx.Err())
+	}
+
+	defer c.sessions.logoutAll(context.Background())
+	startTime := time.Now()
+	resultCollector, err := c.checkerFunc(c).runChecks(ctx, util.NewClusterInfo())
+	if err != nil {
+		return false, err
+	}
+	report := resultCollector.Report(startTime, time.Now(), c.zone())
+	if *checkReportFile != "" {
+		if err := writeCheckReport(*checkReportFile, report); err != nil {
+			klog.Errorf("Failed to write check report to %s: %s", *checkReportFile, err)
+		}
+	}
+	if err := printCheckReport(out, report, opts.Output); err != nil {
+		return false, err
+	}
+	return reportFailed(report), nil
+}
+
+// waitForInformers waits until all started informers sync. It returns false when ctx ends first.
+func waitForInformers(ctx context.Context, kubeInformers v1helpers.KubeInformersForNamespaces, configInformers configinformer.SharedInformerFactory) bool {
+	var synced []map[reflect.Type]bool
+	for namespace := range kubeInformers.Namespaces() {
+		synced = append(synced, kubeInformers.InformersFor(namespace).WaitForCacheSync(ctx.Done()))
+	}
+	synced = append(synced, configInformers.WaitForCacheSync(ctx.Done()))
+	for _, informers := range synced {
+		for informerType, ok := range informers {
+			if !ok {
+				klog.Errorf("Informer %s did not sync", informerType)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// reportFailed returns true when at least one check in the report failed or timed out.
+func reportFailed(report *CheckReport) bool {
+	for _, res := range report.ClusterChecks {
+		if res.Outcome == checkStatusFailed || res.Outcome == checkStatusTimeout {
+			return true
+		}
+	}
+	for _, results := range report.NodeChecks {
+		for _, res := range results {
+			if res.Outcome == checkStatusFailed || res.Outcome == checkStatusTimeout {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// printCheckReport prints the report in the given output format. The table has a row for each cluster check
+// and for each node check on each node, sorted by node name, followed by a summary of outcomes.
+func printCheckReport(out io.Writer, report *CheckReport, output string) error {
+	if output == OutputJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+
+	rows := append([]CheckReportResult(nil), report.ClusterChecks...)
+	var nodes []string
+	for node := range report.NodeChecks {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		rows = append(rows, report.NodeChecks[node]...)
+	}
+
+	outcomes := make(map[string]int)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tTARGET\tOUTCOME\tMESSAGE")
+	for _, res := range rows {
+		target := res.Target
+		if res.Node != "" {
+			target = res.Node
+		}
+		// Errors joined by JoinErrors span several lines, keep each check on a single row.
+		message := strings.ReplaceAll(res.Message, "\n", " ")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", res.Name, target, res.Outcome, message)
+		outcomes[res.Outcome]++
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "\n%d passed, %d failed, %d timed out, %d disabled\n",
+		outcomes[checkStatusPassed], outcomes[checkStatusFailed], outcomes[checkStatusTimeout], outcomes[checkStatusDisabled])
+	return err
+}
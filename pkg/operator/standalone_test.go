@@ -0,0 +1,128 @@
+package operator
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStandaloneOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          StandaloneOptions
+		expectedError string
+	}{
+		{
+			name: "table",
+			opts: StandaloneOptions{Output: OutputTable},
+		},
+		{
+			name: "json with credentials",
+			opts: StandaloneOptions{Output: OutputJSON, Username: "user", Password: "pass"},
+		},
+		{
+			name:          "unsupported output",
+			opts:          StandaloneOptions{Output: "yaml"},
+			expectedError: `unsupported output "yaml", use "table" or "json"`,
+		},
+		{
+			name:          "username without password",
+			opts:          StandaloneOptions{Output: OutputTable, Username: "user"},
+			expectedError: "both username and password must be set",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.opts.Validate()
+			errString := ""
+			if err != nil {
+				errString = err.Error()
+			}
+			if errString != test.expectedError {
+				t.Errorf("Expected error %q, got %q", test.expectedError, errString)
+			}
+		})
+	}
+}
+
+func TestPrintCheckReport(t *testing.T) {
+	startTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	collector := NewResultsCollector()
+	collector.AddResult(checkResult{Name: "CheckB", VCenter: "vc1"})
+	collector.AddResult(checkResult{Name: "CheckA", Disabled: true})
+	collector.AddResult(checkResult{Name: "CheckC", Node: "node2", VCenter: "vc1", Error: errors.New("error 1;\nerror 2")})
+	collector.AddResult(checkResult{Name: "CheckC", Node: "node1", VCenter: "vc1", TimedOut: true, Error: errors.New("timed out")})
+	report := collector.Report(startTime, startTime.Add(time.Minute), "")
+
+	var out bytes.Buffer
+	if err := printCheckReport(&out, report, OutputTable); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expectedTable := `CHECK   TARGET   OUTCOME   MESSAGE
+CheckA  cluster  disabled  
+CheckB  cluster  passed    
+CheckC  node1    timeout   timed out
+CheckC  node2    failed    error 1; error 2
+
+1 passed, 1 failed, 1 timed out, 1 disabled
+`
+	if out.String() != expectedTable {
+		t.Errorf("Expected table:\n%s\ngot:\n%s", expectedTable, out.String())
+	}
+
+	out.Reset()
+	if err := printCheckReport(&out, report, OutputJSON); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(out.String(), `"outcome": "timeout"`) {
+		t.Errorf("Expected JSON report, got:\n%s", out.String())
+	}
+}
+
+func TestReportFailed(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  []checkResult
+		expected bool
+	}{
+		{
+			name: "passed and disabled",
+			results: []checkResult{
+				{Name: "CheckA"},
+				{Name: "CheckB", Disabled: true},
+				{Name: "CheckC", Node: "node1"},
+			},
+		},
+		{
+			name: "failed cluster check",
+			results: []checkResult{
+				{Name: "CheckA", Error: errors.New("error")},
+			},
+			expected: true,
+		},
+		{
+			name: "timed out node check",
+			results: []checkResult{
+				{Name: "CheckA"},
+				{Name: "CheckC", Node: "node1", TimedOut: true, Error: errors.New("timed out")},
+			},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			collector := NewResultsCollector()
+			for _, res := range test.results {
+				collector.AddResult(res)
+			}
+			report := collector.Report(time.Now(), time.Now(), "")
+			if failed := reportFailed(report); failed != test.expected {
+				t.Errorf("Expected failed %t, got %t", test.expected, failed)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	"github.com/openshift/library-go/pkg/operator/loglevel"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
 	"k8s.io/client-go/kubernetes"
 
 	"k8s.io/klog/v2"
@@ -29,7 +30,7 @@ func RunOperator(ctx context.Context, controllerConfig *controllercmd.Controller
 	if err != nil {
 		return err
 	}
-	kubeInformers := v1helpers.NewKubeInformersForNamespaces(kubeClient, operatorNamespace, cloudConfigNamespace, "")
+	kubeInformers := v1helpers.NewKubeInformersForNamespaces(kubeClient, operatorNamespace, cloudConfigNamespace, csiDriverNamespace, "")
 
 	csiConfigClient, err := operatorclient.NewForConfig(controllerConfig.KubeConfig)
 	if err != nil {
@@ -48,14 +49,27 @@ func RunOperator(ctx context.Context, controllerConfig *controllercmd.Controller
 	}
 	configInformers := configinformer.NewSharedInformerFactoryWithOptions(configClient, resync)
 
+	resultStore := check.NewMemoryResultStore(resultStoreRuns)
+	reportStore := NewReportStore()
+	sessions := newSessionManager()
 	operator := NewVSphereProblemDetectorController(
 		operatorClient,
 		kubeClient,
 		kubeInformers,
 		configInformers.Config().V1().Infrastructures(),
+		configInformers.Config().V1().Proxies(),
+		resultStore,
+		reportStore,
+		sessions,
 		controllerConfig.EventRecorder,
 	)
 
+	if controllerConfig.Server != nil {
+		registerHealthHandlers(controllerConfig.Server.Handler.NonGoRestfulMux, NewHealthHandler(resultStore), NewResultsHandler(reportStore))
+	} else {
+		klog.Warningf("Metrics server is not running, health status and check results are not served")
+	}
+
 	logLevelController := loglevel.NewClusterOperatorLoggingController(operatorClient, controllerConfig.EventRecorder)
 
 	klog.Info("Starting the Informers.")
@@ -80,5 +94,10 @@ func RunOperator(ctx context.Context, controllerConfig *controllercmd.Controller
 	}
 	<-ctx.Done()
 
+	// Checks stop with ctx, log out so the sessions do not stay open in vCenters until they expire.
+	logoutCtx, cancel := context.WithTimeout(context.Background(), *check.Timeout)
+	defer cancel()
+	sessions.logoutAll(logoutCtx)
+
 	return fmt.Errorf("stopped")
 }
@@ -3,19 +3,21 @@ package operator
 import (
 	"context"
 	"fmt"
-	"net/url"
+	"sort"
 	"strings"
+	"time"
 
 	ocpv1 "github.com/openshift/api/config/v1"
 	"github.com/openshift/vsphere-problem-detector/pkg/check"
 	"github.com/openshift/vsphere-problem-detector/pkg/util"
-	"github.com/openshift/vsphere-problem-detector/pkg/version"
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
-	"github.com/vmware/govmomi/vim25/soap"
+	vim "github.com/vmware/govmomi/vim25/types"
 	"gopkg.in/gcfg.v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
@@ -28,6 +30,21 @@ type vSphereCheckerInterface interface {
 
 type vSphereChecker struct {
 	controller *vSphereProblemDetectorController
+	// vCenterContexts holds CheckContext of each connected vCenter, vCenter name -> context.
+	// Node checks of a node use the context of the vCenter that runs the node VM.
+	vCenterContexts map[string]*check.CheckContext
+	// connectErrors holds errors of vCenters that the checker failed to connect to, vCenter name -> error.
+	connectErrors map[string]error
+	// csiCredentials holds credentials from the vSphere CSI driver config, vCenter name -> credentials.
+	// vCenters without credentials there use the cloud credentials secret.
+	csiCredentials map[string]vCenterCredentials
+	// cloudConfig is the vSphere cloud provider config used instead of the config of the cluster.
+	// The config is read from the cluster when empty.
+	cloudConfig string
+	// credentials are used for all vCenters instead of credentials from the cluster, when set.
+	credentials *vCenterCredentials
+	// proxy is the cluster-wide proxy used to connect to vCenters, nil when the cluster has none.
+	proxy *clusterProxy
 }
 
 var _ vSphereCheckerInterface = &vSphereChecker{}
@@ -38,17 +55,12 @@ func newVSphereChecker(c *vSphereProblemDetectorController) vSphereCheckerInterf
 
 func (v *vSphereChecker) runChecks(ctx context.Context, clusterInfo *util.ClusterInfo) (*ResultCollector, error) {
 	resultCollector := NewResultsCollector()
+	// Sessions of vCenters are kept by the controller for the next rounds, they're not logged out here.
 	vmConfig, vmClient, err := v.connect(ctx)
 	if err != nil {
 		return resultCollector, err
 	}
 
-	defer func() {
-		if err := vmClient.Logout(ctx); err != nil {
-			klog.Errorf("Failed to logout: %v", err)
-		}
-	}()
-
 	// Get the fully-qualified vsphere username
 	sessionMgr := session.NewManager(vmClient.Client)
 	user, err := sessionMgr.UserSession(ctx)
@@ -66,9 +78,44 @@ func (v *vSphereChecker) runChecks(ctx context.Context, clusterInfo *util.Cluste
 		Username:    user.UserName,
 		KubeClient:  v.controller,
 		ClusterInfo: clusterInfo,
+		VMClients: map[string]*vim25.Client{
+			vmConfig.Workspace.VCenterIP: vmClient.Client,
+		},
+		ResultStore: v.controller.resultStore,
+		ReportSink:  resultCollector,
+		VMCache:     check.NewVMCache(),
+	}
+
+	// Only checks of vSphere tags need the REST API, the other checks run without it.
+	restClient, err := v.controller.sessions.restClient(ctx, vmConfig.Workspace.VCenterIP)
+	if err != nil {
+		klog.Errorf("Failed to log in to REST API of vCenter %s: %s", vmConfig.Workspace.VCenterIP, err)
+	} else {
+		checkContext.TagManager = tags.NewManager(restClient)
+	}
+
+	v.vCenterContexts = map[string]*check.CheckContext{
+		vmConfig.Workspace.VCenterIP: checkContext,
+	}
+	v.connectErrors = make(map[string]error)
+
+	// Connect to the other vCenters. Failure to connect to one of them fails only
+	// node checks of nodes that may run there.
+	for _, vCenter := range getOtherVCenters(vmConfig) {
+		vcClient, vcContext, err := v.connectVCenter(checkContext, vCenter)
+		if err != nil {
+			klog.Errorf("Failed to connect to vCenter %s: %s", vCenter, err)
+			v.connectErrors[vCenter] = err
+			continue
+		}
+		checkContext.VMClients[vCenter] = vcClient.Client
+		v.vCenterContexts[vCenter] = vcContext
 	}
 
-	checkRunner := NewCheckThreadPool(parallelVSPhereCalls, channelBufferSize)
+	if *checkWorkers < 1 {
+		return resultCollector, fmt.Errorf("invalid check-workers %d, it must be at least 1", *checkWorkers)
+	}
+	checkRunner := NewCheckThreadPool(*checkWorkers, channelBufferSize)
 
 	v.enqueueClusterChecks(checkContext, checkRunner, resultCollector)
 	if err := v.enqueueNodeChecks(checkContext, checkRunner, resultCollector); err != nil {
@@ -80,26 +127,27 @@ func (v *vSphereChecker) runChecks(ctx context.Context, clusterInfo *util.Cluste
 		return resultCollector, err
 	}
 	v.finishNodeChecks(checkContext)
+	check.CollectInventory(checkContext)
 	return resultCollector, nil
 }
 
 func (c *vSphereChecker) connect(ctx context.Context) (*vsphere.VSphereConfig, *govmomi.Client, error) {
-	cfgString, err := c.getVSphereConfig(ctx)
+	cfg, err := c.loadConfig(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	cfg, err := parseConfig(cfgString)
+	username, password, err := c.getCredentials(cfg.Workspace.VCenterIP)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse config: %s", err)
+		return nil, nil, err
 	}
 
-	username, password, err := c.getCredentials(cfg)
+	c.proxy, err = c.getClusterProxy()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	vmClient, err := newClient(ctx, cfg, username, password)
+	vmClient, err := c.controller.sessions.client(ctx, cfg, cfg.Workspace.VCenterIP, username, password, c.proxy)
 	if err != nil {
 		if strings.Index(username, "\n") != -1 {
 			syncErrrorMetric.WithLabelValues("UsernameWithNewLine").Set(1)
@@ -115,7 +163,7 @@ func (c *vSphereChecker) connect(ctx context.Context) (*vsphere.VSphereConfig, *
 			syncErrrorMetric.WithLabelValues("PasswordWithNewLine").Set(0)
 		}
 		syncErrrorMetric.WithLabelValues("InvalidCredentials").Set(1)
-		return nil, nil, fmt.Errorf("failed to connect to %s: %s", cfg.Workspace.VCenterIP, err)
+		return nil, nil, check.DescribeAuthenticationError(cfg.Workspace.VCenterIP, username, err)
 	} else {
 		syncErrrorMetric.WithLabelValues("InvalidCredentials").Set(0)
 	}
@@ -127,17 +175,112 @@ func (c *vSphereChecker) connect(ctx context.Context) (*vsphere.VSphereConfig, *
 	return cfg, vmClient, nil
 }
 
-func (c *vSphereChecker) getCredentials(cfg *vsphere.VSphereConfig) (string, string, error) {
+// connectVCenter logs in to a vCenter other than the Workspace one. It returns the client and CheckContext
+// for node checks of nodes that run in the vCenter, derived from the Workspace checkContext.
+func (c *vSphereChecker) connectVCenter(checkContext *check.CheckContext, vCenter string) (*govmomi.Client, *check.CheckContext, error) {
+	ctx := checkContext.Context
+	username, password, err := c.getCredentials(vCenter)
+	if err != nil {
+		return nil, nil, err
+	}
+	vmClient, err := c.controller.sessions.client(ctx, checkContext.VMConfig, vCenter, username, password, c.proxy)
+	if err != nil {
+		return nil, nil, check.DescribeAuthenticationError(vCenter, username, err)
+	}
+
+	sessionMgr := session.NewManager(vmClient.Client)
+	user, err := sessionMgr.UserSession(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	klog.V(2).Infof("Connected to %s as %s", vCenter, username)
+
+	vcContext := *checkContext
+	vcContext.VMClient = vmClient.Client
+	vcContext.AuthManager = object.NewAuthorizationManager(vmClient.Client)
+	vcContext.Username = user.UserName
+	vcContext.TagManager = nil
+	restClient, err := c.controller.sessions.restClient(ctx, vCenter)
+	if err != nil {
+		klog.Errorf("Failed to log in to REST API of vCenter %s: %s", vCenter, err)
+	} else {
+		vcContext.TagManager = tags.NewManager(restClient)
+	}
+	vcContext.VMCache = check.NewVMCache()
+	return vmClient, &vcContext, nil
+}
+
+// getOtherVCenters returns sorted names of vCenters in the config other than the Workspace one.
+func getOtherVCenters(cfg *vsphere.VSphereConfig) []string {
+	var vCenters []string
+	for vCenter := range cfg.VirtualCenter {
+		if vCenter != cfg.Workspace.VCenterIP {
+			vCenters = append(vCenters, vCenter)
+		}
+	}
+	sort.Strings(vCenters)
+	return vCenters
+}
+
+// loadConfig returns vSphere configuration from the vSphere CSI driver config, completed by the legacy
+// cloud provider config. Only the legacy config is used when the CSI driver config is not available.
+// cloudConfig of the checker, when set, is used instead of both.
+func (c *vSphereChecker) loadConfig(ctx context.Context) (*vsphere.VSphereConfig, error) {
+	if c.cloudConfig != "" {
+		cfg, err := parseConfig(c.cloudConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config: %s", err)
+		}
+		return cfg, nil
+	}
+
+	var legacyCfg *vsphere.VSphereConfig
+	cfgString, legacyErr := c.getVSphereConfig(ctx)
+	if legacyErr == nil {
+		legacyCfg, legacyErr = parseConfig(cfgString)
+		if legacyErr != nil {
+			legacyErr = fmt.Errorf("failed to parse config: %s", legacyErr)
+		}
+	}
+
+	csiCfg, err := c.getCSIConfig()
+	if err != nil {
+		if legacyErr != nil {
+			return nil, legacyErr
+		}
+		klog.V(2).Infof("Using legacy cloud provider config, vSphere CSI driver config is not available: %s", err)
+		return legacyCfg, nil
+	}
+	if legacyErr != nil {
+		klog.V(2).Infof("Using only vSphere CSI driver config, legacy cloud provider config is not available: %s", legacyErr)
+		legacyCfg = nil
+	}
+	cfg, credentials, err := mergeCSIConfig(csiCfg, legacyCfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vSphere CSI driver config: %s", err)
+	}
+	c.csiCredentials = credentials
+	klog.V(4).Infof("Using vSphere CSI driver config with vCenters %s", strings.Join(append([]string{cfg.Workspace.VCenterIP}, getOtherVCenters(cfg)...), ", "))
+	return cfg, nil
+}
+
+func (c *vSphereChecker) getCredentials(vCenter string) (string, string, error) {
+	if c.credentials != nil {
+		return c.credentials.username, c.credentials.password, nil
+	}
+	if credentials, found := c.csiCredentials[vCenter]; found {
+		return credentials.username, credentials.password, nil
+	}
 	secret, err := c.controller.secretLister.Secrets(operatorNamespace).Get(cloudCredentialsSecretName)
 	if err != nil {
 		return "", "", err
 	}
-	userKey := cfg.Workspace.VCenterIP + "." + "username"
+	userKey := vCenter + "." + "username"
 	username, ok := secret.Data[userKey]
 	if !ok {
 		return "", "", fmt.Errorf("error parsing secret %q: key %q not found", cloudCredentialsSecretName, userKey)
 	}
-	passwordKey := cfg.Workspace.VCenterIP + "." + "password"
+	passwordKey := vCenter + "." + "password"
 	password, ok := secret.Data[passwordKey]
 	if !ok {
 		return "", "", fmt.Errorf("error parsing secret %q: key %q not found", cloudCredentialsSecretName, passwordKey)
@@ -172,43 +315,217 @@ func (c *vSphereChecker) getVSphereConfig(ctx context.Context) (string, error) {
 	return cfgString, nil
 }
 
+// enqueueClusterChecks enqueues each enabled cluster check for each connected vCenter, see clusterCheckContexts.
 func (c *vSphereChecker) enqueueClusterChecks(checkContext *check.CheckContext, checkRunner *CheckThreadPool, resultCollector *ResultCollector) {
+	vCenterContexts := c.clusterCheckContexts(checkContext)
 	for name, checkFunc := range c.controller.clusterChecks {
 		name := name
 		checkFunc := checkFunc
-		checkRunner.RunGoroutine(checkContext.Context, func() {
-			runSingleClusterCheck(checkContext, name, checkFunc, resultCollector)
-		})
+		contexts := vCenterContexts
+		if check.WorkspaceClusterChecks[name] {
+			contexts = vCenterContexts[:1]
+		}
+		if !c.controller.checkOptions.IsEnabled(name) {
+			klog.V(2).Infof("%s disabled", name)
+			for _, vcContext := range contexts {
+				clusterCheckVCenterErrorMetric.WithLabelValues(name, vcContext.VMConfig.Workspace.VCenterIP).Set(0)
+			}
+			resultCollector.AddResult(checkResult{Name: name, Disabled: true})
+			continue
+		}
+		if c.controller.schedule.isSkipped(name) {
+			klog.V(4).Infof("%s skipped, its interval has not elapsed yet", name)
+			for _, res := range c.controller.schedule.skippedResults(name) {
+				resultCollector.AddResult(res)
+			}
+			continue
+		}
+		for _, vcContext := range contexts {
+			vcContext := vcContext
+			checkRunner.RunGoroutine(checkContext.Context, func() {
+				runSingleClusterCheck(vcContext, name, checkFunc, resultCollector)
+			})
+		}
 	}
 }
 
+// clusterCheckContexts returns CheckContext for cluster checks of each connected vCenter, the Workspace one first.
+// Workspace of the other contexts points to their vCenter without any datacenter, so the checks find node VMs
+// and their objects in all datacenters of the vCenter in VMConfig. Datacenter, folder, default datastore
+// and resource pool of the Workspace section are in the Workspace vCenter, so they are cleared there.
+func (c *vSphereChecker) clusterCheckContexts(checkContext *check.CheckContext) []*check.CheckContext {
+	contexts := []*check.CheckContext{checkContext}
+	for _, vCenter := range getOtherVCenters(checkContext.VMConfig) {
+		vcContext, found := c.vCenterContexts[vCenter]
+		if !found {
+			// Not connected, CheckVCenterConnectivity and node checks report it.
+			continue
+		}
+		vmConfig := *vcContext.VMConfig
+		vmConfig.Workspace.VCenterIP = vCenter
+		vmConfig.Workspace.Datacenter = ""
+		vmConfig.Workspace.Folder = ""
+		vmConfig.Workspace.DefaultDatastore = ""
+		vmConfig.Workspace.ResourcePoolPath = ""
+		clusterContext := *vcContext
+		clusterContext.VMConfig = &vmConfig
+		contexts = append(contexts, &clusterContext)
+	}
+	return contexts
+}
+
+// nodeVM is the VM of a node found in the vCenter that runs it.
+type nodeVM struct {
+	node *v1.Node
+	// nodeContext is CheckContext for node checks of the node, see nodeCheckContext
+	nodeContext *check.CheckContext
+	vmRef       vim.ManagedObjectReference
+	// match is how the VM was matched to the node, nodeVMMatchNone when err is set.
+	match string
+	err   error
+}
+
 func (c *vSphereChecker) enqueueNodeChecks(checkContext *check.CheckContext, checkRunner *CheckThreadPool, resultCollector *ResultCollector) error {
 	nodes, err := c.controller.ListNodes(checkContext.Context)
 	if err != nil {
 		return err
 	}
 
-	for _, nodeCheck := range c.controller.nodeChecks {
+	for _, nodeCheck := range c.enabledNodeChecks() {
 		nodeCheck.StartCheck()
 	}
+	// Drop nodes that do not exist any longer.
+	nodeVMMatchMetric.Reset()
+	c.addSkippedNodeResults(nodes, resultCollector)
 
-	for i := range nodes {
-		node := nodes[i]
-		c.enqueueSingleNodeChecks(checkContext, checkRunner, resultCollector, node)
+	nodeVMs, err := c.findNodeVMs(checkContext, nodes)
+	if err != nil {
+		return err
+	}
+	prefetchNodeVMs(nodeVMs)
+	for i := range nodeVMs {
+		c.enqueueSingleNodeChecks(checkContext, checkRunner, resultCollector, nodeVMs[i])
 	}
 	return nil
 }
 
-func (c *vSphereChecker) enqueueSingleNodeChecks(checkContext *check.CheckContext, checkRunner *CheckThreadPool, resultCollector *ResultCollector, node *v1.Node) {
-	// Run a go routine that reads VM from vSphere and schedules separate goroutines for each check.
+// addSkippedNodeResults reports results of the last run of node checks that are skipped in this round,
+// for nodes that still exist. Nodes created since the last run get results when the checks run again.
+func (c *vSphereChecker) addSkippedNodeResults(nodes []*v1.Node, resultCollector *ResultCollector) {
+	nodeNames := make(map[string]bool)
+	for _, node := range nodes {
+		nodeNames[node.Name] = true
+	}
+	for _, nodeCheck := range c.controller.nodeChecks {
+		name := nodeCheck.Name()
+		if !c.controller.checkOptions.IsEnabled(name) || !c.controller.schedule.isSkipped(name) {
+			continue
+		}
+		klog.V(4).Infof("%s skipped, its interval has not elapsed yet", name)
+		for _, res := range c.controller.schedule.skippedResults(name) {
+			if nodeNames[res.Node] {
+				resultCollector.AddResult(res)
+			}
+		}
+	}
+}
+
+// findNodeVMs finds VMs of all nodes in parallel, in the order of nodes.
+func (c *vSphereChecker) findNodeVMs(checkContext *check.CheckContext, nodes []*v1.Node) ([]*nodeVM, error) {
+	pool := NewCheckThreadPool(*checkWorkers, channelBufferSize)
+	nodeVMs := make([]*nodeVM, len(nodes))
+	for i := range nodes {
+		i := i
+		pool.RunGoroutine(checkContext.Context, func() {
+			nodeVMs[i] = c.findNodeVM(checkContext, nodes[i])
+		})
+	}
+	if err := pool.Wait(checkContext.Context); err != nil {
+		return nil, err
+	}
+	return nodeVMs, nil
+}
+
+// findNodeVM finds the VM of the node in the vCenter that runs it.
+func (c *vSphereChecker) findNodeVM(checkContext *check.CheckContext, node *v1.Node) *nodeVM {
+	res := &nodeVM{node: node, match: nodeVMMatchNone}
+	nodeContext, err := c.nodeCheckContext(checkContext, node)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	res.nodeContext = nodeContext
+	vmRef, match, err := findVM(nodeContext, node)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	res.vmRef = vmRef
+	res.match = match
+	return res
+}
+
+// prefetchNodeVMs retrieves properties of all found node VMs, in a batch for each vCenter.
+func prefetchNodeVMs(nodeVMs []*nodeVM) {
+	// VMCache of a vCenter -> context of the vCenter and VMs in it
+	contexts := make(map[*check.VMCache]*check.CheckContext)
+	refs := make(map[*check.VMCache][]vim.ManagedObjectReference)
+	var caches []*check.VMCache
+	for _, nodeVM := range nodeVMs {
+		if nodeVM.err != nil || nodeVM.nodeContext.VMCache == nil {
+			continue
+		}
+		cache := nodeVM.nodeContext.VMCache
+		if _, found := contexts[cache]; !found {
+			contexts[cache] = nodeVM.nodeContext
+			caches = append(caches, cache)
+		}
+		refs[cache] = append(refs[cache], nodeVM.vmRef)
+	}
+	for _, cache := range caches {
+		contexts[cache].PrefetchVMs(refs[cache])
+	}
+}
+
+func (c *vSphereChecker) enqueueSingleNodeChecks(checkContext *check.CheckContext, checkRunner *CheckThreadPool, resultCollector *ResultCollector, nodeVM *nodeVM) {
+	node := nodeVM.node
+	// Run a go routine that gets VM from the cache and schedules separate goroutines for each check.
 	checkRunner.RunGoroutine(checkContext.Context, func() {
-		// Try to get VM
-		vm, err := getVM(checkContext, node)
+		// Report disabled checks
+		for _, check := range c.controller.nodeChecks {
+			if !c.controller.checkOptions.IsEnabled(check.Name()) {
+				klog.V(4).Infof("%s:%s disabled", check.Name(), node.Name)
+				nodeCheckErrrorMetric.WithLabelValues(check.Name(), node.Name).Set(0)
+				if nodeVM.err == nil {
+					nodeCheckVCenterErrorMetric.WithLabelValues(check.Name(), node.Name, nodeVM.nodeContext.VMConfig.Workspace.VCenterIP).Set(0)
+				}
+				resultCollector.AddResult(checkResult{Name: check.Name(), Node: node.Name, Disabled: true})
+			}
+		}
+		nodeChecks := c.enabledNodeChecks()
+
+		var vm *mo.VirtualMachine
+		var vCenter string
+		match, err := nodeVM.match, nodeVM.err
+		if err == nil {
+			vm, err = nodeVM.nodeContext.GetVM(nodeVM.vmRef)
+			if err != nil {
+				err = fmt.Errorf("failed to load VM %s: %s", node.Name, err)
+				match = nodeVMMatchNone
+			} else {
+				vCenter = nodeVM.nodeContext.VMConfig.Workspace.VCenterIP
+			}
+		}
+		nodeVMMatchMetric.WithLabelValues(node.Name, match, vCenter).Set(1)
+		resultCollector.AddNodeVMMatch(node.Name, match)
 		if err != nil {
+			err = c.withConnectErrors(err)
+			klog.Warningf("Failed to find VM of node %s by ProviderID or by name: %s", node.Name, err)
 			// mark all checks as failed
-			for _, check := range c.controller.nodeChecks {
+			for _, check := range nodeChecks {
 				res := checkResult{
 					Name:  check.Name(),
+					Node:  node.Name,
 					Error: err,
 				}
 				resultCollector.AddResult(res)
@@ -216,59 +533,123 @@ func (c *vSphereChecker) enqueueSingleNodeChecks(checkContext *check.CheckContex
 			return
 		}
 		// We got the VM, enqueue all node checks
-		for i := range c.controller.nodeChecks {
-			check := c.controller.nodeChecks[i]
+		for i := range nodeChecks {
+			check := nodeChecks[i]
 			klog.V(4).Infof("Adding node check %s:%s", node.Name, check.Name())
-			runSingleNodeSingleCheck(checkContext, resultCollector, node, vm, check)
+			runSingleNodeSingleCheck(nodeVM.nodeContext, resultCollector, node, vm, check)
 		}
 	})
 }
 
+// nodeCheckContext returns CheckContext for node checks of the node. It is connected to the vCenter that runs
+// the node VM and its Workspace points to the vCenter and datacenter of the VM.
+func (c *vSphereChecker) nodeCheckContext(checkContext *check.CheckContext, node *v1.Node) (*check.CheckContext, error) {
+	if len(checkContext.VMClients) <= 1 {
+		return checkContext, nil
+	}
+	nodeVCenter, err := checkContext.VMClientForNode(node)
+	if err != nil {
+		return nil, err
+	}
+	vcContext, found := c.vCenterContexts[nodeVCenter.VCenter]
+	if !found {
+		return nil, fmt.Errorf("vCenter %s of node %s is not connected", nodeVCenter.VCenter, node.Name)
+	}
+	vmConfig := *vcContext.VMConfig
+	vmConfig.Workspace.VCenterIP = nodeVCenter.VCenter
+	vmConfig.Workspace.Datacenter = nodeVCenter.Datacenter
+	nodeContext := *vcContext
+	nodeContext.VMConfig = &vmConfig
+	return &nodeContext, nil
+}
+
+// withConnectErrors adds errors of vCenters that the checker failed to connect to, the VM could run there.
+func (c *vSphereChecker) withConnectErrors(err error) error {
+	if len(c.connectErrors) == 0 {
+		return err
+	}
+	var vCenters []string
+	for vCenter := range c.connectErrors {
+		vCenters = append(vCenters, vCenter)
+	}
+	sort.Strings(vCenters)
+	errs := []error{err}
+	for _, vCenter := range vCenters {
+		errs = append(errs, fmt.Errorf("vCenter %s is not connected: %s", vCenter, c.connectErrors[vCenter]))
+	}
+	return check.JoinErrors(errs)
+}
+
 func runSingleClusterCheck(checkContext *check.CheckContext, name string, checkFunc check.ClusterCheck, resultCollector *ResultCollector) {
+	vCenter := checkContext.VMConfig.Workspace.VCenterIP
 	res := checkResult{
-		Name: name,
+		Name:    name,
+		VCenter: vCenter,
 	}
-	klog.V(4).Infof("%s starting", name)
+	// Logging is done by check.WithInstrumentation
+	start := time.Now()
 	err := checkFunc(checkContext)
+	res.StartTime = start
+	res.Duration = time.Since(start)
 	if err != nil {
 		res.Error = err
-		clusterCheckErrrorMetric.WithLabelValues(name).Set(1)
-		klog.V(2).Infof("%s failed: %s", name, err)
+		res.TimedOut = check.IsCheckTimeout(err)
+		clusterCheckVCenterErrorMetric.WithLabelValues(name, vCenter).Set(1)
 	} else {
-		clusterCheckErrrorMetric.WithLabelValues(name).Set(0)
-		klog.V(2).Infof("%s passed", name)
+		clusterCheckVCenterErrorMetric.WithLabelValues(name, vCenter).Set(0)
 	}
+	// vsphere_cluster_check_errors is set when all vCenters finished the check, see reportClusterCheckErrors.
 	clusterCheckTotalMetric.WithLabelValues(name).Inc()
 	resultCollector.AddResult(res)
 }
 
-func runSingleNodeSingleCheck(checkContext *check.CheckContext, resultCollector *ResultCollector, node *v1.Node, vm *mo.VirtualMachine, check check.NodeCheck) {
-	name := check.Name()
+func runSingleNodeSingleCheck(checkContext *check.CheckContext, resultCollector *ResultCollector, node *v1.Node, vm *mo.VirtualMachine, nodeCheck check.NodeCheck) {
+	name := nodeCheck.Name()
 	res := checkResult{
-		Name: name,
-	}
-	klog.V(4).Infof("%s:%s starting ", name, node.Name)
-	err := check.CheckNode(checkContext, node, vm)
+		Name:    name,
+		Node:    node.Name,
+		VCenter: checkContext.VMConfig.Workspace.VCenterIP,
+	}
+	// Logging is done by check.WithNodeInstrumentation
+	start := time.Now()
+	err := nodeCheck.CheckNode(checkContext, node, vm)
+	res.StartTime = start
+	res.Duration = time.Since(start)
 	if err != nil {
 		res.Error = err
+		res.TimedOut = check.IsCheckTimeout(err)
 		nodeCheckErrrorMetric.WithLabelValues(name, node.Name).Set(1)
-		klog.V(2).Infof("%s:%s failed: %s", name, node.Name, err)
+		nodeCheckVCenterErrorMetric.WithLabelValues(name, node.Name, res.VCenter).Set(1)
 	} else {
 		nodeCheckErrrorMetric.WithLabelValues(name, node.Name).Set(0)
-		klog.V(2).Infof("%s:%s passed", name, node.Name)
+		nodeCheckVCenterErrorMetric.WithLabelValues(name, node.Name, res.VCenter).Set(0)
 	}
 	nodeCheckTotalMetric.WithLabelValues(name, node.Name).Inc()
 	resultCollector.AddResult(res)
 }
 
 func (c *vSphereChecker) finishNodeChecks(ctx *check.CheckContext) {
-	for i := range c.controller.nodeChecks {
-		check := c.controller.nodeChecks[i]
+	nodeChecks := c.enabledNodeChecks()
+	for i := range nodeChecks {
+		check := nodeChecks[i]
 		check.FinishCheck(ctx)
 	}
 }
 
-func getVM(checkContext *check.CheckContext, node *v1.Node) (*mo.VirtualMachine, error) {
+// enabledNodeChecks returns node checks that are not disabled in CheckOptions and that are not skipped
+// in this round, because their interval has not elapsed yet.
+func (c *vSphereChecker) enabledNodeChecks() []check.NodeCheck {
+	var nodeChecks []check.NodeCheck
+	for _, nodeCheck := range c.controller.nodeChecks {
+		if c.controller.checkOptions.IsEnabled(nodeCheck.Name()) && !c.controller.schedule.isSkipped(nodeCheck.Name()) {
+			nodeChecks = append(nodeChecks, nodeCheck)
+		}
+	}
+	return nodeChecks
+}
+
+// findVM returns reference to the VM of the node and how the VM was matched to the node.
+func findVM(checkContext *check.CheckContext, node *v1.Node) (vim.ManagedObjectReference, string, error) {
 	tctx, cancel := context.WithTimeout(checkContext.Context, *check.Timeout)
 	defer cancel()
 
@@ -276,33 +657,18 @@ func getVM(checkContext *check.CheckContext, node *v1.Node) (*mo.VirtualMachine,
 	finder := find.NewFinder(checkContext.VMClient, false)
 	dc, err := finder.Datacenter(tctx, checkContext.VMConfig.Workspace.Datacenter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to access Datacenter %s: %s", checkContext.VMConfig.Workspace.Datacenter, err)
+		return vim.ManagedObjectReference{}, "", fmt.Errorf("failed to access Datacenter %s: %s", checkContext.VMConfig.Workspace.Datacenter, err)
 	}
 
-	// Find VM reference in the datastore, by UUID
-	s := object.NewSearchIndex(dc.Client())
-	vmUUID := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(node.Spec.ProviderID, "vsphere://")))
-	tctx, cancel = context.WithTimeout(checkContext.Context, *check.Timeout)
-	defer cancel()
-	svm, err := s.FindByUuid(tctx, dc, vmUUID, true, nil)
+	// Find VM reference in the datastore, by UUID or by name when the node has no ProviderID
+	vmRef, match, err := check.FindNodeVM(checkContext, dc, node)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find VM by UUID %s: %s", vmUUID, err)
-	}
-	if svm == nil {
-		return nil, fmt.Errorf("unable to find VM by UUID %s", vmUUID)
+		return vim.ManagedObjectReference{}, "", err
 	}
-
-	// Find VM properties
-	vm := object.NewVirtualMachine(checkContext.VMClient, svm.Reference())
-	tctx, cancel = context.WithTimeout(checkContext.Context, *check.Timeout)
-	defer cancel()
-	var o mo.VirtualMachine
-	err = vm.Properties(tctx, vm.Reference(), check.NodeProperties, &o)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load VM %s: %s", node.Name, err)
+	if match != check.NodeVMMatchProviderID {
+		klog.V(2).Infof("Node %s has no ProviderID, matched VM %s by name", node.Name, vmRef.Value)
 	}
-
-	return &o, nil
+	return vmRef, match, nil
 }
 
 func parseConfig(data string) (*vsphere.VSphereConfig, error) {
@@ -313,36 +679,3 @@ func parseConfig(data string) (*vsphere.VSphereConfig, error) {
 	}
 	return &cfg, nil
 }
-
-func newClient(ctx context.Context, cfg *vsphere.VSphereConfig, username, password string) (*govmomi.Client, error) {
-	serverAddress := cfg.Workspace.VCenterIP
-	serverURL, err := soap.ParseURL(serverAddress)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %s", err)
-	}
-
-	insecure := cfg.Global.InsecureFlag
-
-	tctx, cancel := context.WithTimeout(ctx, *check.Timeout)
-	defer cancel()
-	klog.V(4).Infof("Connecting to %s as %s, insecure %t", serverAddress, username, insecure)
-
-	// Set user to nil there for prevent login during client creation.
-	// See https://github.com/vmware/govmomi/blob/master/client.go#L91
-	serverURL.User = nil
-	client, err := govmomi.NewClient(tctx, serverURL, insecure)
-
-	if err != nil {
-		return nil, err
-	}
-
-	// Set up user agent before login for being able to track vpdo component in vcenter sessions list
-	vpdVersion := version.Get()
-	client.UserAgent = fmt.Sprintf("vsphere-problem-detector/%s", vpdVersion)
-
-	if err := client.Login(tctx, url.UserPassword(username, password)); err != nil {
-		return nil, fmt.Errorf("unable to login to vCenter: %w", err)
-	}
-
-	return client, nil
-}
@@ -0,0 +1,246 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openshift/vsphere-problem-detector/pkg/check"
+	testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func TestGetOtherVCenters(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           string
+		expectedVCenters []string
+	}{
+		{
+			name: "single vCenter",
+			config: `[Workspace]
+server = "vc1"
+datacenter = "DC1"
+
+[VirtualCenter "vc1"]
+datacenters = "DC1"
+`,
+		},
+		{
+			name: "multiple vCenters",
+			config: `[Workspace]
+server = "vc1"
+datacenter = "DC1"
+
+[VirtualCenter "vc3"]
+datacenters = "DC3"
+
+[VirtualCenter "vc1"]
+datacenters = "DC1"
+
+[VirtualCenter "vc2"]
+datacenters = "DC2"
+`,
+			expectedVCenters: []string{"vc2", "vc3"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := parseConfig(test.config)
+			if err != nil {
+				t.Fatalf("Failed to parse config: %s", err)
+			}
+			vCenters := getOtherVCenters(cfg)
+			if !reflect.DeepEqual(vCenters, test.expectedVCenters) {
+				t.Errorf("Expected vCenters %v, got %v", test.expectedVCenters, vCenters)
+			}
+		})
+	}
+}
+
+func TestWithConnectErrors(t *testing.T) {
+	checker := &vSphereChecker{
+		connectErrors: map[string]error{},
+	}
+	err := checker.withConnectErrors(errors.New("unable to find VM by UUID foo"))
+	if err.Error() != "unable to find VM by UUID foo" {
+		t.Errorf("Unexpected error without connect errors: %q", err)
+	}
+
+	checker.connectErrors["vc3"] = errors.New("failed to connect to vc3: timeout")
+	checker.connectErrors["vc2"] = errors.New("failed to connect to vc2: invalid login")
+	err = checker.withConnectErrors(errors.New("unable to find VM by UUID foo"))
+	expectedError := "unable to find VM by UUID foo;\n" +
+		"vCenter vc2 is not connected: failed to connect to vc2: invalid login;\n" +
+		"vCenter vc3 is not connected: failed to connect to vc3: timeout"
+	if err.Error() != expectedError {
+		t.Errorf("Expected error %q, got %q", expectedError, err)
+	}
+}
+
+func TestCheckerOverrides(t *testing.T) {
+	checker := &vSphereChecker{
+		cloudConfig: testLegacyConfig,
+		credentials: &vCenterCredentials{username: "user", password: "pass"},
+	}
+	cfg, err := checker.loadConfig(context.TODO())
+	if err != nil {
+		t.Fatalf("Failed to load config: %s", err)
+	}
+	if cfg.Workspace.VCenterIP != "vc1" || cfg.Workspace.Datacenter != "DC1" {
+		t.Errorf("Expected Workspace vc1/DC1, got %s/%s", cfg.Workspace.VCenterIP, cfg.Workspace.Datacenter)
+	}
+	username, password, err := checker.getCredentials("vc2")
+	if err != nil {
+		t.Fatalf("Failed to get credentials: %s", err)
+	}
+	if username != "user" || password != "pass" {
+		t.Errorf("Expected credentials user/pass, got %s/%s", username, password)
+	}
+}
+
+func TestEnqueueClusterChecksPerVCenter(t *testing.T) {
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+	cfg, err := parseConfig(`[Workspace]
+server = "vc1"
+datacenter = "DC1"
+default-datastore = "LocalDS_0"
+folder = "/DC1/vm/cluster"
+
+[VirtualCenter "vc1"]
+datacenters = "DC1"
+
+[VirtualCenter "vc2"]
+datacenters = "DC2, DC3"
+
+[VirtualCenter "vc3"]
+datacenters = "DC4"
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse config: %s", err)
+	}
+	checkContext := &check.CheckContext{Context: context.TODO(), VMConfig: cfg}
+	vc2Context := &check.CheckContext{Context: context.TODO(), VMConfig: cfg, VMCache: check.NewVMCache()}
+
+	// Workspace seen by each run of the checks, "check@vCenter" -> "datacenter,default datastore,folder".
+	var lock sync.Mutex
+	workspaces := make(map[string]string)
+	recordWorkspace := func(name string) check.ClusterCheck {
+		return func(ctx *check.CheckContext) error {
+			lock.Lock()
+			defer lock.Unlock()
+			ws := ctx.VMConfig.Workspace
+			workspaces[name+"@"+ws.VCenterIP] = strings.Join([]string{ws.Datacenter, ws.DefaultDatastore, ws.Folder}, ",")
+			if ws.VCenterIP == "vc2" {
+				if ctx.VMCache != vc2Context.VMCache {
+					return errors.New("expected VMCache of vc2")
+				}
+				return fmt.Errorf("%s failed", name)
+			}
+			return nil
+		}
+	}
+	checker := &vSphereChecker{
+		controller: &vSphereProblemDetectorController{
+			clusterChecks: map[string]check.ClusterCheck{
+				"CheckFoo":         recordWorkspace("CheckFoo"),
+				"CheckCredentials": recordWorkspace("CheckCredentials"),
+			},
+		},
+		// vc3 is not connected.
+		vCenterContexts: map[string]*check.CheckContext{
+			"vc1": checkContext,
+			"vc2": vc2Context,
+		},
+	}
+	resultCollector := NewResultsCollector()
+	checkRunner := NewCheckThreadPool(2, channelBufferSize)
+
+	checker.enqueueClusterChecks(checkContext, checkRunner, resultCollector)
+	if err := checkRunner.Wait(context.TODO()); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expectedWorkspaces := map[string]string{
+		"CheckFoo@vc1":         "DC1,LocalDS_0,/DC1/vm/cluster",
+		"CheckFoo@vc2":         ",,",
+		"CheckCredentials@vc1": "DC1,LocalDS_0,/DC1/vm/cluster",
+	}
+	if !reflect.DeepEqual(workspaces, expectedWorkspaces) {
+		t.Errorf("Expected checks to run with Workspaces %v, got %v", expectedWorkspaces, workspaces)
+	}
+	if cfg.Workspace.VCenterIP != "vc1" || cfg.Workspace.Folder != "/DC1/vm/cluster" {
+		t.Errorf("Expected unchanged vSphere configuration, got Workspace %+v", cfg.Workspace)
+	}
+
+	var results []string
+	for _, res := range resultCollector.Results() {
+		results = append(results, fmt.Sprintf("%s@%s: %v", res.Name, res.VCenter, res.Error))
+	}
+	sort.Strings(results)
+	expectedResults := []string{
+		"CheckCredentials@vc1: <nil>",
+		"CheckFoo@vc1: <nil>",
+		"CheckFoo@vc2: CheckFoo failed",
+	}
+	if !reflect.DeepEqual(results, expectedResults) {
+		t.Errorf("Expected results %v, got %v", expectedResults, results)
+	}
+
+	reportClusterCheckErrors(resultCollector.Results())
+	expectedMetrics := `
+# HELP vsphere_cluster_check_errors [ALPHA] Indicates failing vSphere cluster-level checks performed by vsphere-problem-detector. Value of 1 means - a particular check is failing.
+# TYPE vsphere_cluster_check_errors gauge
+vsphere_cluster_check_errors{check="CheckCredentials"} 0
+vsphere_cluster_check_errors{check="CheckFoo"} 1
+# HELP vsphere_cluster_check_vcenter_errors [ALPHA] Indicates failing vSphere cluster-level checks performed by vsphere-problem-detector in each vCenter. Value of 1 means - a particular check is failing in the vCenter of the vcenter label.
+# TYPE vsphere_cluster_check_vcenter_errors gauge
+vsphere_cluster_check_vcenter_errors{check="CheckCredentials",vcenter="vc1"} 0
+vsphere_cluster_check_vcenter_errors{check="CheckFoo",vcenter="vc1"} 0
+vsphere_cluster_check_vcenter_errors{check="CheckFoo",vcenter="vc2"} 1
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_cluster_check_errors", "vsphere_cluster_check_vcenter_errors"); err != nil {
+		t.Errorf("Unexpected metrics: %s", err)
+	}
+}
+
+func TestRunSingleNodeSingleCheckMetrics(t *testing.T) {
+	// Reset metrics from previous tests. Note: the tests can't run in parallel!
+	legacyregistry.Reset()
+	cfg, err := parseConfig(`[Workspace]
+server = "vc1"
+datacenter = "DC1"
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse config: %s", err)
+	}
+	vmConfig := *cfg
+	vmConfig.Workspace.VCenterIP = "vc2"
+	nodeContext := &check.CheckContext{Context: context.TODO(), VMConfig: &vmConfig}
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	resultCollector := NewResultsCollector()
+
+	runSingleNodeSingleCheck(nodeContext, resultCollector, node, &mo.VirtualMachine{}, &failingNodeCheck{})
+
+	expectedMetrics := `
+# HELP vsphere_node_check_errors [ALPHA] Indicates failing vSphere node-level checks performed by vsphere-problem-detector. Value of 1 means - a particular check is failing on a node.
+# TYPE vsphere_node_check_errors gauge
+vsphere_node_check_errors{check="FailingNodeCheck",node="node1"} 1
+# HELP vsphere_node_check_vcenter_errors [ALPHA] Indicates failing vSphere node-level checks performed by vsphere-problem-detector in each vCenter. Value of 1 means - a particular check is failing on a node whose VM runs in the vCenter of the vcenter label.
+# TYPE vsphere_node_check_vcenter_errors gauge
+vsphere_node_check_vcenter_errors{check="FailingNodeCheck",node="node1",vcenter="vc2"} 1
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expectedMetrics), "vsphere_node_check_errors", "vsphere_node_check_vcenter_errors"); err != nil {
+		t.Errorf("Unexpected metrics: %s", err)
+	}
+}
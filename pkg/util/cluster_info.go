@@ -9,17 +9,23 @@ type ESXiVersionInfo struct {
 
 type ClusterInfo struct {
 	// map of host and its esxi version info
-	esxiVersions      map[string]ESXiVersionInfo
+	esxiVersions map[string]ESXiVersionInfo
+	// map of host and its user-friendly name
+	hostNames         map[string]string
 	hwVersions        map[string]int
+	datastoreTypes    map[string]string
 	vcenterVersion    string
 	vcenterAPIVersion string
+	vcenterBuild      string
 	esxiVersionsLock  sync.RWMutex
 }
 
 func NewClusterInfo() *ClusterInfo {
 	info := &ClusterInfo{
-		esxiVersions: make(map[string]ESXiVersionInfo),
-		hwVersions:   make(map[string]int),
+		esxiVersions:   make(map[string]ESXiVersionInfo),
+		hostNames:      make(map[string]string),
+		hwVersions:     make(map[string]int),
+		datastoreTypes: make(map[string]string),
 	}
 	return info
 }
@@ -27,13 +33,16 @@ func NewClusterInfo() *ClusterInfo {
 // MakeClusterInfo is only used for tests
 func MakeClusterInfo(d map[string]string) *ClusterInfo {
 	info := &ClusterInfo{
-		esxiVersions: make(map[string]ESXiVersionInfo),
-		hwVersions:   make(map[string]int),
+		esxiVersions:   make(map[string]ESXiVersionInfo),
+		hostNames:      make(map[string]string),
+		hwVersions:     make(map[string]int),
+		datastoreTypes: make(map[string]string),
 	}
 	info.esxiVersions[d["host_name"]] = ESXiVersionInfo{d["host_version"], d["host_api_version"]}
 	info.hwVersions[d["hw_version"]] = 1
 	info.vcenterAPIVersion = d["vcenter_api_version"]
 	info.vcenterVersion = d["vcenter_version"]
+	info.vcenterBuild = d["vcenter_build"]
 	return info
 }
 
@@ -44,6 +53,14 @@ func (c *ClusterInfo) SetHostVersion(hostname, version, apiVersion string) {
 	c.esxiVersions[hostname] = ESXiVersionInfo{version, apiVersion}
 }
 
+// SetHostName stores user-friendly name of the host, e.g. its IP address.
+func (c *ClusterInfo) SetHostName(hostname, name string) {
+	c.esxiVersionsLock.Lock()
+	defer c.esxiVersionsLock.Unlock()
+
+	c.hostNames[hostname] = name
+}
+
 func (c *ClusterInfo) SetHardwareVersion(version string) {
 	c.esxiVersionsLock.Lock()
 	defer c.esxiVersionsLock.Unlock()
@@ -63,6 +80,26 @@ func (c *ClusterInfo) GetHardwareVersion() map[string]int {
 	return hwVersions
 }
 
+// SetDatastoreType stores type of the datastore, as reported in its summary.
+func (c *ClusterInfo) SetDatastoreType(name, dsType string) {
+	c.esxiVersionsLock.Lock()
+	defer c.esxiVersionsLock.Unlock()
+
+	c.datastoreTypes[name] = dsType
+}
+
+// GetDatastoreTypes returns map of datastore type and number of datastores of that type.
+func (c *ClusterInfo) GetDatastoreTypes() map[string]int {
+	c.esxiVersionsLock.RLock()
+	defer c.esxiVersionsLock.RUnlock()
+
+	dsTypes := make(map[string]int)
+	for _, dsType := range c.datastoreTypes {
+		dsTypes[dsType]++
+	}
+	return dsTypes
+}
+
 func (c *ClusterInfo) SetVCenterVersion(version, apiVersion string) {
 	c.esxiVersionsLock.Lock()
 	defer c.esxiVersionsLock.Unlock()
@@ -70,6 +107,12 @@ func (c *ClusterInfo) SetVCenterVersion(version, apiVersion string) {
 	c.vcenterAPIVersion = apiVersion
 }
 
+func (c *ClusterInfo) SetVCenterBuild(build string) {
+	c.esxiVersionsLock.Lock()
+	defer c.esxiVersionsLock.Unlock()
+	c.vcenterBuild = build
+}
+
 func (c *ClusterInfo) GetHostVersions() map[string]ESXiVersionInfo {
 	c.esxiVersionsLock.RLock()
 	defer c.esxiVersionsLock.RUnlock()
@@ -82,19 +125,41 @@ func (c *ClusterInfo) GetHostVersions() map[string]ESXiVersionInfo {
 	return hostVersions
 }
 
+// GetHostNames returns map of host and its user-friendly name. Hosts without a known name are not present.
+func (c *ClusterInfo) GetHostNames() map[string]string {
+	c.esxiVersionsLock.RLock()
+	defer c.esxiVersionsLock.RUnlock()
+
+	// make a copy of return values
+	hostNames := make(map[string]string)
+	for h, n := range c.hostNames {
+		hostNames[h] = n
+	}
+	return hostNames
+}
+
 func (c *ClusterInfo) GetVCenterVersion() (string, string) {
 	c.esxiVersionsLock.RLock()
 	defer c.esxiVersionsLock.RUnlock()
 	return c.vcenterVersion, c.vcenterAPIVersion
 }
 
+func (c *ClusterInfo) GetVCenterBuild() string {
+	c.esxiVersionsLock.RLock()
+	defer c.esxiVersionsLock.RUnlock()
+	return c.vcenterBuild
+}
+
 func (c *ClusterInfo) Reset() {
 	c.esxiVersionsLock.Lock()
 	defer c.esxiVersionsLock.Unlock()
 	c.esxiVersions = make(map[string]ESXiVersionInfo)
+	c.hostNames = make(map[string]string)
 	c.hwVersions = make(map[string]int)
+	c.datastoreTypes = make(map[string]string)
 	c.vcenterVersion = ""
 	c.vcenterAPIVersion = ""
+	c.vcenterBuild = ""
 }
 
 func (c *ClusterInfo) MarkHostForProcessing(hostname string) (string, bool) {